@@ -1,22 +1,66 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/spf13/viper"
+	"github.com/subosito/gotenv"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server ServerConfig
-	App    AppConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	App          AppConfig
+	Log          LogConfig
+	Tracing      TracingConfig
+	Store        StoreConfig
+	Auth         AuthConfig
+	Transactions TransactionConfig
+	Loyalty      LoyaltyConfig
+	Features     FeatureConfig
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port int
-	Host string
+	Port              int
+	Host              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+
+	// CORSAllowedOrigins lists the browser origins allowed to call the API;
+	// "*" allows any origin and an empty list disables CORS
+	CORSAllowedOrigins []string
+}
+
+// DatabaseConfig holds database connection and pool configuration. URL takes
+// precedence over the individual connection settings.
+type DatabaseConfig struct {
+	URL             string
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+	PingTimeout       time.Duration
 }
 
 // AppConfig holds application-related configuration
@@ -24,53 +68,416 @@ type AppConfig struct {
 	Name        string
 	Version     string
 	Environment string
+	Timezone    string
+	Location    *time.Location
+}
+
+// LogConfig holds logging settings
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+// TracingConfig holds OpenTelemetry tracing settings
+type TracingConfig struct {
+	// Exporter is "none", "stdout" (spans printed for local runs) or "otlp"
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318
+	Endpoint    string
+	SampleRatio float64
+}
+
+// StoreConfig holds the identity of the store printed on receipts
+type StoreConfig struct {
+	Name    string
+	Address string
+	Phone   string
+}
+
+// AuthConfig holds authentication secrets
+type AuthConfig struct {
+	JWTSecret string
+}
+
+// TransactionConfig holds sales related settings
+type TransactionConfig struct {
+	IdempotencyTTL           time.Duration
+	BundleRevenueAttribution string
+}
+
+// LoyaltyConfig holds the loyalty program of customers
+type LoyaltyConfig struct {
+	// RupiahPerPoint is how much a customer spends to earn one point before
+	// the category multipliers
+	RupiahPerPoint int
+	// PointValue is the Rupiah a redeemed point pays
+	PointValue int
+	// PointsTTL is how long earned points last; 0 keeps them forever
+	PointsTTL time.Duration
+}
+
+// FeatureConfig holds feature toggles
+type FeatureConfig struct {
+	Swagger     bool
+	OfflineSync bool
+	Idempotency bool
+	// Loyalty earns and redeems loyalty points on the sales of customers
+	Loyalty bool
+	// Metrics serves Prometheus metrics on /metrics
+	Metrics bool
+}
+
+// setting describes a configuration key: its YAML key, the environment
+// variables it is read from (first match wins) and its default value
+type setting struct {
+	key string
+	env []string
+	def interface{}
+}
+
+// settings lists every configuration key the application reads
+var settings = []setting{
+	{key: "server.host", env: []string{"SERVER_HOST"}, def: ""},
+	{key: "server.port", env: []string{"SERVER_PORT", "PORT"}, def: 8080},
+	{key: "server.read_timeout", env: []string{"SERVER_READ_TIMEOUT"}, def: "15s"},
+	{key: "server.read_header_timeout", env: []string{"SERVER_READ_HEADER_TIMEOUT"}, def: "5s"},
+	{key: "server.write_timeout", env: []string{"SERVER_WRITE_TIMEOUT"}, def: "30s"},
+	{key: "server.idle_timeout", env: []string{"SERVER_IDLE_TIMEOUT"}, def: "60s"},
+	{key: "server.shutdown_timeout", env: []string{"SERVER_SHUTDOWN_TIMEOUT"}, def: "30s"},
+	{key: "server.cors_allowed_origins", env: []string{"CORS_ALLOWED_ORIGINS"}, def: ""},
+
+	{key: "database.url", env: []string{"DATABASE_URL"}, def: ""},
+	{key: "database.host", env: []string{"DB_HOST"}, def: ""},
+	{key: "database.port", env: []string{"DB_PORT"}, def: 5432},
+	{key: "database.user", env: []string{"DB_USER"}, def: ""},
+	{key: "database.password", env: []string{"DB_PASSWORD"}, def: ""},
+	{key: "database.name", env: []string{"DB_NAME"}, def: ""},
+	{key: "database.sslmode", env: []string{"DB_SSLMODE"}, def: "require"},
+	{key: "database.max_open_conns", env: []string{"DB_MAX_OPEN_CONNS"}, def: 10},
+	{key: "database.max_idle_conns", env: []string{"DB_MAX_IDLE_CONNS"}, def: 5},
+	{key: "database.conn_max_lifetime", env: []string{"DB_CONN_MAX_LIFETIME"}, def: "30m"},
+	{key: "database.conn_max_idle_time", env: []string{"DB_CONN_MAX_IDLE_TIME"}, def: "5m"},
+	{key: "database.connect_attempts", env: []string{"DB_CONNECT_ATTEMPTS"}, def: 10},
+	{key: "database.connect_backoff", env: []string{"DB_CONNECT_BACKOFF"}, def: "1s"},
+	{key: "database.connect_max_backoff", env: []string{"DB_CONNECT_MAX_BACKOFF"}, def: "30s"},
+	{key: "database.ping_timeout", env: []string{"DB_PING_TIMEOUT"}, def: "5s"},
+
+	{key: "app.name", env: []string{"APP_NAME"}, def: "Kasir API"},
+	{key: "app.version", env: []string{"APP_VERSION"}, def: "1.0"},
+	{key: "app.environment", env: []string{"APP_ENVIRONMENT"}, def: "development"},
+	{key: "app.timezone", env: []string{"APP_TIMEZONE"}, def: "Asia/Jakarta"},
+
+	{key: "log.level", env: []string{"LOG_LEVEL"}, def: "info"},
+	{key: "log.format", env: []string{"LOG_FORMAT"}, def: "json"},
+
+	{key: "tracing.exporter", env: []string{"TRACING_EXPORTER"}, def: "none"},
+	{key: "tracing.endpoint", env: []string{"OTEL_EXPORTER_OTLP_ENDPOINT"}, def: ""},
+	{key: "tracing.sample_ratio", env: []string{"TRACING_SAMPLE_RATIO"}, def: 1.0},
+
+	{key: "store.name", env: []string{"STORE_NAME"}, def: "Kasir"},
+	{key: "store.address", env: []string{"STORE_ADDRESS"}, def: ""},
+	{key: "store.phone", env: []string{"STORE_PHONE"}, def: ""},
+
+	{key: "auth.jwt_secret", env: []string{"AUTH_JWT_SECRET"}, def: ""},
+
+	{key: "transactions.idempotency_ttl", env: []string{"IDEMPOTENCY_TTL"}, def: "24h"},
+	{key: "transactions.bundle_revenue_attribution", env: []string{"BUNDLE_REVENUE_ATTRIBUTION"}, def: "bundle"},
+
+	{key: "loyalty.rupiah_per_point", env: []string{"LOYALTY_RUPIAH_PER_POINT"}, def: 1000},
+	{key: "loyalty.point_value", env: []string{"LOYALTY_POINT_VALUE"}, def: 10},
+	{key: "loyalty.points_ttl", env: []string{"LOYALTY_POINTS_TTL"}, def: "8760h"},
+
+	{key: "features.swagger", env: []string{"FEATURE_SWAGGER"}, def: true},
+	{key: "features.offline_sync", env: []string{"FEATURE_OFFLINE_SYNC"}, def: true},
+	{key: "features.idempotency", env: []string{"FEATURE_IDEMPOTENCY"}, def: true},
+	{key: "features.loyalty", env: []string{"FEATURE_LOYALTY"}, def: true},
+	{key: "features.metrics", env: []string{"FEATURE_METRICS"}, def: true},
 }
 
+// Supported values of enumerated settings
+var (
+	environments        = []string{"development", "staging", "production"}
+	revenueAttributions = []string{"bundle", "components"}
+	logLevels           = []string{"debug", "info", "warn", "error"}
+	logFormats          = []string{"json", "text"}
+	tracingExporters    = []string{"none", "stdout", "otlp"}
+)
+
 var AppConfiguration *Config
 
-// LoadConfig loads configuration from .env file and environment variables
+// LoadConfig loads configuration from, in increasing order of precedence:
+// defaults, config.yaml, .env and environment variables. Both files are looked
+// up in path and are optional. Invalid settings are reported together.
 func LoadConfig(path string) (*Config, error) {
-	viper.SetConfigName(".env")
-	viper.SetConfigType("env")
-	viper.AddConfigPath(path)
-	viper.AddConfigPath(".")
+	v := viper.New()
 
 	// Set default values
-	viper.SetDefault("SERVER_PORT", 8080)
-	viper.SetDefault("SERVER_HOST", "localhost")
-	viper.SetDefault("APP_NAME", "Kasir API")
-	viper.SetDefault("APP_VERSION", "1.0")
-	viper.SetDefault("APP_ENVIRONMENT", "development")
+	for _, s := range settings {
+		v.SetDefault(s.key, s.def)
+	}
 
-	// Read environment variables
-	viper.AutomaticEnv()
+	// Read config.yaml
+	for _, name := range []string{"config.yaml", "config.yml"} {
+		file := filepath.Join(path, name)
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", file, err)
+		}
+		break
+	}
 
-	// Read .env file
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			log.Println(".env file not found, using defaults and environment variables")
-		} else {
+	// Read .env file; it overrides config.yaml but not the real environment
+	envFile := filepath.Join(path, ".env")
+	if _, err := os.Stat(envFile); err == nil {
+		values, err := gotenv.Read(envFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading .env file: %w", err)
+		}
+		if err := v.MergeConfigMap(envFileConfig(values)); err != nil {
 			return nil, fmt.Errorf("error reading .env file: %w", err)
 		}
 	}
 
+	// Read environment variables
+	for _, s := range settings {
+		if err := v.BindEnv(append([]string{s.key}, s.env...)...); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := build(v)
+	if err != nil {
+		return nil, err
+	}
+
+	AppConfiguration = config
+	return config, nil
+}
+
+// envFileConfig turns the variables of a .env file into nested config keys
+func envFileConfig(values map[string]string) map[string]interface{} {
+	config := make(map[string]interface{})
+	for _, s := range settings {
+		for _, env := range s.env {
+			value, ok := values[env]
+			if !ok || value == "" {
+				continue
+			}
+			section, key, _ := strings.Cut(s.key, ".")
+			if _, ok := config[section]; !ok {
+				config[section] = make(map[string]interface{})
+			}
+			config[section].(map[string]interface{})[key] = value
+			break
+		}
+	}
+	return config
+}
+
+// build reads the typed configuration out of v and validates it
+func build(v *viper.Viper) (*Config, error) {
+	var errs []error
+	duration := func(key string) time.Duration {
+		d, err := time.ParseDuration(v.GetString(key))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid duration %q", key, v.GetString(key)))
+		}
+		return d
+	}
+	// list accepts a YAML list or a comma separated string
+	list := func(key string) []string {
+		var values []string
+		for _, item := range v.GetStringSlice(key) {
+			for _, value := range strings.Split(item, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					values = append(values, value)
+				}
+			}
+		}
+		return values
+	}
+
 	config := &Config{
 		Server: ServerConfig{
-			Port: viper.GetInt("SERVER_PORT"),
-			Host: viper.GetString("SERVER_HOST"),
+			Port:              v.GetInt("server.port"),
+			Host:              v.GetString("server.host"),
+			ReadTimeout:       duration("server.read_timeout"),
+			ReadHeaderTimeout: duration("server.read_header_timeout"),
+			WriteTimeout:      duration("server.write_timeout"),
+			IdleTimeout:       duration("server.idle_timeout"),
+			ShutdownTimeout:   duration("server.shutdown_timeout"),
+
+			CORSAllowedOrigins: list("server.cors_allowed_origins"),
+		},
+		Database: DatabaseConfig{
+			URL:             v.GetString("database.url"),
+			Host:            v.GetString("database.host"),
+			Port:            v.GetInt("database.port"),
+			User:            v.GetString("database.user"),
+			Password:        v.GetString("database.password"),
+			Name:            v.GetString("database.name"),
+			SSLMode:         v.GetString("database.sslmode"),
+			MaxOpenConns:    v.GetInt("database.max_open_conns"),
+			MaxIdleConns:    v.GetInt("database.max_idle_conns"),
+			ConnMaxLifetime: duration("database.conn_max_lifetime"),
+			ConnMaxIdleTime: duration("database.conn_max_idle_time"),
+
+			ConnectAttempts:   v.GetInt("database.connect_attempts"),
+			ConnectBackoff:    duration("database.connect_backoff"),
+			ConnectMaxBackoff: duration("database.connect_max_backoff"),
+			PingTimeout:       duration("database.ping_timeout"),
 		},
 		App: AppConfig{
-			Name:        viper.GetString("APP_NAME"),
-			Version:     viper.GetString("APP_VERSION"),
-			Environment: viper.GetString("APP_ENVIRONMENT"),
+			Name:        v.GetString("app.name"),
+			Version:     v.GetString("app.version"),
+			Environment: v.GetString("app.environment"),
+			Timezone:    v.GetString("app.timezone"),
+		},
+		Log: LogConfig{
+			Level:  strings.ToLower(v.GetString("log.level")),
+			Format: strings.ToLower(v.GetString("log.format")),
+		},
+		Tracing: TracingConfig{
+			Exporter:    strings.ToLower(v.GetString("tracing.exporter")),
+			Endpoint:    v.GetString("tracing.endpoint"),
+			SampleRatio: v.GetFloat64("tracing.sample_ratio"),
+		},
+		Store: StoreConfig{
+			Name:    v.GetString("store.name"),
+			Address: v.GetString("store.address"),
+			Phone:   v.GetString("store.phone"),
+		},
+		Auth: AuthConfig{
+			JWTSecret: v.GetString("auth.jwt_secret"),
+		},
+		Transactions: TransactionConfig{
+			IdempotencyTTL:           duration("transactions.idempotency_ttl"),
+			BundleRevenueAttribution: v.GetString("transactions.bundle_revenue_attribution"),
+		},
+		Loyalty: LoyaltyConfig{
+			RupiahPerPoint: v.GetInt("loyalty.rupiah_per_point"),
+			PointValue:     v.GetInt("loyalty.point_value"),
+			PointsTTL:      duration("loyalty.points_ttl"),
+		},
+		Features: FeatureConfig{
+			Swagger:     v.GetBool("features.swagger"),
+			OfflineSync: v.GetBool("features.offline_sync"),
+			Idempotency: v.GetBool("features.idempotency"),
+			Loyalty:     v.GetBool("features.loyalty"),
+			Metrics:     v.GetBool("features.metrics"),
 		},
 	}
 
-	AppConfiguration = config
+	location, err := time.LoadLocation(config.App.Timezone)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("app.timezone: unknown timezone %q", config.App.Timezone))
+	}
+	config.App.Location = location
+
+	errs = append(errs, config.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
 	return config, nil
 }
 
+// validate checks the configuration for missing or out of range values
+func (c *Config) validate() []error {
+	var errs []error
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port: must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"database.conn_max_lifetime", c.Database.ConnMaxLifetime},
+		{"database.conn_max_idle_time", c.Database.ConnMaxIdleTime},
+		{"database.connect_backoff", c.Database.ConnectBackoff},
+		{"database.connect_max_backoff", c.Database.ConnectMaxBackoff},
+		{"database.ping_timeout", c.Database.PingTimeout},
+		{"loyalty.points_ttl", c.Loyalty.PointsTTL},
+	} {
+		if d.value < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", d.key))
+		}
+	}
+	if c.Transactions.IdempotencyTTL <= 0 {
+		errs = append(errs, errors.New("transactions.idempotency_ttl: must be greater than 0"))
+	}
+	if c.Loyalty.RupiahPerPoint <= 0 {
+		errs = append(errs, fmt.Errorf("loyalty.rupiah_per_point: must be greater than 0, got %d", c.Loyalty.RupiahPerPoint))
+	}
+	if c.Loyalty.PointValue <= 0 {
+		errs = append(errs, fmt.Errorf("loyalty.point_value: must be greater than 0, got %d", c.Loyalty.PointValue))
+	}
+
+	if c.Database.URL == "" {
+		if c.Database.Host == "" || c.Database.User == "" || c.Database.Name == "" {
+			errs = append(errs, errors.New("database: set DATABASE_URL or DB_HOST, DB_USER and DB_NAME"))
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			errs = append(errs, fmt.Errorf("database.port: must be between 1 and 65535, got %d", c.Database.Port))
+		}
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database: pool sizes must not be negative"))
+	}
+	if c.Database.ConnectAttempts < 1 {
+		errs = append(errs, fmt.Errorf("database.connect_attempts: must be at least 1, got %d", c.Database.ConnectAttempts))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("database.max_idle_conns: must not exceed max_open_conns (%d)", c.Database.MaxOpenConns))
+	}
+
+	if !contains(environments, c.App.Environment) {
+		errs = append(errs, fmt.Errorf("app.environment: must be one of %s, got %q", strings.Join(environments, ", "), c.App.Environment))
+	}
+	if !contains(logLevels, c.Log.Level) {
+		errs = append(errs, fmt.Errorf("log.level: must be one of %s, got %q", strings.Join(logLevels, ", "), c.Log.Level))
+	}
+	if !contains(logFormats, c.Log.Format) {
+		errs = append(errs, fmt.Errorf("log.format: must be one of %s, got %q", strings.Join(logFormats, ", "), c.Log.Format))
+	}
+	if !contains(tracingExporters, c.Tracing.Exporter) {
+		errs = append(errs, fmt.Errorf("tracing.exporter: must be one of %s, got %q", strings.Join(tracingExporters, ", "), c.Tracing.Exporter))
+	}
+	if c.Tracing.Exporter == "otlp" && c.Tracing.Endpoint == "" {
+		errs = append(errs, errors.New("tracing.endpoint: required for the otlp exporter"))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio: must be between 0 and 1, got %v", c.Tracing.SampleRatio))
+	}
+	if c.App.Environment == "production" && c.Auth.JWTSecret == "" {
+		errs = append(errs, errors.New("auth.jwt_secret: required in production"))
+	}
+	if !contains(revenueAttributions, c.Transactions.BundleRevenueAttribution) {
+		errs = append(errs, fmt.Errorf("transactions.bundle_revenue_attribution: must be one of %s, got %q", strings.Join(revenueAttributions, ", "), c.Transactions.BundleRevenueAttribution))
+	}
+
+	return errs
+}
+
 // GetServerAddress returns the server address in host:port format
 func (c *Config) GetServerAddress() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
+
+// contains reports whether value is one of values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv blanks every environment variable the configuration reads, so the
+// tests do not depend on the environment they run in
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, s := range settings {
+		for _, env := range s.env {
+			t.Setenv(env, "")
+		}
+	}
+}
+
+// writeFile writes a file into dir
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DATABASE_URL", "postgres://localhost/kasir")
+
+	cfg, err := LoadConfig(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Server.Port != 8080 {
+		t.Errorf("Server.Port = %d, want 8080", cfg.Server.Port)
+	}
+	if cfg.Server.WriteTimeout != 30*time.Second {
+		t.Errorf("Server.WriteTimeout = %v, want 30s", cfg.Server.WriteTimeout)
+	}
+	if cfg.Database.SSLMode != "require" {
+		t.Errorf("Database.SSLMode = %q, want require", cfg.Database.SSLMode)
+	}
+	if cfg.App.Location == nil || cfg.App.Location.String() != "Asia/Jakarta" {
+		t.Errorf("App.Location = %v, want Asia/Jakarta", cfg.App.Location)
+	}
+	if cfg.Transactions.IdempotencyTTL != 24*time.Hour {
+		t.Errorf("Transactions.IdempotencyTTL = %v, want 24h", cfg.Transactions.IdempotencyTTL)
+	}
+	if cfg.Log.Level != "info" || cfg.Log.Format != "json" {
+		t.Errorf("Log = %+v, want info/json", cfg.Log)
+	}
+	if cfg.Tracing.Exporter != "none" || cfg.Tracing.SampleRatio != 1 {
+		t.Errorf("Tracing = %+v, want none with every trace sampled", cfg.Tracing)
+	}
+	if cfg.Loyalty.RupiahPerPoint != 1000 || cfg.Loyalty.PointValue != 10 || cfg.Loyalty.PointsTTL != 365*24*time.Hour {
+		t.Errorf("Loyalty = %+v, want a point per Rp 1.000 worth Rp 10 for a year", cfg.Loyalty)
+	}
+	if !cfg.Features.Swagger || !cfg.Features.OfflineSync || !cfg.Features.Idempotency || !cfg.Features.Loyalty || !cfg.Features.Metrics {
+		t.Errorf("Features = %+v, want all enabled", cfg.Features)
+	}
+	if AppConfiguration != cfg {
+		t.Errorf("AppConfiguration was not set to the loaded config")
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		dotenv   string
+		env      map[string]string
+		wantPort int
+		wantName string
+	}{
+		{
+			name:     "default",
+			wantPort: 8080,
+			wantName: "Kasir",
+		},
+		{
+			name:     "yaml overrides default",
+			yaml:     "server:\n  port: 9000\nstore:\n  name: Toko Yaml\n",
+			wantPort: 9000,
+			wantName: "Toko Yaml",
+		},
+		{
+			name:     ".env overrides yaml",
+			yaml:     "server:\n  port: 9000\nstore:\n  name: Toko Yaml\n",
+			dotenv:   "SERVER_PORT=9100\n",
+			wantPort: 9100,
+			wantName: "Toko Yaml",
+		},
+		{
+			name:     "environment overrides .env",
+			yaml:     "server:\n  port: 9000\n",
+			dotenv:   "SERVER_PORT=9100\nSTORE_NAME=Toko Env File\n",
+			env:      map[string]string{"SERVER_PORT": "9200"},
+			wantPort: 9200,
+			wantName: "Toko Env File",
+		},
+		{
+			name:     "PORT is used when SERVER_PORT is not set",
+			env:      map[string]string{"PORT": "9300"},
+			wantPort: 9300,
+			wantName: "Kasir",
+		},
+		{
+			name:     "SERVER_PORT wins over PORT",
+			env:      map[string]string{"SERVER_PORT": "9400", "PORT": "9300"},
+			wantPort: 9400,
+			wantName: "Kasir",
+		},
+		{
+			name:     "PORT in .env is overridden by environment",
+			dotenv:   "PORT=9500\n",
+			env:      map[string]string{"PORT": "9600"},
+			wantPort: 9600,
+			wantName: "Kasir",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("DATABASE_URL", "postgres://localhost/kasir")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			dir := t.TempDir()
+			if tt.yaml != "" {
+				writeFile(t, dir, "config.yaml", tt.yaml)
+			}
+			if tt.dotenv != "" {
+				writeFile(t, dir, ".env", tt.dotenv)
+			}
+
+			cfg, err := LoadConfig(dir)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.Server.Port != tt.wantPort {
+				t.Errorf("Server.Port = %d, want %d", cfg.Server.Port, tt.wantPort)
+			}
+			if cfg.Store.Name != tt.wantName {
+				t.Errorf("Store.Name = %q, want %q", cfg.Store.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestLoadConfigDatabaseSettings(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "DB_HOST=db.example.com\nDB_PORT=6543\nDB_USER=kasir\nDB_NAME=postgres\nDB_MAX_OPEN_CONNS=20\n")
+
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Database.Host != "db.example.com" || cfg.Database.Port != 6543 || cfg.Database.User != "kasir" {
+		t.Errorf("Database = %+v, want values from .env", cfg.Database)
+	}
+	if cfg.Database.MaxOpenConns != 20 || cfg.Database.MaxIdleConns != 5 {
+		t.Errorf("Database pool = %d/%d, want 20/5", cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns)
+	}
+	if cfg.Database.ConnectAttempts != 10 || cfg.Database.ConnectBackoff != time.Second {
+		t.Errorf("Database retry = %d/%v, want 10/1s", cfg.Database.ConnectAttempts, cfg.Database.ConnectBackoff)
+	}
+}
+
+func TestLoadConfigCORSAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  string
+		want []string
+	}{
+		{name: "disabled by default"},
+		{name: "comma separated", env: "https://pos.example.com, https://admin.example.com", want: []string{"https://pos.example.com", "https://admin.example.com"}},
+		{name: "yaml list", yaml: "server:\n  cors_allowed_origins:\n    - https://pos.example.com\n    - \"*\"\n", want: []string{"https://pos.example.com", "*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("DATABASE_URL", "postgres://localhost/kasir")
+			t.Setenv("CORS_ALLOWED_ORIGINS", tt.env)
+			dir := t.TempDir()
+			if tt.yaml != "" {
+				writeFile(t, dir, "config.yaml", tt.yaml)
+			}
+
+			cfg, err := LoadConfig(dir)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if strings.Join(cfg.Server.CORSAllowedOrigins, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("CORSAllowedOrigins = %q, want %q", cfg.Server.CORSAllowedOrigins, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing database",
+			env:     map[string]string{"DATABASE_URL": ""},
+			wantErr: "set DATABASE_URL or DB_HOST",
+		},
+		{
+			name:    "port out of range",
+			env:     map[string]string{"SERVER_PORT": "70000"},
+			wantErr: "server.port",
+		},
+		{
+			name:    "invalid duration",
+			env:     map[string]string{"SERVER_WRITE_TIMEOUT": "soon"},
+			wantErr: "server.write_timeout: invalid duration",
+		},
+		{
+			name:    "unknown timezone",
+			env:     map[string]string{"APP_TIMEZONE": "Mars/Olympus"},
+			wantErr: "app.timezone",
+		},
+		{
+			name:    "unknown environment",
+			env:     map[string]string{"APP_ENVIRONMENT": "qa"},
+			wantErr: "app.environment",
+		},
+		{
+			name:    "unknown log level",
+			env:     map[string]string{"LOG_LEVEL": "verbose"},
+			wantErr: "log.level",
+		},
+		{
+			name:    "unknown log format",
+			env:     map[string]string{"LOG_FORMAT": "xml"},
+			wantErr: "log.format",
+		},
+		{
+			name:    "unknown tracing exporter",
+			env:     map[string]string{"TRACING_EXPORTER": "jaeger"},
+			wantErr: "tracing.exporter",
+		},
+		{
+			name:    "otlp without endpoint",
+			env:     map[string]string{"TRACING_EXPORTER": "otlp"},
+			wantErr: "tracing.endpoint",
+		},
+		{
+			name:    "sample ratio out of range",
+			env:     map[string]string{"TRACING_SAMPLE_RATIO": "1.5"},
+			wantErr: "tracing.sample_ratio",
+		},
+		{
+			name:    "production without jwt secret",
+			env:     map[string]string{"APP_ENVIRONMENT": "production"},
+			wantErr: "auth.jwt_secret",
+		},
+		{
+			name:    "more idle than open connections",
+			env:     map[string]string{"DB_MAX_OPEN_CONNS": "2", "DB_MAX_IDLE_CONNS": "4"},
+			wantErr: "database.max_idle_conns",
+		},
+		{
+			name:    "no connect attempts",
+			env:     map[string]string{"DB_CONNECT_ATTEMPTS": "0"},
+			wantErr: "database.connect_attempts",
+		},
+		{
+			name:    "unknown bundle revenue attribution",
+			env:     map[string]string{"BUNDLE_REVENUE_ATTRIBUTION": "split"},
+			wantErr: "transactions.bundle_revenue_attribution",
+		},
+		{
+			name:    "no Rupiah per loyalty point",
+			env:     map[string]string{"LOYALTY_RUPIAH_PER_POINT": "0"},
+			wantErr: "loyalty.rupiah_per_point",
+		},
+		{
+			name:    "negative loyalty points ttl",
+			env:     map[string]string{"LOYALTY_POINTS_TTL": "-1h"},
+			wantErr: "loyalty.points_ttl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("DATABASE_URL", "postgres://localhost/kasir")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := LoadConfig(t.TempDir())
+			if err == nil {
+				t.Fatalf("LoadConfig() error = nil, want error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfig() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigReportsAllErrors(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SERVER_PORT", "0")
+	t.Setenv("APP_TIMEZONE", "Nowhere/City")
+
+	_, err := LoadConfig(t.TempDir())
+	if err == nil {
+		t.Fatal("LoadConfig() error = nil, want error")
+	}
+	for _, want := range []string{"server.port", "app.timezone", "DATABASE_URL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig() error = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestGetServerAddress(t *testing.T) {
+	cfg := &Config{Server: ServerConfig{Host: "localhost", Port: 8080}}
+	if got := cfg.GetServerAddress(); got != "localhost:8080" {
+		t.Errorf("GetServerAddress() = %q, want localhost:8080", got)
+	}
+}
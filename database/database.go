@@ -1,10 +1,15 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 )
 
 // DB holds the database connection
@@ -20,6 +25,22 @@ type DBConfig struct {
 	SSLMode  string
 }
 
+// Options holds connection pool and startup retry settings
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectAttempts is how many times the initial ping is tried before
+	// giving up; the wait between attempts starts at ConnectBackoff and
+	// doubles up to ConnectMaxBackoff
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+	PingTimeout       time.Duration
+}
+
 // BuildConnectionString builds a PostgreSQL connection string from config
 func BuildConnectionString(cfg DBConfig) string {
 	return fmt.Sprintf(
@@ -28,24 +49,87 @@ func BuildConnectionString(cfg DBConfig) string {
 	)
 }
 
-// InitDB initializes the database connection
-func InitDB(connStr string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", connStr)
+// InitDB initializes the database connection pool and waits for the database
+// to accept connections, retrying with backoff (e.g. while Supabase wakes up).
+// Every statement gets a span on the global tracer provider, so tracing must
+// be set up first.
+func InitDB(ctx context.Context, connStr string, opts Options) (*sql.DB, error) {
+	db, err := otelsql.Open("postgres", connStr,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	if err := waitForDB(ctx, db, opts); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	DB = db
-	fmt.Println("Database connected successfully")
+	slog.InfoContext(ctx, "database connected")
 	return db, nil
 }
 
 // InitDBWithConfig initializes the database connection using DBConfig
-func InitDBWithConfig(cfg DBConfig) (*sql.DB, error) {
+func InitDBWithConfig(ctx context.Context, cfg DBConfig, opts Options) (*sql.DB, error) {
 	connStr := BuildConnectionString(cfg)
-	return InitDB(connStr)
+	return InitDB(ctx, connStr, opts)
+}
+
+// waitForDB pings the database until it answers, the attempts run out or ctx
+// is cancelled
+func waitForDB(ctx context.Context, db *sql.DB, opts Options) error {
+	attempts := opts.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := opts.ConnectBackoff
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = Ping(ctx, db, opts.PingTimeout); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		slog.WarnContext(ctx, "database not ready, retrying",
+			"attempt", attempt,
+			"attempts", attempts,
+			"error", err,
+			"retry_in", backoff.String(),
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if opts.ConnectMaxBackoff > 0 && backoff > opts.ConnectMaxBackoff {
+			backoff = opts.ConnectMaxBackoff
+		}
+	}
+	return err
+}
+
+// Ping checks that the database answers within timeout
+func Ping(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return db.PingContext(ctx)
 }
@@ -0,0 +1,38 @@
+-- Migration: Product variants (size, flavor) with their own SKU, price and stock
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create product_variants table
+CREATE TABLE IF NOT EXISTS product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    sku VARCHAR(100) NOT NULL UNIQUE,
+    price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    stock INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW()),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW())
+);
+
+-- Transaction details keep the parent product_id so reports roll variants up
+ALTER TABLE transaction_details
+    ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL;
+
+-- Insert sample drink products with S/M/L variants
+INSERT INTO products (name, price, stock, category_id) VALUES
+    ('Es Teh', 5000, 0, 3)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO product_variants (product_id, name, sku, price, stock)
+SELECT p.id, v.name, v.sku, v.price, v.stock
+FROM products p
+CROSS JOIN (VALUES
+    ('Small', 'ESTEH-S', 5000, 100),
+    ('Medium', 'ESTEH-M', 7000, 100),
+    ('Large', 'ESTEH-L', 9000, 100)
+) AS v(name, sku, price, stock)
+WHERE p.name = 'Es Teh'
+ON CONFLICT DO NOTHING;
+
+-- Create index for better query performance
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_details_variant_id ON transaction_details(variant_id);
@@ -0,0 +1,53 @@
+-- Migration: Units of measure and fractional quantities for weighed goods
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create units table; precision is the number of decimals allowed for quantities
+-- and factor converts the unit into its base_unit (e.g. 1 gram = 0.001 kg)
+CREATE TABLE IF NOT EXISTS units (
+    code VARCHAR(20) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    precision SMALLINT NOT NULL DEFAULT 0 CHECK (precision BETWEEN 0 AND 3),
+    base_unit VARCHAR(20) REFERENCES units(code),
+    factor DECIMAL(14, 6) NOT NULL DEFAULT 1 CHECK (factor > 0)
+);
+
+-- Insert default units
+INSERT INTO units (code, name, precision, base_unit, factor) VALUES
+    ('pcs', 'Pieces', 0, NULL, 1),
+    ('kg', 'Kilogram', 3, NULL, 1),
+    ('liter', 'Liter', 3, NULL, 1)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO units (code, name, precision, base_unit, factor) VALUES
+    ('gram', 'Gram', 0, 'kg', 0.001)
+ON CONFLICT DO NOTHING;
+
+-- Products are priced and stocked per unit; stock may be fractional
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS unit VARCHAR(20) NOT NULL DEFAULT 'pcs' REFERENCES units(code);
+ALTER TABLE products ALTER COLUMN stock TYPE DECIMAL(12, 3);
+ALTER TABLE product_variants ALTER COLUMN stock TYPE DECIMAL(12, 3);
+
+-- Fruit in the sample catalog is sold per kg
+UPDATE products SET unit = 'kg' WHERE name IN ('Apple', 'Banana', 'Orange');
+
+-- Product specific packaging units (e.g. 1 box = 24 pcs)
+CREATE TABLE IF NOT EXISTS product_unit_conversions (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    unit VARCHAR(20) NOT NULL,
+    factor DECIMAL(14, 6) NOT NULL CHECK (factor > 0),
+    UNIQUE (product_id, unit)
+);
+
+-- Transaction details store the quantity in the product unit (used for stock
+-- and reports) next to the quantity and unit the item was sold in
+ALTER TABLE transaction_details ALTER COLUMN quantity TYPE DECIMAL(12, 3);
+ALTER TABLE transaction_details
+    ADD COLUMN IF NOT EXISTS unit VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS unit_quantity DECIMAL(12, 3);
+
+UPDATE transaction_details SET unit_quantity = quantity WHERE unit_quantity IS NULL;
+UPDATE transaction_details td SET unit = p.unit
+FROM products p
+WHERE td.product_id = p.id AND td.unit IS NULL;
@@ -0,0 +1,40 @@
+-- Migration: Bundle/combo products that decompose into component stock
+-- Run this SQL in your Supabase SQL Editor
+
+-- Bundles have no stock of their own; availability is computed from components
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS is_bundle BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create product_bundle_items table; quantity is in the component product unit
+CREATE TABLE IF NOT EXISTS product_bundle_items (
+    id SERIAL PRIMARY KEY,
+    bundle_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    component_product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity DECIMAL(12, 3) NOT NULL CHECK (quantity > 0),
+    UNIQUE (bundle_id, component_product_id)
+);
+
+-- Component stock taken by a sold bundle and the share of revenue allocated to it
+CREATE TABLE IF NOT EXISTS transaction_detail_components (
+    id SERIAL PRIMARY KEY,
+    transaction_detail_id INTEGER NOT NULL REFERENCES transaction_details(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity DECIMAL(12, 3) NOT NULL,
+    subtotal INTEGER NOT NULL
+);
+
+-- Insert sample fruit bundle
+INSERT INTO products (name, price, stock, unit, category_id, is_bundle) VALUES
+    ('Paket Buah', 150000, 0, 'pcs', 1, TRUE)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO product_bundle_items (bundle_id, component_product_id, quantity)
+SELECT b.id, c.id, 1
+FROM products b
+JOIN products c ON c.name IN ('Apple', 'Banana', 'Orange')
+WHERE b.name = 'Paket Buah'
+ON CONFLICT DO NOTHING;
+
+-- Create index for better query performance
+CREATE INDEX IF NOT EXISTS idx_product_bundle_items_bundle_id ON product_bundle_items(bundle_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_detail_components_detail_id ON transaction_detail_components(transaction_detail_id);
@@ -0,0 +1,16 @@
+-- Migration: Idempotency keys for transaction creation
+-- Run this SQL in your Supabase SQL Editor
+
+-- A key is reserved (status_code NULL) while its request is processed, then
+-- stores the response so retries with the same key replay it until expires_at
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status_code INTEGER,
+    response_body TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW()),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create index for purging expired keys
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
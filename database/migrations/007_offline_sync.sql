@@ -0,0 +1,22 @@
+-- Migration: Offline POS sync of batched transactions
+-- Run this SQL in your Supabase SQL Editor
+
+-- Transactions created offline carry a client generated UUID, which makes
+-- re-sending a batch idempotent
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS client_id UUID UNIQUE,
+    ADD COLUMN IF NOT EXISTS synced_at TIMESTAMP WITH TIME ZONE;
+
+-- Stock that went negative while applying offline sales, kept for review
+CREATE TABLE IF NOT EXISTS stock_conflicts (
+    id SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL,
+    quantity DECIMAL(12, 3) NOT NULL,
+    stock_after DECIMAL(12, 3) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW())
+);
+
+-- Create index for better query performance
+CREATE INDEX IF NOT EXISTS idx_stock_conflicts_transaction_id ON stock_conflicts(transaction_id);
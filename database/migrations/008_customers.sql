@@ -0,0 +1,21 @@
+-- Migration: Customer/member accounts attached to transactions
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create customers table; phone and member number identify a customer at the till
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    phone VARCHAR(30) UNIQUE,
+    email VARCHAR(255),
+    member_number VARCHAR(50) UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW()),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW())
+);
+
+-- Sales stay anonymous unless a customer is given; deleting a customer keeps
+-- their sales
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS customer_id INTEGER REFERENCES customers(id) ON DELETE SET NULL;
+
+-- Create index for better query performance
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_id ON transactions(customer_id);
@@ -0,0 +1,35 @@
+-- Migration: Loyalty points earned on purchases and redeemed at checkout
+-- Run this SQL in your Supabase SQL Editor
+
+-- Purchases in a category earn its multiplier times the regular points
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS points_multiplier DECIMAL(6, 2) NOT NULL DEFAULT 1 CHECK (points_multiplier >= 0);
+
+-- The balance is kept with the customer so checkouts can lock and check it
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS points_balance INTEGER NOT NULL DEFAULT 0;
+
+-- Points earned and redeemed by a sale; points_amount is the Rupiah paid with points
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS points_earned INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS points_redeemed INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS points_amount INTEGER NOT NULL DEFAULT 0;
+
+-- Every change of a balance. Points added (earn, void_redeem) are lots that
+-- are used up oldest expiry first; remaining is what is left of a lot.
+-- transaction_id is not a foreign key: the ledger outlives voided sales.
+CREATE TABLE IF NOT EXISTS loyalty_points_ledger (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    transaction_id INTEGER,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('earn', 'redeem', 'expire', 'void_earn', 'void_redeem')),
+    points INTEGER NOT NULL,
+    remaining INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW())
+);
+
+-- Create indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_loyalty_points_ledger_customer_id ON loyalty_points_ledger(customer_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_points_ledger_transaction_id ON loyalty_points_ledger(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_points_ledger_open_lots ON loyalty_points_ledger(expires_at) WHERE remaining > 0;
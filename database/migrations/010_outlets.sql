@@ -0,0 +1,48 @@
+-- Migration: Outlets with their own stock and prices on a shared catalog
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create outlets table; the main outlet (ID 1) holds the stock that existed
+-- before outlets and takes the sales that name no outlet
+CREATE TABLE IF NOT EXISTS outlets (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW()),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW())
+);
+
+INSERT INTO outlets (id, code, name) VALUES (1, 'PUSAT', 'Outlet Pusat')
+ON CONFLICT DO NOTHING;
+SELECT setval(pg_get_serial_sequence('outlets', 'id'), GREATEST((SELECT MAX(id) FROM outlets), 1));
+
+-- Stock of a product, or of one of its variants, at an outlet and the price
+-- that overrides the catalog price there. products.stock and
+-- product_variants.stock stay the total over all outlets.
+CREATE TABLE IF NOT EXISTS outlet_products (
+    id SERIAL PRIMARY KEY,
+    outlet_id INTEGER NOT NULL REFERENCES outlets(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    stock DECIMAL(12, 3) NOT NULL DEFAULT 0,
+    price DECIMAL(10, 2) CHECK (price >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outlet_products_stock_row
+    ON outlet_products(outlet_id, product_id, (COALESCE(variant_id, 0)));
+
+-- Existing stock is held by the main outlet
+INSERT INTO outlet_products (outlet_id, product_id, stock)
+SELECT 1, id, stock FROM products
+ON CONFLICT DO NOTHING;
+
+INSERT INTO outlet_products (outlet_id, product_id, variant_id, stock)
+SELECT 1, product_id, id, stock FROM product_variants
+ON CONFLICT DO NOTHING;
+
+-- Every sale takes place at an outlet; outlets with sales cannot be deleted
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS outlet_id INTEGER NOT NULL DEFAULT 1 REFERENCES outlets(id);
+
+-- Create index for better query performance
+CREATE INDEX IF NOT EXISTS idx_transactions_outlet_id ON transactions(outlet_id);
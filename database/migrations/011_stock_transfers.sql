@@ -0,0 +1,35 @@
+-- Migration: Stock transfers between outlets
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create stock_transfers table; a draft does not change stock, sending it
+-- takes the lines off the source outlet and receiving adds what arrived to
+-- the destination
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    id SERIAL PRIMARY KEY,
+    from_outlet_id INTEGER NOT NULL REFERENCES outlets(id),
+    to_outlet_id INTEGER NOT NULL REFERENCES outlets(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'in_transit', 'received')),
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW()),
+    sent_at TIMESTAMP WITH TIME ZONE,
+    received_at TIMESTAMP WITH TIME ZONE,
+    CHECK (from_outlet_id <> to_outlet_id)
+);
+
+-- Create stock_transfer_lines table; discrepancy is what never arrived once
+-- the transfer was received
+CREATE TABLE IF NOT EXISTS stock_transfer_lines (
+    id SERIAL PRIMARY KEY,
+    transfer_id INTEGER NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    variant_id INTEGER REFERENCES product_variants(id),
+    quantity DECIMAL(12, 3) NOT NULL CHECK (quantity > 0),
+    received_quantity DECIMAL(12, 3) NOT NULL DEFAULT 0 CHECK (received_quantity >= 0 AND received_quantity <= quantity),
+    discrepancy DECIMAL(12, 3) NOT NULL DEFAULT 0,
+    discrepancy_note TEXT
+);
+
+-- Create indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_from_outlet_id ON stock_transfers(from_outlet_id);
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_to_outlet_id ON stock_transfers(to_outlet_id);
+CREATE INDEX IF NOT EXISTS idx_stock_transfer_lines_transfer_id ON stock_transfer_lines(transfer_id);
@@ -0,0 +1,46 @@
+-- Migration: Open bills
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create open_bills table; an open bill is an order parked under a table
+-- number or customer name until it is checked out into a transaction or
+-- cancelled
+CREATE TABLE IF NOT EXISTS open_bills (
+    id SERIAL PRIMARY KEY,
+    outlet_id INTEGER NOT NULL REFERENCES outlets(id),
+    label VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'checked_out', 'cancelled')),
+    reserve_stock BOOLEAN NOT NULL DEFAULT FALSE,
+    transaction_id INTEGER REFERENCES transactions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW()),
+    closed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create open_bill_items table; items leave with their product or variant
+CREATE TABLE IF NOT EXISTS open_bill_items (
+    id SERIAL PRIMARY KEY,
+    bill_id INTEGER NOT NULL REFERENCES open_bills(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity DECIMAL(12, 3) NOT NULL CHECK (quantity > 0),
+    unit VARCHAR(20) NOT NULL,
+    note TEXT
+);
+
+-- Create open_bill_reservations table; the stock an item of a bill that
+-- reserves stock took off its outlet, given back when the item is removed or
+-- the bill is closed
+CREATE TABLE IF NOT EXISTS open_bill_reservations (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES open_bill_items(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity DECIMAL(12, 3) NOT NULL CHECK (quantity > 0)
+);
+
+-- A table or name has one open bill per outlet at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_open_bills_open_label ON open_bills(outlet_id, LOWER(label)) WHERE status = 'open';
+
+-- Create indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_open_bills_outlet_id ON open_bills(outlet_id);
+CREATE INDEX IF NOT EXISTS idx_open_bill_items_bill_id ON open_bill_items(bill_id);
+CREATE INDEX IF NOT EXISTS idx_open_bill_reservations_item_id ON open_bill_reservations(item_id);
@@ -0,0 +1,42 @@
+-- Migration: Price lists and price rules
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create price_lists table; a price list such as wholesale or member prices
+-- replaces the retail prices for the customers assigned to it
+CREATE TABLE IF NOT EXISTS price_lists (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW())
+);
+
+-- Create price_rules table; a rule prices a product or variant instead of the
+-- catalog price for a price list (or everyone when price_list_id is NULL),
+-- from a minimum quantity, between starts_at and ends_at and within a daily
+-- time window such as a happy hour
+CREATE TABLE IF NOT EXISTS price_rules (
+    id SERIAL PRIMARY KEY,
+    price_list_id INTEGER REFERENCES price_lists(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    min_quantity DECIMAL(12, 3) NOT NULL DEFAULT 0 CHECK (min_quantity >= 0),
+    price DECIMAL(10, 2) NOT NULL CHECK (price >= 0),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    daily_from TIME,
+    daily_to TIME,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW()),
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at),
+    CHECK ((daily_from IS NULL) = (daily_to IS NULL))
+);
+
+-- Customers may buy from a price list
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS price_list_id INTEGER REFERENCES price_lists(id) ON DELETE SET NULL;
+
+-- Sales record the price list and the rule each line was priced with
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS price_list_id INTEGER REFERENCES price_lists(id) ON DELETE SET NULL;
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS price_rule_id INTEGER REFERENCES price_rules(id) ON DELETE SET NULL;
+
+-- Create indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_price_rules_product_id ON price_rules(product_id);
+CREATE INDEX IF NOT EXISTS idx_price_rules_price_list_id ON price_rules(price_list_id);
@@ -0,0 +1,26 @@
+-- Migration: Product change history
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create product_versions table; every create and every update that changes
+-- a product records its new state as the next version, with who made the
+-- change and when. category_id keeps no foreign key so the history outlives
+-- deleted categories.
+CREATE TABLE IF NOT EXISTS product_versions (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL CHECK (version > 0),
+    name VARCHAR(255) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    stock DECIMAL(12, 3) NOT NULL,
+    unit VARCHAR(20) NOT NULL,
+    category_id INTEGER,
+    changed_by VARCHAR(255),
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW()),
+    UNIQUE (product_id, version)
+);
+
+-- Existing products start their history at their current state
+INSERT INTO product_versions (product_id, version, name, price, stock, unit, category_id)
+SELECT id, 1, name, price, stock, unit, category_id
+FROM products
+WHERE NOT EXISTS (SELECT 1 FROM product_versions v WHERE v.product_id = products.id);
@@ -0,0 +1,32 @@
+-- Migration: Audit log
+-- Run this SQL in your Supabase SQL Editor
+
+-- Create audit_log table; every create, update and delete made through the
+-- API records who made it, from where and what it changed
+CREATE TABLE IF NOT EXISTS audit_log (
+    id SERIAL PRIMARY KEY,
+    actor VARCHAR(255),
+    action VARCHAR(20) NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    before JSONB,
+    after JSONB,
+    ip VARCHAR(45),
+    request_id VARCHAR(128),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT TIMEZONE('utc', NOW())
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+
+-- The audit log is append-only: entries cannot be changed or removed
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
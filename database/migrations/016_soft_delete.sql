@@ -0,0 +1,16 @@
+-- Migration: Soft delete of products and categories
+-- Run this SQL in your Supabase SQL Editor
+
+-- Deleting a product or category sets deleted_at and keeps the row, so past
+-- sales and reports keep their names; it can be restored until it is purged
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Listings only show the items that are not deleted
+CREATE INDEX IF NOT EXISTS idx_products_not_deleted ON products(id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_categories_not_deleted ON categories(id) WHERE deleted_at IS NULL;
+
+-- The audit log records restores and purges as actions of their own
+ALTER TABLE audit_log DROP CONSTRAINT IF EXISTS audit_log_action_check;
+ALTER TABLE audit_log ADD CONSTRAINT audit_log_action_check
+    CHECK (action IN ('create', 'update', 'delete', 'restore', 'purge'));
@@ -0,0 +1,9 @@
+-- Migration: Idempotency keys per user
+-- Run this SQL in your Supabase SQL Editor
+
+-- A key belongs to the user who sent it, so clients choosing the same key
+-- neither collide nor replay each other's responses. Keys stored before are
+-- kept without an owner until they expire.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS owner VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (owner, key);
@@ -15,9 +15,102 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/audit-log": {
+            "get": {
+                "description": "Get a page of the audit log of the creates, updates and deletes of products, categories and transactions, newest first. Only owners may read the audit log.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "List the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by the user who made the change",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "create",
+                            "update",
+                            "delete",
+                            "restore",
+                            "purge"
+                        ],
+                        "type": "string",
+                        "description": "Filter by action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by entity type, e.g. product, product_variant, category or transaction",
+                        "name": "entity_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by entity ID",
+                        "name": "entity_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made at or after this time (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made before this time (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of entries, at most 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuditEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Owner role required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/categories": {
             "get": {
-                "description": "Get all categories",
+                "description": "Get all categories; deleted categories are left out unless include_deleted is true",
                 "produces": [
                     "application/json"
                 ],
@@ -25,6 +118,14 @@ const docTemplate = `{
                     "categories"
                 ],
                 "summary": "List all categories",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "List deleted categories too",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -168,7 +269,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete category by ID",
+                "description": "Soft delete category by ID: it is no longer listed and can be restored. A category with products is only deleted when reassign_to names the category they move to.",
                 "produces": [
                     "application/json"
                 ],
@@ -183,6 +284,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Category to move the products to",
+                        "name": "reassign_to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -193,7 +300,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid category ID",
+                        "description": "Invalid category ID or reassign_to",
                         "schema": {
                             "type": "string"
                         }
@@ -203,66 +310,125 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "409": {
+                        "description": "Category has products",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/health": {
-            "get": {
-                "description": "Check API health status",
+        "/categories/{id}/purge": {
+            "delete": {
+                "description": "Permanently remove a deleted category; its products lose their category, recorded in their history. Categories whose products were sold cannot be purged.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "categories"
+                ],
+                "summary": "Purge a category",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Category ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Category purged successfully",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid category ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Category is not deleted or referenced by sales",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/products": {
-            "get": {
-                "description": "Get all products with optional filters",
+        "/categories/{id}/restore": {
+            "post": {
+                "description": "Undo the deletion of a category",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "categories"
                 ],
-                "summary": "List all products",
+                "summary": "Restore a category",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Filter by product name",
-                        "name": "name",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
-                        "description": "Filter by category ID",
-                        "name": "category_id",
-                        "in": "query"
+                        "description": "Category ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Category"
+                        }
                     },
-                    {
-                        "type": "number",
-                        "description": "Filter by minimum price",
-                        "name": "min_price",
-                        "in": "query"
+                    "400": {
+                        "description": "Invalid category ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
+                    "409": {
+                        "description": "Category is not deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers": {
+            "get": {
+                "description": "Get all customers ordered by name, optionally searched by name, phone, email or member number",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "List all customers",
+                "parameters": [
                     {
-                        "type": "number",
-                        "description": "Filter by maximum price",
-                        "name": "max_price",
+                        "type": "string",
+                        "description": "Part of the name, phone, email or member number",
+                        "name": "search",
                         "in": "query"
                     }
                 ],
@@ -272,14 +438,14 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Product"
+                                "$ref": "#/definitions/models.Customer"
                             }
                         }
                     }
                 }
             },
             "post": {
-                "description": "Create a new product",
+                "description": "Create a new customer; phone and member number must be unique",
                 "consumes": [
                     "application/json"
                 ],
@@ -287,17 +453,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "Create a new product",
+                "summary": "Create a new customer",
                 "parameters": [
                     {
-                        "description": "Product object",
-                        "name": "product",
+                        "description": "Customer object",
+                        "name": "customer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.Customer"
                         }
                     }
                 ],
@@ -305,7 +471,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.Customer"
                         }
                     },
                     "400": {
@@ -317,20 +483,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/products/{id}": {
+        "/customers/{id}": {
             "get": {
-                "description": "Get product details by ID",
+                "description": "Get customer details by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "Get product by ID",
+                "summary": "Get customer by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -340,17 +506,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.Customer"
                         }
                     },
                     "400": {
-                        "description": "Invalid product ID",
+                        "description": "Invalid customer ID",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Product not found",
+                        "description": "Customer not found",
                         "schema": {
                             "type": "string"
                         }
@@ -358,7 +524,7 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update product by ID",
+                "description": "Update customer by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -366,24 +532,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "Update a product",
+                "summary": "Update a customer",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Product object",
-                        "name": "product",
+                        "description": "Customer object",
+                        "name": "customer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.Customer"
                         }
                     }
                 ],
@@ -391,7 +557,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.Customer"
                         }
                     },
                     "400": {
@@ -401,7 +567,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Product not found",
+                        "description": "Customer not found",
                         "schema": {
                             "type": "string"
                         }
@@ -409,18 +575,18 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete product by ID",
+                "description": "Delete customer by ID; their transactions are kept without a customer",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "customers"
                 ],
-                "summary": "Delete a product",
+                "summary": "Delete a customer",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -428,19 +594,19 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Product deleted successfully",
+                        "description": "Customer deleted successfully",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid product ID",
+                        "description": "Invalid customer ID",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Product not found",
+                        "description": "Customer not found",
                         "schema": {
                             "type": "string"
                         }
@@ -448,29 +614,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/report": {
+        "/customers/{id}/lifetime-value": {
             "get": {
-                "description": "Get sales summary for a specific date range",
+                "description": "Get the number of transactions, total and average spent and the first and last purchase of a customer",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "report"
+                    "customers"
                 ],
-                "summary": "Get sales report by date range",
+                "summary": "Get customer lifetime value",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Start date (YYYY-MM-DD)",
-                        "name": "start_date",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "End date (YYYY-MM-DD)",
-                        "name": "end_date",
-                        "in": "query",
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -478,11 +637,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SalesReport"
+                            "$ref": "#/definitions/models.CustomerLifetimeValue"
                         }
                     },
                     "400": {
-                        "description": "Missing or invalid date parameters",
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
                         "schema": {
                             "type": "string"
                         }
@@ -490,36 +655,66 @@ const docTemplate = `{
                 }
             }
         },
-        "/report/hari-ini": {
+        "/customers/{id}/points": {
             "get": {
-                "description": "Get sales summary for today including total revenue, transaction count, and best selling product",
+                "description": "Get the points balance of a customer, the Rupiah value of a point and the points ledger, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "report"
+                    "customers"
+                ],
+                "summary": "Get customer loyalty points",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get today's sales report",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SalesReport"
+                            "$ref": "#/definitions/models.PointsStatement"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/transactions": {
+        "/customers/{id}/transactions": {
             "get": {
-                "description": "Get all transactions",
+                "description": "Get the transactions of a customer, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "customers"
+                ],
+                "summary": "Get customer purchase history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List all transactions",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -529,11 +724,153 @@ const docTemplate = `{
                                 "$ref": "#/definitions/models.Transaction"
                             }
                         }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Ping the database and report connection pool statistics. Returns 503 when the database is unreachable.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthStatus"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/live": {
+            "get": {
+                "description": "Report that the API process is running. Does not check the database.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/ready": {
+            "get": {
+                "description": "Ping the database and report connection pool statistics. Returns 503 when the database is unreachable.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthStatus"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/open-bills": {
+            "get": {
+                "description": "Get the open bills of the given outlets, newest first, without their items. Without outlet_id the bills of every outlet the user is assigned to are listed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "open-bills"
+                ],
+                "summary": "List all open bills",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Filter by outlet ID; may be repeated",
+                        "name": "outlet_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "open",
+                            "checked_out",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OpenBill"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
             "post": {
-                "description": "Create a new transaction with items",
+                "description": "Park an order under a table number or customer name at an outlet, the first outlet of the user unless outlet_id is given, with optional first items. A bill with reserve_stock takes the stock of its items off the outlet until it is checked out or cancelled.",
                 "consumes": [
                     "application/json"
                 ],
@@ -541,17 +878,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "open-bills"
                 ],
-                "summary": "Create a new transaction",
+                "summary": "Open a bill",
                 "parameters": [
                     {
-                        "description": "Transaction items",
-                        "name": "transaction",
+                        "description": "Outlet, label, stock reservation and items",
+                        "name": "bill",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateTransactionRequest"
+                            "$ref": "#/definitions/models.OpenBill"
                         }
                     }
                 ],
@@ -559,11 +896,17 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Transaction"
+                            "$ref": "#/definitions/models.OpenBill"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
                         "schema": {
                             "type": "string"
                         }
@@ -571,20 +914,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/transactions/{id}": {
+        "/open-bills/{id}": {
             "get": {
-                "description": "Get transaction details by ID including line items",
+                "description": "Get an open bill with its items by ID; a bill that is still open comes with its total at the current prices, left out when its items cannot be priced any more",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "open-bills"
                 ],
-                "summary": "Get transaction by ID",
+                "summary": "Get open bill by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Transaction ID",
+                        "description": "Open bill ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -594,36 +937,44 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Transaction"
+                            "$ref": "#/definitions/models.OpenBill"
                         }
                     },
                     "400": {
-                        "description": "Invalid transaction ID",
+                        "description": "Invalid open bill ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Transaction not found",
+                        "description": "Open bill not found",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete transaction by ID",
+            }
+        },
+        "/open-bills/{id}/cancel": {
+            "post": {
+                "description": "Close an open bill without a sale, giving back the stock it reserved",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "open-bills"
                 ],
-                "summary": "Delete a transaction",
+                "summary": "Cancel an open bill",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Transaction ID",
+                        "description": "Open bill ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -631,110 +982,3583 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Transaction deleted successfully",
+                        "description": "Open bill cancelled successfully",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid transaction ID",
+                        "description": "Invalid open bill ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Transaction not found",
+                        "description": "Open bill not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Open bill is closed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/open-bills/{id}/checkout": {
+            "post": {
+                "description": "Turn an open bill into a transaction at the outlet of the bill, priced at the current prices, and close the bill. The stock the bill reserved becomes the stock the sale takes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "open-bills"
+                ],
+                "summary": "Check out an open bill",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Open bill ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Customer and loyalty points to redeem",
+                        "name": "checkout",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CheckoutBillRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or insufficient stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Open bill not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Open bill is closed or its items changed during checkout",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/open-bills/{id}/items": {
+            "post": {
+                "description": "Add an item to an open bill and return the bill",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "open-bills"
+                ],
+                "summary": "Add an item to an open bill",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Open bill ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Product, variant, quantity, unit and note",
+                        "name": "item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenBillItem"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenBill"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or insufficient stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Open bill not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Open bill is closed",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.BestSellerInfo": {
+        },
+        "/open-bills/{id}/items/{item_id}": {
+            "put": {
+                "description": "Replace an item of an open bill and return the bill",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "open-bills"
+                ],
+                "summary": "Update an item of an open bill",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Open bill ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Open bill item ID",
+                        "name": "item_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Product, variant, quantity, unit and note",
+                        "name": "item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenBillItem"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenBill"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or insufficient stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Open bill not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Open bill is closed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove an item from an open bill, giving back the stock it reserved, and return the bill",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "open-bills"
+                ],
+                "summary": "Remove an item from an open bill",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Open bill ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Open bill item ID",
+                        "name": "item_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenBill"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Open bill or item not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Open bill is closed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/outlets": {
+            "get": {
+                "description": "Get the outlets the user is assigned to, or all outlets for owners",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outlets"
+                ],
+                "summary": "List all outlets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Outlet"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new outlet; the code must be unique. Only users with access to every outlet may create outlets.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outlets"
+                ],
+                "summary": "Create a new outlet",
+                "parameters": [
+                    {
+                        "description": "Outlet object",
+                        "name": "outlet",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Outlet"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Outlet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/outlets/{id}": {
+            "get": {
+                "description": "Get outlet details by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outlets"
+                ],
+                "summary": "Get outlet by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Outlet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Outlet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid outlet ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Outlet not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update outlet by ID. Only users with access to every outlet may update outlets.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outlets"
+                ],
+                "summary": "Update an outlet",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Outlet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Outlet object",
+                        "name": "outlet",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Outlet"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Outlet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Outlet not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an outlet without sales by ID together with its stock; the main outlet cannot be deleted. Only users with access to every outlet may delete outlets.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outlets"
+                ],
+                "summary": "Delete an outlet",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Outlet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outlet deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid outlet ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Outlet not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/outlets/{id}/products": {
+            "get": {
+                "description": "Get the stock of the products and variants at an outlet with the prices that override the catalog prices there",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outlets"
+                ],
+                "summary": "Get outlet stock",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Outlet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OutletProduct"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid outlet ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Outlet not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/outlets/{id}/products/{product_id}": {
+            "put": {
+                "description": "Set the stock of a product, or of one of its variants, at an outlet and the price that overrides the catalog price there; leave out price to sell at the catalog price. The total stock of the product changes by the difference.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outlets"
+                ],
+                "summary": "Set outlet stock and price",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Outlet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Stock, optional variant and price override",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OutletProduct"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OutletProduct"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/price-lists": {
+            "get": {
+                "description": "Get all price lists, such as wholesale or member prices",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "List all price lists",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PriceList"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new price list; the code must be unique. Only users with access to every outlet may change prices.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "Create a new price list",
+                "parameters": [
+                    {
+                        "description": "Price list object",
+                        "name": "price_list",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceList"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/price-lists/{id}": {
+            "get": {
+                "description": "Get price list details by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "Get price list by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Price list ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid price list ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Price list not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update price list by ID. Only users with access to every outlet may change prices.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "Update a price list",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Price list ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Price list object",
+                        "name": "price_list",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceList"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Price list not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a price list by ID together with its rules; its customers buy at retail prices again. Only users with access to every outlet may change prices.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "Delete a price list",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Price list ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Price list deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid price list ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Price list not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/price-rules": {
+            "get": {
+                "description": "Get the price rules, optionally of one product or one price list",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "List price rules",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Filter by product ID",
+                        "name": "product_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by price list ID",
+                        "name": "price_list_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PriceRule"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a price rule for a product or variant: a price list price (or a retail price without price_list_id), a quantity break from min_quantity, a scheduled price from starts_at or a happy hour between daily_from and daily_to (\"15:00\"). Only users with access to every outlet may change prices.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "Create a new price rule",
+                "parameters": [
+                    {
+                        "description": "Price rule object",
+                        "name": "price_rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/price-rules/{id}": {
+            "get": {
+                "description": "Get price rule details by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "Get price rule by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Price rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid price rule ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Price rule not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update price rule by ID; sales already made keep their prices. Only users with access to every outlet may change prices.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "Update a price rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Price rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Price rule object",
+                        "name": "price_rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PriceRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Price rule not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a price rule by ID; sales already made keep their prices. Only users with access to every outlet may change prices.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "price-lists"
+                ],
+                "summary": "Delete a price rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Price rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Price rule deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid price rule ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Price rule not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products": {
+            "get": {
+                "description": "Get all products with optional filters; deleted products are left out unless include_deleted is true",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List all products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by product name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by category ID",
+                        "name": "category_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Filter by minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Filter by maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "List deleted products too",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Product"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new product",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Create a new product",
+                "parameters": [
+                    {
+                        "description": "Product object",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "get": {
+                "description": "Get product details by ID; a deleted product has deleted_at set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update product by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Product object",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Soft delete product by ID: it is no longer sold or listed, past sales and reports keep it and it can be restored",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Product is a component of a bundle",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/components": {
+            "get": {
+                "description": "Get the products contained in a bundle product",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List bundle components",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BundleComponent"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the components of a bundle product. An empty list turns the bundle back into a regular product.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Set bundle components",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Bundle components",
+                        "name": "components",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BundleComponent"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BundleComponent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/conversions": {
+            "get": {
+                "description": "Get the packaging units of a product (e.g. 1 box = 24 pcs)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List product unit conversions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UnitConversion"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Define how many product units one packaging unit contains",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Create or update a product unit conversion",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Unit conversion object",
+                        "name": "conversion",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UnitConversion"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.UnitConversion"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/conversions/{unit}": {
+            "delete": {
+                "description": "Delete a packaging unit of a product",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete a product unit conversion",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Packaging unit",
+                        "name": "unit",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Unit conversion deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Unit conversion not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/history": {
+            "get": {
+                "description": "Get every version of a product, oldest first, with the changed fields and who changed them. With at, only the version in effect at that time.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Point in time (RFC3339)",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ProductVersion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/purge": {
+            "delete": {
+                "description": "Permanently remove a deleted product together with its version history. Products referenced by sales, bundles, transfers or open bills cannot be purged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Purge a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product purged successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Product is not deleted or still referenced",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/restore": {
+            "post": {
+                "description": "Undo the deletion of a product. A product whose category was deleted meanwhile is restored without a category.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Restore a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Product is not deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/variants": {
+            "get": {
+                "description": "Get all variants (size, flavor, ...) of a product",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List product variants",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ProductVariant"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new variant with its own SKU, price and stock",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Create a product variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variant object",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductVariant"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductVariant"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/variants/{variantId}": {
+            "put": {
+                "description": "Update variant by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update a product variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID",
+                        "name": "variantId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variant object",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductVariant"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductVariant"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Variant not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete variant by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete a product variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID",
+                        "name": "variantId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Variant deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Variant not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/report": {
+            "get": {
+                "description": "Get sales summary for a specific date range with the sales of each outlet. Without outlet_id the report rolls up every outlet the user is assigned to.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "report"
+                ],
+                "summary": "Get sales report by date range",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Outlet ID; may be repeated",
+                        "name": "outlet_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SalesReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or invalid date parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/report/hari-ini": {
+            "get": {
+                "description": "Get sales summary for today including total revenue, transaction count, best selling product and the sales of each outlet. Without outlet_id the report rolls up every outlet the user is assigned to.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "report"
+                ],
+                "summary": "Get today's sales report",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Outlet ID; may be repeated",
+                        "name": "outlet_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SalesReport"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/sync/transactions": {
+            "post": {
+                "description": "Insert a batch of transactions created offline. Each transaction needs a client generated UUID and its original timestamp and takes place at its outlet, by default the first outlet of the user; re-sent transactions are reported as duplicate, and stock that goes negative is flagged as a conflict. A batch holds at most 500 transactions; a temporary failure answers 500 and the batch can be sent again as is. A batch with a transaction at an outlet the user is not assigned to is refused.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Sync offline transactions",
+                "parameters": [
+                    {
+                        "description": "Offline transactions",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncTransactionsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncTransactionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Temporary failure; send the batch again",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions": {
+            "get": {
+                "description": "Get all transactions, newest first, with optional filters. Without outlet_id the transactions of every outlet the user is assigned to are listed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "List all transactions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Filter by customer ID",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Filter by outlet ID; may be repeated",
+                        "name": "outlet_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Transaction"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new transaction with items at an outlet, by default the first outlet of the user. Retries by the same user sending the same Idempotency-Key replay the original response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Create a new transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client generated key that makes retries safe",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Transaction items",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTransactionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency-Key reused with a different payload, still in progress or of a request that failed unexpectedly",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Temporary failure; the request can be retried with the same Idempotency-Key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{id}": {
+            "get": {
+                "description": "Get transaction details by ID including line items",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete transaction by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Delete a transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{id}/receipt": {
+            "get": {
+                "description": "Get the receipt of a transaction with the store identity, item names, quantities in the unit sold and unit prices",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction receipt",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Receipt"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers": {
+            "get": {
+                "description": "Get the stock transfers from or to the given outlets, newest first, without their lines. Without outlet_id the transfers of every outlet the user is assigned to are listed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "List all stock transfers",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Filter by source or destination outlet ID; may be repeated",
+                        "name": "outlet_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "in_transit",
+                            "received"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.StockTransfer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a draft stock transfer from one outlet to another. A draft does not change stock until it is sent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Create a stock transfer",
+                "parameters": [
+                    {
+                        "description": "Source and destination outlet, note and lines",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StockTransfer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.StockTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers/{id}": {
+            "get": {
+                "description": "Get a stock transfer with its lines by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Get stock transfer by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StockTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transfer ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the outlets, note and lines of a draft stock transfer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Update a stock transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Source and destination outlet, note and lines",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StockTransfer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StockTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is no longer a draft",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a draft stock transfer by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Delete a stock transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transfer deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transfer ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is no longer a draft",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers/{id}/receive": {
+            "post": {
+                "description": "Add the quantities that arrived to the stock of the destination outlet. Lines may arrive over several receipts; the transfer is received once every line arrived in full, or when close is set, which records what did not arrive as the discrepancy of its line.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Receive a stock transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Quantities that arrived per line",
+                        "name": "receipt",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TransferReceipt"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StockTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is not in transit",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers/{id}/send": {
+            "post": {
+                "description": "Take the lines of a draft stock transfer off the stock of the source outlet and put it in transit. The source outlet must have the stock.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Send a stock transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StockTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Insufficient stock",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Outlet not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is no longer a draft",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/units": {
+            "get": {
+                "description": "Get all units of measure with their quantity precision and conversion factor",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "units"
+                ],
+                "summary": "List all units of measure",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Unit"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new unit of measure",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "units"
+                ],
+                "summary": "Create a new unit of measure",
+                "parameters": [
+                    {
+                        "description": "Unit object",
+                        "name": "unit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Unit"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Unit"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/units/{code}": {
+            "put": {
+                "description": "Update unit name, precision or conversion factor",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "units"
+                ],
+                "summary": "Update a unit of measure",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unit code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Unit object",
+                        "name": "unit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Unit"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Unit"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Unit not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "entity_id": {
+                    "type": "integer"
+                },
+                "entity_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BestSellerInfo": {
+            "type": "object",
+            "properties": {
+                "nama": {
+                    "type": "string"
+                },
+                "qty_terjual": {
+                    "type": "number"
+                },
+                "satuan": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BundleComponent": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.Category": {
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "description": "DeletedAt is set once the category is deleted; it is no longer listed,\nbut can be restored until it is purged",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "points_multiplier": {
+                    "description": "PointsMultiplier multiplies the loyalty points earned on the products of\nthe category; it defaults to 1 and 0 earns no points",
+                    "type": "number"
+                }
+            }
+        },
+        "models.CheckoutBillRequest": {
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "integer"
+                },
+                "price_list_id": {
+                    "description": "PriceListID prices the sale from a price list instead of the price\nlist of the customer",
+                    "type": "integer"
+                },
+                "redeem_points": {
+                    "description": "RedeemPoints pays part of the total with loyalty points of the customer",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CreateTransactionRequest": {
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TransactionItem"
+                    }
+                },
+                "outlet_id": {
+                    "description": "OutletID is the outlet the sale takes place at; 0 is the first outlet\nof the user",
+                    "type": "integer"
+                },
+                "price_list_id": {
+                    "description": "PriceListID prices the sale from a price list instead of the price\nlist of the customer",
+                    "type": "integer"
+                },
+                "redeem_points": {
+                    "description": "RedeemPoints pays part of the total with loyalty points of the customer",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Customer": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "member_number": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "points_balance": {
+                    "description": "PointsBalance is the loyalty points balance; it only changes through sales",
+                    "type": "integer"
+                },
+                "price_list_id": {
+                    "description": "PriceListID is the price list the customer buys from; nil is retail",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CustomerLifetimeValue": {
+            "type": "object",
+            "properties": {
+                "average_spent": {
+                    "type": "integer"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "first_purchase_at": {
+                    "type": "string"
+                },
+                "last_purchase_at": {
+                    "type": "string"
+                },
+                "total_spent": {
+                    "type": "integer"
+                },
+                "total_transactions": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DatabaseHealth": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "pool": {
+                    "$ref": "#/definitions/models.PoolStats"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.HealthStatus": {
+            "type": "object",
+            "properties": {
+                "database": {
+                    "$ref": "#/definitions/models.DatabaseHealth"
+                },
+                "environment": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "uptime": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OfflineTransaction": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TransactionItem"
+                    }
+                },
+                "outlet_id": {
+                    "description": "OutletID is the outlet the sale took place at; 0 is the first outlet\nof the user",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OpenBill": {
+            "type": "object",
+            "properties": {
+                "closed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OpenBillItem"
+                    }
+                },
+                "label": {
+                    "type": "string"
+                },
+                "outlet_id": {
+                    "type": "integer"
+                },
+                "reserve_stock": {
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total_amount": {
+                    "description": "TotalAmount is what the items of an open bill cost at the current\nprices; it is left out when they cannot be priced any more",
+                    "type": "integer"
+                },
+                "transaction_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OpenBillItem": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "reserved": {
+                    "description": "Reserved is the stock the item holds when its bill reserves stock, in\nthe product unit; a bundle holds the stock of its components",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReservedStock"
+                    }
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Outlet": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OutletProduct": {
+            "type": "object",
+            "properties": {
+                "outlet_id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "stock": {
+                    "type": "number"
+                },
+                "variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OutletSales": {
+            "type": "object",
+            "properties": {
+                "nama": {
+                    "type": "string"
+                },
+                "outlet_id": {
+                    "type": "integer"
+                },
+                "total_revenue": {
+                    "type": "integer"
+                },
+                "total_transaksi": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PointsEntry": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "remaining": {
+                    "type": "integer"
+                },
+                "transaction_id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PointsStatement": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PointsEntry"
+                    }
+                },
+                "point_value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PoolStats": {
+            "type": "object",
+            "properties": {
+                "idle": {
+                    "type": "integer"
+                },
+                "in_use": {
+                    "type": "integer"
+                },
+                "max_idle_closed": {
+                    "type": "integer"
+                },
+                "max_idle_time_closed": {
+                    "type": "integer"
+                },
+                "max_lifetime_closed": {
+                    "type": "integer"
+                },
+                "max_open_connections": {
+                    "type": "integer"
+                },
+                "open_connections": {
+                    "type": "integer"
+                },
+                "wait_count": {
+                    "type": "integer"
+                },
+                "wait_duration_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PriceList": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PriceRule": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_from": {
+                    "type": "string"
+                },
+                "daily_to": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "min_quantity": {
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "price_list_id": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Product": {
+            "type": "object",
+            "properties": {
+                "category_id": {
+                    "description": "CategoryID is nil for a product without a category",
+                    "type": "integer"
+                },
+                "components": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BundleComponent"
+                    }
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set once the product is deleted; it is no longer sold\nor listed, but can be restored until it is purged",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_bundle": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "stock": {
+                    "type": "number"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProductVariant"
+                    }
+                }
+            }
+        },
+        "models.ProductSales": {
+            "type": "object",
+            "properties": {
+                "nama": {
+                    "type": "string"
+                },
+                "pendapatan": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "qty_terjual": {
+                    "type": "number"
+                },
+                "satuan": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProductVariant": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "sku": {
+                    "type": "string"
+                },
+                "stock": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.ProductVersion": {
+            "type": "object",
+            "properties": {
+                "category_id": {
+                    "type": "integer"
+                },
+                "changed_at": {
+                    "type": "string"
+                },
+                "changed_by": {
+                    "type": "string"
+                },
+                "changes": {
+                    "description": "Changes names the fields that differ from the previous version",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "stock": {
+                    "type": "number"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Receipt": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReceiptItem"
+                    }
+                },
+                "outlet_id": {
+                    "type": "integer"
+                },
+                "points_amount": {
+                    "type": "integer"
+                },
+                "points_earned": {
+                    "type": "integer"
+                },
+                "points_redeemed": {
+                    "description": "Loyalty points of the customer: PointsAmount is the part of the total\npaid with the redeemed points",
+                    "type": "integer"
+                },
+                "store": {
+                    "$ref": "#/definitions/models.StoreInfo"
+                },
+                "total_amount": {
+                    "type": "integer"
+                },
+                "transaction_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ReceiptItem": {
             "type": "object",
             "properties": {
-                "nama": {
+                "name": {
                     "type": "string"
                 },
-                "qty_terjual": {
+                "quantity": {
+                    "type": "number"
+                },
+                "subtotal": {
+                    "type": "integer"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "unit_price": {
                     "type": "integer"
                 }
             }
         },
-        "models.Category": {
+        "models.ReceivedLine": {
             "type": "object",
             "properties": {
-                "description": {
+                "line_id": {
+                    "type": "integer"
+                },
+                "note": {
                     "type": "string"
                 },
-                "id": {
+                "quantity": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.ReservedStock": {
+            "type": "object",
+            "properties": {
+                "product_id": {
                     "type": "integer"
                 },
-                "name": {
-                    "type": "string"
+                "quantity": {
+                    "type": "number"
+                },
+                "variant_id": {
+                    "type": "integer"
                 }
             }
         },
-        "models.CreateTransactionRequest": {
+        "models.SalesReport": {
             "type": "object",
             "properties": {
-                "items": {
+                "end_date": {
+                    "type": "string"
+                },
+                "produk_terlaris": {
+                    "$ref": "#/definitions/models.BestSellerInfo"
+                },
+                "rincian_outlet": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.TransactionItem"
+                        "$ref": "#/definitions/models.OutletSales"
+                    }
+                },
+                "rincian_produk": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProductSales"
                     }
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "total_revenue": {
+                    "type": "integer"
+                },
+                "total_transaksi": {
+                    "type": "integer"
                 }
             }
         },
-        "models.Product": {
+        "models.StockConflict": {
             "type": "object",
             "properties": {
-                "category_id": {
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "stock_after": {
+                    "type": "number"
+                },
+                "variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StockTransfer": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "from_outlet_id": {
                     "type": "integer"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "name": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TransferLine"
+                    }
+                },
+                "note": {
                     "type": "string"
                 },
-                "price": {
-                    "type": "number"
+                "received_at": {
+                    "type": "string"
                 },
-                "stock": {
+                "sent_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to_outlet_id": {
                     "type": "integer"
                 }
             }
         },
-        "models.SalesReport": {
+        "models.StoreInfo": {
             "type": "object",
             "properties": {
-                "end_date": {
+                "address": {
                     "type": "string"
                 },
-                "produk_terlaris": {
-                    "$ref": "#/definitions/models.BestSellerInfo"
+                "name": {
+                    "type": "string"
                 },
-                "start_date": {
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SyncResult": {
+            "type": "object",
+            "properties": {
+                "client_id": {
                     "type": "string"
                 },
-                "total_revenue": {
-                    "type": "integer"
+                "conflicts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StockConflict"
+                    }
                 },
-                "total_transaksi": {
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction_id": {
                     "type": "integer"
                 }
             }
         },
+        "models.SyncTransactionsRequest": {
+            "type": "object",
+            "properties": {
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OfflineTransaction"
+                    }
+                }
+            }
+        },
+        "models.SyncTransactionsResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SyncResult"
+                    }
+                }
+            }
+        },
         "models.Transaction": {
             "type": "object",
             "properties": {
+                "client_id": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "customer_id": {
+                    "type": "integer"
+                },
                 "details": {
                     "type": "array",
                     "items": {
@@ -744,6 +4568,25 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "outlet_id": {
+                    "type": "integer"
+                },
+                "points_amount": {
+                    "type": "integer"
+                },
+                "points_earned": {
+                    "description": "Loyalty points of the customer: PointsAmount is the part of the total\npaid with the redeemed points",
+                    "type": "integer"
+                },
+                "points_expire_at": {
+                    "type": "string"
+                },
+                "points_redeemed": {
+                    "type": "integer"
+                },
+                "price_list_id": {
+                    "type": "integer"
+                },
                 "total_amount": {
                     "type": "integer"
                 }
@@ -752,20 +4595,53 @@ const docTemplate = `{
         "models.TransactionDetail": {
             "type": "object",
             "properties": {
+                "components": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TransactionDetailComponent"
+                    }
+                },
                 "id": {
                     "type": "integer"
                 },
+                "price_rule_id": {
+                    "description": "PriceRuleID is the price rule the line was priced with; nil is the\ncatalog or outlet price",
+                    "type": "integer"
+                },
                 "product_id": {
                     "type": "integer"
                 },
                 "quantity": {
-                    "type": "integer"
+                    "type": "number"
                 },
                 "subtotal": {
                     "type": "integer"
                 },
                 "transaction_id": {
                     "type": "integer"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "unit_quantity": {
+                    "type": "number"
+                },
+                "variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TransactionDetailComponent": {
+            "type": "object",
+            "properties": {
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "subtotal": {
+                    "type": "integer"
                 }
             }
         },
@@ -776,7 +4652,90 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "quantity": {
+                    "type": "number"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TransferLine": {
+            "type": "object",
+            "properties": {
+                "discrepancy": {
+                    "type": "number"
+                },
+                "discrepancy_note": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "received_quantity": {
+                    "type": "number"
+                },
+                "variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TransferReceipt": {
+            "type": "object",
+            "properties": {
+                "close": {
+                    "type": "boolean"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReceivedLine"
+                    }
+                }
+            }
+        },
+        "models.Unit": {
+            "type": "object",
+            "properties": {
+                "base_unit": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "factor": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "precision": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UnitConversion": {
+            "type": "object",
+            "properties": {
+                "factor": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "product_id": {
                     "type": "integer"
+                },
+                "unit": {
+                    "type": "string"
                 }
             }
         }
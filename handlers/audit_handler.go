@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kasir-api/logging"
+	"kasir-api/middleware"
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// AuditHandler handles HTTP requests for the audit log
+type AuditHandler struct {
+	service *services.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(service *services.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// RegisterRoutes registers the audit log routes
+func (h *AuditHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/audit-log", h.ListAuditLog)
+}
+
+// ListAuditLog menampilkan log audit
+// @Summary List the audit log
+// @Description Get a page of the audit log of the creates, updates and deletes of products, categories and transactions, newest first. Only owners may read the audit log.
+// @Tags audit
+// @Produce json
+// @Param actor query string false "Filter by the user who made the change"
+// @Param action query string false "Filter by action" Enums(create, update, delete, restore, purge)
+// @Param entity_type query string false "Filter by entity type, e.g. product, product_variant, category or transaction"
+// @Param entity_id query int false "Filter by entity ID"
+// @Param from query string false "Only changes made at or after this time (RFC3339)"
+// @Param to query string false "Only changes made before this time (RFC3339)"
+// @Param limit query int false "Number of entries, at most 200" default(50)
+// @Param offset query int false "Number of entries to skip"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 403 {string} string "Owner role required"
+// @Router /audit-log [get]
+func (h *AuditHandler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ownerAllowed(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Actor:      query.Get("actor"),
+		Action:     query.Get("action"),
+		EntityType: query.Get("entity_type"),
+	}
+	if entityID := query.Get("entity_id"); entityID != "" {
+		if id, err := strconv.Atoi(entityID); err == nil {
+			filter.EntityID = id
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if n, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = n
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		if n, err := strconv.Atoi(offset); err == nil {
+			filter.Offset = n
+		}
+	}
+	var ok bool
+	if filter.From, ok = timeQuery(w, r, "from"); !ok {
+		return
+	}
+	if filter.To, ok = timeQuery(w, r, "to"); !ok {
+		return
+	}
+
+	entries, err := h.service.GetAuditLog(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+// timeQuery parses an optional RFC3339 query parameter. It answers 400 and
+// returns false when the value is invalid.
+func timeQuery(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		http.Error(w, "Invalid "+name+" format. Use RFC3339", http.StatusBadRequest)
+		return nil, false
+	}
+	return &t, true
+}
+
+// recordAudit records a change made by a request in the audit log. The change
+// is made by then, so failing to record it is logged instead of failing the
+// request.
+func recordAudit(r *http.Request, service *services.AuditService, action, entityType string, entityID int, before, after any) {
+	entry := models.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IP:         clientIP(r),
+		RequestID:  logging.RequestID(r.Context()),
+	}
+	if err := service.Record(actorContext(r), entry, before, after); err != nil {
+		slog.ErrorContext(r.Context(), "recording audit entry failed",
+			slog.String("action", action),
+			slog.String("entity_type", entityType),
+			slog.Int("entity_id", entityID),
+			slog.Any("error", err))
+	}
+}
+
+// clientIP returns the IP address a request came from
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ownerAllowed checks the user is an owner and answers 403 when they are not
+func ownerAllowed(w http.ResponseWriter, r *http.Request) bool {
+	if !middleware.ClaimsFromContext(r.Context()).IsOwner() {
+		http.Error(w, "Owner role required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
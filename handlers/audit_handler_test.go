@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"kasir-api/middleware"
+)
+
+func TestAuditHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "product update", method: http.MethodGet, target: "/api/audit-log?entity_type=product&action=update", wantStatus: http.StatusOK, wantBody: `"actor":"budi","action":"update","entity_type":"product","entity_id":1,"before":{"price":5000},"after":{"price":6000},"ip":"192.0.2.1","request_id":"req-1"`},
+		{name: "category create", method: http.MethodGet, target: "/api/audit-log?entity_type=category", wantStatus: http.StatusOK, wantBody: `"action":"create","entity_type":"category","entity_id":2,"after":{"description":"","id":2,"name":"Makanan","points_multiplier":1}`},
+		{name: "variant delete", method: http.MethodGet, target: "/api/audit-log?entity_type=product_variant", wantStatus: http.StatusOK, wantBody: `"action":"delete","entity_type":"product_variant","entity_id":1,"before":{"id":1,"name":"L","price":5000,"product_id":2,"sku":"TEH-L","stock":3}`},
+		{name: "transaction create", method: http.MethodGet, target: "/api/audit-log?entity_type=transaction&action=create", wantStatus: http.StatusOK, wantBody: `"entity_type":"transaction","entity_id":1,"after":{`},
+		{name: "transaction delete", method: http.MethodGet, target: "/api/audit-log?entity_type=transaction&action=delete", wantStatus: http.StatusOK, wantBody: `"action":"delete","entity_type":"transaction","entity_id":1,"before":{`},
+		{name: "newest first", method: http.MethodGet, target: "/api/audit-log?limit=1", wantStatus: http.StatusOK, wantBody: `[{"id":5,`},
+		{name: "page", method: http.MethodGet, target: "/api/audit-log?limit=1&offset=4", wantStatus: http.StatusOK, wantBody: `[{"id":1,`},
+		{name: "by entity ID", method: http.MethodGet, target: "/api/audit-log?entity_type=product&entity_id=2", wantStatus: http.StatusOK, wantBody: `[]`},
+		{name: "failed changes are not recorded", method: http.MethodGet, target: "/api/audit-log?entity_type=product&action=delete", wantStatus: http.StatusOK, wantBody: `[]`},
+		{name: "invalid action", method: http.MethodGet, target: "/api/audit-log?action=read", wantStatus: http.StatusBadRequest, wantBody: "action must be create, update, delete, restore or purge"},
+		{name: "invalid time", method: http.MethodGet, target: "/api/audit-log?from=yesterday", wantStatus: http.StatusBadRequest, wantBody: "Invalid from format"},
+		{name: "limit too large", method: http.MethodGet, target: "/api/audit-log?limit=1000", wantStatus: http.StatusBadRequest, wantBody: "limit must be between 1 and 200"},
+	}
+
+	const secret = "secret"
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{
+		Role:             middleware.RoleOwner,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "budi"},
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := map[string]string{"Authorization": "Bearer " + signed, middleware.RequestIDHeader: "req-1"}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			handler := middleware.RequestID(middleware.Auth(secret)(h.router))
+
+			seed := []handlerCase{
+				{method: http.MethodPut, target: "/api/products/1", body: `{"name":"Kopi","price":6000,"stock":10,"category_id":1}`, wantStatus: http.StatusOK},
+				{method: http.MethodPost, target: "/api/categories", body: `{"name":"Makanan"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodDelete, target: "/api/products/2/variants/1", wantStatus: http.StatusOK},
+				{method: http.MethodDelete, target: "/api/products/1", wantStatus: http.StatusConflict},
+				{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusCreated},
+				{method: http.MethodDelete, target: "/api/transactions/1", wantStatus: http.StatusOK},
+			}
+			for _, s := range seed {
+				s.header = header
+				s.run(t, handler)
+			}
+
+			tc.header = header
+			tc.run(t, handler)
+		})
+	}
+}
+
+func TestAuditHandlerSalesAndBills(t *testing.T) {
+	h := newTestHandlers(t)
+
+	steps := []handlerCase{
+		{name: "update bundle", method: http.MethodPut, target: "/api/products/3", body: `{"name":"Paket Kopi","price":9500,"category_id":1}`, wantStatus: http.StatusOK},
+		{name: "open bill", method: http.MethodPost, target: "/api/open-bills", body: `{"label":"Meja 1","reserve_stock":true,"items":[{"product_id":1,"quantity":2}]}`, wantStatus: http.StatusCreated},
+		{name: "checkout bill", method: http.MethodPost, target: "/api/open-bills/1/checkout", wantStatus: http.StatusCreated},
+		{name: "open second bill", method: http.MethodPost, target: "/api/open-bills", body: `{"label":"Meja 2","reserve_stock":true,"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusCreated},
+		{name: "cancel bill", method: http.MethodPost, target: "/api/open-bills/2/cancel", wantStatus: http.StatusOK},
+		{name: "sync", method: http.MethodPost, target: "/api/sync/transactions", body: `{"transactions":[{"client_id":"7d9f4a52-3c1e-4b8a-9f60-2a1b3c4d5e6f","created_at":"2026-01-10T08:00:00Z","items":[{"product_id":1,"quantity":2}]}]}`, wantStatus: http.StatusOK, wantBody: `"status":"accepted"`},
+
+		// The update is logged as stored, without fields the request left out
+		{name: "product update", method: http.MethodGet, target: "/api/audit-log?entity_type=product&entity_id=3", wantStatus: http.StatusOK, wantBody: `"before":{"price":9000},"after":{"price":9500}`},
+		{name: "checkout sale", method: http.MethodGet, target: "/api/audit-log?entity_type=transaction&entity_id=1", wantStatus: http.StatusOK, wantBody: `"action":"create","entity_type":"transaction","entity_id":1,"after":{`},
+		{name: "checked out bill", method: http.MethodGet, target: "/api/audit-log?entity_type=open_bill&entity_id=1", wantStatus: http.StatusOK, wantBody: `"status":"checked_out","transaction_id":1}`},
+		{name: "cancelled bill", method: http.MethodGet, target: "/api/audit-log?entity_type=open_bill&entity_id=2", wantStatus: http.StatusOK, wantBody: `"status":"cancelled"}`},
+		{name: "synced sale", method: http.MethodGet, target: "/api/audit-log?entity_type=transaction&entity_id=2", wantStatus: http.StatusOK, wantBody: `"action":"create","entity_type":"transaction","entity_id":2,"after":{`},
+	}
+	for _, step := range steps {
+		step.run(t, h.router)
+	}
+}
+
+func TestAuditHandlerAccess(t *testing.T) {
+	const secret = "secret"
+	token := func(claims middleware.Claims) map[string]string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]string{"Authorization": "Bearer " + signed}
+	}
+
+	tests := []handlerCase{
+		{name: "owner", method: http.MethodGet, target: "/api/audit-log", header: token(middleware.Claims{Role: middleware.RoleOwner}), wantStatus: http.StatusOK},
+		{name: "cashier of every outlet", method: http.MethodGet, target: "/api/audit-log", header: token(middleware.Claims{Role: "cashier"}), wantStatus: http.StatusForbidden, wantBody: "Owner role required"},
+		{name: "cashier of one outlet", method: http.MethodGet, target: "/api/audit-log", header: token(middleware.Claims{Role: "cashier", Outlets: []int{2}}), wantStatus: http.StatusForbidden, wantBody: "Owner role required"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			tc.run(t, middleware.Auth(secret)(h.router))
+		})
+	}
+}
@@ -2,9 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"kasir-api/models"
 	"kasir-api/services"
@@ -13,44 +13,45 @@ import (
 // CategoryHandler handles HTTP requests for categories
 type CategoryHandler struct {
 	service *services.CategoryService
+	audit   *services.AuditService
 }
 
-// NewCategoryHandler creates a new CategoryHandler
-func NewCategoryHandler(service *services.CategoryService) *CategoryHandler {
-	return &CategoryHandler{service: service}
+// NewCategoryHandler creates a new CategoryHandler; changes are recorded in
+// the audit log
+func NewCategoryHandler(service *services.CategoryService, audit *services.AuditService) *CategoryHandler {
+	return &CategoryHandler{service: service, audit: audit}
 }
 
-// Handle menangani routing berdasarkan method HTTP
-func (h *CategoryHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		path := strings.TrimPrefix(r.URL.Path, "/api/categories")
-		if path == "" || path == "/" {
-			h.ListCategories(w, r)
-		} else {
-			h.GetCategory(w, r)
-		}
-	case http.MethodPost:
-		h.CreateCategory(w, r)
-	case http.MethodPut:
-		h.UpdateCategory(w, r)
-	case http.MethodDelete:
-		h.DeleteCategory(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+// RegisterRoutes registers the category routes
+func (h *CategoryHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/categories", h.ListCategories)
+	r.HandleFunc("POST /api/categories", h.CreateCategory)
+	r.HandleFunc("GET /api/categories/{id}", h.GetCategory)
+	r.HandleFunc("PUT /api/categories/{id}", h.UpdateCategory)
+	r.HandleFunc("DELETE /api/categories/{id}", h.DeleteCategory)
+	r.HandleFunc("POST /api/categories/{id}/restore", h.RestoreCategory)
+	r.HandleFunc("DELETE /api/categories/{id}/purge", h.PurgeCategory)
 }
 
 // ListCategories menampilkan semua kategori
 // @Summary List all categories
-// @Description Get all categories
+// @Description Get all categories; deleted categories are left out unless include_deleted is true
 // @Tags categories
 // @Produce json
+// @Param include_deleted query bool false "List deleted categories too"
 // @Success 200 {array} models.Category
 // @Router /categories [get]
 func (h *CategoryHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	categories, err := h.service.GetAllCategories()
+
+	var filter models.CategoryFilter
+	if includeDeleted := r.URL.Query().Get("include_deleted"); includeDeleted != "" {
+		if include, err := strconv.ParseBool(includeDeleted); err == nil {
+			filter.IncludeDeleted = include
+		}
+	}
+
+	categories, err := h.service.GetAllCategories(r.Context(), filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -70,14 +71,13 @@ func (h *CategoryHandler) ListCategories(w http.ResponseWriter, r *http.Request)
 // @Router /categories/{id} [get]
 func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/categories/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
 		http.Error(w, "Invalid category ID", http.StatusBadRequest)
 		return
 	}
 
-	category, err := h.service.GetCategoryByID(id)
+	category, err := h.service.GetCategoryByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -106,11 +106,12 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	createdCategory, err := h.service.CreateCategory(newCategory)
+	createdCategory, err := h.service.CreateCategory(r.Context(), newCategory)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	recordAudit(r, h.audit, models.AuditCreate, "category", createdCategory.ID, nil, createdCategory)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createdCategory)
@@ -131,8 +132,7 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/categories/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
 		http.Error(w, "Invalid category ID", http.StatusBadRequest)
 		return
@@ -145,40 +145,141 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	category, err := h.service.UpdateCategory(id, updatedCategory)
+	before, err := h.service.GetCategoryByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
+	category, err := h.service.UpdateCategory(r.Context(), id, updatedCategory)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditUpdate, "category", id, before, category)
+
 	json.NewEncoder(w).Encode(category)
 }
 
 // DeleteCategory menghapus kategori berdasarkan ID
 // @Summary Delete a category
-// @Description Delete category by ID
+// @Description Soft delete category by ID: it is no longer listed and can be restored. A category with products is only deleted when reassign_to names the category they move to.
 // @Tags categories
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param reassign_to query int false "Category to move the products to"
 // @Success 200 {string} string "Category deleted successfully"
-// @Failure 400 {string} string "Invalid category ID"
+// @Failure 400 {string} string "Invalid category ID or reassign_to"
 // @Failure 404 {string} string "Category not found"
+// @Failure 409 {string} string "Category has products"
 // @Router /categories/{id} [delete]
 func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/categories/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
 		http.Error(w, "Invalid category ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.service.DeleteCategory(id)
+	var reassignTo *int
+	if v := r.URL.Query().Get("reassign_to"); v != "" {
+		target, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid reassign_to", http.StatusBadRequest)
+			return
+		}
+		reassignTo = &target
+	}
+
+	before, err := h.service.GetCategoryByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
+	err = h.service.DeleteCategory(actorContext(r), id, reassignTo)
+	if err != nil {
+		if errors.Is(err, services.ErrReassignTarget) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		deleteError(w, err, http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditDelete, "category", id, before, nil)
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Category deleted successfully"})
 }
+
+// RestoreCategory memulihkan kategori yang sudah dihapus
+// @Summary Restore a category
+// @Description Undo the deletion of a category
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {object} models.Category
+// @Failure 400 {string} string "Invalid category ID"
+// @Failure 404 {string} string "Category not found"
+// @Failure 409 {string} string "Category is not deleted"
+// @Router /categories/{id}/restore [post]
+func (h *CategoryHandler) RestoreCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetCategoryByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	category, err := h.service.RestoreCategory(r.Context(), id)
+	if err != nil {
+		deleteError(w, err, http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditRestore, "category", id, before, category)
+
+	json.NewEncoder(w).Encode(category)
+}
+
+// PurgeCategory menghapus kategori secara permanen
+// @Summary Purge a category
+// @Description Permanently remove a deleted category; its products lose their category, recorded in their history. Categories whose products were sold cannot be purged.
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {string} string "Category purged successfully"
+// @Failure 400 {string} string "Invalid category ID"
+// @Failure 404 {string} string "Category not found"
+// @Failure 409 {string} string "Category is not deleted or referenced by sales"
+// @Router /categories/{id}/purge [delete]
+func (h *CategoryHandler) PurgeCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetCategoryByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	err = h.service.PurgeCategory(actorContext(r), id)
+	if err != nil {
+		deleteError(w, err, http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditPurge, "category", id, before, nil)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Category purged successfully"})
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCategoryHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "list", method: http.MethodGet, target: "/api/categories", wantStatus: http.StatusOK, wantBody: `"name":"Minuman"`},
+		{name: "get", method: http.MethodGet, target: "/api/categories/1", wantStatus: http.StatusOK, wantBody: `"id":1`},
+		{name: "get missing", method: http.MethodGet, target: "/api/categories/99", wantStatus: http.StatusNotFound, wantBody: "Category with ID 99 not found"},
+		{name: "get invalid id", method: http.MethodGet, target: "/api/categories/abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid category ID"},
+		{name: "create", method: http.MethodPost, target: "/api/categories", body: `{"name":"Makanan","description":"Food"}`, wantStatus: http.StatusCreated, wantBody: `"id":2`},
+		{name: "create invalid body", method: http.MethodPost, target: "/api/categories", body: `{`, wantStatus: http.StatusBadRequest, wantBody: "Invalid request body"},
+		{name: "update", method: http.MethodPut, target: "/api/categories/1", body: `{"name":"Minuman Dingin"}`, wantStatus: http.StatusOK, wantBody: `"name":"Minuman Dingin"`},
+		{name: "update missing", method: http.MethodPut, target: "/api/categories/99", body: `{"name":"X"}`, wantStatus: http.StatusNotFound},
+		{name: "delete with products", method: http.MethodDelete, target: "/api/categories/1", wantStatus: http.StatusConflict, wantBody: "category with ID 1 has products"},
+		{name: "delete reassigning to missing category", method: http.MethodDelete, target: "/api/categories/1?reassign_to=9", wantStatus: http.StatusBadRequest, wantBody: "category with ID 9 does not exist"},
+		{name: "delete reassigning to itself", method: http.MethodDelete, target: "/api/categories/1?reassign_to=1", wantStatus: http.StatusBadRequest, wantBody: "invalid reassignment target"},
+		{name: "delete with invalid reassign_to", method: http.MethodDelete, target: "/api/categories/1?reassign_to=abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid reassign_to"},
+		{name: "delete missing", method: http.MethodDelete, target: "/api/categories/99", wantStatus: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPatch, target: "/api/categories/1", wantStatus: http.StatusMethodNotAllowed},
+		{name: "restore category that is not deleted", method: http.MethodPost, target: "/api/categories/1/restore", wantStatus: http.StatusConflict, wantBody: "is not deleted"},
+		{name: "restore missing", method: http.MethodPost, target: "/api/categories/99/restore", wantStatus: http.StatusNotFound},
+		{name: "purge category that is not deleted", method: http.MethodDelete, target: "/api/categories/1/purge", wantStatus: http.StatusConflict, wantBody: "must be deleted before it is purged"},
+		{name: "purge missing", method: http.MethodDelete, target: "/api/categories/99/purge", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			tc.run(t, h.router)
+		})
+	}
+}
+
+func TestCategoryHandlerSoftDelete(t *testing.T) {
+	h := newTestHandlers(t)
+	steps := []handlerCase{
+		{method: http.MethodPost, target: "/api/categories", body: `{"name":"Makanan"}`, wantStatus: http.StatusCreated, wantBody: `"id":2`},
+		{method: http.MethodDelete, target: "/api/categories/2", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/api/categories", wantStatus: http.StatusOK, wantBody: `[{"id":1,"name":"Minuman"`},
+		{method: http.MethodGet, target: "/api/categories?include_deleted=true", wantStatus: http.StatusOK, wantBody: `"name":"Makanan","description":"","points_multiplier":1,"deleted_at":"`},
+		{method: http.MethodPut, target: "/api/categories/2", body: `{"name":"Makanan Ringan"}`, wantStatus: http.StatusNotFound},
+		{method: http.MethodPost, target: "/api/categories/2/restore", wantStatus: http.StatusOK, wantBody: `"name":"Makanan"`},
+		{method: http.MethodPost, target: "/api/categories/2/restore", wantStatus: http.StatusConflict, wantBody: "is not deleted"},
+		{method: http.MethodDelete, target: "/api/categories/2", wantStatus: http.StatusOK},
+		{method: http.MethodDelete, target: "/api/categories/2/purge", wantStatus: http.StatusOK, wantBody: "Category purged successfully"},
+		{method: http.MethodGet, target: "/api/categories/2", wantStatus: http.StatusNotFound},
+		// Deleting a category with products moves them to another one
+		{method: http.MethodPost, target: "/api/categories", body: `{"name":"Kopi"}`, wantStatus: http.StatusCreated, wantBody: `"id":3`},
+		{method: http.MethodDelete, target: "/api/categories/1?reassign_to=3", wantStatus: http.StatusOK, wantBody: "Category deleted successfully"},
+		{method: http.MethodGet, target: "/api/products?category_id=3", wantStatus: http.StatusOK, wantBody: `"name":"Paket Kopi","price":9000,"stock":5,"unit":"pcs","category_id":3`},
+		{method: http.MethodGet, target: "/api/products/1/history", wantStatus: http.StatusOK, wantBody: `"category_id":3,"changes":["category_id"]`},
+	}
+	for _, s := range steps {
+		s.run(t, h.router)
+	}
+}
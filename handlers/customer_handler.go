@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// CustomerHandler handles HTTP requests for customers
+type CustomerHandler struct {
+	service *services.CustomerService
+}
+
+// NewCustomerHandler creates a new CustomerHandler
+func NewCustomerHandler(service *services.CustomerService) *CustomerHandler {
+	return &CustomerHandler{service: service}
+}
+
+// RegisterRoutes registers the customer routes, including purchase history
+// and lifetime value
+func (h *CustomerHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/customers", h.ListCustomers)
+	r.HandleFunc("POST /api/customers", h.CreateCustomer)
+	r.HandleFunc("GET /api/customers/{id}", h.GetCustomer)
+	r.HandleFunc("PUT /api/customers/{id}", h.UpdateCustomer)
+	r.HandleFunc("DELETE /api/customers/{id}", h.DeleteCustomer)
+	r.HandleFunc("GET /api/customers/{id}/transactions", h.GetPurchaseHistory)
+	r.HandleFunc("GET /api/customers/{id}/lifetime-value", h.GetLifetimeValue)
+}
+
+// ListCustomers menampilkan semua pelanggan dengan pencarian opsional
+// @Summary List all customers
+// @Description Get all customers ordered by name, optionally searched by name, phone, email or member number
+// @Tags customers
+// @Produce json
+// @Param search query string false "Part of the name, phone, email or member number"
+// @Success 200 {array} models.Customer
+// @Router /customers [get]
+func (h *CustomerHandler) ListCustomers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter := models.CustomerFilter{Search: r.URL.Query().Get("search")}
+	customers, err := h.service.GetAllCustomers(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(customers)
+}
+
+// GetCustomer menampilkan detail pelanggan berdasarkan ID
+// @Summary Get customer by ID
+// @Description Get customer details by ID
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {object} models.Customer
+// @Failure 400 {string} string "Invalid customer ID"
+// @Failure 404 {string} string "Customer not found"
+// @Router /customers/{id} [get]
+func (h *CustomerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.service.GetCustomerByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(customer)
+}
+
+// CreateCustomer membuat pelanggan baru
+// @Summary Create a new customer
+// @Description Create a new customer; phone and member number must be unique
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param customer body models.Customer true "Customer object"
+// @Success 201 {object} models.Customer
+// @Failure 400 {string} string "Invalid request body"
+// @Router /customers [post]
+func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var newCustomer models.Customer
+	if err := json.NewDecoder(r.Body).Decode(&newCustomer); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.service.CreateCustomer(r.Context(), newCustomer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(customer)
+}
+
+// UpdateCustomer mengupdate pelanggan berdasarkan ID
+// @Summary Update a customer
+// @Description Update customer by ID
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param customer body models.Customer true "Customer object"
+// @Success 200 {object} models.Customer
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Customer not found"
+// @Router /customers/{id} [put]
+func (h *CustomerHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedCustomer models.Customer
+	if err := json.NewDecoder(r.Body).Decode(&updatedCustomer); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.service.UpdateCustomer(r.Context(), id, updatedCustomer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(customer)
+}
+
+// DeleteCustomer menghapus pelanggan berdasarkan ID
+// @Summary Delete a customer
+// @Description Delete customer by ID; their transactions are kept without a customer
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {string} string "Customer deleted successfully"
+// @Failure 400 {string} string "Invalid customer ID"
+// @Failure 404 {string} string "Customer not found"
+// @Router /customers/{id} [delete]
+func (h *CustomerHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCustomer(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Customer deleted successfully"})
+}
+
+// GetPurchaseHistory menampilkan riwayat belanja pelanggan
+// @Summary Get customer purchase history
+// @Description Get the transactions of a customer, newest first
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {array} models.Transaction
+// @Failure 400 {string} string "Invalid customer ID"
+// @Failure 404 {string} string "Customer not found"
+// @Router /customers/{id}/transactions [get]
+func (h *CustomerHandler) GetPurchaseHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	transactions, err := h.service.GetPurchaseHistory(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transactions)
+}
+
+// GetLifetimeValue menampilkan total belanja pelanggan
+// @Summary Get customer lifetime value
+// @Description Get the number of transactions, total and average spent and the first and last purchase of a customer
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {object} models.CustomerLifetimeValue
+// @Failure 400 {string} string "Invalid customer ID"
+// @Failure 404 {string} string "Customer not found"
+// @Router /customers/{id}/lifetime-value [get]
+func (h *CustomerHandler) GetLifetimeValue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	value, err := h.service.GetLifetimeValue(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"kasir-api/models"
+	"kasir-api/repositories/memory"
+)
+
+func TestCustomerHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "list", method: http.MethodGet, target: "/api/customers", wantStatus: http.StatusOK, wantBody: `"name":"Budi"`},
+		{name: "search", method: http.MethodGet, target: "/api/customers?search=m-001", wantStatus: http.StatusOK, wantBody: `"member_number":"M-001"`},
+		{name: "search without match", method: http.MethodGet, target: "/api/customers?search=sari", wantStatus: http.StatusOK, wantBody: "null"},
+		{name: "get", method: http.MethodGet, target: "/api/customers/1", wantStatus: http.StatusOK, wantBody: `"phone":"0812"`},
+		{name: "get missing", method: http.MethodGet, target: "/api/customers/99", wantStatus: http.StatusNotFound, wantBody: "Customer with ID 99 not found"},
+		{name: "get invalid id", method: http.MethodGet, target: "/api/customers/abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid customer ID"},
+		{name: "create", method: http.MethodPost, target: "/api/customers", body: `{"name":"Sari","phone":"0813"}`, wantStatus: http.StatusCreated, wantBody: `"id":2`},
+		{name: "create without name", method: http.MethodPost, target: "/api/customers", body: `{"phone":"0813"}`, wantStatus: http.StatusBadRequest, wantBody: "customer name is required"},
+		{name: "create with taken phone", method: http.MethodPost, target: "/api/customers", body: `{"name":"Sari","phone":"0812"}`, wantStatus: http.StatusBadRequest, wantBody: "already exists"},
+		{name: "create invalid body", method: http.MethodPost, target: "/api/customers", body: `{`, wantStatus: http.StatusBadRequest, wantBody: "Invalid request body"},
+		{name: "update", method: http.MethodPut, target: "/api/customers/1", body: `{"name":"Budi Santoso","phone":"0812"}`, wantStatus: http.StatusOK, wantBody: `"name":"Budi Santoso"`},
+		{name: "update missing", method: http.MethodPut, target: "/api/customers/99", body: `{"name":"X"}`, wantStatus: http.StatusNotFound},
+		{name: "delete", method: http.MethodDelete, target: "/api/customers/1", wantStatus: http.StatusOK, wantBody: "deleted"},
+		{name: "delete missing", method: http.MethodDelete, target: "/api/customers/99", wantStatus: http.StatusNotFound},
+		{name: "history", method: http.MethodGet, target: "/api/customers/1/transactions", wantStatus: http.StatusOK, wantBody: `"customer_id":1`},
+		{name: "history of missing customer", method: http.MethodGet, target: "/api/customers/99/transactions", wantStatus: http.StatusNotFound},
+		{name: "lifetime value", method: http.MethodGet, target: "/api/customers/1/lifetime-value", wantStatus: http.StatusOK, wantBody: `"total_transactions":1,"total_spent":10000,"average_spent":10000`},
+		{name: "lifetime value invalid id", method: http.MethodGet, target: "/api/customers/abc/lifetime-value", wantStatus: http.StatusBadRequest},
+		{name: "transactions by customer", method: http.MethodGet, target: "/api/transactions?customer_id=1", wantStatus: http.StatusOK, wantBody: `"customer_id":1`},
+		{name: "sale with unknown customer", method: http.MethodPost, target: "/api/transactions", body: `{"customer_id":99,"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusBadRequest, wantBody: "Customer with ID 99 not found"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			ctx := context.Background()
+			customer, err := memory.NewCustomerRepository(h.store).Create(ctx, models.Customer{Name: "Budi", Phone: "0812", MemberNumber: "M-001"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = memory.NewTransactionRepository(h.store).Create(ctx, models.Transaction{
+				OutletID:    models.DefaultOutletID,
+				CustomerID:  &customer.ID,
+				TotalAmount: 10000,
+				Details:     []models.TransactionDetail{{ProductID: 1, Quantity: 2, Unit: "pcs", UnitQuantity: 2, Subtotal: 10000}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.run(t, h.router)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories/memory"
+	"kasir-api/services"
+)
+
+// testHandlers wires every handler to services on a fresh in-memory store
+type testHandlers struct {
+	store        *memory.Store
+	products     *ProductHandler
+	units        *UnitHandler
+	categories   *CategoryHandler
+	customers    *CustomerHandler
+	outlets      *OutletHandler
+	transfers    *TransferHandler
+	loyalty      *LoyaltyHandler
+	priceLists   *PriceListHandler
+	transactions *TransactionHandler
+	openBills    *OpenBillHandler
+	sync         *SyncHandler
+	reports      *ReportHandler
+	audit        *AuditHandler
+	health       *HealthHandler
+
+	// router serves the routes of all handlers
+	router *Router
+}
+
+// newTestHandlers creates the handlers on an in-memory store seeded with
+// category 1, product 1 "Kopi" (pcs, Rp 5.000, 10 in stock, box of 6),
+// product 2 "Es Teh" with variant 1 and product 3, a bundle of two Kopi
+func newTestHandlers(t *testing.T) *testHandlers {
+	t.Helper()
+
+	store := memory.NewStore()
+	productRepo := memory.NewProductRepository(store)
+	variantRepo := memory.NewProductVariantRepository(store)
+
+	unitService := services.NewUnitService(memory.NewUnitRepository(store))
+	productService := services.NewProductService(productRepo, variantRepo)
+	categoryRepo := memory.NewCategoryRepository(store)
+	categoryService := services.NewCategoryService(categoryRepo)
+	customerRepo := memory.NewCustomerRepository(store)
+	customerService := services.NewCustomerService(customerRepo, memory.NewTransactionRepository(store))
+	outletRepo := memory.NewOutletRepository(store)
+	outletService := services.NewOutletService(outletRepo, productRepo)
+	transferService := services.NewTransferService(memory.NewTransferRepository(store), outletRepo, productRepo, variantRepo)
+	loyaltyService := services.NewLoyaltyService(memory.NewLoyaltyRepository(store), categoryRepo, customerRepo, services.LoyaltyProgram{
+		RupiahPerPoint: 1000,
+		PointValue:     10,
+		PointsTTL:      365 * 24 * time.Hour,
+	})
+	priceListService := services.NewPriceListService(memory.NewPriceListRepository(store), productRepo, variantRepo, time.UTC)
+	transactionService := services.NewTransactionService(memory.NewTransactionRepository(store), productRepo, variantRepo, customerRepo, outletRepo, unitService, loyaltyService, priceListService, nil)
+	openBillService := services.NewOpenBillService(memory.NewOpenBillRepository(store), outletRepo, transactionService)
+	idempotencyService := services.NewIdempotencyService(memory.NewIdempotencyRepository(store), time.Hour)
+	reportService := services.NewReportService(memory.NewReportRepository(store), services.AttributeToBundle, time.UTC)
+	receiptService := services.NewReceiptService(memory.NewTransactionRepository(store), productRepo, variantRepo, models.StoreInfo{Name: "Toko Test"}, time.UTC)
+	auditService := services.NewAuditService(memory.NewAuditRepository(store))
+	healthService := services.NewHealthService(memory.NewHealthRepository(store), "Kasir API", "1.0", "development")
+
+	ctx := context.Background()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("seeding test data: %v", err)
+		}
+	}
+	category, err := categoryService.CreateCategory(ctx, models.Category{Name: "Minuman"})
+	must(err)
+	_, err = productService.CreateProduct(ctx, models.Product{Name: "Kopi", Price: 5000, Stock: 10, CategoryID: &category.ID})
+	must(err)
+	_, err = productService.CreateProduct(ctx, models.Product{Name: "Es Teh", Price: 3000, CategoryID: &category.ID})
+	must(err)
+	_, err = productService.CreateProduct(ctx, models.Product{Name: "Paket Kopi", Price: 9000, CategoryID: &category.ID})
+	must(err)
+	_, err = productService.CreateVariant(ctx, 2, models.ProductVariant{Name: "L", SKU: "TEH-L", Price: 5000, Stock: 3})
+	must(err)
+	_, err = productService.SetComponents(ctx, 3, []models.BundleComponent{{ProductID: 1, Quantity: 2}})
+	must(err)
+	_, err = unitService.SaveConversion(ctx, 1, models.UnitConversion{Unit: "box", Factor: 6})
+	must(err)
+
+	h := &testHandlers{
+		store:        store,
+		products:     NewProductHandler(productService, unitService, auditService),
+		units:        NewUnitHandler(unitService),
+		categories:   NewCategoryHandler(categoryService, auditService),
+		customers:    NewCustomerHandler(customerService),
+		outlets:      NewOutletHandler(outletService),
+		transfers:    NewTransferHandler(transferService),
+		loyalty:      NewLoyaltyHandler(loyaltyService),
+		priceLists:   NewPriceListHandler(priceListService),
+		transactions: NewTransactionHandler(transactionService, receiptService, idempotencyService, auditService),
+		openBills:    NewOpenBillHandler(openBillService, auditService),
+		sync:         NewSyncHandler(transactionService, auditService),
+		reports:      NewReportHandler(reportService),
+		audit:        NewAuditHandler(auditService),
+		health:       NewHealthHandler(healthService),
+		router:       NewRouter(),
+	}
+	h.health.RegisterRoutes(h.router)
+	h.products.RegisterRoutes(h.router)
+	h.units.RegisterRoutes(h.router)
+	h.categories.RegisterRoutes(h.router)
+	h.customers.RegisterRoutes(h.router)
+	h.priceLists.RegisterRoutes(h.router)
+	h.outlets.RegisterRoutes(h.router)
+	h.transfers.RegisterRoutes(h.router)
+	h.loyalty.RegisterRoutes(h.router)
+	h.transactions.RegisterRoutes(h.router)
+	h.openBills.RegisterRoutes(h.router)
+	h.sync.RegisterRoutes(h.router)
+	h.reports.RegisterRoutes(h.router)
+	h.audit.RegisterRoutes(h.router)
+	return h
+}
+
+// handlerCase is a single request against a handler and the expected response
+type handlerCase struct {
+	name       string
+	method     string
+	target     string
+	body       string
+	header     map[string]string
+	wantStatus int
+	wantBody   string
+	wantHeader map[string]string
+}
+
+// run sends the request of a case to handler and checks the response
+func (tc handlerCase) run(t *testing.T, handler http.Handler) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+	for k, v := range tc.header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != tc.wantStatus {
+		t.Errorf("%s %s status = %d, want %d (body: %s)", tc.method, tc.target, rec.Code, tc.wantStatus, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), tc.wantBody) {
+		t.Errorf("%s %s body = %s, want it to contain %q", tc.method, tc.target, rec.Body.String(), tc.wantBody)
+	}
+	for k, want := range tc.wantHeader {
+		if got := rec.Header().Get(k); got != want {
+			t.Errorf("%s %s header %s = %q, want %q", tc.method, tc.target, k, got, want)
+		}
+	}
+	return rec
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// HealthHandler handles HTTP requests for liveness and readiness checks
+type HealthHandler struct {
+	service *services.HealthService
+}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler(service *services.HealthService) *HealthHandler {
+	return &HealthHandler{service: service}
+}
+
+// RegisterRoutes registers the health check routes; /api/health is the same
+// as /api/health/ready
+func (h *HealthHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/health", h.Ready)
+	r.HandleFunc("GET /api/health/live", h.Live)
+	r.HandleFunc("GET /api/health/ready", h.Ready)
+}
+
+// Live menampilkan status liveness API
+// @Summary Liveness check
+// @Description Report that the API process is running. Does not check the database.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthStatus
+// @Router /health/live [get]
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, h.service.Liveness())
+}
+
+// Ready menampilkan status readiness API beserta koneksi database
+// @Summary Readiness check
+// @Description Ping the database and report connection pool statistics. Returns 503 when the database is unreachable.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthStatus
+// @Failure 503 {object} models.HealthStatus
+// @Router /health/ready [get]
+// @Router /health [get]
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, h.service.Readiness(r.Context()))
+}
+
+// writeHealth writes a health status, using 503 when it is not OK
+func writeHealth(w http.ResponseWriter, health models.HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if health.Status != models.HealthStatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
+}
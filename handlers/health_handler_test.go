@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		handlerCase
+		pingErr error
+	}{
+		{handlerCase: handlerCase{name: "live", method: http.MethodGet, target: "/api/health/live", wantStatus: http.StatusOK, wantBody: `"status":"OK"`}},
+		{handlerCase: handlerCase{name: "live while database is down", method: http.MethodGet, target: "/api/health/live", wantStatus: http.StatusOK, wantBody: `"status":"OK"`}, pingErr: errors.New("connection refused")},
+		{handlerCase: handlerCase{name: "ready", method: http.MethodGet, target: "/api/health/ready", wantStatus: http.StatusOK, wantBody: `"pool":{`}},
+		{handlerCase: handlerCase{name: "health is ready", method: http.MethodGet, target: "/api/health", wantStatus: http.StatusOK, wantBody: `"database":{`}},
+		{handlerCase: handlerCase{name: "not ready", method: http.MethodGet, target: "/api/health/ready", wantStatus: http.StatusServiceUnavailable, wantBody: `"error":"connection refused"`}, pingErr: errors.New("connection refused")},
+		{handlerCase: handlerCase{name: "method not allowed", method: http.MethodPost, target: "/api/health/ready", wantStatus: http.StatusMethodNotAllowed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			h.store.SetPingError(tt.pingErr)
+			tt.run(t, h.router)
+		})
+	}
+}
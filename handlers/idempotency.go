@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"kasir-api/middleware"
+	"kasir-api/services"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client chosen key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotent wraps a handler so that retries carrying the same Idempotency-Key
+// replay the original response instead of executing the request again. Keys
+// are scoped to the authenticated user. A nil service disables idempotency
+// keys.
+func Idempotent(service *services.IdempotencyService, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || service == nil {
+			next(w, r)
+			return
+		}
+		if len(key) > 255 {
+			http.Error(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var owner string
+		if claims := middleware.ClaimsFromContext(r.Context()); claims != nil {
+			owner = claims.Subject
+		}
+		record, err := service.Begin(r.Context(), owner, key, hashRequest(r, body))
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused), errors.Is(err, services.ErrIdempotencyInProgress),
+			errors.Is(err, services.ErrIdempotencyFailed):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		case record != nil:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.StatusCode)
+			io.WriteString(w, record.ResponseBody)
+			return
+		}
+
+		// Store the response even when the client has gone away, so its retry can be replayed
+		ctx := context.WithoutCancel(r.Context())
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		defer func() {
+			if p := recover(); p != nil {
+				// The request may have committed before it panicked, so the
+				// key is not given back for a retry that could sell twice;
+				// the recovery middleware answers the panic
+				if err := service.Fail(ctx, owner, key); err != nil {
+					slog.ErrorContext(ctx, "failed to mark idempotency key failed", "idempotency_key", key, "error", err)
+				}
+				panic(p)
+			}
+		}()
+		next(recorder, r)
+
+		if err := service.Complete(ctx, owner, key, recorder.statusCode, recorder.body.String()); err != nil {
+			slog.ErrorContext(ctx, "failed to store idempotent response", "idempotency_key", key, "error", err)
+		}
+	}
+}
+
+// hashRequest fingerprints the method, path and body of a request
+func hashRequest(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
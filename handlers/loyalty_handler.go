@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kasir-api/services"
+)
+
+// LoyaltyHandler handles HTTP requests for the loyalty points of customers
+type LoyaltyHandler struct {
+	service *services.LoyaltyService
+}
+
+// NewLoyaltyHandler creates a new LoyaltyHandler
+func NewLoyaltyHandler(service *services.LoyaltyService) *LoyaltyHandler {
+	return &LoyaltyHandler{service: service}
+}
+
+// RegisterRoutes registers the loyalty routes
+func (h *LoyaltyHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/customers/{id}/points", h.GetPoints)
+}
+
+// GetPoints menampilkan saldo poin pelanggan beserta riwayatnya
+// @Summary Get customer loyalty points
+// @Description Get the points balance of a customer, the Rupiah value of a point and the points ledger, newest first
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {object} models.PointsStatement
+// @Failure 400 {string} string "Invalid customer ID"
+// @Failure 404 {string} string "Customer not found"
+// @Router /customers/{id}/points [get]
+func (h *LoyaltyHandler) GetPoints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	statement, err := h.service.GetPoints(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(statement)
+}
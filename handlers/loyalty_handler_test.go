@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"kasir-api/models"
+	"kasir-api/repositories/memory"
+)
+
+func TestLoyaltyHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "points", method: http.MethodGet, target: "/api/customers/1/points", wantStatus: http.StatusOK, wantBody: `"balance":10,"point_value":10`},
+		{name: "points ledger", method: http.MethodGet, target: "/api/customers/1/points", wantStatus: http.StatusOK, wantBody: `"type":"earn","points":10,"remaining":10`},
+		{name: "points of missing customer", method: http.MethodGet, target: "/api/customers/99/points", wantStatus: http.StatusNotFound, wantBody: "Customer with ID 99 not found"},
+		{name: "points invalid id", method: http.MethodGet, target: "/api/customers/abc/points", wantStatus: http.StatusBadRequest, wantBody: "Invalid customer ID"},
+		{name: "sale earns points", method: http.MethodPost, target: "/api/transactions", body: `{"customer_id":1,"items":[{"product_id":1,"quantity":2}]}`, wantStatus: http.StatusCreated, wantBody: `"points_earned":10`},
+		{name: "sale redeems points", method: http.MethodPost, target: "/api/transactions", body: `{"customer_id":1,"redeem_points":5,"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusCreated, wantBody: `"points_redeemed":5,"points_amount":50`},
+		{name: "sale redeems too many points", method: http.MethodPost, target: "/api/transactions", body: `{"customer_id":1,"redeem_points":11,"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusBadRequest, wantBody: "insufficient loyalty points"},
+		{name: "anonymous sale redeems points", method: http.MethodPost, target: "/api/transactions", body: `{"redeem_points":1,"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusBadRequest, wantBody: "redeeming points requires a customer"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			ctx := context.Background()
+			customer, err := memory.NewCustomerRepository(h.store).Create(ctx, models.Customer{Name: "Budi"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = memory.NewTransactionRepository(h.store).Create(ctx, models.Transaction{
+				OutletID:     models.DefaultOutletID,
+				CustomerID:   &customer.ID,
+				TotalAmount:  10000,
+				PointsEarned: 10,
+				Details:      []models.TransactionDetail{{ProductID: 1, Quantity: 2, Unit: "pcs", UnitQuantity: 2, Subtotal: 10000}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.run(t, h.router)
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// OpenBillHandler handles HTTP requests for open bills
+type OpenBillHandler struct {
+	service *services.OpenBillService
+	audit   *services.AuditService
+}
+
+// NewOpenBillHandler creates a new OpenBillHandler; sales made by checking
+// out a bill and cancelled bills are recorded in the audit log
+func NewOpenBillHandler(service *services.OpenBillService, audit *services.AuditService) *OpenBillHandler {
+	return &OpenBillHandler{service: service, audit: audit}
+}
+
+// RegisterRoutes registers the open bill routes
+func (h *OpenBillHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/open-bills", h.ListOpenBills)
+	r.HandleFunc("POST /api/open-bills", h.CreateOpenBill)
+	r.HandleFunc("GET /api/open-bills/{id}", h.GetOpenBill)
+	r.HandleFunc("POST /api/open-bills/{id}/items", h.AddBillItem)
+	r.HandleFunc("PUT /api/open-bills/{id}/items/{item_id}", h.UpdateBillItem)
+	r.HandleFunc("DELETE /api/open-bills/{id}/items/{item_id}", h.RemoveBillItem)
+	r.HandleFunc("POST /api/open-bills/{id}/checkout", h.CheckoutBill)
+	r.HandleFunc("POST /api/open-bills/{id}/cancel", h.CancelBill)
+}
+
+// ListOpenBills menampilkan semua open bill dengan filter opsional
+// @Summary List all open bills
+// @Description Get the open bills of the given outlets, newest first, without their items. Without outlet_id the bills of every outlet the user is assigned to are listed.
+// @Tags open-bills
+// @Produce json
+// @Param outlet_id query []int false "Filter by outlet ID; may be repeated" collectionFormat(multi)
+// @Param status query string false "Filter by status" Enums(open, checked_out, cancelled)
+// @Success 200 {array} models.OpenBill
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Router /open-bills [get]
+func (h *OpenBillHandler) ListOpenBills(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	outletIDs, ok := outletScope(w, r)
+	if !ok {
+		return
+	}
+
+	bills, err := h.service.GetAllOpenBills(r.Context(), models.OpenBillFilter{
+		OutletIDs: outletIDs,
+		Status:    r.URL.Query().Get("status"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(bills)
+}
+
+// GetOpenBill menampilkan detail open bill berdasarkan ID
+// @Summary Get open bill by ID
+// @Description Get an open bill with its items by ID; a bill that is still open comes with its total at the current prices, left out when its items cannot be priced any more
+// @Tags open-bills
+// @Produce json
+// @Param id path int true "Open bill ID"
+// @Success 200 {object} models.OpenBill
+// @Failure 400 {string} string "Invalid open bill ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Open bill not found"
+// @Router /open-bills/{id} [get]
+func (h *OpenBillHandler) GetOpenBill(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bill, ok := h.bill(w, r)
+	if !ok {
+		return
+	}
+	h.service.PriceOpenBill(r.Context(), bill)
+
+	json.NewEncoder(w).Encode(bill)
+}
+
+// CreateOpenBill membuka open bill baru untuk meja atau nama pelanggan
+// @Summary Open a bill
+// @Description Park an order under a table number or customer name at an outlet, the first outlet of the user unless outlet_id is given, with optional first items. A bill with reserve_stock takes the stock of its items off the outlet until it is checked out or cancelled.
+// @Tags open-bills
+// @Accept json
+// @Produce json
+// @Param bill body models.OpenBill true "Outlet, label, stock reservation and items"
+// @Success 201 {object} models.OpenBill
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Router /open-bills [post]
+func (h *OpenBillHandler) CreateOpenBill(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var newBill models.OpenBill
+	if err := json.NewDecoder(r.Body).Decode(&newBill); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if newBill.OutletID == 0 {
+		newBill.OutletID = userOutlet(r)
+	}
+	if newBill.OutletID != 0 && !outletAllowed(w, r, newBill.OutletID) {
+		return
+	}
+
+	bill, err := h.service.OpenBill(r.Context(), newBill)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bill)
+}
+
+// AddBillItem menambahkan item ke open bill
+// @Summary Add an item to an open bill
+// @Description Add an item to an open bill and return the bill
+// @Tags open-bills
+// @Accept json
+// @Produce json
+// @Param id path int true "Open bill ID"
+// @Param item body models.OpenBillItem true "Product, variant, quantity, unit and note"
+// @Success 200 {object} models.OpenBill
+// @Failure 400 {string} string "Invalid request or insufficient stock"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Open bill not found"
+// @Failure 409 {string} string "Open bill is closed"
+// @Router /open-bills/{id}/items [post]
+func (h *OpenBillHandler) AddBillItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	existing, ok := h.bill(w, r)
+	if !ok {
+		return
+	}
+
+	var item models.OpenBillItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	bill, err := h.service.AddBillItem(r.Context(), existing.ID, item)
+	if err != nil {
+		billError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(bill)
+}
+
+// UpdateBillItem mengubah item pada open bill
+// @Summary Update an item of an open bill
+// @Description Replace an item of an open bill and return the bill
+// @Tags open-bills
+// @Accept json
+// @Produce json
+// @Param id path int true "Open bill ID"
+// @Param item_id path int true "Open bill item ID"
+// @Param item body models.OpenBillItem true "Product, variant, quantity, unit and note"
+// @Success 200 {object} models.OpenBill
+// @Failure 400 {string} string "Invalid request or insufficient stock"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Open bill not found"
+// @Failure 409 {string} string "Open bill is closed"
+// @Router /open-bills/{id}/items/{item_id} [put]
+func (h *OpenBillHandler) UpdateBillItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	existing, ok := h.bill(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := pathID(r, "item_id")
+	if err != nil {
+		http.Error(w, "Invalid open bill item ID", http.StatusBadRequest)
+		return
+	}
+
+	var item models.OpenBillItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	bill, err := h.service.UpdateBillItem(r.Context(), existing.ID, itemID, item)
+	if err != nil {
+		billError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(bill)
+}
+
+// RemoveBillItem menghapus item dari open bill
+// @Summary Remove an item from an open bill
+// @Description Remove an item from an open bill, giving back the stock it reserved, and return the bill
+// @Tags open-bills
+// @Produce json
+// @Param id path int true "Open bill ID"
+// @Param item_id path int true "Open bill item ID"
+// @Success 200 {object} models.OpenBill
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Open bill or item not found"
+// @Failure 409 {string} string "Open bill is closed"
+// @Router /open-bills/{id}/items/{item_id} [delete]
+func (h *OpenBillHandler) RemoveBillItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	existing, ok := h.bill(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := pathID(r, "item_id")
+	if err != nil {
+		http.Error(w, "Invalid open bill item ID", http.StatusBadRequest)
+		return
+	}
+
+	bill, err := h.service.RemoveBillItem(r.Context(), existing.ID, itemID)
+	if err != nil {
+		billError(w, err, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(bill)
+}
+
+// CheckoutBill membayar open bill menjadi transaksi
+// @Summary Check out an open bill
+// @Description Turn an open bill into a transaction at the outlet of the bill, priced at the current prices, and close the bill. The stock the bill reserved becomes the stock the sale takes.
+// @Tags open-bills
+// @Accept json
+// @Produce json
+// @Param id path int true "Open bill ID"
+// @Param checkout body models.CheckoutBillRequest false "Customer and loyalty points to redeem"
+// @Success 201 {object} models.Transaction
+// @Failure 400 {string} string "Invalid request or insufficient stock"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Open bill not found"
+// @Failure 409 {string} string "Open bill is closed or its items changed during checkout"
+// @Router /open-bills/{id}/checkout [post]
+func (h *OpenBillHandler) CheckoutBill(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bill, ok := h.bill(w, r)
+	if !ok {
+		return
+	}
+
+	// The body is optional: an anonymous sale needs none
+	var req models.CheckoutBillRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	transaction, err := h.service.CheckoutBill(r.Context(), bill.ID, req)
+	if err != nil {
+		billError(w, err, http.StatusBadRequest)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditCreate, "transaction", transaction.ID, nil, transaction)
+	h.recordBillClosed(r, bill)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// CancelBill membatalkan open bill
+// @Summary Cancel an open bill
+// @Description Close an open bill without a sale, giving back the stock it reserved
+// @Tags open-bills
+// @Produce json
+// @Param id path int true "Open bill ID"
+// @Success 200 {string} string "Open bill cancelled successfully"
+// @Failure 400 {string} string "Invalid open bill ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Open bill not found"
+// @Failure 409 {string} string "Open bill is closed"
+// @Router /open-bills/{id}/cancel [post]
+func (h *OpenBillHandler) CancelBill(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bill, ok := h.bill(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelBill(r.Context(), bill.ID); err != nil {
+		billError(w, err, http.StatusNotFound)
+		return
+	}
+	h.recordBillClosed(r, bill)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Open bill cancelled successfully"})
+}
+
+// bill loads the open bill of the request path without pricing it; it
+// answers 400, 403 or 404 and returns false when the ID is invalid, the bill
+// does not exist or the user has no access to its outlet
+func (h *OpenBillHandler) bill(w http.ResponseWriter, r *http.Request) (*models.OpenBill, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid open bill ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	bill, err := h.service.LookupOpenBill(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if !outletAllowed(w, r, bill.OutletID) {
+		return nil, false
+	}
+	return bill, true
+}
+
+// recordBillClosed records in the audit log that a bill was checked out or
+// cancelled, giving back or selling the stock it reserved, with the bill as
+// stored once it was closed
+func (h *OpenBillHandler) recordBillClosed(r *http.Request, before *models.OpenBill) {
+	after, err := h.service.LookupOpenBill(r.Context(), before.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "reading closed open bill failed", slog.Int("open_bill_id", before.ID), slog.Any("error", err))
+		return
+	}
+	recordAudit(r, h.audit, models.AuditUpdate, "open_bill", before.ID, before, after)
+}
+
+// billError answers 409 when an open bill was already closed or changed while
+// it was checked out and status otherwise
+func billError(w http.ResponseWriter, err error, status int) {
+	if errors.Is(err, services.ErrBillClosed) || errors.Is(err, services.ErrBillChanged) {
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"kasir-api/middleware"
+)
+
+func TestOpenBillHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "list", method: http.MethodGet, target: "/api/open-bills", wantStatus: http.StatusOK, wantBody: `"label":"Meja 1","status":"open"`},
+		{name: "list by status", method: http.MethodGet, target: "/api/open-bills?status=cancelled", wantStatus: http.StatusOK, wantBody: "null"},
+		{name: "list unknown status", method: http.MethodGet, target: "/api/open-bills?status=paid", wantStatus: http.StatusBadRequest, wantBody: `unknown open bill status "paid"`},
+		{name: "get", method: http.MethodGet, target: "/api/open-bills/1", wantStatus: http.StatusOK, wantBody: `"items":[{"id":1,"product_id":1,"quantity":2,"unit":"pcs","reserved":[{"product_id":1,"quantity":2}]}],"total_amount":10000`},
+		{name: "get unknown", method: http.MethodGet, target: "/api/open-bills/9", wantStatus: http.StatusNotFound, wantBody: "Open bill with ID 9 not found"},
+		{name: "get invalid ID", method: http.MethodGet, target: "/api/open-bills/abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid open bill ID"},
+		{name: "open without label", method: http.MethodPost, target: "/api/open-bills", body: `{"label":" "}`, wantStatus: http.StatusBadRequest, wantBody: "open bill label is required"},
+		{name: "open same label", method: http.MethodPost, target: "/api/open-bills", body: `{"label":"meja 1"}`, wantStatus: http.StatusBadRequest, wantBody: "already exists"},
+		{name: "open invalid body", method: http.MethodPost, target: "/api/open-bills", body: `{`, wantStatus: http.StatusBadRequest, wantBody: "Invalid request body"},
+		{name: "add item", method: http.MethodPost, target: "/api/open-bills/1/items", body: `{"product_id":2,"variant_id":1,"quantity":1,"note":"less ice"}`, wantStatus: http.StatusOK, wantBody: `"note":"less ice"`},
+		{name: "add item beyond stock", method: http.MethodPost, target: "/api/open-bills/1/items", body: `{"product_id":1,"quantity":9}`, wantStatus: http.StatusBadRequest, wantBody: "insufficient stock"},
+		{name: "update item", method: http.MethodPut, target: "/api/open-bills/1/items/1", body: `{"product_id":1,"quantity":3}`, wantStatus: http.StatusOK, wantBody: `"total_amount":15000`},
+		{name: "update unknown item", method: http.MethodPut, target: "/api/open-bills/1/items/9", body: `{"product_id":1,"quantity":3}`, wantStatus: http.StatusBadRequest, wantBody: "Open bill item with ID 9 not found"},
+		{name: "remove item", method: http.MethodDelete, target: "/api/open-bills/1/items/1", wantStatus: http.StatusOK, wantBody: `"status":"open"`},
+		{name: "remove invalid item ID", method: http.MethodDelete, target: "/api/open-bills/1/items/abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid open bill item ID"},
+		{name: "checkout", method: http.MethodPost, target: "/api/open-bills/1/checkout", wantStatus: http.StatusCreated, wantBody: `"total_amount":10000`},
+		{name: "checkout unknown customer", method: http.MethodPost, target: "/api/open-bills/1/checkout", body: `{"customer_id":9}`, wantStatus: http.StatusBadRequest, wantBody: "Customer with ID 9 not found"},
+		{name: "cancel", method: http.MethodPost, target: "/api/open-bills/1/cancel", wantStatus: http.StatusOK, wantBody: "Open bill cancelled successfully"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := []handlerCase{
+				{method: http.MethodPost, target: "/api/open-bills", body: `{"label":"Meja 1","reserve_stock":true,"items":[{"product_id":1,"quantity":2}]}`, wantStatus: http.StatusCreated},
+			}
+			for _, s := range seed {
+				s.run(t, h.router)
+			}
+
+			tc.run(t, h.router)
+		})
+	}
+}
+
+func TestOpenBillHandlerLifecycle(t *testing.T) {
+	h := newTestHandlers(t)
+
+	steps := []handlerCase{
+		{name: "open", method: http.MethodPost, target: "/api/open-bills", body: `{"label":"Meja 7","reserve_stock":true}`, wantStatus: http.StatusCreated, wantBody: `"status":"open"`},
+		{name: "add bundle", method: http.MethodPost, target: "/api/open-bills/1/items", body: `{"product_id":3,"quantity":2}`, wantStatus: http.StatusOK, wantBody: `"reserved":[{"product_id":1,"quantity":4}]`},
+		{name: "stock reserved", method: http.MethodGet, target: "/api/outlets/1/products", wantStatus: http.StatusOK, wantBody: `"product_id":1,"stock":6`},
+		{name: "sale beyond the rest", method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":7}]}`, wantStatus: http.StatusBadRequest, wantBody: "insufficient stock"},
+		{name: "checkout", method: http.MethodPost, target: "/api/open-bills/1/checkout", body: `{}`, wantStatus: http.StatusCreated, wantBody: `"total_amount":18000`},
+		{name: "stock sold", method: http.MethodGet, target: "/api/outlets/1/products", wantStatus: http.StatusOK, wantBody: `"product_id":1,"stock":6`},
+		{name: "checked out", method: http.MethodGet, target: "/api/open-bills/1", wantStatus: http.StatusOK, wantBody: `"status":"checked_out"`},
+		{name: "add after checkout", method: http.MethodPost, target: "/api/open-bills/1/items", body: `{"product_id":1,"quantity":1}`, wantStatus: http.StatusConflict, wantBody: "open bill with ID 1 is checked_out"},
+		{name: "cancel after checkout", method: http.MethodPost, target: "/api/open-bills/1/cancel", wantStatus: http.StatusConflict, wantBody: "open bill is closed"},
+		{name: "reopen table", method: http.MethodPost, target: "/api/open-bills", body: `{"label":"Meja 7"}`, wantStatus: http.StatusCreated},
+	}
+	for _, step := range steps {
+		step.run(t, h.router)
+	}
+}
+
+func TestOpenBillHandlerUnpriceable(t *testing.T) {
+	h := newTestHandlers(t)
+
+	// A bill whose items can no longer be priced can still be read and
+	// cancelled; only its checkout fails
+	steps := []handlerCase{
+		{name: "open", method: http.MethodPost, target: "/api/open-bills", body: `{"label":"Meja 2","reserve_stock":true,"items":[{"product_id":1,"quantity":1,"unit":"box"}]}`, wantStatus: http.StatusCreated, wantBody: `"total_amount":30000`},
+		{name: "remove box", method: http.MethodDelete, target: "/api/products/1/conversions/box", wantStatus: http.StatusOK},
+		{name: "get", method: http.MethodGet, target: "/api/open-bills/1", wantStatus: http.StatusOK, wantBody: `"unit":"box","reserved":[{"product_id":1,"quantity":6}]}],"created_at"`},
+		{name: "checkout", method: http.MethodPost, target: "/api/open-bills/1/checkout", wantStatus: http.StatusBadRequest, wantBody: "box"},
+		{name: "remove item", method: http.MethodDelete, target: "/api/open-bills/1/items/9", wantStatus: http.StatusNotFound, wantBody: "Open bill item with ID 9 not found"},
+		{name: "cancel", method: http.MethodPost, target: "/api/open-bills/1/cancel", wantStatus: http.StatusOK, wantBody: "Open bill cancelled successfully"},
+		{name: "stock back", method: http.MethodGet, target: "/api/outlets/1/products", wantStatus: http.StatusOK, wantBody: `"product_id":1,"stock":10`},
+	}
+	for _, step := range steps {
+		step.run(t, h.router)
+	}
+}
+
+func TestOpenBillHandlerAccess(t *testing.T) {
+	const secret = "secret"
+	token := func(outlets ...int) map[string]string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{Outlets: outlets}).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]string{"Authorization": "Bearer " + signed}
+	}
+	main, branch := token(1), token(2)
+
+	tests := []handlerCase{
+		{name: "own outlet", method: http.MethodGet, target: "/api/open-bills/1", header: main, wantStatus: http.StatusOK},
+		{name: "other outlet", method: http.MethodGet, target: "/api/open-bills/1", header: branch, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "other outlet list", method: http.MethodGet, target: "/api/open-bills", header: branch, wantStatus: http.StatusOK, wantBody: "null"},
+		{name: "other outlet checkout", method: http.MethodPost, target: "/api/open-bills/1/checkout", header: branch, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "open at own outlet", method: http.MethodPost, target: "/api/open-bills", body: `{"label":"Meja 1"}`, header: branch, wantStatus: http.StatusCreated, wantBody: `"outlet_id":2`},
+		{name: "open at other outlet", method: http.MethodPost, target: "/api/open-bills", body: `{"outlet_id":1,"label":"Meja 2"}`, header: branch, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := []handlerCase{
+				{method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG1","name":"Cabang 1"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPost, target: "/api/open-bills", body: `{"label":"Meja 1","items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusCreated},
+			}
+			for _, s := range seed {
+				s.run(t, h.router)
+			}
+
+			tc.run(t, middleware.Auth(secret)(h.router))
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"kasir-api/middleware"
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// OutletHandler handles HTTP requests for outlets and their stock and prices
+type OutletHandler struct {
+	service *services.OutletService
+}
+
+// NewOutletHandler creates a new OutletHandler
+func NewOutletHandler(service *services.OutletService) *OutletHandler {
+	return &OutletHandler{service: service}
+}
+
+// RegisterRoutes registers the outlet routes, including the stock and price
+// overrides of each outlet
+func (h *OutletHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/outlets", h.ListOutlets)
+	r.HandleFunc("POST /api/outlets", h.CreateOutlet)
+	r.HandleFunc("GET /api/outlets/{id}", h.GetOutlet)
+	r.HandleFunc("PUT /api/outlets/{id}", h.UpdateOutlet)
+	r.HandleFunc("DELETE /api/outlets/{id}", h.DeleteOutlet)
+	r.HandleFunc("GET /api/outlets/{id}/products", h.GetOutletProducts)
+	r.HandleFunc("PUT /api/outlets/{id}/products/{product_id}", h.SetOutletProduct)
+}
+
+// ListOutlets menampilkan semua outlet yang boleh diakses pengguna
+// @Summary List all outlets
+// @Description Get the outlets the user is assigned to, or all outlets for owners
+// @Tags outlets
+// @Produce json
+// @Success 200 {array} models.Outlet
+// @Router /outlets [get]
+func (h *OutletHandler) ListOutlets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	outlets, err := h.service.GetAllOutlets(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(r.Context())
+	allowed := make([]models.Outlet, 0, len(outlets))
+	for _, o := range outlets {
+		if claims.OutletAllowed(o.ID) {
+			allowed = append(allowed, o)
+		}
+	}
+	json.NewEncoder(w).Encode(allowed)
+}
+
+// GetOutlet menampilkan detail outlet berdasarkan ID
+// @Summary Get outlet by ID
+// @Description Get outlet details by ID
+// @Tags outlets
+// @Produce json
+// @Param id path int true "Outlet ID"
+// @Success 200 {object} models.Outlet
+// @Failure 400 {string} string "Invalid outlet ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Outlet not found"
+// @Router /outlets/{id} [get]
+func (h *OutletHandler) GetOutlet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid outlet ID", http.StatusBadRequest)
+		return
+	}
+	if !outletAllowed(w, r, id) {
+		return
+	}
+
+	outlet, err := h.service.GetOutletByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(outlet)
+}
+
+// CreateOutlet membuat outlet baru
+// @Summary Create a new outlet
+// @Description Create a new outlet; the code must be unique. Only users with access to every outlet may create outlets.
+// @Tags outlets
+// @Accept json
+// @Produce json
+// @Param outlet body models.Outlet true "Outlet object"
+// @Success 201 {object} models.Outlet
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Router /outlets [post]
+func (h *OutletHandler) CreateOutlet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	var newOutlet models.Outlet
+	if err := json.NewDecoder(r.Body).Decode(&newOutlet); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	outlet, err := h.service.CreateOutlet(r.Context(), newOutlet)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(outlet)
+}
+
+// UpdateOutlet mengupdate outlet berdasarkan ID
+// @Summary Update an outlet
+// @Description Update outlet by ID. Only users with access to every outlet may update outlets.
+// @Tags outlets
+// @Accept json
+// @Produce json
+// @Param id path int true "Outlet ID"
+// @Param outlet body models.Outlet true "Outlet object"
+// @Success 200 {object} models.Outlet
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Outlet not found"
+// @Router /outlets/{id} [put]
+func (h *OutletHandler) UpdateOutlet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid outlet ID", http.StatusBadRequest)
+		return
+	}
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	var updatedOutlet models.Outlet
+	if err := json.NewDecoder(r.Body).Decode(&updatedOutlet); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	outlet, err := h.service.UpdateOutlet(r.Context(), id, updatedOutlet)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(outlet)
+}
+
+// DeleteOutlet menghapus outlet berdasarkan ID
+// @Summary Delete an outlet
+// @Description Delete an outlet without sales by ID together with its stock; the main outlet cannot be deleted. Only users with access to every outlet may delete outlets.
+// @Tags outlets
+// @Produce json
+// @Param id path int true "Outlet ID"
+// @Success 200 {string} string "Outlet deleted successfully"
+// @Failure 400 {string} string "Invalid outlet ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Outlet not found"
+// @Router /outlets/{id} [delete]
+func (h *OutletHandler) DeleteOutlet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid outlet ID", http.StatusBadRequest)
+		return
+	}
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	if err := h.service.DeleteOutlet(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Outlet deleted successfully"})
+}
+
+// GetOutletProducts menampilkan stok dan harga khusus di outlet
+// @Summary Get outlet stock
+// @Description Get the stock of the products and variants at an outlet with the prices that override the catalog prices there
+// @Tags outlets
+// @Produce json
+// @Param id path int true "Outlet ID"
+// @Success 200 {array} models.OutletProduct
+// @Failure 400 {string} string "Invalid outlet ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Outlet not found"
+// @Router /outlets/{id}/products [get]
+func (h *OutletHandler) GetOutletProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid outlet ID", http.StatusBadRequest)
+		return
+	}
+	if !outletAllowed(w, r, id) {
+		return
+	}
+
+	products, err := h.service.GetOutletProducts(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(products)
+}
+
+// SetOutletProduct mengatur stok dan harga khusus produk di outlet
+// @Summary Set outlet stock and price
+// @Description Set the stock of a product, or of one of its variants, at an outlet and the price that overrides the catalog price there; leave out price to sell at the catalog price. The total stock of the product changes by the difference.
+// @Tags outlets
+// @Accept json
+// @Produce json
+// @Param id path int true "Outlet ID"
+// @Param product_id path int true "Product ID"
+// @Param product body models.OutletProduct true "Stock, optional variant and price override"
+// @Success 200 {object} models.OutletProduct
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Router /outlets/{id}/products/{product_id} [put]
+func (h *OutletHandler) SetOutletProduct(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid outlet ID", http.StatusBadRequest)
+		return
+	}
+	productID, err := pathID(r, "product_id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	if !outletAllowed(w, r, id) {
+		return
+	}
+
+	var product models.OutletProduct
+	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	product.OutletID = id
+	product.ProductID = productID
+
+	updated, err := h.service.SetOutletProduct(r.Context(), product)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// outletAllowed checks the user has access to an outlet and answers 403
+// when they do not
+func outletAllowed(w http.ResponseWriter, r *http.Request, id int) bool {
+	if !middleware.ClaimsFromContext(r.Context()).OutletAllowed(id) {
+		http.Error(w, "Outlet not allowed", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// allOutletsAllowed checks the user has access to every outlet and answers
+// 403 when they do not
+func allOutletsAllowed(w http.ResponseWriter, r *http.Request) bool {
+	if !middleware.ClaimsFromContext(r.Context()).AllOutlets() {
+		http.Error(w, "Outlet not allowed", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// userOutlet returns the outlet a request that names none takes place at:
+// the first outlet of the user, or 0 for the main outlet when the user has
+// access to every outlet
+func userOutlet(r *http.Request) int {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims.AllOutlets() {
+		return 0
+	}
+	return claims.Outlets[0]
+}
+
+// actorContext returns the request context carrying the user making the
+// request, which the services record with what they change
+func actorContext(r *http.Request) context.Context {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		return r.Context()
+	}
+	return services.WithActor(r.Context(), claims.Subject)
+}
+
+// outletScope returns the outlets a report or listing covers: the outlet_id
+// query values, which the user must have access to, or else the outlets of
+// the user, nil being every outlet. It answers 400 or 403 and returns false
+// when the scope is invalid or not allowed.
+func outletScope(w http.ResponseWriter, r *http.Request) ([]int, bool) {
+	values := r.URL.Query()["outlet_id"]
+	if len(values) == 0 {
+		claims := middleware.ClaimsFromContext(r.Context())
+		if claims.AllOutlets() {
+			return nil, true
+		}
+		return claims.Outlets, true
+	}
+
+	ids := make([]int, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid outlet ID", http.StatusBadRequest)
+			return nil, false
+		}
+		if !outletAllowed(w, r, id) {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
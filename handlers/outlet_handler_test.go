@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"kasir-api/middleware"
+)
+
+func TestOutletHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "list", method: http.MethodGet, target: "/api/outlets", wantStatus: http.StatusOK, wantBody: `"code":"PUSAT"`},
+		{name: "get", method: http.MethodGet, target: "/api/outlets/1", wantStatus: http.StatusOK, wantBody: `"name":"Outlet Pusat"`},
+		{name: "get unknown", method: http.MethodGet, target: "/api/outlets/9", wantStatus: http.StatusNotFound, wantBody: "Outlet with ID 9 not found"},
+		{name: "get invalid ID", method: http.MethodGet, target: "/api/outlets/abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid outlet ID"},
+		{name: "create", method: http.MethodPost, target: "/api/outlets", body: `{"code":"cbg2","name":"Cabang 2"}`, wantStatus: http.StatusCreated, wantBody: `"code":"CBG2"`},
+		{name: "create taken code", method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG1","name":"Cabang Lain"}`, wantStatus: http.StatusBadRequest, wantBody: "already exists"},
+		{name: "create without name", method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG2"}`, wantStatus: http.StatusBadRequest, wantBody: "outlet name is required"},
+		{name: "create invalid body", method: http.MethodPost, target: "/api/outlets", body: `{`, wantStatus: http.StatusBadRequest, wantBody: "Invalid request body"},
+		{name: "update", method: http.MethodPut, target: "/api/outlets/2", body: `{"code":"CBG1","name":"Cabang Satu","address":"Jl. Merdeka 1"}`, wantStatus: http.StatusOK, wantBody: `"address":"Jl. Merdeka 1"`},
+		{name: "update unknown", method: http.MethodPut, target: "/api/outlets/9", body: `{"code":"CBG9","name":"Cabang 9"}`, wantStatus: http.StatusNotFound, wantBody: "Outlet with ID 9 not found"},
+		{name: "delete", method: http.MethodDelete, target: "/api/outlets/2", wantStatus: http.StatusOK, wantBody: "Outlet deleted successfully"},
+		{name: "delete main outlet", method: http.MethodDelete, target: "/api/outlets/1", wantStatus: http.StatusNotFound, wantBody: "the main outlet cannot be deleted"},
+		{name: "stock", method: http.MethodGet, target: "/api/outlets/2/products", wantStatus: http.StatusOK, wantBody: `[{"outlet_id":2,"product_id":1,"stock":4,"price":6000}]`},
+		{name: "stock of unknown outlet", method: http.MethodGet, target: "/api/outlets/9/products", wantStatus: http.StatusNotFound, wantBody: "Outlet with ID 9 not found"},
+		{name: "set variant stock", method: http.MethodPut, target: "/api/outlets/2/products/2", body: `{"variant_id":1,"stock":2}`, wantStatus: http.StatusOK, wantBody: `"variant_id":1,"stock":2`},
+		{name: "set negative stock", method: http.MethodPut, target: "/api/outlets/2/products/1", body: `{"stock":-1}`, wantStatus: http.StatusBadRequest, wantBody: "stock must not be negative"},
+		{name: "set unknown product", method: http.MethodPut, target: "/api/outlets/2/products/99", body: `{"stock":1}`, wantStatus: http.StatusBadRequest, wantBody: "Product with ID 99 not found"},
+		{name: "set invalid product ID", method: http.MethodPut, target: "/api/outlets/2/products/abc", body: `{"stock":1}`, wantStatus: http.StatusBadRequest, wantBody: "Invalid product ID"},
+		{name: "sale at outlet price", method: http.MethodPost, target: "/api/transactions", body: `{"outlet_id":2,"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusCreated, wantBody: `"outlet_id":2,"total_amount":6000`},
+		{name: "sale beyond outlet stock", method: http.MethodPost, target: "/api/transactions", body: `{"outlet_id":2,"items":[{"product_id":1,"quantity":5}]}`, wantStatus: http.StatusBadRequest, wantBody: "insufficient stock"},
+		{name: "report per outlet", method: http.MethodGet, target: "/api/report/hari-ini?outlet_id=2", wantStatus: http.StatusOK, wantBody: `"total_revenue":0`},
+		{name: "report invalid outlet", method: http.MethodGet, target: "/api/report/hari-ini?outlet_id=abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid outlet ID"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := []handlerCase{
+				{method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG1","name":"Cabang 1"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPut, target: "/api/outlets/2/products/1", body: `{"stock":4,"price":6000}`, wantStatus: http.StatusOK},
+			}
+			for _, s := range seed {
+				s.run(t, h.router)
+			}
+
+			tc.run(t, h.router)
+		})
+	}
+}
+
+func TestOutletHandlerAccess(t *testing.T) {
+	const secret = "secret"
+	token := func(claims middleware.Claims) map[string]string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]string{"Authorization": "Bearer " + signed}
+	}
+	cashier := token(middleware.Claims{Outlets: []int{2}})
+	owner := token(middleware.Claims{Role: middleware.RoleOwner, Outlets: []int{2}})
+
+	tests := []handlerCase{
+		{name: "list only assigned outlets", method: http.MethodGet, target: "/api/outlets", header: cashier, wantStatus: http.StatusOK, wantBody: `[{"id":2,`},
+		{name: "get other outlet", method: http.MethodGet, target: "/api/outlets/1", header: cashier, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "create outlet", method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG2","name":"Cabang 2"}`, header: cashier, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "create outlet as owner", method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG2","name":"Cabang 2"}`, header: owner, wantStatus: http.StatusCreated},
+		{name: "stock of other outlet", method: http.MethodPut, target: "/api/outlets/1/products/1", body: `{"stock":1}`, header: cashier, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "sale at own outlet by default", method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":1}]}`, header: cashier, wantStatus: http.StatusCreated, wantBody: `"outlet_id":2`},
+		{name: "sale at other outlet", method: http.MethodPost, target: "/api/transactions", body: `{"outlet_id":1,"items":[{"product_id":1,"quantity":1}]}`, header: cashier, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "transaction of other outlet", method: http.MethodGet, target: "/api/transactions/1", header: cashier, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "delete transaction of other outlet", method: http.MethodDelete, target: "/api/transactions/1", header: cashier, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "list own transactions", method: http.MethodGet, target: "/api/transactions", header: cashier, wantStatus: http.StatusOK, wantBody: "null"},
+		{name: "report of own outlets", method: http.MethodGet, target: "/api/report/hari-ini", header: cashier, wantStatus: http.StatusOK, wantBody: `"total_revenue":0`},
+		{name: "report of other outlet", method: http.MethodGet, target: "/api/report/hari-ini?outlet_id=1", header: cashier, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "rollup as owner", method: http.MethodGet, target: "/api/report/hari-ini", header: owner, wantStatus: http.StatusOK, wantBody: `"total_revenue":5000`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := []handlerCase{
+				{method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG1","name":"Cabang 1"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPut, target: "/api/outlets/2/products/1", body: `{"stock":4}`, wantStatus: http.StatusOK},
+				{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusCreated},
+			}
+			for _, s := range seed {
+				s.run(t, h.router)
+			}
+
+			tc.run(t, middleware.Auth(secret)(h.router))
+		})
+	}
+}
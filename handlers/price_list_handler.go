@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// PriceListHandler handles HTTP requests for price lists and price rules
+type PriceListHandler struct {
+	service *services.PriceListService
+}
+
+// NewPriceListHandler creates a new PriceListHandler
+func NewPriceListHandler(service *services.PriceListService) *PriceListHandler {
+	return &PriceListHandler{service: service}
+}
+
+// RegisterRoutes registers the price list and price rule routes
+func (h *PriceListHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/price-lists", h.ListPriceLists)
+	r.HandleFunc("POST /api/price-lists", h.CreatePriceList)
+	r.HandleFunc("GET /api/price-lists/{id}", h.GetPriceList)
+	r.HandleFunc("PUT /api/price-lists/{id}", h.UpdatePriceList)
+	r.HandleFunc("DELETE /api/price-lists/{id}", h.DeletePriceList)
+	r.HandleFunc("GET /api/price-rules", h.ListPriceRules)
+	r.HandleFunc("POST /api/price-rules", h.CreatePriceRule)
+	r.HandleFunc("GET /api/price-rules/{id}", h.GetPriceRule)
+	r.HandleFunc("PUT /api/price-rules/{id}", h.UpdatePriceRule)
+	r.HandleFunc("DELETE /api/price-rules/{id}", h.DeletePriceRule)
+}
+
+// ListPriceLists menampilkan semua daftar harga
+// @Summary List all price lists
+// @Description Get all price lists, such as wholesale or member prices
+// @Tags price-lists
+// @Produce json
+// @Success 200 {array} models.PriceList
+// @Router /price-lists [get]
+func (h *PriceListHandler) ListPriceLists(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	lists, err := h.service.GetAllPriceLists(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(lists)
+}
+
+// GetPriceList menampilkan detail daftar harga berdasarkan ID
+// @Summary Get price list by ID
+// @Description Get price list details by ID
+// @Tags price-lists
+// @Produce json
+// @Param id path int true "Price list ID"
+// @Success 200 {object} models.PriceList
+// @Failure 400 {string} string "Invalid price list ID"
+// @Failure 404 {string} string "Price list not found"
+// @Router /price-lists/{id} [get]
+func (h *PriceListHandler) GetPriceList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price list ID", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.service.GetPriceListByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreatePriceList membuat daftar harga baru
+// @Summary Create a new price list
+// @Description Create a new price list; the code must be unique. Only users with access to every outlet may change prices.
+// @Tags price-lists
+// @Accept json
+// @Produce json
+// @Param price_list body models.PriceList true "Price list object"
+// @Success 201 {object} models.PriceList
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Router /price-lists [post]
+func (h *PriceListHandler) CreatePriceList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	var newList models.PriceList
+	if err := json.NewDecoder(r.Body).Decode(&newList); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.service.CreatePriceList(r.Context(), newList)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(list)
+}
+
+// UpdatePriceList mengupdate daftar harga berdasarkan ID
+// @Summary Update a price list
+// @Description Update price list by ID. Only users with access to every outlet may change prices.
+// @Tags price-lists
+// @Accept json
+// @Produce json
+// @Param id path int true "Price list ID"
+// @Param price_list body models.PriceList true "Price list object"
+// @Success 200 {object} models.PriceList
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Price list not found"
+// @Router /price-lists/{id} [put]
+func (h *PriceListHandler) UpdatePriceList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price list ID", http.StatusBadRequest)
+		return
+	}
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	var updatedList models.PriceList
+	if err := json.NewDecoder(r.Body).Decode(&updatedList); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.service.UpdatePriceList(r.Context(), id, updatedList)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(list)
+}
+
+// DeletePriceList menghapus daftar harga berdasarkan ID
+// @Summary Delete a price list
+// @Description Delete a price list by ID together with its rules; its customers buy at retail prices again. Only users with access to every outlet may change prices.
+// @Tags price-lists
+// @Produce json
+// @Param id path int true "Price list ID"
+// @Success 200 {string} string "Price list deleted successfully"
+// @Failure 400 {string} string "Invalid price list ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Price list not found"
+// @Router /price-lists/{id} [delete]
+func (h *PriceListHandler) DeletePriceList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price list ID", http.StatusBadRequest)
+		return
+	}
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	if err := h.service.DeletePriceList(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Price list deleted successfully"})
+}
+
+// ListPriceRules menampilkan aturan harga, bisa difilter per produk dan daftar harga
+// @Summary List price rules
+// @Description Get the price rules, optionally of one product or one price list
+// @Tags price-lists
+// @Produce json
+// @Param product_id query int false "Filter by product ID"
+// @Param price_list_id query int false "Filter by price list ID"
+// @Success 200 {array} models.PriceRule
+// @Router /price-rules [get]
+func (h *PriceListHandler) ListPriceRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var filter models.PriceRuleFilter
+	if productID := r.URL.Query().Get("product_id"); productID != "" {
+		if id, err := strconv.Atoi(productID); err == nil {
+			filter.ProductID = id
+		}
+	}
+	if priceListID := r.URL.Query().Get("price_list_id"); priceListID != "" {
+		if id, err := strconv.Atoi(priceListID); err == nil {
+			filter.PriceListID = id
+		}
+	}
+
+	rules, err := h.service.GetPriceRules(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rules)
+}
+
+// GetPriceRule menampilkan detail aturan harga berdasarkan ID
+// @Summary Get price rule by ID
+// @Description Get price rule details by ID
+// @Tags price-lists
+// @Produce json
+// @Param id path int true "Price rule ID"
+// @Success 200 {object} models.PriceRule
+// @Failure 400 {string} string "Invalid price rule ID"
+// @Failure 404 {string} string "Price rule not found"
+// @Router /price-rules/{id} [get]
+func (h *PriceListHandler) GetPriceRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price rule ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.GetPriceRuleByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+// CreatePriceRule membuat aturan harga baru
+// @Summary Create a new price rule
+// @Description Create a price rule for a product or variant: a price list price (or a retail price without price_list_id), a quantity break from min_quantity, a scheduled price from starts_at or a happy hour between daily_from and daily_to ("15:00"). Only users with access to every outlet may change prices.
+// @Tags price-lists
+// @Accept json
+// @Produce json
+// @Param price_rule body models.PriceRule true "Price rule object"
+// @Success 201 {object} models.PriceRule
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Router /price-rules [post]
+func (h *PriceListHandler) CreatePriceRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	var newRule models.PriceRule
+	if err := json.NewDecoder(r.Body).Decode(&newRule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.CreatePriceRule(r.Context(), newRule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdatePriceRule mengupdate aturan harga berdasarkan ID
+// @Summary Update a price rule
+// @Description Update price rule by ID; sales already made keep their prices. Only users with access to every outlet may change prices.
+// @Tags price-lists
+// @Accept json
+// @Produce json
+// @Param id path int true "Price rule ID"
+// @Param price_rule body models.PriceRule true "Price rule object"
+// @Success 200 {object} models.PriceRule
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Price rule not found"
+// @Router /price-rules/{id} [put]
+func (h *PriceListHandler) UpdatePriceRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price rule ID", http.StatusBadRequest)
+		return
+	}
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	var updatedRule models.PriceRule
+	if err := json.NewDecoder(r.Body).Decode(&updatedRule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.UpdatePriceRule(r.Context(), id, updatedRule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeletePriceRule menghapus aturan harga berdasarkan ID
+// @Summary Delete a price rule
+// @Description Delete a price rule by ID; sales already made keep their prices. Only users with access to every outlet may change prices.
+// @Tags price-lists
+// @Produce json
+// @Param id path int true "Price rule ID"
+// @Success 200 {string} string "Price rule deleted successfully"
+// @Failure 400 {string} string "Invalid price rule ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Price rule not found"
+// @Router /price-rules/{id} [delete]
+func (h *PriceListHandler) DeletePriceRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price rule ID", http.StatusBadRequest)
+		return
+	}
+	if !allOutletsAllowed(w, r) {
+		return
+	}
+
+	if err := h.service.DeletePriceRule(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Price rule deleted successfully"})
+}
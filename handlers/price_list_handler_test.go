@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"kasir-api/middleware"
+)
+
+func TestPriceListHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "list", method: http.MethodGet, target: "/api/price-lists", wantStatus: http.StatusOK, wantBody: `"code":"GROSIR","name":"Grosir"`},
+		{name: "get", method: http.MethodGet, target: "/api/price-lists/1", wantStatus: http.StatusOK, wantBody: `"code":"GROSIR"`},
+		{name: "get unknown", method: http.MethodGet, target: "/api/price-lists/9", wantStatus: http.StatusNotFound, wantBody: "Price list with ID 9 not found"},
+		{name: "get invalid ID", method: http.MethodGet, target: "/api/price-lists/abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid price list ID"},
+		{name: "create same code", method: http.MethodPost, target: "/api/price-lists", body: `{"code":"grosir","name":"Grosir 2"}`, wantStatus: http.StatusBadRequest, wantBody: "already exists"},
+		{name: "create without name", method: http.MethodPost, target: "/api/price-lists", body: `{"code":"MEMBER"}`, wantStatus: http.StatusBadRequest, wantBody: "price list name is required"},
+		{name: "update", method: http.MethodPut, target: "/api/price-lists/1", body: `{"code":"GROSIR","name":"Harga Grosir"}`, wantStatus: http.StatusOK, wantBody: `"name":"Harga Grosir"`},
+		{name: "delete", method: http.MethodDelete, target: "/api/price-lists/1", wantStatus: http.StatusOK, wantBody: "Price list deleted successfully"},
+		{name: "list rules", method: http.MethodGet, target: "/api/price-rules?product_id=1", wantStatus: http.StatusOK, wantBody: `"price_list_id":1,"product_id":1,"min_quantity":6,"price":4000`},
+		{name: "list rules of other product", method: http.MethodGet, target: "/api/price-rules?product_id=2", wantStatus: http.StatusOK, wantBody: `"daily_from":"15:00","daily_to":"17:00"`},
+		{name: "get rule unknown", method: http.MethodGet, target: "/api/price-rules/9", wantStatus: http.StatusNotFound, wantBody: "Price rule with ID 9 not found"},
+		{name: "create rule with half a window", method: http.MethodPost, target: "/api/price-rules", body: `{"product_id":1,"price":1,"daily_to":"17:00"}`, wantStatus: http.StatusBadRequest, wantBody: "must be set together"},
+		{name: "update rule", method: http.MethodPut, target: "/api/price-rules/1", body: `{"price_list_id":1,"product_id":1,"min_quantity":12,"price":3800}`, wantStatus: http.StatusOK, wantBody: `"min_quantity":12,"price":3800`},
+		{name: "delete rule", method: http.MethodDelete, target: "/api/price-rules/1", wantStatus: http.StatusOK, wantBody: "Price rule deleted successfully"},
+		{name: "sale at retail price", method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":6}]}`, wantStatus: http.StatusCreated, wantBody: `"total_amount":30000`},
+		{name: "sale at wholesale tier", method: http.MethodPost, target: "/api/transactions", body: `{"price_list_id":1,"items":[{"product_id":1,"quantity":6}]}`, wantStatus: http.StatusCreated, wantBody: `"subtotal":24000,"price_rule_id":1}`},
+		{name: "sale for wholesale customer", method: http.MethodPost, target: "/api/transactions", body: `{"customer_id":1,"items":[{"product_id":1,"quantity":1,"unit":"box"}]}`, wantStatus: http.StatusCreated, wantBody: `"price_list_id":1,"total_amount":24000`},
+		{name: "sale with unknown price list", method: http.MethodPost, target: "/api/transactions", body: `{"price_list_id":9,"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusBadRequest, wantBody: "Price list with ID 9 not found"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := []handlerCase{
+				{method: http.MethodPost, target: "/api/price-lists", body: `{"code":"GROSIR","name":"Grosir"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPost, target: "/api/price-rules", body: `{"price_list_id":1,"product_id":1,"min_quantity":6,"price":4000}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPost, target: "/api/price-rules", body: `{"product_id":2,"variant_id":1,"price":4000,"daily_from":"15:00","daily_to":"17:00"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPost, target: "/api/customers", body: `{"name":"Toko Makmur","price_list_id":1}`, wantStatus: http.StatusCreated},
+			}
+			for _, s := range seed {
+				s.run(t, h.router)
+			}
+
+			tc.run(t, h.router)
+		})
+	}
+}
+
+func TestPriceListHandlerAccess(t *testing.T) {
+	const secret = "secret"
+	token := func(outlets ...int) map[string]string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{Outlets: outlets}).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]string{"Authorization": "Bearer " + signed}
+	}
+	owner, branch := token(), token(2)
+
+	tests := []handlerCase{
+		{name: "list at one outlet", method: http.MethodGet, target: "/api/price-lists", header: branch, wantStatus: http.StatusOK, wantBody: `"code":"GROSIR"`},
+		{name: "create at one outlet", method: http.MethodPost, target: "/api/price-lists", body: `{"code":"MEMBER","name":"Member"}`, header: branch, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "create rule at one outlet", method: http.MethodPost, target: "/api/price-rules", body: `{"product_id":1,"price":1}`, header: branch, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "delete at one outlet", method: http.MethodDelete, target: "/api/price-lists/1", header: branch, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "create as owner", method: http.MethodPost, target: "/api/price-lists", body: `{"code":"MEMBER","name":"Member"}`, header: owner, wantStatus: http.StatusCreated},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := []handlerCase{
+				{method: http.MethodPost, target: "/api/price-lists", body: `{"code":"GROSIR","name":"Grosir"}`, wantStatus: http.StatusCreated},
+			}
+			for _, s := range seed {
+				s.run(t, h.router)
+			}
+
+			tc.run(t, middleware.Auth(secret)(h.router))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kasir-api/models"
+)
+
+// ListComponents menampilkan komponen sebuah produk paket
+// @Summary List bundle components
+// @Description Get the products contained in a bundle product
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} models.BundleComponent
+// @Failure 404 {string} string "Product not found"
+// @Router /products/{id}/components [get]
+func (h *ProductHandler) ListComponents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	components, err := h.service.GetComponents(r.Context(), productID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(components)
+}
+
+// SetComponents mengganti komponen sebuah produk paket
+// @Summary Set bundle components
+// @Description Replace the components of a bundle product. An empty list turns the bundle back into a regular product.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param components body []models.BundleComponent true "Bundle components"
+// @Success 200 {array} models.BundleComponent
+// @Failure 400 {string} string "Invalid request body"
+// @Router /products/{id}/components [put]
+func (h *ProductHandler) SetComponents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var components []models.BundleComponent
+	err = json.NewDecoder(r.Body).Decode(&components)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetComponents(r.Context(), productID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	savedComponents, err := h.service.SetComponents(r.Context(), productID, components)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditUpdate, "product", productID,
+		map[string]any{"components": before}, map[string]any{"components": savedComponents})
+
+	json.NewEncoder(w).Encode(savedComponents)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kasir-api/models"
+)
+
+// ListConversions menampilkan satuan kemasan sebuah produk
+// @Summary List product unit conversions
+// @Description Get the packaging units of a product (e.g. 1 box = 24 pcs)
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} models.UnitConversion
+// @Router /products/{id}/conversions [get]
+func (h *ProductHandler) ListConversions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	conversions, err := h.unitService.GetConversions(r.Context(), productID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(conversions)
+}
+
+// SaveConversion membuat atau mengupdate satuan kemasan produk
+// @Summary Create or update a product unit conversion
+// @Description Define how many product units one packaging unit contains
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param conversion body models.UnitConversion true "Unit conversion object"
+// @Success 201 {object} models.UnitConversion
+// @Failure 400 {string} string "Invalid request body"
+// @Router /products/{id}/conversions [post]
+func (h *ProductHandler) SaveConversion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var conversion models.UnitConversion
+	err = json.NewDecoder(r.Body).Decode(&conversion)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.findConversion(r, productID, conversion.Unit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	savedConversion, err := h.unitService.SaveConversion(r.Context(), productID, conversion)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if before == nil {
+		recordAudit(r, h.audit, models.AuditCreate, "unit_conversion", savedConversion.ID, nil, savedConversion)
+	} else {
+		recordAudit(r, h.audit, models.AuditUpdate, "unit_conversion", savedConversion.ID, before, savedConversion)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(savedConversion)
+}
+
+// DeleteConversion menghapus satuan kemasan produk
+// @Summary Delete a product unit conversion
+// @Description Delete a packaging unit of a product
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param unit path string true "Packaging unit"
+// @Success 200 {string} string "Unit conversion deleted successfully"
+// @Failure 404 {string} string "Unit conversion not found"
+// @Router /products/{id}/conversions/{unit} [delete]
+func (h *ProductHandler) DeleteConversion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.findConversion(r, productID, r.PathValue("unit"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.unitService.DeleteConversion(r.Context(), productID, r.PathValue("unit"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if before != nil {
+		recordAudit(r, h.audit, models.AuditDelete, "unit_conversion", before.ID, before, nil)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Unit conversion deleted successfully"})
+}
+
+// findConversion returns the packaging unit of a product, nil when the
+// product has no such unit
+func (h *ProductHandler) findConversion(r *http.Request, productID int, unit string) (*models.UnitConversion, error) {
+	conversions, err := h.unitService.GetConversions(r.Context(), productID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conversions {
+		if c.Unit == unit {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
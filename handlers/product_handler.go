@@ -2,9 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"kasir-api/models"
 	"kasir-api/services"
@@ -12,44 +13,52 @@ import (
 
 // ProductHandler handles HTTP requests for products
 type ProductHandler struct {
-	service *services.ProductService
+	service     *services.ProductService
+	unitService *services.UnitService
+	audit       *services.AuditService
 }
 
-// NewProductHandler creates a new ProductHandler
-func NewProductHandler(service *services.ProductService) *ProductHandler {
-	return &ProductHandler{service: service}
+// NewProductHandler creates a new ProductHandler; changes are recorded in the
+// audit log
+func NewProductHandler(service *services.ProductService, unitService *services.UnitService, audit *services.AuditService) *ProductHandler {
+	return &ProductHandler{service: service, unitService: unitService, audit: audit}
 }
 
-// Handle menangani routing berdasarkan method HTTP
-func (h *ProductHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		path := strings.TrimPrefix(r.URL.Path, "/api/products")
-		if path == "" || path == "/" {
-			h.ListProducts(w, r)
-		} else {
-			h.GetProduct(w, r)
-		}
-	case http.MethodPost:
-		h.CreateProduct(w, r)
-	case http.MethodPut:
-		h.UpdateProduct(w, r)
-	case http.MethodDelete:
-		h.DeleteProduct(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+// RegisterRoutes registers the product routes, including variants, packaging
+// units and bundle components
+func (h *ProductHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/products", h.ListProducts)
+	r.HandleFunc("POST /api/products", h.CreateProduct)
+	r.HandleFunc("GET /api/products/{id}", h.GetProduct)
+	r.HandleFunc("PUT /api/products/{id}", h.UpdateProduct)
+	r.HandleFunc("DELETE /api/products/{id}", h.DeleteProduct)
+	r.HandleFunc("POST /api/products/{id}/restore", h.RestoreProduct)
+	r.HandleFunc("DELETE /api/products/{id}/purge", h.PurgeProduct)
+	r.HandleFunc("GET /api/products/{id}/history", h.GetProductHistory)
+
+	r.HandleFunc("GET /api/products/{id}/variants", h.ListVariants)
+	r.HandleFunc("POST /api/products/{id}/variants", h.CreateVariant)
+	r.HandleFunc("PUT /api/products/{id}/variants/{variantId}", h.UpdateVariant)
+	r.HandleFunc("DELETE /api/products/{id}/variants/{variantId}", h.DeleteVariant)
+
+	r.HandleFunc("GET /api/products/{id}/conversions", h.ListConversions)
+	r.HandleFunc("POST /api/products/{id}/conversions", h.SaveConversion)
+	r.HandleFunc("DELETE /api/products/{id}/conversions/{unit}", h.DeleteConversion)
+
+	r.HandleFunc("GET /api/products/{id}/components", h.ListComponents)
+	r.HandleFunc("PUT /api/products/{id}/components", h.SetComponents)
 }
 
 // ListProducts menampilkan semua produk dengan filter opsional
 // @Summary List all products
-// @Description Get all products with optional filters
+// @Description Get all products with optional filters; deleted products are left out unless include_deleted is true
 // @Tags products
 // @Produce json
 // @Param name query string false "Filter by product name"
 // @Param category_id query int false "Filter by category ID"
 // @Param min_price query number false "Filter by minimum price"
 // @Param max_price query number false "Filter by maximum price"
+// @Param include_deleted query bool false "List deleted products too"
 // @Success 200 {array} models.Product
 // @Router /products [get]
 func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
@@ -78,7 +87,13 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	products, err := h.service.GetAllProducts(filter)
+	if includeDeleted := r.URL.Query().Get("include_deleted"); includeDeleted != "" {
+		if include, err := strconv.ParseBool(includeDeleted); err == nil {
+			filter.IncludeDeleted = include
+		}
+	}
+
+	products, err := h.service.GetAllProducts(r.Context(), filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -88,7 +103,7 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 
 // GetProduct menampilkan detail produk berdasarkan ID
 // @Summary Get product by ID
-// @Description Get product details by ID
+// @Description Get product details by ID; a deleted product has deleted_at set
 // @Tags products
 // @Produce json
 // @Param id path int true "Product ID"
@@ -98,14 +113,13 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 // @Router /products/{id} [get]
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/products/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
 	}
 
-	product, err := h.service.GetProductByID(id)
+	product, err := h.service.GetProductByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -114,6 +128,39 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(product)
 }
 
+// GetProductHistory menampilkan riwayat perubahan produk
+// @Summary Get product history
+// @Description Get every version of a product, oldest first, with the changed fields and who changed them. With at, only the version in effect at that time.
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param at query string false "Point in time (RFC3339)"
+// @Success 200 {array} models.ProductVersion
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Product not found"
+// @Router /products/{id}/history [get]
+func (h *ProductHandler) GetProductHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	at, ok := timeQuery(w, r, "at")
+	if !ok {
+		return
+	}
+
+	versions, err := h.service.GetProductHistory(r.Context(), id, at)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(versions)
+}
+
 // CreateProduct membuat produk baru
 // @Summary Create a new product
 // @Description Create a new product
@@ -134,11 +181,12 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdProduct, err := h.service.CreateProduct(newProduct)
+	createdProduct, err := h.service.CreateProduct(actorContext(r), newProduct)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	recordAudit(r, h.audit, models.AuditCreate, "product", createdProduct.ID, nil, createdProduct)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createdProduct)
@@ -159,8 +207,7 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/products/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
@@ -173,40 +220,146 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := h.service.UpdateProduct(id, updatedProduct)
+	before, err := h.service.GetProductByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	// The update leaves the variants and components alone and does not
+	// return them
+	before.Variants, before.Components = nil, nil
+
+	product, err := h.service.UpdateProduct(actorContext(r), id, updatedProduct)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	// Log the product as stored rather than as requested, which leaves out
+	// the fields the update does not set
+	if after, err := h.service.GetProductByID(r.Context(), id); err == nil {
+		after.Variants, after.Components = nil, nil
+		recordAudit(r, h.audit, models.AuditUpdate, "product", id, before, after)
+	} else {
+		slog.ErrorContext(r.Context(), "reading updated product failed", slog.Int("product_id", id), slog.Any("error", err))
+	}
 
 	json.NewEncoder(w).Encode(product)
 }
 
 // DeleteProduct menghapus produk berdasarkan ID
 // @Summary Delete a product
-// @Description Delete product by ID
+// @Description Soft delete product by ID: it is no longer sold or listed, past sales and reports keep it and it can be restored
 // @Tags products
 // @Produce json
 // @Param id path int true "Product ID"
 // @Success 200 {string} string "Product deleted successfully"
 // @Failure 400 {string} string "Invalid product ID"
 // @Failure 404 {string} string "Product not found"
+// @Failure 409 {string} string "Product is a component of a bundle"
 // @Router /products/{id} [delete]
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/products/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.service.DeleteProduct(id)
+	before, err := h.service.GetProductByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
+	err = h.service.DeleteProduct(r.Context(), id)
+	if err != nil {
+		deleteError(w, err, http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditDelete, "product", id, before, nil)
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Product deleted successfully"})
 }
+
+// RestoreProduct memulihkan produk yang sudah dihapus
+// @Summary Restore a product
+// @Description Undo the deletion of a product. A product whose category was deleted meanwhile is restored without a category.
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.Product
+// @Failure 400 {string} string "Invalid product ID"
+// @Failure 404 {string} string "Product not found"
+// @Failure 409 {string} string "Product is not deleted"
+// @Router /products/{id}/restore [post]
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetProductByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	before.Variants, before.Components = nil, nil
+
+	product, err := h.service.RestoreProduct(actorContext(r), id)
+	if err != nil {
+		deleteError(w, err, http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditRestore, "product", id, before, product)
+
+	json.NewEncoder(w).Encode(product)
+}
+
+// PurgeProduct menghapus produk secara permanen
+// @Summary Purge a product
+// @Description Permanently remove a deleted product together with its version history. Products referenced by sales, bundles, transfers or open bills cannot be purged.
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {string} string "Product purged successfully"
+// @Failure 400 {string} string "Invalid product ID"
+// @Failure 404 {string} string "Product not found"
+// @Failure 409 {string} string "Product is not deleted or still referenced"
+// @Router /products/{id}/purge [delete]
+func (h *ProductHandler) PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetProductByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	err = h.service.PurgeProduct(r.Context(), id)
+	if err != nil {
+		deleteError(w, err, http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditPurge, "product", id, before, nil)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Product purged successfully"})
+}
+
+// deleteError answers 409 when a product or category is still in use or not
+// deleted, and status otherwise
+func deleteError(w http.ResponseWriter, err error, status int) {
+	if errors.Is(err, services.ErrInUse) || errors.Is(err, services.ErrNotDeleted) {
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
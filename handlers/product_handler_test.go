@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"kasir-api/middleware"
+)
+
+func TestProductHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "list", method: http.MethodGet, target: "/api/products", wantStatus: http.StatusOK, wantBody: `"name":"Paket Kopi"`},
+		{name: "list filtered by name", method: http.MethodGet, target: "/api/products?name=teh", wantStatus: http.StatusOK, wantBody: `"name":"Es Teh"`},
+		{name: "list filtered by price", method: http.MethodGet, target: "/api/products?min_price=6000", wantStatus: http.StatusOK, wantBody: `"price":9000`},
+		{name: "get with variants", method: http.MethodGet, target: "/api/products/2", wantStatus: http.StatusOK, wantBody: `"sku":"TEH-L"`},
+		{name: "get bundle with components", method: http.MethodGet, target: "/api/products/3", wantStatus: http.StatusOK, wantBody: `"stock":5`},
+		{name: "get missing", method: http.MethodGet, target: "/api/products/99", wantStatus: http.StatusNotFound, wantBody: "Product with ID 99 not found"},
+		{name: "get invalid id", method: http.MethodGet, target: "/api/products/abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid product ID"},
+		{name: "create", method: http.MethodPost, target: "/api/products", body: `{"name":"Gula","price":14000,"stock":20,"unit":"kg","category_id":1}`, wantStatus: http.StatusCreated, wantBody: `"unit":"kg"`},
+		{name: "create defaults unit", method: http.MethodPost, target: "/api/products", body: `{"name":"Roti","price":8000,"category_id":1}`, wantStatus: http.StatusCreated, wantBody: `"unit":"pcs"`},
+		{name: "create without category", method: http.MethodPost, target: "/api/products", body: `{"name":"Roti","price":8000}`, wantStatus: http.StatusCreated, wantBody: `"category_id":null`},
+		{name: "create invalid body", method: http.MethodPost, target: "/api/products", body: `[]`, wantStatus: http.StatusBadRequest, wantBody: "Invalid request body"},
+		{name: "update", method: http.MethodPut, target: "/api/products/1", body: `{"name":"Kopi Hitam","price":6000,"stock":10,"category_id":1}`, wantStatus: http.StatusOK, wantBody: `"name":"Kopi Hitam"`},
+		{name: "update missing", method: http.MethodPut, target: "/api/products/99", body: `{"name":"X","category_id":1}`, wantStatus: http.StatusNotFound},
+		{name: "history", method: http.MethodGet, target: "/api/products/1/history", wantStatus: http.StatusOK, wantBody: `"product_id":1,"version":1,"name":"Kopi"`},
+		{name: "history before creation", method: http.MethodGet, target: "/api/products/1/history?at=2000-01-01T00:00:00Z", wantStatus: http.StatusOK, wantBody: `[]`},
+		{name: "history with invalid time", method: http.MethodGet, target: "/api/products/1/history?at=yesterday", wantStatus: http.StatusBadRequest, wantBody: "Invalid at format"},
+		{name: "history of missing product", method: http.MethodGet, target: "/api/products/99/history", wantStatus: http.StatusNotFound},
+		{name: "delete", method: http.MethodDelete, target: "/api/products/2", wantStatus: http.StatusOK, wantBody: "Product deleted successfully"},
+		{name: "delete bundle component", method: http.MethodDelete, target: "/api/products/1", wantStatus: http.StatusConflict, wantBody: "referenced by bundles"},
+		{name: "restore product that is not deleted", method: http.MethodPost, target: "/api/products/2/restore", wantStatus: http.StatusConflict, wantBody: "is not deleted"},
+		{name: "restore missing", method: http.MethodPost, target: "/api/products/99/restore", wantStatus: http.StatusNotFound},
+		{name: "purge product that is not deleted", method: http.MethodDelete, target: "/api/products/2/purge", wantStatus: http.StatusConflict, wantBody: "must be deleted before it is purged"},
+		{name: "purge missing", method: http.MethodDelete, target: "/api/products/99/purge", wantStatus: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPatch, target: "/api/products/1", wantStatus: http.StatusMethodNotAllowed, wantHeader: map[string]string{"Allow": "DELETE, GET, HEAD, PUT"}},
+		{name: "post to product is not allowed", method: http.MethodPost, target: "/api/products/1", body: `{"name":"Gula","category_id":1}`, wantStatus: http.StatusMethodNotAllowed},
+		{name: "unknown sub-resource", method: http.MethodGet, target: "/api/products/1/anything", wantStatus: http.StatusNotFound},
+
+		{name: "list variants", method: http.MethodGet, target: "/api/products/2/variants", wantStatus: http.StatusOK, wantBody: `"name":"L"`},
+		{name: "list variants of missing product", method: http.MethodGet, target: "/api/products/99/variants", wantStatus: http.StatusNotFound},
+		{name: "create variant", method: http.MethodPost, target: "/api/products/2/variants", body: `{"name":"M","sku":"TEH-M","price":4000,"stock":5}`, wantStatus: http.StatusCreated, wantBody: `"product_id":2`},
+		{name: "create variant without sku", method: http.MethodPost, target: "/api/products/2/variants", body: `{"name":"M"}`, wantStatus: http.StatusBadRequest, wantBody: "variant name and sku are required"},
+		{name: "update variant", method: http.MethodPut, target: "/api/products/2/variants/1", body: `{"name":"Large","sku":"TEH-L","price":5500,"stock":3}`, wantStatus: http.StatusOK, wantBody: `"name":"Large"`},
+		{name: "update variant of other product", method: http.MethodPut, target: "/api/products/1/variants/1", body: `{"name":"L","sku":"TEH-L"}`, wantStatus: http.StatusNotFound},
+		{name: "delete variant", method: http.MethodDelete, target: "/api/products/2/variants/1", wantStatus: http.StatusOK},
+		{name: "invalid variant id", method: http.MethodDelete, target: "/api/products/2/variants/x", wantStatus: http.StatusBadRequest, wantBody: "Invalid variant ID"},
+
+		{name: "list conversions", method: http.MethodGet, target: "/api/products/1/conversions", wantStatus: http.StatusOK, wantBody: `"unit":"box"`},
+		{name: "save conversion", method: http.MethodPost, target: "/api/products/1/conversions", body: `{"unit":"karton","factor":48}`, wantStatus: http.StatusCreated, wantBody: `"factor":48`},
+		{name: "save conversion without factor", method: http.MethodPost, target: "/api/products/1/conversions", body: `{"unit":"karton"}`, wantStatus: http.StatusBadRequest},
+		{name: "conversions method not allowed", method: http.MethodPut, target: "/api/products/1/conversions", wantStatus: http.StatusMethodNotAllowed, wantHeader: map[string]string{"Allow": "GET, HEAD, POST"}},
+		{name: "delete conversion", method: http.MethodDelete, target: "/api/products/1/conversions/box", wantStatus: http.StatusOK},
+		{name: "delete missing conversion", method: http.MethodDelete, target: "/api/products/1/conversions/karton", wantStatus: http.StatusNotFound},
+
+		{name: "list components", method: http.MethodGet, target: "/api/products/3/components", wantStatus: http.StatusOK, wantBody: `"name":"Kopi"`},
+		{name: "set components", method: http.MethodPut, target: "/api/products/2/components", body: `[{"product_id":1,"quantity":1}]`, wantStatus: http.StatusOK, wantBody: `"product_id":1`},
+		{name: "set bundle as component", method: http.MethodPut, target: "/api/products/2/components", body: `[{"product_id":3,"quantity":1}]`, wantStatus: http.StatusBadRequest, wantBody: "is a bundle"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			tc.run(t, h.router)
+		})
+	}
+}
+
+func TestProductHandlerSoftDelete(t *testing.T) {
+	h := newTestHandlers(t)
+	steps := []handlerCase{
+		{method: http.MethodDelete, target: "/api/products/2", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/api/products?name=teh", wantStatus: http.StatusOK, wantBody: "null"},
+		{method: http.MethodGet, target: "/api/products?name=teh&include_deleted=true", wantStatus: http.StatusOK, wantBody: `"deleted_at":"`},
+		{method: http.MethodGet, target: "/api/products/2", wantStatus: http.StatusOK, wantBody: `"deleted_at":"`},
+		{method: http.MethodPut, target: "/api/products/2", body: `{"name":"Es Teh","price":5000,"category_id":1}`, wantStatus: http.StatusNotFound},
+		{method: http.MethodDelete, target: "/api/products/2", wantStatus: http.StatusNotFound},
+		{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":2,"quantity":1}]}`, wantStatus: http.StatusBadRequest, wantBody: "not found"},
+		{method: http.MethodPost, target: "/api/products/2/restore", wantStatus: http.StatusOK, wantBody: `"name":"Es Teh"`},
+		{method: http.MethodGet, target: "/api/products?name=teh", wantStatus: http.StatusOK, wantBody: `"name":"Es Teh"`},
+		{method: http.MethodDelete, target: "/api/products/2", wantStatus: http.StatusOK},
+		{method: http.MethodDelete, target: "/api/products/2/purge", wantStatus: http.StatusOK, wantBody: "Product purged successfully"},
+		{method: http.MethodGet, target: "/api/products/2", wantStatus: http.StatusNotFound},
+		// Sold products stay in the books
+		{method: http.MethodPost, target: "/api/products", body: `{"name":"Gula","price":14000,"stock":20,"unit":"kg","category_id":1}`, wantStatus: http.StatusCreated, wantBody: `"id":4`},
+		{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":4,"quantity":1}]}`, wantStatus: http.StatusCreated},
+		{method: http.MethodDelete, target: "/api/products/4", wantStatus: http.StatusOK},
+		{method: http.MethodDelete, target: "/api/products/4/purge", wantStatus: http.StatusConflict, wantBody: "referenced by sales"},
+		{method: http.MethodGet, target: "/api/audit-log?entity_type=product&entity_id=2&action=restore", wantStatus: http.StatusOK, wantBody: `"action":"restore","entity_type":"product","entity_id":2,"before":{"deleted_at":"`},
+		{method: http.MethodGet, target: "/api/audit-log?entity_type=product&entity_id=2&action=purge", wantStatus: http.StatusOK, wantBody: `"action":"purge","entity_type":"product","entity_id":2,"before":{`},
+	}
+	for _, s := range steps {
+		s.run(t, h.router)
+	}
+}
+
+func TestProductHandlerHistory(t *testing.T) {
+	const secret = "secret"
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "budi"},
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := map[string]string{"Authorization": "Bearer " + signed}
+
+	h := newTestHandlers(t)
+	handler := middleware.Auth(secret)(h.router)
+	steps := []handlerCase{
+		{method: http.MethodPut, target: "/api/products/1", body: `{"name":"Kopi","price":6000,"stock":10,"category_id":1}`, header: header, wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/api/products/1/history", header: header, wantStatus: http.StatusOK, wantBody: `"version":2,"name":"Kopi","price":6000,"stock":10,"unit":"pcs","category_id":1,"changes":["price"],"changed_by":"budi"`},
+	}
+	for _, s := range steps {
+		s.run(t, handler)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kasir-api/models"
+)
+
+// ListVariants menampilkan semua varian dari sebuah produk
+// @Summary List product variants
+// @Description Get all variants (size, flavor, ...) of a product
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} models.ProductVariant
+// @Failure 404 {string} string "Product not found"
+// @Router /products/{id}/variants [get]
+func (h *ProductHandler) ListVariants(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	variants, err := h.service.GetVariants(r.Context(), productID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(variants)
+}
+
+// CreateVariant membuat varian baru untuk sebuah produk
+// @Summary Create a product variant
+// @Description Create a new variant with its own SKU, price and stock
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param variant body models.ProductVariant true "Variant object"
+// @Success 201 {object} models.ProductVariant
+// @Failure 400 {string} string "Invalid request body"
+// @Router /products/{id}/variants [post]
+func (h *ProductHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var newVariant models.ProductVariant
+	err = json.NewDecoder(r.Body).Decode(&newVariant)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	createdVariant, err := h.service.CreateVariant(r.Context(), productID, newVariant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditCreate, "product_variant", createdVariant.ID, nil, createdVariant)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdVariant)
+}
+
+// UpdateVariant mengupdate varian produk
+// @Summary Update a product variant
+// @Description Update variant by ID
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param variantId path int true "Variant ID"
+// @Param variant body models.ProductVariant true "Variant object"
+// @Success 200 {object} models.ProductVariant
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Variant not found"
+// @Router /products/{id}/variants/{variantId} [put]
+func (h *ProductHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	variantID, err := pathID(r, "variantId")
+	if err != nil {
+		http.Error(w, "Invalid variant ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedVariant models.ProductVariant
+	err = json.NewDecoder(r.Body).Decode(&updatedVariant)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetVariant(r.Context(), productID, variantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	variant, err := h.service.UpdateVariant(r.Context(), productID, variantID, updatedVariant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditUpdate, "product_variant", variantID, before, variant)
+
+	json.NewEncoder(w).Encode(variant)
+}
+
+// DeleteVariant menghapus varian produk
+// @Summary Delete a product variant
+// @Description Delete variant by ID
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param variantId path int true "Variant ID"
+// @Success 200 {string} string "Variant deleted successfully"
+// @Failure 404 {string} string "Variant not found"
+// @Router /products/{id}/variants/{variantId} [delete]
+func (h *ProductHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	variantID, err := pathID(r, "variantId")
+	if err != nil {
+		http.Error(w, "Invalid variant ID", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetVariant(r.Context(), productID, variantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	err = h.service.DeleteVariant(r.Context(), productID, variantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditDelete, "product_variant", variantID, before, nil)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Variant deleted successfully"})
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	"kasir-api/services"
 )
@@ -18,36 +17,30 @@ func NewReportHandler(service *services.ReportService) *ReportHandler {
 	return &ReportHandler{service: service}
 }
 
-// Handle menangani routing berdasarkan path
-func (h *ReportHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	path := strings.TrimPrefix(r.URL.Path, "/api/report")
-
-	switch {
-	case path == "/hari-ini" || path == "/hari-ini/":
-		h.GetTodayReport(w, r)
-	case path == "" || path == "/":
-		h.GetReportByDateRange(w, r)
-	default:
-		http.Error(w, "Not found", http.StatusNotFound)
-	}
+// RegisterRoutes registers the report routes
+func (h *ReportHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/report", h.GetReportByDateRange)
+	r.HandleFunc("GET /api/report/hari-ini", h.GetTodayReport)
 }
 
 // GetTodayReport menampilkan laporan penjualan hari ini
 // @Summary Get today's sales report
-// @Description Get sales summary for today including total revenue, transaction count, and best selling product
+// @Description Get sales summary for today including total revenue, transaction count, best selling product and the sales of each outlet. Without outlet_id the report rolls up every outlet the user is assigned to.
 // @Tags report
 // @Produce json
+// @Param outlet_id query []int false "Outlet ID; may be repeated" collectionFormat(multi)
 // @Success 200 {object} models.SalesReport
+// @Failure 403 {string} string "Outlet not allowed"
 // @Router /report/hari-ini [get]
 func (h *ReportHandler) GetTodayReport(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	report, err := h.service.GetTodayReport()
+	outletIDs, ok := outletScope(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetTodayReport(r.Context(), outletIDs)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -58,13 +51,15 @@ func (h *ReportHandler) GetTodayReport(w http.ResponseWriter, r *http.Request) {
 
 // GetReportByDateRange menampilkan laporan penjualan berdasarkan rentang tanggal
 // @Summary Get sales report by date range
-// @Description Get sales summary for a specific date range
+// @Description Get sales summary for a specific date range with the sales of each outlet. Without outlet_id the report rolls up every outlet the user is assigned to.
 // @Tags report
 // @Produce json
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Param outlet_id query []int false "Outlet ID; may be repeated" collectionFormat(multi)
 // @Success 200 {object} models.SalesReport
 // @Failure 400 {string} string "Missing or invalid date parameters"
+// @Failure 403 {string} string "Outlet not allowed"
 // @Router /report [get]
 func (h *ReportHandler) GetReportByDateRange(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -77,7 +72,12 @@ func (h *ReportHandler) GetReportByDateRange(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	report, err := h.service.GetReportByDateRange(startDate, endDate)
+	outletIDs, ok := outletScope(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetReportByDateRange(r.Context(), startDate, endDate, outletIDs)
 	if err != nil {
 		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
 		return
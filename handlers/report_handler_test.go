@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReportHandler(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+
+	tests := []handlerCase{
+		{name: "today", method: http.MethodGet, target: "/api/report/hari-ini", wantStatus: http.StatusOK, wantBody: `"total_revenue":10000`},
+		{name: "date range", method: http.MethodGet, target: "/api/report?start_date=" + today + "&end_date=" + today, wantStatus: http.StatusOK, wantBody: `"produk_terlaris":{"nama":"Kopi"`},
+		{name: "range without sales", method: http.MethodGet, target: "/api/report?start_date=2000-01-01&end_date=2000-01-31", wantStatus: http.StatusOK, wantBody: `"total_transaksi":0`},
+		{name: "missing dates", method: http.MethodGet, target: "/api/report?start_date=" + today, wantStatus: http.StatusBadRequest, wantBody: "start_date and end_date are required"},
+		{name: "invalid date", method: http.MethodGet, target: "/api/report?start_date=today&end_date=" + today, wantStatus: http.StatusBadRequest, wantBody: "Invalid date format"},
+		{name: "unknown report", method: http.MethodGet, target: "/api/report/kemarin", wantStatus: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPost, target: "/api/report/hari-ini", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := handlerCase{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":2}]}`, wantStatus: http.StatusCreated}
+			seed.run(t, h.router)
+
+			tc.run(t, h.router)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"kasir-api/middleware"
+)
+
+// Router registers routes on a ServeMux using method and path patterns, e.g.
+// "GET /api/products/{id}". Requests for a known path with another method get
+// 405 with an Allow header, unknown paths 404. Routes registered through a
+// group are wrapped in the middleware of that group.
+type Router struct {
+	mux         *http.ServeMux
+	middlewares []middleware.Middleware
+}
+
+// NewRouter creates a Router with an empty ServeMux
+func NewRouter() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Group returns a router that registers its routes on the same mux, wrapped in
+// the middleware of r followed by middlewares
+func (r *Router) Group(middlewares ...middleware.Middleware) *Router {
+	return &Router{
+		mux:         r.mux,
+		middlewares: append(append([]middleware.Middleware{}, r.middlewares...), middlewares...),
+	}
+}
+
+// Handle registers handler for pattern
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	r.mux.Handle(pattern, middleware.Chain(handler, r.middlewares...))
+}
+
+// HandleFunc registers handler for pattern
+func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	r.Handle(pattern, handler)
+}
+
+// ServeHTTP dispatches the request to the route matching its method and path
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+// pathID parses an integer path parameter, e.g. the {id} of /api/products/{id}
+func pathID(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(r.PathValue(name))
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"kasir-api/middleware"
+)
+
+func TestRouterGroup(t *testing.T) {
+	// tag adds a header naming the middleware, in the order they ran
+	tag := func(name string) middleware.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Middleware", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }
+
+	router := NewRouter()
+	router.HandleFunc("GET /public", ok)
+	api := router.Group(tag("api"))
+	api.HandleFunc("GET /api/{id}", ok)
+	api.Group(tag("admin")).HandleFunc("DELETE /api/{id}", ok)
+
+	tests := []struct {
+		handlerCase
+		wantMiddleware []string
+	}{
+		{handlerCase: handlerCase{name: "outside group", method: http.MethodGet, target: "/public", wantStatus: http.StatusOK}},
+		{handlerCase: handlerCase{name: "group", method: http.MethodGet, target: "/api/1", wantStatus: http.StatusOK}, wantMiddleware: []string{"api"}},
+		{handlerCase: handlerCase{name: "nested group", method: http.MethodDelete, target: "/api/1", wantStatus: http.StatusOK}, wantMiddleware: []string{"api", "admin"}},
+		{handlerCase: handlerCase{name: "method not allowed", method: http.MethodPost, target: "/api/1", wantStatus: http.StatusMethodNotAllowed, wantHeader: map[string]string{"Allow": "DELETE, GET, HEAD"}}},
+		{handlerCase: handlerCase{name: "not found", method: http.MethodGet, target: "/api/1/more", wantStatus: http.StatusNotFound}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.run(t, router)
+			got := rec.Header().Values("X-Middleware")
+			if len(got) != len(tt.wantMiddleware) {
+				t.Fatalf("middleware = %v, want %v", got, tt.wantMiddleware)
+			}
+			for i := range got {
+				if got[i] != tt.wantMiddleware[i] {
+					t.Errorf("middleware = %v, want %v", got, tt.wantMiddleware)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// SyncHandler handles HTTP requests for offline POS synchronisation
+type SyncHandler struct {
+	service *services.TransactionService
+	audit   *services.AuditService
+}
+
+// NewSyncHandler creates a new SyncHandler; the sales a batch adds are
+// recorded in the audit log
+func NewSyncHandler(service *services.TransactionService, audit *services.AuditService) *SyncHandler {
+	return &SyncHandler{service: service, audit: audit}
+}
+
+// RegisterRoutes registers the offline sync routes
+func (h *SyncHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("POST /api/sync/transactions", h.SyncTransactions)
+}
+
+// SyncTransactions menyinkronkan transaksi yang dibuat saat POS offline
+// @Summary Sync offline transactions
+// @Description Insert a batch of transactions created offline. Each transaction needs a client generated UUID and its original timestamp and takes place at its outlet, by default the first outlet of the user; re-sent transactions are reported as duplicate, and stock that goes negative is flagged as a conflict. A batch holds at most 500 transactions; a temporary failure answers 500 and the batch can be sent again as is. A batch with a transaction at an outlet the user is not assigned to is refused.
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Param batch body models.SyncTransactionsRequest true "Offline transactions"
+// @Success 200 {object} models.SyncTransactionsResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 500 {string} string "Temporary failure; send the batch again"
+// @Router /sync/transactions [post]
+func (h *SyncHandler) SyncTransactions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.SyncTransactionsRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for i := range req.Transactions {
+		if req.Transactions[i].OutletID == 0 {
+			req.Transactions[i].OutletID = userOutlet(r)
+		}
+		if id := req.Transactions[i].OutletID; id != 0 && !outletAllowed(w, r, id) {
+			return
+		}
+	}
+
+	response, err := h.service.SyncTransactions(r.Context(), req)
+	if err != nil {
+		checkoutError(w, err, http.StatusBadRequest)
+		return
+	}
+	for _, result := range response.Results {
+		if result.Status != models.SyncStatusAccepted {
+			continue
+		}
+		transaction, err := h.service.GetTransactionByID(r.Context(), result.TransactionID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "reading synced transaction failed",
+				slog.Int("transaction_id", result.TransactionID), slog.Any("error", err))
+			continue
+		}
+		recordAudit(r, h.audit, models.AuditCreate, "transaction", transaction.ID, nil, transaction)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
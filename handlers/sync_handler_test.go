@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSyncHandler(t *testing.T) {
+	tests := []handlerCase{
+		{
+			name:       "accepted",
+			method:     http.MethodPost,
+			target:     "/api/sync/transactions",
+			body:       `{"transactions":[{"client_id":"7d9f4a52-3c1e-4b8a-9f60-2a1b3c4d5e6f","created_at":"2026-01-10T08:00:00Z","items":[{"product_id":1,"quantity":2}]}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"status":"accepted"`,
+		},
+		{
+			name:       "stock conflict is reported",
+			method:     http.MethodPost,
+			target:     "/api/sync/transactions",
+			body:       `{"transactions":[{"client_id":"7d9f4a52-3c1e-4b8a-9f60-2a1b3c4d5e6f","created_at":"2026-01-10T08:00:00Z","items":[{"product_id":1,"quantity":12}]}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"stock_after":-2`,
+		},
+		{
+			name:       "invalid transaction is rejected",
+			method:     http.MethodPost,
+			target:     "/api/sync/transactions",
+			body:       `{"transactions":[{"client_id":"pos-1","created_at":"2026-01-10T08:00:00Z","items":[{"product_id":1,"quantity":1}]}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"reason":"client_id must be a UUID"`,
+		},
+		{
+			name:       "empty batch",
+			method:     http.MethodPost,
+			target:     "/api/sync/transactions",
+			body:       `{"transactions":[]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "batch too large",
+			method:     http.MethodPost,
+			target:     "/api/sync/transactions",
+			body:       `{"transactions":[` + strings.Repeat(`{},`, 500) + `{}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "sync batch must have at most 500 transactions",
+		},
+		{
+			name:       "invalid body",
+			method:     http.MethodPost,
+			target:     "/api/sync/transactions",
+			body:       `{"transactions":`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Invalid request body",
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodGet,
+			target:     "/api/sync/transactions",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			tc.run(t, h.router)
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"kasir-api/models"
 	"kasir-api/services"
@@ -12,43 +11,53 @@ import (
 
 // TransactionHandler handles HTTP requests for transactions
 type TransactionHandler struct {
-	service *services.TransactionService
+	service     *services.TransactionService
+	receipts    *services.ReceiptService
+	idempotency *services.IdempotencyService
+	audit       *services.AuditService
 }
 
-// NewTransactionHandler creates a new TransactionHandler
-func NewTransactionHandler(service *services.TransactionService) *TransactionHandler {
-	return &TransactionHandler{service: service}
+// NewTransactionHandler creates a new TransactionHandler; sales made and
+// deleted are recorded in the audit log
+func NewTransactionHandler(service *services.TransactionService, receipts *services.ReceiptService, idempotency *services.IdempotencyService, audit *services.AuditService) *TransactionHandler {
+	return &TransactionHandler{service: service, receipts: receipts, idempotency: idempotency, audit: audit}
 }
 
-// Handle menangani routing berdasarkan method HTTP
-func (h *TransactionHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		path := strings.TrimPrefix(r.URL.Path, "/api/transactions")
-		if path == "" || path == "/" {
-			h.ListTransactions(w, r)
-		} else {
-			h.GetTransaction(w, r)
-		}
-	case http.MethodPost:
-		h.CreateTransaction(w, r)
-	case http.MethodDelete:
-		h.DeleteTransaction(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+// RegisterRoutes registers the transaction routes
+func (h *TransactionHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/transactions", h.ListTransactions)
+	r.Handle("POST /api/transactions", Idempotent(h.idempotency, h.CreateTransaction))
+	r.HandleFunc("GET /api/transactions/{id}", h.GetTransaction)
+	r.HandleFunc("DELETE /api/transactions/{id}", h.DeleteTransaction)
+	r.HandleFunc("GET /api/transactions/{id}/receipt", h.GetReceipt)
 }
 
-// ListTransactions menampilkan semua transaksi
+// ListTransactions menampilkan semua transaksi dengan filter opsional
 // @Summary List all transactions
-// @Description Get all transactions
+// @Description Get all transactions, newest first, with optional filters. Without outlet_id the transactions of every outlet the user is assigned to are listed.
 // @Tags transactions
 // @Produce json
+// @Param customer_id query int false "Filter by customer ID"
+// @Param outlet_id query []int false "Filter by outlet ID; may be repeated" collectionFormat(multi)
 // @Success 200 {array} models.Transaction
+// @Failure 403 {string} string "Outlet not allowed"
 // @Router /transactions [get]
 func (h *TransactionHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	transactions, err := h.service.GetAllTransactions()
+
+	outletIDs, ok := outletScope(w, r)
+	if !ok {
+		return
+	}
+
+	filter := models.TransactionFilter{OutletIDs: outletIDs}
+	if customerID := r.URL.Query().Get("customer_id"); customerID != "" {
+		if id, err := strconv.Atoi(customerID); err == nil {
+			filter.CustomerID = id
+		}
+	}
+
+	transactions, err := h.service.GetAllTransactions(r.Context(), filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -64,35 +73,73 @@ func (h *TransactionHandler) ListTransactions(w http.ResponseWriter, r *http.Req
 // @Param id path int true "Transaction ID"
 // @Success 200 {object} models.Transaction
 // @Failure 400 {string} string "Invalid transaction ID"
+// @Failure 403 {string} string "Outlet not allowed"
 // @Failure 404 {string} string "Transaction not found"
 // @Router /transactions/{id} [get]
 func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/transactions/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
 		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
 		return
 	}
 
-	transaction, err := h.service.GetTransactionByID(id)
+	transaction, err := h.service.GetTransactionByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if !outletAllowed(w, r, transaction.OutletID) {
+		return
+	}
 
 	json.NewEncoder(w).Encode(transaction)
 }
 
+// GetReceipt menampilkan struk transaksi
+// @Summary Get transaction receipt
+// @Description Get the receipt of a transaction with the store identity, item names, quantities in the unit sold and unit prices
+// @Tags transactions
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Success 200 {object} models.Receipt
+// @Failure 400 {string} string "Invalid transaction ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Transaction not found"
+// @Router /transactions/{id}/receipt [get]
+func (h *TransactionHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := h.receipts.GetReceipt(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if !outletAllowed(w, r, receipt.OutletID) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(receipt)
+}
+
 // CreateTransaction membuat transaksi baru
 // @Summary Create a new transaction
-// @Description Create a new transaction with items
+// @Description Create a new transaction with items at an outlet, by default the first outlet of the user. Retries by the same user sending the same Idempotency-Key replay the original response.
 // @Tags transactions
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Client generated key that makes retries safe"
 // @Param transaction body models.CreateTransactionRequest true "Transaction items"
 // @Success 201 {object} models.Transaction
 // @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 409 {string} string "Idempotency-Key reused with a different payload, still in progress or of a request that failed unexpectedly"
+// @Failure 500 {string} string "Temporary failure; the request can be retried with the same Idempotency-Key"
 // @Router /transactions [post]
 func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -103,12 +150,19 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.OutletID == 0 {
+		req.OutletID = userOutlet(r)
+	}
+	if req.OutletID != 0 && !outletAllowed(w, r, req.OutletID) {
+		return
+	}
 
-	transaction, err := h.service.CreateTransaction(req)
+	transaction, err := h.service.CreateTransaction(r.Context(), req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		checkoutError(w, err, http.StatusBadRequest)
 		return
 	}
+	recordAudit(r, h.audit, models.AuditCreate, "transaction", transaction.ID, nil, transaction)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(transaction)
@@ -122,23 +176,43 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 // @Param id path int true "Transaction ID"
 // @Success 200 {string} string "Transaction deleted successfully"
 // @Failure 400 {string} string "Invalid transaction ID"
+// @Failure 403 {string} string "Outlet not allowed"
 // @Failure 404 {string} string "Transaction not found"
 // @Router /transactions/{id} [delete]
 func (h *TransactionHandler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/transactions/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
 		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.service.DeleteTransaction(id)
+	transaction, err := h.service.GetTransactionByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if !outletAllowed(w, r, transaction.OutletID) {
+		return
+	}
+
+	err = h.service.DeleteTransaction(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	recordAudit(r, h.audit, models.AuditDelete, "transaction", id, transaction, nil)
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "Transaction deleted successfully"})
 }
+
+// checkoutError answers a failed sale with status, or with 500 when the
+// failure is temporary so that a retry with the same Idempotency-Key runs the
+// sale again instead of replaying the failure
+func checkoutError(w http.ResponseWriter, err error, status int) {
+	if services.IsTransient(err) {
+		status = http.StatusInternalServerError
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"kasir-api/middleware"
+	"kasir-api/repositories/memory"
+	"kasir-api/services"
+)
+
+func TestTransactionHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "create", method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":2}]}`, wantStatus: http.StatusCreated, wantBody: `"total_amount":10000`},
+		{name: "create with variant", method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":2,"variant_id":1,"quantity":1}]}`, wantStatus: http.StatusCreated, wantBody: `"total_amount":5000`},
+		{name: "create with packaging unit", method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":1,"unit":"box"}]}`, wantStatus: http.StatusCreated, wantBody: `"unit_quantity":1`},
+		{name: "create bundle", method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":3,"quantity":1}]}`, wantStatus: http.StatusCreated, wantBody: `"components"`},
+		{name: "insufficient stock", method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":11}]}`, wantStatus: http.StatusBadRequest, wantBody: "insufficient stock"},
+		{name: "no items", method: http.MethodPost, target: "/api/transactions", body: `{"items":[]}`, wantStatus: http.StatusBadRequest, wantBody: "at least one item"},
+		{name: "invalid body", method: http.MethodPost, target: "/api/transactions", body: `nope`, wantStatus: http.StatusBadRequest, wantBody: "Invalid request body"},
+		{name: "list", method: http.MethodGet, target: "/api/transactions", wantStatus: http.StatusOK, wantBody: `[{"id":1`},
+		{name: "get", method: http.MethodGet, target: "/api/transactions/1", wantStatus: http.StatusOK, wantBody: `"details":[`},
+		{name: "get missing", method: http.MethodGet, target: "/api/transactions/99", wantStatus: http.StatusNotFound, wantBody: "Transaction with ID 99 not found"},
+		{name: "get invalid id", method: http.MethodGet, target: "/api/transactions/x", wantStatus: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, target: "/api/transactions/1", wantStatus: http.StatusOK, wantBody: "Transaction deleted successfully"},
+		{name: "delete missing", method: http.MethodDelete, target: "/api/transactions/99", wantStatus: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPut, target: "/api/transactions/1", wantStatus: http.StatusMethodNotAllowed, wantHeader: map[string]string{"Allow": "DELETE, GET, HEAD"}},
+		{name: "receipt", method: http.MethodGet, target: "/api/transactions/1/receipt", wantStatus: http.StatusOK, wantBody: `"items":[{"name":"Kopi","quantity":1,"unit":"pcs","unit_price":5000,"subtotal":5000}]`},
+		{name: "receipt has store", method: http.MethodGet, target: "/api/transactions/1/receipt", wantStatus: http.StatusOK, wantBody: `"store":{"name":"Toko Test"}`},
+		{name: "receipt missing", method: http.MethodGet, target: "/api/transactions/99/receipt", wantStatus: http.StatusNotFound, wantBody: "Transaction with ID 99 not found"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			// Transaction 1 sells one Kopi
+			seed := handlerCase{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusCreated}
+			seed.run(t, h.router)
+
+			tc.run(t, h.router)
+		})
+	}
+}
+
+func TestTransactionHandlerIdempotencyKey(t *testing.T) {
+	const body = `{"items":[{"product_id":1,"quantity":2}]}`
+	key := map[string]string{IdempotencyKeyHeader: "checkout-1"}
+
+	tests := []struct {
+		name      string
+		requests  []handlerCase
+		wantStock float64
+	}{
+		{
+			name: "retry is replayed",
+			requests: []handlerCase{
+				{method: http.MethodPost, target: "/api/transactions", body: body, header: key, wantStatus: http.StatusCreated, wantBody: `"id":1`},
+				{method: http.MethodPost, target: "/api/transactions", body: body, header: key, wantStatus: http.StatusCreated, wantBody: `"id":1`},
+			},
+			wantStock: 8,
+		},
+		{
+			name: "key reused with another payload",
+			requests: []handlerCase{
+				{method: http.MethodPost, target: "/api/transactions", body: body, header: key, wantStatus: http.StatusCreated},
+				{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":3}]}`, header: key, wantStatus: http.StatusConflict, wantBody: "different request payload"},
+			},
+			wantStock: 8,
+		},
+		{
+			name: "rejected request is replayed",
+			requests: []handlerCase{
+				{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":20}]}`, header: key, wantStatus: http.StatusBadRequest, wantBody: "insufficient stock"},
+				{method: http.MethodPost, target: "/api/transactions", body: `{"items":[{"product_id":1,"quantity":20}]}`, header: key, wantStatus: http.StatusBadRequest, wantBody: "insufficient stock"},
+			},
+			wantStock: 10,
+		},
+		{
+			name: "without key every request is executed",
+			requests: []handlerCase{
+				{method: http.MethodPost, target: "/api/transactions", body: body, wantStatus: http.StatusCreated, wantBody: `"id":1`},
+				{method: http.MethodPost, target: "/api/transactions", body: body, wantStatus: http.StatusCreated, wantBody: `"id":2`},
+			},
+			wantStock: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			for i, tc := range tt.requests {
+				rec := tc.run(t, h.router)
+				replayed := rec.Header().Get("Idempotent-Replayed") == "true"
+				if wantReplay := i > 0 && tc.header != nil && tc.wantStatus != http.StatusConflict; replayed != wantReplay {
+					t.Errorf("request %d replayed = %v, want %v", i, replayed, wantReplay)
+				}
+			}
+
+			kopi, err := memory.NewProductRepository(h.store).GetByID(context.Background(), 1)
+			if err != nil || kopi.Stock != tt.wantStock {
+				t.Errorf("stock of Kopi = %+v (%v), want %v", kopi, err, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyPerUser(t *testing.T) {
+	const secret = "secret"
+	token := func(subject string) map[string]string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+		}).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]string{"Authorization": "Bearer " + signed, IdempotencyKeyHeader: "checkout-1"}
+	}
+
+	// Two cashiers choosing the same key each get their own sale; a retry
+	// replays only the response of its own user
+	const body = `{"items":[{"product_id":1,"quantity":2}]}`
+	h := newTestHandlers(t)
+	router := middleware.Auth(secret)(h.router)
+	steps := []handlerCase{
+		{method: http.MethodPost, target: "/api/transactions", body: body, header: token("7"), wantStatus: http.StatusCreated, wantBody: `"id":1`},
+		{method: http.MethodPost, target: "/api/transactions", body: body, header: token("8"), wantStatus: http.StatusCreated, wantBody: `"id":2`},
+		{method: http.MethodPost, target: "/api/transactions", body: body, header: token("7"), wantStatus: http.StatusCreated, wantBody: `"id":1`,
+			wantHeader: map[string]string{"Idempotent-Replayed": "true"}},
+	}
+	for _, step := range steps {
+		step.run(t, router)
+	}
+}
+
+func TestIdempotentFailure(t *testing.T) {
+	key := map[string]string{IdempotencyKeyHeader: "checkout-1"}
+
+	tests := []struct {
+		name       string
+		fail       func(w http.ResponseWriter)
+		wantRetry  handlerCase
+		wantCalled int
+	}{
+		{
+			// The key is given back, so the retry runs the request again
+			name: "temporary failure",
+			fail: func(w http.ResponseWriter) {
+				checkoutError(w, fmt.Errorf("loading products: %w", context.Canceled), http.StatusBadRequest)
+			},
+			wantRetry:  handlerCase{wantStatus: http.StatusCreated},
+			wantCalled: 2,
+		},
+		{
+			// The sale may have been committed before the panic, so the
+			// retry is refused rather than run again
+			name:       "panic",
+			fail:       func(w http.ResponseWriter) { panic("boom") },
+			wantRetry:  handlerCase{wantStatus: http.StatusConflict, wantBody: "failed unexpectedly"},
+			wantCalled: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := services.NewIdempotencyService(memory.NewIdempotencyRepository(memory.NewStore()), time.Hour)
+			calls := 0
+			handler := Idempotent(service, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					tt.fail(w)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			first := handlerCase{method: http.MethodPost, target: "/api/transactions", body: `{}`, header: key, wantStatus: http.StatusInternalServerError}
+			func() {
+				defer func() { recover() }()
+				first.run(t, handler)
+			}()
+
+			retry := tt.wantRetry
+			retry.method, retry.target, retry.body, retry.header = http.MethodPost, "/api/transactions", `{}`, key
+			if rec := retry.run(t, handler); rec.Header().Get("Idempotent-Replayed") != "" {
+				t.Error("retry was replayed")
+			}
+			if calls != tt.wantCalled {
+				t.Errorf("handler called %d times, want %d", calls, tt.wantCalled)
+			}
+		})
+	}
+}
+
+func TestCheckoutErrorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "rejected sale", err: errors.New("insufficient stock for product with ID 1"), wantStatus: http.StatusBadRequest},
+		{name: "cancelled request", err: fmt.Errorf("creating transaction: %w", context.Canceled), wantStatus: http.StatusInternalServerError},
+		{name: "timeout", err: context.DeadlineExceeded, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			checkoutError(rec, tt.err, http.StatusBadRequest)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"kasir-api/middleware"
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// TransferHandler handles HTTP requests for stock transfers between outlets
+type TransferHandler struct {
+	service *services.TransferService
+}
+
+// NewTransferHandler creates a new TransferHandler
+func NewTransferHandler(service *services.TransferService) *TransferHandler {
+	return &TransferHandler{service: service}
+}
+
+// RegisterRoutes registers the transfer routes. The source outlet drafts and
+// sends a transfer; the destination outlet receives it.
+func (h *TransferHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/transfers", h.ListTransfers)
+	r.HandleFunc("POST /api/transfers", h.CreateTransfer)
+	r.HandleFunc("GET /api/transfers/{id}", h.GetTransfer)
+	r.HandleFunc("PUT /api/transfers/{id}", h.UpdateTransfer)
+	r.HandleFunc("DELETE /api/transfers/{id}", h.DeleteTransfer)
+	r.HandleFunc("POST /api/transfers/{id}/send", h.SendTransfer)
+	r.HandleFunc("POST /api/transfers/{id}/receive", h.ReceiveTransfer)
+}
+
+// ListTransfers menampilkan semua transfer stok dengan filter opsional
+// @Summary List all stock transfers
+// @Description Get the stock transfers from or to the given outlets, newest first, without their lines. Without outlet_id the transfers of every outlet the user is assigned to are listed.
+// @Tags transfers
+// @Produce json
+// @Param outlet_id query []int false "Filter by source or destination outlet ID; may be repeated" collectionFormat(multi)
+// @Param status query string false "Filter by status" Enums(draft, in_transit, received)
+// @Success 200 {array} models.StockTransfer
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Router /transfers [get]
+func (h *TransferHandler) ListTransfers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	outletIDs, ok := outletScope(w, r)
+	if !ok {
+		return
+	}
+
+	transfers, err := h.service.GetAllTransfers(r.Context(), models.TransferFilter{
+		OutletIDs: outletIDs,
+		Status:    r.URL.Query().Get("status"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// GetTransfer menampilkan detail transfer stok berdasarkan ID
+// @Summary Get stock transfer by ID
+// @Description Get a stock transfer with its lines by ID
+// @Tags transfers
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Success 200 {object} models.StockTransfer
+// @Failure 400 {string} string "Invalid transfer ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Transfer not found"
+// @Router /transfers/{id} [get]
+func (h *TransferHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	transfer, ok := h.transfer(w, r)
+	if !ok {
+		return
+	}
+	claims := middleware.ClaimsFromContext(r.Context())
+	if !claims.OutletAllowed(transfer.FromOutletID) && !outletAllowed(w, r, transfer.ToOutletID) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// CreateTransfer membuat draft transfer stok antar outlet
+// @Summary Create a stock transfer
+// @Description Create a draft stock transfer from one outlet to another. A draft does not change stock until it is sent.
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param transfer body models.StockTransfer true "Source and destination outlet, note and lines"
+// @Success 201 {object} models.StockTransfer
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Router /transfers [post]
+func (h *TransferHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var newTransfer models.StockTransfer
+	if err := json.NewDecoder(r.Body).Decode(&newTransfer); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !outletAllowed(w, r, newTransfer.FromOutletID) {
+		return
+	}
+
+	transfer, err := h.service.CreateTransfer(r.Context(), newTransfer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// UpdateTransfer mengubah draft transfer stok
+// @Summary Update a stock transfer
+// @Description Replace the outlets, note and lines of a draft stock transfer
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Param transfer body models.StockTransfer true "Source and destination outlet, note and lines"
+// @Success 200 {object} models.StockTransfer
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Transfer not found"
+// @Failure 409 {string} string "Transfer is no longer a draft"
+// @Router /transfers/{id} [put]
+func (h *TransferHandler) UpdateTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	existing, ok := h.transfer(w, r)
+	if !ok || !outletAllowed(w, r, existing.FromOutletID) {
+		return
+	}
+
+	var updatedTransfer models.StockTransfer
+	if err := json.NewDecoder(r.Body).Decode(&updatedTransfer); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !outletAllowed(w, r, updatedTransfer.FromOutletID) {
+		return
+	}
+
+	transfer, err := h.service.UpdateTransfer(r.Context(), existing.ID, updatedTransfer)
+	if err != nil {
+		transferError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// DeleteTransfer menghapus draft transfer stok
+// @Summary Delete a stock transfer
+// @Description Delete a draft stock transfer by ID
+// @Tags transfers
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Success 200 {string} string "Transfer deleted successfully"
+// @Failure 400 {string} string "Invalid transfer ID"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Transfer not found"
+// @Failure 409 {string} string "Transfer is no longer a draft"
+// @Router /transfers/{id} [delete]
+func (h *TransferHandler) DeleteTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	transfer, ok := h.transfer(w, r)
+	if !ok || !outletAllowed(w, r, transfer.FromOutletID) {
+		return
+	}
+
+	if err := h.service.DeleteTransfer(r.Context(), transfer.ID); err != nil {
+		transferError(w, err, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Transfer deleted successfully"})
+}
+
+// SendTransfer mengirim transfer stok dari outlet asal
+// @Summary Send a stock transfer
+// @Description Take the lines of a draft stock transfer off the stock of the source outlet and put it in transit. The source outlet must have the stock.
+// @Tags transfers
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Success 200 {object} models.StockTransfer
+// @Failure 400 {string} string "Insufficient stock"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Transfer not found"
+// @Failure 409 {string} string "Transfer is no longer a draft"
+// @Router /transfers/{id}/send [post]
+func (h *TransferHandler) SendTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	transfer, ok := h.transfer(w, r)
+	if !ok || !outletAllowed(w, r, transfer.FromOutletID) {
+		return
+	}
+
+	sent, err := h.service.SendTransfer(r.Context(), transfer.ID)
+	if err != nil {
+		transferError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(sent)
+}
+
+// ReceiveTransfer menerima barang transfer stok di outlet tujuan
+// @Summary Receive a stock transfer
+// @Description Add the quantities that arrived to the stock of the destination outlet. Lines may arrive over several receipts; the transfer is received once every line arrived in full, or when close is set, which records what did not arrive as the discrepancy of its line.
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Param receipt body models.TransferReceipt true "Quantities that arrived per line"
+// @Success 200 {object} models.StockTransfer
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Outlet not allowed"
+// @Failure 404 {string} string "Transfer not found"
+// @Failure 409 {string} string "Transfer is not in transit"
+// @Router /transfers/{id}/receive [post]
+func (h *TransferHandler) ReceiveTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	transfer, ok := h.transfer(w, r)
+	if !ok || !outletAllowed(w, r, transfer.ToOutletID) {
+		return
+	}
+
+	var receipt models.TransferReceipt
+	if err := json.NewDecoder(r.Body).Decode(&receipt); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	received, err := h.service.ReceiveTransfer(r.Context(), transfer.ID, receipt)
+	if err != nil {
+		transferError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(received)
+}
+
+// transfer loads the transfer of the request path; it answers 400 or 404 and
+// returns false when the ID is invalid or the transfer does not exist
+func (h *TransferHandler) transfer(w http.ResponseWriter, r *http.Request) (*models.StockTransfer, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	transfer, err := h.service.GetTransferByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return transfer, true
+}
+
+// transferError answers 409 when a transfer is not in the status a change
+// requires and status otherwise
+func transferError(w http.ResponseWriter, err error, status int) {
+	if errors.Is(err, services.ErrTransferStatus) {
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
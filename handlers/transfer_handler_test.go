@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"kasir-api/middleware"
+)
+
+func TestTransferHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "list", method: http.MethodGet, target: "/api/transfers", wantStatus: http.StatusOK, wantBody: `"status":"draft"`},
+		{name: "list by status", method: http.MethodGet, target: "/api/transfers?status=received", wantStatus: http.StatusOK, wantBody: "null"},
+		{name: "list unknown status", method: http.MethodGet, target: "/api/transfers?status=lost", wantStatus: http.StatusBadRequest, wantBody: `unknown transfer status "lost"`},
+		{name: "get", method: http.MethodGet, target: "/api/transfers/1", wantStatus: http.StatusOK, wantBody: `"lines":[{"id":1,"product_id":1,"quantity":4,"received_quantity":0}]`},
+		{name: "get unknown", method: http.MethodGet, target: "/api/transfers/9", wantStatus: http.StatusNotFound, wantBody: "Transfer with ID 9 not found"},
+		{name: "get invalid ID", method: http.MethodGet, target: "/api/transfers/abc", wantStatus: http.StatusBadRequest, wantBody: "Invalid transfer ID"},
+		{name: "create same outlet", method: http.MethodPost, target: "/api/transfers", body: `{"from_outlet_id":1,"to_outlet_id":1,"lines":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusBadRequest, wantBody: "two different outlets"},
+		{name: "create invalid body", method: http.MethodPost, target: "/api/transfers", body: `{`, wantStatus: http.StatusBadRequest, wantBody: "Invalid request body"},
+		{name: "update", method: http.MethodPut, target: "/api/transfers/1", body: `{"from_outlet_id":1,"to_outlet_id":2,"lines":[{"product_id":2,"variant_id":1,"quantity":2}]}`, wantStatus: http.StatusOK, wantBody: `"variant_id":1,"quantity":2`},
+		{name: "delete", method: http.MethodDelete, target: "/api/transfers/1", wantStatus: http.StatusOK, wantBody: "Transfer deleted successfully"},
+		{name: "send", method: http.MethodPost, target: "/api/transfers/1/send", wantStatus: http.StatusOK, wantBody: `"status":"in_transit"`},
+		{name: "receive draft", method: http.MethodPost, target: "/api/transfers/1/receive", body: `{"lines":[{"line_id":1,"quantity":4}]}`, wantStatus: http.StatusConflict, wantBody: "is draft, not in_transit"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := []handlerCase{
+				{method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG1","name":"Cabang 1"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPost, target: "/api/transfers", body: `{"from_outlet_id":1,"to_outlet_id":2,"lines":[{"product_id":1,"quantity":4}]}`, wantStatus: http.StatusCreated},
+			}
+			for _, s := range seed {
+				s.run(t, h.router)
+			}
+
+			tc.run(t, h.router)
+		})
+	}
+}
+
+func TestTransferHandlerLifecycle(t *testing.T) {
+	h := newTestHandlers(t)
+
+	steps := []handlerCase{
+		{name: "branch", method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG1","name":"Cabang 1"}`, wantStatus: http.StatusCreated},
+		{name: "draft", method: http.MethodPost, target: "/api/transfers", body: `{"from_outlet_id":1,"to_outlet_id":2,"lines":[{"product_id":1,"quantity":12}]}`, wantStatus: http.StatusCreated, wantBody: `"status":"draft"`},
+		{name: "send beyond stock", method: http.MethodPost, target: "/api/transfers/1/send", wantStatus: http.StatusBadRequest, wantBody: "insufficient stock for product with ID 1"},
+		{name: "fix quantity", method: http.MethodPut, target: "/api/transfers/1", body: `{"from_outlet_id":1,"to_outlet_id":2,"lines":[{"product_id":1,"quantity":4}]}`, wantStatus: http.StatusOK},
+		{name: "send", method: http.MethodPost, target: "/api/transfers/1/send", wantStatus: http.StatusOK, wantBody: `"status":"in_transit"`},
+		{name: "edit sent", method: http.MethodPut, target: "/api/transfers/1", body: `{"from_outlet_id":1,"to_outlet_id":2,"lines":[{"product_id":1,"quantity":1}]}`, wantStatus: http.StatusConflict, wantBody: "invalid transfer status"},
+		{name: "receive too much", method: http.MethodPost, target: "/api/transfers/1/receive", body: `{"lines":[{"line_id":2,"quantity":5}]}`, wantStatus: http.StatusBadRequest, wantBody: "exceeds the quantity sent"},
+		{name: "receive part", method: http.MethodPost, target: "/api/transfers/1/receive", body: `{"lines":[{"line_id":2,"quantity":3,"note":"1 pecah"}]}`, wantStatus: http.StatusOK, wantBody: `"status":"in_transit"`},
+		{name: "close", method: http.MethodPost, target: "/api/transfers/1/receive", body: `{"close":true}`, wantStatus: http.StatusOK, wantBody: `"received_quantity":3,"discrepancy":1,"discrepancy_note":"1 pecah"`},
+		{name: "branch stock", method: http.MethodGet, target: "/api/outlets/2/products", wantStatus: http.StatusOK, wantBody: `"product_id":1,"stock":3`},
+		{name: "main outlet stock", method: http.MethodGet, target: "/api/outlets/1/products", wantStatus: http.StatusOK, wantBody: `"product_id":1,"stock":6`},
+		{name: "delete received", method: http.MethodDelete, target: "/api/transfers/1", wantStatus: http.StatusConflict, wantBody: "is received, not draft"},
+	}
+	for _, step := range steps {
+		step.run(t, h.router)
+	}
+}
+
+func TestTransferHandlerAccess(t *testing.T) {
+	const secret = "secret"
+	token := func(outlets ...int) map[string]string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{Outlets: outlets}).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]string{"Authorization": "Bearer " + signed}
+	}
+	source, destination, other := token(1), token(2), token(3)
+
+	tests := []handlerCase{
+		{name: "destination sees transfer", method: http.MethodGet, target: "/api/transfers/1", header: destination, wantStatus: http.StatusOK},
+		{name: "other outlet", method: http.MethodGet, target: "/api/transfers/1", header: other, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "other outlet list", method: http.MethodGet, target: "/api/transfers", header: other, wantStatus: http.StatusOK, wantBody: "null"},
+		{name: "destination lists transfer", method: http.MethodGet, target: "/api/transfers", header: destination, wantStatus: http.StatusOK, wantBody: `"to_outlet_id":2`},
+		{name: "draft from other outlet", method: http.MethodPost, target: "/api/transfers", body: `{"from_outlet_id":1,"to_outlet_id":2,"lines":[{"product_id":1,"quantity":1}]}`, header: destination, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "destination sends", method: http.MethodPost, target: "/api/transfers/1/send", header: destination, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+		{name: "source sends", method: http.MethodPost, target: "/api/transfers/1/send", header: source, wantStatus: http.StatusOK},
+		{name: "source receives", method: http.MethodPost, target: "/api/transfers/1/receive", body: `{"close":true}`, header: source, wantStatus: http.StatusForbidden, wantBody: "Outlet not allowed"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+
+			seed := []handlerCase{
+				{method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG1","name":"Cabang 1"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPost, target: "/api/outlets", body: `{"code":"CBG2","name":"Cabang 2"}`, wantStatus: http.StatusCreated},
+				{method: http.MethodPost, target: "/api/transfers", body: `{"from_outlet_id":1,"to_outlet_id":2,"lines":[{"product_id":1,"quantity":4}]}`, wantStatus: http.StatusCreated},
+			}
+			for _, s := range seed {
+				s.run(t, h.router)
+			}
+
+			tc.run(t, middleware.Auth(secret)(h.router))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+// UnitHandler handles HTTP requests for units of measure
+type UnitHandler struct {
+	service *services.UnitService
+}
+
+// NewUnitHandler creates a new UnitHandler
+func NewUnitHandler(service *services.UnitService) *UnitHandler {
+	return &UnitHandler{service: service}
+}
+
+// RegisterRoutes registers the unit of measure routes
+func (h *UnitHandler) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /api/units", h.ListUnits)
+	r.HandleFunc("POST /api/units", h.CreateUnit)
+	r.HandleFunc("PUT /api/units/{code}", h.UpdateUnit)
+}
+
+// ListUnits menampilkan semua satuan
+// @Summary List all units of measure
+// @Description Get all units of measure with their quantity precision and conversion factor
+// @Tags units
+// @Produce json
+// @Success 200 {array} models.Unit
+// @Router /units [get]
+func (h *UnitHandler) ListUnits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	units, err := h.service.GetAllUnits(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(units)
+}
+
+// CreateUnit membuat satuan baru
+// @Summary Create a new unit of measure
+// @Description Create a new unit of measure
+// @Tags units
+// @Accept json
+// @Produce json
+// @Param unit body models.Unit true "Unit object"
+// @Success 201 {object} models.Unit
+// @Failure 400 {string} string "Invalid request body"
+// @Router /units [post]
+func (h *UnitHandler) CreateUnit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var newUnit models.Unit
+	err := json.NewDecoder(r.Body).Decode(&newUnit)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	createdUnit, err := h.service.CreateUnit(r.Context(), newUnit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdUnit)
+}
+
+// UpdateUnit mengupdate satuan berdasarkan kode
+// @Summary Update a unit of measure
+// @Description Update unit name, precision or conversion factor
+// @Tags units
+// @Accept json
+// @Produce json
+// @Param code path string true "Unit code"
+// @Param unit body models.Unit true "Unit object"
+// @Success 200 {object} models.Unit
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Unit not found"
+// @Router /units/{code} [put]
+func (h *UnitHandler) UpdateUnit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	code := r.PathValue("code")
+	var updatedUnit models.Unit
+	err := json.NewDecoder(r.Body).Decode(&updatedUnit)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	unit, err := h.service.UpdateUnit(r.Context(), code, updatedUnit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(unit)
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUnitHandler(t *testing.T) {
+	tests := []handlerCase{
+		{name: "list", method: http.MethodGet, target: "/api/units", wantStatus: http.StatusOK, wantBody: `"code":"gram"`},
+		{name: "get is not supported", method: http.MethodGet, target: "/api/units/kg", wantStatus: http.StatusMethodNotAllowed, wantHeader: map[string]string{"Allow": "PUT"}},
+		{name: "create", method: http.MethodPost, target: "/api/units", body: `{"code":"ons","name":"Ons","precision":1,"base_unit":"kg","factor":0.1}`, wantStatus: http.StatusCreated, wantBody: `"code":"ons"`},
+		{name: "create invalid precision", method: http.MethodPost, target: "/api/units", body: `{"code":"mg","name":"Milligram","precision":5}`, wantStatus: http.StatusBadRequest, wantBody: "precision must be between 0 and 3"},
+		{name: "update", method: http.MethodPut, target: "/api/units/pcs", body: `{"name":"Buah"}`, wantStatus: http.StatusOK, wantBody: `"name":"Buah"`},
+		{name: "update missing", method: http.MethodPut, target: "/api/units/ton", body: `{"name":"Ton"}`, wantStatus: http.StatusNotFound, wantBody: "Unit ton not found"},
+		{name: "update without code", method: http.MethodPut, target: "/api/units", body: `{"name":"Ton"}`, wantStatus: http.StatusMethodNotAllowed, wantHeader: map[string]string{"Allow": "GET, HEAD, POST"}},
+		{name: "method not allowed", method: http.MethodDelete, target: "/api/units/kg", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(t)
+			tc.run(t, h.router)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "info", FormatJSON).With("component", "test")
+
+	logger.InfoContext(WithRequestID(context.Background(), "req-1"), "sale created", "transaction_id", 7)
+	logger.InfoContext(context.Background(), "outside a request")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2: %s", len(lines), buf.String())
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if first["request_id"] != "req-1" || first["component"] != "test" || first["transaction_id"] != float64(7) {
+		t.Errorf("log line = %v, want request_id, component and transaction_id", first)
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("log line outside a request = %s, want no request_id", lines[1])
+	}
+}
+
+func TestNewLevelAndFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "warn", FormatText)
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	if got := buf.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "level=WARN msg=shown") {
+		t.Errorf("log = %q, want only the warning in text format", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+		"loud":  slog.LevelInfo,
+	}
+	for level, want := range tests {
+		if got := ParseLevel(level); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestNewAddsTraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "info", FormatJSON)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	logger.InfoContext(ctx, "traced")
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if line["trace_id"] != traceID.String() || line["span_id"] != spanID.String() {
+		t.Errorf("log line = %v, want trace_id and span_id", line)
+	}
+}
@@ -1,188 +1,298 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"kasir-api/config"
 	"kasir-api/database"
 	"kasir-api/handlers"
+	"kasir-api/logging"
+	"kasir-api/metrics"
+	"kasir-api/middleware"
+	"kasir-api/models"
 	"kasir-api/repositories"
 	"kasir-api/services"
-
-	"github.com/spf13/viper"
+	"kasir-api/tracing"
 
 	_ "kasir-api/docs"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-// AppConfig holds the application configuration
-type AppConfig struct {
-	Port   string `mapstructure:"PORT"`
-	DBConn string `mapstructure:"DB_CONN"`
-}
-
 // @title Kasir API
 // @version 1.0
 // @description API untuk sistem kasir sederhana
 // @host localhost:8080
 // @BasePath /api
 
-// healthHandler menampilkan status health API
-// @Summary Health check
-// @Description Check API health status
-// @Tags health
-// @Produce json
-// @Success 200 {object} map[string]string
-// @Router /health [get]
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "OK",
-		"message": "API Running",
-		"version": "1.0",
-		"stage":   "development",
-	})
-}
-
 func main() {
-	// Load configuration from environment
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-
-	if _, err := os.Stat(".env"); err == nil {
-		viper.SetConfigFile(".env")
-		_ = viper.ReadInConfig()
+	// Load configuration from config.yaml, .env and environment variables
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		os.Exit(1)
 	}
 
-	port := viper.GetString("PORT")
-	if port == "" {
-		port = os.Getenv("PORT")
-	}
-	if port == "" {
-		port = "8080"
+	// Structured logs on stdout; the standard log package goes through it too
+	slog.SetDefault(logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format))
+
+	// Cancelled on SIGINT/SIGTERM to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Tracing is set up before the database so every SQL statement gets a span
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName:    cfg.App.Name,
+		ServiceVersion: cfg.App.Version,
+		Environment:    cfg.App.Environment,
+		Exporter:       cfg.Tracing.Exporter,
+		Endpoint:       cfg.Tracing.Endpoint,
+		SampleRatio:    cfg.Tracing.SampleRatio,
+		Stdout:         os.Stdout,
+	})
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
 	}
 
-	// Check for DATABASE_URL first (Railway provides this)
+	// DATABASE_URL (Railway/Supabase format) takes precedence over DB_* settings
+	dbOptions := database.Options{
+		MaxOpenConns:      cfg.Database.MaxOpenConns,
+		MaxIdleConns:      cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:   cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime:   cfg.Database.ConnMaxIdleTime,
+		ConnectAttempts:   cfg.Database.ConnectAttempts,
+		ConnectBackoff:    cfg.Database.ConnectBackoff,
+		ConnectMaxBackoff: cfg.Database.ConnectMaxBackoff,
+		PingTimeout:       cfg.Database.PingTimeout,
+	}
 	var db *sql.DB
-	databaseURL := viper.GetString("DATABASE_URL")
-	if databaseURL == "" {
-		databaseURL = os.Getenv("DATABASE_URL")
+	if cfg.Database.URL != "" {
+		db, err = database.InitDB(ctx, cfg.Database.URL, dbOptions)
+	} else {
+		db, err = database.InitDBWithConfig(ctx, database.DBConfig{
+			Host:     cfg.Database.Host,
+			Port:     strconv.Itoa(cfg.Database.Port),
+			User:     cfg.Database.User,
+			Password: cfg.Database.Password,
+			DBName:   cfg.Database.Name,
+			SSLMode:  cfg.Database.SSLMode,
+		}, dbOptions)
+	}
+	if err != nil {
+		slog.Error("failed to initialize database", "error", err)
+		os.Exit(1)
 	}
 
-	// Debug: print whether DATABASE_URL is set
-	if databaseURL != "" {
-		fmt.Println("DATABASE_URL is set, length:", len(databaseURL))
-	} else {
-		fmt.Println("DATABASE_URL is NOT set, checking individual vars...")
-		fmt.Println("DB_HOST:", viper.GetString("DB_HOST"))
+	// Prometheus metrics; nil records nothing when the feature is off
+	var appMetrics *metrics.Metrics
+	if cfg.Features.Metrics {
+		appMetrics = metrics.New()
+		appMetrics.RegisterDB(db, "kasir")
 	}
 
-	if databaseURL != "" {
-		// Use DATABASE_URL directly (Railway/Supabase format)
-		fmt.Println("Using DATABASE_URL connection string")
-		var err error
-		db, err = database.InitDB(databaseURL)
-		if err != nil {
-			log.Fatal("Failed to initialize database:", err)
-		}
-	} else {
-		// Fallback to individual environment variables - use viper
-		dbHost := viper.GetString("DB_HOST")
-		dbPort := viper.GetString("DB_PORT")
-		dbUser := viper.GetString("DB_USER")
-		dbPassword := viper.GetString("DB_PASSWORD")
-		dbName := viper.GetString("DB_NAME")
-		dbSSLMode := viper.GetString("DB_SSLMODE")
-
-		if dbPort == "" {
-			dbPort = "5432"
-		}
-		if dbSSLMode == "" {
-			dbSSLMode = "require"
-		}
+	// Initialize health layers
+	healthRepo := repositories.NewHealthRepository(db, cfg.Database.PingTimeout)
+	healthService := services.NewHealthService(healthRepo, cfg.App.Name, cfg.App.Version, cfg.App.Environment)
+	healthHandler := handlers.NewHealthHandler(healthService)
 
-		dbConfig := database.DBConfig{
-			Host:     dbHost,
-			Port:     dbPort,
-			User:     dbUser,
-			Password: dbPassword,
-			DBName:   dbName,
-			SSLMode:  dbSSLMode,
-		}
+	// Initialize unit layers
+	unitRepo := repositories.NewUnitRepository(db)
+	unitService := services.NewUnitService(unitRepo)
+	unitHandler := handlers.NewUnitHandler(unitService)
 
-		var err error
-		db, err = database.InitDBWithConfig(dbConfig)
-		if err != nil {
-			log.Fatal("Failed to initialize database:", err)
-		}
-	}
-	defer db.Close()
+	// Initialize audit layers
+	auditService := services.NewAuditService(repositories.NewAuditRepository(db))
+	auditHandler := handlers.NewAuditHandler(auditService)
 
 	// Initialize product layers
 	productRepo := repositories.NewProductRepository(db)
-	productService := services.NewProductService(productRepo)
-	productHandler := handlers.NewProductHandler(productService)
+	variantRepo := repositories.NewProductVariantRepository(db)
+	productService := services.NewProductService(productRepo, variantRepo)
+	productHandler := handlers.NewProductHandler(productService, unitService, auditService)
 
 	// Initialize category layers
 	categoryRepo := repositories.NewCategoryRepository(db)
 	categoryService := services.NewCategoryService(categoryRepo)
-	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, auditService)
+
+	// Initialize outlet layers
+	outletRepo := repositories.NewOutletRepository(db)
+	outletService := services.NewOutletService(outletRepo, productRepo)
+	outletHandler := handlers.NewOutletHandler(outletService)
+	transferService := services.NewTransferService(repositories.NewTransferRepository(db), outletRepo, productRepo, variantRepo)
+	transferHandler := handlers.NewTransferHandler(transferService)
 
-	// Initialize transaction layers
+	// Initialize idempotency layers
+	var idempotencyService *services.IdempotencyService
+	if cfg.Features.Idempotency {
+		idempotencyRepo := repositories.NewIdempotencyRepository(db)
+		idempotencyService = services.NewIdempotencyService(idempotencyRepo, cfg.Transactions.IdempotencyTTL)
+		go purgeIdempotencyKeys(ctx, idempotencyService)
+	}
+
+	// Initialize transaction, customer and loyalty layers
 	transactionRepo := repositories.NewTransactionRepository(db)
-	transactionService := services.NewTransactionService(transactionRepo, productRepo)
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	customerRepo := repositories.NewCustomerRepository(db)
+	customerService := services.NewCustomerService(customerRepo, transactionRepo)
+	customerHandler := handlers.NewCustomerHandler(customerService)
+	var loyaltyService *services.LoyaltyService
+	if cfg.Features.Loyalty {
+		loyaltyRepo := repositories.NewLoyaltyRepository(db)
+		loyaltyService = services.NewLoyaltyService(loyaltyRepo, categoryRepo, customerRepo, services.LoyaltyProgram{
+			RupiahPerPoint: cfg.Loyalty.RupiahPerPoint,
+			PointValue:     cfg.Loyalty.PointValue,
+			PointsTTL:      cfg.Loyalty.PointsTTL,
+		})
+		go expireLoyaltyPoints(ctx, loyaltyService)
+	}
+	priceListService := services.NewPriceListService(repositories.NewPriceListRepository(db), productRepo, variantRepo, cfg.App.Location)
+	priceListHandler := handlers.NewPriceListHandler(priceListService)
+	transactionService := services.NewTransactionService(transactionRepo, productRepo, variantRepo, customerRepo, outletRepo, unitService, loyaltyService, priceListService, appMetrics)
+	receiptService := services.NewReceiptService(transactionRepo, productRepo, variantRepo, models.StoreInfo{
+		Name:    cfg.Store.Name,
+		Address: cfg.Store.Address,
+		Phone:   cfg.Store.Phone,
+	}, cfg.App.Location)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, receiptService, idempotencyService, auditService)
+	openBillService := services.NewOpenBillService(repositories.NewOpenBillRepository(db), outletRepo, transactionService)
+	openBillHandler := handlers.NewOpenBillHandler(openBillService, auditService)
+	syncHandler := handlers.NewSyncHandler(transactionService, auditService)
 
 	// Initialize report layers
 	reportRepo := repositories.NewReportRepository(db)
-	reportService := services.NewReportService(reportRepo)
+	reportService := services.NewReportService(reportRepo, cfg.Transactions.BundleRevenueAttribution, cfg.App.Location)
 	reportHandler := handlers.NewReportHandler(reportService)
 
-	// Define HTTP routes
-	http.HandleFunc("/api/health", healthHandler)
-	http.HandleFunc("/api/products", productHandler.Handle)
-	http.HandleFunc("/api/products/", productHandler.Handle)
-	http.HandleFunc("/api/categories", categoryHandler.Handle)
-	http.HandleFunc("/api/categories/", categoryHandler.Handle)
-	http.HandleFunc("/api/transactions", transactionHandler.Handle)
-	http.HandleFunc("/api/transactions/", transactionHandler.Handle)
-	http.HandleFunc("/api/report", reportHandler.Handle)
-	http.HandleFunc("/api/report/", reportHandler.Handle)
+	// Define HTTP routes; everything except the health checks and the docs
+	// requires a bearer token
+	router := handlers.NewRouter()
+	healthHandler.RegisterRoutes(router)
+
+	api := router.Group(middleware.Auth(cfg.Auth.JWTSecret))
+	productHandler.RegisterRoutes(api)
+	unitHandler.RegisterRoutes(api)
+	categoryHandler.RegisterRoutes(api)
+	outletHandler.RegisterRoutes(api)
+	transferHandler.RegisterRoutes(api)
+	transactionHandler.RegisterRoutes(api)
+	openBillHandler.RegisterRoutes(api)
+	customerHandler.RegisterRoutes(api)
+	priceListHandler.RegisterRoutes(api)
+	if loyaltyService != nil {
+		handlers.NewLoyaltyHandler(loyaltyService).RegisterRoutes(api)
+	}
+	if cfg.Features.OfflineSync {
+		syncHandler.RegisterRoutes(api)
+	}
+	reportHandler.RegisterRoutes(api)
+	auditHandler.RegisterRoutes(api)
+
+	// Prometheus scrapes without a token, like the health checks
+	if cfg.Features.Metrics {
+		router.Handle("GET /metrics", appMetrics.Handler())
+	}
 
 	// Swagger documentation
-	http.HandleFunc("/swagger/", httpSwagger.WrapHandler)
-
-	fmt.Println("Server running on localhost:" + port)
-	fmt.Println("Swagger docs available at: http://localhost:" + port + "/swagger/index.html")
-	fmt.Println("\nAvailable endpoints:")
-	fmt.Println("Health:")
-	fmt.Println("  GET    /api/health       - API health check")
-	fmt.Println("\nProducts:")
-	fmt.Println("  GET    /api/products     - List all products")
-	fmt.Println("  GET    /api/products/{id} - Get product by ID")
-	fmt.Println("  POST   /api/products     - Create new product")
-	fmt.Println("  PUT    /api/products/{id} - Update product")
-	fmt.Println("  DELETE /api/products/{id} - Delete product")
-	fmt.Println("\nCategories:")
-	fmt.Println("  GET    /api/categories     - List all categories")
-	fmt.Println("  GET    /api/categories/{id} - Get category by ID")
-	fmt.Println("  POST   /api/categories     - Create new category")
-	fmt.Println("  PUT    /api/categories/{id} - Update category")
-	fmt.Println("  DELETE /api/categories/{id} - Delete category")
-	fmt.Println("\nTransactions:")
-	fmt.Println("  GET    /api/transactions     - List all transactions")
-	fmt.Println("  GET    /api/transactions/{id} - Get transaction by ID")
-	fmt.Println("  POST   /api/transactions     - Create new transaction")
-	fmt.Println("  DELETE /api/transactions/{id} - Delete transaction")
-	fmt.Println("\nReport:")
-	fmt.Println("  GET    /api/report/hari-ini  - Today's sales summary")
-	fmt.Println("  GET    /api/report?start_date=YYYY-MM-DD&end_date=YYYY-MM-DD - Sales by date range")
-
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	if cfg.Features.Swagger {
+		router.Handle("GET /swagger/", httpSwagger.WrapHandler)
+	}
+
+	handler := middleware.Chain(router,
+		middleware.RequestID,
+		middleware.Logging,
+		middleware.Recovery,
+		middleware.CORS(cfg.Server.CORSAllowedOrigins),
+		middleware.Tracing,
+		middleware.Metrics(appMetrics),
+	)
+
+	slog.Info("server starting",
+		"app", cfg.App.Name,
+		"version", cfg.App.Version,
+		"environment", cfg.App.Environment,
+		"address", cfg.GetServerAddress(),
+		"swagger", cfg.Features.Swagger,
+		"tracing", cfg.Tracing.Exporter,
+	)
+
+	server := &http.Server{
+		Addr:              cfg.GetServerAddress(),
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		db.Close()
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// Stop accepting connections and let in-flight requests finish before
+	// closing the database
+	slog.Info("shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("graceful shutdown did not complete", "error", err)
+	}
+
+	if err := db.Close(); err != nil {
+		slog.Error("failed to close database", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("failed to flush spans", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+// purgeIdempotencyKeys removes expired idempotency keys every hour until ctx is cancelled
+func purgeIdempotencyKeys(ctx context.Context, service *services.IdempotencyService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := service.PurgeExpired(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to purge expired idempotency keys", "error", err)
+			}
+		}
+	}
+}
+
+// expireLoyaltyPoints expires the loyalty points past their expiry every hour until ctx is cancelled
+func expireLoyaltyPoints(ctx context.Context, service *services.LoyaltyService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := service.ExpirePoints(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to expire loyalty points", "error", err)
+			}
+		}
+	}
 }
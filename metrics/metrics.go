@@ -0,0 +1,159 @@
+// Package metrics exposes Prometheus metrics for the API: request counts and
+// latency per route, the database connection pool and the sales counters the
+// store is alerted on.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"kasir-api/models"
+)
+
+// namespace prefixes every metric name
+const namespace = "kasir"
+
+// UnmatchedRoute is the route label of requests that matched no route, so
+// scans of random paths do not create a series per path
+const UnmatchedRoute = "unmatched"
+
+// Metrics holds the collectors of the API on their own registry. A nil
+// *Metrics is valid and records nothing, so services and tests that do not
+// care about metrics can leave it out.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	transactionsCreated *prometheus.CounterVec
+	itemsSold           prometheus.Counter
+	revenue             prometheus.Counter
+	transactionsVoided  prometheus.Counter
+	stockOutRejections  prometheus.Counter
+}
+
+// New creates the metrics on a new registry together with the Go runtime and
+// process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		transactionsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_created_total",
+			Help:      "Sales recorded, by source: pos for online checkout, offline for synced sales.",
+		}, []string{"source"}),
+		itemsSold: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "items_sold_total",
+			Help:      "Quantity sold in the unit of each product.",
+		}),
+		revenue: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "revenue_rupiah_total",
+			Help:      "Total amount of the sales recorded, in Rupiah.",
+		}),
+		transactionsVoided: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_voided_total",
+			Help:      "Transactions voided with their stock restored.",
+		}),
+		stockOutRejections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "stock_out_rejections_total",
+			Help:      "Checkouts rejected because a product was out of stock.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.transactionsCreated,
+		m.itemsSold,
+		m.revenue,
+		m.transactionsVoided,
+		m.stockOutRejections,
+	)
+	return m
+}
+
+// Registry returns the registry the metrics are registered on
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB exports the connection pool statistics of db (open, in use and
+// idle connections, waits) as go_sql_* gauges labelled with dbName
+func (m *Metrics) RegisterDB(db *sql.DB, dbName string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// ObserveRequest records a served request; route is the pattern that matched,
+// e.g. "GET /api/products/{id}", or empty when no route matched
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// TransactionCreated counts a recorded sale, its quantities and its amount;
+// offline tells whether it was synced from a POS that was offline
+func (m *Metrics) TransactionCreated(transaction *models.Transaction, offline bool) {
+	if m == nil {
+		return
+	}
+	source := "pos"
+	if offline {
+		source = "offline"
+	}
+	m.transactionsCreated.WithLabelValues(source).Inc()
+	for _, d := range transaction.Details {
+		m.itemsSold.Add(d.Quantity)
+	}
+	m.revenue.Add(float64(transaction.TotalAmount))
+}
+
+// TransactionVoided counts a voided transaction
+func (m *Metrics) TransactionVoided() {
+	if m == nil {
+		return
+	}
+	m.transactionsVoided.Inc()
+}
+
+// StockOutRejected counts a checkout rejected for insufficient stock
+func (m *Metrics) StockOutRejected() {
+	if m == nil {
+		return
+	}
+	m.stockOutRejections.Inc()
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+// scrape returns the metrics as served on /metrics
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+// assertLines fails unless every line of want appears in the scraped metrics
+func assertLines(t *testing.T, got string, want ...string) {
+	t.Helper()
+	for _, line := range want {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("metrics do not contain %q", line)
+		}
+	}
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+	m.ObserveRequest(http.MethodGet, "GET /api/products/{id}", http.StatusOK, 20*time.Millisecond)
+	m.ObserveRequest(http.MethodGet, "GET /api/products/{id}", http.StatusNotFound, 5*time.Millisecond)
+	m.ObserveRequest(http.MethodGet, "", http.StatusNotFound, time.Millisecond)
+
+	assertLines(t, scrape(t, m),
+		`kasir_http_requests_total{method="GET",route="GET /api/products/{id}",status="200"} 1`,
+		`kasir_http_requests_total{method="GET",route="GET /api/products/{id}",status="404"} 1`,
+		`kasir_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`kasir_http_request_duration_seconds_count{method="GET",route="GET /api/products/{id}"} 2`,
+		`kasir_http_request_duration_seconds_bucket{method="GET",route="GET /api/products/{id}",le="0.01"} 1`,
+	)
+}
+
+func TestBusinessCounters(t *testing.T) {
+	m := New()
+	m.TransactionCreated(&models.Transaction{
+		TotalAmount: 23000,
+		Details:     []models.TransactionDetail{{Quantity: 1}, {Quantity: 1.5}},
+	}, false)
+	m.TransactionCreated(&models.Transaction{
+		TotalAmount: 5000,
+		Details:     []models.TransactionDetail{{Quantity: 1}},
+	}, true)
+	m.TransactionVoided()
+	m.StockOutRejected()
+	m.StockOutRejected()
+
+	assertLines(t, scrape(t, m),
+		`kasir_transactions_created_total{source="pos"} 1`,
+		`kasir_transactions_created_total{source="offline"} 1`,
+		`kasir_items_sold_total 3.5`,
+		`kasir_revenue_rupiah_total 28000`,
+		`kasir_transactions_voided_total 1`,
+		`kasir_stock_out_rejections_total 2`,
+	)
+}
+
+func TestRegisterDB(t *testing.T) {
+	db := sql.OpenDB(nopConnector{})
+	db.SetMaxOpenConns(4)
+	defer db.Close()
+
+	m := New()
+	m.RegisterDB(db, "kasir")
+	assertLines(t, scrape(t, m),
+		`go_sql_max_open_connections{db_name="kasir"} 4`,
+		`go_sql_open_connections{db_name="kasir"} 0`,
+	)
+}
+
+// nopConnector opens a *sql.DB without a database; the pool statistics are
+// read without ever connecting
+type nopConnector struct{}
+
+func (nopConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("no database")
+}
+
+func (nopConnector) Driver() driver.Driver { return nil }
+
+func TestNilMetricsRecordsNothing(t *testing.T) {
+	var m *Metrics
+	m.ObserveRequest(http.MethodGet, "", http.StatusOK, time.Millisecond)
+	m.TransactionCreated(&models.Transaction{}, false)
+	m.TransactionVoided()
+	m.StockOutRejected()
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RoleOwner is the role of the owners of the business, who have access to
+// every outlet
+const RoleOwner = "owner"
+
+// Claims are the claims of an API token: the subject identifies the user, the
+// role what they may do and the outlets where they may do it
+type Claims struct {
+	Role string `json:"role,omitempty"`
+	// Outlets are the IDs of the outlets the user is assigned to; owners and
+	// tokens without outlets have access to every outlet
+	Outlets []int `json:"outlets,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// AllOutlets reports whether the claims give access to every outlet; so do
+// nil claims, which requests get when authentication is disabled
+func (c *Claims) AllOutlets() bool {
+	return c == nil || c.Role == RoleOwner || len(c.Outlets) == 0
+}
+
+// IsOwner reports whether the claims are those of an owner; so are nil
+// claims, which requests get when authentication is disabled
+func (c *Claims) IsOwner() bool {
+	return c == nil || c.Role == RoleOwner
+}
+
+// OutletAllowed reports whether the claims give access to an outlet
+func (c *Claims) OutletAllowed(id int) bool {
+	return c.AllOutlets() || slices.Contains(c.Outlets, id)
+}
+
+// claimsKey is the context key of the authenticated claims
+type claimsKey struct{}
+
+// ClaimsFromContext returns the claims of the authenticated request, or nil
+// when the request was not authenticated
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// Auth requires a valid HS256 bearer token signed with secret and stores its
+// claims in the request context. With an empty secret authentication is
+// disabled, which the configuration only allows outside production.
+func Auth(secret string) Middleware {
+	if secret == "" {
+		slog.Warn("auth.jwt_secret is not set, API authentication is disabled")
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	keyFunc := func(*jwt.Token) (interface{}, error) { return []byte(secret), nil }
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || tokenString == "" {
+				unauthorized(w, "Missing bearer token")
+				return
+			}
+
+			claims := &Claims{}
+			if _, err := parser.ParseWithClaims(tokenString, claims, keyFunc); err != nil {
+				unauthorized(w, "Invalid token")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+// unauthorized writes a 401 response asking for a bearer token
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="kasir-api"`)
+	http.Error(w, message, http.StatusUnauthorized)
+}
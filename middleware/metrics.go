@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"kasir-api/metrics"
+)
+
+// Metrics records the count and latency of requests per route. It must wrap
+// the router directly: the route pattern is read from the request after the
+// router has matched it, and requests copied by outer middleware do not see it.
+// A handler that panics before writing a response is counted as a 500, the
+// response Recovery sends for it.
+func Metrics(m *metrics.Metrics) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			completed := false
+			defer func() {
+				status := rec.Status()
+				if !completed && rec.status == 0 {
+					status = http.StatusInternalServerError
+				}
+				m.ObserveRequest(r.Method, r.Pattern, status, time.Since(start))
+			}()
+
+			next.ServeHTTP(rec, r)
+			completed = true
+		})
+	}
+}
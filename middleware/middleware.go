@@ -0,0 +1,55 @@
+// Package middleware provides the HTTP middleware wrapped around the API
+// routes: request IDs, access logging, panic recovery, CORS, tracing,
+// Prometheus metrics and bearer token authentication.
+package middleware
+
+import "net/http"
+
+// Middleware wraps a handler with extra behaviour
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with middlewares; the first middleware is the outermost, so it
+// sees the request first and the response last
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// statusRecorder captures the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Status returns the status code written so far, 200 when nothing was
+// written yet
+func (rec *statusRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
@@ -0,0 +1,357 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"kasir-api/logging"
+	"kasir-api/metrics"
+)
+
+// ok is a handler that writes 200 "ok"
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+// captureLog sends JSON logs into a buffer for the duration of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, "debug", logging.FormatJSON))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logEntries decodes the JSON log lines in buf
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	Chain(ok, mark("first"), mark("second")).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("order = %v, want first,second", order)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "generated"},
+		{name: "taken from header", header: "pos-42.checkout:7", wantSame: true},
+		{name: "unsafe header is replaced", header: "id with spaces\n"},
+		{name: "too long header is replaced", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inContext string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				inContext = logging.RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got == "" || got != inContext {
+				t.Fatalf("response ID = %q, context ID = %q, want the same non-empty ID", got, inContext)
+			}
+			if (got == tt.header) != tt.wantSame {
+				t.Errorf("request ID = %q, header was %q", got, tt.header)
+			}
+		})
+	}
+}
+
+func TestLogging(t *testing.T) {
+	buf := captureLog(t)
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "looking up product")
+		http.Error(w, "Not found", http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/products/9?x=1", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	Chain(notFound, RequestID, Logging).ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logEntries(t, buf)
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		if entry["request_id"] != "req-1" {
+			t.Errorf("entry %v has no request_id req-1", entry)
+		}
+	}
+	access := entries[1]
+	if access["msg"] != "request" || access["level"] != "WARN" || access["method"] != "GET" ||
+		access["path"] != "/api/products/9" || access["query"] != "x=1" || access["status"] != float64(404) {
+		t.Errorf("access log = %v", access)
+	}
+	if _, ok := access["duration_ms"].(float64); !ok {
+		t.Errorf("access log = %v, want duration_ms", access)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	buf := captureLog(t)
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	Chain(panicking, RequestID, Logging, Recovery).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"error":"Internal server error","request_id":"req-1"}` {
+		t.Errorf("body = %s, want a JSON error with the request ID", got)
+	}
+
+	entries := logEntries(t, buf)
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want the panic and the access log", len(entries))
+	}
+	panicked := entries[0]
+	if panicked["panic"] != "boom" || panicked["request_id"] != "req-1" || !strings.Contains(panicked["stack"].(string), "goroutine") {
+		t.Errorf("panic log = %v, want the panic, request ID and stack", panicked)
+	}
+	if entries[1]["status"] != float64(500) || entries[1]["level"] != "ERROR" {
+		t.Errorf("access log = %v, want status 500 at error level", entries[1])
+	}
+}
+
+func TestRecoveryAfterHeadersSent(t *testing.T) {
+	captureLog(t)
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	})
+
+	rec := httptest.NewRecorder()
+	Recovery(panicking).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Errorf("response = %d %q, want the status already sent and no error body", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/products/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /api/transactions", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := Chain(mux, Recovery, Metrics(m))
+
+	captureLog(t)
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/products/1", nil),
+		httptest.NewRequest(http.MethodGet, "/api/products/2", nil),
+		httptest.NewRequest(http.MethodGet, "/wp-login.php", nil),
+		httptest.NewRequest(http.MethodPost, "/api/transactions", nil),
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`kasir_http_requests_total{method="GET",route="GET /api/products/{id}",status="200"} 2`,
+		`kasir_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`kasir_http_requests_total{method="POST",route="POST /api/transactions",status="500"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want+"\n") {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/products/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/report", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database down", http.StatusInternalServerError)
+	})
+	handler := Chain(mux, RequestID, Tracing)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/products/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/report", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	product := spans[0]
+	if product.Name() != "GET /api/products/{id}" {
+		t.Errorf("span name = %q, want the route pattern", product.Name())
+	}
+	if got := product.Parent().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the one of the traceparent header", got)
+	}
+	attrs := make(map[string]string)
+	for _, attr := range product.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	for key, want := range map[string]string{
+		"http.route":                       "GET /api/products/{id}",
+		"url.path":                         "/api/products/7",
+		"http.response.status_code":        "200",
+		"http.request.header.x-request-id": `["req-1"]`,
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %q, want %q", key, attrs[key], want)
+		}
+	}
+
+	if report := spans[1]; report.Status().Code != codes.Error {
+		t.Errorf("status of a 500 = %v, want error", report.Status())
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name       string
+		origins    []string
+		method     string
+		header     map[string]string
+		wantStatus int
+		wantOrigin string
+	}{
+		{name: "allowed origin", origins: []string{"https://pos.example.com"}, method: http.MethodGet, header: map[string]string{"Origin": "https://pos.example.com"}, wantStatus: http.StatusOK, wantOrigin: "https://pos.example.com"},
+		{name: "other origin", origins: []string{"https://pos.example.com"}, method: http.MethodGet, header: map[string]string{"Origin": "https://evil.example.com"}, wantStatus: http.StatusOK},
+		{name: "any origin", origins: []string{"*"}, method: http.MethodGet, header: map[string]string{"Origin": "https://evil.example.com"}, wantStatus: http.StatusOK, wantOrigin: "*"},
+		{name: "disabled", method: http.MethodGet, header: map[string]string{"Origin": "https://pos.example.com"}, wantStatus: http.StatusOK},
+		{name: "preflight", origins: []string{"https://pos.example.com"}, method: http.MethodOptions, header: map[string]string{"Origin": "https://pos.example.com", "Access-Control-Request-Method": "POST"}, wantStatus: http.StatusNoContent, wantOrigin: "https://pos.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/products", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			CORS(tt.origins)(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	const secret = "test-secret"
+	sign := func(method jwt.SigningMethod, key interface{}, claims Claims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := Claims{Role: "cashier", RegisteredClaims: jwt.RegisteredClaims{Subject: "7", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}}
+	expired := Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "7", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))}}
+
+	tests := []struct {
+		name       string
+		secret     string
+		header     string
+		wantStatus int
+	}{
+		{name: "valid token", secret: secret, header: "Bearer " + sign(jwt.SigningMethodHS256, []byte(secret), valid), wantStatus: http.StatusOK},
+		{name: "missing token", secret: secret, wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", secret: secret, header: "Basic dXNlcjpwYXNz", wantStatus: http.StatusUnauthorized},
+		{name: "wrong secret", secret: secret, header: "Bearer " + sign(jwt.SigningMethodHS256, []byte("other"), valid), wantStatus: http.StatusUnauthorized},
+		{name: "expired", secret: secret, header: "Bearer " + sign(jwt.SigningMethodHS256, []byte(secret), expired), wantStatus: http.StatusUnauthorized},
+		{name: "unsigned", secret: secret, header: "Bearer " + sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), wantStatus: http.StatusUnauthorized},
+		{name: "disabled", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			var claims *Claims
+			handler := Auth(tt.secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				claims = ClaimsFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate header")
+			}
+			if tt.name == "valid token" && (claims == nil || claims.Subject != "7" || claims.Role != "cashier") {
+				t.Errorf("claims = %+v, want subject 7 with role cashier", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"kasir-api/logging"
+	"kasir-api/models"
+)
+
+// Recovery turns a panic in a handler into a 500 JSON error response instead
+// of a dropped connection, and logs the panic with its stack
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// Let the server abort the response as it was meant to
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			slog.ErrorContext(r.Context(), "panic while serving request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(err),
+				"stack", string(debug.Stack()),
+			)
+
+			// Headers already sent cannot be replaced
+			if rec.status != 0 {
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:     "Internal server error",
+				RequestID: logging.RequestID(r.Context()),
+			})
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
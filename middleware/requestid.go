@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"net/http"
+
+	"kasir-api/logging"
+)
+
+// RequestIDHeader is the header carrying the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs taken from clients
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID, taken from the X-Request-ID header when
+// a client or proxy sent a usable one and generated otherwise. The ID is
+// echoed in the response header and stored in the request context, where the
+// logger picks it up.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = rand.Text()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// validRequestID reports whether a client supplied request ID is safe to log
+// and echo: not too long and only letters, digits and -_.:
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
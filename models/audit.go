@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// AuditEntry is a record of the append-only audit log: who made a change to
+// which entity, from where and in which request. Before and After hold the
+// fields the change touched: none before a create, every field before a
+// delete or purge and only the changed fields of an update or restore.
+type AuditEntry struct {
+	ID         int             `json:"id"`
+	Actor      string          `json:"actor,omitempty"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	IP         string          `json:"ip,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter represents query filters and the page of the audit log
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   int
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
@@ -1,8 +1,24 @@
 package models
 
+import "time"
+
 // Category represents a product category
 type Category struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+
+	// PointsMultiplier multiplies the loyalty points earned on the products of
+	// the category; it defaults to 1 and 0 earns no points
+	PointsMultiplier *float64 `json:"points_multiplier,omitempty"`
+
+	// DeletedAt is set once the category is deleted; it is no longer listed,
+	// but can be restored until it is purged
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// CategoryFilter represents query filters for categories
+type CategoryFilter struct {
+	// IncludeDeleted lists the deleted categories too
+	IncludeDeleted bool
 }
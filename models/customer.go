@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Customer represents a customer or member of the store
+type Customer struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Phone        string    `json:"phone,omitempty"`
+	Email        string    `json:"email,omitempty"`
+	MemberNumber string    `json:"member_number,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// PriceListID is the price list the customer buys from; nil is retail
+	PriceListID *int `json:"price_list_id,omitempty"`
+
+	// PointsBalance is the loyalty points balance; it only changes through sales
+	PointsBalance int `json:"points_balance"`
+}
+
+// CustomerFilter represents query filters for customers
+type CustomerFilter struct {
+	// Search matches part of the name, phone, email or member number
+	Search string
+}
+
+// CustomerLifetimeValue represents what a customer has spent over all their purchases
+type CustomerLifetimeValue struct {
+	CustomerID        int        `json:"customer_id"`
+	TotalTransactions int        `json:"total_transactions"`
+	TotalSpent        int        `json:"total_spent"`
+	AverageSpent      int        `json:"average_spent"`
+	FirstPurchaseAt   *time.Time `json:"first_purchase_at,omitempty"`
+	LastPurchaseAt    *time.Time `json:"last_purchase_at,omitempty"`
+}
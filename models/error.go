@@ -0,0 +1,8 @@
+package models
+
+// ErrorResponse represents an error returned as JSON, with the request ID to
+// quote when reporting it
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// Health statuses reported by the health endpoints
+const (
+	HealthStatusOK          = "OK"
+	HealthStatusUnavailable = "UNAVAILABLE"
+)
+
+// HealthStatus represents the liveness or readiness of the API
+type HealthStatus struct {
+	Status      string          `json:"status"`
+	Name        string          `json:"name"`
+	Version     string          `json:"version"`
+	Environment string          `json:"environment"`
+	Uptime      string          `json:"uptime"`
+	Database    *DatabaseHealth `json:"database,omitempty"`
+}
+
+// DatabaseHealth represents the result of a database ping and the connection pool state
+type DatabaseHealth struct {
+	Status    string    `json:"status"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	Pool      PoolStats `json:"pool"`
+}
+
+// PoolStats represents database connection pool statistics
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyRecord represents a stored Idempotency-Key and the response of
+// the request that first used it
+type IdempotencyRecord struct {
+	Owner        string
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ResponseBody string
+	Completed    bool
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Points ledger entry types
+const (
+	PointsEarn       = "earn"
+	PointsRedeem     = "redeem"
+	PointsExpire     = "expire"
+	PointsVoidEarn   = "void_earn"
+	PointsVoidRedeem = "void_redeem"
+)
+
+// PointsEntry represents a change of the loyalty points of a customer.
+// Entries that add points are lots: Remaining is what has not been redeemed,
+// voided or expired yet and ExpiresAt when it expires.
+type PointsEntry struct {
+	ID            int        `json:"id"`
+	CustomerID    int        `json:"customer_id"`
+	TransactionID *int       `json:"transaction_id,omitempty"`
+	Type          string     `json:"type"`
+	Points        int        `json:"points"`
+	Remaining     int        `json:"remaining,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// PointsStatement represents the points balance of a customer with its ledger, newest first
+type PointsStatement struct {
+	CustomerID int           `json:"customer_id"`
+	Balance    int           `json:"balance"`
+	PointValue int           `json:"point_value"`
+	Entries    []PointsEntry `json:"entries"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Open bill statuses
+const (
+	BillOpen       = "open"
+	BillCheckedOut = "checked_out"
+	BillCancelled  = "cancelled"
+)
+
+// OpenBill represents an order parked at an outlet under a table number or
+// customer name. Items are added, changed and removed while the bill is open;
+// checking it out turns it into a transaction. A bill that reserves stock
+// takes the stock of its items off the outlet as they are added, so other
+// sales cannot sell it first.
+type OpenBill struct {
+	ID           int            `json:"id"`
+	OutletID     int            `json:"outlet_id,omitempty"`
+	Label        string         `json:"label"`
+	Status       string         `json:"status"`
+	ReserveStock bool           `json:"reserve_stock,omitempty"`
+	Items        []OpenBillItem `json:"items,omitempty"`
+	// TotalAmount is what the items of an open bill cost at the current
+	// prices; it is left out when they cannot be priced any more
+	TotalAmount   int        `json:"total_amount,omitempty"`
+	TransactionID *int       `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+}
+
+// OpenBillItem represents an item on an open bill, with an optional note for
+// the kitchen
+type OpenBillItem struct {
+	ID        int     `json:"id"`
+	ProductID int     `json:"product_id"`
+	VariantID *int    `json:"variant_id,omitempty"`
+	Quantity  float64 `json:"quantity"`
+	Unit      string  `json:"unit,omitempty"`
+	Note      string  `json:"note,omitempty"`
+	// Reserved is the stock the item holds when its bill reserves stock, in
+	// the product unit; a bundle holds the stock of its components
+	Reserved []ReservedStock `json:"reserved,omitempty"`
+}
+
+// ReservedStock represents the quantity of a product, or of one of its
+// variants, held by an open bill item
+type ReservedStock struct {
+	ProductID int     `json:"product_id"`
+	VariantID *int    `json:"variant_id,omitempty"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// CheckoutBillRequest represents the request body for checking out an open
+// bill into a transaction
+type CheckoutBillRequest struct {
+	CustomerID *int `json:"customer_id,omitempty"`
+	// PriceListID prices the sale from a price list instead of the price
+	// list of the customer
+	PriceListID *int `json:"price_list_id,omitempty"`
+	// RedeemPoints pays part of the total with loyalty points of the customer
+	RedeemPoints int `json:"redeem_points,omitempty"`
+}
+
+// OpenBillFilter represents the optional filters for listing open bills
+type OpenBillFilter struct {
+	// OutletIDs limits the bills to those outlets; nil means every outlet
+	OutletIDs []int
+	Status    string
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// DefaultOutletID is the main outlet created with the outlets; it holds the
+// stock that existed before outlets and takes the sales that name no outlet
+const DefaultOutletID = 1
+
+// Outlet represents a store location selling from the shared catalog
+type Outlet struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OutletProduct represents the stock of a product, or of one of its variants,
+// at an outlet. Price overrides the catalog price at the outlet when set.
+type OutletProduct struct {
+	OutletID  int      `json:"outlet_id"`
+	ProductID int      `json:"product_id"`
+	VariantID *int     `json:"variant_id,omitempty"`
+	Stock     float64  `json:"stock"`
+	Price     *float64 `json:"price,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// PriceList represents a set of prices, such as wholesale or member prices,
+// that replaces the retail prices for the customers assigned to it. Retail
+// prices are the catalog prices and the rules of no price list.
+type PriceList struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PriceRule represents a price for a product, or one of its variants, that
+// applies instead of the catalog price while its conditions hold: the sale
+// uses its price list (every sale when PriceListID is nil), the line has at
+// least MinQuantity in the product unit, the sale is between StartsAt and
+// EndsAt and, when set, within the daily window from DailyFrom to DailyTo
+// ("15:00") in the store timezone. A rule with only StartsAt is a scheduled
+// price change.
+type PriceRule struct {
+	ID          int        `json:"id"`
+	PriceListID *int       `json:"price_list_id,omitempty"`
+	ProductID   int        `json:"product_id"`
+	VariantID   *int       `json:"variant_id,omitempty"`
+	MinQuantity float64    `json:"min_quantity,omitempty"`
+	Price       float64    `json:"price"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	DailyFrom   string     `json:"daily_from,omitempty"`
+	DailyTo     string     `json:"daily_to,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// PriceRuleFilter represents the optional filters for listing price rules
+type PriceRuleFilter struct {
+	ProductID   int
+	PriceListID int
+}
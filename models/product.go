@@ -1,12 +1,57 @@
 package models
 
+import "time"
+
 // Product represents a product in the store
 type Product struct {
-	ID         int     `json:"id"`
+	ID    int     `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+	Stock float64 `json:"stock"`
+	Unit  string  `json:"unit"`
+	// CategoryID is nil for a product without a category
+	CategoryID *int              `json:"category_id"`
+	IsBundle   bool              `json:"is_bundle"`
+	Variants   []ProductVariant  `json:"variants,omitempty"`
+	Components []BundleComponent `json:"components,omitempty"`
+	// DeletedAt is set once the product is deleted; it is no longer sold
+	// or listed, but can be restored until it is purged
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ProductVersion represents the state of a product after a change. Version 1
+// is the product as created; every update that changes the product adds the
+// next version with the user who made it, so the history answers what a
+// product cost at any time.
+type ProductVersion struct {
+	ProductID  int     `json:"product_id"`
+	Version    int     `json:"version"`
 	Name       string  `json:"name"`
 	Price      float64 `json:"price"`
-	Stock      int     `json:"stock"`
-	CategoryID int     `json:"category_id"`
+	Stock      float64 `json:"stock"`
+	Unit       string  `json:"unit"`
+	CategoryID *int    `json:"category_id"`
+	// Changes names the fields that differ from the previous version
+	Changes   []string  `json:"changes,omitempty"`
+	ChangedBy string    `json:"changed_by,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// BundleComponent represents a product contained in a bundle product
+type BundleComponent struct {
+	ProductID int     `json:"product_id"`
+	Name      string  `json:"name,omitempty"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// ProductVariant represents a sellable variant of a product (e.g. size or flavor)
+type ProductVariant struct {
+	ID        int     `json:"id"`
+	ProductID int     `json:"product_id"`
+	Name      string  `json:"name"`
+	SKU       string  `json:"sku"`
+	Price     float64 `json:"price"`
+	Stock     float64 `json:"stock"`
 }
 
 // ProductFilter represents query filters for products
@@ -15,4 +60,6 @@ type ProductFilter struct {
 	CategoryID int
 	MinPrice   float64
 	MaxPrice   float64
+	// IncludeDeleted lists the deleted products too
+	IncludeDeleted bool
 }
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Receipt represents a printable receipt of a transaction
+type Receipt struct {
+	TransactionID int           `json:"transaction_id"`
+	OutletID      int           `json:"outlet_id"`
+	Store         StoreInfo     `json:"store"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Items         []ReceiptItem `json:"items"`
+	TotalAmount   int           `json:"total_amount"`
+
+	// Loyalty points of the customer: PointsAmount is the part of the total
+	// paid with the redeemed points
+	PointsRedeemed int `json:"points_redeemed,omitempty"`
+	PointsAmount   int `json:"points_amount,omitempty"`
+	PointsEarned   int `json:"points_earned,omitempty"`
+}
+
+// StoreInfo represents the store identity printed on receipts
+type StoreInfo struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+}
+
+// ReceiptItem represents a sold line as printed on a receipt, in the unit it
+// was sold in
+type ReceiptItem struct {
+	Name      string  `json:"name"`
+	Quantity  float64 `json:"quantity"`
+	Unit      string  `json:"unit"`
+	UnitPrice int     `json:"unit_price"`
+	Subtotal  int     `json:"subtotal"`
+}
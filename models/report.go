@@ -5,12 +5,32 @@ type SalesReport struct {
 	TotalRevenue   int             `json:"total_revenue"`
 	TotalTransaksi int             `json:"total_transaksi"`
 	ProdukTerlaris *BestSellerInfo `json:"produk_terlaris,omitempty"`
+	RincianProduk  []ProductSales  `json:"rincian_produk,omitempty"`
+	RincianOutlet  []OutletSales   `json:"rincian_outlet,omitempty"`
 	StartDate      string          `json:"start_date,omitempty"`
 	EndDate        string          `json:"end_date,omitempty"`
 }
 
 // BestSellerInfo represents the best selling product info
 type BestSellerInfo struct {
-	Nama       string `json:"nama"`
-	QtyTerjual int    `json:"qty_terjual"`
+	Nama       string  `json:"nama"`
+	QtyTerjual float64 `json:"qty_terjual"`
+	Satuan     string  `json:"satuan"`
+}
+
+// ProductSales represents the quantity sold and revenue of a single product
+type ProductSales struct {
+	ProductID  int     `json:"product_id"`
+	Nama       string  `json:"nama"`
+	QtyTerjual float64 `json:"qty_terjual"`
+	Satuan     string  `json:"satuan"`
+	Pendapatan int     `json:"pendapatan"`
+}
+
+// OutletSales represents the revenue and number of transactions of a single outlet
+type OutletSales struct {
+	OutletID       int    `json:"outlet_id"`
+	Nama           string `json:"nama"`
+	TotalRevenue   int    `json:"total_revenue"`
+	TotalTransaksi int    `json:"total_transaksi"`
 }
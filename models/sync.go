@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Sync result statuses
+const (
+	SyncStatusAccepted  = "accepted"
+	SyncStatusDuplicate = "duplicate"
+	SyncStatusRejected  = "rejected"
+)
+
+// SyncTransactionsRequest represents a batch of transactions created offline
+type SyncTransactionsRequest struct {
+	Transactions []OfflineTransaction `json:"transactions"`
+}
+
+// OfflineTransaction represents a transaction created while the POS was offline
+type OfflineTransaction struct {
+	ClientID string `json:"client_id"`
+	// OutletID is the outlet the sale took place at; 0 is the first outlet
+	// of the user
+	OutletID  int               `json:"outlet_id,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Items     []TransactionItem `json:"items"`
+}
+
+// SyncTransactionsResponse represents the outcome of a sync batch, one result per transaction
+type SyncTransactionsResponse struct {
+	Results []SyncResult `json:"results"`
+}
+
+// SyncResult represents the outcome of syncing a single offline transaction
+type SyncResult struct {
+	ClientID      string          `json:"client_id"`
+	Status        string          `json:"status"`
+	TransactionID int             `json:"transaction_id,omitempty"`
+	Reason        string          `json:"reason,omitempty"`
+	Conflicts     []StockConflict `json:"conflicts,omitempty"`
+}
+
+// StockConflict represents stock that went negative while applying a sale
+type StockConflict struct {
+	ProductID  int     `json:"product_id"`
+	VariantID  *int    `json:"variant_id,omitempty"`
+	Quantity   float64 `json:"quantity"`
+	StockAfter float64 `json:"stock_after"`
+}
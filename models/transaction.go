@@ -5,27 +5,71 @@ import "time"
 // Transaction represents a sales transaction
 type Transaction struct {
 	ID          int                 `json:"id"`
+	ClientID    *string             `json:"client_id,omitempty"`
+	OutletID    int                 `json:"outlet_id"`
+	CustomerID  *int                `json:"customer_id,omitempty"`
+	PriceListID *int                `json:"price_list_id,omitempty"`
 	TotalAmount int                 `json:"total_amount"`
 	CreatedAt   time.Time           `json:"created_at"`
 	Details     []TransactionDetail `json:"details,omitempty"`
+
+	// Loyalty points of the customer: PointsAmount is the part of the total
+	// paid with the redeemed points
+	PointsEarned   int        `json:"points_earned,omitempty"`
+	PointsExpireAt *time.Time `json:"points_expire_at,omitempty"`
+	PointsRedeemed int        `json:"points_redeemed,omitempty"`
+	PointsAmount   int        `json:"points_amount,omitempty"`
 }
 
 // TransactionDetail represents a detail line item in a transaction
 type TransactionDetail struct {
-	ID            int `json:"id"`
-	TransactionID int `json:"transaction_id"`
-	ProductID     int `json:"product_id"`
-	Quantity      int `json:"quantity"`
-	Subtotal      int `json:"subtotal"`
+	ID            int                          `json:"id"`
+	TransactionID int                          `json:"transaction_id"`
+	ProductID     int                          `json:"product_id"`
+	VariantID     *int                         `json:"variant_id,omitempty"`
+	Quantity      float64                      `json:"quantity"`
+	Unit          string                       `json:"unit"`
+	UnitQuantity  float64                      `json:"unit_quantity"`
+	Subtotal      int                          `json:"subtotal"`
+	Components    []TransactionDetailComponent `json:"components,omitempty"`
+	// PriceRuleID is the price rule the line was priced with; nil is the
+	// catalog or outlet price
+	PriceRuleID *int `json:"price_rule_id,omitempty"`
+}
+
+// TransactionDetailComponent represents the component stock taken by a sold
+// bundle and the share of the bundle revenue allocated to it
+type TransactionDetailComponent struct {
+	ProductID int     `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+	Subtotal  int     `json:"subtotal"`
 }
 
 // CreateTransactionRequest represents the request body for creating a transaction
 type CreateTransactionRequest struct {
-	Items []TransactionItem `json:"items"`
+	// OutletID is the outlet the sale takes place at; 0 is the first outlet
+	// of the user
+	OutletID   int  `json:"outlet_id,omitempty"`
+	CustomerID *int `json:"customer_id,omitempty"`
+	// PriceListID prices the sale from a price list instead of the price
+	// list of the customer
+	PriceListID *int `json:"price_list_id,omitempty"`
+	// RedeemPoints pays part of the total with loyalty points of the customer
+	RedeemPoints int               `json:"redeem_points,omitempty"`
+	Items        []TransactionItem `json:"items"`
+}
+
+// TransactionFilter represents query filters for transactions
+type TransactionFilter struct {
+	CustomerID int
+	// OutletIDs limits the transactions to those outlets; nil is all outlets
+	OutletIDs []int
 }
 
 // TransactionItem represents a single item in a transaction request
 type TransactionItem struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ProductID int     `json:"product_id"`
+	VariantID *int    `json:"variant_id,omitempty"`
+	Quantity  float64 `json:"quantity"`
+	Unit      string  `json:"unit,omitempty"`
 }
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Stock transfer statuses
+const (
+	TransferDraft     = "draft"
+	TransferInTransit = "in_transit"
+	TransferReceived  = "received"
+)
+
+// StockTransfer represents goods moved from one outlet to another. A draft
+// does not change stock; sending it takes the lines off the stock of the
+// source outlet and receiving adds what arrived to the stock of the
+// destination.
+type StockTransfer struct {
+	ID           int            `json:"id"`
+	FromOutletID int            `json:"from_outlet_id"`
+	ToOutletID   int            `json:"to_outlet_id"`
+	Status       string         `json:"status"`
+	Note         string         `json:"note,omitempty"`
+	Lines        []TransferLine `json:"lines,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	SentAt       *time.Time     `json:"sent_at,omitempty"`
+	ReceivedAt   *time.Time     `json:"received_at,omitempty"`
+}
+
+// TransferLine represents the quantity of a product, or of one of its
+// variants, on a transfer. ReceivedQuantity is what arrived so far and
+// Discrepancy what never arrived once the transfer was received, with the
+// reason given in DiscrepancyNote.
+type TransferLine struct {
+	ID               int     `json:"id"`
+	ProductID        int     `json:"product_id"`
+	VariantID        *int    `json:"variant_id,omitempty"`
+	Quantity         float64 `json:"quantity"`
+	ReceivedQuantity float64 `json:"received_quantity"`
+	Discrepancy      float64 `json:"discrepancy,omitempty"`
+	DiscrepancyNote  string  `json:"discrepancy_note,omitempty"`
+}
+
+// TransferReceipt represents goods arriving for a transfer in transit. The
+// transfer is received once every line arrived in full, or when Close is set;
+// what did not arrive then is recorded as the discrepancy of its line.
+type TransferReceipt struct {
+	Lines []ReceivedLine `json:"lines"`
+	Close bool           `json:"close,omitempty"`
+}
+
+// ReceivedLine represents the quantity of a transfer line that arrived, with
+// an optional note explaining a discrepancy
+type ReceivedLine struct {
+	LineID   int     `json:"line_id"`
+	Quantity float64 `json:"quantity"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// TransferFilter represents the optional filters for listing transfers
+type TransferFilter struct {
+	// OutletIDs limits the transfers to those from or to one of the outlets;
+	// nil means every outlet
+	OutletIDs []int
+	Status    string
+}
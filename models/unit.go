@@ -0,0 +1,21 @@
+package models
+
+// DefaultUnit is the unit assigned to products created without one
+const DefaultUnit = "pcs"
+
+// Unit represents a unit of measure (pcs, kg, gram, liter, ...)
+type Unit struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	Precision int     `json:"precision"`
+	BaseUnit  string  `json:"base_unit,omitempty"`
+	Factor    float64 `json:"factor"`
+}
+
+// UnitConversion represents a product specific packaging unit, e.g. 1 box = 24 pcs
+type UnitConversion struct {
+	ID        int     `json:"id"`
+	ProductID int     `json:"product_id"`
+	Unit      string  `json:"unit"`
+	Factor    float64 `json:"factor"`
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"kasir-api/models"
+)
+
+// auditRepository is the PostgreSQL implementation of AuditRepository
+type auditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Create appends an entry to the audit log
+func (r *auditRepository) Create(ctx context.Context, entry models.AuditEntry) (*models.AuditEntry, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO audit_log (actor, action, entity_type, entity_id, before, after, ip, request_id)
+		VALUES (NULLIF($1, ''), $2, $3, $4, NULLIF($5, '')::jsonb, NULLIF($6, '')::jsonb, NULLIF($7, ''), NULLIF($8, ''))
+		RETURNING id, created_at
+	`, entry.Actor, entry.Action, entry.EntityType, entry.EntityID, string(entry.Before), string(entry.After), entry.IP, entry.RequestID).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetAll returns the page of entries matching the filter, newest first
+func (r *auditRepository) GetAll(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query := `
+		SELECT id, COALESCE(actor, ''), action, entity_type, entity_id, before, after,
+			COALESCE(ip, ''), COALESCE(request_id, ''), created_at
+		FROM audit_log WHERE 1=1`
+	var args []interface{}
+	argIndex := 1
+
+	if filter.Actor != "" {
+		query += fmt.Sprintf(" AND actor = $%d", argIndex)
+		args = append(args, filter.Actor)
+		argIndex++
+	}
+
+	if filter.Action != "" {
+		query += fmt.Sprintf(" AND action = $%d", argIndex)
+		args = append(args, filter.Action)
+		argIndex++
+	}
+
+	if filter.EntityType != "" {
+		query += fmt.Sprintf(" AND entity_type = $%d", argIndex)
+		args = append(args, filter.EntityType)
+		argIndex++
+	}
+
+	if filter.EntityID > 0 {
+		query += fmt.Sprintf(" AND entity_id = $%d", argIndex)
+		args = append(args, filter.EntityID)
+		argIndex++
+	}
+
+	if filter.From != nil {
+		query += fmt.Sprintf(" AND created_at >= $%d", argIndex)
+		args = append(args, *filter.From)
+		argIndex++
+	}
+
+	if filter.To != nil {
+		query += fmt.Sprintf(" AND created_at < $%d", argIndex)
+		args = append(args, *filter.To)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.EntityType, &e.EntityID, &before, &after,
+			&e.IP, &e.RequestID, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Before, e.After = before, after
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,69 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+func TestPostgresAuditRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewAuditRepository(db)
+
+	start := time.Now().Add(-time.Minute)
+	created, err := repo.Create(ctx, models.AuditEntry{
+		Actor: "budi", Action: models.AuditUpdate, EntityType: "product", EntityID: kopiID,
+		Before: json.RawMessage(`{"price":5000}`), After: json.RawMessage(`{"price":6000}`),
+		IP: "10.0.0.1", RequestID: "req-1",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID == 0 || created.CreatedAt.IsZero() {
+		t.Errorf("Create() = %+v, want an ID and time", created)
+	}
+	if _, err := repo.Create(ctx, models.AuditEntry{Action: models.AuditCreate, EntityType: "category", EntityID: 1, After: json.RawMessage(`{"name":"Minuman"}`)}); err != nil {
+		t.Fatalf("Create() without actor error = %v", err)
+	}
+
+	entries, err := repo.GetAll(ctx, models.AuditFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].EntityType != "category" || entries[0].Before != nil || entries[0].Actor != "" {
+		t.Fatalf("GetAll() = %+v, want the category entry first, without actor or before", entries)
+	}
+	got := entries[1]
+	if got.Actor != "budi" || got.IP != "10.0.0.1" || got.RequestID != "req-1" || string(got.Before) != `{"price": 5000}` {
+		t.Errorf("entry = %+v (before %s), want the product update", got, got.Before)
+	}
+
+	entries, err = repo.GetAll(ctx, models.AuditFilter{Actor: "budi", Action: models.AuditUpdate, EntityType: "product", EntityID: kopiID, From: &start, Limit: 10})
+	if err != nil || len(entries) != 1 || entries[0].ID != created.ID {
+		t.Errorf("GetAll() filtered = %+v, %v, want the product update", entries, err)
+	}
+	entries, err = repo.GetAll(ctx, models.AuditFilter{Limit: 1, Offset: 1})
+	if err != nil || len(entries) != 1 || entries[0].ID != created.ID {
+		t.Errorf("GetAll() second page = %+v, %v, want the product update", entries, err)
+	}
+	if entries, err := repo.GetAll(ctx, models.AuditFilter{To: &start, Limit: 10}); err != nil || len(entries) != 0 {
+		t.Errorf("GetAll() before start = %+v, %v, want none", entries, err)
+	}
+
+	// The audit log is append-only
+	if _, err := db.ExecContext(ctx, "UPDATE audit_log SET actor = 'sari'"); err == nil {
+		t.Error("UPDATE audit_log succeeded")
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM audit_log"); err == nil {
+		t.Error("DELETE FROM audit_log succeeded")
+	}
+	if got := countRows(t, db, "audit_log"); got != 2 {
+		t.Errorf("audit log entries = %d, want 2", got)
+	}
+}
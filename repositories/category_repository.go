@@ -1,25 +1,52 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"kasir-api/models"
 )
 
-// CategoryRepository handles data access for categories
-type CategoryRepository struct {
+// categoryRepository is the PostgreSQL implementation of CategoryRepository
+type categoryRepository struct {
 	db *sql.DB
 }
 
 // NewCategoryRepository creates a new CategoryRepository
-func NewCategoryRepository(db *sql.DB) *CategoryRepository {
-	return &CategoryRepository{db: db}
+func NewCategoryRepository(db *sql.DB) CategoryRepository {
+	return &categoryRepository{db: db}
 }
 
-// GetAll returns all categories
-func (r *CategoryRepository) GetAll() ([]models.Category, error) {
-	rows, err := r.db.Query("SELECT id, name, description FROM categories")
+// categoryColumns selects a category row
+const categoryColumns = "SELECT id, name, description, points_multiplier, deleted_at FROM categories"
+
+// scanCategory scans a row selected by categoryColumns
+func scanCategory(row interface{ Scan(...interface{}) error }) (*models.Category, error) {
+	var c models.Category
+	var description sql.NullString
+	var deletedAt sql.NullTime
+	c.PointsMultiplier = new(float64)
+	if err := row.Scan(&c.ID, &c.Name, &description, c.PointsMultiplier, &deletedAt); err != nil {
+		return nil, err
+	}
+	if description.Valid {
+		c.Description = description.String
+	}
+	if deletedAt.Valid {
+		c.DeletedAt = &deletedAt.Time
+	}
+	return &c, nil
+}
+
+// GetAll returns the categories, without the deleted ones unless the filter
+// includes them
+func (r *categoryRepository) GetAll(ctx context.Context, filter models.CategoryFilter) ([]models.Category, error) {
+	query := categoryColumns
+	if !filter.IncludeDeleted {
+		query += " WHERE deleted_at IS NULL"
+	}
+	rows, err := r.db.QueryContext(ctx, query+" ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
@@ -27,75 +54,211 @@ func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 
 	var categories []models.Category
 	for rows.Next() {
-		var c models.Category
-		var description sql.NullString
-		if err := rows.Scan(&c.ID, &c.Name, &description); err != nil {
+		c, err := scanCategory(rows)
+		if err != nil {
 			return nil, err
 		}
-		if description.Valid {
-			c.Description = description.String
-		}
-		categories = append(categories, c)
+		categories = append(categories, *c)
 	}
 	return categories, nil
 }
 
 // GetByID returns a category by ID
-func (r *CategoryRepository) GetByID(id int) (*models.Category, error) {
-	var c models.Category
-	var description sql.NullString
-	err := r.db.QueryRow("SELECT id, name, description FROM categories WHERE id = $1", id).
-		Scan(&c.ID, &c.Name, &description)
+func (r *categoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	c, err := scanCategory(r.db.QueryRowContext(ctx, categoryColumns+" WHERE id = $1", id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("Category with ID %d not found", id)
 		}
 		return nil, err
 	}
-	if description.Valid {
-		c.Description = description.String
-	}
-	return &c, nil
+	return c, nil
 }
 
-// Create adds a new category
-func (r *CategoryRepository) Create(category models.Category) (*models.Category, error) {
-	err := r.db.QueryRow(
-		"INSERT INTO categories (name, description) VALUES ($1, $2) RETURNING id",
-		category.Name, category.Description,
-	).Scan(&category.ID)
+// Create adds a new category; the points multiplier defaults to 1
+func (r *categoryRepository) Create(ctx context.Context, category models.Category) (*models.Category, error) {
+	multiplier := new(float64)
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO categories (name, description, points_multiplier) VALUES ($1, $2, COALESCE($3, 1)) RETURNING id, points_multiplier",
+		category.Name, category.Description, category.PointsMultiplier,
+	).Scan(&category.ID, multiplier)
 	if err != nil {
 		return nil, err
 	}
+	category.PointsMultiplier = multiplier
 	return &category, nil
 }
 
-// Update updates an existing category
-func (r *CategoryRepository) Update(id int, category models.Category) (*models.Category, error) {
-	result, err := r.db.Exec(
-		"UPDATE categories SET name = $1, description = $2 WHERE id = $3",
-		category.Name, category.Description, id,
-	)
+// Update updates an existing category; the points multiplier is kept when
+// it is not given
+func (r *categoryRepository) Update(ctx context.Context, id int, category models.Category) (*models.Category, error) {
+	multiplier := new(float64)
+	err := r.db.QueryRowContext(ctx,
+		"UPDATE categories SET name = $1, description = $2, points_multiplier = COALESCE($3, points_multiplier) WHERE id = $4 AND deleted_at IS NULL RETURNING points_multiplier",
+		category.Name, category.Description, category.PointsMultiplier, id,
+	).Scan(multiplier)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Category with ID %d not found", id)
+		}
 		return nil, err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return nil, fmt.Errorf("Category with ID %d not found", id)
-	}
 	category.ID = id
+	category.PointsMultiplier = multiplier
 	return &category, nil
 }
 
-// Delete removes a category by ID
-func (r *CategoryRepository) Delete(id int) error {
-	result, err := r.db.Exec("DELETE FROM categories WHERE id = $1", id)
+// Delete soft deletes a category by ID; deleting it again is not found. Its
+// products move to reassignTo in the same transaction, or it is refused while
+// it has products that are not deleted.
+func (r *categoryRepository) Delete(ctx context.Context, id int, reassignTo *int, changedBy string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the category so no product is added to it meanwhile
+	var found bool
+	err = tx.QueryRowContext(ctx, "SELECT true FROM categories WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&found)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("Category with ID %d not found", id)
+		}
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("Category with ID %d not found", id)
+
+	if reassignTo == nil {
+		var hasProducts bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM products WHERE category_id = $1 AND deleted_at IS NULL)", id,
+		).Scan(&hasProducts)
+		if err != nil {
+			return err
+		}
+		if hasProducts {
+			return fmt.Errorf("%w: category with ID %d has products", ErrInUse, id)
+		}
+	} else {
+		err = tx.QueryRowContext(ctx,
+			"SELECT true FROM categories WHERE id = $1 AND id <> $2 AND deleted_at IS NULL FOR SHARE", *reassignTo, id,
+		).Scan(&found)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: category with ID %d does not exist", ErrReassignTarget, *reassignTo)
+			}
+			return err
+		}
+		// Lock the products before moving them, so their next version
+		// numbers are read after any change still in flight
+		if _, err := tx.ExecContext(ctx, "SELECT id FROM products WHERE category_id = $1 ORDER BY id FOR UPDATE", id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			WITH moved AS (
+				UPDATE products SET category_id = $2 WHERE category_id = $1
+				RETURNING id, name, price, stock, unit, category_id
+			)
+			INSERT INTO product_versions (product_id, version, name, price, stock, unit, category_id, changed_by)
+			SELECT m.id, (SELECT COALESCE(MAX(version), 0) + 1 FROM product_versions v WHERE v.product_id = m.id),
+				m.name, m.price, m.stock, m.unit, m.category_id, NULLIF($3, '')
+			FROM moved m
+		`, id, *reassignTo, changedBy)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE categories SET deleted_at = NOW() WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Restore undoes the deletion of a category
+func (r *categoryRepository) Restore(ctx context.Context, id int) (*models.Category, error) {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `
+		WITH category AS (
+			SELECT id, deleted_at IS NOT NULL AS deleted FROM categories WHERE id = $1 FOR UPDATE
+		), restored AS (
+			UPDATE categories c SET deleted_at = NULL FROM category WHERE c.id = category.id AND category.deleted
+		)
+		SELECT deleted FROM category
+	`, id).Scan(&restored)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Category with ID %d not found", id)
+		}
+		return nil, err
+	}
+	if !restored {
+		return nil, fmt.Errorf("%w: category with ID %d is not deleted", ErrNotDeleted, id)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Purge permanently removes a deleted category; its products lose their
+// category, each recorded as the next version of the product by changedBy, so
+// a category whose products were sold cannot be purged
+func (r *categoryRepository) Purge(ctx context.Context, id int, changedBy string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the category so no product is moved into it meanwhile
+	var deleted bool
+	err = tx.QueryRowContext(ctx, "SELECT deleted_at IS NOT NULL FROM categories WHERE id = $1 FOR UPDATE", id).Scan(&deleted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("Category with ID %d not found", id)
+		}
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("%w: category with ID %d must be deleted before it is purged", ErrNotDeleted, id)
+	}
+
+	// Lock the products before checking and clearing them, so their next
+	// version numbers are read after any change still in flight
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM products WHERE category_id = $1 ORDER BY id FOR UPDATE", id); err != nil {
+		return err
+	}
+	var sold bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM products p
+			WHERE p.category_id = $1 AND (
+				EXISTS (SELECT 1 FROM transaction_details WHERE product_id = p.id)
+				OR EXISTS (SELECT 1 FROM transaction_detail_components WHERE product_id = p.id)
+				OR EXISTS (SELECT 1 FROM stock_conflicts WHERE product_id = p.id)
+			)
+		)
+	`, id).Scan(&sold)
+	if err != nil {
+		return err
+	}
+	if sold {
+		return fmt.Errorf("%w: category with ID %d is referenced by sales", ErrInUse, id)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		WITH cleared AS (
+			UPDATE products SET category_id = NULL WHERE category_id = $1
+			RETURNING id, name, price, stock, unit, category_id
+		)
+		INSERT INTO product_versions (product_id, version, name, price, stock, unit, category_id, changed_by)
+		SELECT c.id, (SELECT COALESCE(MAX(version), 0) + 1 FROM product_versions v WHERE v.product_id = c.id),
+			c.name, c.price, c.stock, c.unit, c.category_id, NULLIF($2, '')
+		FROM cleared c
+	`, id, changedBy)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id); err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
@@ -0,0 +1,178 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestPostgresCategoryRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewCategoryRepository(db)
+
+	created, err := repo.Create(ctx, models.Category{Name: "Minuman"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	categories, err := repo.GetAll(ctx, models.CategoryFilter{})
+	if err != nil || len(categories) != 2 {
+		t.Fatalf("GetAll() = %+v, %v", categories, err)
+	}
+
+	got, err := repo.GetByID(ctx, 1)
+	if err != nil || got.Name != "Sembako" || got.Description != "Bahan pokok" {
+		t.Errorf("GetByID(1) = %+v, %v", got, err)
+	}
+
+	if _, err := repo.Update(ctx, created.ID, models.Category{Name: "Minuman Dingin", Description: "Es"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, created.ID); got.Name != "Minuman Dingin" || got.Description != "Es" {
+		t.Errorf("after Update() = %+v", got)
+	}
+
+	if err := repo.Delete(ctx, created.ID, nil, ""); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := repo.GetByID(ctx, created.ID); err != nil || got.DeletedAt == nil {
+		t.Errorf("GetByID() of deleted category = %+v, %v, want it marked deleted", got, err)
+	}
+	if categories, err := repo.GetAll(ctx, models.CategoryFilter{}); err != nil || len(categories) != 1 {
+		t.Errorf("GetAll() = %+v, %v, want the deleted category left out", categories, err)
+	}
+	if categories, err := repo.GetAll(ctx, models.CategoryFilter{IncludeDeleted: true}); err != nil || len(categories) != 2 {
+		t.Errorf("GetAll() including deleted = %+v, %v, want both categories", categories, err)
+	}
+	if _, err := repo.Update(ctx, created.ID, models.Category{Name: "X"}); err == nil {
+		t.Error("Update() of deleted category succeeded")
+	}
+	if err := repo.Delete(ctx, created.ID, nil, ""); err == nil {
+		t.Error("second Delete() succeeded")
+	}
+
+	if restored, err := repo.Restore(ctx, created.ID); err != nil || restored.DeletedAt != nil {
+		t.Fatalf("Restore() = %+v, %v", restored, err)
+	}
+	if _, err := repo.Restore(ctx, created.ID); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("second Restore() error = %v, want ErrNotDeleted", err)
+	}
+	if err := repo.Purge(ctx, created.ID, ""); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("Purge() of live category error = %v, want ErrNotDeleted", err)
+	}
+	if err := repo.Delete(ctx, created.ID, nil, ""); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Purge(ctx, created.ID, ""); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, created.ID); err == nil {
+		t.Error("GetByID() of purged category succeeded")
+	}
+}
+
+func TestPostgresCategoryRepositoryProducts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewCategoryRepository(db)
+	products := NewProductRepository(db)
+
+	minuman, err := repo.Create(ctx, models.Category{Name: "Minuman"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Delete(ctx, 1, nil, ""); !errors.Is(err, ErrInUse) {
+		t.Errorf("Delete() of category with products error = %v, want ErrInUse", err)
+	}
+	if err := repo.Delete(ctx, 1, intPtr(1), ""); !errors.Is(err, ErrReassignTarget) {
+		t.Errorf("Delete() reassigning to itself error = %v, want ErrReassignTarget", err)
+	}
+	if err := repo.Delete(ctx, 1, intPtr(99), ""); !errors.Is(err, ErrReassignTarget) {
+		t.Errorf("Delete() reassigning to missing category error = %v, want ErrReassignTarget", err)
+	}
+
+	if err := repo.Delete(ctx, 1, &minuman.ID, "budi"); err != nil {
+		t.Fatalf("Delete() reassigning error = %v", err)
+	}
+	moved, err := products.GetAll(ctx, models.ProductFilter{CategoryID: minuman.ID})
+	if err != nil || len(moved) != 4 {
+		t.Errorf("products of the target = %+v, %v, want all 4", moved, err)
+	}
+	versions, err := products.GetHistory(ctx, kopiID)
+	if err != nil || len(versions) != 2 || *versions[1].CategoryID != minuman.ID || versions[1].ChangedBy != "budi" {
+		t.Errorf("GetHistory() = %+v, %v, want the move by budi", versions, err)
+	}
+	if _, err := products.Create(ctx, models.Product{Name: "Gula", Unit: "kg", CategoryID: intPtr(1)}, ""); err == nil {
+		t.Error("Create() in deleted category succeeded")
+	}
+
+	// Purging a category leaves its deleted products without one
+	roti, err := products.Create(ctx, models.Product{Name: "Roti", Unit: "pcs", CategoryID: &minuman.ID}, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := products.Delete(ctx, roti.ID); err != nil {
+		t.Fatalf("Delete() product error = %v", err)
+	}
+	makanan, err := repo.Create(ctx, models.Category{Name: "Makanan"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Delete(ctx, minuman.ID, &makanan.ID, ""); err != nil {
+		t.Fatalf("Delete() reassigning error = %v", err)
+	}
+	if err := repo.Delete(ctx, makanan.ID, nil, ""); !errors.Is(err, ErrInUse) {
+		t.Errorf("Delete() of category with products error = %v, want ErrInUse", err)
+	}
+	for _, id := range []int{kopiID, berasID, esTehID, paketID} {
+		if _, err := products.Update(ctx, id, models.Product{Name: "X", Unit: "pcs"}, ""); err != nil {
+			t.Fatalf("Update() without category error = %v", err)
+		}
+	}
+	if err := repo.Delete(ctx, makanan.ID, nil, ""); err != nil {
+		t.Fatalf("Delete() with only deleted products error = %v", err)
+	}
+	if err := repo.Purge(ctx, makanan.ID, "sari"); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	got, err := products.GetByID(ctx, roti.ID)
+	if err != nil || got.CategoryID != nil {
+		t.Errorf("GetByID() of product of purged category = %+v, %v, want no category", got, err)
+	}
+	versions, err = products.GetHistory(ctx, roti.ID)
+	if err != nil || len(versions) != 3 || versions[2].CategoryID != nil || versions[2].ChangedBy != "sari" {
+		t.Errorf("GetHistory() of product of purged category = %+v, %v, want the category dropped by sari", versions, err)
+	}
+	if _, err := products.GetAll(ctx, models.ProductFilter{IncludeDeleted: true}); err != nil {
+		t.Errorf("GetAll() with products without category error = %v", err)
+	}
+
+	// A product restored after its category was deleted comes back without it
+	snack, err := repo.Create(ctx, models.Category{Name: "Snack"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	keripik, err := products.Create(ctx, models.Product{Name: "Keripik", Unit: "pcs", CategoryID: &snack.ID}, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := products.Delete(ctx, keripik.ID); err != nil {
+		t.Fatalf("Delete() product error = %v", err)
+	}
+	if err := repo.Delete(ctx, snack.ID, nil, ""); err != nil {
+		t.Fatalf("Delete() with only deleted products error = %v", err)
+	}
+	restored, err := products.Restore(ctx, keripik.ID, "budi")
+	if err != nil || restored.CategoryID != nil {
+		t.Fatalf("Restore() = %+v, %v, want no category", restored, err)
+	}
+	versions, err = products.GetHistory(ctx, keripik.ID)
+	if err != nil || len(versions) != 2 || versions[1].CategoryID != nil || versions[1].ChangedBy != "budi" {
+		t.Errorf("GetHistory() = %+v, %v, want the category dropped by budi", versions, err)
+	}
+}
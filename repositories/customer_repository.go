@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"kasir-api/models"
+)
+
+// customerRepository is the PostgreSQL implementation of CustomerRepository
+type customerRepository struct {
+	db *sql.DB
+}
+
+// NewCustomerRepository creates a new CustomerRepository
+func NewCustomerRepository(db *sql.DB) CustomerRepository {
+	return &customerRepository{db: db}
+}
+
+// customerColumns selects a customer row; the optional fields are stored as
+// NULL so the unique phone and member number allow several customers without
+const customerColumns = `
+	SELECT id, name, COALESCE(phone, ''), COALESCE(email, ''), COALESCE(member_number, ''), price_list_id, created_at, points_balance
+	FROM customers`
+
+// GetAll returns all customers, optionally searched by name, phone, email or
+// member number
+func (r *customerRepository) GetAll(ctx context.Context, filter models.CustomerFilter) ([]models.Customer, error) {
+	query := customerColumns
+	var args []interface{}
+	if filter.Search != "" {
+		query += ` WHERE name ILIKE $1 OR phone ILIKE $1 OR email ILIKE $1 OR member_number ILIKE $1`
+		args = append(args, "%"+filter.Search+"%")
+	}
+	query += " ORDER BY name, id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []models.Customer
+	for rows.Next() {
+		c, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, *c)
+	}
+	return customers, rows.Err()
+}
+
+// GetByID returns a customer by ID
+func (r *customerRepository) GetByID(ctx context.Context, id int) (*models.Customer, error) {
+	c, err := scanCustomer(r.db.QueryRowContext(ctx, customerColumns+" WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Customer with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Create adds a new customer
+func (r *customerRepository) Create(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO customers (name, phone, email, member_number, price_list_id)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5)
+		RETURNING id, created_at, points_balance
+	`, customer.Name, customer.Phone, customer.Email, customer.MemberNumber, customer.PriceListID).Scan(&customer.ID, &customer.CreatedAt, &customer.PointsBalance)
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// Update updates an existing customer; the points balance only changes
+// through sales
+func (r *customerRepository) Update(ctx context.Context, id int, customer models.Customer) (*models.Customer, error) {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE customers
+		SET name = $1, phone = NULLIF($2, ''), email = NULLIF($3, ''), member_number = NULLIF($4, ''),
+			price_list_id = $5, updated_at = TIMEZONE('utc', NOW())
+		WHERE id = $6
+		RETURNING created_at, points_balance
+	`, customer.Name, customer.Phone, customer.Email, customer.MemberNumber, customer.PriceListID, id).Scan(&customer.CreatedAt, &customer.PointsBalance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Customer with ID %d not found", id)
+		}
+		return nil, err
+	}
+	customer.ID = id
+	return &customer, nil
+}
+
+// Delete removes a customer by ID
+func (r *customerRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM customers WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("Customer with ID %d not found", id)
+	}
+	return nil
+}
+
+// scanCustomer scans a customer row selected with customerColumns
+func scanCustomer(row interface{ Scan(...interface{}) error }) (*models.Customer, error) {
+	var c models.Customer
+	var priceListID sql.NullInt64
+	if err := row.Scan(&c.ID, &c.Name, &c.Phone, &c.Email, &c.MemberNumber, &priceListID, &c.CreatedAt, &c.PointsBalance); err != nil {
+		return nil, err
+	}
+	c.PriceListID = nullIntPtr(priceListID)
+	return &c, nil
+}
@@ -0,0 +1,79 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestPostgresCustomerRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewCustomerRepository(db)
+
+	budi, err := repo.Create(ctx, models.Customer{Name: "Budi", Phone: "0812", Email: "budi@example.com", MemberNumber: "M-001"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if budi.ID == 0 || budi.CreatedAt.IsZero() {
+		t.Errorf("Create() = %+v, want ID and created_at set", budi)
+	}
+	// Optional fields are stored as NULL, so several customers may leave them out
+	for _, name := range []string{"Andi", "Sari"} {
+		if _, err := repo.Create(ctx, models.Customer{Name: name}); err != nil {
+			t.Fatalf("Create(%s) without phone error = %v", name, err)
+		}
+	}
+	if _, err := repo.Create(ctx, models.Customer{Name: "Budi Lain", Phone: "0812"}); err == nil {
+		t.Error("Create() with taken phone succeeded, want unique violation")
+	}
+
+	customers, err := repo.GetAll(ctx, models.CustomerFilter{})
+	if err != nil || len(customers) != 3 || customers[0].Name != "Andi" || customers[2].Phone != "" {
+		t.Fatalf("GetAll() = %+v, %v; want 3 customers by name", customers, err)
+	}
+	for _, search := range []string{"bud", "0812", "EXAMPLE", "m-001"} {
+		found, err := repo.GetAll(ctx, models.CustomerFilter{Search: search})
+		if err != nil || len(found) != 1 || found[0].ID != budi.ID {
+			t.Errorf("GetAll(%q) = %+v, %v; want Budi", search, found, err)
+		}
+	}
+
+	updated, err := repo.Update(ctx, budi.ID, models.Customer{Name: "Budi Santoso", Phone: "0812"})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !updated.CreatedAt.Equal(budi.CreatedAt) {
+		t.Errorf("Update() created_at = %v, want %v", updated.CreatedAt, budi.CreatedAt)
+	}
+	if got, err := repo.GetByID(ctx, budi.ID); err != nil || got.Name != "Budi Santoso" || got.MemberNumber != "" {
+		t.Errorf("GetByID() after Update() = %+v, %v", got, err)
+	}
+	if _, err := repo.Update(ctx, 99, models.Customer{Name: "X"}); err == nil {
+		t.Error("Update() of missing customer succeeded")
+	}
+
+	// Sales of a deleted customer are kept without the customer
+	transactions := NewTransactionRepository(db)
+	sale, err := transactions.Create(ctx, models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		CustomerID:  &budi.ID,
+		TotalAmount: 5000,
+		Details:     []models.TransactionDetail{{ProductID: kopiID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000}},
+	})
+	if err != nil {
+		t.Fatalf("creating a sale: %v", err)
+	}
+	if err := repo.Delete(ctx, budi.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := transactions.GetByID(ctx, sale.ID); err != nil || got.CustomerID != nil {
+		t.Errorf("sale of deleted customer = %+v, %v; want it without customer", got, err)
+	}
+	if err := repo.Delete(ctx, budi.ID); err == nil {
+		t.Error("second Delete() succeeded")
+	}
+}
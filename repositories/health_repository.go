@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"kasir-api/database"
+	"kasir-api/models"
+)
+
+// healthRepository is the PostgreSQL implementation of HealthRepository
+type healthRepository struct {
+	db          *sql.DB
+	pingTimeout time.Duration
+}
+
+// NewHealthRepository creates a new HealthRepository
+func NewHealthRepository(db *sql.DB, pingTimeout time.Duration) HealthRepository {
+	return &healthRepository{db: db, pingTimeout: pingTimeout}
+}
+
+// Ping checks that the database answers within the ping timeout
+func (r *healthRepository) Ping(ctx context.Context) error {
+	return database.Ping(ctx, r.db, r.pingTimeout)
+}
+
+// Stats returns the current connection pool statistics
+func (r *healthRepository) Stats() models.PoolStats {
+	stats := r.db.Stats()
+	return models.PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
@@ -0,0 +1,27 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPostgresHealthRepository(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewHealthRepository(db, time.Second)
+
+	if err := repo.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if stats := repo.Stats(); stats.OpenConnections < 1 {
+		t.Errorf("Stats() = %+v, want an open connection", stats)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := repo.Ping(ctx); err == nil {
+		t.Error("Ping() with a cancelled context succeeded")
+	}
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"kasir-api/models"
+)
+
+// idempotencyRepository is the PostgreSQL implementation of IdempotencyRepository
+type idempotencyRepository struct {
+	db *sql.DB
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve claims a key of an owner for a request. It returns true when the
+// key was free (or expired); otherwise it returns the record already stored
+// for the key.
+func (r *idempotencyRepository) Reserve(ctx context.Context, owner, key, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	// An expired key is free to be used again
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE owner = $1 AND key = $2 AND expires_at <= NOW()", owner, key); err != nil {
+		return nil, false, err
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (owner, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (owner, key) DO NOTHING
+	`, owner, key, requestHash, expiresAt)
+	if err != nil {
+		return nil, false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 1 {
+		return nil, true, nil
+	}
+
+	var record models.IdempotencyRecord
+	var statusCode sql.NullInt64
+	var responseBody sql.NullString
+	err = r.db.QueryRowContext(ctx,
+		"SELECT owner, key, request_hash, status_code, response_body, expires_at FROM idempotency_keys WHERE owner = $1 AND key = $2",
+		owner, key,
+	).Scan(&record.Owner, &record.Key, &record.RequestHash, &statusCode, &responseBody, &record.ExpiresAt)
+	if err != nil {
+		return nil, false, err
+	}
+	record.Completed = statusCode.Valid
+	record.StatusCode = int(statusCode.Int64)
+	record.ResponseBody = responseBody.String
+	return &record, false, nil
+}
+
+// Complete stores the response of the request that reserved a key
+func (r *idempotencyRepository) Complete(ctx context.Context, owner, key string, statusCode int, responseBody string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status_code = $1, response_body = $2 WHERE owner = $3 AND key = $4",
+		statusCode, responseBody, owner, key,
+	)
+	return err
+}
+
+// Release frees a reserved key so the request can be retried
+func (r *idempotencyRepository) Release(ctx context.Context, owner, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE owner = $1 AND key = $2 AND status_code IS NULL", owner, key)
+	return err
+}
+
+// DeleteExpired removes all expired keys and returns how many were removed
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPostgresIdempotencyRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewIdempotencyRepository(db)
+	expiresAt := time.Now().Add(time.Hour)
+
+	record, reserved, err := repo.Reserve(ctx, "7", "key-1", "hash-1", expiresAt)
+	if err != nil || !reserved || record != nil {
+		t.Fatalf("first Reserve() = %+v, %v, %v; want reserved", record, reserved, err)
+	}
+
+	record, reserved, err = repo.Reserve(ctx, "7", "key-1", "hash-2", expiresAt)
+	if err != nil || reserved {
+		t.Fatalf("second Reserve() = %+v, %v, %v; want the existing record", record, reserved, err)
+	}
+	if record.RequestHash != "hash-1" || record.Completed {
+		t.Errorf("in-flight record = %+v, want hash-1 and not completed", record)
+	}
+
+	if err := repo.Complete(ctx, "7", "key-1", 201, `{"id":1}`); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	record, _, _ = repo.Reserve(ctx, "7", "key-1", "hash-1", expiresAt)
+	if !record.Completed || record.StatusCode != 201 || record.ResponseBody != `{"id":1}` {
+		t.Errorf("completed record = %+v", record)
+	}
+
+	if _, reserved, _ := repo.Reserve(ctx, "7", "key-2", "hash-1", expiresAt); !reserved {
+		t.Fatal("Reserve(key-2) was not reserved")
+	}
+	if err := repo.Release(ctx, "7", "key-2"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if _, reserved, _ := repo.Reserve(ctx, "7", "key-2", "hash-1", expiresAt); !reserved {
+		t.Error("released key could not be reserved again")
+	}
+
+	// An expired key is free again and is removed by DeleteExpired
+	if _, reserved, _ := repo.Reserve(ctx, "7", "key-3", "hash-1", time.Now().Add(-time.Minute)); !reserved {
+		t.Fatal("Reserve(key-3) was not reserved")
+	}
+	if _, reserved, _ := repo.Reserve(ctx, "7", "key-3", "hash-2", time.Now().Add(-time.Minute)); !reserved {
+		t.Error("expired key could not be reserved again")
+	}
+	// Keys are scoped to their owner
+	if _, reserved, _ := repo.Reserve(ctx, "8", "key-1", "hash-2", expiresAt); !reserved {
+		t.Error("Reserve() of another owner's key was not reserved")
+	}
+
+	deleted, err := repo.DeleteExpired(ctx)
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteExpired() = %d, %v; want 1", deleted, err)
+	}
+	if n := countRows(t, db, "idempotency_keys"); n != 3 {
+		t.Errorf("idempotency_keys has %d rows, want 3", n)
+	}
+}
@@ -0,0 +1,349 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// loyaltyRepository is the PostgreSQL implementation of LoyaltyRepository
+type loyaltyRepository struct {
+	db *sql.DB
+}
+
+// NewLoyaltyRepository creates a new LoyaltyRepository
+func NewLoyaltyRepository(db *sql.DB) LoyaltyRepository {
+	return &loyaltyRepository{db: db}
+}
+
+// GetLedger returns the ledger entries of a customer, newest first
+func (r *loyaltyRepository) GetLedger(ctx context.Context, customerID int) ([]models.PointsEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, customer_id, transaction_id, type, points, remaining, expires_at, created_at
+		FROM loyalty_points_ledger
+		WHERE customer_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.PointsEntry
+	for rows.Next() {
+		var e models.PointsEntry
+		var transactionID sql.NullInt64
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.CustomerID, &transactionID, &e.Type, &e.Points, &e.Remaining, &expiresAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if transactionID.Valid {
+			id := int(transactionID.Int64)
+			e.TransactionID = &id
+		}
+		if expiresAt.Valid {
+			e.ExpiresAt = &expiresAt.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ExpirePoints books the expiry of the points left in lots that expired and
+// returns the number of points expired. The customers are locked first, in
+// the same order as checkouts lock them, before their lots.
+func (r *loyaltyRepository) ExpirePoints(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		SELECT id FROM customers
+		WHERE id IN (SELECT customer_id FROM loyalty_points_ledger WHERE remaining > 0 AND expires_at <= NOW())
+		ORDER BY id
+		FOR UPDATE
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := expireLots(ctx, tx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// expireLots books the expiry of the points left in the expired lots of a
+// customer, of every customer when customerID is nil, and returns the number
+// of points expired. The customers must be locked by tx.
+func expireLots(ctx context.Context, tx *sql.Tx, customerID *int) (int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE loyalty_points_ledger l SET remaining = 0
+		FROM (
+			SELECT id, remaining FROM loyalty_points_ledger
+			WHERE remaining > 0 AND expires_at <= NOW() AND ($1::int IS NULL OR customer_id = $1)
+			FOR UPDATE
+		) expired
+		WHERE l.id = expired.id
+		RETURNING l.customer_id, l.transaction_id, expired.remaining, l.expires_at
+	`, customerID)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		customerIDs    []int64
+		transactionIDs []sql.NullInt64
+		points         []int64
+		expiresAt      []time.Time
+		total          int
+	)
+	for rows.Next() {
+		var customerID, remaining int64
+		var transactionID sql.NullInt64
+		var expiry time.Time
+		if err := rows.Scan(&customerID, &transactionID, &remaining, &expiry); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		customerIDs = append(customerIDs, customerID)
+		transactionIDs = append(transactionIDs, transactionID)
+		points = append(points, -remaining)
+		expiresAt = append(expiresAt, expiry)
+		total += int(remaining)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO loyalty_points_ledger (customer_id, transaction_id, type, points, expires_at)
+		SELECT e.customer_id, e.transaction_id, 'expire', e.points, e.expires_at
+		FROM unnest($1::int[], $2::int[], $3::int[], $4::timestamptz[]) AS e(customer_id, transaction_id, points, expires_at)
+	`, pq.Array(customerIDs), pq.Array(transactionIDs), pq.Array(points), pq.Array(expiresAt))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE customers c SET points_balance = c.points_balance + e.points
+		FROM (
+			SELECT customer_id, SUM(points) AS points
+			FROM unnest($1::int[], $2::int[]) AS e(customer_id, points)
+			GROUP BY customer_id
+		) e
+		WHERE c.id = e.customer_id
+	`, pq.Array(customerIDs), pq.Array(points))
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// lockPointsBalance locks the customer row for the rest of tx and returns
+// its points balance. Lots past their expiry that ExpirePoints has not
+// booked yet are expired first, so they cannot be spent.
+func lockPointsBalance(ctx context.Context, tx *sql.Tx, customerID int) (int, error) {
+	var balance int
+	err := tx.QueryRowContext(ctx, "SELECT points_balance FROM customers WHERE id = $1 FOR UPDATE", customerID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("Customer with ID %d not found", customerID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	expired, err := expireLots(ctx, tx, &customerID)
+	if err != nil {
+		return 0, err
+	}
+	return balance - expired, nil
+}
+
+// bookPoints books the points a new transaction redeems and earns on the
+// ledger of its customer. Redeemed points are taken from the lots expiring
+// first; earned points become a new lot, less what pays off a balance that
+// went negative when a sale whose points were already spent was voided.
+func bookPoints(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error {
+	if transaction.CustomerID == nil || (transaction.PointsEarned == 0 && transaction.PointsRedeemed == 0) {
+		return nil
+	}
+	customerID := *transaction.CustomerID
+
+	balance, err := lockPointsBalance(ctx, tx, customerID)
+	if err != nil {
+		return err
+	}
+
+	if transaction.PointsRedeemed > 0 {
+		if balance < transaction.PointsRedeemed {
+			return fmt.Errorf("%w for customer with ID %d", ErrInsufficientPoints, customerID)
+		}
+		expiresAt, err := consumeLots(ctx, tx, customerID, transaction.PointsRedeemed, 0)
+		if err != nil {
+			return err
+		}
+		err = insertPointsEntry(ctx, tx, customerID, transaction.ID, models.PointsRedeem, -transaction.PointsRedeemed, 0, expiresAt)
+		if err != nil {
+			return err
+		}
+		balance -= transaction.PointsRedeemed
+	}
+
+	if transaction.PointsEarned > 0 {
+		err := insertPointsEntry(ctx, tx, customerID, transaction.ID, models.PointsEarn, transaction.PointsEarned,
+			lotRemaining(transaction.PointsEarned, balance), transaction.PointsExpireAt)
+		if err != nil {
+			return err
+		}
+		balance += transaction.PointsEarned
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE customers SET points_balance = $1 WHERE id = $2", balance, customerID)
+	return err
+}
+
+// voidPoints books back the points a transaction earned and redeemed before
+// it is deleted. Voided earned points are taken from the lot of the sale
+// first; when they were already spent the balance goes negative and is paid
+// off by the next points added. Voided redeemed points come back as a lot
+// with the earliest expiry of the lots they were taken from.
+func voidPoints(ctx context.Context, tx *sql.Tx, transactionID int) error {
+	var customerID sql.NullInt64
+	var earned, redeemed int
+	err := tx.QueryRowContext(ctx,
+		"SELECT customer_id, points_earned, points_redeemed FROM transactions WHERE id = $1 FOR UPDATE",
+		transactionID,
+	).Scan(&customerID, &earned, &redeemed)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !customerID.Valid || (earned == 0 && redeemed == 0) {
+		return nil
+	}
+	customer := int(customerID.Int64)
+
+	balance, err := lockPointsBalance(ctx, tx, customer)
+	if err != nil {
+		return err
+	}
+
+	if earned > 0 {
+		if _, err := consumeLots(ctx, tx, customer, min(earned, max(balance, 0)), transactionID); err != nil {
+			return err
+		}
+		if err := insertPointsEntry(ctx, tx, customer, transactionID, models.PointsVoidEarn, -earned, 0, nil); err != nil {
+			return err
+		}
+		balance -= earned
+	}
+
+	if redeemed > 0 {
+		var expiresAt sql.NullTime
+		err := tx.QueryRowContext(ctx,
+			"SELECT expires_at FROM loyalty_points_ledger WHERE transaction_id = $1 AND type = 'redeem' ORDER BY id LIMIT 1",
+			transactionID,
+		).Scan(&expiresAt)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		var expiry *time.Time
+		if expiresAt.Valid {
+			expiry = &expiresAt.Time
+		}
+		err = insertPointsEntry(ctx, tx, customer, transactionID, models.PointsVoidRedeem, redeemed, lotRemaining(redeemed, balance), expiry)
+		if err != nil {
+			return err
+		}
+		balance += redeemed
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE customers SET points_balance = $1 WHERE id = $2", balance, customer)
+	return err
+}
+
+// consumeLots takes points off the open lots of a customer, the lot earned
+// by transactionID first, then the lots expiring first, and returns the
+// earliest expiry of the lots taken from
+func consumeLots(ctx context.Context, tx *sql.Tx, customerID, points, transactionID int) (*time.Time, error) {
+	if points <= 0 {
+		return nil, nil
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, remaining, expires_at
+		FROM loyalty_points_ledger
+		WHERE customer_id = $1 AND remaining > 0
+		ORDER BY (transaction_id = $2 AND type = 'earn') IS TRUE DESC, expires_at ASC NULLS LAST, id
+		FOR UPDATE
+	`, customerID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids, remaining []int64
+	var earliest *time.Time
+	for rows.Next() && points > 0 {
+		var id, left int64
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&id, &left, &expiresAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		taken := min(left, int64(points))
+		points -= int(taken)
+		ids = append(ids, id)
+		remaining = append(remaining, left-taken)
+		if expiresAt.Valid && (earliest == nil || expiresAt.Time.Before(*earliest)) {
+			expiry := expiresAt.Time
+			earliest = &expiry
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE loyalty_points_ledger l SET remaining = u.remaining
+		FROM unnest($1::int[], $2::int[]) AS u(id, remaining)
+		WHERE l.id = u.id
+	`, pq.Array(ids), pq.Array(remaining))
+	if err != nil {
+		return nil, err
+	}
+	return earliest, nil
+}
+
+// insertPointsEntry appends an entry to the ledger of a customer
+func insertPointsEntry(ctx context.Context, tx *sql.Tx, customerID, transactionID int, entryType string, points, remaining int, expiresAt *time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO loyalty_points_ledger (customer_id, transaction_id, type, points, remaining, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, customerID, transactionID, entryType, points, remaining, expiresAt)
+	return err
+}
+
+// lotRemaining returns what is left of points added to a balance once they
+// paid off a negative balance
+func lotRemaining(points, balance int) int {
+	if balance < 0 {
+		return max(points+balance, 0)
+	}
+	return points
+}
@@ -0,0 +1,162 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+// pointsBalance returns the points balance of a customer and checks that the
+// points left in the lots of the ledger add up to it
+func pointsBalance(t *testing.T, db *sql.DB, customerID int) int {
+	t.Helper()
+	customer, err := NewCustomerRepository(db).GetByID(context.Background(), customerID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	entries, err := NewLoyaltyRepository(db).GetLedger(context.Background(), customerID)
+	if err != nil {
+		t.Fatalf("GetLedger() error = %v", err)
+	}
+	var remaining int
+	for _, e := range entries {
+		remaining += e.Remaining
+	}
+	if remaining != max(customer.PointsBalance, 0) {
+		t.Errorf("points left in lots = %d, want %d", remaining, max(customer.PointsBalance, 0))
+	}
+	return customer.PointsBalance
+}
+
+func TestPostgresLoyaltyPoints(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransactionRepository(db)
+	loyalty := NewLoyaltyRepository(db)
+
+	customer, err := NewCustomerRepository(db).Create(ctx, models.Customer{Name: "Budi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := models.TransactionDetail{ProductID: kopiID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000}
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+
+	earning, err := repo.Create(ctx, models.Transaction{
+		OutletID:   models.DefaultOutletID,
+		CustomerID: &customer.ID, TotalAmount: 5000, PointsEarned: 15, PointsExpireAt: &expiresAt,
+		Details: []models.TransactionDetail{line},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := pointsBalance(t, db, customer.ID); got != 15 {
+		t.Fatalf("balance = %d, want 15", got)
+	}
+	got, err := repo.GetByID(ctx, earning.ID)
+	if err != nil || got.PointsEarned != 15 || got.PointsExpireAt == nil || !got.PointsExpireAt.Equal(expiresAt) {
+		t.Fatalf("GetByID() = %+v, %v; want 15 points expiring at %v", got, err, expiresAt)
+	}
+
+	// Redeeming more than the balance rolls the whole sale back
+	_, err = repo.Create(ctx, models.Transaction{
+		OutletID:   models.DefaultOutletID,
+		CustomerID: &customer.ID, TotalAmount: 5000, PointsRedeemed: 16, PointsAmount: 160,
+		Details: []models.TransactionDetail{line},
+	})
+	if !errors.Is(err, ErrInsufficientPoints) {
+		t.Fatalf("Create() error = %v, want ErrInsufficientPoints", err)
+	}
+	if stock := stockOf(t, db, kopiID); stock != 9 {
+		t.Errorf("Kopi stock = %v, want 9", stock)
+	}
+
+	redeeming, err := repo.Create(ctx, models.Transaction{
+		OutletID:   models.DefaultOutletID,
+		CustomerID: &customer.ID, TotalAmount: 5000, PointsRedeemed: 10, PointsAmount: 100, PointsEarned: 4, PointsExpireAt: &expiresAt,
+		Details: []models.TransactionDetail{line},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := pointsBalance(t, db, customer.ID); got != 9 {
+		t.Errorf("balance = %d, want 9", got)
+	}
+
+	// Voiding the sales books their points back; voiding the earning sale
+	// after its points were spent leaves the balance negative
+	if err := repo.Delete(ctx, earning.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := pointsBalance(t, db, customer.ID); got != -6 {
+		t.Errorf("balance = %d, want -6", got)
+	}
+	if err := repo.Delete(ctx, redeeming.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := pointsBalance(t, db, customer.ID); got != 0 {
+		t.Errorf("balance = %d, want 0", got)
+	}
+
+	entries, err := loyalty.GetLedger(ctx, customer.ID)
+	if err != nil {
+		t.Fatalf("GetLedger() error = %v", err)
+	}
+	var types []string
+	for _, e := range entries {
+		types = append(types, e.Type)
+	}
+	want := []string{models.PointsVoidRedeem, models.PointsVoidEarn, models.PointsVoidEarn, models.PointsEarn, models.PointsRedeem, models.PointsEarn}
+	if len(types) != len(want) {
+		t.Fatalf("ledger = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("ledger = %v, want %v", types, want)
+		}
+	}
+}
+
+func TestPostgresLoyaltyExpirePoints(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransactionRepository(db)
+	loyalty := NewLoyaltyRepository(db)
+
+	customer, err := NewCustomerRepository(db).Create(ctx, models.Customer{Name: "Budi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := models.TransactionDetail{ProductID: kopiID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000}
+	expired := time.Now().Add(-time.Hour)
+	valid := time.Now().Add(time.Hour)
+	for _, expiresAt := range []*time.Time{&expired, &valid, nil} {
+		_, err := repo.Create(ctx, models.Transaction{
+			OutletID:   models.DefaultOutletID,
+			CustomerID: &customer.ID, TotalAmount: 5000, PointsEarned: 5, PointsExpireAt: expiresAt,
+			Details: []models.TransactionDetail{line},
+		})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	points, err := loyalty.ExpirePoints(ctx)
+	if err != nil {
+		t.Fatalf("ExpirePoints() error = %v", err)
+	}
+	if points != 5 {
+		t.Errorf("ExpirePoints() = %d, want 5", points)
+	}
+	if got := pointsBalance(t, db, customer.ID); got != 10 {
+		t.Errorf("balance = %d, want 10", got)
+	}
+	if points, err := loyalty.ExpirePoints(ctx); err != nil || points != 0 {
+		t.Errorf("ExpirePoints() again = %d, %v; want nothing left to expire", points, err)
+	}
+}
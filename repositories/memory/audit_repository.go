@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// auditRepository is the in-memory implementation of AuditRepository
+type auditRepository struct {
+	store *Store
+}
+
+// NewAuditRepository creates a new AuditRepository on the store
+func NewAuditRepository(store *Store) repositories.AuditRepository {
+	return &auditRepository{store: store}
+}
+
+// Create appends an entry to the audit log
+func (r *auditRepository) Create(ctx context.Context, entry models.AuditEntry) (*models.AuditEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entry.ID = r.store.nextID("audit_log")
+	entry.CreatedAt = r.store.Now()
+	entry.Before = slices.Clone(entry.Before)
+	entry.After = slices.Clone(entry.After)
+	r.store.auditLog = append(r.store.auditLog, entry)
+	return &entry, nil
+}
+
+// GetAll returns the page of entries matching the filter, newest first
+func (r *auditRepository) GetAll(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var entries []models.AuditEntry
+	skipped := 0
+	for i := len(r.store.auditLog) - 1; i >= 0 && len(entries) < filter.Limit; i-- {
+		e := r.store.auditLog[i]
+		if (filter.Actor != "" && e.Actor != filter.Actor) ||
+			(filter.Action != "" && e.Action != filter.Action) ||
+			(filter.EntityType != "" && e.EntityType != filter.EntityType) ||
+			(filter.EntityID > 0 && e.EntityID != filter.EntityID) ||
+			(filter.From != nil && e.CreatedAt.Before(*filter.From)) ||
+			(filter.To != nil && !e.CreatedAt.Before(*filter.To)) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		e.Before = slices.Clone(e.Before)
+		e.After = slices.Clone(e.After)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// categoryRepository is the in-memory implementation of CategoryRepository
+type categoryRepository struct {
+	store *Store
+}
+
+// NewCategoryRepository creates a new CategoryRepository on the store
+func NewCategoryRepository(store *Store) repositories.CategoryRepository {
+	return &categoryRepository{store: store}
+}
+
+// GetAll returns the categories, without the deleted ones unless the filter
+// includes them
+func (r *categoryRepository) GetAll(ctx context.Context, filter models.CategoryFilter) ([]models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var categories []models.Category
+	for _, c := range r.store.categories {
+		if c.DeletedAt != nil && !filter.IncludeDeleted {
+			continue
+		}
+		categories = append(categories, *copyCategory(c))
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories, nil
+}
+
+// GetByID returns a category by ID
+func (r *categoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, ok := r.store.categories[id]
+	if !ok {
+		return nil, fmt.Errorf("Category with ID %d not found", id)
+	}
+	return copyCategory(c), nil
+}
+
+// Create adds a new category
+func (r *categoryRepository) Create(ctx context.Context, category models.Category) (*models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	category.ID = r.store.nextID("categories")
+	category.DeletedAt = nil
+	if category.PointsMultiplier == nil {
+		category.PointsMultiplier = floatPtr(1)
+	}
+	r.store.categories[category.ID] = category
+	return copyCategory(category), nil
+}
+
+// Update updates an existing category
+func (r *categoryRepository) Update(ctx context.Context, id int, category models.Category) (*models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.categories[id]
+	if !ok || existing.DeletedAt != nil {
+		return nil, fmt.Errorf("Category with ID %d not found", id)
+	}
+	category.ID = id
+	category.DeletedAt = nil
+	if category.PointsMultiplier == nil {
+		category.PointsMultiplier = existing.PointsMultiplier
+	}
+	r.store.categories[id] = category
+	return copyCategory(category), nil
+}
+
+// Delete soft deletes a category by ID; deleting it again is not found. Its
+// products move to reassignTo, or it is refused while it has products that
+// are not deleted.
+func (r *categoryRepository) Delete(ctx context.Context, id int, reassignTo *int, changedBy string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, ok := r.store.categories[id]
+	if !ok || c.DeletedAt != nil {
+		return fmt.Errorf("Category with ID %d not found", id)
+	}
+	if reassignTo != nil {
+		target, ok := r.store.categories[*reassignTo]
+		if !ok || target.DeletedAt != nil || target.ID == id {
+			return fmt.Errorf("%w: category with ID %d does not exist", repositories.ErrReassignTarget, *reassignTo)
+		}
+	}
+
+	var products []int
+	for pid, p := range r.store.products {
+		if !inCategory(p, id) {
+			continue
+		}
+		if reassignTo == nil && p.DeletedAt == nil {
+			return fmt.Errorf("%w: category with ID %d has products", repositories.ErrInUse, id)
+		}
+		products = append(products, pid)
+	}
+	if reassignTo != nil {
+		sort.Ints(products)
+		for _, pid := range products {
+			p := r.store.products[pid]
+			target := *reassignTo
+			p.CategoryID = &target
+			r.store.products[pid] = p
+			r.store.addProductVersion(p, changedBy)
+		}
+	}
+
+	deletedAt := r.store.Now()
+	c.DeletedAt = &deletedAt
+	r.store.categories[id] = c
+	return nil
+}
+
+// Restore undoes the deletion of a category
+func (r *categoryRepository) Restore(ctx context.Context, id int) (*models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, ok := r.store.categories[id]
+	if !ok {
+		return nil, fmt.Errorf("Category with ID %d not found", id)
+	}
+	if c.DeletedAt == nil {
+		return nil, fmt.Errorf("%w: category with ID %d is not deleted", repositories.ErrNotDeleted, id)
+	}
+	c.DeletedAt = nil
+	r.store.categories[id] = c
+	return copyCategory(c), nil
+}
+
+// Purge permanently removes a deleted category; its products lose their
+// category, each recorded as the next version of the product by changedBy, so
+// a category whose products were sold cannot be purged
+func (r *categoryRepository) Purge(ctx context.Context, id int, changedBy string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, ok := r.store.categories[id]
+	if !ok {
+		return fmt.Errorf("Category with ID %d not found", id)
+	}
+	if c.DeletedAt == nil {
+		return fmt.Errorf("%w: category with ID %d must be deleted before it is purged", repositories.ErrNotDeleted, id)
+	}
+	var products []int
+	for pid, p := range r.store.products {
+		if !inCategory(p, id) {
+			continue
+		}
+		if r.store.productSold(pid) {
+			return fmt.Errorf("%w: category with ID %d is referenced by sales", repositories.ErrInUse, id)
+		}
+		products = append(products, pid)
+	}
+	delete(r.store.categories, id)
+
+	sort.Ints(products)
+	for _, pid := range products {
+		p := r.store.products[pid]
+		p.CategoryID = nil
+		r.store.products[pid] = p
+		r.store.addProductVersion(p, changedBy)
+	}
+	return nil
+}
+
+// copyCategory copies a category so callers cannot change the stored multiplier
+func copyCategory(c models.Category) *models.Category {
+	if c.PointsMultiplier != nil {
+		c.PointsMultiplier = floatPtr(*c.PointsMultiplier)
+	}
+	return &c
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// customerRepository is the in-memory implementation of CustomerRepository
+type customerRepository struct {
+	store *Store
+}
+
+// NewCustomerRepository creates a new CustomerRepository on the store
+func NewCustomerRepository(store *Store) repositories.CustomerRepository {
+	return &customerRepository{store: store}
+}
+
+// GetAll returns all customers, optionally searched by name, phone, email or
+// member number
+func (r *customerRepository) GetAll(ctx context.Context, filter models.CustomerFilter) ([]models.Customer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	search := strings.ToLower(filter.Search)
+	var customers []models.Customer
+	for _, c := range r.store.customers {
+		if search != "" &&
+			!strings.Contains(strings.ToLower(c.Name), search) &&
+			!strings.Contains(strings.ToLower(c.Phone), search) &&
+			!strings.Contains(strings.ToLower(c.Email), search) &&
+			!strings.Contains(strings.ToLower(c.MemberNumber), search) {
+			continue
+		}
+		customers = append(customers, c)
+	}
+	sort.Slice(customers, func(i, j int) bool {
+		if customers[i].Name == customers[j].Name {
+			return customers[i].ID < customers[j].ID
+		}
+		return customers[i].Name < customers[j].Name
+	})
+	return customers, nil
+}
+
+// GetByID returns a customer by ID
+func (r *customerRepository) GetByID(ctx context.Context, id int) (*models.Customer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, ok := r.store.customers[id]
+	if !ok {
+		return nil, fmt.Errorf("Customer with ID %d not found", id)
+	}
+	return &c, nil
+}
+
+// Create adds a new customer
+func (r *customerRepository) Create(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkCustomer(0, customer); err != nil {
+		return nil, err
+	}
+	customer.ID = r.store.nextID("customers")
+	customer.CreatedAt = r.store.Now()
+	customer.PointsBalance = 0
+	r.store.customers[customer.ID] = customer
+	return &customer, nil
+}
+
+// Update updates an existing customer; the points balance only changes
+// through sales
+func (r *customerRepository) Update(ctx context.Context, id int, customer models.Customer) (*models.Customer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.customers[id]
+	if !ok {
+		return nil, fmt.Errorf("Customer with ID %d not found", id)
+	}
+	if err := r.checkCustomer(id, customer); err != nil {
+		return nil, err
+	}
+	customer.ID = id
+	customer.CreatedAt = existing.CreatedAt
+	customer.PointsBalance = existing.PointsBalance
+	r.store.customers[id] = customer
+	return &customer, nil
+}
+
+// Delete removes a customer by ID with their points ledger; their
+// transactions lose the customer
+func (r *customerRepository) Delete(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.customers[id]; !ok {
+		return fmt.Errorf("Customer with ID %d not found", id)
+	}
+	delete(r.store.customers, id)
+
+	ledger := r.store.ledger[:0]
+	for _, e := range r.store.ledger {
+		if e.CustomerID != id {
+			ledger = append(ledger, e)
+		}
+	}
+	r.store.ledger = ledger
+
+	for tid, t := range r.store.transactions {
+		if t.CustomerID != nil && *t.CustomerID == id {
+			t.CustomerID = nil
+			r.store.transactions[tid] = t
+		}
+	}
+	return nil
+}
+
+// checkCustomer enforces the unique phone and member number of customers and
+// the foreign key of their price list
+func (r *customerRepository) checkCustomer(id int, customer models.Customer) error {
+	if customer.PriceListID != nil {
+		if _, ok := r.store.priceLists[*customer.PriceListID]; !ok {
+			return fmt.Errorf("price list with ID %d does not exist", *customer.PriceListID)
+		}
+	}
+	for _, c := range r.store.customers {
+		if c.ID == id {
+			continue
+		}
+		if customer.Phone != "" && c.Phone == customer.Phone {
+			return fmt.Errorf("customer with phone %s already exists", customer.Phone)
+		}
+		if customer.MemberNumber != "" && c.MemberNumber == customer.MemberNumber {
+			return fmt.Errorf("customer with member number %s already exists", customer.MemberNumber)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// healthRepository is the in-memory implementation of HealthRepository
+type healthRepository struct {
+	store *Store
+}
+
+// NewHealthRepository creates a new HealthRepository on the store
+func NewHealthRepository(store *Store) repositories.HealthRepository {
+	return &healthRepository{store: store}
+}
+
+// Ping reports the error set with Store.SetPingError, if any
+func (r *healthRepository) Ping(ctx context.Context) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.store.pingErr
+}
+
+// Stats returns empty pool statistics; the store has no connection pool
+func (r *healthRepository) Stats() models.PoolStats {
+	return models.PoolStats{}
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// idempotencyKey identifies an idempotency key of an owner
+type idempotencyKey struct {
+	owner string
+	key   string
+}
+
+// idempotencyRepository is the in-memory implementation of IdempotencyRepository
+type idempotencyRepository struct {
+	store *Store
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository on the store
+func NewIdempotencyRepository(store *Store) repositories.IdempotencyRepository {
+	return &idempotencyRepository{store: store}
+}
+
+// Reserve claims a key of an owner for a request. It returns true when the
+// key was free (or expired); otherwise it returns the record already stored
+// for the key.
+func (r *idempotencyRepository) Reserve(ctx context.Context, owner, key, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id := idempotencyKey{owner: owner, key: key}
+	record, ok := r.store.idempotency[id]
+	if ok && !record.ExpiresAt.After(r.store.Now()) {
+		ok = false
+	}
+	if ok {
+		return &record, false, nil
+	}
+
+	r.store.idempotency[id] = models.IdempotencyRecord{Owner: owner, Key: key, RequestHash: requestHash, ExpiresAt: expiresAt}
+	return nil, true, nil
+}
+
+// Complete stores the response of the request that reserved a key
+func (r *idempotencyRepository) Complete(ctx context.Context, owner, key string, statusCode int, responseBody string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id := idempotencyKey{owner: owner, key: key}
+	if record, ok := r.store.idempotency[id]; ok {
+		record.StatusCode = statusCode
+		record.ResponseBody = responseBody
+		record.Completed = true
+		r.store.idempotency[id] = record
+	}
+	return nil
+}
+
+// Release frees a reserved key so the request can be retried
+func (r *idempotencyRepository) Release(ctx context.Context, owner, key string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id := idempotencyKey{owner: owner, key: key}
+	if record, ok := r.store.idempotency[id]; ok && !record.Completed {
+		delete(r.store.idempotency, id)
+	}
+	return nil
+}
+
+// DeleteExpired removes all expired keys and returns how many were removed
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var removed int64
+	now := r.store.Now()
+	for key, record := range r.store.idempotency {
+		if !record.ExpiresAt.After(now) {
+			delete(r.store.idempotency, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// loyaltyRepository is the in-memory implementation of LoyaltyRepository
+type loyaltyRepository struct {
+	store *Store
+}
+
+// NewLoyaltyRepository creates a new LoyaltyRepository on the store
+func NewLoyaltyRepository(store *Store) repositories.LoyaltyRepository {
+	return &loyaltyRepository{store: store}
+}
+
+// GetLedger returns the ledger entries of a customer, newest first
+func (r *loyaltyRepository) GetLedger(ctx context.Context, customerID int) ([]models.PointsEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var entries []models.PointsEntry
+	for i := len(r.store.ledger) - 1; i >= 0; i-- {
+		if e := r.store.ledger[i]; e.CustomerID == customerID {
+			entries = append(entries, copyPointsEntry(e))
+		}
+	}
+	return entries, nil
+}
+
+// ExpirePoints books the expiry of the points left in lots that expired and
+// returns the number of points expired
+func (r *loyaltyRepository) ExpirePoints(ctx context.Context) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.expireLots(0), nil
+}
+
+// expireLots books the expiry of the points left in the expired lots of a
+// customer, of every customer when customerID is 0, and returns the number of
+// points expired
+func (s *Store) expireLots(customerID int) int {
+	now := s.Now()
+	var total int
+	for i := range s.ledger {
+		lot := &s.ledger[i]
+		if customerID != 0 && lot.CustomerID != customerID {
+			continue
+		}
+		if lot.Remaining == 0 || lot.ExpiresAt == nil || lot.ExpiresAt.After(now) {
+			continue
+		}
+		expired := lot.Remaining
+		lot.Remaining = 0
+		customer := s.customers[lot.CustomerID]
+		customer.PointsBalance -= expired
+		s.customers[customer.ID] = customer
+		s.addPointsEntry(lot.CustomerID, lot.TransactionID, models.PointsExpire, -expired, 0, lot.ExpiresAt)
+		total += expired
+	}
+	return total
+}
+
+// bookPoints books the points a new transaction redeems and earns on the
+// ledger of its customer; the caller checked the customer has the points
+func (s *Store) bookPoints(transaction models.Transaction) {
+	if transaction.CustomerID == nil || (transaction.PointsEarned == 0 && transaction.PointsRedeemed == 0) {
+		return
+	}
+	customer := s.customers[*transaction.CustomerID]
+	transactionID := transaction.ID
+
+	if transaction.PointsRedeemed > 0 {
+		expiresAt := s.consumeLots(customer.ID, transaction.PointsRedeemed, 0)
+		s.addPointsEntry(customer.ID, &transactionID, models.PointsRedeem, -transaction.PointsRedeemed, 0, expiresAt)
+		customer.PointsBalance -= transaction.PointsRedeemed
+	}
+	if transaction.PointsEarned > 0 {
+		s.addPointsEntry(customer.ID, &transactionID, models.PointsEarn, transaction.PointsEarned,
+			lotRemaining(transaction.PointsEarned, customer.PointsBalance), transaction.PointsExpireAt)
+		customer.PointsBalance += transaction.PointsEarned
+	}
+	s.customers[customer.ID] = customer
+}
+
+// voidPoints books back the points a transaction earned and redeemed, like
+// the PostgreSQL repository does when a transaction is deleted
+func (s *Store) voidPoints(transaction models.Transaction) {
+	if transaction.CustomerID == nil || (transaction.PointsEarned == 0 && transaction.PointsRedeemed == 0) {
+		return
+	}
+	if _, ok := s.customers[*transaction.CustomerID]; !ok {
+		return
+	}
+	s.expireLots(*transaction.CustomerID)
+	customer := s.customers[*transaction.CustomerID]
+	transactionID := transaction.ID
+
+	if earned := transaction.PointsEarned; earned > 0 {
+		s.consumeLots(customer.ID, min(earned, max(customer.PointsBalance, 0)), transactionID)
+		s.addPointsEntry(customer.ID, &transactionID, models.PointsVoidEarn, -earned, 0, nil)
+		customer.PointsBalance -= earned
+	}
+	if redeemed := transaction.PointsRedeemed; redeemed > 0 {
+		var expiresAt *time.Time
+		for _, e := range s.ledger {
+			if e.Type == models.PointsRedeem && e.TransactionID != nil && *e.TransactionID == transactionID {
+				expiresAt = e.ExpiresAt
+				break
+			}
+		}
+		s.addPointsEntry(customer.ID, &transactionID, models.PointsVoidRedeem, redeemed,
+			lotRemaining(redeemed, customer.PointsBalance), expiresAt)
+		customer.PointsBalance += redeemed
+	}
+	s.customers[customer.ID] = customer
+}
+
+// consumeLots takes points off the open lots of a customer, the lot earned
+// by transactionID first, then the lots expiring first, and returns the
+// earliest expiry of the lots taken from
+func (s *Store) consumeLots(customerID, points, transactionID int) *time.Time {
+	var lots []int
+	for i, e := range s.ledger {
+		if e.CustomerID == customerID && e.Remaining > 0 {
+			lots = append(lots, i)
+		}
+	}
+	own := func(e models.PointsEntry) bool {
+		return e.Type == models.PointsEarn && e.TransactionID != nil && *e.TransactionID == transactionID
+	}
+	sort.SliceStable(lots, func(i, j int) bool {
+		a, b := s.ledger[lots[i]], s.ledger[lots[j]]
+		if own(a) != own(b) {
+			return own(a)
+		}
+		if a.ExpiresAt == nil || b.ExpiresAt == nil {
+			return a.ExpiresAt != nil && b.ExpiresAt == nil
+		}
+		return a.ExpiresAt.Before(*b.ExpiresAt)
+	})
+
+	var earliest *time.Time
+	for _, i := range lots {
+		if points <= 0 {
+			break
+		}
+		lot := &s.ledger[i]
+		taken := min(lot.Remaining, points)
+		lot.Remaining -= taken
+		points -= taken
+		if lot.ExpiresAt != nil && (earliest == nil || lot.ExpiresAt.Before(*earliest)) {
+			earliest = lot.ExpiresAt
+		}
+	}
+	return earliest
+}
+
+// addPointsEntry appends an entry to the ledger of a customer
+func (s *Store) addPointsEntry(customerID int, transactionID *int, entryType string, points, remaining int, expiresAt *time.Time) {
+	s.ledger = append(s.ledger, copyPointsEntry(models.PointsEntry{
+		ID:            s.nextID("loyalty_points_ledger"),
+		CustomerID:    customerID,
+		TransactionID: transactionID,
+		Type:          entryType,
+		Points:        points,
+		Remaining:     remaining,
+		ExpiresAt:     expiresAt,
+		CreatedAt:     s.Now(),
+	}))
+}
+
+// lotRemaining returns what is left of points added to a balance once they
+// paid off a negative balance
+func lotRemaining(points, balance int) int {
+	if balance < 0 {
+		return max(points+balance, 0)
+	}
+	return points
+}
+
+// copyPointsEntry copies a ledger entry so callers cannot change stored rows
+func copyPointsEntry(e models.PointsEntry) models.PointsEntry {
+	if e.TransactionID != nil {
+		id := *e.TransactionID
+		e.TransactionID = &id
+	}
+	if e.ExpiresAt != nil {
+		expiresAt := *e.ExpiresAt
+		e.ExpiresAt = &expiresAt
+	}
+	return e
+}
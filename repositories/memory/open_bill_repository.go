@@ -0,0 +1,382 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// openBillRepository is the in-memory implementation of OpenBillRepository
+type openBillRepository struct {
+	store *Store
+}
+
+// NewOpenBillRepository creates a new OpenBillRepository on the store
+func NewOpenBillRepository(store *Store) repositories.OpenBillRepository {
+	return &openBillRepository{store: store}
+}
+
+// GetAll returns the bills matching filter, newest first, without their items
+func (r *openBillRepository) GetAll(ctx context.Context, filter models.OpenBillFilter) ([]models.OpenBill, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var bills []models.OpenBill
+	for _, b := range r.store.bills {
+		if filter.OutletIDs != nil && !slices.Contains(filter.OutletIDs, b.OutletID) {
+			continue
+		}
+		if filter.Status != "" && b.Status != filter.Status {
+			continue
+		}
+		b.Items = nil
+		bills = append(bills, copyOpenBill(b))
+	}
+	sort.Slice(bills, func(i, j int) bool {
+		if bills[i].CreatedAt.Equal(bills[j].CreatedAt) {
+			return bills[i].ID > bills[j].ID
+		}
+		return bills[i].CreatedAt.After(bills[j].CreatedAt)
+	})
+	return bills, nil
+}
+
+// GetByID returns an open bill by ID with its items and their reserved stock
+func (r *openBillRepository) GetByID(ctx context.Context, id int) (*models.OpenBill, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	b, ok := r.store.bills[id]
+	if !ok {
+		return nil, fmt.Errorf("Open bill with ID %d not found", id)
+	}
+	b = copyOpenBill(b)
+	return &b, nil
+}
+
+// Create opens a bill with its items, reserving their stock when the bill
+// reserves stock
+func (r *openBillRepository) Create(ctx context.Context, bill models.OpenBill) (*models.OpenBill, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.outlets[bill.OutletID]; !ok {
+		return nil, fmt.Errorf("outlet with ID %d does not exist", bill.OutletID)
+	}
+	for _, b := range r.store.bills {
+		if b.Status == models.BillOpen && b.OutletID == bill.OutletID && strings.EqualFold(b.Label, bill.Label) {
+			return nil, fmt.Errorf("open bill with label %s already exists", bill.Label)
+		}
+	}
+
+	items := make([]models.OpenBillItem, 0, len(bill.Items))
+	for _, item := range bill.Items {
+		created, err := r.newItem(bill.OutletID, bill.ReserveStock, item)
+		if err != nil {
+			// Give back what the items before reserved
+			for _, reserved := range items {
+				r.store.releaseStock(bill.OutletID, reserved.Reserved)
+			}
+			return nil, err
+		}
+		items = append(items, created)
+	}
+
+	bill.ID = r.store.nextID("open_bills")
+	bill.Status = models.BillOpen
+	bill.Items = items
+	bill.TotalAmount = 0
+	bill.TransactionID = nil
+	bill.CreatedAt = r.store.Now()
+	bill.ClosedAt = nil
+	r.store.bills[bill.ID] = bill
+
+	bill = copyOpenBill(bill)
+	return &bill, nil
+}
+
+// AddItem adds an item to an open bill
+func (r *openBillRepository) AddItem(ctx context.Context, billID int, item models.OpenBillItem) (*models.OpenBillItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	b, err := r.openBill(billID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := r.newItem(b.OutletID, b.ReserveStock, item)
+	if err != nil {
+		return nil, err
+	}
+	b.Items = append(b.Items, created)
+	r.store.bills[billID] = b
+
+	created = copyBillItem(created)
+	return &created, nil
+}
+
+// UpdateItem replaces an item of an open bill; the stock it reserved is given
+// back before the stock of the new item is reserved
+func (r *openBillRepository) UpdateItem(ctx context.Context, billID, itemID int, item models.OpenBillItem) (*models.OpenBillItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	b, err := r.openBill(billID)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(b.Items, func(it models.OpenBillItem) bool { return it.ID == itemID })
+	if i < 0 {
+		return nil, fmt.Errorf("Open bill item with ID %d not found", itemID)
+	}
+
+	existing := b.Items[i]
+	r.store.releaseStock(b.OutletID, existing.Reserved)
+	updated, err := r.checkItem(b.OutletID, b.ReserveStock, item)
+	if err != nil {
+		r.store.reserveStock(b.OutletID, existing.Reserved)
+		return nil, err
+	}
+	updated.ID = itemID
+	b.Items = slices.Clone(b.Items)
+	b.Items[i] = updated
+	r.store.bills[billID] = b
+
+	updated = copyBillItem(updated)
+	return &updated, nil
+}
+
+// RemoveItem removes an item from an open bill and gives back the stock it
+// reserved
+func (r *openBillRepository) RemoveItem(ctx context.Context, billID, itemID int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	b, err := r.openBill(billID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(b.Items, func(it models.OpenBillItem) bool { return it.ID == itemID })
+	if i < 0 {
+		return fmt.Errorf("Open bill item with ID %d not found", itemID)
+	}
+
+	r.store.releaseStock(b.OutletID, b.Items[i].Reserved)
+	b.Items = slices.Delete(slices.Clone(b.Items), i, i+1)
+	r.store.bills[billID] = b
+	return nil
+}
+
+// Cancel closes an open bill without a sale and gives back the stock it
+// reserved
+func (r *openBillRepository) Cancel(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	b, err := r.openBill(id)
+	if err != nil {
+		return err
+	}
+	r.close(&b, models.BillCancelled)
+	return nil
+}
+
+// Checkout gives back the stock an open bill reserved, creates its
+// transaction, which takes the sold stock again, and closes the bill
+func (r *openBillRepository) Checkout(ctx context.Context, id int, items []models.OpenBillItem, transaction models.Transaction) (*models.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	b, err := r.openBill(id)
+	if err != nil {
+		return nil, err
+	}
+	if !repositories.SameBillItems(b.Items, items) {
+		return nil, fmt.Errorf("%w: the items of open bill with ID %d changed, check it out again", repositories.ErrBillChanged, id)
+	}
+	for _, item := range b.Items {
+		r.store.releaseStock(b.OutletID, item.Reserved)
+	}
+	if err := r.store.createTransaction(&transaction); err != nil {
+		for _, item := range b.Items {
+			r.store.reserveStock(b.OutletID, item.Reserved)
+		}
+		return nil, err
+	}
+
+	b.TransactionID = &transaction.ID
+	r.close(&b, models.BillCheckedOut)
+	return &transaction, nil
+}
+
+// openBill returns a bill that must still be open
+func (r *openBillRepository) openBill(id int) (models.OpenBill, error) {
+	b, ok := r.store.bills[id]
+	if !ok {
+		return b, fmt.Errorf("Open bill with ID %d not found", id)
+	}
+	if b.Status != models.BillOpen {
+		return b, fmt.Errorf("%w: open bill with ID %d is %s", repositories.ErrBillClosed, id, b.Status)
+	}
+	return b, nil
+}
+
+// close closes a bill with status; the stock its items reserved is given back
+// unless a checkout already did
+func (r *openBillRepository) close(b *models.OpenBill, status string) {
+	items := make([]models.OpenBillItem, len(b.Items))
+	for i, item := range b.Items {
+		if status != models.BillCheckedOut {
+			r.store.releaseStock(b.OutletID, item.Reserved)
+		}
+		item.Reserved = nil
+		items[i] = item
+	}
+	now := r.store.Now()
+	b.Items = items
+	b.Status = status
+	b.ClosedAt = &now
+	r.store.bills[b.ID] = *b
+}
+
+// newItem gives a new item its ID and reserves its stock
+func (r *openBillRepository) newItem(outletID int, reserve bool, item models.OpenBillItem) (models.OpenBillItem, error) {
+	created, err := r.checkItem(outletID, reserve, item)
+	if err != nil {
+		return created, err
+	}
+	created.ID = r.store.nextID("open_bill_items")
+	return created, nil
+}
+
+// checkItem enforces the foreign keys of an item and reserves its stock when
+// the bill reserves stock
+func (r *openBillRepository) checkItem(outletID int, reserve bool, item models.OpenBillItem) (models.OpenBillItem, error) {
+	if _, ok := r.store.products[item.ProductID]; !ok {
+		return item, fmt.Errorf("product with ID %d does not exist", item.ProductID)
+	}
+	if item.VariantID != nil {
+		if _, ok := r.store.variants[*item.VariantID]; !ok {
+			return item, fmt.Errorf("variant with ID %d does not exist", *item.VariantID)
+		}
+	}
+
+	item = copyBillItem(item)
+	item.Quantity = roundStock(item.Quantity)
+	if !reserve || len(item.Reserved) == 0 {
+		item.Reserved = nil
+		return item, nil
+	}
+	details := make([]models.TransactionDetail, len(item.Reserved))
+	for i := range item.Reserved {
+		res := &item.Reserved[i]
+		res.Quantity = roundStock(res.Quantity)
+		details[i] = models.TransactionDetail{ProductID: res.ProductID, VariantID: res.VariantID, Quantity: res.Quantity}
+	}
+	if _, err := r.store.deductStock(details, outletID, false); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// reserveStock takes reserved quantities off the stock of an outlet again
+// after they were given back
+func (s *Store) reserveStock(outletID int, reserved []models.ReservedStock) {
+	for _, res := range reserved {
+		s.addTotalStock(res.ProductID, res.VariantID, -res.Quantity)
+		s.addOutletStock(outletID, res.ProductID, res.VariantID, -res.Quantity)
+	}
+}
+
+// releaseStock gives reserved quantities back to the stock of an outlet
+func (s *Store) releaseStock(outletID int, reserved []models.ReservedStock) {
+	for _, res := range reserved {
+		s.addTotalStock(res.ProductID, res.VariantID, res.Quantity)
+		s.addOutletStock(outletID, res.ProductID, res.VariantID, res.Quantity)
+	}
+}
+
+// dropBillItems removes the items of a deleted product or variant from the
+// bills, with their reservations (ON DELETE CASCADE)
+func (s *Store) dropBillItems(productID int, variantID *int) {
+	for id, b := range s.bills {
+		items := slices.DeleteFunc(slices.Clone(b.Items), func(item models.OpenBillItem) bool {
+			if variantID != nil {
+				return item.VariantID != nil && *item.VariantID == *variantID
+			}
+			return item.ProductID == productID
+		})
+		if len(items) != len(b.Items) {
+			b.Items = items
+			s.bills[id] = b
+		}
+	}
+}
+
+// openBillsReferenceProduct reports whether a bill that is still open holds
+// an item of a product
+func (s *Store) openBillsReferenceProduct(productID int) bool {
+	for _, b := range s.bills {
+		if b.Status != models.BillOpen {
+			continue
+		}
+		for _, item := range b.Items {
+			if item.ProductID == productID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// billsReferenceOutlet reports whether an outlet has bills, which the foreign
+// keys of the migrations do not allow to be deleted
+func (s *Store) billsReferenceOutlet(outletID int) bool {
+	for _, b := range s.bills {
+		if b.OutletID == outletID {
+			return true
+		}
+	}
+	return false
+}
+
+// copyOpenBill copies a bill so callers cannot change stored rows
+func copyOpenBill(b models.OpenBill) models.OpenBill {
+	if b.TransactionID != nil {
+		id := *b.TransactionID
+		b.TransactionID = &id
+	}
+	if b.Items != nil {
+		items := make([]models.OpenBillItem, len(b.Items))
+		for i, item := range b.Items {
+			items[i] = copyBillItem(item)
+		}
+		b.Items = items
+	}
+	return b
+}
+
+// copyBillItem copies a bill item with its reservations
+func copyBillItem(item models.OpenBillItem) models.OpenBillItem {
+	if item.VariantID != nil {
+		id := *item.VariantID
+		item.VariantID = &id
+	}
+	if item.Reserved != nil {
+		reserved := make([]models.ReservedStock, len(item.Reserved))
+		for i, res := range item.Reserved {
+			if res.VariantID != nil {
+				id := *res.VariantID
+				res.VariantID = &id
+			}
+			reserved[i] = res
+		}
+		item.Reserved = reserved
+	}
+	return item
+}
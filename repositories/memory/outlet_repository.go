@@ -0,0 +1,228 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// outletStockKey identifies the stock row of a product, or of one of its
+// variants when variantID is not 0, at an outlet
+type outletStockKey struct {
+	outletID  int
+	productID int
+	variantID int
+}
+
+// stockKey returns the key of a stock row
+func stockKey(outletID, productID int, variantID *int) outletStockKey {
+	key := outletStockKey{outletID: outletID, productID: productID}
+	if variantID != nil {
+		key.variantID = *variantID
+	}
+	return key
+}
+
+// outletRepository is the in-memory implementation of OutletRepository
+type outletRepository struct {
+	store *Store
+}
+
+// NewOutletRepository creates a new OutletRepository on the store
+func NewOutletRepository(store *Store) repositories.OutletRepository {
+	return &outletRepository{store: store}
+}
+
+// GetAll returns all outlets
+func (r *outletRepository) GetAll(ctx context.Context) ([]models.Outlet, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var outlets []models.Outlet
+	for _, o := range r.store.outlets {
+		outlets = append(outlets, o)
+	}
+	sort.Slice(outlets, func(i, j int) bool { return outlets[i].ID < outlets[j].ID })
+	return outlets, nil
+}
+
+// GetByID returns an outlet by ID
+func (r *outletRepository) GetByID(ctx context.Context, id int) (*models.Outlet, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	o, ok := r.store.outlets[id]
+	if !ok {
+		return nil, fmt.Errorf("Outlet with ID %d not found", id)
+	}
+	return &o, nil
+}
+
+// Create adds a new outlet
+func (r *outletRepository) Create(ctx context.Context, outlet models.Outlet) (*models.Outlet, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkCode(0, outlet.Code); err != nil {
+		return nil, err
+	}
+	outlet.ID = r.store.nextID("outlets")
+	outlet.CreatedAt = r.store.Now()
+	r.store.outlets[outlet.ID] = outlet
+	return &outlet, nil
+}
+
+// Update updates an existing outlet
+func (r *outletRepository) Update(ctx context.Context, id int, outlet models.Outlet) (*models.Outlet, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.outlets[id]
+	if !ok {
+		return nil, fmt.Errorf("Outlet with ID %d not found", id)
+	}
+	if err := r.checkCode(id, outlet.Code); err != nil {
+		return nil, err
+	}
+	outlet.ID = id
+	outlet.CreatedAt = existing.CreatedAt
+	r.store.outlets[id] = outlet
+	return &outlet, nil
+}
+
+// Delete removes an outlet by ID with its stock rows, taking their stock off
+// the totals of the products and variants
+func (r *outletRepository) Delete(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.outlets[id]; !ok {
+		return fmt.Errorf("Outlet with ID %d not found", id)
+	}
+	for _, t := range r.store.transactions {
+		if t.OutletID == id {
+			return fmt.Errorf("outlet with ID %d is referenced by sales", id)
+		}
+	}
+	for _, t := range r.store.transfers {
+		if t.FromOutletID == id || t.ToOutletID == id {
+			return fmt.Errorf("outlet with ID %d is referenced by transfers", id)
+		}
+	}
+	if r.store.billsReferenceOutlet(id) {
+		return fmt.Errorf("outlet with ID %d is referenced by open bills", id)
+	}
+
+	for key, row := range r.store.outletStock {
+		if key.outletID != id {
+			continue
+		}
+		r.store.addTotalStock(row.ProductID, row.VariantID, -row.Stock)
+		delete(r.store.outletStock, key)
+	}
+	delete(r.store.outlets, id)
+	return nil
+}
+
+// GetProducts returns the stock rows of an outlet
+func (r *outletRepository) GetProducts(ctx context.Context, outletID int, productIDs []int) ([]models.OutletProduct, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	wanted := make(map[int]bool, len(productIDs))
+	for _, id := range productIDs {
+		wanted[id] = true
+	}
+
+	var products []models.OutletProduct
+	for key, row := range r.store.outletStock {
+		if key.outletID != outletID || (productIDs != nil && !wanted[key.productID]) {
+			continue
+		}
+		products = append(products, copyOutletProduct(row))
+	}
+	sort.Slice(products, func(i, j int) bool {
+		a, b := stockKey(0, products[i].ProductID, products[i].VariantID), stockKey(0, products[j].ProductID, products[j].VariantID)
+		if a.productID != b.productID {
+			return a.productID < b.productID
+		}
+		return a.variantID < b.variantID
+	})
+	return products, nil
+}
+
+// SetProduct sets the stock and price override of a product or variant at an
+// outlet; the total stock changes by the difference
+func (r *outletRepository) SetProduct(ctx context.Context, product models.OutletProduct) (*models.OutletProduct, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.outlets[product.OutletID]; !ok {
+		return nil, fmt.Errorf("outlet with ID %d does not exist", product.OutletID)
+	}
+	if product.VariantID != nil {
+		if v, ok := r.store.variants[*product.VariantID]; !ok || v.ProductID != product.ProductID {
+			return nil, fmt.Errorf("Variant with ID %d not found", *product.VariantID)
+		}
+	} else if _, ok := r.store.products[product.ProductID]; !ok {
+		return nil, fmt.Errorf("Product with ID %d not found", product.ProductID)
+	}
+
+	key := stockKey(product.OutletID, product.ProductID, product.VariantID)
+	product.Stock = roundStock(product.Stock)
+	r.store.addTotalStock(product.ProductID, product.VariantID, product.Stock-r.store.outletStock[key].Stock)
+	r.store.outletStock[key] = copyOutletProduct(product)
+	return &product, nil
+}
+
+// checkCode enforces the unique code of outlets
+func (r *outletRepository) checkCode(id int, code string) error {
+	for _, o := range r.store.outlets {
+		if o.ID != id && o.Code == code {
+			return fmt.Errorf("outlet with code %s already exists", code)
+		}
+	}
+	return nil
+}
+
+// addOutletStock adds a quantity to the stock of a product or variant at an
+// outlet, creating the stock row the outlet did not have yet
+func (s *Store) addOutletStock(outletID, productID int, variantID *int, quantity float64) {
+	key := stockKey(outletID, productID, variantID)
+	row, ok := s.outletStock[key]
+	if !ok {
+		row = copyOutletProduct(models.OutletProduct{OutletID: outletID, ProductID: productID, VariantID: variantID})
+	}
+	row.Stock = roundStock(row.Stock + quantity)
+	s.outletStock[key] = row
+}
+
+// addTotalStock adds a quantity to the total stock of a product or variant
+func (s *Store) addTotalStock(productID int, variantID *int, quantity float64) {
+	if variantID != nil {
+		if v, ok := s.variants[*variantID]; ok {
+			v.Stock = roundStock(v.Stock + quantity)
+			s.variants[v.ID] = v
+		}
+		return
+	}
+	if p, ok := s.products[productID]; ok {
+		p.Stock = roundStock(p.Stock + quantity)
+		s.products[productID] = p
+	}
+}
+
+// copyOutletProduct copies a stock row so callers cannot change stored rows
+func copyOutletProduct(p models.OutletProduct) models.OutletProduct {
+	if p.VariantID != nil {
+		id := *p.VariantID
+		p.VariantID = &id
+	}
+	if p.Price != nil {
+		p.Price = floatPtr(*p.Price)
+	}
+	return p
+}
@@ -0,0 +1,310 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// priceListRepository is the in-memory implementation of PriceListRepository
+type priceListRepository struct {
+	store *Store
+}
+
+// NewPriceListRepository creates a new PriceListRepository on the store
+func NewPriceListRepository(store *Store) repositories.PriceListRepository {
+	return &priceListRepository{store: store}
+}
+
+// GetAll returns all price lists
+func (r *priceListRepository) GetAll(ctx context.Context) ([]models.PriceList, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var lists []models.PriceList
+	for _, l := range r.store.priceLists {
+		lists = append(lists, l)
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].ID < lists[j].ID })
+	return lists, nil
+}
+
+// GetByID returns a price list by ID
+func (r *priceListRepository) GetByID(ctx context.Context, id int) (*models.PriceList, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	l, ok := r.store.priceLists[id]
+	if !ok {
+		return nil, fmt.Errorf("Price list with ID %d not found", id)
+	}
+	return &l, nil
+}
+
+// Create adds a new price list
+func (r *priceListRepository) Create(ctx context.Context, list models.PriceList) (*models.PriceList, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkCode(0, list.Code); err != nil {
+		return nil, err
+	}
+	list.ID = r.store.nextID("price_lists")
+	list.CreatedAt = r.store.Now()
+	r.store.priceLists[list.ID] = list
+	return &list, nil
+}
+
+// Update updates an existing price list
+func (r *priceListRepository) Update(ctx context.Context, id int, list models.PriceList) (*models.PriceList, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.priceLists[id]
+	if !ok {
+		return nil, fmt.Errorf("Price list with ID %d not found", id)
+	}
+	if err := r.checkCode(id, list.Code); err != nil {
+		return nil, err
+	}
+	list.ID = id
+	list.CreatedAt = existing.CreatedAt
+	r.store.priceLists[id] = list
+	return &list, nil
+}
+
+// Delete removes a price list by ID with its rules; its customers and
+// transactions lose the price list
+func (r *priceListRepository) Delete(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.priceLists[id]; !ok {
+		return fmt.Errorf("Price list with ID %d not found", id)
+	}
+	delete(r.store.priceLists, id)
+	r.store.dropPriceRules(func(rule models.PriceRule) bool {
+		return rule.PriceListID != nil && *rule.PriceListID == id
+	})
+
+	for cid, c := range r.store.customers {
+		if c.PriceListID != nil && *c.PriceListID == id {
+			c.PriceListID = nil
+			r.store.customers[cid] = c
+		}
+	}
+	for tid, t := range r.store.transactions {
+		if t.PriceListID != nil && *t.PriceListID == id {
+			t.PriceListID = nil
+			r.store.transactions[tid] = t
+		}
+	}
+	return nil
+}
+
+// GetRules returns the rules matching filter
+func (r *priceListRepository) GetRules(ctx context.Context, filter models.PriceRuleFilter) ([]models.PriceRule, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.rules(func(rule models.PriceRule) bool {
+		if filter.ProductID > 0 && rule.ProductID != filter.ProductID {
+			return false
+		}
+		return filter.PriceListID <= 0 || (rule.PriceListID != nil && *rule.PriceListID == filter.PriceListID)
+	}), nil
+}
+
+// GetRuleByID returns a price rule by ID
+func (r *priceListRepository) GetRuleByID(ctx context.Context, id int) (*models.PriceRule, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rule, ok := r.store.priceRules[id]
+	if !ok {
+		return nil, fmt.Errorf("Price rule with ID %d not found", id)
+	}
+	rule = copyPriceRule(rule)
+	return &rule, nil
+}
+
+// CreateRule adds a new price rule
+func (r *priceListRepository) CreateRule(ctx context.Context, rule models.PriceRule) (*models.PriceRule, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkRule(rule); err != nil {
+		return nil, err
+	}
+	rule.ID = r.store.nextID("price_rules")
+	rule.CreatedAt = r.store.Now()
+	r.store.priceRules[rule.ID] = copyPriceRule(rule)
+	return &rule, nil
+}
+
+// UpdateRule updates an existing price rule
+func (r *priceListRepository) UpdateRule(ctx context.Context, id int, rule models.PriceRule) (*models.PriceRule, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.priceRules[id]
+	if !ok {
+		return nil, fmt.Errorf("Price rule with ID %d not found", id)
+	}
+	if err := r.checkRule(rule); err != nil {
+		return nil, err
+	}
+	rule.ID = id
+	rule.CreatedAt = existing.CreatedAt
+	r.store.priceRules[id] = copyPriceRule(rule)
+	return &rule, nil
+}
+
+// DeleteRule removes a price rule by ID; sold lines priced by it lose the
+// rule
+func (r *priceListRepository) DeleteRule(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.priceRules[id]; !ok {
+		return fmt.Errorf("Price rule with ID %d not found", id)
+	}
+	r.store.dropPriceRules(func(rule models.PriceRule) bool { return rule.ID == id })
+	return nil
+}
+
+// GetRulesInEffect returns the rules of the products that have started and
+// not ended at the given time
+func (r *priceListRepository) GetRulesInEffect(ctx context.Context, productIDs []int, at time.Time) ([]models.PriceRule, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	wanted := make(map[int]bool, len(productIDs))
+	for _, id := range productIDs {
+		wanted[id] = true
+	}
+	return r.rules(func(rule models.PriceRule) bool {
+		return wanted[rule.ProductID] &&
+			(rule.StartsAt == nil || !rule.StartsAt.After(at)) &&
+			(rule.EndsAt == nil || rule.EndsAt.After(at))
+	}), nil
+}
+
+// rules returns copies of the rules matching keep in the order of the
+// PostgreSQL repository: by product, variant and price list with nulls first,
+// then minimum quantity
+func (r *priceListRepository) rules(keep func(models.PriceRule) bool) []models.PriceRule {
+	var rules []models.PriceRule
+	for _, rule := range r.store.priceRules {
+		if keep(rule) {
+			rules = append(rules, copyPriceRule(rule))
+		}
+	}
+	nullsFirst := func(a, b *int) (int, bool) {
+		switch {
+		case a == nil && b == nil, a != nil && b != nil && *a == *b:
+			return 0, false
+		case a == nil:
+			return -1, true
+		case b == nil:
+			return 1, true
+		case *a < *b:
+			return -1, true
+		default:
+			return 1, true
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		if cmp, ok := nullsFirst(a.VariantID, b.VariantID); ok {
+			return cmp < 0
+		}
+		if cmp, ok := nullsFirst(a.PriceListID, b.PriceListID); ok {
+			return cmp < 0
+		}
+		if a.MinQuantity != b.MinQuantity {
+			return a.MinQuantity < b.MinQuantity
+		}
+		return a.ID < b.ID
+	})
+	return rules
+}
+
+// checkCode enforces the unique code of price lists
+func (r *priceListRepository) checkCode(id int, code string) error {
+	for _, l := range r.store.priceLists {
+		if l.ID != id && l.Code == code {
+			return fmt.Errorf("price list with code %s already exists", code)
+		}
+	}
+	return nil
+}
+
+// checkRule enforces the foreign keys of a price rule
+func (r *priceListRepository) checkRule(rule models.PriceRule) error {
+	if rule.PriceListID != nil {
+		if _, ok := r.store.priceLists[*rule.PriceListID]; !ok {
+			return fmt.Errorf("price list with ID %d does not exist", *rule.PriceListID)
+		}
+	}
+	if _, ok := r.store.products[rule.ProductID]; !ok {
+		return fmt.Errorf("product with ID %d does not exist", rule.ProductID)
+	}
+	if rule.VariantID != nil {
+		if _, ok := r.store.variants[*rule.VariantID]; !ok {
+			return fmt.Errorf("variant with ID %d does not exist", *rule.VariantID)
+		}
+	}
+	return nil
+}
+
+// dropPriceRules removes the rules matching drop; sold lines priced by them
+// lose the rule (ON DELETE SET NULL)
+func (s *Store) dropPriceRules(drop func(models.PriceRule) bool) {
+	dropped := make(map[int]bool)
+	for id, rule := range s.priceRules {
+		if drop(rule) {
+			dropped[id] = true
+			delete(s.priceRules, id)
+		}
+	}
+	if len(dropped) == 0 {
+		return
+	}
+	for tid, t := range s.transactions {
+		for i := range t.Details {
+			if t.Details[i].PriceRuleID != nil && dropped[*t.Details[i].PriceRuleID] {
+				t.Details[i].PriceRuleID = nil
+			}
+		}
+		s.transactions[tid] = t
+	}
+}
+
+// copyPriceRule copies a price rule so callers cannot change stored rows
+func copyPriceRule(rule models.PriceRule) models.PriceRule {
+	if rule.PriceListID != nil {
+		priceListID := *rule.PriceListID
+		rule.PriceListID = &priceListID
+	}
+	if rule.VariantID != nil {
+		variantID := *rule.VariantID
+		rule.VariantID = &variantID
+	}
+	if rule.StartsAt != nil {
+		startsAt := *rule.StartsAt
+		rule.StartsAt = &startsAt
+	}
+	if rule.EndsAt != nil {
+		endsAt := *rule.EndsAt
+		rule.EndsAt = &endsAt
+	}
+	return rule
+}
@@ -0,0 +1,363 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// productRepository is the in-memory implementation of ProductRepository
+type productRepository struct {
+	store *Store
+}
+
+// NewProductRepository creates a new ProductRepository on the store
+func NewProductRepository(store *Store) repositories.ProductRepository {
+	return &productRepository{store: store}
+}
+
+// GetAll returns all products with optional filters
+func (r *productRepository) GetAll(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var products []models.Product
+	for _, p := range r.store.products {
+		if p.DeletedAt != nil && !filter.IncludeDeleted {
+			continue
+		}
+		if filter.Name != "" && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(filter.Name)) {
+			continue
+		}
+		if filter.CategoryID > 0 && !inCategory(p, filter.CategoryID) {
+			continue
+		}
+		if filter.MinPrice > 0 && p.Price < filter.MinPrice {
+			continue
+		}
+		if filter.MaxPrice > 0 && p.Price > filter.MaxPrice {
+			continue
+		}
+		products = append(products, r.row(p))
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	return products, nil
+}
+
+// GetByID returns a product by ID
+func (r *productRepository) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.products[id]
+	if !ok {
+		return nil, fmt.Errorf("Product with ID %d not found", id)
+	}
+	product := r.row(p)
+	return &product, nil
+}
+
+// GetByIDs returns the products with the given IDs
+func (r *productRepository) GetByIDs(ctx context.Context, ids []int) ([]models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var products []models.Product
+	for _, id := range uniqueIDs(ids) {
+		if p, ok := r.store.products[id]; ok {
+			products = append(products, r.row(p))
+		}
+	}
+	return products, nil
+}
+
+// Create adds a new product; its initial stock is held by the main outlet
+func (r *productRepository) Create(ctx context.Context, product models.Product, changedBy string) (*models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkReferences(product); err != nil {
+		return nil, err
+	}
+	product.ID = r.store.nextID("products")
+	product.Stock = roundStock(product.Stock)
+	product.IsBundle = false
+	product.Variants = nil
+	product.Components = nil
+	product.DeletedAt = nil
+	r.store.products[product.ID] = product
+	r.store.addOutletStock(models.DefaultOutletID, product.ID, nil, product.Stock)
+	r.store.addProductVersion(product, changedBy)
+	return &product, nil
+}
+
+// Update updates an existing product; the main outlet takes the change of
+// stock, since the stock of a product is the total over all outlets. A change
+// is recorded as the next version of the product.
+func (r *productRepository) Update(ctx context.Context, id int, product models.Product, changedBy string) (*models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.products[id]
+	if !ok || existing.DeletedAt != nil {
+		return nil, fmt.Errorf("Product with ID %d not found", id)
+	}
+	if err := r.checkReferences(product); err != nil {
+		return nil, err
+	}
+
+	product.ID = id
+	stored := product
+	stored.Stock = roundStock(product.Stock)
+	stored.IsBundle = existing.IsBundle
+	stored.Variants = nil
+	stored.Components = nil
+	stored.DeletedAt = nil
+	r.store.products[id] = stored
+	r.store.addOutletStock(models.DefaultOutletID, id, nil, stored.Stock-existing.Stock)
+	if stored.Name != existing.Name || stored.Price != existing.Price || stored.Stock != existing.Stock ||
+		stored.Unit != existing.Unit || !sameCategory(stored.CategoryID, existing.CategoryID) {
+		r.store.addProductVersion(stored, changedBy)
+	}
+	return &product, nil
+}
+
+// Delete soft deletes a product by ID; deleting it again is not found
+func (r *productRepository) Delete(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.products[id]
+	if !ok || p.DeletedAt != nil {
+		return fmt.Errorf("Product with ID %d not found", id)
+	}
+	for bundleID, items := range r.store.bundleItems {
+		if r.store.products[bundleID].DeletedAt != nil {
+			continue
+		}
+		for _, item := range items {
+			if item.ProductID == id {
+				return fmt.Errorf("%w: product with ID %d is referenced by bundles", repositories.ErrInUse, id)
+			}
+		}
+	}
+
+	deletedAt := r.store.Now()
+	p.DeletedAt = &deletedAt
+	r.store.products[id] = p
+	return nil
+}
+
+// Restore undoes the deletion of a product; a product whose category was
+// deleted meanwhile loses it, so it is not live in a deleted category
+func (r *productRepository) Restore(ctx context.Context, id int, changedBy string) (*models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.products[id]
+	if !ok {
+		return nil, fmt.Errorf("Product with ID %d not found", id)
+	}
+	if p.DeletedAt == nil {
+		return nil, fmt.Errorf("%w: product with ID %d is not deleted", repositories.ErrNotDeleted, id)
+	}
+	p.DeletedAt = nil
+	if p.CategoryID != nil {
+		if c, ok := r.store.categories[*p.CategoryID]; !ok || c.DeletedAt != nil {
+			p.CategoryID = nil
+			r.store.addProductVersion(p, changedBy)
+		}
+	}
+	r.store.products[id] = p
+	product := r.row(p)
+	return &product, nil
+}
+
+// Purge permanently removes a deleted product together with its history,
+// variants, packaging units, bundle components, stock at the outlets, price
+// rules and items on closed bills; a product on a bill that is still open
+// cannot be purged
+func (r *productRepository) Purge(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.products[id]
+	if !ok {
+		return fmt.Errorf("Product with ID %d not found", id)
+	}
+	if p.DeletedAt == nil {
+		return fmt.Errorf("%w: product with ID %d must be deleted before it is purged", repositories.ErrNotDeleted, id)
+	}
+	if r.store.productSold(id) {
+		return fmt.Errorf("%w: product with ID %d is referenced by sales", repositories.ErrInUse, id)
+	}
+	if r.store.productInBundle(id) {
+		return fmt.Errorf("%w: product with ID %d is referenced by bundles", repositories.ErrInUse, id)
+	}
+	if r.store.transferReferenced(id, nil) {
+		return fmt.Errorf("%w: product with ID %d is referenced by transfers", repositories.ErrInUse, id)
+	}
+	if r.store.openBillsReferenceProduct(id) {
+		return fmt.Errorf("%w: product with ID %d is on open bills", repositories.ErrInUse, id)
+	}
+
+	delete(r.store.products, id)
+	delete(r.store.history, id)
+	delete(r.store.bundleItems, id)
+	r.store.dropBillItems(id, nil)
+	r.store.dropPriceRules(func(rule models.PriceRule) bool { return rule.ProductID == id })
+	for vid, v := range r.store.variants {
+		if v.ProductID == id {
+			delete(r.store.variants, vid)
+		}
+	}
+	for cid, c := range r.store.conversions {
+		if c.ProductID == id {
+			delete(r.store.conversions, cid)
+		}
+	}
+	for key := range r.store.outletStock {
+		if key.productID == id {
+			delete(r.store.outletStock, key)
+		}
+	}
+	return nil
+}
+
+// GetHistory returns the versions of a product, oldest first
+func (r *productRepository) GetHistory(ctx context.Context, productID int) ([]models.ProductVersion, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return append([]models.ProductVersion(nil), r.store.history[productID]...), nil
+}
+
+// addProductVersion records the state of a product as its next version
+func (s *Store) addProductVersion(product models.Product, changedBy string) {
+	versions := s.history[product.ID]
+	s.history[product.ID] = append(versions, models.ProductVersion{
+		ProductID:  product.ID,
+		Version:    len(versions) + 1,
+		Name:       product.Name,
+		Price:      product.Price,
+		Stock:      product.Stock,
+		Unit:       product.Unit,
+		CategoryID: product.CategoryID,
+		ChangedBy:  changedBy,
+		ChangedAt:  s.Now(),
+	})
+}
+
+// GetComponents returns the components of a bundle product
+func (r *productRepository) GetComponents(ctx context.Context, bundleID int) ([]models.BundleComponent, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var components []models.BundleComponent
+	for _, item := range r.store.bundleItems[bundleID] {
+		item.Name = r.store.products[item.ProductID].Name
+		components = append(components, item)
+	}
+	return components, nil
+}
+
+// GetComponentsByBundleIDs returns the components of several bundle products
+func (r *productRepository) GetComponentsByBundleIDs(ctx context.Context, bundleIDs []int) (map[int][]models.BundleComponent, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	components := make(map[int][]models.BundleComponent)
+	for _, bundleID := range uniqueIDs(bundleIDs) {
+		for _, item := range r.store.bundleItems[bundleID] {
+			item.Name = r.store.products[item.ProductID].Name
+			components[bundleID] = append(components[bundleID], item)
+		}
+	}
+	return components, nil
+}
+
+// SetComponents replaces the components of a product; a product with
+// components becomes a bundle, one without components a regular product
+func (r *productRepository) SetComponents(ctx context.Context, bundleID int, components []models.BundleComponent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	product, ok := r.store.products[bundleID]
+	if !ok {
+		return fmt.Errorf("Product with ID %d not found", bundleID)
+	}
+
+	seen := make(map[int]bool)
+	items := make([]models.BundleComponent, 0, len(components))
+	for _, c := range components {
+		if _, ok := r.store.products[c.ProductID]; !ok {
+			return fmt.Errorf("product with ID %d does not exist", c.ProductID)
+		}
+		if seen[c.ProductID] {
+			return fmt.Errorf("product with ID %d is listed more than once", c.ProductID)
+		}
+		seen[c.ProductID] = true
+		items = append(items, models.BundleComponent{ProductID: c.ProductID, Quantity: c.Quantity})
+	}
+
+	product.IsBundle = len(components) > 0
+	r.store.products[bundleID] = product
+	if len(items) > 0 {
+		r.store.bundleItems[bundleID] = items
+	} else {
+		delete(r.store.bundleItems, bundleID)
+	}
+	return nil
+}
+
+// row returns a product as it is selected; the stock of a bundle is the
+// number of bundles that can be assembled from the stock of its components
+func (r *productRepository) row(p models.Product) models.Product {
+	if !p.IsBundle {
+		return p
+	}
+
+	items := r.store.bundleItems[p.ID]
+	if len(items) == 0 {
+		p.Stock = 0
+		return p
+	}
+	p.Stock = math.Inf(1)
+	for _, item := range items {
+		available := math.Floor(r.store.products[item.ProductID].Stock / item.Quantity)
+		p.Stock = math.Min(p.Stock, available)
+	}
+	return p
+}
+
+// checkReferences checks the unit and category foreign keys of a product; a
+// product may have no category, but not a deleted one
+func (r *productRepository) checkReferences(product models.Product) error {
+	if _, ok := r.store.units[product.Unit]; !ok {
+		return fmt.Errorf("unit %s does not exist", product.Unit)
+	}
+	if product.CategoryID != nil {
+		if c, ok := r.store.categories[*product.CategoryID]; !ok || c.DeletedAt != nil {
+			return fmt.Errorf("category with ID %d does not exist", *product.CategoryID)
+		}
+	}
+	return nil
+}
+
+// inCategory reports whether a product belongs to a category
+func inCategory(p models.Product, categoryID int) bool {
+	return p.CategoryID != nil && *p.CategoryID == categoryID
+}
+
+// sameCategory reports whether two category IDs are equal; nil is no category
+func sameCategory(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// productVariantRepository is the in-memory implementation of ProductVariantRepository
+type productVariantRepository struct {
+	store *Store
+}
+
+// NewProductVariantRepository creates a new ProductVariantRepository on the store
+func NewProductVariantRepository(store *Store) repositories.ProductVariantRepository {
+	return &productVariantRepository{store: store}
+}
+
+// GetByProductID returns all variants of a product
+func (r *productVariantRepository) GetByProductID(ctx context.Context, productID int) ([]models.ProductVariant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var variants []models.ProductVariant
+	for _, v := range r.store.variants {
+		if v.ProductID == productID {
+			variants = append(variants, v)
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].ID < variants[j].ID })
+	return variants, nil
+}
+
+// GetByID returns a variant by ID
+func (r *productVariantRepository) GetByID(ctx context.Context, id int) (*models.ProductVariant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	v, ok := r.store.variants[id]
+	if !ok {
+		return nil, fmt.Errorf("Variant with ID %d not found", id)
+	}
+	return &v, nil
+}
+
+// GetByIDs returns the variants with the given IDs
+func (r *productVariantRepository) GetByIDs(ctx context.Context, ids []int) ([]models.ProductVariant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var variants []models.ProductVariant
+	for _, id := range uniqueIDs(ids) {
+		if v, ok := r.store.variants[id]; ok {
+			variants = append(variants, v)
+		}
+	}
+	return variants, nil
+}
+
+// Create adds a new variant to a product; its initial stock is held by the
+// main outlet
+func (r *productVariantRepository) Create(ctx context.Context, variant models.ProductVariant) (*models.ProductVariant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.products[variant.ProductID]; !ok {
+		return nil, fmt.Errorf("product with ID %d does not exist", variant.ProductID)
+	}
+	if err := r.checkSKU(0, variant.SKU); err != nil {
+		return nil, err
+	}
+	variant.ID = r.store.nextID("product_variants")
+	variant.Stock = roundStock(variant.Stock)
+	r.store.variants[variant.ID] = variant
+	r.store.addOutletStock(models.DefaultOutletID, variant.ProductID, &variant.ID, variant.Stock)
+	return &variant, nil
+}
+
+// Update updates an existing variant of a product; the main outlet takes the
+// change of stock, since the stock of a variant is the total over all outlets
+func (r *productVariantRepository) Update(ctx context.Context, productID, id int, variant models.ProductVariant) (*models.ProductVariant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.variants[id]
+	if !ok || existing.ProductID != productID {
+		return nil, fmt.Errorf("Variant with ID %d not found", id)
+	}
+	if err := r.checkSKU(id, variant.SKU); err != nil {
+		return nil, err
+	}
+	variant.ID = id
+	variant.ProductID = productID
+	stored := variant
+	stored.Stock = roundStock(variant.Stock)
+	r.store.variants[id] = stored
+	r.store.addOutletStock(models.DefaultOutletID, productID, &id, stored.Stock-existing.Stock)
+	return &variant, nil
+}
+
+// Delete removes a variant of a product
+func (r *productVariantRepository) Delete(ctx context.Context, productID, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.variants[id]
+	if !ok || existing.ProductID != productID {
+		return fmt.Errorf("Variant with ID %d not found", id)
+	}
+	if r.store.transferReferenced(productID, &id) {
+		return fmt.Errorf("variant with ID %d is referenced by transfers", id)
+	}
+	delete(r.store.variants, id)
+	r.store.dropBillItems(productID, &id)
+	r.store.dropPriceRules(func(rule models.PriceRule) bool {
+		return rule.VariantID != nil && *rule.VariantID == id
+	})
+	for key := range r.store.outletStock {
+		if key.variantID == id {
+			delete(r.store.outletStock, key)
+		}
+	}
+
+	// Sold lines keep the product but lose the variant (ON DELETE SET NULL)
+	for tid, t := range r.store.transactions {
+		for i := range t.Details {
+			if t.Details[i].VariantID != nil && *t.Details[i].VariantID == id {
+				t.Details[i].VariantID = nil
+			}
+		}
+		r.store.transactions[tid] = t
+	}
+	return nil
+}
+
+// checkSKU enforces the unique SKU of variants
+func (r *productVariantRepository) checkSKU(id int, sku string) error {
+	for _, v := range r.store.variants {
+		if v.ID != id && v.SKU == sku {
+			return fmt.Errorf("variant with SKU %s already exists", sku)
+		}
+	}
+	return nil
+}
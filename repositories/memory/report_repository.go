@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// reportRepository is the in-memory implementation of ReportRepository
+type reportRepository struct {
+	store *Store
+}
+
+// NewReportRepository creates a new ReportRepository on the store
+func NewReportRepository(store *Store) repositories.ReportRepository {
+	return &reportRepository{store: store}
+}
+
+// GetSalesReport returns sales summary for a date range of outletIDs, or of
+// all outlets when outletIDs is nil, with the sales of each outlet. When
+// attributeToComponents is set, bundle sales are reported on their components.
+func (r *reportRepository) GetSalesReport(ctx context.Context, startDate, endDate time.Time, attributeToComponents bool, outletIDs []int) (*models.SalesReport, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	report := &models.SalesReport{}
+	sales := make(map[int]*models.ProductSales)
+	add := func(productID int, quantity float64, subtotal int) {
+		s, ok := sales[productID]
+		if !ok {
+			p := r.store.products[productID]
+			s = &models.ProductSales{ProductID: productID, Nama: p.Name, Satuan: p.Unit}
+			sales[productID] = s
+		}
+		s.QtyTerjual += quantity
+		s.Pendapatan += subtotal
+	}
+
+	outlets := make(map[int]*models.OutletSales)
+	for _, t := range r.store.transactions {
+		if t.CreatedAt.Before(startDate) || !t.CreatedAt.Before(endDate) {
+			continue
+		}
+		if outletIDs != nil && !slices.Contains(outletIDs, t.OutletID) {
+			continue
+		}
+		report.TotalRevenue += t.TotalAmount
+		report.TotalTransaksi++
+
+		o, ok := outlets[t.OutletID]
+		if !ok {
+			o = &models.OutletSales{OutletID: t.OutletID, Nama: r.store.outlets[t.OutletID].Name}
+			outlets[t.OutletID] = o
+		}
+		o.TotalRevenue += t.TotalAmount
+		o.TotalTransaksi++
+
+		for _, d := range t.Details {
+			if attributeToComponents && len(d.Components) > 0 {
+				for _, c := range d.Components {
+					add(c.ProductID, c.Quantity, c.Subtotal)
+				}
+				continue
+			}
+			add(d.ProductID, d.Quantity, d.Subtotal)
+		}
+	}
+
+	// Best selling first
+	for _, s := range sales {
+		s.QtyTerjual = roundStock(s.QtyTerjual)
+		report.RincianProduk = append(report.RincianProduk, *s)
+	}
+	sort.Slice(report.RincianProduk, func(i, j int) bool {
+		a, b := report.RincianProduk[i], report.RincianProduk[j]
+		if a.QtyTerjual != b.QtyTerjual {
+			return a.QtyTerjual > b.QtyTerjual
+		}
+		return a.ProductID < b.ProductID
+	})
+
+	for _, o := range outlets {
+		report.RincianOutlet = append(report.RincianOutlet, *o)
+	}
+	sort.Slice(report.RincianOutlet, func(i, j int) bool {
+		return report.RincianOutlet[i].OutletID < report.RincianOutlet[j].OutletID
+	})
+
+	if len(report.RincianProduk) > 0 {
+		best := report.RincianProduk[0]
+		report.ProdukTerlaris = &models.BestSellerInfo{
+			Nama:       best.Nama,
+			QtyTerjual: best.QtyTerjual,
+			Satuan:     best.Satuan,
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,172 @@
+// Package memory implements the repositories on in-memory tables so services
+// and handlers can be tested without PostgreSQL. It follows the semantics of
+// the PostgreSQL repositories: the same not-found messages, stock deducted and
+// restored atomically with transactions, and the foreign keys and unique
+// constraints of the migrations.
+package memory
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"kasir-api/models"
+)
+
+// Store holds the tables of the in-memory repositories. Repositories created
+// from the same Store share their data, like repositories sharing a database.
+type Store struct {
+	mu sync.Mutex
+
+	// Now returns the current time; tests may replace it to control the
+	// created_at of transactions and the expiry of idempotency keys and
+	// loyalty points
+	Now func() time.Time
+
+	lastID map[string]int
+
+	categories   map[int]models.Category
+	products     map[int]models.Product
+	history      map[int][]models.ProductVersion
+	bundleItems  map[int][]models.BundleComponent
+	variants     map[int]models.ProductVariant
+	units        map[string]models.Unit
+	conversions  map[int]models.UnitConversion
+	customers    map[int]models.Customer
+	outlets      map[int]models.Outlet
+	outletStock  map[outletStockKey]models.OutletProduct
+	transfers    map[int]models.StockTransfer
+	bills        map[int]models.OpenBill
+	priceLists   map[int]models.PriceList
+	priceRules   map[int]models.PriceRule
+	transactions map[int]models.Transaction
+	conflicts    map[int][]models.StockConflict
+	idempotency  map[idempotencyKey]models.IdempotencyRecord
+	ledger       []models.PointsEntry
+	auditLog     []models.AuditEntry
+
+	pingErr error
+}
+
+// NewStore creates an empty store with the default units of measure and the
+// main outlet
+func NewStore() *Store {
+	s := &Store{
+		Now:          time.Now,
+		lastID:       make(map[string]int),
+		categories:   make(map[int]models.Category),
+		products:     make(map[int]models.Product),
+		history:      make(map[int][]models.ProductVersion),
+		bundleItems:  make(map[int][]models.BundleComponent),
+		variants:     make(map[int]models.ProductVariant),
+		units:        make(map[string]models.Unit),
+		conversions:  make(map[int]models.UnitConversion),
+		customers:    make(map[int]models.Customer),
+		outlets:      make(map[int]models.Outlet),
+		outletStock:  make(map[outletStockKey]models.OutletProduct),
+		transfers:    make(map[int]models.StockTransfer),
+		bills:        make(map[int]models.OpenBill),
+		priceLists:   make(map[int]models.PriceList),
+		priceRules:   make(map[int]models.PriceRule),
+		transactions: make(map[int]models.Transaction),
+		conflicts:    make(map[int][]models.StockConflict),
+		idempotency:  make(map[idempotencyKey]models.IdempotencyRecord),
+	}
+
+	for _, u := range []models.Unit{
+		{Code: "pcs", Name: "Pieces", Precision: 0, Factor: 1},
+		{Code: "kg", Name: "Kilogram", Precision: 3, Factor: 1},
+		{Code: "liter", Name: "Liter", Precision: 3, Factor: 1},
+		{Code: "gram", Name: "Gram", Precision: 0, BaseUnit: "kg", Factor: 0.001},
+	} {
+		s.units[u.Code] = u
+	}
+	s.outlets[models.DefaultOutletID] = models.Outlet{
+		ID:        s.nextID("outlets"),
+		Code:      "PUSAT",
+		Name:      "Outlet Pusat",
+		CreatedAt: s.Now(),
+	}
+	return s
+}
+
+// SetPingError makes health checks fail with err, simulating an unreachable
+// database; nil makes them pass again
+func (s *Store) SetPingError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pingErr = err
+}
+
+// nextID returns the next value of a table's serial ID
+func (s *Store) nextID(table string) int {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// roundStock rounds a quantity to the DECIMAL(12, 3) precision of stock columns
+func roundStock(quantity float64) float64 {
+	return math.Round(quantity*1000) / 1000
+}
+
+// floatPtr returns a pointer to v
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// uniqueIDs returns ids sorted and without duplicates, the order rows
+// selected with id = ANY($1) ORDER BY id come in
+func uniqueIDs(ids []int) []int {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	unique := sorted[:0]
+	for i, id := range sorted {
+		if i == 0 || id != sorted[i-1] {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// productSold reports whether a product is used by a sale or a stock
+// conflict of a synced sale, which the foreign keys of the migrations do not
+// allow to be deleted
+func (s *Store) productSold(id int) bool {
+	for _, t := range s.transactions {
+		for _, d := range t.Details {
+			if d.ProductID == id {
+				return true
+			}
+			for _, c := range d.Components {
+				if c.ProductID == id {
+					return true
+				}
+			}
+		}
+	}
+	for _, conflicts := range s.conflicts {
+		for _, c := range conflicts {
+			if c.ProductID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// productInBundle reports whether a product is a component of a bundle,
+// which the foreign keys of the migrations do not allow to be deleted
+func (s *Store) productInBundle(id int) bool {
+	for bundleID, items := range s.bundleItems {
+		if bundleID == id {
+			continue
+		}
+		for _, item := range items {
+			if item.ProductID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
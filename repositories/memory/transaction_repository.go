@@ -0,0 +1,336 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// transactionRepository is the in-memory implementation of TransactionRepository
+type transactionRepository struct {
+	store *Store
+}
+
+// NewTransactionRepository creates a new TransactionRepository on the store
+func NewTransactionRepository(store *Store) repositories.TransactionRepository {
+	return &transactionRepository{store: store}
+}
+
+// Create creates a new transaction with details, takes the sold quantities
+// off the stock of its outlet and books its loyalty points; nothing is stored
+// when any line is short of stock or the customer is short of points
+func (r *transactionRepository) Create(ctx context.Context, transaction models.Transaction) (*models.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.createTransaction(&transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// createTransaction stores a sale, takes the sold quantities off the stock of
+// its outlet and books its loyalty points, or changes nothing on error
+func (s *Store) createTransaction(transaction *models.Transaction) error {
+	transaction.CreatedAt = s.Now()
+	if _, ok := s.outlets[transaction.OutletID]; !ok {
+		return fmt.Errorf("outlet with ID %d does not exist", transaction.OutletID)
+	}
+	if transaction.CustomerID != nil {
+		if _, ok := s.customers[*transaction.CustomerID]; !ok {
+			return fmt.Errorf("Customer with ID %d not found", *transaction.CustomerID)
+		}
+		// Lots past their expiry cannot be spent, even before ExpirePoints ran
+		s.expireLots(*transaction.CustomerID)
+		customer := s.customers[*transaction.CustomerID]
+		if transaction.PointsRedeemed > 0 && customer.PointsBalance < transaction.PointsRedeemed {
+			return fmt.Errorf("%w for customer with ID %d", repositories.ErrInsufficientPoints, customer.ID)
+		}
+	}
+	if transaction.PriceListID != nil {
+		if _, ok := s.priceLists[*transaction.PriceListID]; !ok {
+			return fmt.Errorf("price list with ID %d does not exist", *transaction.PriceListID)
+		}
+	}
+	if _, err := s.deductStock(transaction.Details, transaction.OutletID, false); err != nil {
+		return err
+	}
+
+	stored := *transaction
+	stored.ClientID = nil
+	s.insertTransaction(transaction, stored)
+	s.bookPoints(*transaction)
+	return nil
+}
+
+// CreateOffline creates a transaction that was made while the POS was offline,
+// keeping its client ID and original timestamp. A transaction whose client ID
+// was already synced is reported as duplicate and not inserted again. Stock is
+// allowed to go negative; every product that did is returned as a conflict.
+func (r *transactionRepository) CreateOffline(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, []models.StockConflict, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if transaction.ClientID != nil {
+		for _, t := range r.store.transactions {
+			if t.ClientID != nil && strings.EqualFold(*t.ClientID, *transaction.ClientID) {
+				transaction.ID = t.ID
+				transaction.OutletID = t.OutletID
+				transaction.TotalAmount = t.TotalAmount
+				transaction.CreatedAt = t.CreatedAt
+				transaction.Details = nil
+				return &transaction, true, nil, nil
+			}
+		}
+	}
+
+	if _, ok := r.store.outlets[transaction.OutletID]; !ok {
+		return nil, false, nil, fmt.Errorf("outlet with ID %d does not exist", transaction.OutletID)
+	}
+	conflicts, err := r.store.deductStock(transaction.Details, transaction.OutletID, true)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	r.store.insertTransaction(&transaction, transaction)
+	if len(conflicts) > 0 {
+		r.store.conflicts[transaction.ID] = conflicts
+	}
+	return &transaction, false, conflicts, nil
+}
+
+// insertTransaction assigns the IDs of a transaction and its details and
+// stores a copy
+func (s *Store) insertTransaction(transaction *models.Transaction, stored models.Transaction) {
+	transaction.ID = s.nextID("transactions")
+	for i := range transaction.Details {
+		transaction.Details[i].ID = s.nextID("transaction_details")
+		transaction.Details[i].TransactionID = transaction.ID
+	}
+
+	stored.ID = transaction.ID
+	stored.CreatedAt = transaction.CreatedAt
+	stored.Details = copyDetails(transaction.Details)
+	if transaction.CustomerID != nil {
+		customerID := *transaction.CustomerID
+		stored.CustomerID = &customerID
+	}
+	if transaction.PriceListID != nil {
+		priceListID := *transaction.PriceListID
+		stored.PriceListID = &priceListID
+	}
+	s.transactions[transaction.ID] = stored
+}
+
+// GetAll returns the transactions matching filter, newest first, without
+// their details
+func (r *transactionRepository) GetAll(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var transactions []models.Transaction
+	for _, t := range r.store.transactions {
+		if filter.CustomerID > 0 && (t.CustomerID == nil || *t.CustomerID != filter.CustomerID) {
+			continue
+		}
+		if filter.OutletIDs != nil && !slices.Contains(filter.OutletIDs, t.OutletID) {
+			continue
+		}
+		t.Details = nil
+		transactions = append(transactions, t)
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].CreatedAt.Equal(transactions[j].CreatedAt) {
+			return transactions[i].ID > transactions[j].ID
+		}
+		return transactions[i].CreatedAt.After(transactions[j].CreatedAt)
+	})
+	return transactions, nil
+}
+
+// GetByID returns a transaction by ID with its details
+func (r *transactionRepository) GetByID(ctx context.Context, id int) (*models.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.transactions[id]
+	if !ok {
+		return nil, fmt.Errorf("Transaction with ID %d not found", id)
+	}
+	t.Details = copyDetails(t.Details)
+	return &t, nil
+}
+
+// Delete deletes a transaction by ID, returns its items to the stock of its
+// outlet and books back its loyalty points
+func (r *transactionRepository) Delete(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.transactions[id]
+	if !ok {
+		return fmt.Errorf("Transaction with ID %d not found", id)
+	}
+
+	for _, d := range t.Details {
+		if len(d.Components) > 0 {
+			for _, c := range d.Components {
+				r.store.addTotalStock(c.ProductID, nil, c.Quantity)
+				r.store.addOutletStock(t.OutletID, c.ProductID, nil, c.Quantity)
+			}
+			continue
+		}
+		r.store.addTotalStock(d.ProductID, d.VariantID, d.Quantity)
+		r.store.addOutletStock(t.OutletID, d.ProductID, d.VariantID, d.Quantity)
+	}
+
+	r.store.voidPoints(t)
+	delete(r.store.transactions, id)
+	// Bills checked out into the sale lose it (ON DELETE SET NULL)
+	for billID, b := range r.store.bills {
+		if b.TransactionID != nil && *b.TransactionID == id {
+			b.TransactionID = nil
+			r.store.bills[billID] = b
+		}
+	}
+	delete(r.store.conflicts, id)
+	return nil
+}
+
+// GetCustomerLifetimeValue sums up the transactions of a customer
+func (r *transactionRepository) GetCustomerLifetimeValue(ctx context.Context, customerID int) (*models.CustomerLifetimeValue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	value := models.CustomerLifetimeValue{CustomerID: customerID}
+	for _, t := range r.store.transactions {
+		if t.CustomerID == nil || *t.CustomerID != customerID {
+			continue
+		}
+		value.TotalTransactions++
+		value.TotalSpent += t.TotalAmount
+		if value.FirstPurchaseAt == nil || t.CreatedAt.Before(*value.FirstPurchaseAt) {
+			first := t.CreatedAt
+			value.FirstPurchaseAt = &first
+		}
+		if value.LastPurchaseAt == nil || t.CreatedAt.After(*value.LastPurchaseAt) {
+			last := t.CreatedAt
+			value.LastPurchaseAt = &last
+		}
+	}
+	return &value, nil
+}
+
+// stockDeduction is the quantity sold from one stock row: a variant, or a
+// product when VariantID is nil
+type stockDeduction struct {
+	productID int
+	variantID *int
+	quantity  float64
+}
+
+// deductStock takes the sold quantities of the details off the variant
+// stock, or the product stock when no variant was selected, and off the
+// stock of the outlet; bundles take their stock from the components. Like
+// the PostgreSQL repository, the quantities taken from the same stock row are
+// added up first. Stock of the outlet that would go negative is an error that
+// leaves all stock untouched, or a conflict per stock row when allowNegative
+// is set.
+func (s *Store) deductStock(details []models.TransactionDetail, outletID int, allowNegative bool) ([]models.StockConflict, error) {
+	var sold []stockDeduction
+	index := make(map[[2]int]int)
+	add := func(productID int, variantID *int, quantity float64) {
+		key := [2]int{productID, 0}
+		if variantID != nil {
+			key = [2]int{0, *variantID}
+		}
+		if i, ok := index[key]; ok {
+			sold[i].quantity += quantity
+			return
+		}
+		index[key] = len(sold)
+		sold = append(sold, stockDeduction{productID: productID, variantID: variantID, quantity: quantity})
+	}
+	for _, d := range details {
+		if len(d.Components) > 0 {
+			for _, c := range d.Components {
+				add(c.ProductID, nil, c.Quantity)
+			}
+			continue
+		}
+		add(d.ProductID, d.VariantID, d.Quantity)
+	}
+
+	products := make(map[int]models.Product)
+	variants := make(map[int]models.ProductVariant)
+	var conflicts []models.StockConflict
+	for _, d := range sold {
+		if d.variantID != nil {
+			v, ok := s.variants[*d.variantID]
+			if !ok {
+				return nil, fmt.Errorf("Product with ID %d not found", d.productID)
+			}
+			v.Stock = roundStock(v.Stock - d.quantity)
+			variants[v.ID] = v
+		} else {
+			p, ok := s.products[d.productID]
+			if !ok {
+				return nil, fmt.Errorf("Product with ID %d not found", d.productID)
+			}
+			p.Stock = roundStock(p.Stock - d.quantity)
+			products[p.ID] = p
+		}
+		stock := roundStock(s.outletStock[stockKey(outletID, d.productID, d.variantID)].Stock - d.quantity)
+
+		if stock >= 0 {
+			continue
+		}
+		if !allowNegative {
+			return nil, fmt.Errorf("%w for product with ID %d", repositories.ErrInsufficientStock, d.productID)
+		}
+		conflicts = append(conflicts, models.StockConflict{
+			ProductID:  d.productID,
+			VariantID:  d.variantID,
+			Quantity:   d.quantity,
+			StockAfter: stock,
+		})
+	}
+
+	// Apply the new stock only once every row succeeded
+	for id, p := range products {
+		s.products[id] = p
+	}
+	for id, v := range variants {
+		s.variants[id] = v
+	}
+	for _, d := range sold {
+		s.addOutletStock(outletID, d.productID, d.variantID, -d.quantity)
+	}
+	return conflicts, nil
+}
+
+// copyDetails copies transaction details so callers cannot change stored rows
+func copyDetails(details []models.TransactionDetail) []models.TransactionDetail {
+	if details == nil {
+		return nil
+	}
+	copied := make([]models.TransactionDetail, len(details))
+	for i, d := range details {
+		if d.VariantID != nil {
+			variantID := *d.VariantID
+			d.VariantID = &variantID
+		}
+		if d.PriceRuleID != nil {
+			priceRuleID := *d.PriceRuleID
+			d.PriceRuleID = &priceRuleID
+		}
+		d.Components = append([]models.TransactionDetailComponent(nil), d.Components...)
+		copied[i] = d
+	}
+	return copied
+}
@@ -0,0 +1,274 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// transferRepository is the in-memory implementation of TransferRepository
+type transferRepository struct {
+	store *Store
+}
+
+// NewTransferRepository creates a new TransferRepository on the store
+func NewTransferRepository(store *Store) repositories.TransferRepository {
+	return &transferRepository{store: store}
+}
+
+// GetAll returns the transfers matching filter, newest first, without their lines
+func (r *transferRepository) GetAll(ctx context.Context, filter models.TransferFilter) ([]models.StockTransfer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var transfers []models.StockTransfer
+	for _, t := range r.store.transfers {
+		if filter.OutletIDs != nil && !slices.Contains(filter.OutletIDs, t.FromOutletID) && !slices.Contains(filter.OutletIDs, t.ToOutletID) {
+			continue
+		}
+		if filter.Status != "" && t.Status != filter.Status {
+			continue
+		}
+		t.Lines = nil
+		transfers = append(transfers, t)
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].CreatedAt.Equal(transfers[j].CreatedAt) {
+			return transfers[i].ID > transfers[j].ID
+		}
+		return transfers[i].CreatedAt.After(transfers[j].CreatedAt)
+	})
+	return transfers, nil
+}
+
+// GetByID returns a transfer by ID with its lines
+func (r *transferRepository) GetByID(ctx context.Context, id int) (*models.StockTransfer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.transfers[id]
+	if !ok {
+		return nil, fmt.Errorf("Transfer with ID %d not found", id)
+	}
+	t.Lines = copyTransferLines(t.Lines)
+	return &t, nil
+}
+
+// Create adds a draft transfer with its lines
+func (r *transferRepository) Create(ctx context.Context, transfer models.StockTransfer) (*models.StockTransfer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkReferences(transfer); err != nil {
+		return nil, err
+	}
+	transfer.ID = r.store.nextID("stock_transfers")
+	transfer.Status = models.TransferDraft
+	transfer.CreatedAt = r.store.Now()
+	transfer.SentAt = nil
+	transfer.ReceivedAt = nil
+	transfer.Lines = r.newLines(transfer.Lines)
+	r.store.transfers[transfer.ID] = transfer
+
+	transfer.Lines = copyTransferLines(transfer.Lines)
+	return &transfer, nil
+}
+
+// Update replaces the outlets, note and lines of a draft transfer
+func (r *transferRepository) Update(ctx context.Context, id int, transfer models.StockTransfer) (*models.StockTransfer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := r.transferIn(id, models.TransferDraft)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkReferences(transfer); err != nil {
+		return nil, err
+	}
+	existing.FromOutletID = transfer.FromOutletID
+	existing.ToOutletID = transfer.ToOutletID
+	existing.Note = transfer.Note
+	existing.Lines = r.newLines(transfer.Lines)
+	r.store.transfers[id] = existing
+
+	existing.Lines = copyTransferLines(existing.Lines)
+	return &existing, nil
+}
+
+// Delete removes a draft transfer
+func (r *transferRepository) Delete(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, err := r.transferIn(id, models.TransferDraft); err != nil {
+		return err
+	}
+	delete(r.store.transfers, id)
+	return nil
+}
+
+// Send takes the lines of a draft transfer off the stock of the source outlet,
+// the way a sale does, and puts the transfer in transit
+func (r *transferRepository) Send(ctx context.Context, id int) (*models.StockTransfer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, err := r.transferIn(id, models.TransferDraft)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]models.TransactionDetail, len(t.Lines))
+	for i, l := range t.Lines {
+		details[i] = models.TransactionDetail{ProductID: l.ProductID, VariantID: l.VariantID, Quantity: l.Quantity}
+	}
+	if _, err := r.store.deductStock(details, t.FromOutletID, false); err != nil {
+		return nil, err
+	}
+
+	now := r.store.Now()
+	t.Status = models.TransferInTransit
+	t.SentAt = &now
+	r.store.transfers[id] = t
+
+	t.Lines = copyTransferLines(t.Lines)
+	return &t, nil
+}
+
+// Receive adds the quantities that arrived to the stock of the destination
+// outlet. The transfer is received when every line arrived in full or the
+// receipt closes it; what did not arrive is then recorded as discrepancy.
+func (r *transferRepository) Receive(ctx context.Context, id int, receipt models.TransferReceipt) (*models.StockTransfer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, err := r.transferIn(id, models.TransferInTransit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check every line before changing any stock
+	lines := copyTransferLines(t.Lines)
+	for _, rl := range receipt.Lines {
+		i := slices.IndexFunc(lines, func(l models.TransferLine) bool { return l.ID == rl.LineID })
+		if i < 0 {
+			return nil, fmt.Errorf("Transfer line with ID %d not found", rl.LineID)
+		}
+		line := &lines[i]
+		line.ReceivedQuantity = roundStock(line.ReceivedQuantity + rl.Quantity)
+		if line.ReceivedQuantity > line.Quantity {
+			return nil, fmt.Errorf("received quantity of transfer line with ID %d exceeds the quantity sent", rl.LineID)
+		}
+		if rl.Note != "" {
+			line.DiscrepancyNote = rl.Note
+		}
+	}
+
+	complete := true
+	for i, l := range lines {
+		if arrived := roundStock(l.ReceivedQuantity - t.Lines[i].ReceivedQuantity); arrived > 0 {
+			r.store.addTotalStock(l.ProductID, l.VariantID, arrived)
+			r.store.addOutletStock(t.ToOutletID, l.ProductID, l.VariantID, arrived)
+		}
+		if l.ReceivedQuantity < l.Quantity {
+			complete = false
+		}
+	}
+
+	if complete || receipt.Close {
+		for i := range lines {
+			lines[i].Discrepancy = roundStock(lines[i].Quantity - lines[i].ReceivedQuantity)
+		}
+		now := r.store.Now()
+		t.Status = models.TransferReceived
+		t.ReceivedAt = &now
+	}
+	t.Lines = lines
+	r.store.transfers[id] = t
+
+	t.Lines = copyTransferLines(t.Lines)
+	return &t, nil
+}
+
+// transferIn returns a transfer for a change that requires status
+func (r *transferRepository) transferIn(id int, status string) (models.StockTransfer, error) {
+	t, ok := r.store.transfers[id]
+	if !ok {
+		return t, fmt.Errorf("Transfer with ID %d not found", id)
+	}
+	if t.Status != status {
+		return t, fmt.Errorf("%w: transfer with ID %d is %s, not %s", repositories.ErrTransferStatus, id, t.Status, status)
+	}
+	return t, nil
+}
+
+// checkReferences enforces the foreign keys of a transfer and its lines
+func (r *transferRepository) checkReferences(transfer models.StockTransfer) error {
+	for _, id := range []int{transfer.FromOutletID, transfer.ToOutletID} {
+		if _, ok := r.store.outlets[id]; !ok {
+			return fmt.Errorf("outlet with ID %d does not exist", id)
+		}
+	}
+	for _, l := range transfer.Lines {
+		if _, ok := r.store.products[l.ProductID]; !ok {
+			return fmt.Errorf("product with ID %d does not exist", l.ProductID)
+		}
+		if l.VariantID != nil {
+			if _, ok := r.store.variants[*l.VariantID]; !ok {
+				return fmt.Errorf("variant with ID %d does not exist", *l.VariantID)
+			}
+		}
+	}
+	return nil
+}
+
+// newLines gives new transfer lines their IDs, with nothing received yet
+func (r *transferRepository) newLines(lines []models.TransferLine) []models.TransferLine {
+	created := make([]models.TransferLine, len(lines))
+	for i, l := range lines {
+		created[i] = models.TransferLine{
+			ID:        r.store.nextID("stock_transfer_lines"),
+			ProductID: l.ProductID,
+			VariantID: l.VariantID,
+			Quantity:  roundStock(l.Quantity),
+		}
+	}
+	return copyTransferLines(created)
+}
+
+// transferReferenced reports whether a product or variant is on a transfer,
+// which the foreign keys of the migrations do not allow to be deleted
+func (s *Store) transferReferenced(productID int, variantID *int) bool {
+	for _, t := range s.transfers {
+		for _, l := range t.Lines {
+			if variantID == nil && l.ProductID == productID {
+				return true
+			}
+			if variantID != nil && l.VariantID != nil && *l.VariantID == *variantID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyTransferLines copies transfer lines so callers cannot change stored rows
+func copyTransferLines(lines []models.TransferLine) []models.TransferLine {
+	if lines == nil {
+		return nil
+	}
+	copied := make([]models.TransferLine, len(lines))
+	for i, l := range lines {
+		if l.VariantID != nil {
+			variantID := *l.VariantID
+			l.VariantID = &variantID
+		}
+		copied[i] = l
+	}
+	return copied
+}
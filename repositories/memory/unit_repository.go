@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// unitRepository is the in-memory implementation of UnitRepository
+type unitRepository struct {
+	store *Store
+}
+
+// NewUnitRepository creates a new UnitRepository on the store
+func NewUnitRepository(store *Store) repositories.UnitRepository {
+	return &unitRepository{store: store}
+}
+
+// GetAll returns all units of measure
+func (r *unitRepository) GetAll(ctx context.Context) ([]models.Unit, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var units []models.Unit
+	for _, u := range r.store.units {
+		units = append(units, u)
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Code < units[j].Code })
+	return units, nil
+}
+
+// GetByCode returns a unit by its code
+func (r *unitRepository) GetByCode(ctx context.Context, code string) (*models.Unit, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.store.units[code]
+	if !ok {
+		return nil, fmt.Errorf("Unit %s not found", code)
+	}
+	return &u, nil
+}
+
+// Create adds a new unit of measure
+func (r *unitRepository) Create(ctx context.Context, unit models.Unit) (*models.Unit, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.units[unit.Code]; ok {
+		return nil, fmt.Errorf("unit %s already exists", unit.Code)
+	}
+	if err := r.checkBaseUnit(unit); err != nil {
+		return nil, err
+	}
+	r.store.units[unit.Code] = unit
+	return &unit, nil
+}
+
+// Update updates an existing unit of measure
+func (r *unitRepository) Update(ctx context.Context, code string, unit models.Unit) (*models.Unit, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.units[code]; !ok {
+		return nil, fmt.Errorf("Unit %s not found", code)
+	}
+	unit.Code = code
+	if err := r.checkBaseUnit(unit); err != nil {
+		return nil, err
+	}
+	r.store.units[code] = unit
+	return &unit, nil
+}
+
+// GetConversions returns the packaging units defined for a product
+func (r *unitRepository) GetConversions(ctx context.Context, productID int) ([]models.UnitConversion, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var conversions []models.UnitConversion
+	for _, c := range r.store.conversions {
+		if c.ProductID == productID {
+			conversions = append(conversions, c)
+		}
+	}
+	sort.Slice(conversions, func(i, j int) bool { return conversions[i].Unit < conversions[j].Unit })
+	return conversions, nil
+}
+
+// GetConversion returns a packaging unit of a product, or nil when none is defined
+func (r *unitRepository) GetConversion(ctx context.Context, productID int, unit string) (*models.UnitConversion, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, c := range r.store.conversions {
+		if c.ProductID == productID && c.Unit == unit {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetConversionsByProductIDs returns the packaging units of several products
+func (r *unitRepository) GetConversionsByProductIDs(ctx context.Context, productIDs []int) ([]models.UnitConversion, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	wanted := make(map[int]bool)
+	for _, id := range productIDs {
+		wanted[id] = true
+	}
+	var conversions []models.UnitConversion
+	for _, c := range r.store.conversions {
+		if wanted[c.ProductID] {
+			conversions = append(conversions, c)
+		}
+	}
+	sort.Slice(conversions, func(i, j int) bool {
+		if conversions[i].ProductID != conversions[j].ProductID {
+			return conversions[i].ProductID < conversions[j].ProductID
+		}
+		return conversions[i].Unit < conversions[j].Unit
+	})
+	return conversions, nil
+}
+
+// SaveConversion creates or updates a packaging unit of a product
+func (r *unitRepository) SaveConversion(ctx context.Context, conversion models.UnitConversion) (*models.UnitConversion, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.products[conversion.ProductID]; !ok {
+		return nil, fmt.Errorf("product with ID %d does not exist", conversion.ProductID)
+	}
+	for id, c := range r.store.conversions {
+		if c.ProductID == conversion.ProductID && c.Unit == conversion.Unit {
+			conversion.ID = id
+			r.store.conversions[id] = conversion
+			return &conversion, nil
+		}
+	}
+	conversion.ID = r.store.nextID("product_unit_conversions")
+	r.store.conversions[conversion.ID] = conversion
+	return &conversion, nil
+}
+
+// DeleteConversion removes a packaging unit of a product
+func (r *unitRepository) DeleteConversion(ctx context.Context, productID int, unit string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, c := range r.store.conversions {
+		if c.ProductID == productID && c.Unit == unit {
+			delete(r.store.conversions, id)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unit conversion %s not found for product with ID %d", unit, productID)
+}
+
+// checkBaseUnit checks the base unit foreign key of a unit
+func (r *unitRepository) checkBaseUnit(unit models.Unit) error {
+	if unit.BaseUnit == "" {
+		return nil
+	}
+	if _, ok := r.store.units[unit.BaseUnit]; !ok {
+		return fmt.Errorf("unit %s does not exist", unit.BaseUnit)
+	}
+	return nil
+}
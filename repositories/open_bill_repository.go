@@ -0,0 +1,482 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// openBillRepository is the PostgreSQL implementation of OpenBillRepository
+type openBillRepository struct {
+	db *sql.DB
+}
+
+// NewOpenBillRepository creates a new OpenBillRepository
+func NewOpenBillRepository(db *sql.DB) OpenBillRepository {
+	return &openBillRepository{db: db}
+}
+
+// openBillColumns selects an open bill row
+const openBillColumns = `SELECT id, outlet_id, label, status, reserve_stock, transaction_id, created_at, closed_at
+	FROM open_bills`
+
+// GetAll returns the bills matching filter, newest first, without their items
+func (r *openBillRepository) GetAll(ctx context.Context, filter models.OpenBillFilter) ([]models.OpenBill, error) {
+	query := openBillColumns + " WHERE 1=1"
+	var args []interface{}
+	argIndex := 1
+
+	if filter.OutletIDs != nil {
+		query += fmt.Sprintf(" AND outlet_id = ANY($%d)", argIndex)
+		args = append(args, pq.Array(filter.OutletIDs))
+		argIndex++
+	}
+
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, filter.Status)
+		argIndex++
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bills []models.OpenBill
+	for rows.Next() {
+		b, err := scanOpenBill(rows)
+		if err != nil {
+			return nil, err
+		}
+		bills = append(bills, *b)
+	}
+	return bills, rows.Err()
+}
+
+// GetByID returns an open bill by ID with its items and their reserved stock
+func (r *openBillRepository) GetByID(ctx context.Context, id int) (*models.OpenBill, error) {
+	b, err := scanOpenBill(r.db.QueryRowContext(ctx, openBillColumns+" WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Open bill with ID %d not found", id)
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, product_id, variant_id, quantity, unit, COALESCE(note, '')
+		FROM open_bill_items WHERE bill_id = $1 ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := make(map[int]int)
+	for rows.Next() {
+		var item models.OpenBillItem
+		var variantID sql.NullInt64
+		if err := rows.Scan(&item.ID, &item.ProductID, &variantID, &item.Quantity, &item.Unit, &item.Note); err != nil {
+			return nil, err
+		}
+		item.VariantID = nullIntPtr(variantID)
+		index[item.ID] = len(b.Items)
+		b.Items = append(b.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(b.Items) == 0 || !b.ReserveStock {
+		return b, nil
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT r.item_id, r.product_id, r.variant_id, r.quantity
+		FROM open_bill_reservations r
+		JOIN open_bill_items i ON i.id = r.item_id
+		WHERE i.bill_id = $1
+		ORDER BY r.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID int
+		var reserved models.ReservedStock
+		var variantID sql.NullInt64
+		if err := rows.Scan(&itemID, &reserved.ProductID, &variantID, &reserved.Quantity); err != nil {
+			return nil, err
+		}
+		reserved.VariantID = nullIntPtr(variantID)
+		item := &b.Items[index[itemID]]
+		item.Reserved = append(item.Reserved, reserved)
+	}
+	return b, rows.Err()
+}
+
+// Create opens a bill with its items, reserving their stock when the bill
+// reserves stock
+func (r *openBillRepository) Create(ctx context.Context, bill models.OpenBill) (*models.OpenBill, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO open_bills (outlet_id, label, reserve_stock)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, bill.OutletID, bill.Label, bill.ReserveStock).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range bill.Items {
+		if _, err := insertBillItem(ctx, tx, id, bill.OutletID, bill.ReserveStock, item); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// AddItem adds an item to an open bill
+func (r *openBillRepository) AddItem(ctx context.Context, billID int, item models.OpenBillItem) (*models.OpenBillItem, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outletID, reserve, err := lockOpenBill(ctx, tx, billID)
+	if err != nil {
+		return nil, err
+	}
+	added, err := insertBillItem(ctx, tx, billID, outletID, reserve, item)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// UpdateItem replaces an item of an open bill; the stock it reserved is given
+// back before the stock of the new item is reserved
+func (r *openBillRepository) UpdateItem(ctx context.Context, billID, itemID int, item models.OpenBillItem) (*models.OpenBillItem, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outletID, reserve, err := lockOpenBill(ctx, tx, billID)
+	if err != nil {
+		return nil, err
+	}
+	if err := releaseReservations(ctx, tx, outletID, "item_id", itemID); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE open_bill_items SET product_id = $1, variant_id = $2, quantity = $3, unit = $4, note = NULLIF($5, '')
+		WHERE id = $6 AND bill_id = $7
+	`, item.ProductID, item.VariantID, item.Quantity, item.Unit, item.Note, itemID, billID)
+	if err != nil {
+		return nil, err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		return nil, fmt.Errorf("Open bill item with ID %d not found", itemID)
+	}
+
+	item.ID = itemID
+	if err := reserveItemStock(ctx, tx, outletID, reserve, &item); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// RemoveItem removes an item from an open bill and gives back the stock it
+// reserved
+func (r *openBillRepository) RemoveItem(ctx context.Context, billID, itemID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	outletID, _, err := lockOpenBill(ctx, tx, billID)
+	if err != nil {
+		return err
+	}
+	if err := releaseReservations(ctx, tx, outletID, "item_id", itemID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM open_bill_items WHERE id = $1 AND bill_id = $2", itemID, billID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return fmt.Errorf("Open bill item with ID %d not found", itemID)
+	}
+	return tx.Commit()
+}
+
+// Cancel closes an open bill without a sale and gives back the stock it
+// reserved
+func (r *openBillRepository) Cancel(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	outletID, _, err := lockOpenBill(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := releaseReservations(ctx, tx, outletID, "bill_id", id); err != nil {
+		return err
+	}
+	if err := closeOpenBill(ctx, tx, id, models.BillCancelled, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Checkout gives back the stock an open bill reserved, creates its
+// transaction, which takes the sold stock again, and closes the bill. The
+// items are checked once the bill is locked, so an item changed after the
+// sale was priced is not left out of it.
+func (r *openBillRepository) Checkout(ctx context.Context, id int, items []models.OpenBillItem, transaction models.Transaction) (*models.Transaction, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outletID, _, err := lockOpenBill(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBillItems(ctx, tx, id, items); err != nil {
+		return nil, err
+	}
+	if err := releaseReservations(ctx, tx, outletID, "bill_id", id); err != nil {
+		return nil, err
+	}
+	if err := createTransaction(ctx, tx, &transaction); err != nil {
+		slog.DebugContext(ctx, "rolling back checkout", "bill_id", id, "error", err)
+		return nil, err
+	}
+	if err := closeOpenBill(ctx, tx, id, models.BillCheckedOut, &transaction.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// lockOpenBill locks a bill that must still be open and returns its outlet
+// and whether it reserves stock
+func lockOpenBill(ctx context.Context, tx *sql.Tx, id int) (int, bool, error) {
+	var outletID int
+	var reserve bool
+	var status string
+	err := tx.QueryRowContext(ctx,
+		"SELECT outlet_id, reserve_stock, status FROM open_bills WHERE id = $1 FOR UPDATE", id,
+	).Scan(&outletID, &reserve, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, fmt.Errorf("Open bill with ID %d not found", id)
+		}
+		return 0, false, err
+	}
+	if status != models.BillOpen {
+		return 0, false, fmt.Errorf("%w: open bill with ID %d is %s", ErrBillClosed, id, status)
+	}
+	return outletID, reserve, nil
+}
+
+// checkBillItems fails with ErrBillChanged unless a locked open bill holds
+// exactly items, in item ID order
+func checkBillItems(ctx context.Context, tx *sql.Tx, id int, items []models.OpenBillItem) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, product_id, variant_id, quantity, unit FROM open_bill_items WHERE bill_id = $1 ORDER BY id", id,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var stored []models.OpenBillItem
+	for rows.Next() {
+		var item models.OpenBillItem
+		var variantID sql.NullInt64
+		if err := rows.Scan(&item.ID, &item.ProductID, &variantID, &item.Quantity, &item.Unit); err != nil {
+			return err
+		}
+		item.VariantID = nullIntPtr(variantID)
+		stored = append(stored, item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !SameBillItems(stored, items) {
+		return fmt.Errorf("%w: the items of open bill with ID %d changed, check it out again", ErrBillChanged, id)
+	}
+	return nil
+}
+
+// SameBillItems reports whether two lists of open bill items hold the same
+// items with the same product, variant, quantity and unit, in the same order
+func SameBillItems(a, b []models.OpenBillItem) bool {
+	return slices.EqualFunc(a, b, func(x, y models.OpenBillItem) bool {
+		return x.ID == y.ID && x.ProductID == y.ProductID && x.Quantity == y.Quantity && x.Unit == y.Unit &&
+			(x.VariantID == nil) == (y.VariantID == nil) && (x.VariantID == nil || *x.VariantID == *y.VariantID)
+	})
+}
+
+// closeOpenBill closes a locked open bill with status
+func closeOpenBill(ctx context.Context, tx *sql.Tx, id int, status string, transactionID *int) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE open_bills SET status = $1, transaction_id = $2, closed_at = NOW() WHERE id = $3",
+		status, transactionID, id,
+	)
+	return err
+}
+
+// insertBillItem inserts an item of an open bill and reserves its stock when
+// the bill reserves stock
+func insertBillItem(ctx context.Context, tx *sql.Tx, billID, outletID int, reserve bool, item models.OpenBillItem) (*models.OpenBillItem, error) {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO open_bill_items (bill_id, product_id, variant_id, quantity, unit, note)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		RETURNING id
+	`, billID, item.ProductID, item.VariantID, item.Quantity, item.Unit, item.Note).Scan(&item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := reserveItemStock(ctx, tx, outletID, reserve, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// reserveItemStock takes the stock of an item off its outlet, the way a sale
+// does, and records it as reserved; items of bills that do not reserve stock
+// reserve nothing
+func reserveItemStock(ctx context.Context, tx *sql.Tx, outletID int, reserve bool, item *models.OpenBillItem) error {
+	if !reserve || len(item.Reserved) == 0 {
+		item.Reserved = nil
+		return nil
+	}
+
+	details := make([]models.TransactionDetail, len(item.Reserved))
+	productIDs := make([]int64, len(item.Reserved))
+	variantIDs := make([]sql.NullInt64, len(item.Reserved))
+	quantities := make([]float64, len(item.Reserved))
+	for i, res := range item.Reserved {
+		details[i] = models.TransactionDetail{ProductID: res.ProductID, VariantID: res.VariantID, Quantity: res.Quantity}
+		productIDs[i] = int64(res.ProductID)
+		if res.VariantID != nil {
+			variantIDs[i] = sql.NullInt64{Int64: int64(*res.VariantID), Valid: true}
+		}
+		quantities[i] = res.Quantity
+	}
+	if _, err := deductStock(ctx, tx, details, outletID, false); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO open_bill_reservations (item_id, product_id, variant_id, quantity)
+		SELECT $1, r.product_id, r.variant_id, r.quantity
+		FROM unnest($2::int[], $3::int[], $4::numeric[]) AS r(product_id, variant_id, quantity)
+	`, item.ID, pq.Array(productIDs), pq.Array(variantIDs), pq.Array(quantities))
+	return err
+}
+
+// releaseReservations gives the stock reserved by the items of a bill, or by
+// one item, back to its outlet and deletes the reservations; column is
+// bill_id or item_id
+func releaseReservations(ctx context.Context, tx *sql.Tx, outletID int, column string, id int) error {
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM open_bill_reservations r
+		USING open_bill_items i
+		WHERE i.id = r.item_id AND i.`+column+` = $1
+		RETURNING r.product_id, r.variant_id, r.quantity
+	`, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var released []stockDeduction
+	for rows.Next() {
+		var s stockDeduction
+		var variantID sql.NullInt64
+		if err := rows.Scan(&s.ProductID, &variantID, &s.Quantity); err != nil {
+			return err
+		}
+		s.VariantID = nullIntPtr(variantID)
+		released = append(released, s)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	return addStock(ctx, tx, outletID, released)
+}
+
+// scanOpenBill scans an open bill row selected with openBillColumns
+func scanOpenBill(row interface{ Scan(...interface{}) error }) (*models.OpenBill, error) {
+	var b models.OpenBill
+	var transactionID sql.NullInt64
+	var closedAt sql.NullTime
+	if err := row.Scan(&b.ID, &b.OutletID, &b.Label, &b.Status, &b.ReserveStock, &transactionID, &b.CreatedAt, &closedAt); err != nil {
+		return nil, err
+	}
+	b.TransactionID = nullIntPtr(transactionID)
+	if closedAt.Valid {
+		b.ClosedAt = &closedAt.Time
+	}
+	return &b, nil
+}
+
+// nullIntPtr returns the value of a nullable integer column as a pointer
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	id := int(n.Int64)
+	return &id
+}
@@ -0,0 +1,149 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestPostgresOpenBillRepositoryReservation(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewOpenBillRepository(db)
+
+	bill, err := repo.Create(ctx, models.OpenBill{
+		OutletID:     models.DefaultOutletID,
+		Label:        "Meja 4",
+		ReserveStock: true,
+		Items: []models.OpenBillItem{
+			{ProductID: kopiID, Quantity: 2, Unit: "pcs", Note: "tanpa gula",
+				Reserved: []models.ReservedStock{{ProductID: kopiID, Quantity: 2}}},
+			{ProductID: paketID, Quantity: 1, Unit: "pcs",
+				Reserved: []models.ReservedStock{{ProductID: kopiID, Quantity: 2}, {ProductID: berasID, Quantity: 0.5}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if bill.Status != models.BillOpen || len(bill.Items) != 2 || len(bill.Items[1].Reserved) != 2 || bill.Items[0].Note != "tanpa gula" {
+		t.Fatalf("Create() = %+v, want an open bill with 2 items and their reservations", bill)
+	}
+	if got := outletStockOf(t, db, models.DefaultOutletID, kopiID, nil); got != 6 {
+		t.Errorf("main outlet Kopi stock = %v, want 6", got)
+	}
+	if got := stockOf(t, db, berasID); got != 4.5 {
+		t.Errorf("total Beras stock = %v, want 4.5", got)
+	}
+
+	// The label is taken while the bill is open
+	if _, err := repo.Create(ctx, models.OpenBill{OutletID: models.DefaultOutletID, Label: "meja 4"}); err == nil {
+		t.Error("Create() with the label of an open bill succeeded")
+	}
+
+	if _, err := repo.UpdateItem(ctx, bill.ID, bill.Items[0].ID, models.OpenBillItem{
+		ProductID: kopiID, Quantity: 3, Unit: "pcs",
+		Reserved: []models.ReservedStock{{ProductID: kopiID, Quantity: 3}},
+	}); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if got := stockOf(t, db, kopiID); got != 5 {
+		t.Errorf("total Kopi stock after update = %v, want 5", got)
+	}
+
+	// Reserving more than the outlet has changes nothing
+	_, err = repo.AddItem(ctx, bill.ID, models.OpenBillItem{
+		ProductID: kopiID, Quantity: 6, Unit: "pcs",
+		Reserved: []models.ReservedStock{{ProductID: kopiID, Quantity: 6}},
+	})
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("AddItem() error = %v, want insufficient stock", err)
+	}
+
+	if err := repo.RemoveItem(ctx, bill.ID, bill.Items[1].ID); err != nil {
+		t.Fatalf("RemoveItem() error = %v", err)
+	}
+	if got := stockOf(t, db, kopiID); got != 7 {
+		t.Errorf("total Kopi stock after removal = %v, want 7", got)
+	}
+	if got := outletStockOf(t, db, models.DefaultOutletID, berasID, nil); got != 5 {
+		t.Errorf("main outlet Beras stock after removal = %v, want 5", got)
+	}
+
+	// A checkout priced from items that changed meanwhile is refused
+	sale := models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		TotalAmount: 15000,
+		Details:     []models.TransactionDetail{{ProductID: kopiID, Quantity: 3, Unit: "pcs", UnitQuantity: 3, Subtotal: 15000}},
+	}
+	if _, err := repo.Checkout(ctx, bill.ID, bill.Items, sale); !errors.Is(err, ErrBillChanged) {
+		t.Errorf("Checkout() of stale items error = %v, want ErrBillChanged", err)
+	}
+	if got := stockOf(t, db, kopiID); got != 7 {
+		t.Errorf("total Kopi stock after refused checkout = %v, want 7", got)
+	}
+	current, err := repo.GetByID(ctx, bill.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+
+	transaction, err := repo.Checkout(ctx, bill.ID, current.Items, sale)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if got := stockOf(t, db, kopiID); got != 7 {
+		t.Errorf("total Kopi stock after checkout = %v, want 7", got)
+	}
+	if got := countRows(t, db, "open_bill_reservations"); got != 0 {
+		t.Errorf("reservations after checkout = %d, want 0", got)
+	}
+
+	closed, err := repo.GetByID(ctx, bill.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if closed.Status != models.BillCheckedOut || closed.TransactionID == nil || *closed.TransactionID != transaction.ID || closed.ClosedAt == nil {
+		t.Errorf("GetByID() = %+v, want checked out into transaction %d", closed, transaction.ID)
+	}
+	if err := repo.Cancel(ctx, bill.ID); !errors.Is(err, ErrBillClosed) {
+		t.Errorf("Cancel() after checkout error = %v, want ErrBillClosed", err)
+	}
+}
+
+func TestPostgresOpenBillRepositoryCancel(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewOpenBillRepository(db)
+
+	bill, err := repo.Create(ctx, models.OpenBill{
+		OutletID:     models.DefaultOutletID,
+		Label:        "Budi",
+		ReserveStock: true,
+		Items: []models.OpenBillItem{{ProductID: esTehID, VariantID: intPtr(2), Quantity: 2, Unit: "pcs",
+			Reserved: []models.ReservedStock{{ProductID: esTehID, VariantID: intPtr(2), Quantity: 2}}}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := outletStockOf(t, db, models.DefaultOutletID, esTehID, intPtr(2)); got != 0 {
+		t.Errorf("Es Teh L stock = %v, want 0", got)
+	}
+
+	if err := repo.Cancel(ctx, bill.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if got := outletStockOf(t, db, models.DefaultOutletID, esTehID, intPtr(2)); got != 2 {
+		t.Errorf("Es Teh L stock after cancel = %v, want 2", got)
+	}
+
+	bills, err := repo.GetAll(ctx, models.OpenBillFilter{OutletIDs: []int{models.DefaultOutletID}, Status: models.BillCancelled})
+	if err != nil || len(bills) != 1 || bills[0].Items != nil {
+		t.Errorf("GetAll() = %+v, %v, want the cancelled bill without items", bills, err)
+	}
+	if _, err := repo.Create(ctx, models.OpenBill{OutletID: models.DefaultOutletID, Label: "Budi"}); err != nil {
+		t.Errorf("Create() after cancel error = %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// outletStockConflict is the ON CONFLICT target of the stock rows of outlets,
+// one per outlet and product or variant
+const outletStockConflict = "ON CONFLICT (outlet_id, product_id, (COALESCE(variant_id, 0)))"
+
+// outletRepository is the PostgreSQL implementation of OutletRepository
+type outletRepository struct {
+	db *sql.DB
+}
+
+// NewOutletRepository creates a new OutletRepository
+func NewOutletRepository(db *sql.DB) OutletRepository {
+	return &outletRepository{db: db}
+}
+
+// outletColumns selects an outlet row
+const outletColumns = "SELECT id, code, name, COALESCE(address, ''), created_at FROM outlets"
+
+// GetAll returns all outlets
+func (r *outletRepository) GetAll(ctx context.Context) ([]models.Outlet, error) {
+	rows, err := r.db.QueryContext(ctx, outletColumns+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outlets []models.Outlet
+	for rows.Next() {
+		var o models.Outlet
+		if err := rows.Scan(&o.ID, &o.Code, &o.Name, &o.Address, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		outlets = append(outlets, o)
+	}
+	return outlets, rows.Err()
+}
+
+// GetByID returns an outlet by ID
+func (r *outletRepository) GetByID(ctx context.Context, id int) (*models.Outlet, error) {
+	var o models.Outlet
+	err := r.db.QueryRowContext(ctx, outletColumns+" WHERE id = $1", id).
+		Scan(&o.ID, &o.Code, &o.Name, &o.Address, &o.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Outlet with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &o, nil
+}
+
+// Create adds a new outlet
+func (r *outletRepository) Create(ctx context.Context, outlet models.Outlet) (*models.Outlet, error) {
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO outlets (code, name, address) VALUES ($1, $2, NULLIF($3, '')) RETURNING id, created_at",
+		outlet.Code, outlet.Name, outlet.Address,
+	).Scan(&outlet.ID, &outlet.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &outlet, nil
+}
+
+// Update updates an existing outlet
+func (r *outletRepository) Update(ctx context.Context, id int, outlet models.Outlet) (*models.Outlet, error) {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE outlets SET code = $1, name = $2, address = NULLIF($3, ''), updated_at = NOW()
+		WHERE id = $4
+		RETURNING created_at
+	`, outlet.Code, outlet.Name, outlet.Address, id).Scan(&outlet.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Outlet with ID %d not found", id)
+		}
+		return nil, err
+	}
+	outlet.ID = id
+	return &outlet, nil
+}
+
+// Delete removes an outlet by ID with its stock rows, taking their stock off
+// the totals of the products and variants
+func (r *outletRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE products p SET stock = p.stock - op.stock
+		FROM outlet_products op
+		WHERE op.outlet_id = $1 AND op.variant_id IS NULL AND p.id = op.product_id
+	`, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE product_variants v SET stock = v.stock - op.stock
+		FROM outlet_products op
+		WHERE op.outlet_id = $1 AND v.id = op.variant_id
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM outlets WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("Outlet with ID %d not found", id)
+	}
+	return tx.Commit()
+}
+
+// GetProducts returns the stock rows of an outlet
+func (r *outletRepository) GetProducts(ctx context.Context, outletID int, productIDs []int) ([]models.OutletProduct, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT outlet_id, product_id, variant_id, stock, price
+		FROM outlet_products
+		WHERE outlet_id = $1 AND ($2::int[] IS NULL OR product_id = ANY($2))
+		ORDER BY product_id, COALESCE(variant_id, 0)
+	`, outletID, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.OutletProduct
+	for rows.Next() {
+		var p models.OutletProduct
+		var variantID sql.NullInt64
+		var price sql.NullFloat64
+		if err := rows.Scan(&p.OutletID, &p.ProductID, &variantID, &p.Stock, &price); err != nil {
+			return nil, err
+		}
+		if variantID.Valid {
+			id := int(variantID.Int64)
+			p.VariantID = &id
+		}
+		if price.Valid {
+			p.Price = &price.Float64
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// SetProduct sets the stock and price override of a product or variant at an
+// outlet. The product or variant row is locked first, in the same order as
+// sales lock them, and its total stock changes by the difference.
+func (r *outletRepository) SetProduct(ctx context.Context, product models.OutletProduct) (*models.OutletProduct, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if product.VariantID != nil {
+		err = tx.QueryRowContext(ctx,
+			"SELECT id FROM product_variants WHERE id = $1 AND product_id = $2 FOR UPDATE",
+			*product.VariantID, product.ProductID,
+		).Scan(new(int))
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Variant with ID %d not found", *product.VariantID)
+		}
+	} else {
+		err = tx.QueryRowContext(ctx, "SELECT id FROM products WHERE id = $1 FOR UPDATE", product.ProductID).Scan(new(int))
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Product with ID %d not found", product.ProductID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var old float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT stock FROM outlet_products
+		WHERE outlet_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = COALESCE($3, 0)
+		FOR UPDATE
+	`, product.OutletID, product.ProductID, product.VariantID).Scan(&old)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO outlet_products (outlet_id, product_id, variant_id, stock, price)
+		VALUES ($1, $2, $3, $4, $5)
+		`+outletStockConflict+` DO UPDATE SET stock = EXCLUDED.stock, price = EXCLUDED.price
+		RETURNING stock
+	`, product.OutletID, product.ProductID, product.VariantID, product.Stock, product.Price).Scan(&product.Stock)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.VariantID != nil {
+		_, err = tx.ExecContext(ctx, "UPDATE product_variants SET stock = stock + $1 WHERE id = $2", product.Stock-old, *product.VariantID)
+	} else {
+		_, err = tx.ExecContext(ctx, "UPDATE products SET stock = stock + $1 WHERE id = $2", product.Stock-old, product.ProductID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
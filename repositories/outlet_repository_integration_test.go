@@ -0,0 +1,101 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestPostgresOutletRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewOutletRepository(db)
+
+	cabang, err := repo.Create(ctx, models.Outlet{Code: "CBG", Name: "Cabang"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := repo.Create(ctx, models.Outlet{Code: "CBG", Name: "Cabang Lain"}); err == nil {
+		t.Error("Create() with taken code succeeded, want unique violation")
+	}
+
+	updated, err := repo.Update(ctx, cabang.ID, models.Outlet{Code: "CBG-1", Name: "Cabang Satu", Address: "Jl. Merdeka 1"})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.ID != cabang.ID || !updated.CreatedAt.Equal(cabang.CreatedAt) {
+		t.Errorf("Update() = %+v, want ID %d created at %v", updated, cabang.ID, cabang.CreatedAt)
+	}
+	if _, err := repo.Update(ctx, 99, models.Outlet{Code: "X", Name: "X"}); err == nil || err.Error() != "Outlet with ID 99 not found" {
+		t.Errorf("Update() of missing outlet error = %v, want not found", err)
+	}
+
+	outlets, err := repo.GetAll(ctx)
+	if err != nil || len(outlets) != 2 || outlets[0].ID != models.DefaultOutletID ||
+		outlets[1].Code != "CBG-1" || outlets[1].Address != "Jl. Merdeka 1" {
+		t.Fatalf("GetAll() = %+v, %v; want the main outlet and CBG-1", outlets, err)
+	}
+
+	// The main outlet holds the stock of the seeded catalog, a row per
+	// product and per variant
+	pusat, err := repo.GetProducts(ctx, models.DefaultOutletID, nil)
+	if err != nil || len(pusat) != 6 {
+		t.Fatalf("GetProducts(main) = %+v, %v; want 6 stock rows", pusat, err)
+	}
+	if pusat[0].ProductID != kopiID || pusat[0].Stock != 10 || pusat[2].ProductID != esTehID || pusat[2].VariantID != nil ||
+		pusat[3].VariantID == nil || *pusat[3].VariantID != 1 || pusat[3].Stock != 5 {
+		t.Errorf("GetProducts(main) = %+v, want ordered by product and variant", pusat)
+	}
+	filtered, err := repo.GetProducts(ctx, models.DefaultOutletID, []int{berasID})
+	if err != nil || len(filtered) != 1 || filtered[0].Stock != 5 {
+		t.Errorf("GetProducts(main, Beras) = %+v, %v; want 5 kg of Beras", filtered, err)
+	}
+
+	// Setting the same product or variant twice updates its row through the
+	// expression index and changes the total stock by the difference
+	price := 5500.0
+	steps := []struct {
+		product   models.OutletProduct
+		wantTotal float64
+	}{
+		{models.OutletProduct{OutletID: cabang.ID, ProductID: kopiID, Stock: 4, Price: &price}, 14},
+		{models.OutletProduct{OutletID: cabang.ID, ProductID: kopiID, Stock: 3}, 13},
+		{models.OutletProduct{OutletID: cabang.ID, ProductID: esTehID, VariantID: intPtr(2), Stock: 1}, 0},
+		{models.OutletProduct{OutletID: cabang.ID, ProductID: esTehID, VariantID: intPtr(2), Stock: 6}, 0},
+	}
+	for _, step := range steps {
+		if _, err := repo.SetProduct(ctx, step.product); err != nil {
+			t.Fatalf("SetProduct(%+v) error = %v", step.product, err)
+		}
+		if got := stockOf(t, db, step.product.ProductID); step.product.VariantID == nil && got != step.wantTotal {
+			t.Errorf("stock of product %d = %v, want %v", step.product.ProductID, got, step.wantTotal)
+		}
+	}
+	var variantStock float64
+	if err := db.QueryRow("SELECT stock FROM product_variants WHERE id = 2").Scan(&variantStock); err != nil || variantStock != 8 {
+		t.Errorf("stock of variant 2 = %v, %v; want 8", variantStock, err)
+	}
+
+	rows, err := repo.GetProducts(ctx, cabang.ID, nil)
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("GetProducts(cabang) = %+v, %v; want a row for Kopi and one for the variant", rows, err)
+	}
+	if rows[0].ProductID != kopiID || rows[0].Stock != 3 || rows[0].Price != nil {
+		t.Errorf("Kopi at cabang = %+v, want 3 without a price override", rows[0])
+	}
+	if rows[1].VariantID == nil || *rows[1].VariantID != 2 || rows[1].Stock != 6 {
+		t.Errorf("variant at cabang = %+v, want 6 of variant 2", rows[1])
+	}
+
+	if _, err := repo.SetProduct(ctx, models.OutletProduct{OutletID: cabang.ID, ProductID: kopiID, VariantID: intPtr(1)}); err == nil ||
+		err.Error() != "Variant with ID 1 not found" {
+		t.Errorf("SetProduct() with a variant of another product error = %v, want not found", err)
+	}
+	if _, err := repo.SetProduct(ctx, models.OutletProduct{OutletID: cabang.ID, ProductID: 99}); err == nil ||
+		err.Error() != "Product with ID 99 not found" {
+		t.Errorf("SetProduct() of missing product error = %v, want not found", err)
+	}
+}
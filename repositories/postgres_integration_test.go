@@ -0,0 +1,257 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	_ "github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// The integration tests run the PostgreSQL repositories against a throwaway
+// server started with initdb and pg_ctl from the local PostgreSQL
+// installation; no Docker or network access is needed:
+//
+//	go test -tags=integration ./repositories/
+//
+// Set PG_BIN to the directory holding initdb and pg_ctl when they are not on
+// PATH. The tests are skipped when no PostgreSQL installation is found.
+
+// testDB is the connection to the throwaway server, nil when it could not be started
+var testDB *sql.DB
+
+// skipReason explains why testDB is nil
+var skipReason string
+
+func TestMain(m *testing.M) {
+	os.Exit(runWithPostgres(m))
+}
+
+// runWithPostgres starts a PostgreSQL server in a temporary directory, applies
+// the migrations, runs the tests and removes the server again
+func runWithPostgres(m *testing.M) int {
+	initdb, pgCtl, err := postgresBinaries()
+	if err != nil {
+		skipReason = err.Error()
+		return m.Run()
+	}
+	if os.Geteuid() == 0 {
+		skipReason = "initdb cannot be run as root"
+		return m.Run()
+	}
+
+	dir, err := os.MkdirTemp("", "kasir-pg-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "creating data directory:", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	dataDir := filepath.Join(dir, "data")
+	if err := run(initdb, "-D", dataDir, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-locale"); err != nil {
+		fmt.Fprintln(os.Stderr, "initdb:", err)
+		return 1
+	}
+
+	port, err := freePort()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "finding a free port:", err)
+		return 1
+	}
+
+	// Listen on a Unix socket in the temporary directory only
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses='' -c timezone=UTC -c fsync=off", port, dir)
+	if err := run(pgCtl, "-D", dataDir, "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start"); err != nil {
+		fmt.Fprintln(os.Stderr, "pg_ctl start:", err)
+		return 1
+	}
+	defer run(pgCtl, "-D", dataDir, "-m", "immediate", "-w", "stop")
+
+	db, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%d user=postgres dbname=postgres sslmode=disable", dir, port))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connecting:", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := applyMigrations(db, filepath.Join("..", "database", "migrations")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	testDB = db
+	return m.Run()
+}
+
+// postgresBinaries locates initdb and pg_ctl in PG_BIN, on PATH or in the
+// usual installation directories
+func postgresBinaries() (string, string, error) {
+	var dirs []string
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if path, err := exec.LookPath("initdb"); err == nil {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+	for _, pattern := range []string{"/usr/lib/postgresql/*/bin", "/usr/pgsql-*/bin", "/opt/homebrew/opt/postgresql*/bin", "/usr/local/opt/postgresql*/bin"} {
+		matches, _ := filepath.Glob(pattern)
+		sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+		dirs = append(dirs, matches...)
+	}
+
+	for _, dir := range dirs {
+		initdb := filepath.Join(dir, "initdb")
+		pgCtl := filepath.Join(dir, "pg_ctl")
+		if isExecutable(initdb) && isExecutable(pgCtl) {
+			return initdb, pgCtl, nil
+		}
+	}
+	return "", "", fmt.Errorf("initdb and pg_ctl not found; install PostgreSQL or set PG_BIN")
+}
+
+// isExecutable reports whether path is an executable file
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// run runs a command and includes its output in the error
+func run(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, output)
+	}
+	return nil
+}
+
+// freePort returns a TCP port that is not in use
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// applyMigrations runs the migration files of dir in name order
+func applyMigrations(db *sql.DB, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			return fmt.Errorf("applying %s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+// Products created by newTestDB, in creation order
+const (
+	kopiID  = 1 // pcs, Rp 5.000, 10 in stock
+	berasID = 2 // kg, Rp 12.000, 5 kg in stock
+	esTehID = 3 // pcs, Rp 3.000, variants S (id 1, 5 in stock) and L (id 2, 2 in stock)
+	paketID = 4 // bundle of 2 Kopi and 0.5 kg Beras, Rp 15.000
+)
+
+// newTestDB empties every table except the default units and the main outlet
+// and creates a small catalog stocked at the main outlet: category 1, a piece
+// product, a weighed product, a product with variants and a bundle
+func newTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	if testDB == nil {
+		t.Skip(skipReason)
+	}
+
+	ctx := context.Background()
+	_, err := testDB.ExecContext(ctx, `
+		TRUNCATE categories, products, product_variants, product_unit_conversions,
+			product_bundle_items, transactions, transaction_details,
+			transaction_detail_components, stock_conflicts, idempotency_keys, customers,
+			loyalty_points_ledger, outlet_products, stock_transfers, stock_transfer_lines,
+			open_bills, open_bill_items, open_bill_reservations, price_lists, price_rules,
+			product_versions, audit_log
+		RESTART IDENTITY CASCADE;
+		DELETE FROM units WHERE code NOT IN ('pcs', 'kg', 'liter', 'gram');
+		DELETE FROM outlets WHERE id <> 1;
+		SELECT setval(pg_get_serial_sequence('outlets', 'id'), 1);
+	`)
+	if err != nil {
+		t.Fatalf("resetting database: %v", err)
+	}
+
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("seeding test data: %v", err)
+		}
+	}
+	category, err := NewCategoryRepository(testDB).Create(ctx, models.Category{Name: "Sembako", Description: "Bahan pokok"})
+	must(err)
+
+	products := NewProductRepository(testDB)
+	for _, p := range []models.Product{
+		{Name: "Kopi", Price: 5000, Stock: 10, Unit: "pcs", CategoryID: &category.ID},
+		{Name: "Beras", Price: 12000, Stock: 5, Unit: "kg", CategoryID: &category.ID},
+		{Name: "Es Teh", Price: 3000, Unit: "pcs", CategoryID: &category.ID},
+		{Name: "Paket Sarapan", Price: 15000, Unit: "pcs", CategoryID: &category.ID},
+	} {
+		_, err := products.Create(ctx, p, "")
+		must(err)
+	}
+
+	variants := NewProductVariantRepository(testDB)
+	_, err = variants.Create(ctx, models.ProductVariant{ProductID: esTehID, Name: "S", SKU: "TEH-S", Price: 3000, Stock: 5})
+	must(err)
+	_, err = variants.Create(ctx, models.ProductVariant{ProductID: esTehID, Name: "L", SKU: "TEH-L", Price: 5000, Stock: 2})
+	must(err)
+
+	must(products.SetComponents(ctx, paketID, []models.BundleComponent{
+		{ProductID: kopiID, Quantity: 2},
+		{ProductID: berasID, Quantity: 0.5},
+	}))
+
+	return testDB
+}
+
+// stockOf returns the stock of a product as selected by ProductRepository
+func stockOf(t *testing.T, db *sql.DB, productID int) float64 {
+	t.Helper()
+	p, err := NewProductRepository(db).GetByID(context.Background(), productID)
+	if err != nil {
+		t.Fatalf("GetByID(%d) error = %v", productID, err)
+	}
+	return p.Stock
+}
+
+// countRows returns the number of rows in a table
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatalf("counting %s: %v", table, err)
+	}
+	return n
+}
+
+// intPtr returns a pointer to v
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// priceListRepository is the PostgreSQL implementation of PriceListRepository
+type priceListRepository struct {
+	db *sql.DB
+}
+
+// NewPriceListRepository creates a new PriceListRepository
+func NewPriceListRepository(db *sql.DB) PriceListRepository {
+	return &priceListRepository{db: db}
+}
+
+// priceRuleColumns selects a price rule row; the daily window is selected as
+// "15:00"
+const priceRuleColumns = `SELECT id, price_list_id, product_id, variant_id, min_quantity, price, starts_at, ends_at,
+		COALESCE(TO_CHAR(daily_from, 'HH24:MI'), ''), COALESCE(TO_CHAR(daily_to, 'HH24:MI'), ''), created_at
+	FROM price_rules`
+
+// priceRuleOrder orders price rules by product, variant, price list and
+// minimum quantity
+const priceRuleOrder = " ORDER BY product_id, variant_id NULLS FIRST, price_list_id NULLS FIRST, min_quantity, id"
+
+// GetAll returns all price lists
+func (r *priceListRepository) GetAll(ctx context.Context) ([]models.PriceList, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, code, name, created_at FROM price_lists ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []models.PriceList
+	for rows.Next() {
+		var l models.PriceList
+		if err := rows.Scan(&l.ID, &l.Code, &l.Name, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	return lists, rows.Err()
+}
+
+// GetByID returns a price list by ID
+func (r *priceListRepository) GetByID(ctx context.Context, id int) (*models.PriceList, error) {
+	var l models.PriceList
+	err := r.db.QueryRowContext(ctx, "SELECT id, code, name, created_at FROM price_lists WHERE id = $1", id).
+		Scan(&l.ID, &l.Code, &l.Name, &l.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Price list with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Create adds a new price list
+func (r *priceListRepository) Create(ctx context.Context, list models.PriceList) (*models.PriceList, error) {
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO price_lists (code, name) VALUES ($1, $2) RETURNING id, created_at",
+		list.Code, list.Name,
+	).Scan(&list.ID, &list.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Update updates an existing price list
+func (r *priceListRepository) Update(ctx context.Context, id int, list models.PriceList) (*models.PriceList, error) {
+	err := r.db.QueryRowContext(ctx,
+		"UPDATE price_lists SET code = $1, name = $2 WHERE id = $3 RETURNING created_at",
+		list.Code, list.Name, id,
+	).Scan(&list.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Price list with ID %d not found", id)
+		}
+		return nil, err
+	}
+	list.ID = id
+	return &list, nil
+}
+
+// Delete removes a price list by ID; its rules go with it and its customers
+// lose it
+func (r *priceListRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM price_lists WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("Price list with ID %d not found", id)
+	}
+	return nil
+}
+
+// GetRules returns the rules matching filter
+func (r *priceListRepository) GetRules(ctx context.Context, filter models.PriceRuleFilter) ([]models.PriceRule, error) {
+	query := priceRuleColumns + " WHERE 1=1"
+	var args []interface{}
+	argIndex := 1
+
+	if filter.ProductID > 0 {
+		query += fmt.Sprintf(" AND product_id = $%d", argIndex)
+		args = append(args, filter.ProductID)
+		argIndex++
+	}
+
+	if filter.PriceListID > 0 {
+		query += fmt.Sprintf(" AND price_list_id = $%d", argIndex)
+		args = append(args, filter.PriceListID)
+		argIndex++
+	}
+
+	return r.queryRules(ctx, query+priceRuleOrder, args...)
+}
+
+// GetRuleByID returns a price rule by ID
+func (r *priceListRepository) GetRuleByID(ctx context.Context, id int) (*models.PriceRule, error) {
+	rule, err := scanPriceRule(r.db.QueryRowContext(ctx, priceRuleColumns+" WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Price rule with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return rule, nil
+}
+
+// CreateRule adds a new price rule
+func (r *priceListRepository) CreateRule(ctx context.Context, rule models.PriceRule) (*models.PriceRule, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO price_rules (price_list_id, product_id, variant_id, min_quantity, price, starts_at, ends_at, daily_from, daily_to)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::time, NULLIF($9, '')::time)
+		RETURNING id
+	`, rule.PriceListID, rule.ProductID, rule.VariantID, rule.MinQuantity, rule.Price,
+		rule.StartsAt, rule.EndsAt, rule.DailyFrom, rule.DailyTo,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetRuleByID(ctx, id)
+}
+
+// UpdateRule updates an existing price rule
+func (r *priceListRepository) UpdateRule(ctx context.Context, id int, rule models.PriceRule) (*models.PriceRule, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE price_rules
+		SET price_list_id = $1, product_id = $2, variant_id = $3, min_quantity = $4, price = $5,
+			starts_at = $6, ends_at = $7, daily_from = NULLIF($8, '')::time, daily_to = NULLIF($9, '')::time
+		WHERE id = $10
+	`, rule.PriceListID, rule.ProductID, rule.VariantID, rule.MinQuantity, rule.Price,
+		rule.StartsAt, rule.EndsAt, rule.DailyFrom, rule.DailyTo, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("Price rule with ID %d not found", id)
+	}
+	return r.GetRuleByID(ctx, id)
+}
+
+// DeleteRule removes a price rule by ID
+func (r *priceListRepository) DeleteRule(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM price_rules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("Price rule with ID %d not found", id)
+	}
+	return nil
+}
+
+// GetRulesInEffect returns the rules of the products that have started and
+// not ended at the given time
+func (r *priceListRepository) GetRulesInEffect(ctx context.Context, productIDs []int, at time.Time) ([]models.PriceRule, error) {
+	return r.queryRules(ctx, priceRuleColumns+`
+		WHERE product_id = ANY($1)
+			AND (starts_at IS NULL OR starts_at <= $2)
+			AND (ends_at IS NULL OR ends_at > $2)
+	`+priceRuleOrder, pq.Array(productIDs), at)
+}
+
+// queryRules selects price rules with a query built on priceRuleColumns
+func (r *priceListRepository) queryRules(ctx context.Context, query string, args ...interface{}) ([]models.PriceRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.PriceRule
+	for rows.Next() {
+		rule, err := scanPriceRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, rows.Err()
+}
+
+// scanPriceRule scans a price rule row selected with priceRuleColumns
+func scanPriceRule(row interface{ Scan(...interface{}) error }) (*models.PriceRule, error) {
+	var rule models.PriceRule
+	var priceListID, variantID sql.NullInt64
+	var startsAt, endsAt sql.NullTime
+	err := row.Scan(&rule.ID, &priceListID, &rule.ProductID, &variantID, &rule.MinQuantity, &rule.Price,
+		&startsAt, &endsAt, &rule.DailyFrom, &rule.DailyTo, &rule.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rule.PriceListID = nullIntPtr(priceListID)
+	rule.VariantID = nullIntPtr(variantID)
+	if startsAt.Valid {
+		rule.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		rule.EndsAt = &endsAt.Time
+	}
+	return &rule, nil
+}
@@ -0,0 +1,110 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+func TestPostgresPriceListRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewPriceListRepository(db)
+
+	wholesale, err := repo.Create(ctx, models.PriceList{Code: "GROSIR", Name: "Grosir"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := repo.Create(ctx, models.PriceList{Code: "GROSIR", Name: "Grosir 2"}); err == nil {
+		t.Error("Create() with a taken code succeeded")
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	later := now.Add(time.Hour)
+	rule, err := repo.CreateRule(ctx, models.PriceRule{
+		PriceListID: &wholesale.ID, ProductID: kopiID, MinQuantity: 10, Price: 4000,
+	})
+	if err != nil {
+		t.Fatalf("CreateRule() error = %v", err)
+	}
+	happyHour, err := repo.CreateRule(ctx, models.PriceRule{
+		ProductID: esTehID, VariantID: intPtr(2), Price: 4000, DailyFrom: "15:00", DailyTo: "17:30",
+	})
+	if err != nil {
+		t.Fatalf("CreateRule() happy hour error = %v", err)
+	}
+	if happyHour.DailyFrom != "15:00" || happyHour.DailyTo != "17:30" || *happyHour.VariantID != 2 || happyHour.PriceListID != nil {
+		t.Errorf("happy hour = %+v, want the daily window of variant 2", happyHour)
+	}
+	scheduled, err := repo.CreateRule(ctx, models.PriceRule{ProductID: kopiID, Price: 6000, StartsAt: &later})
+	if err != nil {
+		t.Fatalf("CreateRule() scheduled error = %v", err)
+	}
+	if _, err := repo.CreateRule(ctx, models.PriceRule{ProductID: kopiID, Price: 1, DailyFrom: "15:00"}); err == nil {
+		t.Error("CreateRule() with half a daily window succeeded")
+	}
+
+	rules, err := repo.GetRulesInEffect(ctx, []int{kopiID, esTehID}, now)
+	if err != nil {
+		t.Fatalf("GetRulesInEffect() error = %v", err)
+	}
+	if len(rules) != 2 || rules[0].ID != rule.ID || rules[1].ID != happyHour.ID {
+		t.Errorf("GetRulesInEffect() = %+v, want the wholesale and happy hour rules", rules)
+	}
+	rules, err = repo.GetRulesInEffect(ctx, []int{kopiID}, later)
+	if err != nil || len(rules) != 2 || rules[0].ID != scheduled.ID {
+		t.Errorf("GetRulesInEffect() after the change = %+v, %v, want the scheduled rule first", rules, err)
+	}
+	rules, err = repo.GetRules(ctx, models.PriceRuleFilter{PriceListID: wholesale.ID})
+	if err != nil || len(rules) != 1 || rules[0].ID != rule.ID {
+		t.Errorf("GetRules() of the price list = %+v, %v, want the wholesale rule", rules, err)
+	}
+
+	// Sales record the price list and rule and keep them until deleted
+	transactionRepo := NewTransactionRepository(db)
+	transaction, err := transactionRepo.Create(ctx, models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		PriceListID: &wholesale.ID,
+		TotalAmount: 40000,
+		Details: []models.TransactionDetail{
+			{ProductID: kopiID, Quantity: 10, Unit: "pcs", UnitQuantity: 10, Subtotal: 40000, PriceRuleID: &rule.ID},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() transaction error = %v", err)
+	}
+	stored, err := transactionRepo.GetByID(ctx, transaction.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if stored.PriceListID == nil || *stored.PriceListID != wholesale.ID || stored.Details[0].PriceRuleID == nil || *stored.Details[0].PriceRuleID != rule.ID {
+		t.Errorf("transaction = %+v, want the price list and rule", stored)
+	}
+
+	customer, err := NewCustomerRepository(db).Create(ctx, models.Customer{Name: "Toko Makmur", PriceListID: &wholesale.ID})
+	if err != nil {
+		t.Fatalf("Create() customer error = %v", err)
+	}
+
+	if err := repo.Delete(ctx, wholesale.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := countRows(t, db, "price_rules"); got != 2 {
+		t.Errorf("price rules after delete = %d, want 2", got)
+	}
+	stored, err = transactionRepo.GetByID(ctx, transaction.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if stored.PriceListID != nil || stored.Details[0].PriceRuleID != nil || stored.TotalAmount != 40000 {
+		t.Errorf("transaction after delete = %+v, want its total without the price list and rule", stored)
+	}
+	got, err := NewCustomerRepository(db).GetByID(ctx, customer.ID)
+	if err != nil || got.PriceListID != nil {
+		t.Errorf("customer after delete = %+v, %v, want no price list", got, err)
+	}
+}
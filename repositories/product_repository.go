@@ -1,28 +1,63 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
+	"github.com/lib/pq"
+
 	"kasir-api/models"
 )
 
-// ProductRepository handles data access for products
-type ProductRepository struct {
+// productRepository is the PostgreSQL implementation of ProductRepository
+type productRepository struct {
 	db *sql.DB
 }
 
 // NewProductRepository creates a new ProductRepository
-func NewProductRepository(db *sql.DB) *ProductRepository {
-	return &ProductRepository{db: db}
+func NewProductRepository(db *sql.DB) ProductRepository {
+	return &productRepository{db: db}
+}
+
+// productColumns selects a product row; the stock of a bundle is the number of
+// bundles that can be assembled from the stock of its components
+const productColumns = `
+	SELECT id, name, price,
+		CASE WHEN is_bundle THEN COALESCE((
+			SELECT MIN(FLOOR(c.stock / bi.quantity))
+			FROM product_bundle_items bi
+			JOIN products c ON c.id = bi.component_product_id
+			WHERE bi.bundle_id = products.id
+		), 0) ELSE stock END AS stock,
+		unit, category_id, is_bundle, deleted_at
+	FROM products`
+
+// scanProduct scans a row selected by productColumns
+func scanProduct(row interface{ Scan(...interface{}) error }) (*models.Product, error) {
+	var p models.Product
+	var categoryID sql.NullInt64
+	var deletedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &p.Unit, &categoryID, &p.IsBundle, &deletedAt); err != nil {
+		return nil, err
+	}
+	p.CategoryID = nullIntPtr(categoryID)
+	if deletedAt.Valid {
+		p.DeletedAt = &deletedAt.Time
+	}
+	return &p, nil
 }
 
 // GetAll returns all products with optional filters
-func (r *ProductRepository) GetAll(filter models.ProductFilter) ([]models.Product, error) {
-	query := "SELECT id, name, price, stock, category_id FROM products WHERE 1=1"
+func (r *productRepository) GetAll(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	query := productColumns + " WHERE 1=1"
 	var args []interface{}
 	argIndex := 1
 
+	if !filter.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
 	if filter.Name != "" {
 		query += fmt.Sprintf(" AND LOWER(name) LIKE LOWER($%d)", argIndex)
 		args = append(args, "%"+filter.Name+"%")
@@ -47,7 +82,7 @@ func (r *ProductRepository) GetAll(filter models.ProductFilter) ([]models.Produc
 		argIndex++
 	}
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -55,34 +90,63 @@ func (r *ProductRepository) GetAll(filter models.ProductFilter) ([]models.Produc
 
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &p.CategoryID); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
-		products = append(products, p)
+		products = append(products, *p)
 	}
 	return products, nil
 }
 
 // GetByID returns a product by ID
-func (r *ProductRepository) GetByID(id int) (*models.Product, error) {
-	var p models.Product
-	err := r.db.QueryRow("SELECT id, name, price, stock, category_id FROM products WHERE id = $1", id).
-		Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &p.CategoryID)
+func (r *productRepository) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	p, err := scanProduct(r.db.QueryRowContext(ctx, productColumns+" WHERE id = $1", id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("Product with ID %d not found", id)
 		}
 		return nil, err
 	}
-	return &p, nil
+	return p, nil
+}
+
+// GetByIDs returns the products with the given IDs
+func (r *productRepository) GetByIDs(ctx context.Context, ids []int) ([]models.Product, error) {
+	rows, err := r.db.QueryContext(ctx, productColumns+" WHERE id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *p)
+	}
+	return products, rows.Err()
 }
 
-// Create adds a new product
-func (r *ProductRepository) Create(product models.Product) (*models.Product, error) {
-	err := r.db.QueryRow(
-		"INSERT INTO products (name, price, stock, category_id) VALUES ($1, $2, $3, $4) RETURNING id",
-		product.Name, product.Price, product.Stock, product.CategoryID,
+// Create adds a new product; its initial stock is held by the main outlet
+func (r *productRepository) Create(ctx context.Context, product models.Product, changedBy string) (*models.Product, error) {
+	if err := r.checkCategory(ctx, product.CategoryID); err != nil {
+		return nil, err
+	}
+	err := r.db.QueryRowContext(ctx, `
+		WITH product AS (
+			INSERT INTO products (name, price, stock, unit, category_id) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, name, price, stock, unit, category_id
+		), history AS (
+			INSERT INTO product_versions (product_id, version, name, price, stock, unit, category_id, changed_by)
+			SELECT id, 1, name, price, stock, unit, category_id, NULLIF($7, '') FROM product
+		)
+		INSERT INTO outlet_products (outlet_id, product_id, stock)
+		SELECT $6, id, stock FROM product
+		RETURNING product_id
+	`, product.Name, product.Price, product.Stock, product.Unit, product.CategoryID, models.DefaultOutletID, changedBy,
 	).Scan(&product.ID)
 	if err != nil {
 		return nil, err
@@ -90,32 +154,312 @@ func (r *ProductRepository) Create(product models.Product) (*models.Product, err
 	return &product, nil
 }
 
-// Update updates an existing product
-func (r *ProductRepository) Update(id int, product models.Product) (*models.Product, error) {
-	result, err := r.db.Exec(
-		"UPDATE products SET name = $1, price = $2, stock = $3, category_id = $4 WHERE id = $5",
-		product.Name, product.Price, product.Stock, product.CategoryID, id,
-	)
+// Update updates an existing product; the main outlet takes the change of
+// stock, since the stock of a product is the total over all outlets. A change
+// is recorded as the next version of the product.
+func (r *productRepository) Update(ctx context.Context, id int, product models.Product, changedBy string) (*models.Product, error) {
+	if err := r.checkCategory(ctx, product.CategoryID); err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	defer tx.Rollback()
+
+	deleted, err := lockProduct(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deleted {
 		return nil, fmt.Errorf("Product with ID %d not found", id)
 	}
-	product.ID = id
+	err = tx.QueryRowContext(ctx, `
+		WITH old AS (
+			SELECT id, name, price, stock, unit, category_id,
+				(SELECT COALESCE(MAX(version), 0) FROM product_versions v WHERE v.product_id = products.id) AS version
+			FROM products WHERE id = $6
+		), updated AS (
+			UPDATE products p SET name = $1, price = $2, stock = $3, unit = $4, category_id = $5
+			FROM old WHERE p.id = old.id
+			RETURNING p.id, p.name, p.price, p.stock, p.unit, p.category_id, p.stock - old.stock AS change
+		), history AS (
+			INSERT INTO product_versions (product_id, version, name, price, stock, unit, category_id, changed_by)
+			SELECT u.id, old.version + 1, u.name, u.price, u.stock, u.unit, u.category_id, NULLIF($8, '')
+			FROM updated u, old
+			WHERE (u.name, u.price, u.stock, u.unit, u.category_id) IS DISTINCT FROM
+				(old.name, old.price, old.stock, old.unit, old.category_id)
+		)
+		INSERT INTO outlet_products (outlet_id, product_id, stock)
+		SELECT $7, id, change FROM updated
+		`+outletStockConflict+` DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock
+		RETURNING product_id
+	`, product.Name, product.Price, product.Stock, product.Unit, product.CategoryID, id, models.DefaultOutletID, changedBy,
+	).Scan(&product.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &product, nil
 }
 
-// Delete removes a product by ID
-func (r *ProductRepository) Delete(id int) error {
-	result, err := r.db.Exec("DELETE FROM products WHERE id = $1", id)
+// lockProduct locks a product for the rest of the transaction and reports
+// whether it is deleted. Statements run after the lock see every version
+// recorded before it, so two changes of a product never take the same
+// version number.
+func lockProduct(ctx context.Context, tx *sql.Tx, id int) (bool, error) {
+	var deleted bool
+	err := tx.QueryRowContext(ctx, "SELECT deleted_at IS NOT NULL FROM products WHERE id = $1 FOR UPDATE", id).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("Product with ID %d not found", id)
+	}
+	return deleted, err
+}
+
+// checkCategory checks that the category of a product is not deleted, which
+// its foreign key does not; a product may have no category
+func (r *productRepository) checkCategory(ctx context.Context, categoryID *int) error {
+	if categoryID == nil {
+		return nil
+	}
+	var deleted bool
+	err := r.db.QueryRowContext(ctx, "SELECT deleted_at IS NOT NULL FROM categories WHERE id = $1", *categoryID).Scan(&deleted)
+	if err == sql.ErrNoRows || deleted {
+		return fmt.Errorf("category with ID %d does not exist", *categoryID)
+	}
+	return err
+}
+
+// Delete soft deletes a product by ID; deleting it again is not found
+func (r *productRepository) Delete(ctx context.Context, id int) error {
+	var inBundle bool
+	err := r.db.QueryRowContext(ctx, `
+		WITH product AS (
+			SELECT id, EXISTS (
+				SELECT 1 FROM product_bundle_items bi
+				JOIN products b ON b.id = bi.bundle_id
+				WHERE bi.component_product_id = products.id AND b.deleted_at IS NULL
+			) AS in_bundle
+			FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+		), deleted AS (
+			UPDATE products p SET deleted_at = NOW()
+			FROM product WHERE p.id = product.id AND NOT product.in_bundle
+		)
+		SELECT in_bundle FROM product
+	`, id).Scan(&inBundle)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("Product with ID %d not found", id)
+		}
+		return err
+	}
+	if inBundle {
+		return fmt.Errorf("%w: product with ID %d is referenced by bundles", ErrInUse, id)
+	}
+	return nil
+}
+
+// Restore undoes the deletion of a product; a product whose category was
+// deleted meanwhile loses it, so it is not live in a deleted category
+func (r *productRepository) Restore(ctx context.Context, id int, changedBy string) (*models.Product, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted, err := lockProduct(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, fmt.Errorf("%w: product with ID %d is not deleted", ErrNotDeleted, id)
+	}
+	_, err = tx.ExecContext(ctx, `
+		WITH product AS (
+			SELECT p.id, c.deleted_at IS NOT NULL AS category_deleted
+			FROM products p LEFT JOIN categories c ON c.id = p.category_id
+			WHERE p.id = $1
+		), restored AS (
+			UPDATE products p SET deleted_at = NULL,
+				category_id = CASE WHEN product.category_deleted THEN NULL ELSE p.category_id END
+			FROM product WHERE p.id = product.id
+			RETURNING p.id, p.name, p.price, p.stock, p.unit, p.category_id, product.category_deleted
+		)
+		INSERT INTO product_versions (product_id, version, name, price, stock, unit, category_id, changed_by)
+		SELECT r.id, (SELECT COALESCE(MAX(version), 0) + 1 FROM product_versions v WHERE v.product_id = r.id),
+			r.name, r.price, r.stock, r.unit, r.category_id, NULLIF($2, '')
+		FROM restored r
+		WHERE r.category_deleted
+	`, id, changedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Purge permanently removes a deleted product together with its history,
+// variants, packaging units, stock at the outlets, price rules and items on
+// closed bills. Sales, bundles, transfers and open bills keep their products,
+// so a product they reference cannot be purged. The product row is locked
+// first: referencing it from a new row waits for the purge and then fails on
+// the foreign key.
+func (r *productRepository) Purge(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleted, err := lockProduct(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	var sold, inBundle, transferred, onOpenBill bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM transaction_details WHERE product_id = $1)
+				OR EXISTS (SELECT 1 FROM transaction_detail_components WHERE product_id = $1)
+				OR EXISTS (SELECT 1 FROM stock_conflicts WHERE product_id = $1),
+			EXISTS (SELECT 1 FROM product_bundle_items WHERE component_product_id = $1),
+			EXISTS (SELECT 1 FROM stock_transfer_lines WHERE product_id = $1),
+			EXISTS (
+				SELECT 1 FROM open_bill_items i JOIN open_bills b ON b.id = i.bill_id
+				WHERE i.product_id = $1 AND b.status = 'open'
+			)
+	`, id).Scan(&sold, &inBundle, &transferred, &onOpenBill)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !deleted:
+		return fmt.Errorf("%w: product with ID %d must be deleted before it is purged", ErrNotDeleted, id)
+	case sold:
+		return fmt.Errorf("%w: product with ID %d is referenced by sales", ErrInUse, id)
+	case inBundle:
+		return fmt.Errorf("%w: product with ID %d is referenced by bundles", ErrInUse, id)
+	case transferred:
+		return fmt.Errorf("%w: product with ID %d is referenced by transfers", ErrInUse, id)
+	case onOpenBill:
+		return fmt.Errorf("%w: product with ID %d is on open bills", ErrInUse, id)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM products WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetHistory returns the versions of a product, oldest first
+func (r *productRepository) GetHistory(ctx context.Context, productID int) ([]models.ProductVersion, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT product_id, version, name, price, stock, unit, category_id, COALESCE(changed_by, ''), changed_at
+		FROM product_versions WHERE product_id = $1 ORDER BY version
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []models.ProductVersion
+	for rows.Next() {
+		var v models.ProductVersion
+		var categoryID sql.NullInt64
+		if err := rows.Scan(&v.ProductID, &v.Version, &v.Name, &v.Price, &v.Stock, &v.Unit, &categoryID, &v.ChangedBy, &v.ChangedAt); err != nil {
+			return nil, err
+		}
+		v.CategoryID = nullIntPtr(categoryID)
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// GetComponents returns the components of a bundle product
+func (r *productRepository) GetComponents(ctx context.Context, bundleID int) ([]models.BundleComponent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT bi.component_product_id, p.name, bi.quantity
+		FROM product_bundle_items bi
+		JOIN products p ON p.id = bi.component_product_id
+		WHERE bi.bundle_id = $1
+		ORDER BY bi.id
+	`, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var components []models.BundleComponent
+	for rows.Next() {
+		var c models.BundleComponent
+		if err := rows.Scan(&c.ProductID, &c.Name, &c.Quantity); err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+// GetComponentsByBundleIDs returns the components of several bundle products
+func (r *productRepository) GetComponentsByBundleIDs(ctx context.Context, bundleIDs []int) (map[int][]models.BundleComponent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT bi.bundle_id, bi.component_product_id, p.name, bi.quantity
+		FROM product_bundle_items bi
+		JOIN products p ON p.id = bi.component_product_id
+		WHERE bi.bundle_id = ANY($1)
+		ORDER BY bi.id
+	`, pq.Array(bundleIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := make(map[int][]models.BundleComponent)
+	for rows.Next() {
+		var bundleID int
+		var c models.BundleComponent
+		if err := rows.Scan(&bundleID, &c.ProductID, &c.Name, &c.Quantity); err != nil {
+			return nil, err
+		}
+		components[bundleID] = append(components[bundleID], c)
+	}
+	return components, rows.Err()
+}
+
+// SetComponents replaces the components of a product; a product with
+// components becomes a bundle, one without components a regular product
+func (r *productRepository) SetComponents(ctx context.Context, bundleID int, components []models.BundleComponent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE products SET is_bundle = $1 WHERE id = $2", len(components) > 0, bundleID)
 	if err != nil {
 		return err
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return fmt.Errorf("Product with ID %d not found", id)
+		return fmt.Errorf("Product with ID %d not found", bundleID)
 	}
-	return nil
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM product_bundle_items WHERE bundle_id = $1", bundleID); err != nil {
+		return err
+	}
+
+	for _, c := range components {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO product_bundle_items (bundle_id, component_product_id, quantity) VALUES ($1, $2, $3)",
+			bundleID, c.ProductID, c.Quantity,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
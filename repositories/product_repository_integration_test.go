@@ -0,0 +1,391 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestPostgresProductRepositoryGetAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  models.ProductFilter
+		wantIDs []int
+	}{
+		{name: "all", wantIDs: []int{kopiID, berasID, esTehID, paketID}},
+		{name: "name is case insensitive", filter: models.ProductFilter{Name: "TEH"}, wantIDs: []int{esTehID}},
+		{name: "category", filter: models.ProductFilter{CategoryID: 1}, wantIDs: []int{kopiID, berasID, esTehID, paketID}},
+		{name: "unknown category", filter: models.ProductFilter{CategoryID: 2}},
+		{name: "price range", filter: models.ProductFilter{MinPrice: 4000, MaxPrice: 12000}, wantIDs: []int{kopiID, berasID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			products, err := NewProductRepository(db).GetAll(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatalf("GetAll() error = %v", err)
+			}
+
+			got := make(map[int]bool)
+			for _, p := range products {
+				got[p.ID] = true
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("GetAll() = %+v, want IDs %v", products, tt.wantIDs)
+			}
+			for _, id := range tt.wantIDs {
+				if !got[id] {
+					t.Errorf("GetAll() is missing product %d", id)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresProductRepositoryCRUD(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewProductRepository(db)
+
+	created, err := repo.Create(ctx, models.Product{Name: "Gula", Price: 14000.5, Stock: 2.125, Unit: "kg", CategoryID: intPtr(1)}, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Name != "Gula" || got.Price != 14000.5 || got.Stock != 2.125 || got.Unit != "kg" || got.IsBundle {
+		t.Errorf("GetByID() = %+v", got)
+	}
+
+	if _, err := repo.Update(ctx, created.ID, models.Product{Name: "Gula Pasir", Price: 15000, Stock: 3, Unit: "kg", CategoryID: intPtr(1)}, ""); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, created.ID); got.Name != "Gula Pasir" || got.Stock != 3 {
+		t.Errorf("after Update() = %+v", got)
+	}
+
+	if _, err := repo.Create(ctx, models.Product{Name: "Kain", Unit: "meter", CategoryID: intPtr(1)}, ""); err == nil {
+		t.Error("Create() with unknown unit succeeded, want foreign key error")
+	}
+
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := repo.GetByID(ctx, created.ID); err != nil || got.DeletedAt == nil {
+		t.Errorf("GetByID() of deleted product = %+v, %v, want it marked deleted", got, err)
+	}
+	if products, err := repo.GetAll(ctx, models.ProductFilter{Name: "gula"}); err != nil || len(products) != 0 {
+		t.Errorf("GetAll() = %+v, %v, want the deleted product left out", products, err)
+	}
+	if products, err := repo.GetAll(ctx, models.ProductFilter{Name: "gula", IncludeDeleted: true}); err != nil || len(products) != 1 {
+		t.Errorf("GetAll() including deleted = %+v, %v, want Gula Pasir", products, err)
+	}
+	for name, err := range map[string]error{
+		"Update": func() error {
+			_, err := repo.Update(ctx, created.ID, models.Product{Unit: "kg", CategoryID: intPtr(1)}, "")
+			return err
+		}(),
+		"Delete": repo.Delete(ctx, created.ID),
+	} {
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("%s() of deleted product error = %v, want not found", name, err)
+		}
+	}
+
+	restored, err := repo.Restore(ctx, created.ID, "")
+	if err != nil || restored.DeletedAt != nil || restored.Name != "Gula Pasir" {
+		t.Fatalf("Restore() = %+v, %v", restored, err)
+	}
+	if _, err := repo.Restore(ctx, created.ID, ""); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("second Restore() error = %v, want ErrNotDeleted", err)
+	}
+	if err := repo.Purge(ctx, created.ID); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("Purge() of live product error = %v, want ErrNotDeleted", err)
+	}
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Purge(ctx, created.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, created.ID); err == nil {
+		t.Error("GetByID() of purged product succeeded")
+	}
+}
+
+func TestPostgresProductRepositoryPurgeSold(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewProductRepository(db)
+
+	_, err := NewTransactionRepository(db).Create(ctx, models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		TotalAmount: 3000,
+		Details: []models.TransactionDetail{
+			{ProductID: esTehID, VariantID: intPtr(1), Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 3000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() transaction error = %v", err)
+	}
+	if err := repo.Delete(ctx, kopiID); !errors.Is(err, ErrInUse) {
+		t.Errorf("Delete() of bundle component error = %v, want ErrInUse", err)
+	}
+	if err := repo.Delete(ctx, esTehID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Purge(ctx, esTehID); !errors.Is(err, ErrInUse) || !strings.Contains(err.Error(), "sales") {
+		t.Errorf("Purge() of sold product error = %v, want ErrInUse by sales", err)
+	}
+	if _, err := repo.GetByID(ctx, esTehID); err != nil {
+		t.Errorf("GetByID() after refused Purge() error = %v", err)
+	}
+}
+
+func TestPostgresProductRepositoryPurgeOpenBill(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewProductRepository(db)
+	bills := NewOpenBillRepository(db)
+
+	bill, err := bills.Create(ctx, models.OpenBill{
+		OutletID:     models.DefaultOutletID,
+		Label:        "Budi",
+		ReserveStock: true,
+		Items: []models.OpenBillItem{{ProductID: esTehID, VariantID: intPtr(2), Quantity: 2, Unit: "pcs",
+			Reserved: []models.ReservedStock{{ProductID: esTehID, VariantID: intPtr(2), Quantity: 2}}}},
+	})
+	if err != nil {
+		t.Fatalf("Create() bill error = %v", err)
+	}
+	if err := repo.Delete(ctx, esTehID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Purge(ctx, esTehID); !errors.Is(err, ErrInUse) || !strings.Contains(err.Error(), "open bills") {
+		t.Errorf("Purge() of product on open bill error = %v, want ErrInUse by open bills", err)
+	}
+
+	// Cancelling the bill returns the reserved stock; the closed bill no
+	// longer holds the product back
+	if err := bills.Cancel(ctx, bill.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if err := repo.Purge(ctx, esTehID); err != nil {
+		t.Fatalf("Purge() after cancel error = %v", err)
+	}
+	if n := countRows(t, db, "product_versions WHERE product_id = 3"); n != 0 {
+		t.Errorf("versions of purged product = %d, want 0", n)
+	}
+}
+
+func TestPostgresProductRepositoryHistory(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewProductRepository(db)
+
+	created, err := repo.Create(ctx, models.Product{Name: "Gula", Price: 14000, Stock: 2, Unit: "kg", CategoryID: intPtr(1)}, "budi")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	product := *created
+	product.Price = 15000
+	if _, err := repo.Update(ctx, created.ID, product, "sari"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	// Saving the product unchanged adds no version
+	if _, err := repo.Update(ctx, created.ID, product, "sari"); err != nil {
+		t.Fatalf("Update() unchanged error = %v", err)
+	}
+
+	versions, err := repo.GetHistory(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("GetHistory() = %+v, want 2 versions", versions)
+	}
+	if versions[0].Version != 1 || versions[0].Price != 14000 || versions[0].ChangedBy != "budi" {
+		t.Errorf("version 1 = %+v, want the created product by budi", versions[0])
+	}
+	if versions[1].Version != 2 || versions[1].Price != 15000 || versions[1].Stock != 2 || versions[1].CategoryID == nil || *versions[1].CategoryID != 1 || versions[1].ChangedBy != "sari" {
+		t.Errorf("version 2 = %+v, want the new price by sari", versions[1])
+	}
+
+	// Seeded products start with version 1 and lose their history with them
+	if versions, err := repo.GetHistory(ctx, kopiID); err != nil || len(versions) != 1 || versions[0].ChangedBy != "" {
+		t.Errorf("GetHistory(Kopi) = %+v, %v, want the seeded version", versions, err)
+	}
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if versions, err := repo.GetHistory(ctx, created.ID); err != nil || len(versions) != 2 {
+		t.Errorf("GetHistory() of deleted product = %+v, %v, want it kept", versions, err)
+	}
+	if err := repo.Purge(ctx, created.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if versions, err := repo.GetHistory(ctx, created.ID); err != nil || len(versions) != 0 {
+		t.Errorf("GetHistory() of purged product = %+v, %v, want none", versions, err)
+	}
+}
+
+func TestPostgresProductRepositoryComponents(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewProductRepository(db)
+
+	components, err := repo.GetComponents(ctx, paketID)
+	if err != nil {
+		t.Fatalf("GetComponents() error = %v", err)
+	}
+	if len(components) != 2 || components[0].Name != "Kopi" || components[1].Quantity != 0.5 {
+		t.Errorf("GetComponents() = %+v", components)
+	}
+
+	// 10 Kopi make 5 bundles, 5 kg Beras make 10: the bundle stock is 5
+	bundle, err := repo.GetByID(ctx, paketID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bundle.IsBundle || bundle.Stock != 5 {
+		t.Errorf("bundle = %+v, want a bundle with stock 5", bundle)
+	}
+
+	if err := repo.SetComponents(ctx, paketID, nil); err != nil {
+		t.Fatalf("SetComponents(nil) error = %v", err)
+	}
+	if bundle, _ := repo.GetByID(ctx, paketID); bundle.IsBundle {
+		t.Error("product is still a bundle after removing its components")
+	}
+
+	if err := repo.SetComponents(ctx, 99, []models.BundleComponent{{ProductID: kopiID, Quantity: 1}}); err == nil {
+		t.Error("SetComponents() of unknown product succeeded, want not found")
+	}
+}
+
+func TestPostgresProductRepositoryBulkReads(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewProductRepository(db)
+
+	products, err := repo.GetByIDs(ctx, []int{paketID, 99, kopiID, kopiID})
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	if len(products) != 2 || products[0].ID != kopiID || products[1].ID != paketID {
+		t.Fatalf("GetByIDs() = %+v, want Kopi and the bundle", products)
+	}
+	if !products[1].IsBundle || products[1].Stock != 5 {
+		t.Errorf("bundle = %+v, want a bundle with stock 5", products[1])
+	}
+	if products, err := repo.GetByIDs(ctx, nil); err != nil || len(products) != 0 {
+		t.Errorf("GetByIDs(nil) = %+v, %v; want none", products, err)
+	}
+
+	components, err := repo.GetComponentsByBundleIDs(ctx, []int{paketID, kopiID})
+	if err != nil {
+		t.Fatalf("GetComponentsByBundleIDs() error = %v", err)
+	}
+	if len(components) != 1 || len(components[paketID]) != 2 || components[paketID][1].Quantity != 0.5 {
+		t.Errorf("GetComponentsByBundleIDs() = %+v", components)
+	}
+
+	variants, err := NewProductVariantRepository(db).GetByIDs(ctx, []int{2, 1, 99})
+	if err != nil {
+		t.Fatalf("variant GetByIDs() error = %v", err)
+	}
+	if len(variants) != 2 || variants[0].SKU != "TEH-S" || variants[1].Stock != 2 {
+		t.Errorf("variant GetByIDs() = %+v", variants)
+	}
+}
+
+func TestPostgresProductVariantRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewProductVariantRepository(db)
+
+	variants, err := repo.GetByProductID(ctx, esTehID)
+	if err != nil || len(variants) != 2 || variants[0].SKU != "TEH-S" {
+		t.Fatalf("GetByProductID() = %+v, %v", variants, err)
+	}
+
+	if _, err := repo.Create(ctx, models.ProductVariant{ProductID: kopiID, Name: "S", SKU: "TEH-S"}); err == nil {
+		t.Error("Create() with duplicate SKU succeeded, want unique violation")
+	}
+
+	if _, err := repo.Update(ctx, kopiID, 1, models.ProductVariant{Name: "S", SKU: "TEH-S"}); err == nil {
+		t.Error("Update() through another product succeeded, want not found")
+	}
+	if _, err := repo.Update(ctx, esTehID, 1, models.ProductVariant{Name: "Small", SKU: "TEH-S", Price: 3500, Stock: 4}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if v, err := repo.GetByID(ctx, 1); err != nil || v.Name != "Small" || v.Price != 3500 {
+		t.Errorf("GetByID() = %+v, %v", v, err)
+	}
+
+	if err := repo.Delete(ctx, esTehID, 1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, 1); err == nil {
+		t.Error("GetByID() of deleted variant succeeded")
+	}
+
+	// Purging the product removes its variants
+	products := NewProductRepository(db)
+	if err := products.Delete(ctx, esTehID); err != nil {
+		t.Fatalf("deleting product: %v", err)
+	}
+	if err := products.Purge(ctx, esTehID); err != nil {
+		t.Fatalf("purging product: %v", err)
+	}
+	if variants, _ := repo.GetByProductID(ctx, esTehID); len(variants) != 0 {
+		t.Errorf("variants of purged product = %+v", variants)
+	}
+}
+
+func TestPostgresProductRepositoryConcurrentUpdates(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewProductRepository(db)
+
+	// Every update changes the price, so each one records a version; none
+	// may take a number another one took
+	const updates = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+	for i := range updates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			product := models.Product{Name: "Beras", Price: float64(13000 + i), Stock: 5, Unit: "kg", CategoryID: intPtr(1)}
+			if _, err := repo.Update(ctx, berasID, product, "budi"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent Update() error = %v", err)
+	}
+
+	versions, err := repo.GetHistory(ctx, berasID)
+	if err != nil || len(versions) != updates+1 {
+		t.Fatalf("GetHistory() = %+v, %v, want %d versions", versions, err, updates+1)
+	}
+	for i, v := range versions {
+		if v.Version != i+1 {
+			t.Errorf("version %d numbered %d", i+1, v.Version)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// productVariantRepository is the PostgreSQL implementation of ProductVariantRepository
+type productVariantRepository struct {
+	db *sql.DB
+}
+
+// NewProductVariantRepository creates a new ProductVariantRepository
+func NewProductVariantRepository(db *sql.DB) ProductVariantRepository {
+	return &productVariantRepository{db: db}
+}
+
+// GetByProductID returns all variants of a product
+func (r *productVariantRepository) GetByProductID(ctx context.Context, productID int) ([]models.ProductVariant, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, product_id, name, sku, price, stock FROM product_variants WHERE product_id = $1 ORDER BY id",
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []models.ProductVariant
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.Name, &v.SKU, &v.Price, &v.Stock); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+// GetByID returns a variant by ID
+func (r *productVariantRepository) GetByID(ctx context.Context, id int) (*models.ProductVariant, error) {
+	var v models.ProductVariant
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, product_id, name, sku, price, stock FROM product_variants WHERE id = $1",
+		id,
+	).Scan(&v.ID, &v.ProductID, &v.Name, &v.SKU, &v.Price, &v.Stock)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Variant with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetByIDs returns the variants with the given IDs
+func (r *productVariantRepository) GetByIDs(ctx context.Context, ids []int) ([]models.ProductVariant, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, product_id, name, sku, price, stock FROM product_variants WHERE id = ANY($1) ORDER BY id",
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []models.ProductVariant
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.Name, &v.SKU, &v.Price, &v.Stock); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+// Create adds a new variant to a product; its initial stock is held by the
+// main outlet
+func (r *productVariantRepository) Create(ctx context.Context, variant models.ProductVariant) (*models.ProductVariant, error) {
+	err := r.db.QueryRowContext(ctx, `
+		WITH variant AS (
+			INSERT INTO product_variants (product_id, name, sku, price, stock) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, product_id, stock
+		)
+		INSERT INTO outlet_products (outlet_id, product_id, variant_id, stock)
+		SELECT $6, product_id, id, stock FROM variant
+		RETURNING variant_id
+	`, variant.ProductID, variant.Name, variant.SKU, variant.Price, variant.Stock, models.DefaultOutletID,
+	).Scan(&variant.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// Update updates an existing variant of a product; the main outlet takes the
+// change of stock, since the stock of a variant is the total over all outlets
+func (r *productVariantRepository) Update(ctx context.Context, productID, id int, variant models.ProductVariant) (*models.ProductVariant, error) {
+	err := r.db.QueryRowContext(ctx, `
+		WITH old AS (
+			SELECT id, stock FROM product_variants WHERE id = $5 AND product_id = $6 FOR UPDATE
+		), updated AS (
+			UPDATE product_variants v SET name = $1, sku = $2, price = $3, stock = $4
+			FROM old WHERE v.id = old.id
+			RETURNING v.id, v.product_id, v.stock - old.stock AS change
+		)
+		INSERT INTO outlet_products (outlet_id, product_id, variant_id, stock)
+		SELECT $7, product_id, id, change FROM updated
+		`+outletStockConflict+` DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock
+		RETURNING variant_id
+	`, variant.Name, variant.SKU, variant.Price, variant.Stock, id, productID, models.DefaultOutletID,
+	).Scan(&variant.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Variant with ID %d not found", id)
+		}
+		return nil, err
+	}
+	variant.ProductID = productID
+	return &variant, nil
+}
+
+// Delete removes a variant of a product
+func (r *productVariantRepository) Delete(ctx context.Context, productID, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM product_variants WHERE id = $1 AND product_id = $2", id, productID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("Variant with ID %d not found", id)
+	}
+	return nil
+}
@@ -1,62 +1,132 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
+	"github.com/lib/pq"
+
 	"kasir-api/models"
 )
 
-// ReportRepository handles data access for reports
-type ReportRepository struct {
+// reportRepository is the PostgreSQL implementation of ReportRepository
+type reportRepository struct {
 	db *sql.DB
 }
 
 // NewReportRepository creates a new ReportRepository
-func NewReportRepository(db *sql.DB) *ReportRepository {
-	return &ReportRepository{db: db}
+func NewReportRepository(db *sql.DB) ReportRepository {
+	return &reportRepository{db: db}
 }
 
-// GetSalesReport returns sales summary for a date range
-func (r *ReportRepository) GetSalesReport(startDate, endDate time.Time) (*models.SalesReport, error) {
+// soldLinesByBundle lists every sold line with bundle revenue kept on the bundle
+const soldLinesByBundle = `
+	SELECT td.product_id, td.quantity, td.subtotal
+	FROM transaction_details td
+	JOIN transactions t ON td.transaction_id = t.id
+	WHERE t.created_at >= $1 AND t.created_at < $2 AND ($3::int[] IS NULL OR t.outlet_id = ANY($3))`
+
+// soldLinesByComponent lists every sold line with bundle revenue attributed
+// to the bundle components
+const soldLinesByComponent = `
+	SELECT td.product_id, td.quantity, td.subtotal
+	FROM transaction_details td
+	JOIN transactions t ON td.transaction_id = t.id
+	WHERE t.created_at >= $1 AND t.created_at < $2 AND ($3::int[] IS NULL OR t.outlet_id = ANY($3))
+		AND NOT EXISTS (SELECT 1 FROM transaction_detail_components c WHERE c.transaction_detail_id = td.id)
+	UNION ALL
+	SELECT c.product_id, c.quantity, c.subtotal
+	FROM transaction_detail_components c
+	JOIN transaction_details td ON td.id = c.transaction_detail_id
+	JOIN transactions t ON td.transaction_id = t.id
+	WHERE t.created_at >= $1 AND t.created_at < $2 AND ($3::int[] IS NULL OR t.outlet_id = ANY($3))`
+
+// GetSalesReport returns sales summary for a date range of outletIDs, or of
+// all outlets when outletIDs is nil, with the sales of each outlet. When
+// attributeToComponents is set, bundle sales are reported on their components.
+func (r *reportRepository) GetSalesReport(ctx context.Context, startDate, endDate time.Time, attributeToComponents bool, outletIDs []int) (*models.SalesReport, error) {
 	report := &models.SalesReport{}
+	outlets := pq.Array(outletIDs)
 
 	// Get total revenue and transaction count
-	err := r.db.QueryRow(`
+	err := r.db.QueryRowContext(ctx, `
 		SELECT 
 			COALESCE(SUM(total_amount), 0) as total_revenue,
 			COUNT(*) as total_transaksi
 		FROM transactions
-		WHERE created_at >= $1 AND created_at < $2
-	`, startDate, endDate).Scan(&report.TotalRevenue, &report.TotalTransaksi)
+		WHERE created_at >= $1 AND created_at < $2 AND ($3::int[] IS NULL OR outlet_id = ANY($3))
+	`, startDate, endDate, outlets).Scan(&report.TotalRevenue, &report.TotalTransaksi)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get best selling product
-	var productName sql.NullString
-	var qtyTerjual sql.NullInt64
-	err = r.db.QueryRow(`
+	lines := soldLinesByBundle
+	if attributeToComponents {
+		lines = soldLinesByComponent
+	}
+
+	// Get sales per product, best selling first (variant sales roll up to
+	// their parent product)
+	rows, err := r.db.QueryContext(ctx, `
 		SELECT 
+			p.id,
 			p.name,
-			SUM(td.quantity) as qty_terjual
-		FROM transaction_details td
-		JOIN transactions t ON td.transaction_id = t.id
-		JOIN products p ON td.product_id = p.id
-		WHERE t.created_at >= $1 AND t.created_at < $2
-		GROUP BY p.id, p.name
-		ORDER BY qty_terjual DESC
-		LIMIT 1
-	`, startDate, endDate).Scan(&productName, &qtyTerjual)
-
-	if err != nil && err != sql.ErrNoRows {
+			p.unit,
+			SUM(l.quantity) as qty_terjual,
+			SUM(l.subtotal) as pendapatan
+		FROM (`+lines+`) l
+		JOIN products p ON l.product_id = p.id
+		GROUP BY p.id, p.name, p.unit
+		ORDER BY qty_terjual DESC, p.id
+	`, startDate, endDate, outlets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.ProductSales
+		if err := rows.Scan(&s.ProductID, &s.Nama, &s.Satuan, &s.QtyTerjual, &s.Pendapatan); err != nil {
+			return nil, err
+		}
+		report.RincianProduk = append(report.RincianProduk, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Get sales per outlet
+	outletRows, err := r.db.QueryContext(ctx, `
+		SELECT o.id, o.name, SUM(t.total_amount), COUNT(*)
+		FROM transactions t
+		JOIN outlets o ON o.id = t.outlet_id
+		WHERE t.created_at >= $1 AND t.created_at < $2 AND ($3::int[] IS NULL OR t.outlet_id = ANY($3))
+		GROUP BY o.id, o.name
+		ORDER BY o.id
+	`, startDate, endDate, outlets)
+	if err != nil {
+		return nil, err
+	}
+	defer outletRows.Close()
+
+	for outletRows.Next() {
+		var s models.OutletSales
+		if err := outletRows.Scan(&s.OutletID, &s.Nama, &s.TotalRevenue, &s.TotalTransaksi); err != nil {
+			return nil, err
+		}
+		report.RincianOutlet = append(report.RincianOutlet, s)
+	}
+	if err := outletRows.Err(); err != nil {
 		return nil, err
 	}
 
-	if productName.Valid && qtyTerjual.Valid {
+	if len(report.RincianProduk) > 0 {
+		best := report.RincianProduk[0]
 		report.ProdukTerlaris = &models.BestSellerInfo{
-			Nama:       productName.String,
-			QtyTerjual: int(qtyTerjual.Int64),
+			Nama:       best.Nama,
+			QtyTerjual: best.QtyTerjual,
+			Satuan:     best.Satuan,
 		}
 	}
 
@@ -0,0 +1,159 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+// seedSales records three sales in March 2026 and one in April
+func seedSales(t *testing.T, ctx context.Context, repo TransactionRepository) {
+	t.Helper()
+	sales := []struct {
+		clientID string
+		at       time.Time
+		total    int
+		details  []models.TransactionDetail
+	}{
+		{
+			clientID: "00000000-0000-4000-8000-000000000001",
+			at:       time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+			total:    20000,
+			details: []models.TransactionDetail{
+				{ProductID: kopiID, Quantity: 4, Unit: "pcs", UnitQuantity: 4, Subtotal: 20000},
+			},
+		},
+		{
+			clientID: "00000000-0000-4000-8000-000000000002",
+			at:       time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+			total:    30000,
+			details: []models.TransactionDetail{
+				{ProductID: paketID, Quantity: 2, Unit: "pcs", UnitQuantity: 2, Subtotal: 30000, Components: []models.TransactionDetailComponent{
+					{ProductID: kopiID, Quantity: 4, Subtotal: 12000},
+					{ProductID: berasID, Quantity: 1, Subtotal: 18000},
+				}},
+			},
+		},
+		{
+			clientID: "00000000-0000-4000-8000-000000000003",
+			at:       time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC),
+			total:    33000,
+			details: []models.TransactionDetail{
+				{ProductID: berasID, Quantity: 2.5, Unit: "kg", UnitQuantity: 2.5, Subtotal: 30000},
+				{ProductID: esTehID, VariantID: intPtr(1), Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 3000},
+			},
+		},
+		{
+			clientID: "00000000-0000-4000-8000-000000000004",
+			at:       time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			total:    50000,
+			details: []models.TransactionDetail{
+				{ProductID: esTehID, VariantID: intPtr(2), Quantity: 10, Unit: "pcs", UnitQuantity: 10, Subtotal: 50000},
+			},
+		},
+	}
+	for _, s := range sales {
+		clientID := s.clientID
+		_, _, _, err := repo.CreateOffline(ctx, models.Transaction{OutletID: models.DefaultOutletID, ClientID: &clientID, TotalAmount: s.total, CreatedAt: s.at, Details: s.details})
+		if err != nil {
+			t.Fatalf("seeding sale %s: %v", s.clientID, err)
+		}
+	}
+}
+
+func TestPostgresReportRepositoryGetSalesReport(t *testing.T) {
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                  string
+		start, end            time.Time
+		attributeToComponents bool
+		wantRevenue           int
+		wantCount             int
+		wantBest              *models.BestSellerInfo
+		wantLines             []models.ProductSales
+	}{
+		{
+			name:        "revenue on the bundle",
+			start:       march,
+			end:         april,
+			wantRevenue: 83000,
+			wantCount:   3,
+			wantBest:    &models.BestSellerInfo{Nama: "Kopi", QtyTerjual: 4, Satuan: "pcs"},
+			wantLines: []models.ProductSales{
+				{ProductID: kopiID, Nama: "Kopi", QtyTerjual: 4, Satuan: "pcs", Pendapatan: 20000},
+				{ProductID: berasID, Nama: "Beras", QtyTerjual: 2.5, Satuan: "kg", Pendapatan: 30000},
+				{ProductID: paketID, Nama: "Paket Sarapan", QtyTerjual: 2, Satuan: "pcs", Pendapatan: 30000},
+				{ProductID: esTehID, Nama: "Es Teh", QtyTerjual: 1, Satuan: "pcs", Pendapatan: 3000},
+			},
+		},
+		{
+			name:                  "revenue on the components",
+			start:                 march,
+			end:                   april,
+			attributeToComponents: true,
+			wantRevenue:           83000,
+			wantCount:             3,
+			wantBest:              &models.BestSellerInfo{Nama: "Kopi", QtyTerjual: 8, Satuan: "pcs"},
+			wantLines: []models.ProductSales{
+				{ProductID: kopiID, Nama: "Kopi", QtyTerjual: 8, Satuan: "pcs", Pendapatan: 32000},
+				{ProductID: berasID, Nama: "Beras", QtyTerjual: 3.5, Satuan: "kg", Pendapatan: 48000},
+				{ProductID: esTehID, Nama: "Es Teh", QtyTerjual: 1, Satuan: "pcs", Pendapatan: 3000},
+			},
+		},
+		{
+			name:        "end date is exclusive",
+			start:       march,
+			end:         time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+			wantRevenue: 20000,
+			wantCount:   1,
+			wantBest:    &models.BestSellerInfo{Nama: "Kopi", QtyTerjual: 4, Satuan: "pcs"},
+			wantLines: []models.ProductSales{
+				{ProductID: kopiID, Nama: "Kopi", QtyTerjual: 4, Satuan: "pcs", Pendapatan: 20000},
+			},
+		},
+		{
+			name:  "no sales",
+			start: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			end:   march,
+		},
+	}
+
+	db := newTestDB(t)
+	ctx := context.Background()
+	seedSales(t, ctx, NewTransactionRepository(db))
+	repo := NewReportRepository(db)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := repo.GetSalesReport(ctx, tt.start, tt.end, tt.attributeToComponents, nil)
+			if err != nil {
+				t.Fatalf("GetSalesReport() error = %v", err)
+			}
+			if report.TotalRevenue != tt.wantRevenue || report.TotalTransaksi != tt.wantCount {
+				t.Errorf("totals = %d/%d, want %d/%d", report.TotalRevenue, report.TotalTransaksi, tt.wantRevenue, tt.wantCount)
+			}
+
+			switch {
+			case tt.wantBest == nil && report.ProdukTerlaris != nil:
+				t.Errorf("ProdukTerlaris = %+v, want nil", report.ProdukTerlaris)
+			case tt.wantBest != nil && (report.ProdukTerlaris == nil || *report.ProdukTerlaris != *tt.wantBest):
+				t.Errorf("ProdukTerlaris = %+v, want %+v", report.ProdukTerlaris, tt.wantBest)
+			}
+
+			if len(report.RincianProduk) != len(tt.wantLines) {
+				t.Fatalf("RincianProduk = %+v, want %+v", report.RincianProduk, tt.wantLines)
+			}
+			for i, want := range tt.wantLines {
+				if report.RincianProduk[i] != want {
+					t.Errorf("RincianProduk[%d] = %+v, want %+v", i, report.RincianProduk[i], want)
+				}
+			}
+		})
+	}
+}
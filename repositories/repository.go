@@ -0,0 +1,334 @@
+// Package repositories defines the data access interfaces used by the
+// services and implements them on PostgreSQL. Package memory implements the
+// same interfaces in memory for tests.
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// ErrInsufficientStock is wrapped by the errors returned when a sale would take
+// the stock of a product below zero
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrInsufficientPoints is wrapped by the errors returned when a sale redeems
+// more loyalty points than the customer has
+var ErrInsufficientPoints = errors.New("insufficient loyalty points")
+
+// IsTransient reports whether err is a failure of the database connection,
+// the database server or the request context rather than a rejection of the
+// data, so the same request may succeed when it is retried
+func IsTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		// Connection exception, transaction rollback (serialization failure
+		// or deadlock), insufficient resources, operator intervention (query
+		// cancelled or server shutting down), system error and internal error
+		case "08", "40", "53", "57", "58", "XX":
+			return true
+		}
+	}
+	return false
+}
+
+// ErrTransferStatus is wrapped by the errors returned when a stock transfer is
+// not in the status a change requires, such as editing a transfer already sent
+var ErrTransferStatus = errors.New("invalid transfer status")
+
+// ErrBillClosed is wrapped by the errors returned when an open bill is
+// changed after it was checked out or cancelled
+var ErrBillClosed = errors.New("open bill is closed")
+
+// ErrBillChanged is wrapped by the errors returned when an open bill is
+// checked out while its items changed after they were priced
+var ErrBillChanged = errors.New("open bill changed during checkout")
+
+// ErrInUse is wrapped by the errors returned when a product or category
+// cannot be deleted or purged because other records still reference it
+var ErrInUse = errors.New("still in use")
+
+// ErrNotDeleted is wrapped by the errors returned when a product or category
+// is restored or purged without being deleted first
+var ErrNotDeleted = errors.New("not deleted")
+
+// ErrReassignTarget is wrapped by the errors returned when the products of a
+// deleted category cannot be moved to the category given for them
+var ErrReassignTarget = errors.New("invalid reassignment target")
+
+// ProductRepository handles data access for products and bundle components
+type ProductRepository interface {
+	// GetAll returns the products matching the filter; deleted products only
+	// when the filter includes them
+	GetAll(ctx context.Context, filter models.ProductFilter) ([]models.Product, error)
+	// GetByID returns "Product with ID %d not found" when the product does not
+	// exist; deleted products are returned with DeletedAt set
+	GetByID(ctx context.Context, id int) (*models.Product, error)
+	// GetByIDs returns the products with the given IDs in one query, ordered
+	// by ID, deleted ones included; IDs that do not exist are left out
+	GetByIDs(ctx context.Context, ids []int) ([]models.Product, error)
+	// Create adds a product and records it as version 1 of its history,
+	// changed by changedBy
+	Create(ctx context.Context, product models.Product, changedBy string) (*models.Product, error)
+	// Update changes a product that is not deleted and, when anything
+	// changed, records its new state as the next version of its history in
+	// the same transaction
+	Update(ctx context.Context, id int, product models.Product, changedBy string) (*models.Product, error)
+	// Delete soft deletes a product; it refuses while a bundle that is not
+	// deleted contains the product
+	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a product. A product whose category was
+	// deleted meanwhile is restored without a category, recorded as its next
+	// version by changedBy.
+	Restore(ctx context.Context, id int, changedBy string) (*models.Product, error)
+	// Purge permanently removes a deleted product with its version history;
+	// it refuses when sales, bundles, transfers or open bills reference the
+	// product
+	Purge(ctx context.Context, id int) error
+	// GetHistory returns the versions of a product, oldest first
+	GetHistory(ctx context.Context, productID int) ([]models.ProductVersion, error)
+	GetComponents(ctx context.Context, bundleID int) ([]models.BundleComponent, error)
+	// GetComponentsByBundleIDs returns the components of several bundles in
+	// one query, keyed by bundle ID
+	GetComponentsByBundleIDs(ctx context.Context, bundleIDs []int) (map[int][]models.BundleComponent, error)
+	SetComponents(ctx context.Context, bundleID int, components []models.BundleComponent) error
+}
+
+// ProductVariantRepository handles data access for product variants
+type ProductVariantRepository interface {
+	GetByProductID(ctx context.Context, productID int) ([]models.ProductVariant, error)
+	GetByID(ctx context.Context, id int) (*models.ProductVariant, error)
+	// GetByIDs returns the variants with the given IDs in one query, ordered
+	// by ID; IDs that do not exist are left out
+	GetByIDs(ctx context.Context, ids []int) ([]models.ProductVariant, error)
+	Create(ctx context.Context, variant models.ProductVariant) (*models.ProductVariant, error)
+	Update(ctx context.Context, productID, id int, variant models.ProductVariant) (*models.ProductVariant, error)
+	Delete(ctx context.Context, productID, id int) error
+}
+
+// UnitRepository handles data access for units of measure and product unit conversions
+type UnitRepository interface {
+	GetAll(ctx context.Context) ([]models.Unit, error)
+	GetByCode(ctx context.Context, code string) (*models.Unit, error)
+	Create(ctx context.Context, unit models.Unit) (*models.Unit, error)
+	Update(ctx context.Context, code string, unit models.Unit) (*models.Unit, error)
+	GetConversions(ctx context.Context, productID int) ([]models.UnitConversion, error)
+	// GetConversion returns nil without error when the product has no such packaging unit
+	GetConversion(ctx context.Context, productID int, unit string) (*models.UnitConversion, error)
+	// GetConversionsByProductIDs returns the packaging units of several
+	// products in one query
+	GetConversionsByProductIDs(ctx context.Context, productIDs []int) ([]models.UnitConversion, error)
+	SaveConversion(ctx context.Context, conversion models.UnitConversion) (*models.UnitConversion, error)
+	DeleteConversion(ctx context.Context, productID int, unit string) error
+}
+
+// CategoryRepository handles data access for categories
+type CategoryRepository interface {
+	// GetAll returns the categories, deleted ones only when the filter
+	// includes them
+	GetAll(ctx context.Context, filter models.CategoryFilter) ([]models.Category, error)
+	// GetByID returns a category; deleted categories are returned with
+	// DeletedAt set
+	GetByID(ctx context.Context, id int) (*models.Category, error)
+	Create(ctx context.Context, category models.Category) (*models.Category, error)
+	// Update changes a category that is not deleted
+	Update(ctx context.Context, id int, category models.Category) (*models.Category, error)
+	// Delete soft deletes a category. Without reassignTo it refuses when the
+	// category has products that are not deleted; with it, all products of
+	// the category move to that category first, each recorded as the next
+	// version of the product by changedBy.
+	Delete(ctx context.Context, id int, reassignTo *int, changedBy string) error
+	// Restore undoes the deletion of a category
+	Restore(ctx context.Context, id int) (*models.Category, error)
+	// Purge permanently removes a deleted category; it refuses when products
+	// of the category have been sold, and the other products lose their
+	// category, each recorded as the next version of the product by
+	// changedBy
+	Purge(ctx context.Context, id int, changedBy string) error
+}
+
+// OutletRepository handles data access for outlets and the stock and prices
+// of products at each outlet. The stock of products and variants is the total
+// over all outlets and changes with the stock of the outlets.
+type OutletRepository interface {
+	GetAll(ctx context.Context) ([]models.Outlet, error)
+	// GetByID returns "Outlet with ID %d not found" when the outlet does not exist
+	GetByID(ctx context.Context, id int) (*models.Outlet, error)
+	Create(ctx context.Context, outlet models.Outlet) (*models.Outlet, error)
+	Update(ctx context.Context, id int, outlet models.Outlet) (*models.Outlet, error)
+	// Delete removes an outlet with its stock; outlets with sales or transfers
+	// cannot be deleted
+	Delete(ctx context.Context, id int) error
+	// GetProducts returns the stock rows of an outlet, limited to productIDs
+	// unless it is nil, ordered by product and variant
+	GetProducts(ctx context.Context, outletID int, productIDs []int) ([]models.OutletProduct, error)
+	// SetProduct sets the stock and price override of a product or variant at
+	// an outlet; the total stock changes by the difference
+	SetProduct(ctx context.Context, product models.OutletProduct) (*models.OutletProduct, error)
+}
+
+// TransferRepository handles data access for stock transfers between outlets.
+// Sending a transfer takes its lines off the stock of the source outlet and
+// receiving adds what arrived to the stock of the destination, atomically
+// with the change of status, the way TransactionRepository posts sales.
+type TransferRepository interface {
+	// GetAll returns the transfers matching filter, newest first, without
+	// their lines
+	GetAll(ctx context.Context, filter models.TransferFilter) ([]models.StockTransfer, error)
+	// GetByID returns "Transfer with ID %d not found" when the transfer does not exist
+	GetByID(ctx context.Context, id int) (*models.StockTransfer, error)
+	// Create adds a draft transfer with its lines
+	Create(ctx context.Context, transfer models.StockTransfer) (*models.StockTransfer, error)
+	// Update replaces the outlets, note and lines of a draft transfer
+	Update(ctx context.Context, id int, transfer models.StockTransfer) (*models.StockTransfer, error)
+	// Delete removes a draft transfer
+	Delete(ctx context.Context, id int) error
+	// Send takes the lines of a draft transfer off the stock of the source
+	// outlet and puts the transfer in transit; stock may not go negative
+	Send(ctx context.Context, id int) (*models.StockTransfer, error)
+	// Receive adds the quantities that arrived to the stock of the destination
+	// outlet and receives the transfer when every line arrived in full or the
+	// receipt closes it, recording what did not arrive as discrepancies
+	Receive(ctx context.Context, id int, receipt models.TransferReceipt) (*models.StockTransfer, error)
+}
+
+// PriceListRepository handles data access for price lists and the price
+// rules that price products for a price list, a quantity or a time
+type PriceListRepository interface {
+	GetAll(ctx context.Context) ([]models.PriceList, error)
+	// GetByID returns "Price list with ID %d not found" when the list does not exist
+	GetByID(ctx context.Context, id int) (*models.PriceList, error)
+	Create(ctx context.Context, list models.PriceList) (*models.PriceList, error)
+	Update(ctx context.Context, id int, list models.PriceList) (*models.PriceList, error)
+	// Delete removes a price list with its rules; its customers buy at
+	// retail prices again
+	Delete(ctx context.Context, id int) error
+	// GetRules returns the rules matching filter ordered by product, variant,
+	// price list and minimum quantity
+	GetRules(ctx context.Context, filter models.PriceRuleFilter) ([]models.PriceRule, error)
+	// GetRuleByID returns "Price rule with ID %d not found" when the rule does not exist
+	GetRuleByID(ctx context.Context, id int) (*models.PriceRule, error)
+	CreateRule(ctx context.Context, rule models.PriceRule) (*models.PriceRule, error)
+	UpdateRule(ctx context.Context, id int, rule models.PriceRule) (*models.PriceRule, error)
+	// DeleteRule removes a rule; the sales priced with it keep their prices
+	DeleteRule(ctx context.Context, id int) error
+	// GetRulesInEffect returns the rules of several products in one query
+	// that have started and not ended at the given time; their daily windows
+	// are left to the caller
+	GetRulesInEffect(ctx context.Context, productIDs []int, at time.Time) ([]models.PriceRule, error)
+}
+
+// OpenBillRepository handles data access for open bills. The items of a bill
+// that reserves stock take their stock off the outlet when they are added and
+// give it back when they are changed, removed or the bill is closed, atomically
+// with the change of the bill. Checking a bill out gives its reserved stock
+// back and creates its transaction the way TransactionRepository.Create does,
+// in one database transaction.
+type OpenBillRepository interface {
+	// GetAll returns the bills matching filter, newest first, without their
+	// items
+	GetAll(ctx context.Context, filter models.OpenBillFilter) ([]models.OpenBill, error)
+	// GetByID returns "Open bill with ID %d not found" when the bill does not exist
+	GetByID(ctx context.Context, id int) (*models.OpenBill, error)
+	// Create opens a bill with its items
+	Create(ctx context.Context, bill models.OpenBill) (*models.OpenBill, error)
+	// AddItem adds an item to an open bill
+	AddItem(ctx context.Context, billID int, item models.OpenBillItem) (*models.OpenBillItem, error)
+	// UpdateItem replaces an item of an open bill
+	UpdateItem(ctx context.Context, billID, itemID int, item models.OpenBillItem) (*models.OpenBillItem, error)
+	// RemoveItem removes an item from an open bill
+	RemoveItem(ctx context.Context, billID, itemID int) error
+	// Cancel closes an open bill without a sale
+	Cancel(ctx context.Context, id int) error
+	// Checkout creates the transaction of an open bill and closes the bill.
+	// items are the items the transaction was priced from; it fails with
+	// ErrBillChanged when the bill no longer holds exactly those items.
+	Checkout(ctx context.Context, id int, items []models.OpenBillItem, transaction models.Transaction) (*models.Transaction, error)
+}
+
+// TransactionRepository handles data access for transactions. Creating a
+// transaction takes the sold quantities off the stock of its outlet and
+// deleting one returns them, atomically with the transaction itself. The
+// loyalty points a sale earns and redeems are booked on the points ledger of
+// its customer the same way, and deleting (voiding) the sale books them back.
+type TransactionRepository interface {
+	Create(ctx context.Context, transaction models.Transaction) (*models.Transaction, error)
+	CreateOffline(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, []models.StockConflict, error)
+	// GetAll returns the transactions matching filter, newest first, without
+	// their details
+	GetAll(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error)
+	GetByID(ctx context.Context, id int) (*models.Transaction, error)
+	Delete(ctx context.Context, id int) error
+	// GetCustomerLifetimeValue sums up the transactions of a customer
+	GetCustomerLifetimeValue(ctx context.Context, customerID int) (*models.CustomerLifetimeValue, error)
+}
+
+// CustomerRepository handles data access for customers
+type CustomerRepository interface {
+	GetAll(ctx context.Context, filter models.CustomerFilter) ([]models.Customer, error)
+	// GetByID returns "Customer with ID %d not found" when the customer does not exist
+	GetByID(ctx context.Context, id int) (*models.Customer, error)
+	Create(ctx context.Context, customer models.Customer) (*models.Customer, error)
+	Update(ctx context.Context, id int, customer models.Customer) (*models.Customer, error)
+	// Delete removes a customer; their transactions are kept without a customer
+	Delete(ctx context.Context, id int) error
+}
+
+// LoyaltyRepository handles data access for the loyalty points ledger; points
+// are earned, redeemed and voided through TransactionRepository
+type LoyaltyRepository interface {
+	// GetLedger returns the ledger entries of a customer, newest first
+	GetLedger(ctx context.Context, customerID int) ([]models.PointsEntry, error)
+	// ExpirePoints books the expiry of the points left in lots that expired
+	// and returns the number of points expired
+	ExpirePoints(ctx context.Context) (int, error)
+}
+
+// ReportRepository handles data access for reports
+type ReportRepository interface {
+	// GetSalesReport sums up the sales of outletIDs, or of all outlets when
+	// outletIDs is nil
+	GetSalesReport(ctx context.Context, startDate, endDate time.Time, attributeToComponents bool, outletIDs []int) (*models.SalesReport, error)
+}
+
+// AuditRepository handles data access for the audit log, which entries are
+// only ever added to
+type AuditRepository interface {
+	Create(ctx context.Context, entry models.AuditEntry) (*models.AuditEntry, error)
+	// GetAll returns the page of entries matching the filter, newest first
+	GetAll(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// IdempotencyRepository handles data access for idempotency keys; a key is
+// scoped to its owner, the user who sent it
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, owner, key, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, owner, key string, statusCode int, responseBody string) error
+	Release(ctx context.Context, owner, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// HealthRepository checks the database connection and reports pool statistics
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	Stats() models.PoolStats
+}
@@ -1,75 +1,258 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/lib/pq"
 
 	"kasir-api/models"
 )
 
-// TransactionRepository handles data access for transactions
-type TransactionRepository struct {
+// transactionRepository is the PostgreSQL implementation of TransactionRepository
+type transactionRepository struct {
 	db *sql.DB
 }
 
 // NewTransactionRepository creates a new TransactionRepository
-func NewTransactionRepository(db *sql.DB) *TransactionRepository {
-	return &TransactionRepository{db: db}
+func NewTransactionRepository(db *sql.DB) TransactionRepository {
+	return &transactionRepository{db: db}
 }
 
-// Create creates a new transaction with details
-func (r *TransactionRepository) Create(transaction models.Transaction) (*models.Transaction, error) {
-	tx, err := r.db.Begin()
+// Create creates a new transaction with details and books its loyalty points
+func (r *transactionRepository) Create(ctx context.Context, transaction models.Transaction) (*models.Transaction, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	// Insert transaction
-	err = tx.QueryRow(
-		"INSERT INTO transactions (total_amount) VALUES ($1) RETURNING id, created_at",
-		transaction.TotalAmount,
+	if err := createTransaction(ctx, tx, &transaction); err != nil {
+		slog.DebugContext(ctx, "rolling back transaction", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// createTransaction inserts a sale with its details, takes the sold
+// quantities off the stock of its outlet and books its loyalty points within
+// tx
+func createTransaction(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO transactions (outlet_id, total_amount, customer_id, price_list_id, points_earned, points_redeemed, points_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, transaction.OutletID, transaction.TotalAmount, transaction.CustomerID, transaction.PriceListID, transaction.PointsEarned, transaction.PointsRedeemed, transaction.PointsAmount,
 	).Scan(&transaction.ID, &transaction.CreatedAt)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if _, err := insertDetails(ctx, tx, transaction, false); err != nil {
+		return err
 	}
+	return bookPoints(ctx, tx, transaction)
+}
 
-	// Prepare statement for inserting transaction details (more efficient for multiple inserts)
-	stmt, err := tx.Prepare(`
-		INSERT INTO transaction_details 
-		(transaction_id, product_id, quantity, subtotal)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
-	`)
+// CreateOffline creates a transaction that was made while the POS was offline,
+// keeping its client ID and original timestamp. A transaction whose client ID
+// was already synced is reported as duplicate and not inserted again. Stock is
+// allowed to go negative; every product that did is returned as a conflict.
+func (r *transactionRepository) CreateOffline(ctx context.Context, transaction models.Transaction) (*models.Transaction, bool, []models.StockConflict, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return nil, false, nil, err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	// Insert transaction details using prepared statement
-	for i := range transaction.Details {
-		transaction.Details[i].TransactionID = transaction.ID
-		err = stmt.QueryRow(
-			transaction.ID,
-			transaction.Details[i].ProductID,
-			transaction.Details[i].Quantity,
-			transaction.Details[i].Subtotal,
-		).Scan(&transaction.Details[i].ID)
+	// Insert transaction unless its client ID is already known
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO transactions (outlet_id, total_amount, client_id, created_at, synced_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (client_id) DO NOTHING
+		RETURNING id, created_at
+	`, transaction.OutletID, transaction.TotalAmount, transaction.ClientID, transaction.CreatedAt).Scan(&transaction.ID, &transaction.CreatedAt)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx,
+			"SELECT id, outlet_id, total_amount, created_at FROM transactions WHERE client_id = $1",
+			transaction.ClientID,
+		).Scan(&transaction.ID, &transaction.OutletID, &transaction.TotalAmount, &transaction.CreatedAt)
 		if err != nil {
-			return nil, err
+			return nil, false, nil, err
+		}
+		transaction.Details = nil
+		return &transaction, true, nil, nil
+	}
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	conflicts, err := insertDetails(ctx, tx, &transaction, true)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	// Keep the conflicts for review
+	if len(conflicts) > 0 {
+		productIDs := make([]int64, len(conflicts))
+		variantIDs := make([]sql.NullInt64, len(conflicts))
+		quantities := make([]float64, len(conflicts))
+		stockAfter := make([]float64, len(conflicts))
+		for i, c := range conflicts {
+			slog.DebugContext(ctx, "stock conflict",
+				"product_id", c.ProductID,
+				"quantity", c.Quantity,
+				"stock_after", c.StockAfter,
+			)
+			productIDs[i] = int64(c.ProductID)
+			if c.VariantID != nil {
+				variantIDs[i] = sql.NullInt64{Int64: int64(*c.VariantID), Valid: true}
+			}
+			quantities[i] = c.Quantity
+			stockAfter[i] = c.StockAfter
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO stock_conflicts (transaction_id, product_id, variant_id, quantity, stock_after)
+			SELECT $1, c.product_id, c.variant_id, c.quantity, c.stock_after
+			FROM unnest($2::int[], $3::int[], $4::numeric[], $5::numeric[]) AS c(product_id, variant_id, quantity, stock_after)
+		`, transaction.ID, pq.Array(productIDs), pq.Array(variantIDs), pq.Array(quantities), pq.Array(stockAfter))
+		if err != nil {
+			return nil, false, nil, err
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
+		return nil, false, nil, err
+	}
+
+	return &transaction, false, conflicts, nil
+}
+
+// insertDetails inserts the details of a transaction with their bundle
+// components and takes the sold quantities off the stock of its outlet. It takes the same
+// number of statements whatever the number of lines: the detail IDs are
+// reserved up front so details and components are inserted with one
+// multi-row statement each. When allowNegativeStock is set, stock shortfalls
+// are returned as conflicts instead of failing the transaction.
+func insertDetails(ctx context.Context, tx *sql.Tx, transaction *models.Transaction, allowNegativeStock bool) ([]models.StockConflict, error) {
+	details := transaction.Details
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT nextval(pg_get_serial_sequence('transaction_details', 'id')) FROM generate_series(1, $1)",
+		len(details),
+	)
+	if err != nil {
 		return nil, err
 	}
+	ids := make([]int64, 0, len(details))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
 
-	return &transaction, nil
+	var (
+		productIDs     = make([]int64, len(details))
+		variantIDs     = make([]sql.NullInt64, len(details))
+		quantities     = make([]float64, len(details))
+		units          = make([]string, len(details))
+		unitQuantities = make([]float64, len(details))
+		subtotals      = make([]int64, len(details))
+		priceRuleIDs   = make([]sql.NullInt64, len(details))
+
+		componentDetailIDs  []int64
+		componentProductIDs []int64
+		componentQuantities []float64
+		componentSubtotals  []int64
+	)
+	for i := range details {
+		d := &details[i]
+		d.ID = int(ids[i])
+		d.TransactionID = transaction.ID
+
+		productIDs[i] = int64(d.ProductID)
+		if d.VariantID != nil {
+			variantIDs[i] = sql.NullInt64{Int64: int64(*d.VariantID), Valid: true}
+		}
+		quantities[i] = d.Quantity
+		units[i] = d.Unit
+		unitQuantities[i] = d.UnitQuantity
+		subtotals[i] = int64(d.Subtotal)
+		if d.PriceRuleID != nil {
+			priceRuleIDs[i] = sql.NullInt64{Int64: int64(*d.PriceRuleID), Valid: true}
+		}
+
+		for _, c := range d.Components {
+			componentDetailIDs = append(componentDetailIDs, ids[i])
+			componentProductIDs = append(componentProductIDs, int64(c.ProductID))
+			componentQuantities = append(componentQuantities, c.Quantity)
+			componentSubtotals = append(componentSubtotals, int64(c.Subtotal))
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transaction_details
+		(id, transaction_id, product_id, variant_id, quantity, unit, unit_quantity, subtotal, price_rule_id)
+		SELECT d.id, $1, d.product_id, d.variant_id, d.quantity, d.unit, d.unit_quantity, d.subtotal, d.price_rule_id
+		FROM unnest($2::int[], $3::int[], $4::int[], $5::numeric[], $6::text[], $7::numeric[], $8::int[], $9::int[])
+			AS d(id, product_id, variant_id, quantity, unit, unit_quantity, subtotal, price_rule_id)
+	`, transaction.ID, pq.Array(ids), pq.Array(productIDs), pq.Array(variantIDs),
+		pq.Array(quantities), pq.Array(units), pq.Array(unitQuantities), pq.Array(subtotals), pq.Array(priceRuleIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	// Bundles take their stock from the components
+	if len(componentDetailIDs) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO transaction_detail_components (transaction_detail_id, product_id, quantity, subtotal)
+			SELECT * FROM unnest($1::int[], $2::int[], $3::numeric[], $4::int[])
+		`, pq.Array(componentDetailIDs), pq.Array(componentProductIDs), pq.Array(componentQuantities), pq.Array(componentSubtotals))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return deductStock(ctx, tx, details, transaction.OutletID, allowNegativeStock)
 }
 
-// GetAll returns all transactions
-func (r *TransactionRepository) GetAll() ([]models.Transaction, error) {
-	rows, err := r.db.Query("SELECT id, total_amount, created_at FROM transactions ORDER BY created_at DESC")
+// GetAll returns the transactions matching filter, newest first
+func (r *transactionRepository) GetAll(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error) {
+	query := `SELECT id, outlet_id, client_id, customer_id, price_list_id, total_amount, points_earned, points_redeemed, points_amount, created_at
+		FROM transactions WHERE 1=1`
+	var args []interface{}
+	argIndex := 1
+
+	if filter.CustomerID > 0 {
+		query += fmt.Sprintf(" AND customer_id = $%d", argIndex)
+		args = append(args, filter.CustomerID)
+		argIndex++
+	}
+
+	if filter.OutletIDs != nil {
+		query += fmt.Sprintf(" AND outlet_id = ANY($%d)", argIndex)
+		args = append(args, pq.Array(filter.OutletIDs))
+		argIndex++
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,31 +261,57 @@ func (r *TransactionRepository) GetAll() ([]models.Transaction, error) {
 	var transactions []models.Transaction
 	for rows.Next() {
 		var t models.Transaction
-		if err := rows.Scan(&t.ID, &t.TotalAmount, &t.CreatedAt); err != nil {
+		var clientID sql.NullString
+		var customerID, priceListID sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.OutletID, &clientID, &customerID, &priceListID, &t.TotalAmount, &t.PointsEarned, &t.PointsRedeemed, &t.PointsAmount, &t.CreatedAt); err != nil {
 			return nil, err
 		}
+		if clientID.Valid {
+			t.ClientID = &clientID.String
+		}
+		if customerID.Valid {
+			id := int(customerID.Int64)
+			t.CustomerID = &id
+		}
+		t.PriceListID = nullIntPtr(priceListID)
 		transactions = append(transactions, t)
 	}
 	return transactions, nil
 }
 
 // GetByID returns a transaction by ID with its details
-func (r *TransactionRepository) GetByID(id int) (*models.Transaction, error) {
+func (r *transactionRepository) GetByID(ctx context.Context, id int) (*models.Transaction, error) {
 	var t models.Transaction
-	err := r.db.QueryRow(
-		"SELECT id, total_amount, created_at FROM transactions WHERE id = $1",
-		id,
-	).Scan(&t.ID, &t.TotalAmount, &t.CreatedAt)
+	var clientID sql.NullString
+	var customerID, priceListID sql.NullInt64
+	var pointsExpireAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, outlet_id, client_id, customer_id, price_list_id, total_amount, points_earned, points_redeemed, points_amount, created_at,
+			(SELECT expires_at FROM loyalty_points_ledger l WHERE l.transaction_id = t.id AND l.type = 'earn' ORDER BY l.id LIMIT 1)
+		FROM transactions t WHERE id = $1
+	`, id).Scan(&t.ID, &t.OutletID, &clientID, &customerID, &priceListID, &t.TotalAmount, &t.PointsEarned, &t.PointsRedeemed, &t.PointsAmount, &t.CreatedAt, &pointsExpireAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("Transaction with ID %d not found", id)
 		}
 		return nil, err
 	}
+	if clientID.Valid {
+		t.ClientID = &clientID.String
+	}
+	if customerID.Valid {
+		customer := int(customerID.Int64)
+		t.CustomerID = &customer
+	}
+	t.PriceListID = nullIntPtr(priceListID)
+	if pointsExpireAt.Valid {
+		t.PointsExpireAt = &pointsExpireAt.Time
+	}
 
 	// Get transaction details
-	rows, err := r.db.Query(
-		"SELECT id, transaction_id, product_id, quantity, subtotal FROM transaction_details WHERE transaction_id = $1",
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, transaction_id, product_id, variant_id, quantity, COALESCE(unit, ''), COALESCE(unit_quantity, quantity), subtotal, price_rule_id
+		FROM transaction_details WHERE transaction_id = $1`,
 		id,
 	)
 	if err != nil {
@@ -112,18 +321,124 @@ func (r *TransactionRepository) GetByID(id int) (*models.Transaction, error) {
 
 	for rows.Next() {
 		var d models.TransactionDetail
-		if err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.Quantity, &d.Subtotal); err != nil {
+		var variantID, priceRuleID sql.NullInt64
+		if err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &variantID, &d.Quantity, &d.Unit, &d.UnitQuantity, &d.Subtotal, &priceRuleID); err != nil {
 			return nil, err
 		}
+		if variantID.Valid {
+			v := int(variantID.Int64)
+			d.VariantID = &v
+		}
+		d.PriceRuleID = nullIntPtr(priceRuleID)
 		t.Details = append(t.Details, d)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Get the components of sold bundles
+	componentRows, err := r.db.QueryContext(ctx, `
+		SELECT c.transaction_detail_id, c.product_id, c.quantity, c.subtotal
+		FROM transaction_detail_components c
+		JOIN transaction_details td ON td.id = c.transaction_detail_id
+		WHERE td.transaction_id = $1
+		ORDER BY c.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer componentRows.Close()
+
+	for componentRows.Next() {
+		var detailID int
+		var c models.TransactionDetailComponent
+		if err := componentRows.Scan(&detailID, &c.ProductID, &c.Quantity, &c.Subtotal); err != nil {
+			return nil, err
+		}
+		for i := range t.Details {
+			if t.Details[i].ID == detailID {
+				t.Details[i].Components = append(t.Details[i].Components, c)
+			}
+		}
+	}
 
 	return &t, nil
 }
 
-// Delete deletes a transaction by ID
-func (r *TransactionRepository) Delete(id int) error {
-	result, err := r.db.Exec("DELETE FROM transactions WHERE id = $1", id)
+// Delete deletes a transaction by ID, returns its items to the stock of its
+// outlet and books back its loyalty points
+func (r *transactionRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := voidPoints(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Restore stock of the sold products, bundle components and variants
+	_, err = tx.ExecContext(ctx, `
+		UPDATE products p SET stock = p.stock + d.quantity
+		FROM (
+			SELECT product_id, SUM(quantity) AS quantity
+			FROM (
+				SELECT td.product_id, td.quantity
+				FROM transaction_details td
+				WHERE td.transaction_id = $1 AND td.variant_id IS NULL
+					AND NOT EXISTS (SELECT 1 FROM transaction_detail_components c WHERE c.transaction_detail_id = td.id)
+				UNION ALL
+				SELECT c.product_id, c.quantity
+				FROM transaction_detail_components c
+				JOIN transaction_details td ON td.id = c.transaction_detail_id
+				WHERE td.transaction_id = $1
+			) sold
+			GROUP BY product_id
+		) d
+		WHERE p.id = d.product_id
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE product_variants v SET stock = v.stock + d.quantity
+		FROM (
+			SELECT variant_id, SUM(quantity) AS quantity
+			FROM transaction_details
+			WHERE transaction_id = $1 AND variant_id IS NOT NULL
+			GROUP BY variant_id
+		) d
+		WHERE v.id = d.variant_id
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outlet_products (outlet_id, product_id, variant_id, stock)
+		SELECT t.outlet_id, sold.product_id, sold.variant_id, SUM(sold.quantity)
+		FROM (
+			SELECT td.product_id, td.variant_id, td.quantity
+			FROM transaction_details td
+			WHERE td.transaction_id = $1
+				AND NOT EXISTS (SELECT 1 FROM transaction_detail_components c WHERE c.transaction_detail_id = td.id)
+			UNION ALL
+			SELECT c.product_id, NULL, c.quantity
+			FROM transaction_detail_components c
+			JOIN transaction_details td ON td.id = c.transaction_detail_id
+			WHERE td.transaction_id = $1
+		) sold
+		JOIN transactions t ON t.id = $1
+		GROUP BY t.outlet_id, sold.product_id, sold.variant_id
+		`+outletStockConflict+` DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM transactions WHERE id = $1", id)
 	if err != nil {
 		return err
 	}
@@ -131,5 +446,187 @@ func (r *TransactionRepository) Delete(id int) error {
 	if rowsAffected == 0 {
 		return fmt.Errorf("Transaction with ID %d not found", id)
 	}
-	return nil
+
+	return tx.Commit()
+}
+
+// GetCustomerLifetimeValue sums up the transactions of a customer
+func (r *transactionRepository) GetCustomerLifetimeValue(ctx context.Context, customerID int) (*models.CustomerLifetimeValue, error) {
+	value := models.CustomerLifetimeValue{CustomerID: customerID}
+	var first, last sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(total_amount), 0), MIN(created_at), MAX(created_at)
+		FROM transactions
+		WHERE customer_id = $1
+	`, customerID).Scan(&value.TotalTransactions, &value.TotalSpent, &first, &last)
+	if err != nil {
+		return nil, err
+	}
+	if first.Valid {
+		value.FirstPurchaseAt = &first.Time
+		value.LastPurchaseAt = &last.Time
+	}
+	return &value, nil
+}
+
+// stockDeduction is the quantity sold from one stock row: a variant, or a
+// product when VariantID is nil
+type stockDeduction struct {
+	ProductID int
+	VariantID *int
+	Quantity  float64
+}
+
+// soldQuantities adds up the quantities the details take from each stock row,
+// in the order the rows first appear; bundles take from their components
+func soldQuantities(details []models.TransactionDetail) []stockDeduction {
+	var sold []stockDeduction
+	index := make(map[[2]int]int)
+	add := func(productID int, variantID *int, quantity float64) {
+		key := [2]int{productID, 0}
+		if variantID != nil {
+			key = [2]int{0, *variantID}
+		}
+		if i, ok := index[key]; ok {
+			sold[i].Quantity += quantity
+			return
+		}
+		index[key] = len(sold)
+		sold = append(sold, stockDeduction{ProductID: productID, VariantID: variantID, Quantity: quantity})
+	}
+
+	for _, d := range details {
+		if len(d.Components) > 0 {
+			for _, c := range d.Components {
+				add(c.ProductID, nil, c.Quantity)
+			}
+			continue
+		}
+		add(d.ProductID, d.VariantID, d.Quantity)
+	}
+	return sold
+}
+
+// deductStock takes the sold quantities off the variant stock, or the product
+// stock when no variant was selected, and off the stock of the outlet, with
+// one statement per table. Stock of the outlet that goes negative is an
+// error, or a conflict per stock row when allowNegative is set.
+func deductStock(ctx context.Context, tx *sql.Tx, details []models.TransactionDetail, outletID int, allowNegative bool) ([]models.StockConflict, error) {
+	sold := soldQuantities(details)
+
+	var productIDs, variantIDs []int64
+	var productQuantities, variantQuantities []float64
+	for _, s := range sold {
+		if s.VariantID != nil {
+			variantIDs = append(variantIDs, int64(*s.VariantID))
+			variantQuantities = append(variantQuantities, s.Quantity)
+		} else {
+			productIDs = append(productIDs, int64(s.ProductID))
+			productQuantities = append(productQuantities, s.Quantity)
+		}
+	}
+
+	// update subtracts the quantities and returns the new stock by row ID
+	update := func(table string, ids []int64, quantities []float64) (map[int]float64, error) {
+		stock := make(map[int]float64, len(ids))
+		if len(ids) == 0 {
+			return stock, nil
+		}
+		rows, err := tx.QueryContext(ctx, `
+			UPDATE `+table+` t SET stock = t.stock - s.quantity
+			FROM unnest($1::int[], $2::numeric[]) AS s(id, quantity)
+			WHERE t.id = s.id
+			RETURNING t.id, t.stock
+		`, pq.Array(ids), pq.Array(quantities))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			var after float64
+			if err := rows.Scan(&id, &after); err != nil {
+				return nil, err
+			}
+			stock[id] = after
+		}
+		return stock, rows.Err()
+	}
+
+	productStock, err := update("products", productIDs, productQuantities)
+	if err != nil {
+		return nil, err
+	}
+	variantStock, err := update("product_variants", variantIDs, variantQuantities)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range sold {
+		_, ok := productStock[s.ProductID]
+		if s.VariantID != nil {
+			_, ok = variantStock[*s.VariantID]
+		}
+		if !ok {
+			return nil, fmt.Errorf("Product with ID %d not found", s.ProductID)
+		}
+	}
+
+	// The outlet may not have held the product yet
+	outletProductIDs := make([]int64, len(sold))
+	outletVariantIDs := make([]sql.NullInt64, len(sold))
+	outletQuantities := make([]float64, len(sold))
+	for i, s := range sold {
+		outletProductIDs[i] = int64(s.ProductID)
+		if s.VariantID != nil {
+			outletVariantIDs[i] = sql.NullInt64{Int64: int64(*s.VariantID), Valid: true}
+		}
+		outletQuantities[i] = -s.Quantity
+	}
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO outlet_products (outlet_id, product_id, variant_id, stock)
+		SELECT $1, s.product_id, s.variant_id, s.quantity
+		FROM unnest($2::int[], $3::int[], $4::numeric[]) AS s(product_id, variant_id, quantity)
+		`+outletStockConflict+` DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock
+		RETURNING product_id, COALESCE(variant_id, 0), stock
+	`, outletID, pq.Array(outletProductIDs), pq.Array(outletVariantIDs), pq.Array(outletQuantities))
+	if err != nil {
+		return nil, err
+	}
+	outletStock := make(map[[2]int]float64, len(sold))
+	for rows.Next() {
+		var key [2]int
+		var after float64
+		if err := rows.Scan(&key[0], &key[1], &after); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		outletStock[key] = after
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var conflicts []models.StockConflict
+	for _, s := range sold {
+		key := [2]int{s.ProductID, 0}
+		if s.VariantID != nil {
+			key[1] = *s.VariantID
+		}
+		stock := outletStock[key]
+		if stock >= 0 {
+			continue
+		}
+		if !allowNegative {
+			return nil, fmt.Errorf("%w for product with ID %d", ErrInsufficientStock, s.ProductID)
+		}
+		conflicts = append(conflicts, models.StockConflict{
+			ProductID:  s.ProductID,
+			VariantID:  s.VariantID,
+			Quantity:   s.Quantity,
+			StockAfter: stock,
+		})
+	}
+	return conflicts, nil
 }
@@ -0,0 +1,386 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+func TestPostgresTransactionRepositoryCreate(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransactionRepository(db)
+
+	created, err := repo.Create(ctx, models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		TotalAmount: 42000,
+		Details: []models.TransactionDetail{
+			{ProductID: kopiID, Quantity: 6, Unit: "box", UnitQuantity: 1, Subtotal: 30000},
+			{ProductID: esTehID, VariantID: intPtr(2), Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000},
+			{ProductID: paketID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 15000, Components: []models.TransactionDetailComponent{
+				{ProductID: kopiID, Quantity: 2, Subtotal: 7500},
+				{ProductID: berasID, Quantity: 0.5, Subtotal: 7500},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID == 0 || created.CreatedAt.IsZero() || created.Details[2].ID == 0 {
+		t.Errorf("Create() = %+v, want IDs and created_at set", created)
+	}
+
+	if got := stockOf(t, db, kopiID); got != 2 {
+		t.Errorf("Kopi stock = %v, want 2", got)
+	}
+	if got := stockOf(t, db, berasID); got != 4.5 {
+		t.Errorf("Beras stock = %v, want 4.5", got)
+	}
+	if v, _ := NewProductVariantRepository(db).GetByID(ctx, 2); v.Stock != 1 {
+		t.Errorf("variant L stock = %v, want 1", v.Stock)
+	}
+	if got := stockOf(t, db, esTehID); got != 0 {
+		t.Errorf("Es Teh product stock = %v, want 0 (variants hold the stock)", got)
+	}
+
+	got, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if len(got.Details) != 3 || got.Details[0].Unit != "box" || got.Details[0].UnitQuantity != 1 {
+		t.Fatalf("GetByID() details = %+v", got.Details)
+	}
+	if got.Details[1].VariantID == nil || *got.Details[1].VariantID != 2 {
+		t.Errorf("variant of line 2 = %v, want 2", got.Details[1].VariantID)
+	}
+	if components := got.Details[2].Components; len(components) != 2 || components[1].Quantity != 0.5 {
+		t.Errorf("components of line 3 = %+v", components)
+	}
+	if got.ClientID != nil {
+		t.Errorf("ClientID = %q, want nil", *got.ClientID)
+	}
+}
+
+func TestPostgresTransactionRepositoryCreateRollback(t *testing.T) {
+	tests := []struct {
+		name    string
+		second  models.TransactionDetail
+		wantErr string
+	}{
+		{
+			name:    "insufficient stock",
+			second:  models.TransactionDetail{ProductID: berasID, Quantity: 6, Unit: "kg", UnitQuantity: 6, Subtotal: 72000},
+			wantErr: "insufficient stock for product with ID 2",
+		},
+		{
+			name:    "insufficient variant stock",
+			second:  models.TransactionDetail{ProductID: esTehID, VariantID: intPtr(2), Quantity: 3, Unit: "pcs", UnitQuantity: 3, Subtotal: 15000},
+			wantErr: "insufficient stock for product with ID 3",
+		},
+		{
+			name: "insufficient component stock",
+			second: models.TransactionDetail{ProductID: paketID, Quantity: 6, Unit: "pcs", UnitQuantity: 6, Subtotal: 90000, Components: []models.TransactionDetailComponent{
+				{ProductID: kopiID, Quantity: 12, Subtotal: 45000},
+				{ProductID: berasID, Quantity: 3, Subtotal: 45000},
+			}},
+			wantErr: "insufficient stock for product with ID 1",
+		},
+		{
+			name:    "unknown product",
+			second:  models.TransactionDetail{ProductID: 99, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 1000},
+			wantErr: "foreign key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			ctx := context.Background()
+
+			_, err := NewTransactionRepository(db).Create(ctx, models.Transaction{
+				OutletID:    models.DefaultOutletID,
+				TotalAmount: 5000,
+				Details: []models.TransactionDetail{
+					{ProductID: kopiID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000},
+					tt.second,
+				},
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %q", err, tt.wantErr)
+			}
+
+			// Nothing of the failed transaction is left behind
+			if got := stockOf(t, db, kopiID); got != 10 {
+				t.Errorf("Kopi stock = %v, want 10", got)
+			}
+			if got := stockOf(t, db, berasID); got != 5 {
+				t.Errorf("Beras stock = %v, want 5", got)
+			}
+			if v, _ := NewProductVariantRepository(db).GetByID(ctx, 2); v.Stock != 2 {
+				t.Errorf("variant L stock = %v, want 2", v.Stock)
+			}
+			for _, table := range []string{"transactions", "transaction_details", "transaction_detail_components"} {
+				if n := countRows(t, db, table); n != 0 {
+					t.Errorf("%s has %d rows, want 0", table, n)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresTransactionRepositoryCreateOffline(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransactionRepository(db)
+
+	clientID := "0b8e2b6e-4a7c-4f5e-9d7a-3f1c2e6b9a10"
+	soldAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	offline := models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		ClientID:    &clientID,
+		TotalAmount: 84000,
+		CreatedAt:   soldAt,
+		Details: []models.TransactionDetail{
+			{ProductID: berasID, Quantity: 7, Unit: "kg", UnitQuantity: 7, Subtotal: 84000},
+		},
+	}
+
+	created, duplicate, conflicts, err := repo.CreateOffline(ctx, offline)
+	if err != nil {
+		t.Fatalf("CreateOffline() error = %v", err)
+	}
+	if duplicate {
+		t.Error("first CreateOffline() reported a duplicate")
+	}
+	if !created.CreatedAt.Equal(soldAt) {
+		t.Errorf("CreatedAt = %v, want the offline time %v", created.CreatedAt, soldAt)
+	}
+	if len(conflicts) != 1 || conflicts[0].ProductID != berasID || conflicts[0].StockAfter != -2 {
+		t.Errorf("conflicts = %+v, want Beras at -2", conflicts)
+	}
+	if n := countRows(t, db, "stock_conflicts"); n != 1 {
+		t.Errorf("stock_conflicts has %d rows, want 1", n)
+	}
+	if got := stockOf(t, db, berasID); got != -2 {
+		t.Errorf("Beras stock = %v, want -2", got)
+	}
+
+	// Client IDs are UUIDs, so they match regardless of case
+	upper := strings.ToUpper(clientID)
+	offline.ClientID = &upper
+	again, duplicate, _, err := repo.CreateOffline(ctx, offline)
+	if err != nil {
+		t.Fatalf("second CreateOffline() error = %v", err)
+	}
+	if !duplicate || again.ID != created.ID || again.TotalAmount != 84000 {
+		t.Errorf("second CreateOffline() = %+v, %v; want duplicate of %d", again, duplicate, created.ID)
+	}
+	if got := stockOf(t, db, berasID); got != -2 {
+		t.Errorf("Beras stock after duplicate = %v, want -2", got)
+	}
+	if n := countRows(t, db, "transactions"); n != 1 {
+		t.Errorf("transactions has %d rows, want 1", n)
+	}
+}
+
+func TestPostgresTransactionRepositoryCreateOfflineSameStock(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// Three lines and a bundle component take from the Kopi stock: they make
+	// one conflict with the quantities added up
+	_, _, conflicts, err := NewTransactionRepository(db).CreateOffline(ctx, models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		TotalAmount: 75000,
+		CreatedAt:   time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+		Details: []models.TransactionDetail{
+			{ProductID: kopiID, Quantity: 4, Unit: "pcs", UnitQuantity: 4, Subtotal: 20000},
+			{ProductID: esTehID, VariantID: intPtr(2), Quantity: 3, Unit: "pcs", UnitQuantity: 3, Subtotal: 15000},
+			{ProductID: kopiID, Quantity: 4, Unit: "pcs", UnitQuantity: 4, Subtotal: 20000},
+			{ProductID: paketID, Quantity: 2, Unit: "pcs", UnitQuantity: 2, Subtotal: 30000, Components: []models.TransactionDetailComponent{
+				{ProductID: kopiID, Quantity: 4, Subtotal: 15000},
+				{ProductID: berasID, Quantity: 1, Subtotal: 15000},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateOffline() error = %v", err)
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("conflicts = %+v, want Kopi and variant L", conflicts)
+	}
+	if c := conflicts[0]; c.ProductID != kopiID || c.Quantity != 12 || c.StockAfter != -2 {
+		t.Errorf("first conflict = %+v, want Kopi, 12 sold, at -2", c)
+	}
+	if c := conflicts[1]; c.VariantID == nil || *c.VariantID != 2 || c.StockAfter != -1 {
+		t.Errorf("second conflict = %+v, want variant L at -1", c)
+	}
+	if n := countRows(t, db, "stock_conflicts"); n != 2 {
+		t.Errorf("stock_conflicts has %d rows, want 2", n)
+	}
+	if got := stockOf(t, db, berasID); got != 4 {
+		t.Errorf("Beras stock = %v, want 4", got)
+	}
+	if n := countRows(t, db, "transaction_detail_components"); n != 2 {
+		t.Errorf("transaction_detail_components has %d rows, want 2", n)
+	}
+}
+
+func TestPostgresTransactionRepositoryGetAllAndDelete(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransactionRepository(db)
+
+	clientID := "6f1d0c2a-8b3e-4d9f-a1b2-c3d4e5f60718"
+	older, _, _, err := repo.CreateOffline(ctx, models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		ClientID:    &clientID,
+		TotalAmount: 5000,
+		CreatedAt:   time.Now().Add(-time.Hour),
+		Details:     []models.TransactionDetail{{ProductID: kopiID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := repo.Create(ctx, models.Transaction{
+		OutletID:    models.DefaultOutletID,
+		TotalAmount: 26000,
+		Details: []models.TransactionDetail{
+			{ProductID: esTehID, VariantID: intPtr(1), Quantity: 2, Unit: "pcs", UnitQuantity: 2, Subtotal: 6000},
+			{ProductID: paketID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 15000, Components: []models.TransactionDetailComponent{
+				{ProductID: kopiID, Quantity: 2, Subtotal: 7500},
+				{ProductID: berasID, Quantity: 0.5, Subtotal: 7500},
+			}},
+			{ProductID: kopiID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := repo.GetAll(ctx, models.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(all) != 2 || all[0].ID != newer.ID || all[1].ID != older.ID {
+		t.Fatalf("GetAll() = %+v, want newest first", all)
+	}
+	if all[1].ClientID == nil || *all[1].ClientID != clientID {
+		t.Errorf("ClientID of offline transaction = %v, want %s", all[1].ClientID, clientID)
+	}
+
+	if err := repo.Delete(ctx, newer.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := stockOf(t, db, kopiID); got != 9 {
+		t.Errorf("Kopi stock = %v, want 9 (only the offline sale left)", got)
+	}
+	if got := stockOf(t, db, berasID); got != 5 {
+		t.Errorf("Beras stock = %v, want 5", got)
+	}
+	if v, _ := NewProductVariantRepository(db).GetByID(ctx, 1); v.Stock != 5 {
+		t.Errorf("variant S stock = %v, want 5", v.Stock)
+	}
+	if n := countRows(t, db, "transaction_detail_components"); n != 0 {
+		t.Errorf("transaction_detail_components has %d rows, want 0", n)
+	}
+
+	for name, err := range map[string]error{
+		"GetByID": func() error { _, err := repo.GetByID(ctx, newer.ID); return err }(),
+		"Delete":  repo.Delete(ctx, newer.ID),
+	} {
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("%s() of deleted transaction error = %v, want not found", name, err)
+		}
+	}
+}
+
+func TestPostgresTransactionRepositoryCustomer(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransactionRepository(db)
+
+	customer, err := NewCustomerRepository(db).Create(ctx, models.Customer{Name: "Budi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := repo.GetCustomerLifetimeValue(ctx, customer.ID)
+	if err != nil || value.TotalTransactions != 0 || value.TotalSpent != 0 || value.FirstPurchaseAt != nil {
+		t.Fatalf("GetCustomerLifetimeValue() without purchases = %+v, %v", value, err)
+	}
+
+	line := models.TransactionDetail{ProductID: kopiID, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000}
+	var sales []*models.Transaction
+	for _, sale := range []models.Transaction{
+		{CustomerID: &customer.ID, TotalAmount: 5000},
+		{TotalAmount: 5000},
+		{CustomerID: &customer.ID, TotalAmount: 10000},
+	} {
+		sale.OutletID = models.DefaultOutletID
+		sale.Details = []models.TransactionDetail{line}
+		created, err := repo.Create(ctx, sale)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		sales = append(sales, created)
+	}
+
+	history, err := repo.GetAll(ctx, models.TransactionFilter{CustomerID: customer.ID})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(history) != 2 || history[0].ID != sales[2].ID || history[1].ID != sales[0].ID {
+		t.Fatalf("GetAll() of customer = %+v, want the 2 sales of the customer, newest first", history)
+	}
+	if history[0].CustomerID == nil || *history[0].CustomerID != customer.ID {
+		t.Errorf("CustomerID = %v, want %d", history[0].CustomerID, customer.ID)
+	}
+
+	value, err = repo.GetCustomerLifetimeValue(ctx, customer.ID)
+	if err != nil {
+		t.Fatalf("GetCustomerLifetimeValue() error = %v", err)
+	}
+	if value.TotalTransactions != 2 || value.TotalSpent != 15000 {
+		t.Errorf("GetCustomerLifetimeValue() = %+v, want 2 transactions and 15000 spent", value)
+	}
+	if !value.FirstPurchaseAt.Equal(sales[0].CreatedAt) || !value.LastPurchaseAt.Equal(sales[2].CreatedAt) {
+		t.Errorf("purchases from %v to %v, want %v to %v", value.FirstPurchaseAt, value.LastPurchaseAt, sales[0].CreatedAt, sales[2].CreatedAt)
+	}
+
+	if _, err := repo.Create(ctx, models.Transaction{OutletID: models.DefaultOutletID, CustomerID: intPtr(99), TotalAmount: 5000, Details: []models.TransactionDetail{line}}); err == nil {
+		t.Error("Create() with unknown customer succeeded, want foreign key violation")
+	}
+}
+
+// BenchmarkPostgresTransactionRepositoryCreate checks out baskets of
+// increasing size; the statements of a checkout do not grow with the basket
+func BenchmarkPostgresTransactionRepositoryCreate(b *testing.B) {
+	for _, size := range []int{1, 10, 40} {
+		b.Run(fmt.Sprintf("items=%d", size), func(b *testing.B) {
+			db := newTestDB(b)
+			ctx := context.Background()
+			if _, err := db.ExecContext(ctx, "UPDATE products SET stock = 1e9"); err != nil {
+				b.Fatal(err)
+			}
+
+			details := make([]models.TransactionDetail, size)
+			for i := range details {
+				details[i] = models.TransactionDetail{ProductID: kopiID + i%2, Quantity: 1, Unit: "pcs", UnitQuantity: 1, Subtotal: 5000}
+			}
+			repo := NewTransactionRepository(db)
+
+			b.ResetTimer()
+			for range b.N {
+				if _, err := repo.Create(ctx, models.Transaction{OutletID: models.DefaultOutletID, TotalAmount: 5000 * size, Details: details}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// transferRepository is the PostgreSQL implementation of TransferRepository
+type transferRepository struct {
+	db *sql.DB
+}
+
+// NewTransferRepository creates a new TransferRepository
+func NewTransferRepository(db *sql.DB) TransferRepository {
+	return &transferRepository{db: db}
+}
+
+// transferColumns selects a transfer row
+const transferColumns = `SELECT id, from_outlet_id, to_outlet_id, status, COALESCE(note, ''), created_at, sent_at, received_at
+	FROM stock_transfers`
+
+// transferLineColumns selects the lines of a transfer in the order they were added
+const transferLineColumns = `SELECT id, product_id, variant_id, quantity, received_quantity, discrepancy, COALESCE(discrepancy_note, '')
+	FROM stock_transfer_lines WHERE transfer_id = $1 ORDER BY id`
+
+// GetAll returns the transfers matching filter, newest first, without their lines
+func (r *transferRepository) GetAll(ctx context.Context, filter models.TransferFilter) ([]models.StockTransfer, error) {
+	query := transferColumns + " WHERE 1=1"
+	var args []interface{}
+	argIndex := 1
+
+	if filter.OutletIDs != nil {
+		query += fmt.Sprintf(" AND (from_outlet_id = ANY($%d) OR to_outlet_id = ANY($%d))", argIndex, argIndex)
+		args = append(args, pq.Array(filter.OutletIDs))
+		argIndex++
+	}
+
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, filter.Status)
+		argIndex++
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []models.StockTransfer
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, *t)
+	}
+	return transfers, rows.Err()
+}
+
+// GetByID returns a transfer by ID with its lines
+func (r *transferRepository) GetByID(ctx context.Context, id int) (*models.StockTransfer, error) {
+	t, err := scanTransfer(r.db.QueryRowContext(ctx, transferColumns+" WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Transfer with ID %d not found", id)
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, transferLineColumns, id)
+	if err != nil {
+		return nil, err
+	}
+	t.Lines, err = scanTransferLines(rows)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Create adds a draft transfer with its lines
+func (r *transferRepository) Create(ctx context.Context, transfer models.StockTransfer) (*models.StockTransfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO stock_transfers (from_outlet_id, to_outlet_id, note)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id
+	`, transfer.FromOutletID, transfer.ToOutletID, transfer.Note).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertTransferLines(ctx, tx, id, transfer.Lines); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Update replaces the outlets, note and lines of a draft transfer
+func (r *transferRepository) Update(ctx context.Context, id int, transfer models.StockTransfer) (*models.StockTransfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockTransfer(ctx, tx, id, models.TransferDraft); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE stock_transfers SET from_outlet_id = $1, to_outlet_id = $2, note = NULLIF($3, '')
+		WHERE id = $4
+	`, transfer.FromOutletID, transfer.ToOutletID, transfer.Note, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM stock_transfer_lines WHERE transfer_id = $1", id); err != nil {
+		return nil, err
+	}
+	if err := insertTransferLines(ctx, tx, id, transfer.Lines); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Delete removes a draft transfer
+func (r *transferRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockTransfer(ctx, tx, id, models.TransferDraft); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM stock_transfers WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Send takes the lines of a draft transfer off the stock of the source outlet,
+// the way a sale does, and puts the transfer in transit
+func (r *transferRepository) Send(ctx context.Context, id int) (*models.StockTransfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	fromOutletID, _, err := lockTransfer(ctx, tx, id, models.TransferDraft)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, transferLineColumns, id)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := scanTransferLines(rows)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]models.TransactionDetail, len(lines))
+	for i, l := range lines {
+		details[i] = models.TransactionDetail{ProductID: l.ProductID, VariantID: l.VariantID, Quantity: l.Quantity}
+	}
+	if _, err := deductStock(ctx, tx, details, fromOutletID, false); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE stock_transfers SET status = $1, sent_at = NOW() WHERE id = $2", models.TransferInTransit, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Receive adds the quantities that arrived to the stock of the destination
+// outlet. The transfer is received when every line arrived in full or the
+// receipt closes it; what did not arrive is then recorded as discrepancy.
+func (r *transferRepository) Receive(ctx context.Context, id int, receipt models.TransferReceipt) (*models.StockTransfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, toOutletID, err := lockTransfer(ctx, tx, id, models.TransferInTransit)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, transferLineColumns, id)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := scanTransferLines(rows)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[int]int, len(lines))
+	for i, l := range lines {
+		index[l.ID] = i
+	}
+
+	var arrived []stockDeduction
+	received := make(map[int]bool)
+	for _, rl := range receipt.Lines {
+		i, ok := index[rl.LineID]
+		if !ok {
+			return nil, fmt.Errorf("Transfer line with ID %d not found", rl.LineID)
+		}
+		line := &lines[i]
+		line.ReceivedQuantity = math.Round((line.ReceivedQuantity+rl.Quantity)*1000) / 1000
+		if line.ReceivedQuantity > line.Quantity {
+			return nil, fmt.Errorf("received quantity of transfer line with ID %d exceeds the quantity sent", rl.LineID)
+		}
+		if rl.Note != "" {
+			line.DiscrepancyNote = rl.Note
+		}
+		received[i] = true
+		if rl.Quantity > 0 {
+			arrived = append(arrived, stockDeduction{ProductID: line.ProductID, VariantID: line.VariantID, Quantity: rl.Quantity})
+		}
+	}
+
+	var lineIDs []int64
+	var quantities []float64
+	var notes []string
+	complete := true
+	for i, l := range lines {
+		if received[i] {
+			lineIDs = append(lineIDs, int64(l.ID))
+			quantities = append(quantities, l.ReceivedQuantity)
+			notes = append(notes, l.DiscrepancyNote)
+		}
+		if l.ReceivedQuantity < l.Quantity {
+			complete = false
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE stock_transfer_lines l SET received_quantity = r.quantity, discrepancy_note = NULLIF(r.note, '')
+		FROM unnest($1::int[], $2::numeric[], $3::text[]) AS r(id, quantity, note)
+		WHERE l.id = r.id
+	`, pq.Array(lineIDs), pq.Array(quantities), pq.Array(notes))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := addStock(ctx, tx, toOutletID, arrived); err != nil {
+		return nil, err
+	}
+
+	if complete || receipt.Close {
+		_, err = tx.ExecContext(ctx, "UPDATE stock_transfer_lines SET discrepancy = quantity - received_quantity WHERE transfer_id = $1", id)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE stock_transfers SET status = $1, received_at = NOW() WHERE id = $2", models.TransferReceived, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// lockTransfer locks a transfer for a change that requires status and returns
+// its source and destination outlets
+func lockTransfer(ctx context.Context, tx *sql.Tx, id int, status string) (int, int, error) {
+	var fromOutletID, toOutletID int
+	var current string
+	err := tx.QueryRowContext(ctx,
+		"SELECT from_outlet_id, to_outlet_id, status FROM stock_transfers WHERE id = $1 FOR UPDATE", id,
+	).Scan(&fromOutletID, &toOutletID, &current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, fmt.Errorf("Transfer with ID %d not found", id)
+		}
+		return 0, 0, err
+	}
+	if current != status {
+		return 0, 0, fmt.Errorf("%w: transfer with ID %d is %s, not %s", ErrTransferStatus, id, current, status)
+	}
+	return fromOutletID, toOutletID, nil
+}
+
+// insertTransferLines inserts the lines of a transfer with one statement
+func insertTransferLines(ctx context.Context, tx *sql.Tx, transferID int, lines []models.TransferLine) error {
+	productIDs := make([]int64, len(lines))
+	variantIDs := make([]sql.NullInt64, len(lines))
+	quantities := make([]float64, len(lines))
+	for i, l := range lines {
+		productIDs[i] = int64(l.ProductID)
+		if l.VariantID != nil {
+			variantIDs[i] = sql.NullInt64{Int64: int64(*l.VariantID), Valid: true}
+		}
+		quantities[i] = l.Quantity
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_transfer_lines (transfer_id, product_id, variant_id, quantity)
+		SELECT $1, l.product_id, l.variant_id, l.quantity
+		FROM unnest($2::int[], $3::int[], $4::numeric[]) WITH ORDINALITY AS l(product_id, variant_id, quantity, n)
+		ORDER BY l.n
+	`, transferID, pq.Array(productIDs), pq.Array(variantIDs), pq.Array(quantities))
+	return err
+}
+
+// addStock adds quantities to the stock of an outlet and to the totals of the
+// products and variants, with one statement per table and in the order sales
+// lock them. The outlet may not have held the products yet.
+func addStock(ctx context.Context, tx *sql.Tx, outletID int, added []stockDeduction) error {
+	if len(added) == 0 {
+		return nil
+	}
+
+	var productIDs, variantIDs []int64
+	var productQuantities, variantQuantities []float64
+	outletProductIDs := make([]int64, len(added))
+	outletVariantIDs := make([]sql.NullInt64, len(added))
+	outletQuantities := make([]float64, len(added))
+	for i, a := range added {
+		if a.VariantID != nil {
+			variantIDs = append(variantIDs, int64(*a.VariantID))
+			variantQuantities = append(variantQuantities, a.Quantity)
+			outletVariantIDs[i] = sql.NullInt64{Int64: int64(*a.VariantID), Valid: true}
+		} else {
+			productIDs = append(productIDs, int64(a.ProductID))
+			productQuantities = append(productQuantities, a.Quantity)
+		}
+		outletProductIDs[i] = int64(a.ProductID)
+		outletQuantities[i] = a.Quantity
+	}
+
+	for _, table := range []struct {
+		name       string
+		ids        []int64
+		quantities []float64
+	}{
+		{"products", productIDs, productQuantities},
+		{"product_variants", variantIDs, variantQuantities},
+	} {
+		if len(table.ids) == 0 {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			UPDATE `+table.name+` t SET stock = t.stock + s.quantity
+			FROM (
+				SELECT id, SUM(quantity) AS quantity
+				FROM unnest($1::int[], $2::numeric[]) AS s(id, quantity)
+				GROUP BY id
+			) s
+			WHERE t.id = s.id
+		`, pq.Array(table.ids), pq.Array(table.quantities))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO outlet_products (outlet_id, product_id, variant_id, stock)
+		SELECT $1, s.product_id, s.variant_id, SUM(s.quantity)
+		FROM unnest($2::int[], $3::int[], $4::numeric[]) AS s(product_id, variant_id, quantity)
+		GROUP BY s.product_id, s.variant_id
+		`+outletStockConflict+` DO UPDATE SET stock = outlet_products.stock + EXCLUDED.stock
+	`, outletID, pq.Array(outletProductIDs), pq.Array(outletVariantIDs), pq.Array(outletQuantities))
+	return err
+}
+
+// scanTransfer scans a transfer row selected with transferColumns
+func scanTransfer(row interface{ Scan(...interface{}) error }) (*models.StockTransfer, error) {
+	var t models.StockTransfer
+	var sentAt, receivedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.FromOutletID, &t.ToOutletID, &t.Status, &t.Note, &t.CreatedAt, &sentAt, &receivedAt); err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		t.SentAt = &sentAt.Time
+	}
+	if receivedAt.Valid {
+		t.ReceivedAt = &receivedAt.Time
+	}
+	return &t, nil
+}
+
+// scanTransferLines scans and closes the lines selected with transferLineColumns
+func scanTransferLines(rows *sql.Rows) ([]models.TransferLine, error) {
+	defer rows.Close()
+
+	var lines []models.TransferLine
+	for rows.Next() {
+		var l models.TransferLine
+		var variantID sql.NullInt64
+		if err := rows.Scan(&l.ID, &l.ProductID, &variantID, &l.Quantity, &l.ReceivedQuantity, &l.Discrepancy, &l.DiscrepancyNote); err != nil {
+			return nil, err
+		}
+		if variantID.Valid {
+			id := int(variantID.Int64)
+			l.VariantID = &id
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
@@ -0,0 +1,184 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"kasir-api/models"
+)
+
+// outletStockOf returns the stock of a product, or of one of its variants, at an outlet
+func outletStockOf(t *testing.T, db *sql.DB, outletID, productID int, variantID *int) float64 {
+	t.Helper()
+	var stock float64
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(stock), 0) FROM outlet_products
+		WHERE outlet_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = COALESCE($3, 0)
+	`, outletID, productID, variantID).Scan(&stock)
+	if err != nil {
+		t.Fatalf("selecting outlet stock: %v", err)
+	}
+	return stock
+}
+
+// newTestTransfer creates a branch and drafts a transfer of 4 Kopi and 1 Es
+// Teh L to it from the main outlet
+func newTestTransfer(t *testing.T, db *sql.DB) (*models.StockTransfer, int) {
+	t.Helper()
+	ctx := context.Background()
+	branch, err := NewOutletRepository(db).Create(ctx, models.Outlet{Code: "CBG1", Name: "Cabang 1"})
+	if err != nil {
+		t.Fatalf("creating outlet: %v", err)
+	}
+	transfer, err := NewTransferRepository(db).Create(ctx, models.StockTransfer{
+		FromOutletID: models.DefaultOutletID,
+		ToOutletID:   branch.ID,
+		Note:         "restock",
+		Lines: []models.TransferLine{
+			{ProductID: kopiID, Quantity: 4},
+			{ProductID: esTehID, VariantID: intPtr(2), Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return transfer, branch.ID
+}
+
+func TestPostgresTransferRepositoryLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransferRepository(db)
+	transfer, branchID := newTestTransfer(t, db)
+
+	if transfer.Status != models.TransferDraft || len(transfer.Lines) != 2 || transfer.Lines[1].VariantID == nil {
+		t.Fatalf("Create() = %+v, want a draft with 2 lines", transfer)
+	}
+
+	sent, err := repo.Send(ctx, transfer.ID)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if sent.Status != models.TransferInTransit || sent.SentAt == nil {
+		t.Errorf("Send() = %+v, want in transit", sent)
+	}
+	if got := outletStockOf(t, db, models.DefaultOutletID, kopiID, nil); got != 6 {
+		t.Errorf("main outlet Kopi stock = %v, want 6", got)
+	}
+	if got := stockOf(t, db, kopiID); got != 6 {
+		t.Errorf("total Kopi stock = %v, want 6", got)
+	}
+
+	partial, err := repo.Receive(ctx, transfer.ID, models.TransferReceipt{
+		Lines: []models.ReceivedLine{{LineID: sent.Lines[0].ID, Quantity: 3, Note: "1 pecah"}},
+	})
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if partial.Status != models.TransferInTransit || partial.Lines[0].ReceivedQuantity != 3 {
+		t.Errorf("Receive() = %+v, want in transit with 3 Kopi received", partial)
+	}
+
+	received, err := repo.Receive(ctx, transfer.ID, models.TransferReceipt{
+		Lines: []models.ReceivedLine{{LineID: sent.Lines[1].ID, Quantity: 1}},
+		Close: true,
+	})
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if received.Status != models.TransferReceived || received.ReceivedAt == nil {
+		t.Errorf("Receive() = %+v, want received", received)
+	}
+	if l := received.Lines[0]; l.Discrepancy != 1 || l.DiscrepancyNote != "1 pecah" {
+		t.Errorf("Kopi line = %+v, want 1 missing with the note", l)
+	}
+	if got := outletStockOf(t, db, branchID, kopiID, nil); got != 3 {
+		t.Errorf("branch Kopi stock = %v, want 3", got)
+	}
+	if got := outletStockOf(t, db, branchID, esTehID, intPtr(2)); got != 1 {
+		t.Errorf("branch Es Teh L stock = %v, want 1", got)
+	}
+	if v, _ := NewProductVariantRepository(db).GetByID(ctx, 2); v.Stock != 2 {
+		t.Errorf("total Es Teh L stock = %v, want 2", v.Stock)
+	}
+	if got := stockOf(t, db, kopiID); got != 9 {
+		t.Errorf("total Kopi stock = %v, want 9", got)
+	}
+
+	transfers, err := repo.GetAll(ctx, models.TransferFilter{OutletIDs: []int{branchID}, Status: models.TransferReceived})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(transfers) != 1 || transfers[0].Lines != nil {
+		t.Errorf("GetAll() = %+v, want the transfer without lines", transfers)
+	}
+
+	if err := NewOutletRepository(db).Delete(ctx, branchID); err == nil {
+		t.Error("Delete() of outlet with transfers succeeded")
+	}
+}
+
+func TestPostgresTransferRepositorySendRollback(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransferRepository(db)
+	transfer, branchID := newTestTransfer(t, db)
+
+	transfer.Lines = append(transfer.Lines, models.TransferLine{ProductID: berasID, Quantity: 6})
+	if _, err := repo.Update(ctx, transfer.ID, *transfer); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	_, err := repo.Send(ctx, transfer.ID)
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("Send() error = %v, want ErrInsufficientStock", err)
+	}
+	if got := stockOf(t, db, kopiID); got != 10 {
+		t.Errorf("Kopi stock = %v, want 10 after rollback", got)
+	}
+	got, err := repo.GetByID(ctx, transfer.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Status != models.TransferDraft || len(got.Lines) != 3 {
+		t.Errorf("GetByID() = %+v, want the draft with 3 lines", got)
+	}
+
+	if _, err := repo.Receive(ctx, transfer.ID, models.TransferReceipt{Close: true}); !errors.Is(err, ErrTransferStatus) {
+		t.Errorf("Receive() of draft error = %v, want ErrTransferStatus", err)
+	}
+	if err := repo.Delete(ctx, transfer.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if n := countRows(t, db, "stock_transfer_lines"); n != 0 {
+		t.Errorf("stock_transfer_lines has %d rows, want 0", n)
+	}
+	if got := outletStockOf(t, db, branchID, kopiID, nil); got != 0 {
+		t.Errorf("branch Kopi stock = %v, want 0", got)
+	}
+}
+
+func TestPostgresTransferRepositoryReceiveRejected(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewTransferRepository(db)
+	transfer, branchID := newTestTransfer(t, db)
+	if _, err := repo.Send(ctx, transfer.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := repo.Receive(ctx, transfer.ID, models.TransferReceipt{
+		Lines: []models.ReceivedLine{{LineID: transfer.Lines[1].ID, Quantity: 1}, {LineID: transfer.Lines[0].ID, Quantity: 5}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "exceeds the quantity sent") {
+		t.Fatalf("Receive() error = %v, want too much received", err)
+	}
+	if got := outletStockOf(t, db, branchID, esTehID, intPtr(2)); got != 0 {
+		t.Errorf("branch Es Teh L stock = %v, want 0", got)
+	}
+}
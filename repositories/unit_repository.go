@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"kasir-api/models"
+)
+
+// unitRepository is the PostgreSQL implementation of UnitRepository
+type unitRepository struct {
+	db *sql.DB
+}
+
+// NewUnitRepository creates a new UnitRepository
+func NewUnitRepository(db *sql.DB) UnitRepository {
+	return &unitRepository{db: db}
+}
+
+// GetAll returns all units of measure
+func (r *unitRepository) GetAll(ctx context.Context) ([]models.Unit, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT code, name, precision, base_unit, factor FROM units ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []models.Unit
+	for rows.Next() {
+		var u models.Unit
+		var baseUnit sql.NullString
+		if err := rows.Scan(&u.Code, &u.Name, &u.Precision, &baseUnit, &u.Factor); err != nil {
+			return nil, err
+		}
+		u.BaseUnit = baseUnit.String
+		units = append(units, u)
+	}
+	return units, nil
+}
+
+// GetByCode returns a unit by its code
+func (r *unitRepository) GetByCode(ctx context.Context, code string) (*models.Unit, error) {
+	var u models.Unit
+	var baseUnit sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT code, name, precision, base_unit, factor FROM units WHERE code = $1",
+		code,
+	).Scan(&u.Code, &u.Name, &u.Precision, &baseUnit, &u.Factor)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Unit %s not found", code)
+		}
+		return nil, err
+	}
+	u.BaseUnit = baseUnit.String
+	return &u, nil
+}
+
+// Create adds a new unit of measure
+func (r *unitRepository) Create(ctx context.Context, unit models.Unit) (*models.Unit, error) {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO units (code, name, precision, base_unit, factor) VALUES ($1, $2, $3, NULLIF($4, ''), $5)",
+		unit.Code, unit.Name, unit.Precision, unit.BaseUnit, unit.Factor,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// Update updates an existing unit of measure
+func (r *unitRepository) Update(ctx context.Context, code string, unit models.Unit) (*models.Unit, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE units SET name = $1, precision = $2, base_unit = NULLIF($3, ''), factor = $4 WHERE code = $5",
+		unit.Name, unit.Precision, unit.BaseUnit, unit.Factor, code,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("Unit %s not found", code)
+	}
+	unit.Code = code
+	return &unit, nil
+}
+
+// GetConversions returns the packaging units defined for a product
+func (r *unitRepository) GetConversions(ctx context.Context, productID int) ([]models.UnitConversion, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, product_id, unit, factor FROM product_unit_conversions WHERE product_id = $1 ORDER BY unit",
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversions []models.UnitConversion
+	for rows.Next() {
+		var c models.UnitConversion
+		if err := rows.Scan(&c.ID, &c.ProductID, &c.Unit, &c.Factor); err != nil {
+			return nil, err
+		}
+		conversions = append(conversions, c)
+	}
+	return conversions, nil
+}
+
+// GetConversion returns a packaging unit of a product, or nil when none is defined
+func (r *unitRepository) GetConversion(ctx context.Context, productID int, unit string) (*models.UnitConversion, error) {
+	var c models.UnitConversion
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, product_id, unit, factor FROM product_unit_conversions WHERE product_id = $1 AND unit = $2",
+		productID, unit,
+	).Scan(&c.ID, &c.ProductID, &c.Unit, &c.Factor)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetConversionsByProductIDs returns the packaging units of several products
+func (r *unitRepository) GetConversionsByProductIDs(ctx context.Context, productIDs []int) ([]models.UnitConversion, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, product_id, unit, factor FROM product_unit_conversions WHERE product_id = ANY($1) ORDER BY product_id, unit",
+		pq.Array(productIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversions []models.UnitConversion
+	for rows.Next() {
+		var c models.UnitConversion
+		if err := rows.Scan(&c.ID, &c.ProductID, &c.Unit, &c.Factor); err != nil {
+			return nil, err
+		}
+		conversions = append(conversions, c)
+	}
+	return conversions, rows.Err()
+}
+
+// SaveConversion creates or updates a packaging unit of a product
+func (r *unitRepository) SaveConversion(ctx context.Context, conversion models.UnitConversion) (*models.UnitConversion, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO product_unit_conversions (product_id, unit, factor)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, unit) DO UPDATE SET factor = EXCLUDED.factor
+		RETURNING id
+	`, conversion.ProductID, conversion.Unit, conversion.Factor).Scan(&conversion.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &conversion, nil
+}
+
+// DeleteConversion removes a packaging unit of a product
+func (r *unitRepository) DeleteConversion(ctx context.Context, productID int, unit string) error {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM product_unit_conversions WHERE product_id = $1 AND unit = $2",
+		productID, unit,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("Unit conversion %s not found for product with ID %d", unit, productID)
+	}
+	return nil
+}
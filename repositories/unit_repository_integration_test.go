@@ -0,0 +1,91 @@
+//go:build integration
+
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestPostgresUnitRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewUnitRepository(db)
+
+	units, err := repo.GetAll(ctx)
+	if err != nil || len(units) != 4 || units[0].Code != "gram" {
+		t.Fatalf("GetAll() = %+v, %v; want the 4 default units ordered by code", units, err)
+	}
+
+	gram, err := repo.GetByCode(ctx, "gram")
+	if err != nil || gram.BaseUnit != "kg" || gram.Factor != 0.001 {
+		t.Errorf("GetByCode(gram) = %+v, %v", gram, err)
+	}
+	if _, err := repo.GetByCode(ctx, "ton"); err == nil {
+		t.Error("GetByCode(ton) succeeded, want not found")
+	}
+
+	if _, err := repo.Create(ctx, models.Unit{Code: "ons", Name: "Ons", Precision: 1, BaseUnit: "kg", Factor: 0.1}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := repo.Create(ctx, models.Unit{Code: "pack", Name: "Pack", Factor: 1}); err != nil {
+		t.Fatalf("Create() without base unit error = %v", err)
+	}
+	if pack, _ := repo.GetByCode(ctx, "pack"); pack.BaseUnit != "" {
+		t.Errorf("base unit of pack = %q, want empty", pack.BaseUnit)
+	}
+
+	if _, err := repo.Update(ctx, "ons", models.Unit{Name: "Ons", Precision: 2, Factor: 0.1}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := repo.Update(ctx, "ton", models.Unit{Name: "Ton", Factor: 1}); err == nil {
+		t.Error("Update(ton) succeeded, want not found")
+	}
+}
+
+func TestPostgresUnitRepositoryConversions(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewUnitRepository(db)
+
+	if c, err := repo.GetConversion(ctx, kopiID, "box"); err != nil || c != nil {
+		t.Fatalf("GetConversion() before save = %+v, %v; want nil, nil", c, err)
+	}
+
+	first, err := repo.SaveConversion(ctx, models.UnitConversion{ProductID: kopiID, Unit: "box", Factor: 6})
+	if err != nil {
+		t.Fatalf("SaveConversion() error = %v", err)
+	}
+	second, err := repo.SaveConversion(ctx, models.UnitConversion{ProductID: kopiID, Unit: "box", Factor: 12})
+	if err != nil {
+		t.Fatalf("SaveConversion() update error = %v", err)
+	}
+	if second.ID != first.ID {
+		t.Errorf("SaveConversion() created a second row (%d, %d), want an upsert", first.ID, second.ID)
+	}
+	if _, err := repo.SaveConversion(ctx, models.UnitConversion{ProductID: kopiID, Unit: "karton", Factor: 48}); err != nil {
+		t.Fatal(err)
+	}
+
+	conversions, err := repo.GetConversions(ctx, kopiID)
+	if err != nil || len(conversions) != 2 || conversions[0].Unit != "box" || conversions[0].Factor != 12 {
+		t.Errorf("GetConversions() = %+v, %v", conversions, err)
+	}
+
+	if _, err := repo.SaveConversion(ctx, models.UnitConversion{ProductID: berasID, Unit: "karung", Factor: 25}); err != nil {
+		t.Fatal(err)
+	}
+	conversions, err = repo.GetConversionsByProductIDs(ctx, []int{berasID, kopiID, esTehID})
+	if err != nil || len(conversions) != 3 || conversions[0].Unit != "box" || conversions[2].ProductID != berasID {
+		t.Errorf("GetConversionsByProductIDs() = %+v, %v", conversions, err)
+	}
+
+	if err := repo.DeleteConversion(ctx, kopiID, "box"); err != nil {
+		t.Fatalf("DeleteConversion() error = %v", err)
+	}
+	if err := repo.DeleteConversion(ctx, kopiID, "box"); err == nil {
+		t.Error("second DeleteConversion() succeeded, want not found")
+	}
+}
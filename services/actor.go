@@ -0,0 +1,18 @@
+package services
+
+import "context"
+
+// actorKey is the context key of the user making a request
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the user who makes the changes of
+// a request, which the services record with what they change
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom returns the user carried by ctx, or "" when it is unknown
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// Number of audit log entries returned by default and at most per page
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 200
+)
+
+// AuditService handles business logic for the audit log
+type AuditService struct {
+	repo repositories.AuditRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(repo repositories.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record adds an entry for a change to an entity to the audit log, made by
+// the user carried by ctx. before is nil for a create and after nil for a
+// delete; of an update only the fields that changed are kept.
+func (s *AuditService) Record(ctx context.Context, entry models.AuditEntry, before, after any) error {
+	var err error
+	entry.Actor = actorFrom(ctx)
+	entry.Before, entry.After, err = auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.Create(ctx, entry)
+	return err
+}
+
+// GetAuditLog returns a page of the audit log, newest first
+func (s *AuditService) GetAuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	switch filter.Action {
+	case "", models.AuditCreate, models.AuditUpdate, models.AuditDelete, models.AuditRestore, models.AuditPurge:
+	default:
+		return nil, fmt.Errorf("action must be %s, %s, %s, %s or %s",
+			models.AuditCreate, models.AuditUpdate, models.AuditDelete, models.AuditRestore, models.AuditPurge)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultAuditLimit
+	}
+	if filter.Limit < 0 || filter.Limit > MaxAuditLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", MaxAuditLimit)
+	}
+	if filter.Offset < 0 {
+		return nil, fmt.Errorf("offset cannot be negative")
+	}
+
+	entries, err := s.repo.GetAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+	return entries, nil
+}
+
+// auditDiff returns the JSON of the fields of before and after, leaving out
+// the fields they have in common when both are set
+func auditDiff(before, after any) (json.RawMessage, json.RawMessage, error) {
+	b, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b != nil && a != nil {
+		for field, value := range b {
+			if other, ok := a[field]; ok && reflect.DeepEqual(value, other) {
+				delete(b, field)
+				delete(a, field)
+			}
+		}
+	}
+
+	beforeJSON, err := marshalAuditFields(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterJSON, err := marshalAuditFields(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeJSON, afterJSON, nil
+}
+
+// auditFields returns the JSON fields of an entity, nil for none
+func auditFields(entity any) (map[string]any, error) {
+	if entity == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// marshalAuditFields returns the JSON of fields, nil when there are none
+func marshalAuditFields(fields map[string]any) (json.RawMessage, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	return json.Marshal(fields)
+}
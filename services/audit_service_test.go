@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+func TestAuditServiceRecord(t *testing.T) {
+	points := 2.0
+	tests := []struct {
+		name       string
+		action     string
+		before     any
+		after      any
+		wantBefore string
+		wantAfter  string
+	}{
+		{
+			name:      "create keeps every field after",
+			action:    models.AuditCreate,
+			after:     models.Category{ID: 1, Name: "Minuman"},
+			wantAfter: `{"description":"","id":1,"name":"Minuman"}`,
+		},
+		{
+			name:       "update keeps the changed fields",
+			action:     models.AuditUpdate,
+			before:     models.Product{ID: 1, Name: "Kopi", Price: 5000, Unit: "pcs", CategoryID: intPtr(1)},
+			after:      models.Product{ID: 1, Name: "Kopi", Price: 6000, Unit: "pcs", CategoryID: intPtr(1)},
+			wantBefore: `{"price":5000}`,
+			wantAfter:  `{"price":6000}`,
+		},
+		{
+			name:       "update keeps fields set on one side only",
+			action:     models.AuditUpdate,
+			before:     models.Category{ID: 1, Name: "Minuman"},
+			after:      models.Category{ID: 1, Name: "Minuman", PointsMultiplier: &points},
+			wantBefore: `{}`,
+			wantAfter:  `{"points_multiplier":2}`,
+		},
+		{
+			name:       "delete keeps every field before",
+			action:     models.AuditDelete,
+			before:     &models.Category{ID: 1, Name: "Minuman"},
+			wantBefore: `{"description":"","id":1,"name":"Minuman"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := WithActor(context.Background(), "budi")
+			entry := models.AuditEntry{Action: tt.action, EntityType: "category", EntityID: 1, IP: "10.0.0.1", RequestID: "req-1"}
+			if err := env.audit.Record(ctx, entry, tt.before, tt.after); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+
+			entries, err := env.audit.GetAuditLog(context.Background(), models.AuditFilter{})
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("GetAuditLog() = %+v, %v, want the entry", entries, err)
+			}
+			got := entries[0]
+			if string(got.Before) != tt.wantBefore || string(got.After) != tt.wantAfter {
+				t.Errorf("before, after = %s, %s; want %s, %s", got.Before, got.After, tt.wantBefore, tt.wantAfter)
+			}
+			if got.Actor != "budi" || got.IP != "10.0.0.1" || got.RequestID != "req-1" || got.CreatedAt.IsZero() {
+				t.Errorf("entry = %+v, want the actor, IP, request ID and time", got)
+			}
+		})
+	}
+}
+
+func TestAuditServiceGetAuditLog(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for i, e := range []struct {
+		actor  string
+		action string
+		entity string
+		id     int
+	}{
+		{"budi", models.AuditCreate, "product", 1},
+		{"budi", models.AuditUpdate, "product", 1},
+		{"sari", models.AuditUpdate, "product", 2},
+		{"sari", models.AuditDelete, "transaction", 1},
+	} {
+		env.store.Now = func() time.Time { return monday.AddDate(0, 0, i) }
+		entry := models.AuditEntry{Action: e.action, EntityType: e.entity, EntityID: e.id}
+		if err := env.audit.Record(WithActor(ctx, e.actor), entry, nil, nil); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	tuesday := monday.AddDate(0, 0, 1)
+	thursday := monday.AddDate(0, 0, 3)
+
+	tests := []struct {
+		name    string
+		filter  models.AuditFilter
+		wantIDs []int
+		wantErr string
+	}{
+		{name: "all, newest first", filter: models.AuditFilter{}, wantIDs: []int{4, 3, 2, 1}},
+		{name: "by actor", filter: models.AuditFilter{Actor: "budi"}, wantIDs: []int{2, 1}},
+		{name: "by action", filter: models.AuditFilter{Action: models.AuditUpdate}, wantIDs: []int{3, 2}},
+		{name: "by entity", filter: models.AuditFilter{EntityType: "product", EntityID: 1}, wantIDs: []int{2, 1}},
+		{name: "by time", filter: models.AuditFilter{From: &tuesday, To: &thursday}, wantIDs: []int{3, 2}},
+		{name: "page", filter: models.AuditFilter{Limit: 2, Offset: 1}, wantIDs: []int{3, 2}},
+		{name: "past the last page", filter: models.AuditFilter{Offset: 4}, wantIDs: []int{}},
+		{name: "unknown action", filter: models.AuditFilter{Action: "read"}, wantErr: "action must be"},
+		{name: "limit too large", filter: models.AuditFilter{Limit: MaxAuditLimit + 1}, wantErr: "limit must be between"},
+		{name: "negative offset", filter: models.AuditFilter{Offset: -1}, wantErr: "offset cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := env.audit.GetAuditLog(ctx, tt.filter)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetAuditLog() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetAuditLog() error = %v", err)
+			}
+			ids := []int{}
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("GetAuditLog() IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
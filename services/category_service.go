@@ -1,41 +1,80 @@
 package services
 
 import (
+	"context"
+	"fmt"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
+// ErrReassignTarget is wrapped by the errors returned when the products of a
+// deleted category cannot be moved to the category given for them
+var ErrReassignTarget = repositories.ErrReassignTarget
+
 // CategoryService handles business logic for categories
 type CategoryService struct {
-	repo *repositories.CategoryRepository
+	repo repositories.CategoryRepository
 }
 
 // NewCategoryService creates a new CategoryService
-func NewCategoryService(repo *repositories.CategoryRepository) *CategoryService {
+func NewCategoryService(repo repositories.CategoryRepository) *CategoryService {
 	return &CategoryService{repo: repo}
 }
 
-// GetAllCategories returns all categories
-func (s *CategoryService) GetAllCategories() ([]models.Category, error) {
-	return s.repo.GetAll()
+// GetAllCategories returns the categories, deleted ones only when the filter
+// includes them
+func (s *CategoryService) GetAllCategories(ctx context.Context, filter models.CategoryFilter) ([]models.Category, error) {
+	return s.repo.GetAll(ctx, filter)
 }
 
 // GetCategoryByID returns a category by ID
-func (s *CategoryService) GetCategoryByID(id int) (*models.Category, error) {
-	return s.repo.GetByID(id)
+func (s *CategoryService) GetCategoryByID(ctx context.Context, id int) (*models.Category, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
 // CreateCategory creates a new category
-func (s *CategoryService) CreateCategory(category models.Category) (*models.Category, error) {
-	return s.repo.Create(category)
+func (s *CategoryService) CreateCategory(ctx context.Context, category models.Category) (*models.Category, error) {
+	if err := validateCategory(category); err != nil {
+		return nil, err
+	}
+	category.DeletedAt = nil
+	return s.repo.Create(ctx, category)
 }
 
 // UpdateCategory updates an existing category
-func (s *CategoryService) UpdateCategory(id int, category models.Category) (*models.Category, error) {
-	return s.repo.Update(id, category)
+func (s *CategoryService) UpdateCategory(ctx context.Context, id int, category models.Category) (*models.Category, error) {
+	if err := validateCategory(category); err != nil {
+		return nil, err
+	}
+	category.DeletedAt = nil
+	return s.repo.Update(ctx, id, category)
+}
+
+// DeleteCategory soft deletes a category by ID. It is refused while the
+// category has products, unless reassignTo names the category they move to
+// in the same operation; the move is recorded in their history with the user
+// of ctx.
+func (s *CategoryService) DeleteCategory(ctx context.Context, id int, reassignTo *int) error {
+	return s.repo.Delete(ctx, id, reassignTo, actorFrom(ctx))
+}
+
+// RestoreCategory undoes the deletion of a category
+func (s *CategoryService) RestoreCategory(ctx context.Context, id int) (*models.Category, error) {
+	return s.repo.Restore(ctx, id)
+}
+
+// PurgeCategory permanently removes a deleted category whose products were
+// never sold; its products lose their category, recorded in their history
+// with the user of ctx
+func (s *CategoryService) PurgeCategory(ctx context.Context, id int) error {
+	return s.repo.Purge(ctx, id, actorFrom(ctx))
 }
 
-// DeleteCategory deletes a category by ID
-func (s *CategoryService) DeleteCategory(id int) error {
-	return s.repo.Delete(id)
+// validateCategory checks the points multiplier of a category
+func validateCategory(category models.Category) error {
+	if category.PointsMultiplier != nil && *category.PointsMultiplier < 0 {
+		return fmt.Errorf("points_multiplier must not be negative")
+	}
+	return nil
 }
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestCategoryService(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context, s *CategoryService) error
+		wantErr string
+	}{
+		{
+			name: "create and get",
+			run: func(ctx context.Context, s *CategoryService) error {
+				created, err := s.CreateCategory(ctx, models.Category{Name: "Minuman", Description: "Drinks"})
+				if err != nil {
+					return err
+				}
+				_, err = s.GetCategoryByID(ctx, created.ID)
+				return err
+			},
+		},
+		{
+			name: "update",
+			run: func(ctx context.Context, s *CategoryService) error {
+				_, err := s.UpdateCategory(ctx, 1, models.Category{Name: "Bahan Pokok"})
+				return err
+			},
+		},
+		{
+			name: "update keeps points multiplier",
+			run: func(ctx context.Context, s *CategoryService) error {
+				multiplier := 2.5
+				if _, err := s.UpdateCategory(ctx, 1, models.Category{Name: "Sembako", PointsMultiplier: &multiplier}); err != nil {
+					return err
+				}
+				updated, err := s.UpdateCategory(ctx, 1, models.Category{Name: "Bahan Pokok"})
+				if err == nil && (updated.PointsMultiplier == nil || *updated.PointsMultiplier != 2.5) {
+					t.Errorf("points multiplier = %v, want 2.5 kept", updated.PointsMultiplier)
+				}
+				return err
+			},
+		},
+		{
+			name: "create with negative points multiplier",
+			run: func(ctx context.Context, s *CategoryService) error {
+				multiplier := -1.0
+				_, err := s.CreateCategory(ctx, models.Category{Name: "Promo", PointsMultiplier: &multiplier})
+				return err
+			},
+			wantErr: "points_multiplier must not be negative",
+		},
+		{
+			name: "update missing",
+			run: func(ctx context.Context, s *CategoryService) error {
+				_, err := s.UpdateCategory(ctx, 99, models.Category{Name: "X"})
+				return err
+			},
+			wantErr: "Category with ID 99 not found",
+		},
+		{
+			name: "delete",
+			run: func(ctx context.Context, s *CategoryService) error {
+				created, err := s.CreateCategory(ctx, models.Category{Name: "Minuman"})
+				if err != nil {
+					return err
+				}
+				return s.DeleteCategory(ctx, created.ID, nil)
+			},
+		},
+		{
+			name: "delete with products",
+			run: func(ctx context.Context, s *CategoryService) error {
+				return s.DeleteCategory(ctx, 1, nil)
+			},
+			wantErr: "still in use: category with ID 1 has products",
+		},
+		{
+			name: "delete reassigning to itself",
+			run: func(ctx context.Context, s *CategoryService) error {
+				return s.DeleteCategory(ctx, 1, intPtr(1))
+			},
+			wantErr: "invalid reassignment target: category with ID 1 does not exist",
+		},
+		{
+			name: "delete reassigning to missing category",
+			run: func(ctx context.Context, s *CategoryService) error {
+				return s.DeleteCategory(ctx, 1, intPtr(99))
+			},
+			wantErr: "invalid reassignment target: category with ID 99 does not exist",
+		},
+		{
+			name: "delete missing",
+			run: func(ctx context.Context, s *CategoryService) error {
+				return s.DeleteCategory(ctx, 99, nil)
+			},
+			wantErr: "Category with ID 99 not found",
+		},
+		{
+			name: "get missing",
+			run: func(ctx context.Context, s *CategoryService) error {
+				_, err := s.GetCategoryByID(ctx, 99)
+				return err
+			},
+			wantErr: "Category with ID 99 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			err := tt.run(context.Background(), env.categories)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCategoryServiceGetAllCategories(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	if _, err := env.categories.CreateCategory(ctx, models.Category{Name: "Minuman"}); err != nil {
+		t.Fatal(err)
+	}
+
+	categories, err := env.categories.GetAllCategories(ctx, models.CategoryFilter{})
+	if err != nil {
+		t.Fatalf("GetAllCategories() error = %v", err)
+	}
+	if len(categories) != 2 || categories[0].Name != "Sembako" || categories[1].Name != "Minuman" {
+		t.Errorf("GetAllCategories() = %+v, want Sembako and Minuman", categories)
+	}
+
+	if err := env.categories.DeleteCategory(ctx, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if categories, _ := env.categories.GetAllCategories(ctx, models.CategoryFilter{}); len(categories) != 1 || categories[0].Name != "Sembako" {
+		t.Errorf("GetAllCategories() = %+v, want the deleted category left out", categories)
+	}
+	if categories, _ := env.categories.GetAllCategories(ctx, models.CategoryFilter{IncludeDeleted: true}); len(categories) != 2 || categories[1].DeletedAt == nil {
+		t.Errorf("GetAllCategories() including deleted = %+v, want Minuman marked deleted", categories)
+	}
+}
+
+func TestCategoryServiceDeleteReassigning(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := WithActor(context.Background(), "budi")
+
+	minuman, err := env.categories.CreateCategory(ctx, models.Category{Name: "Minuman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.DeleteProduct(ctx, esTehID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.categories.DeleteCategory(ctx, 1, &minuman.ID); err != nil {
+		t.Fatalf("DeleteCategory() error = %v", err)
+	}
+
+	// Deleted products move too, so none is left in the deleted category
+	products, err := env.products.GetAllProducts(ctx, models.ProductFilter{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range products {
+		if p.CategoryID == nil || *p.CategoryID != minuman.ID {
+			t.Errorf("product %d category = %v, want %d", p.ID, p.CategoryID, minuman.ID)
+		}
+	}
+	versions, err := env.products.GetProductHistory(ctx, kopiID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := versions[len(versions)-1]
+	if len(versions) != 2 || len(last.Changes) != 1 || last.Changes[0] != "category_id" || last.ChangedBy != "budi" {
+		t.Errorf("history of Kopi = %+v, want the move by budi", versions)
+	}
+}
+
+func TestCategoryServiceRestoreProductOfDeletedCategory(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := WithActor(context.Background(), "budi")
+
+	minuman, err := env.categories.CreateCategory(ctx, models.Category{Name: "Minuman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jus, err := env.products.CreateProduct(ctx, models.Product{Name: "Jus", Price: 8000, CategoryID: &minuman.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.DeleteProduct(ctx, jus.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.categories.DeleteCategory(ctx, minuman.ID, nil); err != nil {
+		t.Fatalf("DeleteCategory() with only deleted products error = %v", err)
+	}
+
+	// The product is not restored into the deleted category, so it can be
+	// updated again
+	restored, err := env.products.RestoreProduct(ctx, jus.ID)
+	if err != nil || restored.CategoryID != nil {
+		t.Fatalf("RestoreProduct() = %+v, %v, want no category", restored, err)
+	}
+	if _, err := env.products.UpdateProduct(ctx, jus.ID, models.Product{Name: "Jus Jeruk", Price: 9000}); err != nil {
+		t.Errorf("UpdateProduct() after restore error = %v", err)
+	}
+	versions, err := env.products.GetProductHistory(ctx, jus.ID, nil)
+	if err != nil || len(versions) != 3 || versions[1].CategoryID != nil || versions[1].ChangedBy != "budi" {
+		t.Errorf("history of Jus = %+v, %v, want the category dropped by budi", versions, err)
+	}
+}
+
+func TestCategoryServiceRestoreAndPurge(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	minuman, err := env.categories.CreateCategory(ctx, models.Category{Name: "Minuman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.categories.RestoreCategory(ctx, minuman.ID); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("RestoreCategory() of live category error = %v, want ErrNotDeleted", err)
+	}
+	if err := env.categories.PurgeCategory(ctx, minuman.ID); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("PurgeCategory() of live category error = %v, want ErrNotDeleted", err)
+	}
+	if err := env.categories.DeleteCategory(ctx, minuman.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.categories.UpdateCategory(ctx, minuman.ID, models.Category{Name: "X"}); err == nil {
+		t.Error("UpdateCategory() of deleted category succeeded")
+	}
+	if _, err := env.products.CreateProduct(ctx, models.Product{Name: "Jus", Price: 8000, CategoryID: &minuman.ID}); err == nil {
+		t.Error("CreateProduct() in deleted category succeeded")
+	}
+	restored, err := env.categories.RestoreCategory(ctx, minuman.ID)
+	if err != nil || restored.DeletedAt != nil || restored.Name != "Minuman" {
+		t.Fatalf("RestoreCategory() = %+v, %v", restored, err)
+	}
+
+	// A category whose deleted products were sold stays in the books
+	jus, err := env.products.CreateProduct(ctx, models.Product{Name: "Jus", Price: 8000, Stock: 5, CategoryID: &minuman.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: jus.ID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.DeleteProduct(ctx, jus.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.categories.DeleteCategory(ctx, minuman.ID, nil); err != nil {
+		t.Fatalf("DeleteCategory() with only deleted products error = %v", err)
+	}
+	if err := env.categories.PurgeCategory(ctx, minuman.ID); !errors.Is(err, ErrInUse) {
+		t.Errorf("PurgeCategory() of sold category error = %v, want ErrInUse", err)
+	}
+
+	makanan, err := env.categories.CreateCategory(ctx, models.Category{Name: "Makanan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roti, err := env.products.CreateProduct(ctx, models.Product{Name: "Roti", Price: 8000, CategoryID: &makanan.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.DeleteProduct(ctx, roti.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.categories.DeleteCategory(ctx, makanan.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.categories.PurgeCategory(WithActor(ctx, "budi"), makanan.ID); err != nil {
+		t.Fatalf("PurgeCategory() error = %v", err)
+	}
+	if _, err := env.categories.GetCategoryByID(ctx, makanan.ID); err == nil {
+		t.Error("GetCategoryByID() of purged category succeeded")
+	}
+	// Its products are left without a category
+	if product, err := env.products.GetProductByID(ctx, roti.ID); err != nil || product.CategoryID != nil {
+		t.Errorf("product of purged category = %+v, %v, want no category", product, err)
+	}
+	versions, err := env.products.GetProductHistory(ctx, roti.ID, nil)
+	if err != nil || len(versions) != 2 || versions[1].CategoryID != nil || versions[1].ChangedBy != "budi" {
+		t.Errorf("history of Roti = %+v, %v, want the category dropped by budi", versions, err)
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// CustomerService handles business logic for customers and their purchases
+type CustomerService struct {
+	repo            repositories.CustomerRepository
+	transactionRepo repositories.TransactionRepository
+}
+
+// NewCustomerService creates a new CustomerService
+func NewCustomerService(repo repositories.CustomerRepository, transactionRepo repositories.TransactionRepository) *CustomerService {
+	return &CustomerService{repo: repo, transactionRepo: transactionRepo}
+}
+
+// GetAllCustomers returns all customers, optionally searched by name, phone,
+// email or member number
+func (s *CustomerService) GetAllCustomers(ctx context.Context, filter models.CustomerFilter) ([]models.Customer, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	return s.repo.GetAll(ctx, filter)
+}
+
+// GetCustomerByID returns a customer by ID
+func (s *CustomerService) GetCustomerByID(ctx context.Context, id int) (*models.Customer, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// CreateCustomer creates a new customer
+func (s *CustomerService) CreateCustomer(ctx context.Context, customer models.Customer) (*models.Customer, error) {
+	if err := validateCustomer(&customer); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, customer)
+}
+
+// UpdateCustomer updates an existing customer
+func (s *CustomerService) UpdateCustomer(ctx context.Context, id int, customer models.Customer) (*models.Customer, error) {
+	if err := validateCustomer(&customer); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, id, customer)
+}
+
+// DeleteCustomer deletes a customer by ID; their transactions are kept
+func (s *CustomerService) DeleteCustomer(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// GetPurchaseHistory returns the transactions of a customer, newest first
+func (s *CustomerService) GetPurchaseHistory(ctx context.Context, id int) ([]models.Transaction, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.transactionRepo.GetAll(ctx, models.TransactionFilter{CustomerID: id})
+}
+
+// GetLifetimeValue returns what a customer has spent over all their purchases
+func (s *CustomerService) GetLifetimeValue(ctx context.Context, id int) (*models.CustomerLifetimeValue, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	value, err := s.transactionRepo.GetCustomerLifetimeValue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if value.TotalTransactions > 0 {
+		value.AverageSpent = value.TotalSpent / value.TotalTransactions
+	}
+	return value, nil
+}
+
+// validateCustomer trims the fields of a customer and checks the name and
+// email address
+func validateCustomer(customer *models.Customer) error {
+	customer.Name = strings.TrimSpace(customer.Name)
+	customer.Phone = strings.TrimSpace(customer.Phone)
+	customer.Email = strings.TrimSpace(customer.Email)
+	customer.MemberNumber = strings.TrimSpace(customer.MemberNumber)
+
+	if customer.Name == "" {
+		return fmt.Errorf("customer name is required")
+	}
+	if customer.Email != "" {
+		if address, err := mail.ParseAddress(customer.Email); err != nil || address.Address != customer.Email {
+			return fmt.Errorf("invalid email address %q", customer.Email)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+func TestCustomerService(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context, s *CustomerService) error
+		wantErr string
+	}{
+		{
+			name: "create and get",
+			run: func(ctx context.Context, s *CustomerService) error {
+				created, err := s.CreateCustomer(ctx, models.Customer{Name: " Budi ", Phone: "0812", Email: "budi@example.com", MemberNumber: "M-001"})
+				if err != nil {
+					return err
+				}
+				got, err := s.GetCustomerByID(ctx, created.ID)
+				if err == nil && got.Name != "Budi" {
+					t.Errorf("name = %q, want it trimmed", got.Name)
+				}
+				return err
+			},
+		},
+		{
+			name: "create without name",
+			run: func(ctx context.Context, s *CustomerService) error {
+				_, err := s.CreateCustomer(ctx, models.Customer{Name: "  ", Phone: "0812"})
+				return err
+			},
+			wantErr: "customer name is required",
+		},
+		{
+			name: "create with invalid email",
+			run: func(ctx context.Context, s *CustomerService) error {
+				_, err := s.CreateCustomer(ctx, models.Customer{Name: "Budi", Email: "Budi <budi@example.com>"})
+				return err
+			},
+			wantErr: `invalid email address "Budi <budi@example.com>"`,
+		},
+		{
+			name: "create with taken phone",
+			run: func(ctx context.Context, s *CustomerService) error {
+				if _, err := s.CreateCustomer(ctx, models.Customer{Name: "Budi", Phone: "0812"}); err != nil {
+					return err
+				}
+				_, err := s.CreateCustomer(ctx, models.Customer{Name: "Sari", Phone: "0812"})
+				return err
+			},
+			wantErr: "customer with phone 0812 already exists",
+		},
+		{
+			name: "update keeps own member number",
+			run: func(ctx context.Context, s *CustomerService) error {
+				created, err := s.CreateCustomer(ctx, models.Customer{Name: "Budi", MemberNumber: "M-001"})
+				if err != nil {
+					return err
+				}
+				_, err = s.UpdateCustomer(ctx, created.ID, models.Customer{Name: "Budi Santoso", MemberNumber: "M-001"})
+				return err
+			},
+		},
+		{
+			name: "update missing",
+			run: func(ctx context.Context, s *CustomerService) error {
+				_, err := s.UpdateCustomer(ctx, 99, models.Customer{Name: "X"})
+				return err
+			},
+			wantErr: "Customer with ID 99 not found",
+		},
+		{
+			name: "delete missing",
+			run: func(ctx context.Context, s *CustomerService) error {
+				return s.DeleteCustomer(ctx, 99)
+			},
+			wantErr: "Customer with ID 99 not found",
+		},
+		{
+			name: "history of missing customer",
+			run: func(ctx context.Context, s *CustomerService) error {
+				_, err := s.GetPurchaseHistory(ctx, 99)
+				return err
+			},
+			wantErr: "Customer with ID 99 not found",
+		},
+		{
+			name: "lifetime value of missing customer",
+			run: func(ctx context.Context, s *CustomerService) error {
+				_, err := s.GetLifetimeValue(ctx, 99)
+				return err
+			},
+			wantErr: "Customer with ID 99 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			err := tt.run(context.Background(), env.customers)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCustomerServiceSearch(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	for _, c := range []models.Customer{
+		{Name: "Sari", Phone: "0813", MemberNumber: "M-002"},
+		{Name: "Budi", Phone: "0812", Email: "budi@example.com", MemberNumber: "M-001"},
+		{Name: "Andi", Email: "andi@toko.id"},
+	} {
+		if _, err := env.customers.CreateCustomer(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		{search: "", want: []string{"Andi", "Budi", "Sari"}},
+		{search: "bud", want: []string{"Budi"}},
+		{search: "081", want: []string{"Budi", "Sari"}},
+		{search: "EXAMPLE.COM", want: []string{"Budi"}},
+		{search: " m-002 ", want: []string{"Sari"}},
+		{search: "nobody", want: nil},
+	}
+	for _, tt := range tests {
+		customers, err := env.customers.GetAllCustomers(ctx, models.CustomerFilter{Search: tt.search})
+		if err != nil {
+			t.Fatalf("GetAllCustomers(%q) error = %v", tt.search, err)
+		}
+		var names []string
+		for _, c := range customers {
+			names = append(names, c.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("GetAllCustomers(%q) = %v, want %v", tt.search, names, tt.want)
+		}
+	}
+}
+
+func TestCustomerServicePurchases(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	budi, err := env.customers.CreateCustomer(ctx, models.Customer{Name: "Budi", Phone: "0812"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing bought yet
+	value, err := env.customers.GetLifetimeValue(ctx, budi.ID)
+	if err != nil {
+		t.Fatalf("GetLifetimeValue() error = %v", err)
+	}
+	if value.TotalTransactions != 0 || value.AverageSpent != 0 || value.FirstPurchaseAt != nil {
+		t.Errorf("GetLifetimeValue() without purchases = %+v", value)
+	}
+
+	first := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, req := range []models.CreateTransactionRequest{
+		{CustomerID: &budi.ID, Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 2}}},
+		{Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 1}}},
+		{CustomerID: &budi.ID, Items: []models.TransactionItem{{ProductID: berasID, Quantity: 1}}},
+	} {
+		soldAt := first.Add(time.Duration(i) * time.Hour)
+		env.store.Now = func() time.Time { return soldAt }
+		if _, err := env.transactions.CreateTransaction(ctx, req); err != nil {
+			t.Fatalf("CreateTransaction() error = %v", err)
+		}
+	}
+
+	history, err := env.customers.GetPurchaseHistory(ctx, budi.ID)
+	if err != nil {
+		t.Fatalf("GetPurchaseHistory() error = %v", err)
+	}
+	if len(history) != 2 || history[0].ID != 3 || history[1].ID != 1 || *history[0].CustomerID != budi.ID {
+		t.Fatalf("GetPurchaseHistory() = %+v, want transactions 3 and 1", history)
+	}
+
+	value, err = env.customers.GetLifetimeValue(ctx, budi.ID)
+	if err != nil {
+		t.Fatalf("GetLifetimeValue() error = %v", err)
+	}
+	if value.TotalTransactions != 2 || value.TotalSpent != 22000 || value.AverageSpent != 11000 {
+		t.Errorf("GetLifetimeValue() = %+v, want 2 transactions, 22000 spent, 11000 average", value)
+	}
+	if !value.FirstPurchaseAt.Equal(first) || !value.LastPurchaseAt.Equal(first.Add(2*time.Hour)) {
+		t.Errorf("purchases from %v to %v, want %v to %v", value.FirstPurchaseAt, value.LastPurchaseAt, first, first.Add(2*time.Hour))
+	}
+
+	// Deleting the customer keeps the sales, anonymous
+	if err := env.customers.DeleteCustomer(ctx, budi.ID); err != nil {
+		t.Fatalf("DeleteCustomer() error = %v", err)
+	}
+	sale, err := env.transactions.GetTransactionByID(ctx, 1)
+	if err != nil || sale.CustomerID != nil {
+		t.Errorf("transaction of deleted customer = %+v, %v; want it kept without customer", sale, err)
+	}
+}
+
+func TestCustomerServiceUnknownCustomer(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.transactions.CreateTransaction(context.Background(), models.CreateTransactionRequest{
+		CustomerID: intPtr(99),
+		Items:      []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+	})
+	if err == nil || err.Error() != "Customer with ID 99 not found" {
+		t.Fatalf("CreateTransaction() error = %v, want customer not found", err)
+	}
+	if got := env.stockOf(t, kopiID); got != 10 {
+		t.Errorf("Kopi stock = %v, want 10", got)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// HealthService reports whether the API is alive and ready to serve traffic
+type HealthService struct {
+	repo        repositories.HealthRepository
+	name        string
+	version     string
+	environment string
+	startedAt   time.Time
+}
+
+// NewHealthService creates a new HealthService
+func NewHealthService(repo repositories.HealthRepository, name, version, environment string) *HealthService {
+	return &HealthService{
+		repo:        repo,
+		name:        name,
+		version:     version,
+		environment: environment,
+		startedAt:   time.Now(),
+	}
+}
+
+// Liveness reports that the process is up; it never touches the database so a
+// slow database does not get the process restarted
+func (s *HealthService) Liveness() models.HealthStatus {
+	return s.status(models.HealthStatusOK)
+}
+
+// Readiness pings the database and reports the connection pool state. The
+// returned status is UNAVAILABLE when the database cannot be reached.
+func (s *HealthService) Readiness(ctx context.Context) models.HealthStatus {
+	start := time.Now()
+	err := s.repo.Ping(ctx)
+
+	db := &models.DatabaseHealth{
+		Status:    models.HealthStatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Pool:      s.repo.Stats(),
+	}
+	status := models.HealthStatusOK
+	if err != nil {
+		db.Status = models.HealthStatusUnavailable
+		db.Error = err.Error()
+		status = models.HealthStatusUnavailable
+	}
+
+	health := s.status(status)
+	health.Database = db
+	return health
+}
+
+// status builds a health status with the application details
+func (s *HealthService) status(status string) models.HealthStatus {
+	return models.HealthStatus{
+		Status:      status,
+		Name:        s.name,
+		Version:     s.version,
+		Environment: s.environment,
+		Uptime:      time.Since(s.startedAt).Round(time.Second).String(),
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kasir-api/models"
+	"kasir-api/repositories/memory"
+)
+
+func TestHealthService(t *testing.T) {
+	tests := []struct {
+		name          string
+		pingErr       error
+		wantReady     string
+		wantDatabase  string
+		wantErrorText string
+	}{
+		{
+			name:         "database reachable",
+			wantReady:    models.HealthStatusOK,
+			wantDatabase: models.HealthStatusOK,
+		},
+		{
+			name:          "database unreachable",
+			pingErr:       errors.New("connection refused"),
+			wantReady:     models.HealthStatusUnavailable,
+			wantDatabase:  models.HealthStatusUnavailable,
+			wantErrorText: "connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore()
+			store.SetPingError(tt.pingErr)
+			service := NewHealthService(memory.NewHealthRepository(store), "Kasir API", "1.0", "development")
+
+			live := service.Liveness()
+			if live.Status != models.HealthStatusOK || live.Database != nil || live.Version != "1.0" {
+				t.Errorf("Liveness() = %+v, want OK without database check", live)
+			}
+
+			ready := service.Readiness(context.Background())
+			if ready.Status != tt.wantReady {
+				t.Errorf("Readiness().Status = %s, want %s", ready.Status, tt.wantReady)
+			}
+			if ready.Database == nil || ready.Database.Status != tt.wantDatabase || ready.Database.Error != tt.wantErrorText {
+				t.Errorf("Readiness().Database = %+v, want status %s and error %q", ready.Database, tt.wantDatabase, tt.wantErrorText)
+			}
+		})
+	}
+}
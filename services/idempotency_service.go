@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is reused with a different payload
+	ErrIdempotencyKeyReused = errors.New("Idempotency-Key was already used with a different request payload")
+	// ErrIdempotencyInProgress is returned when the first request with a key has not finished yet
+	ErrIdempotencyInProgress = errors.New("a request with this Idempotency-Key is still being processed")
+	// ErrIdempotencyFailed is returned when the first request with a key failed unexpectedly
+	ErrIdempotencyFailed = errors.New("the request with this Idempotency-Key failed unexpectedly and may have been processed; check the result before retrying with a new key")
+)
+
+// failedStatus is stored for a key whose request failed unexpectedly.
+// Complete never stores server errors, so no response is replayed with it.
+const failedStatus = 500
+
+// IsTransient reports whether err is a temporary failure of the database or
+// of the request context rather than a rejection of the request, so the same
+// request may succeed when it is retried
+func IsTransient(err error) bool {
+	return repositories.IsTransient(err)
+}
+
+// IdempotencyService handles business logic for idempotency keys
+type IdempotencyService struct {
+	repo repositories.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyService creates a new IdempotencyService; keys expire after ttl
+func NewIdempotencyService(repo repositories.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl}
+}
+
+// Begin claims the key of an owner, the user sending the request, for a
+// request with the given payload hash. It returns the stored record when the
+// request is a retry that should be replayed, or nil when the caller should
+// process the request and call Complete. Owners never see each other's keys.
+func (s *IdempotencyService) Begin(ctx context.Context, owner, key, requestHash string) (*models.IdempotencyRecord, error) {
+	record, reserved, err := s.repo.Reserve(ctx, owner, key, requestHash, time.Now().Add(s.ttl))
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	if record.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !record.Completed {
+		return nil, ErrIdempotencyInProgress
+	}
+	if record.StatusCode == failedStatus {
+		return nil, ErrIdempotencyFailed
+	}
+	return record, nil
+}
+
+// Complete stores the response for a key. Server errors are not stored and
+// release the key instead, so the client can retry.
+func (s *IdempotencyService) Complete(ctx context.Context, owner, key string, statusCode int, responseBody string) error {
+	if statusCode >= 500 {
+		return s.repo.Release(ctx, owner, key)
+	}
+	return s.repo.Complete(ctx, owner, key, statusCode, responseBody)
+}
+
+// Fail marks a key whose request failed unexpectedly, such as by a panic.
+// Its changes may have been committed before the failure, so retries with the
+// key are refused with ErrIdempotencyFailed instead of being run again.
+func (s *IdempotencyService) Fail(ctx context.Context, owner, key string) error {
+	return s.repo.Complete(ctx, owner, key, failedStatus, "")
+}
+
+// PurgeExpired removes expired keys
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx)
+}
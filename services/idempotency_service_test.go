@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestIdempotencyServiceBegin(t *testing.T) {
+	type step struct {
+		hash       string
+		complete   int // status code to complete with, 0 to leave in progress
+		advance    time.Duration
+		wantReplay bool
+		wantErr    error
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "first request is processed",
+			steps: []step{
+				{hash: "a"},
+			},
+		},
+		{
+			name: "retry while in progress",
+			steps: []step{
+				{hash: "a"},
+				{hash: "a", wantErr: ErrIdempotencyInProgress},
+			},
+		},
+		{
+			name: "retry after completion is replayed",
+			steps: []step{
+				{hash: "a", complete: 201},
+				{hash: "a", wantReplay: true},
+			},
+		},
+		{
+			name: "client errors are replayed too",
+			steps: []step{
+				{hash: "a", complete: 400},
+				{hash: "a", wantReplay: true},
+			},
+		},
+		{
+			name: "different payload",
+			steps: []step{
+				{hash: "a", complete: 201},
+				{hash: "b", wantErr: ErrIdempotencyKeyReused},
+			},
+		},
+		{
+			name: "server errors release the key",
+			steps: []step{
+				{hash: "a", complete: 500},
+				{hash: "a"},
+			},
+		},
+		{
+			name: "expired key can be used again",
+			steps: []step{
+				{hash: "a", complete: 201},
+				{hash: "b", advance: 2 * time.Hour},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			now := time.Now()
+			env.store.Now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				record, err := env.idempotency.Begin(ctx, "7", "key-1", s.hash)
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d: Begin() error = %v, want %v", i, err, s.wantErr)
+				}
+				if (record != nil) != s.wantReplay {
+					t.Fatalf("step %d: Begin() record = %+v, want replay %v", i, record, s.wantReplay)
+				}
+				if s.wantReplay && record.StatusCode != tt.steps[0].complete {
+					t.Errorf("step %d: replayed status = %d, want %d", i, record.StatusCode, tt.steps[0].complete)
+				}
+				if s.complete != 0 {
+					if err := env.idempotency.Complete(ctx, "7", "key-1", s.complete, `{"id":1}`); err != nil {
+						t.Fatalf("step %d: Complete() error = %v", i, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyServiceOwnersAndFailures(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := env.idempotency.Begin(ctx, "7", "key-1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.idempotency.Complete(ctx, "7", "key-1", 201, `{"id":1}`); err != nil {
+		t.Fatal(err)
+	}
+	// Another user's key of the same name is processed, not replayed
+	if record, err := env.idempotency.Begin(ctx, "8", "key-1", "b"); err != nil || record != nil {
+		t.Errorf("Begin() of another owner = %+v, %v; want it processed", record, err)
+	}
+
+	// A request that failed unexpectedly refuses its retries
+	if err := env.idempotency.Fail(ctx, "8", "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.idempotency.Begin(ctx, "8", "key-1", "b"); !errors.Is(err, ErrIdempotencyFailed) {
+		t.Errorf("Begin() after failure error = %v, want ErrIdempotencyFailed", err)
+	}
+	if record, err := env.idempotency.Begin(ctx, "7", "key-1", "a"); err != nil || record == nil || record.StatusCode != 201 {
+		t.Errorf("Begin() of the first owner = %+v, %v; want its response replayed", record, err)
+	}
+}
+
+func TestIdempotencyServicePurgeExpired(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	now := time.Now()
+	env.store.Now = func() time.Time { return now }
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := env.idempotency.Begin(ctx, "7", key, "hash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if removed, err := env.idempotency.PurgeExpired(ctx); err != nil || removed != 0 {
+		t.Errorf("PurgeExpired() = %d, %v; want 0 before expiry", removed, err)
+	}
+	now = now.Add(2 * time.Hour)
+	if removed, err := env.idempotency.PurgeExpired(ctx); err != nil || removed != 2 {
+		t.Errorf("PurgeExpired() = %d, %v; want 2 after expiry", removed, err)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "validation", err: errors.New("quantity must be greater than 0")},
+		{name: "insufficient stock", err: errors.New("insufficient stock for product with ID 1")},
+		{name: "foreign key violation", err: &pq.Error{Code: "23503"}},
+		{name: "cancelled request", err: fmt.Errorf("creating transaction: %w", context.Canceled), want: true},
+		{name: "timeout", err: context.DeadlineExceeded, want: true},
+		{name: "broken connection", err: driver.ErrBadConn, want: true},
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, want: true},
+		{name: "server shutting down", err: &pq.Error{Code: "57P01"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// LoyaltyProgram holds the rules of the loyalty program
+type LoyaltyProgram struct {
+	// RupiahPerPoint is how much a customer spends to earn one point before
+	// the category multipliers
+	RupiahPerPoint int
+	// PointValue is the Rupiah a redeemed point pays
+	PointValue int
+	// PointsTTL is how long earned points last; 0 keeps them forever
+	PointsTTL time.Duration
+}
+
+// LoyaltyService handles business logic for the loyalty points of customers
+type LoyaltyService struct {
+	repo         repositories.LoyaltyRepository
+	categoryRepo repositories.CategoryRepository
+	customerRepo repositories.CustomerRepository
+	program      LoyaltyProgram
+}
+
+// NewLoyaltyService creates a new LoyaltyService
+func NewLoyaltyService(repo repositories.LoyaltyRepository, categoryRepo repositories.CategoryRepository, customerRepo repositories.CustomerRepository, program LoyaltyProgram) *LoyaltyService {
+	return &LoyaltyService{
+		repo:         repo,
+		categoryRepo: categoryRepo,
+		customerRepo: customerRepo,
+		program:      program,
+	}
+}
+
+// GetPoints returns the points balance of a customer with its ledger
+func (s *LoyaltyService) GetPoints(ctx context.Context, customerID int) (*models.PointsStatement, error) {
+	customer, err := s.customerRepo.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.GetLedger(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.PointsEntry{}
+	}
+	return &models.PointsStatement{
+		CustomerID: customer.ID,
+		Balance:    customer.PointsBalance,
+		PointValue: s.program.PointValue,
+		Entries:    entries,
+	}, nil
+}
+
+// ExpirePoints expires the points left in lots past their expiry
+func (s *LoyaltyService) ExpirePoints(ctx context.Context) (int, error) {
+	expired, err := s.repo.ExpirePoints(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if expired > 0 {
+		slog.InfoContext(ctx, "loyalty points expired", "points", expired)
+	}
+	return expired, nil
+}
+
+// applyPoints sets the points the customer of a transaction redeems and
+// earns. Redeemed points pay part of the total like a tender, so the total
+// and the reports are unchanged; only the part paid with money earns points,
+// each line at the multiplier of the category of its product.
+func (s *LoyaltyService) applyPoints(ctx context.Context, transaction *models.Transaction, products map[int]models.Product, redeem int) error {
+	if redeem < 0 {
+		return fmt.Errorf("redeem_points must not be negative")
+	}
+	if transaction.CustomerID == nil {
+		if redeem > 0 {
+			return fmt.Errorf("redeeming points requires a customer")
+		}
+		return nil
+	}
+
+	amount := redeem * s.program.PointValue
+	if amount > transaction.TotalAmount {
+		return fmt.Errorf("%d points are worth Rp %d, more than the total of Rp %d", redeem, amount, transaction.TotalAmount)
+	}
+
+	categories, err := s.categoryRepo.GetAll(ctx, models.CategoryFilter{IncludeDeleted: true})
+	if err != nil {
+		return err
+	}
+	multipliers := make(map[int]float64, len(categories))
+	for _, c := range categories {
+		if c.PointsMultiplier != nil {
+			multipliers[c.ID] = *c.PointsMultiplier
+		}
+	}
+
+	var spent float64
+	for _, d := range transaction.Details {
+		multiplier := 1.0
+		if categoryID := products[d.ProductID].CategoryID; categoryID != nil {
+			if m, ok := multipliers[*categoryID]; ok {
+				multiplier = m
+			}
+		}
+		spent += float64(d.Subtotal) * multiplier
+	}
+	if transaction.TotalAmount > 0 {
+		spent *= float64(transaction.TotalAmount-amount) / float64(transaction.TotalAmount)
+	}
+
+	transaction.PointsRedeemed = redeem
+	transaction.PointsAmount = amount
+	transaction.PointsEarned = int(math.Floor(spent/float64(s.program.RupiahPerPoint) + 1e-9))
+	if transaction.PointsEarned > 0 && s.program.PointsTTL > 0 {
+		expiresAt := time.Now().Add(s.program.PointsTTL)
+		transaction.PointsExpireAt = &expiresAt
+	}
+	return nil
+}
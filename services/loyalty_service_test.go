@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// newLoyaltyCustomer creates a customer for the loyalty tests
+func newLoyaltyCustomer(t *testing.T, env *testEnv) int {
+	t.Helper()
+	customer, err := env.customers.CreateCustomer(context.Background(), models.Customer{Name: "Budi", MemberNumber: "M-001"})
+	if err != nil {
+		t.Fatalf("CreateCustomer() error = %v", err)
+	}
+	return customer.ID
+}
+
+// buy checks out items for a customer, redeeming points
+func buy(t *testing.T, env *testEnv, customerID, redeem int, items ...models.TransactionItem) *models.Transaction {
+	t.Helper()
+	transaction, err := env.transactions.CreateTransaction(context.Background(), models.CreateTransactionRequest{
+		CustomerID:   &customerID,
+		RedeemPoints: redeem,
+		Items:        items,
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	return transaction
+}
+
+// checkPoints checks the balance of a customer and that the points left in
+// the lots of the ledger add up to it
+func checkPoints(t *testing.T, env *testEnv, customerID, want int) *models.PointsStatement {
+	t.Helper()
+	statement, err := env.loyalty.GetPoints(context.Background(), customerID)
+	if err != nil {
+		t.Fatalf("GetPoints() error = %v", err)
+	}
+	if statement.Balance != want {
+		t.Errorf("balance = %d, want %d", statement.Balance, want)
+	}
+	var remaining int
+	for _, e := range statement.Entries {
+		remaining += e.Remaining
+	}
+	if remaining != max(want, 0) {
+		t.Errorf("points left in lots = %d, want %d", remaining, max(want, 0))
+	}
+	return statement
+}
+
+func TestLoyaltyServiceEarn(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	customerID := newLoyaltyCustomer(t, env)
+
+	// 3 Kopi for Rp 15.000 earn a point per Rp 1.000
+	sale := buy(t, env, customerID, 0, models.TransactionItem{ProductID: kopiID, Quantity: 3})
+	if sale.PointsEarned != 15 || sale.PointsRedeemed != 0 || sale.PointsAmount != 0 {
+		t.Errorf("points = %d earned, %d redeemed for Rp %d, want 15 earned", sale.PointsEarned, sale.PointsRedeemed, sale.PointsAmount)
+	}
+	if sale.PointsExpireAt == nil || time.Until(*sale.PointsExpireAt) < 364*24*time.Hour {
+		t.Errorf("points expire at %v, want in a year", sale.PointsExpireAt)
+	}
+	statement := checkPoints(t, env, customerID, 15)
+	if statement.PointValue != 10 || len(statement.Entries) != 1 || statement.Entries[0].Type != models.PointsEarn {
+		t.Errorf("statement = %+v, want one earn entry worth Rp 10 a point", statement)
+	}
+
+	// Sembako earns double; 1 kg Beras for Rp 12.000 earns 24 points
+	multiplier := 2.0
+	if _, err := env.categories.UpdateCategory(ctx, 1, models.Category{Name: "Sembako", PointsMultiplier: &multiplier}); err != nil {
+		t.Fatalf("UpdateCategory() error = %v", err)
+	}
+	sale = buy(t, env, customerID, 0, models.TransactionItem{ProductID: berasID, Quantity: 1})
+	if sale.PointsEarned != 24 {
+		t.Errorf("points earned = %d, want 24", sale.PointsEarned)
+	}
+	checkPoints(t, env, customerID, 39)
+
+	// Anonymous sales earn nothing
+	anonymous, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if anonymous.PointsEarned != 0 {
+		t.Errorf("anonymous sale earned %d points, want 0", anonymous.PointsEarned)
+	}
+}
+
+func TestLoyaltyServiceRedeem(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := newLoyaltyCustomer(t, env)
+	buy(t, env, customerID, 0, models.TransactionItem{ProductID: kopiID, Quantity: 3})
+
+	// 10 points pay Rp 100 of a Rp 5.000 sale; the other Rp 4.900 earns 4
+	sale := buy(t, env, customerID, 10, models.TransactionItem{ProductID: kopiID, Quantity: 1})
+	if sale.TotalAmount != 5000 || sale.PointsRedeemed != 10 || sale.PointsAmount != 100 || sale.PointsEarned != 4 {
+		t.Errorf("sale = total %d, %d redeemed for Rp %d, %d earned; want 5000, 10 for Rp 100, 4",
+			sale.TotalAmount, sale.PointsRedeemed, sale.PointsAmount, sale.PointsEarned)
+	}
+	statement := checkPoints(t, env, customerID, 9)
+	if got := statement.Entries[1]; got.Type != models.PointsRedeem || got.Points != -10 || got.ExpiresAt == nil {
+		t.Errorf("redeem entry = %+v, want -10 with the expiry of the lot it used", got)
+	}
+}
+
+func TestLoyaltyServiceRedeemRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		customer bool
+		redeem   int
+		wantErr  string
+	}{
+		{name: "more than the balance", customer: true, redeem: 16, wantErr: repositories.ErrInsufficientPoints.Error()},
+		{name: "worth more than the total", customer: true, redeem: 501, wantErr: "501 points are worth Rp 5010, more than the total of Rp 5000"},
+		{name: "negative", customer: true, redeem: -1, wantErr: "redeem_points must not be negative"},
+		{name: "without customer", redeem: 5, wantErr: "redeeming points requires a customer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			customerID := newLoyaltyCustomer(t, env)
+			buy(t, env, customerID, 0, models.TransactionItem{ProductID: kopiID, Quantity: 3})
+
+			req := models.CreateTransactionRequest{
+				RedeemPoints: tt.redeem,
+				Items:        []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+			}
+			if tt.customer {
+				req.CustomerID = &customerID
+			}
+			_, err := env.transactions.CreateTransaction(context.Background(), req)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CreateTransaction() error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.redeem == 16 && !errors.Is(err, repositories.ErrInsufficientPoints) {
+				t.Errorf("CreateTransaction() error = %v, want ErrInsufficientPoints", err)
+			}
+
+			// Nothing was sold or booked
+			if got := env.stockOf(t, kopiID); got != 7 {
+				t.Errorf("Kopi stock = %v, want 7", got)
+			}
+			checkPoints(t, env, customerID, 15)
+		})
+	}
+}
+
+func TestLoyaltyServiceVoid(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	customerID := newLoyaltyCustomer(t, env)
+
+	first := buy(t, env, customerID, 0, models.TransactionItem{ProductID: kopiID, Quantity: 3})
+	second := buy(t, env, customerID, 10, models.TransactionItem{ProductID: kopiID, Quantity: 1})
+	checkPoints(t, env, customerID, 9)
+
+	// Voiding the second sale takes back its 4 points and returns the 10 it redeemed
+	if err := env.transactions.DeleteTransaction(ctx, second.ID); err != nil {
+		t.Fatalf("DeleteTransaction() error = %v", err)
+	}
+	statement := checkPoints(t, env, customerID, 15)
+	if statement.Entries[0].Type != models.PointsVoidRedeem || statement.Entries[0].Points != 10 ||
+		statement.Entries[1].Type != models.PointsVoidEarn || statement.Entries[1].Points != -4 {
+		t.Errorf("entries = %+v, want void_redeem +10 after void_earn -4", statement.Entries[:2])
+	}
+
+	// Spend the points of the first sale, then void it: the balance goes
+	// negative and the next points earned pay it off first
+	buy(t, env, customerID, 15, models.TransactionItem{ProductID: berasID, Quantity: 1})
+	checkPoints(t, env, customerID, 11)
+	if err := env.transactions.DeleteTransaction(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteTransaction() error = %v", err)
+	}
+	checkPoints(t, env, customerID, -4)
+
+	buy(t, env, customerID, 0, models.TransactionItem{ProductID: kopiID, Quantity: 2})
+	checkPoints(t, env, customerID, 6)
+}
+
+func TestLoyaltyServiceExpirePoints(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	customerID := newLoyaltyCustomer(t, env)
+	buy(t, env, customerID, 0, models.TransactionItem{ProductID: kopiID, Quantity: 3})
+	buy(t, env, customerID, 5, models.TransactionItem{ProductID: kopiID, Quantity: 1})
+	checkPoints(t, env, customerID, 14)
+
+	if expired, err := env.loyalty.ExpirePoints(ctx); err != nil || expired != 0 {
+		t.Fatalf("ExpirePoints() = %d, %v; want nothing expired yet", expired, err)
+	}
+
+	env.store.Now = func() time.Time { return time.Now().Add(366 * 24 * time.Hour) }
+	expired, err := env.loyalty.ExpirePoints(ctx)
+	if err != nil {
+		t.Fatalf("ExpirePoints() error = %v", err)
+	}
+	if expired != 14 {
+		t.Errorf("ExpirePoints() = %d, want 14", expired)
+	}
+	statement := checkPoints(t, env, customerID, 0)
+	for _, e := range statement.Entries[:2] {
+		if e.Type != models.PointsExpire {
+			t.Errorf("entry = %+v, want an expiry", e)
+		}
+	}
+
+	_, err = env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		CustomerID:   &customerID,
+		RedeemPoints: 1,
+		Items:        []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+	})
+	if !errors.Is(err, repositories.ErrInsufficientPoints) {
+		t.Errorf("CreateTransaction() error = %v, want ErrInsufficientPoints", err)
+	}
+}
+
+func TestLoyaltyServiceRedeemExpiredLots(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	customerID := newLoyaltyCustomer(t, env)
+	buy(t, env, customerID, 0, models.TransactionItem{ProductID: kopiID, Quantity: 3})
+
+	// The lot expired but ExpirePoints has not run yet: its points cannot
+	// be spent and the sale books their expiry
+	env.store.Now = func() time.Time { return time.Now().Add(366 * 24 * time.Hour) }
+	_, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		CustomerID:   &customerID,
+		RedeemPoints: 10,
+		Items:        []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+	})
+	if !errors.Is(err, repositories.ErrInsufficientPoints) {
+		t.Fatalf("CreateTransaction() error = %v, want ErrInsufficientPoints", err)
+	}
+
+	sale := buy(t, env, customerID, 0, models.TransactionItem{ProductID: kopiID, Quantity: 1})
+	statement := checkPoints(t, env, customerID, sale.PointsEarned)
+	if e := statement.Entries[1]; e.Type != models.PointsExpire || e.Points != -15 {
+		t.Errorf("entry = %+v, want the expiry of the 15 points", e)
+	}
+}
+
+func TestLoyaltyServiceUnknownCustomer(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.loyalty.GetPoints(context.Background(), 99); err == nil || err.Error() != "Customer with ID 99 not found" {
+		t.Errorf("GetPoints() error = %v, want not found", err)
+	}
+}
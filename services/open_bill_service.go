@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// ErrBillClosed is wrapped by the errors returned when an open bill is
+// changed after it was checked out or cancelled
+var ErrBillClosed = repositories.ErrBillClosed
+
+// ErrBillChanged is wrapped by the errors returned when the items of an open
+// bill change while it is checked out
+var ErrBillChanged = repositories.ErrBillChanged
+
+// OpenBillService handles business logic for open bills: orders parked under a
+// table number or customer name that are checked out into a transaction later
+type OpenBillService struct {
+	repo         repositories.OpenBillRepository
+	outletRepo   repositories.OutletRepository
+	transactions *TransactionService
+}
+
+// NewOpenBillService creates a new OpenBillService; bills are priced and
+// checked out with the transaction service
+func NewOpenBillService(repo repositories.OpenBillRepository, outletRepo repositories.OutletRepository, transactions *TransactionService) *OpenBillService {
+	return &OpenBillService{repo: repo, outletRepo: outletRepo, transactions: transactions}
+}
+
+// GetAllOpenBills returns the bills matching filter, newest first
+func (s *OpenBillService) GetAllOpenBills(ctx context.Context, filter models.OpenBillFilter) ([]models.OpenBill, error) {
+	switch filter.Status {
+	case "", models.BillOpen, models.BillCheckedOut, models.BillCancelled:
+	default:
+		return nil, fmt.Errorf("unknown open bill status %q", filter.Status)
+	}
+	return s.repo.GetAll(ctx, filter)
+}
+
+// GetOpenBillByID returns a bill by ID with its items; a bill that is still
+// open is priced at the current prices
+func (s *OpenBillService) GetOpenBillByID(ctx context.Context, id int) (*models.OpenBill, error) {
+	bill, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.PriceOpenBill(ctx, bill)
+	return bill, nil
+}
+
+// LookupOpenBill returns a bill by ID with its items as stored, without
+// pricing it, so a bill whose items can no longer be sold can still be read,
+// changed and cancelled
+func (s *OpenBillService) LookupOpenBill(ctx context.Context, id int) (*models.OpenBill, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// PriceOpenBill sets the total of a bill that is still open to what its
+// items cost at the current prices. Pricing is best effort: when an item
+// cannot be priced any more, for instance because its product was deleted or
+// its unit conversion removed, the total is left out and the error only
+// surfaces at checkout.
+func (s *OpenBillService) PriceOpenBill(ctx context.Context, bill *models.OpenBill) {
+	bill.TotalAmount = 0
+	if bill.Status != models.BillOpen || len(bill.Items) == 0 {
+		return
+	}
+	transaction, _, err := s.transactions.buildTransaction(ctx, bill.OutletID, transactionItems(bill.Items), priceContext{at: time.Now()})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to price open bill", "open_bill_id", bill.ID, "error", err)
+		return
+	}
+	bill.TotalAmount = transaction.TotalAmount
+}
+
+// OpenBill opens a bill at an outlet, the main outlet unless the bill names
+// one, with optional first items
+func (s *OpenBillService) OpenBill(ctx context.Context, bill models.OpenBill) (*models.OpenBill, error) {
+	bill.Label = strings.TrimSpace(bill.Label)
+	if bill.Label == "" {
+		return nil, fmt.Errorf("open bill label is required")
+	}
+	if len(bill.Label) > 100 {
+		return nil, fmt.Errorf("open bill label must be at most 100 characters")
+	}
+	if bill.OutletID == 0 {
+		bill.OutletID = models.DefaultOutletID
+	}
+	if _, err := s.outletRepo.GetByID(ctx, bill.OutletID); err != nil {
+		return nil, err
+	}
+	if len(bill.Items) > 0 {
+		if err := s.prepareItems(ctx, bill.OutletID, bill.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	created, err := s.repo.Create(ctx, bill)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOpenBillByID(ctx, created.ID)
+}
+
+// AddBillItem adds an item to an open bill and returns the bill
+func (s *OpenBillService) AddBillItem(ctx context.Context, billID int, item models.OpenBillItem) (*models.OpenBill, error) {
+	bill, err := s.openBill(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+	items := []models.OpenBillItem{item}
+	if err := s.prepareItems(ctx, bill.OutletID, items); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.AddItem(ctx, billID, items[0]); err != nil {
+		return nil, err
+	}
+	return s.GetOpenBillByID(ctx, billID)
+}
+
+// UpdateBillItem replaces an item of an open bill and returns the bill
+func (s *OpenBillService) UpdateBillItem(ctx context.Context, billID, itemID int, item models.OpenBillItem) (*models.OpenBill, error) {
+	bill, err := s.openBill(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+	items := []models.OpenBillItem{item}
+	if err := s.prepareItems(ctx, bill.OutletID, items); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.UpdateItem(ctx, billID, itemID, items[0]); err != nil {
+		return nil, err
+	}
+	return s.GetOpenBillByID(ctx, billID)
+}
+
+// RemoveBillItem removes an item from an open bill and returns the bill
+func (s *OpenBillService) RemoveBillItem(ctx context.Context, billID, itemID int) (*models.OpenBill, error) {
+	if err := s.repo.RemoveItem(ctx, billID, itemID); err != nil {
+		return nil, err
+	}
+	return s.GetOpenBillByID(ctx, billID)
+}
+
+// CancelBill closes an open bill without a sale, giving back the stock it
+// reserved
+func (s *OpenBillService) CancelBill(ctx context.Context, id int) error {
+	return s.repo.Cancel(ctx, id)
+}
+
+// CheckoutBill turns an open bill into a transaction at the outlet of the
+// bill. The items are priced again at checkout; the stock the bill reserved
+// becomes the stock the sale takes. When the items change meanwhile the
+// checkout fails with ErrBillChanged and can be tried again.
+func (s *OpenBillService) CheckoutBill(ctx context.Context, id int, req models.CheckoutBillRequest) (*models.Transaction, error) {
+	bill, err := s.openBill(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(bill.Items) == 0 {
+		return nil, fmt.Errorf("open bill must have at least one item")
+	}
+
+	return s.transactions.createTransaction(ctx, models.CreateTransactionRequest{
+		OutletID:     bill.OutletID,
+		CustomerID:   req.CustomerID,
+		PriceListID:  req.PriceListID,
+		RedeemPoints: req.RedeemPoints,
+		Items:        transactionItems(bill.Items),
+	}, func(ctx context.Context, transaction models.Transaction) (*models.Transaction, error) {
+		return s.repo.Checkout(ctx, id, bill.Items, transaction)
+	})
+}
+
+// openBill returns a bill that must still be open
+func (s *OpenBillService) openBill(ctx context.Context, id int) (*models.OpenBill, error) {
+	bill, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if bill.Status != models.BillOpen {
+		return nil, fmt.Errorf("%w: open bill with ID %d is %s", ErrBillClosed, id, bill.Status)
+	}
+	return bill, nil
+}
+
+// prepareItems checks items the way a sale checks its items and works out
+// the stock each holds if its bill reserves stock
+func (s *OpenBillService) prepareItems(ctx context.Context, outletID int, items []models.OpenBillItem) error {
+	transaction, _, err := s.transactions.buildTransaction(ctx, outletID, transactionItems(items), priceContext{at: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	for i, d := range transaction.Details {
+		item := &items[i]
+		item.Unit = d.Unit
+		item.Note = strings.TrimSpace(item.Note)
+		item.Reserved = nil
+		if len(d.Components) > 0 {
+			for _, c := range d.Components {
+				item.Reserved = append(item.Reserved, models.ReservedStock{ProductID: c.ProductID, Quantity: c.Quantity})
+			}
+			continue
+		}
+		item.Reserved = []models.ReservedStock{{ProductID: d.ProductID, VariantID: d.VariantID, Quantity: d.Quantity}}
+	}
+	return nil
+}
+
+// transactionItems returns the items of a bill as the items of a sale
+func transactionItems(items []models.OpenBillItem) []models.TransactionItem {
+	transactionItems := make([]models.TransactionItem, len(items))
+	for i, item := range items {
+		transactionItems[i] = models.TransactionItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Unit:      item.Unit,
+		}
+	}
+	return transactionItems
+}
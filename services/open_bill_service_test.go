@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/repositories/memory"
+)
+
+func TestOpenBillServiceReservation(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	bill, err := env.openBills.OpenBill(ctx, models.OpenBill{
+		Label:        " Meja 4 ",
+		ReserveStock: true,
+		Items: []models.OpenBillItem{
+			{ProductID: kopiID, Quantity: 2, Note: " tanpa gula "},
+			{ProductID: paketID, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("OpenBill() error = %v", err)
+	}
+	if bill.Label != "Meja 4" || bill.Status != models.BillOpen || bill.OutletID != models.DefaultOutletID {
+		t.Errorf("bill = %+v, want open bill Meja 4 at the main outlet", bill)
+	}
+	if bill.TotalAmount != 25000 {
+		t.Errorf("total = %d, want 25000", bill.TotalAmount)
+	}
+	if bill.Items[0].Note != "tanpa gula" || bill.Items[0].Unit != "pcs" {
+		t.Errorf("item = %+v, want note and unit", bill.Items[0])
+	}
+
+	// The bundle reserves its components
+	if got := env.stockOf(t, kopiID); got != 6 {
+		t.Errorf("Kopi stock = %v, want 6", got)
+	}
+	if got := env.stockOf(t, berasID); got != 4.5 {
+		t.Errorf("Beras stock = %v, want 4.5", got)
+	}
+
+	bill, err = env.openBills.UpdateBillItem(ctx, bill.ID, bill.Items[0].ID, models.OpenBillItem{ProductID: kopiID, Quantity: 3})
+	if err != nil {
+		t.Fatalf("UpdateBillItem() error = %v", err)
+	}
+	if got := env.stockOf(t, kopiID); got != 5 {
+		t.Errorf("Kopi stock after update = %v, want 5", got)
+	}
+
+	// Reserved stock cannot be sold to someone else
+	_, err = env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 6}},
+	})
+	if !errors.Is(err, repositories.ErrInsufficientStock) {
+		t.Errorf("CreateTransaction() error = %v, want insufficient stock", err)
+	}
+
+	bill, err = env.openBills.RemoveBillItem(ctx, bill.ID, bill.Items[1].ID)
+	if err != nil {
+		t.Fatalf("RemoveBillItem() error = %v", err)
+	}
+	if got := env.stockOf(t, kopiID); got != 7 {
+		t.Errorf("Kopi stock after removal = %v, want 7", got)
+	}
+	if got := env.stockOf(t, berasID); got != 5 {
+		t.Errorf("Beras stock after removal = %v, want 5", got)
+	}
+
+	// Checkout turns the reservation into the sale
+	transaction, err := env.openBills.CheckoutBill(ctx, bill.ID, models.CheckoutBillRequest{})
+	if err != nil {
+		t.Fatalf("CheckoutBill() error = %v", err)
+	}
+	if transaction.TotalAmount != 15000 || len(transaction.Details) != 1 {
+		t.Errorf("transaction = %+v, want 3 Kopi for 15000", transaction)
+	}
+	if got := env.stockOf(t, kopiID); got != 7 {
+		t.Errorf("Kopi stock after checkout = %v, want 7", got)
+	}
+
+	bill, err = env.openBills.GetOpenBillByID(ctx, bill.ID)
+	if err != nil {
+		t.Fatalf("GetOpenBillByID() error = %v", err)
+	}
+	if bill.Status != models.BillCheckedOut || bill.TransactionID == nil || *bill.TransactionID != transaction.ID || bill.ClosedAt == nil {
+		t.Errorf("bill = %+v, want checked out into transaction %d", bill, transaction.ID)
+	}
+
+	_, err = env.openBills.AddBillItem(ctx, bill.ID, models.OpenBillItem{ProductID: kopiID, Quantity: 1})
+	if !errors.Is(err, ErrBillClosed) {
+		t.Errorf("AddBillItem() on checked out bill error = %v, want ErrBillClosed", err)
+	}
+	if _, err := env.openBills.CheckoutBill(ctx, bill.ID, models.CheckoutBillRequest{}); !errors.Is(err, ErrBillClosed) {
+		t.Errorf("CheckoutBill() twice error = %v, want ErrBillClosed", err)
+	}
+}
+
+func TestOpenBillServiceWithoutReservation(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	bill, err := env.openBills.OpenBill(ctx, models.OpenBill{Label: "Budi"})
+	if err != nil {
+		t.Fatalf("OpenBill() error = %v", err)
+	}
+	bill, err = env.openBills.AddBillItem(ctx, bill.ID, models.OpenBillItem{ProductID: kopiID, Quantity: 8})
+	if err != nil {
+		t.Fatalf("AddBillItem() error = %v", err)
+	}
+	if bill.TotalAmount != 40000 || bill.Items[0].Reserved != nil {
+		t.Errorf("bill = %+v, want 40000 without reservations", bill)
+	}
+	if got := env.stockOf(t, kopiID); got != 10 {
+		t.Errorf("Kopi stock = %v, want 10", got)
+	}
+
+	// Another sale takes the stock first; the bill stays open
+	if _, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 5}},
+	}); err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if _, err := env.openBills.CheckoutBill(ctx, bill.ID, models.CheckoutBillRequest{}); !errors.Is(err, repositories.ErrInsufficientStock) {
+		t.Fatalf("CheckoutBill() error = %v, want insufficient stock", err)
+	}
+	bill, err = env.openBills.GetOpenBillByID(ctx, bill.ID)
+	if err != nil || bill.Status != models.BillOpen {
+		t.Fatalf("bill = %+v, %v, want still open", bill, err)
+	}
+
+	bill, err = env.openBills.UpdateBillItem(ctx, bill.ID, bill.Items[0].ID, models.OpenBillItem{ProductID: kopiID, Quantity: 5})
+	if err != nil {
+		t.Fatalf("UpdateBillItem() error = %v", err)
+	}
+	transaction, err := env.openBills.CheckoutBill(ctx, bill.ID, models.CheckoutBillRequest{})
+	if err != nil {
+		t.Fatalf("CheckoutBill() error = %v", err)
+	}
+	if transaction.TotalAmount != 25000 {
+		t.Errorf("total = %d, want 25000", transaction.TotalAmount)
+	}
+	if got := env.stockOf(t, kopiID); got != 0 {
+		t.Errorf("Kopi stock = %v, want 0", got)
+	}
+}
+
+func TestOpenBillServiceCancel(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	bill, err := env.openBills.OpenBill(ctx, models.OpenBill{
+		Label:        "Meja 1",
+		ReserveStock: true,
+		Items:        []models.OpenBillItem{{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("OpenBill() error = %v", err)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, esTehID, intPtr(esTehLargeID)); got != 0 {
+		t.Errorf("Es Teh L stock = %v, want 0", got)
+	}
+
+	// The label is free again once the bill is closed
+	if _, err := env.openBills.OpenBill(ctx, models.OpenBill{Label: "meja 1"}); err == nil {
+		t.Error("OpenBill() with the label of an open bill succeeded")
+	}
+	if err := env.openBills.CancelBill(ctx, bill.ID); err != nil {
+		t.Fatalf("CancelBill() error = %v", err)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, esTehID, intPtr(esTehLargeID)); got != 2 {
+		t.Errorf("Es Teh L stock after cancel = %v, want 2", got)
+	}
+	if _, err := env.openBills.OpenBill(ctx, models.OpenBill{Label: "Meja 1"}); err != nil {
+		t.Errorf("OpenBill() after cancel error = %v", err)
+	}
+	if err := env.openBills.CancelBill(ctx, bill.ID); !errors.Is(err, ErrBillClosed) {
+		t.Errorf("CancelBill() twice error = %v, want ErrBillClosed", err)
+	}
+
+	bills, err := env.openBills.GetAllOpenBills(ctx, models.OpenBillFilter{Status: models.BillCancelled})
+	if err != nil || len(bills) != 1 || bills[0].ID != bill.ID {
+		t.Errorf("cancelled bills = %+v, %v, want bill %d", bills, err, bill.ID)
+	}
+}
+
+func TestOpenBillServiceUnpriceable(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	bill, err := env.openBills.OpenBill(ctx, models.OpenBill{
+		Label:        "Meja 3",
+		ReserveStock: true,
+		Items:        []models.OpenBillItem{{ProductID: kopiID, Quantity: 1, Unit: "box"}},
+	})
+	if err != nil {
+		t.Fatalf("OpenBill() error = %v", err)
+	}
+	if err := env.units.DeleteConversion(ctx, kopiID, "box"); err != nil {
+		t.Fatalf("DeleteConversion() error = %v", err)
+	}
+
+	// The bill is read without a total; only the checkout needs its prices
+	got, err := env.openBills.GetOpenBillByID(ctx, bill.ID)
+	if err != nil || got.TotalAmount != 0 || len(got.Items) != 1 {
+		t.Fatalf("GetOpenBillByID() = %+v, %v; want the bill without a total", got, err)
+	}
+	if _, err := env.openBills.CheckoutBill(ctx, bill.ID, models.CheckoutBillRequest{}); err == nil {
+		t.Error("CheckoutBill() of an unpriceable bill succeeded")
+	}
+	if err := env.openBills.CancelBill(ctx, bill.ID); err != nil {
+		t.Fatalf("CancelBill() error = %v", err)
+	}
+	if got := env.stockOf(t, kopiID); got != 10 {
+		t.Errorf("Kopi stock after cancel = %v, want 10", got)
+	}
+}
+
+// racingBillRepository adds an item to a bill just before its checkout
+// locks it, the way a waiter adding an order at the same time would
+type racingBillRepository struct {
+	repositories.OpenBillRepository
+}
+
+func (r racingBillRepository) Checkout(ctx context.Context, id int, items []models.OpenBillItem, transaction models.Transaction) (*models.Transaction, error) {
+	_, err := r.AddItem(ctx, id, models.OpenBillItem{
+		ProductID: kopiID, Quantity: 1, Unit: "pcs",
+		Reserved: []models.ReservedStock{{ProductID: kopiID, Quantity: 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.OpenBillRepository.Checkout(ctx, id, items, transaction)
+}
+
+func TestOpenBillServiceCheckoutChangedBill(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	repo := memory.NewOpenBillRepository(env.store)
+
+	bill, err := env.openBills.OpenBill(ctx, models.OpenBill{
+		Label:        "Meja 5",
+		ReserveStock: true,
+		Items:        []models.OpenBillItem{{ProductID: kopiID, Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("OpenBill() error = %v", err)
+	}
+
+	racing := NewOpenBillService(racingBillRepository{repo}, memory.NewOutletRepository(env.store), env.transactions)
+	if _, err := racing.CheckoutBill(ctx, bill.ID, models.CheckoutBillRequest{}); !errors.Is(err, ErrBillChanged) {
+		t.Fatalf("CheckoutBill() of a changed bill error = %v, want ErrBillChanged", err)
+	}
+
+	// The bill stays open with both items reserved and checks out in full
+	if got := env.stockOf(t, kopiID); got != 7 {
+		t.Errorf("Kopi stock = %v, want 7", got)
+	}
+	transaction, err := env.openBills.CheckoutBill(ctx, bill.ID, models.CheckoutBillRequest{})
+	if err != nil {
+		t.Fatalf("CheckoutBill() error = %v", err)
+	}
+	if transaction.TotalAmount != 15000 || len(transaction.Details) != 2 {
+		t.Errorf("transaction = %+v, want both items for 15000", transaction)
+	}
+	if got := env.stockOf(t, kopiID); got != 7 {
+		t.Errorf("Kopi stock after checkout = %v, want 7", got)
+	}
+}
+
+func TestOpenBillServiceValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		bill    models.OpenBill
+		wantErr string
+	}{
+		{name: "label required", bill: models.OpenBill{Label: "  "}, wantErr: "label is required"},
+		{name: "label too long", bill: models.OpenBill{Label: strings.Repeat("x", 101)}, wantErr: "at most 100 characters"},
+		{name: "unknown outlet", bill: models.OpenBill{Label: "Meja 1", OutletID: 9}, wantErr: "Outlet with ID 9 not found"},
+		{name: "unknown product", bill: models.OpenBill{Label: "Meja 1", Items: []models.OpenBillItem{{ProductID: 99, Quantity: 1}}}, wantErr: "product with ID 99 not found"},
+		{name: "zero quantity", bill: models.OpenBill{Label: "Meja 1", Items: []models.OpenBillItem{{ProductID: kopiID}}}, wantErr: "quantity must be greater than 0"},
+		{name: "reserve beyond stock", bill: models.OpenBill{Label: "Meja 1", ReserveStock: true, Items: []models.OpenBillItem{{ProductID: kopiID, Quantity: 11}}}, wantErr: "insufficient stock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			_, err := env.openBills.OpenBill(context.Background(), tt.bill)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("OpenBill() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	env := newTestEnv(t)
+	if _, err := env.openBills.GetAllOpenBills(context.Background(), models.OpenBillFilter{Status: "paid"}); err == nil {
+		t.Error("GetAllOpenBills() with unknown status succeeded")
+	}
+	bill, err := env.openBills.OpenBill(context.Background(), models.OpenBill{Label: "Meja 2"})
+	if err != nil {
+		t.Fatalf("OpenBill() error = %v", err)
+	}
+	if _, err := env.openBills.CheckoutBill(context.Background(), bill.ID, models.CheckoutBillRequest{}); err == nil || !strings.Contains(err.Error(), "at least one item") {
+		t.Errorf("CheckoutBill() of empty bill error = %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// OutletService handles business logic for outlets and the stock and prices
+// of products at each outlet
+type OutletService struct {
+	repo        repositories.OutletRepository
+	productRepo repositories.ProductRepository
+}
+
+// NewOutletService creates a new OutletService
+func NewOutletService(repo repositories.OutletRepository, productRepo repositories.ProductRepository) *OutletService {
+	return &OutletService{repo: repo, productRepo: productRepo}
+}
+
+// GetAllOutlets returns all outlets
+func (s *OutletService) GetAllOutlets(ctx context.Context) ([]models.Outlet, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// GetOutletByID returns an outlet by ID
+func (s *OutletService) GetOutletByID(ctx context.Context, id int) (*models.Outlet, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// CreateOutlet creates a new outlet
+func (s *OutletService) CreateOutlet(ctx context.Context, outlet models.Outlet) (*models.Outlet, error) {
+	if err := validateOutlet(&outlet); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, outlet)
+}
+
+// UpdateOutlet updates an existing outlet
+func (s *OutletService) UpdateOutlet(ctx context.Context, id int, outlet models.Outlet) (*models.Outlet, error) {
+	if err := validateOutlet(&outlet); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, id, outlet)
+}
+
+// DeleteOutlet deletes an outlet without sales by ID; the main outlet is kept
+func (s *OutletService) DeleteOutlet(ctx context.Context, id int) error {
+	if id == models.DefaultOutletID {
+		return fmt.Errorf("the main outlet cannot be deleted")
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// GetOutletProducts returns the stock and price overrides of an outlet
+func (s *OutletService) GetOutletProducts(ctx context.Context, id int) ([]models.OutletProduct, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	products, err := s.repo.GetProducts(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if products == nil {
+		products = []models.OutletProduct{}
+	}
+	return products, nil
+}
+
+// SetOutletProduct sets the stock of a product or variant at an outlet and
+// the price that overrides the catalog price there; a nil price sells at the
+// catalog price
+func (s *OutletService) SetOutletProduct(ctx context.Context, product models.OutletProduct) (*models.OutletProduct, error) {
+	if product.Stock < 0 {
+		return nil, fmt.Errorf("stock must not be negative")
+	}
+	if product.Price != nil && *product.Price < 0 {
+		return nil, fmt.Errorf("price must not be negative")
+	}
+	if _, err := s.repo.GetByID(ctx, product.OutletID); err != nil {
+		return nil, err
+	}
+
+	// Bundles are assembled from the stock of their components
+	catalog, err := s.productRepo.GetByID(ctx, product.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if catalog.IsBundle && product.Stock != 0 {
+		return nil, fmt.Errorf("bundle product with ID %d has no stock of its own", product.ProductID)
+	}
+	return s.repo.SetProduct(ctx, product)
+}
+
+// validateOutlet trims the fields of an outlet and checks the code and name
+func validateOutlet(outlet *models.Outlet) error {
+	outlet.Code = strings.ToUpper(strings.TrimSpace(outlet.Code))
+	outlet.Name = strings.TrimSpace(outlet.Name)
+	outlet.Address = strings.TrimSpace(outlet.Address)
+
+	if outlet.Code == "" {
+		return fmt.Errorf("outlet code is required")
+	}
+	if outlet.Name == "" {
+		return fmt.Errorf("outlet name is required")
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// newBranch creates a second outlet for the outlet tests
+func newBranch(t *testing.T, env *testEnv) int {
+	t.Helper()
+	outlet, err := env.outlets.CreateOutlet(context.Background(), models.Outlet{Code: " cbg1 ", Name: "Cabang 1"})
+	if err != nil {
+		t.Fatalf("CreateOutlet() error = %v", err)
+	}
+	if outlet.Code != "CBG1" {
+		t.Errorf("code = %q, want CBG1", outlet.Code)
+	}
+	return outlet.ID
+}
+
+// outletStock returns the stock of a product, or of one of its variants, at an outlet
+func (env *testEnv) outletStock(t *testing.T, outletID, productID int, variantID *int) float64 {
+	t.Helper()
+	products, err := env.outlets.GetOutletProducts(context.Background(), outletID)
+	if err != nil {
+		t.Fatalf("GetOutletProducts(%d) error = %v", outletID, err)
+	}
+	for _, p := range products {
+		if p.ProductID == productID && (p.VariantID == nil) == (variantID == nil) && (variantID == nil || *p.VariantID == *variantID) {
+			return p.Stock
+		}
+	}
+	return 0
+}
+
+func TestOutletServiceStock(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	branchID := newBranch(t, env)
+
+	// The catalog stock is held by the main outlet; stocking the branch adds to the total
+	if got := env.outletStock(t, models.DefaultOutletID, kopiID, nil); got != 10 {
+		t.Errorf("main outlet Kopi stock = %v, want 10", got)
+	}
+	if _, err := env.outlets.SetOutletProduct(ctx, models.OutletProduct{OutletID: branchID, ProductID: kopiID, Stock: 4}); err != nil {
+		t.Fatalf("SetOutletProduct() error = %v", err)
+	}
+	if got := env.stockOf(t, kopiID); got != 14 {
+		t.Errorf("total Kopi stock = %v, want 14", got)
+	}
+
+	// A sale takes from the stock of its outlet only
+	_, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		OutletID: branchID,
+		Items:    []models.TransactionItem{{ProductID: kopiID, Quantity: 5}},
+	})
+	if !errors.Is(err, repositories.ErrInsufficientStock) {
+		t.Fatalf("CreateTransaction() error = %v, want ErrInsufficientStock", err)
+	}
+	sale, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		OutletID: branchID,
+		Items:    []models.TransactionItem{{ProductID: kopiID, Quantity: 3}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if sale.OutletID != branchID {
+		t.Errorf("outlet = %d, want %d", sale.OutletID, branchID)
+	}
+	if got := env.outletStock(t, branchID, kopiID, nil); got != 1 {
+		t.Errorf("branch Kopi stock = %v, want 1", got)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, kopiID, nil); got != 10 {
+		t.Errorf("main outlet Kopi stock = %v, want 10", got)
+	}
+	if got := env.stockOf(t, kopiID); got != 11 {
+		t.Errorf("total Kopi stock = %v, want 11", got)
+	}
+
+	// A sale without an outlet takes place at the main outlet; variants and
+	// bundle components have their own stock rows there
+	_, err = env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{
+			{ProductID: esTehID, VariantID: intPtr(esTehSmallID), Quantity: 2},
+			{ProductID: paketID, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, esTehID, intPtr(esTehSmallID)); got != 3 {
+		t.Errorf("main outlet Es Teh S stock = %v, want 3", got)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, berasID, nil); got != 4.5 {
+		t.Errorf("main outlet Beras stock = %v, want 4.5", got)
+	}
+
+	// Voiding the branch sale returns its stock to the branch
+	if err := env.transactions.DeleteTransaction(ctx, sale.ID); err != nil {
+		t.Fatalf("DeleteTransaction() error = %v", err)
+	}
+	if got := env.outletStock(t, branchID, kopiID, nil); got != 4 {
+		t.Errorf("branch Kopi stock = %v, want 4", got)
+	}
+
+	// Changing the catalog stock changes the stock of the main outlet
+	product, err := env.products.GetProductByID(ctx, kopiID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product.Stock = 20
+	if _, err := env.products.UpdateProduct(ctx, kopiID, *product); err != nil {
+		t.Fatalf("UpdateProduct() error = %v", err)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, kopiID, nil); got != 16 {
+		t.Errorf("main outlet Kopi stock = %v, want 16", got)
+	}
+}
+
+func TestOutletServicePrices(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	branchID := newBranch(t, env)
+
+	price := 6000.0
+	largePrice := 5500.0
+	for _, p := range []models.OutletProduct{
+		{OutletID: branchID, ProductID: kopiID, Stock: 5, Price: &price},
+		{OutletID: branchID, ProductID: esTehID, VariantID: intPtr(esTehLargeID), Stock: 2, Price: &largePrice},
+	} {
+		if _, err := env.outlets.SetOutletProduct(ctx, p); err != nil {
+			t.Fatalf("SetOutletProduct() error = %v", err)
+		}
+	}
+
+	items := []models.TransactionItem{
+		{ProductID: kopiID, Quantity: 1},
+		{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Quantity: 1},
+	}
+	branch, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{OutletID: branchID, Items: items})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if branch.TotalAmount != 11500 {
+		t.Errorf("branch total = %d, want 11500 at the branch prices", branch.TotalAmount)
+	}
+	main, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{Items: items})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if main.TotalAmount != 10000 {
+		t.Errorf("main outlet total = %d, want 10000 at the catalog prices", main.TotalAmount)
+	}
+
+	// Clearing the override sells at the catalog price again
+	if _, err := env.outlets.SetOutletProduct(ctx, models.OutletProduct{OutletID: branchID, ProductID: kopiID, Stock: 4}); err != nil {
+		t.Fatalf("SetOutletProduct() error = %v", err)
+	}
+	sale, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		OutletID: branchID,
+		Items:    []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if sale.TotalAmount != 5000 {
+		t.Errorf("total = %d, want 5000", sale.TotalAmount)
+	}
+}
+
+func TestOutletServiceReports(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	branchID := newBranch(t, env)
+	if _, err := env.outlets.SetOutletProduct(ctx, models.OutletProduct{OutletID: branchID, ProductID: kopiID, Stock: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, req := range []models.CreateTransactionRequest{
+		{Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 2}}},
+		{OutletID: branchID, Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 1}}},
+	} {
+		if _, err := env.transactions.CreateTransaction(ctx, req); err != nil {
+			t.Fatalf("CreateTransaction() error = %v", err)
+		}
+	}
+
+	all, err := env.reports.GetTodayReport(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetTodayReport() error = %v", err)
+	}
+	if all.TotalRevenue != 15000 || all.TotalTransaksi != 2 || len(all.RincianOutlet) != 2 {
+		t.Fatalf("rollup = %+v, want Rp 15.000 from 2 sales at 2 outlets", all)
+	}
+	if got := all.RincianOutlet[1]; got.OutletID != branchID || got.Nama != "Cabang 1" || got.TotalRevenue != 5000 || got.TotalTransaksi != 1 {
+		t.Errorf("branch sales = %+v, want Rp 5.000 from 1 sale", got)
+	}
+
+	branch, err := env.reports.GetTodayReport(ctx, []int{branchID})
+	if err != nil {
+		t.Fatalf("GetTodayReport() error = %v", err)
+	}
+	if branch.TotalRevenue != 5000 || branch.ProdukTerlaris == nil || branch.ProdukTerlaris.QtyTerjual != 1 || len(branch.RincianOutlet) != 1 {
+		t.Errorf("branch report = %+v, want Rp 5.000 for 1 Kopi", branch)
+	}
+
+	transactions, err := env.transactions.GetAllTransactions(ctx, models.TransactionFilter{OutletIDs: []int{models.DefaultOutletID}})
+	if err != nil {
+		t.Fatalf("GetAllTransactions() error = %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].OutletID != models.DefaultOutletID {
+		t.Errorf("transactions = %+v, want the sale at the main outlet", transactions)
+	}
+}
+
+func TestOutletServiceDelete(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	branchID := newBranch(t, env)
+	if _, err := env.outlets.SetOutletProduct(ctx, models.OutletProduct{OutletID: branchID, ProductID: kopiID, Stock: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.outlets.DeleteOutlet(ctx, models.DefaultOutletID); err == nil || err.Error() != "the main outlet cannot be deleted" {
+		t.Errorf("DeleteOutlet(main) error = %v, want refused", err)
+	}
+
+	// The stock of a deleted outlet leaves the totals with it
+	if err := env.outlets.DeleteOutlet(ctx, branchID); err != nil {
+		t.Fatalf("DeleteOutlet() error = %v", err)
+	}
+	if got := env.stockOf(t, kopiID); got != 10 {
+		t.Errorf("total Kopi stock = %v, want 10", got)
+	}
+	if _, err := env.outlets.GetOutletByID(ctx, branchID); err == nil {
+		t.Error("GetOutletByID() of deleted outlet succeeded")
+	}
+
+	// Outlets with sales are kept
+	branchID = newBranch(t, env)
+	if _, err := env.outlets.SetOutletProduct(ctx, models.OutletProduct{OutletID: branchID, ProductID: kopiID, Stock: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		OutletID: branchID,
+		Items:    []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.outlets.DeleteOutlet(ctx, branchID); err == nil {
+		t.Error("DeleteOutlet() of outlet with sales succeeded")
+	}
+}
+
+func TestOutletServiceRejected(t *testing.T) {
+	negative := -1.0
+	tests := []struct {
+		name    string
+		run     func(env *testEnv) error
+		wantErr string
+	}{
+		{
+			name: "outlet without code",
+			run: func(env *testEnv) error {
+				_, err := env.outlets.CreateOutlet(context.Background(), models.Outlet{Name: "Cabang"})
+				return err
+			},
+			wantErr: "outlet code is required",
+		},
+		{
+			name: "outlet without name",
+			run: func(env *testEnv) error {
+				_, err := env.outlets.CreateOutlet(context.Background(), models.Outlet{Code: "CBG"})
+				return err
+			},
+			wantErr: "outlet name is required",
+		},
+		{
+			name: "taken code",
+			run: func(env *testEnv) error {
+				_, err := env.outlets.CreateOutlet(context.Background(), models.Outlet{Code: "pusat", Name: "Pusat Lagi"})
+				return err
+			},
+			wantErr: "outlet with code PUSAT already exists",
+		},
+		{
+			name: "negative stock",
+			run: func(env *testEnv) error {
+				_, err := env.outlets.SetOutletProduct(context.Background(), models.OutletProduct{OutletID: 1, ProductID: kopiID, Stock: -1})
+				return err
+			},
+			wantErr: "stock must not be negative",
+		},
+		{
+			name: "negative price",
+			run: func(env *testEnv) error {
+				_, err := env.outlets.SetOutletProduct(context.Background(), models.OutletProduct{OutletID: 1, ProductID: kopiID, Price: &negative})
+				return err
+			},
+			wantErr: "price must not be negative",
+		},
+		{
+			name: "bundle stock",
+			run: func(env *testEnv) error {
+				_, err := env.outlets.SetOutletProduct(context.Background(), models.OutletProduct{OutletID: 1, ProductID: paketID, Stock: 2})
+				return err
+			},
+			wantErr: "bundle product with ID 4 has no stock of its own",
+		},
+		{
+			name: "variant of another product",
+			run: func(env *testEnv) error {
+				_, err := env.outlets.SetOutletProduct(context.Background(), models.OutletProduct{OutletID: 1, ProductID: kopiID, VariantID: intPtr(esTehSmallID), Stock: 2})
+				return err
+			},
+			wantErr: "Variant with ID 1 not found",
+		},
+		{
+			name: "stock at unknown outlet",
+			run: func(env *testEnv) error {
+				_, err := env.outlets.SetOutletProduct(context.Background(), models.OutletProduct{OutletID: 9, ProductID: kopiID, Stock: 2})
+				return err
+			},
+			wantErr: "Outlet with ID 9 not found",
+		},
+		{
+			name: "sale at unknown outlet",
+			run: func(env *testEnv) error {
+				_, err := env.transactions.CreateTransaction(context.Background(), models.CreateTransactionRequest{
+					OutletID: 9,
+					Items:    []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+				})
+				return err
+			},
+			wantErr: "Outlet with ID 9 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if err := tt.run(env); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if got := env.stockOf(t, kopiID); got != 10 {
+				t.Errorf("Kopi stock = %v, want 10", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// PriceListService handles business logic for price lists and price rules and
+// resolves the price a sale pays for a product
+type PriceListService struct {
+	repo        repositories.PriceListRepository
+	productRepo repositories.ProductRepository
+	variantRepo repositories.ProductVariantRepository
+	// location is the store timezone the daily windows of rules are in
+	location *time.Location
+}
+
+// NewPriceListService creates a new PriceListService; a nil location keeps
+// daily windows in UTC
+func NewPriceListService(repo repositories.PriceListRepository, productRepo repositories.ProductRepository, variantRepo repositories.ProductVariantRepository, location *time.Location) *PriceListService {
+	if location == nil {
+		location = time.UTC
+	}
+	return &PriceListService{
+		repo:        repo,
+		productRepo: productRepo,
+		variantRepo: variantRepo,
+		location:    location,
+	}
+}
+
+// GetAllPriceLists returns all price lists
+func (s *PriceListService) GetAllPriceLists(ctx context.Context) ([]models.PriceList, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// GetPriceListByID returns a price list by ID
+func (s *PriceListService) GetPriceListByID(ctx context.Context, id int) (*models.PriceList, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// CreatePriceList creates a new price list
+func (s *PriceListService) CreatePriceList(ctx context.Context, list models.PriceList) (*models.PriceList, error) {
+	if err := validatePriceList(&list); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, list)
+}
+
+// UpdatePriceList updates an existing price list
+func (s *PriceListService) UpdatePriceList(ctx context.Context, id int, list models.PriceList) (*models.PriceList, error) {
+	if err := validatePriceList(&list); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, id, list)
+}
+
+// DeletePriceList deletes a price list by ID with its rules
+func (s *PriceListService) DeletePriceList(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// GetPriceRules returns the price rules matching filter
+func (s *PriceListService) GetPriceRules(ctx context.Context, filter models.PriceRuleFilter) ([]models.PriceRule, error) {
+	return s.repo.GetRules(ctx, filter)
+}
+
+// GetPriceRuleByID returns a price rule by ID
+func (s *PriceListService) GetPriceRuleByID(ctx context.Context, id int) (*models.PriceRule, error) {
+	return s.repo.GetRuleByID(ctx, id)
+}
+
+// CreatePriceRule creates a new price rule
+func (s *PriceListService) CreatePriceRule(ctx context.Context, rule models.PriceRule) (*models.PriceRule, error) {
+	if err := s.validatePriceRule(ctx, &rule); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateRule(ctx, rule)
+}
+
+// UpdatePriceRule updates an existing price rule
+func (s *PriceListService) UpdatePriceRule(ctx context.Context, id int, rule models.PriceRule) (*models.PriceRule, error) {
+	if err := s.validatePriceRule(ctx, &rule); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateRule(ctx, id, rule)
+}
+
+// DeletePriceRule deletes a price rule by ID
+func (s *PriceListService) DeletePriceRule(ctx context.Context, id int) error {
+	return s.repo.DeleteRule(ctx, id)
+}
+
+// validatePriceList trims the fields of a price list and checks the code and
+// name
+func validatePriceList(list *models.PriceList) error {
+	list.Code = strings.ToUpper(strings.TrimSpace(list.Code))
+	list.Name = strings.TrimSpace(list.Name)
+
+	if list.Code == "" {
+		return fmt.Errorf("price list code is required")
+	}
+	if list.Name == "" {
+		return fmt.Errorf("price list name is required")
+	}
+	return nil
+}
+
+// validatePriceRule checks the price, quantity and schedule of a price rule
+// and that its variant belongs to its product
+func (s *PriceListService) validatePriceRule(ctx context.Context, rule *models.PriceRule) error {
+	rule.DailyFrom = strings.TrimSpace(rule.DailyFrom)
+	rule.DailyTo = strings.TrimSpace(rule.DailyTo)
+
+	if rule.Price < 0 {
+		return fmt.Errorf("price must not be negative")
+	}
+	if rule.MinQuantity < 0 {
+		return fmt.Errorf("min_quantity must not be negative")
+	}
+	if rule.StartsAt != nil && rule.EndsAt != nil && !rule.EndsAt.After(*rule.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	if (rule.DailyFrom == "") != (rule.DailyTo == "") {
+		return fmt.Errorf("daily_from and daily_to must be set together")
+	}
+	if rule.DailyFrom != "" {
+		from, err := minuteOfDay(rule.DailyFrom)
+		if err != nil {
+			return fmt.Errorf("invalid daily_from %q, want HH:MM", rule.DailyFrom)
+		}
+		to, err := minuteOfDay(rule.DailyTo)
+		if err != nil {
+			return fmt.Errorf("invalid daily_to %q, want HH:MM", rule.DailyTo)
+		}
+		if from == to {
+			return fmt.Errorf("daily_to must differ from daily_from")
+		}
+	}
+
+	if rule.PriceListID != nil {
+		if _, err := s.repo.GetByID(ctx, *rule.PriceListID); err != nil {
+			return err
+		}
+	}
+	if _, err := s.productRepo.GetByID(ctx, rule.ProductID); err != nil {
+		return err
+	}
+	if rule.VariantID != nil {
+		variant, err := s.variantRepo.GetByID(ctx, *rule.VariantID)
+		if err != nil {
+			return err
+		}
+		if variant.ProductID != rule.ProductID {
+			return fmt.Errorf("variant with ID %d not found for product with ID %d", *rule.VariantID, rule.ProductID)
+		}
+	}
+	return nil
+}
+
+// minuteOfDay parses a "15:00" time of day into minutes after midnight
+func minuteOfDay(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// priceContext is what a sale is priced for besides its items: the time of
+// the sale and the price list it buys from, nil for retail
+type priceContext struct {
+	at          time.Time
+	priceListID *int
+}
+
+// priceBook holds the price rules in effect for a basket at the time of the
+// sale. A nil priceBook prices everything at the base price.
+type priceBook struct {
+	rules map[int][]models.PriceRule
+	// minute is the minute of the day of the sale in the store timezone
+	minute      int
+	priceListID *int
+}
+
+// loadPriceBook loads the rules of the products that are in effect for the
+// price list at the given time with one query
+func (s *PriceListService) loadPriceBook(ctx context.Context, productIDs []int, pricing priceContext) (*priceBook, error) {
+	if pricing.priceListID != nil {
+		if _, err := s.repo.GetByID(ctx, *pricing.priceListID); err != nil {
+			return nil, err
+		}
+	}
+	rules, err := s.repo.GetRulesInEffect(ctx, productIDs, pricing.at)
+	if err != nil {
+		return nil, err
+	}
+
+	local := pricing.at.In(s.location)
+	book := &priceBook{
+		rules:       make(map[int][]models.PriceRule),
+		minute:      local.Hour()*60 + local.Minute(),
+		priceListID: pricing.priceListID,
+	}
+	for _, rule := range rules {
+		if rule.PriceListID != nil && (book.priceListID == nil || *rule.PriceListID != *book.priceListID) {
+			continue
+		}
+		if !book.inWindow(rule) {
+			continue
+		}
+		book.rules[rule.ProductID] = append(book.rules[rule.ProductID], rule)
+	}
+	return book, nil
+}
+
+// inWindow reports whether the sale is within the daily window of a rule; a
+// window whose end is before its start runs past midnight
+func (b *priceBook) inWindow(rule models.PriceRule) bool {
+	if rule.DailyFrom == "" {
+		return true
+	}
+	from, err := minuteOfDay(rule.DailyFrom)
+	if err != nil {
+		return false
+	}
+	to, err := minuteOfDay(rule.DailyTo)
+	if err != nil {
+		return false
+	}
+	if from < to {
+		return b.minute >= from && b.minute < to
+	}
+	return b.minute >= from || b.minute < to
+}
+
+// price returns the price of a quantity, in the product unit, of a product or
+// variant and the rule it comes from, or base and nil when no rule applies.
+// Rules of the price list beat retail rules, then the highest quantity break
+// wins, then the latest scheduled change.
+func (b *priceBook) price(productID int, variantID *int, quantity, base float64) (float64, *int) {
+	if b == nil {
+		return base, nil
+	}
+
+	var best *models.PriceRule
+	for i, rule := range b.rules[productID] {
+		if !sameVariant(rule.VariantID, variantID) || rule.MinQuantity > quantity {
+			continue
+		}
+		if best == nil || ruleBeats(rule, *best) {
+			best = &b.rules[productID][i]
+		}
+	}
+	if best == nil {
+		return base, nil
+	}
+	id := best.ID
+	return best.Price, &id
+}
+
+// ruleBeats reports whether rule a takes precedence over rule b
+func ruleBeats(a, b models.PriceRule) bool {
+	if (a.PriceListID != nil) != (b.PriceListID != nil) {
+		return a.PriceListID != nil
+	}
+	if a.MinQuantity != b.MinQuantity {
+		return a.MinQuantity > b.MinQuantity
+	}
+	switch {
+	case a.StartsAt == nil && b.StartsAt != nil:
+		return false
+	case a.StartsAt != nil && b.StartsAt == nil:
+		return true
+	case a.StartsAt != nil && !a.StartsAt.Equal(*b.StartsAt):
+		return a.StartsAt.After(*b.StartsAt)
+	}
+	return a.ID > b.ID
+}
+
+// sameVariant reports whether a rule for variant a prices variant b; a rule
+// without a variant prices the product itself
+func sameVariant(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
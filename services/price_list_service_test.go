@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+func TestPriceListServiceWholesale(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	wholesale, err := env.priceLists.CreatePriceList(ctx, models.PriceList{Code: " grosir ", Name: "Grosir"})
+	if err != nil {
+		t.Fatalf("CreatePriceList() error = %v", err)
+	}
+	if wholesale.Code != "GROSIR" {
+		t.Errorf("code = %q, want GROSIR", wholesale.Code)
+	}
+	member, err := env.priceLists.CreatePriceList(ctx, models.PriceList{Code: "MEMBER", Name: "Member"})
+	if err != nil {
+		t.Fatalf("CreatePriceList() error = %v", err)
+	}
+
+	var tier *models.PriceRule
+	for _, rule := range []models.PriceRule{
+		{PriceListID: &wholesale.ID, ProductID: kopiID, Price: 4500},
+		{PriceListID: &wholesale.ID, ProductID: kopiID, MinQuantity: 6, Price: 4000},
+		{PriceListID: &member.ID, ProductID: kopiID, Price: 4800},
+		// Retail quantity break for everyone
+		{ProductID: kopiID, MinQuantity: 8, Price: 4200},
+	} {
+		created, err := env.priceLists.CreatePriceRule(ctx, rule)
+		if err != nil {
+			t.Fatalf("CreatePriceRule() error = %v", err)
+		}
+		if rule.MinQuantity == 6 {
+			tier = created
+		}
+	}
+	if _, err := env.outlets.SetOutletProduct(ctx, models.OutletProduct{OutletID: models.DefaultOutletID, ProductID: kopiID, Stock: 100}); err != nil {
+		t.Fatalf("SetOutletProduct() error = %v", err)
+	}
+	customer, err := env.customers.CreateCustomer(ctx, models.Customer{Name: "Toko Makmur", PriceListID: &wholesale.ID})
+	if err != nil {
+		t.Fatalf("CreateCustomer() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		req         models.CreateTransactionRequest
+		wantTotal   int
+		wantList    *int
+		wantRuleSet bool
+	}{
+		{name: "retail", req: models.CreateTransactionRequest{Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 1}}}, wantTotal: 5000},
+		{name: "customer list", req: models.CreateTransactionRequest{CustomerID: &customer.ID, Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 1}}}, wantTotal: 4500, wantList: &wholesale.ID, wantRuleSet: true},
+		{name: "requested list beats customer list", req: models.CreateTransactionRequest{CustomerID: &customer.ID, PriceListID: &member.ID, Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 1}}}, wantTotal: 4800, wantList: &member.ID, wantRuleSet: true},
+		{name: "list beats retail quantity break", req: models.CreateTransactionRequest{PriceListID: &member.ID, Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 8}}}, wantTotal: 38400, wantList: &member.ID, wantRuleSet: true},
+		{name: "retail quantity break", req: models.CreateTransactionRequest{Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 8}}}, wantTotal: 33600, wantRuleSet: true},
+		{name: "no rule for product", req: models.CreateTransactionRequest{CustomerID: &customer.ID, Items: []models.TransactionItem{{ProductID: berasID, Quantity: 1}}}, wantTotal: 12000, wantList: &wholesale.ID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transaction, err := env.transactions.CreateTransaction(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("CreateTransaction() error = %v", err)
+			}
+			if transaction.TotalAmount != tt.wantTotal {
+				t.Errorf("total = %d, want %d", transaction.TotalAmount, tt.wantTotal)
+			}
+			if (transaction.PriceListID == nil) != (tt.wantList == nil) || (tt.wantList != nil && *transaction.PriceListID != *tt.wantList) {
+				t.Errorf("price list = %v, want %v", transaction.PriceListID, tt.wantList)
+			}
+			if got := transaction.Details[0].PriceRuleID != nil; got != tt.wantRuleSet {
+				t.Errorf("price rule set = %v, want %v", got, tt.wantRuleSet)
+			}
+		})
+	}
+
+	// The wholesale tier starts at 6 pieces, counted in the product unit
+	transaction, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		CustomerID: &customer.ID,
+		Items:      []models.TransactionItem{{ProductID: kopiID, Quantity: 1, Unit: "box"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() by the box error = %v", err)
+	}
+	if transaction.TotalAmount != 24000 || *transaction.Details[0].PriceRuleID != tier.ID {
+		t.Errorf("transaction = %+v, want a box at the 6 piece tier", transaction)
+	}
+
+	if _, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		PriceListID: intPtr(99),
+		Items:       []models.TransactionItem{{ProductID: berasID, Quantity: 1}},
+	}); err == nil || !strings.Contains(err.Error(), "Price list with ID 99 not found") {
+		t.Errorf("CreateTransaction() with unknown price list error = %v", err)
+	}
+
+	// Deleting the list sends its customers back to retail prices
+	if err := env.priceLists.DeletePriceList(ctx, wholesale.ID); err != nil {
+		t.Fatalf("DeletePriceList() error = %v", err)
+	}
+	customer, err = env.customers.GetCustomerByID(ctx, customer.ID)
+	if err != nil || customer.PriceListID != nil {
+		t.Errorf("customer = %+v, %v, want no price list", customer, err)
+	}
+	rules, err := env.priceLists.GetPriceRules(ctx, models.PriceRuleFilter{ProductID: kopiID})
+	if err != nil || len(rules) != 2 {
+		t.Errorf("rules after delete = %+v, %v, want the member and retail rules", rules, err)
+	}
+}
+
+func TestPriceListServiceSchedule(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	increase := time.Date(2026, 3, 1, 0, 0, 0, 0, jakarta)
+	secondIncrease := increase.AddDate(0, 1, 0)
+	rules := []models.PriceRule{
+		// Happy hour on Es Teh L from 15:00 to 17:00
+		{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Price: 4000, DailyFrom: "15:00", DailyTo: "17:00"},
+		// Late night Kopi from 22:00 to 02:00
+		{ProductID: kopiID, Price: 3000, DailyFrom: "22:00", DailyTo: "02:00"},
+		// Beras goes up on the first of March, then again a month later
+		{ProductID: berasID, Price: 13000, StartsAt: &increase},
+		{ProductID: berasID, Price: 14000, StartsAt: &secondIncrease},
+	}
+	for _, rule := range rules {
+		if _, err := env.priceLists.CreatePriceRule(ctx, rule); err != nil {
+			t.Fatalf("CreatePriceRule() error = %v", err)
+		}
+	}
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 2, day, hour, minute, 0, 0, jakarta)
+	}
+	tests := []struct {
+		name      string
+		at        time.Time
+		productID int
+		variantID *int
+		base      float64
+		want      float64
+	}{
+		{name: "happy hour", at: at(10, 15, 30), productID: esTehID, variantID: intPtr(esTehLargeID), base: 5000, want: 4000},
+		{name: "happy hour in UTC", at: at(10, 16, 0).UTC(), productID: esTehID, variantID: intPtr(esTehLargeID), base: 5000, want: 4000},
+		{name: "after happy hour", at: at(10, 17, 0), productID: esTehID, variantID: intPtr(esTehLargeID), base: 5000, want: 5000},
+		{name: "other variant", at: at(10, 15, 30), productID: esTehID, variantID: intPtr(esTehSmallID), base: 3000, want: 3000},
+		{name: "window before midnight", at: at(10, 23, 0), productID: kopiID, base: 5000, want: 3000},
+		{name: "window after midnight", at: at(11, 1, 59), productID: kopiID, base: 5000, want: 3000},
+		{name: "outside window", at: at(11, 2, 0), productID: kopiID, base: 5000, want: 5000},
+		{name: "before price change", at: at(28, 23, 59), productID: berasID, base: 12000, want: 12000},
+		{name: "after price change", at: increase, productID: berasID, base: 12000, want: 13000},
+		{name: "latest price change", at: increase.AddDate(0, 2, 0), productID: berasID, base: 12000, want: 14000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book, err := env.priceLists.loadPriceBook(ctx, []int{tt.productID}, priceContext{at: tt.at})
+			if err != nil {
+				t.Fatalf("loadPriceBook() error = %v", err)
+			}
+			got, ruleID := book.price(tt.productID, tt.variantID, 1, tt.base)
+			if got != tt.want {
+				t.Errorf("price = %v, want %v", got, tt.want)
+			}
+			if (ruleID != nil) != (tt.want != tt.base) {
+				t.Errorf("rule = %v, want a rule only when the price changed", ruleID)
+			}
+		})
+	}
+
+	// Offline sales are priced as of when they were made
+	response, err := env.transactions.SyncTransactions(ctx, models.SyncTransactionsRequest{
+		Transactions: []models.OfflineTransaction{{
+			ClientID:  "7f1c2a4e-3b5d-4c6e-8f90-1a2b3c4d5e6f",
+			CreatedAt: at(10, 23, 0),
+			Items:     []models.TransactionItem{{ProductID: kopiID, Quantity: 2}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("SyncTransactions() error = %v", err)
+	}
+	transaction, err := env.transactions.GetTransactionByID(ctx, response.Results[0].TransactionID)
+	if err != nil {
+		t.Fatalf("GetTransactionByID() error = %v", err)
+	}
+	if transaction.TotalAmount != 6000 {
+		t.Errorf("offline total = %d, want 6000 at the late night price", transaction.TotalAmount)
+	}
+}
+
+func TestPriceListServiceValidation(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		rule    models.PriceRule
+		wantErr string
+	}{
+		{name: "negative price", rule: models.PriceRule{ProductID: kopiID, Price: -1}, wantErr: "price must not be negative"},
+		{name: "negative quantity", rule: models.PriceRule{ProductID: kopiID, MinQuantity: -1}, wantErr: "min_quantity must not be negative"},
+		{name: "ends before start", rule: models.PriceRule{ProductID: kopiID, StartsAt: &start, EndsAt: &start}, wantErr: "ends_at must be after starts_at"},
+		{name: "half a window", rule: models.PriceRule{ProductID: kopiID, DailyFrom: "15:00"}, wantErr: "must be set together"},
+		{name: "invalid time", rule: models.PriceRule{ProductID: kopiID, DailyFrom: "3pm", DailyTo: "17:00"}, wantErr: `invalid daily_from "3pm"`},
+		{name: "empty window", rule: models.PriceRule{ProductID: kopiID, DailyFrom: "15:00", DailyTo: "15:00"}, wantErr: "must differ"},
+		{name: "unknown price list", rule: models.PriceRule{PriceListID: intPtr(9), ProductID: kopiID}, wantErr: "Price list with ID 9 not found"},
+		{name: "unknown product", rule: models.PriceRule{ProductID: 99}, wantErr: "Product with ID 99 not found"},
+		{name: "variant of other product", rule: models.PriceRule{ProductID: kopiID, VariantID: intPtr(esTehSmallID)}, wantErr: "not found for product"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			_, err := env.priceLists.CreatePriceRule(context.Background(), tt.rule)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CreatePriceRule() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	env := newTestEnv(t)
+	if _, err := env.priceLists.CreatePriceList(context.Background(), models.PriceList{Code: "GROSIR"}); err == nil || !strings.Contains(err.Error(), "name is required") {
+		t.Errorf("CreatePriceList() without name error = %v", err)
+	}
+	if _, err := env.priceLists.CreatePriceList(context.Background(), models.PriceList{Name: "Grosir"}); err == nil || !strings.Contains(err.Error(), "code is required") {
+		t.Errorf("CreatePriceList() without code error = %v", err)
+	}
+}
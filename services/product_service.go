@@ -1,41 +1,250 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
+var (
+	// ErrInUse is wrapped by the errors returned when a product or category
+	// is still referenced by records that keep it from being deleted or purged
+	ErrInUse = repositories.ErrInUse
+	// ErrNotDeleted is wrapped by the errors returned when a product or
+	// category is restored or purged without being deleted first
+	ErrNotDeleted = repositories.ErrNotDeleted
+)
+
 // ProductService handles business logic for products
 type ProductService struct {
-	repo *repositories.ProductRepository
+	repo        repositories.ProductRepository
+	variantRepo repositories.ProductVariantRepository
 }
 
 // NewProductService creates a new ProductService
-func NewProductService(repo *repositories.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+func NewProductService(repo repositories.ProductRepository, variantRepo repositories.ProductVariantRepository) *ProductService {
+	return &ProductService{repo: repo, variantRepo: variantRepo}
 }
 
 // GetAllProducts returns all products with optional filters
-func (s *ProductService) GetAllProducts(filter models.ProductFilter) ([]models.Product, error) {
-	return s.repo.GetAll(filter)
+func (s *ProductService) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	return s.repo.GetAll(ctx, filter)
+}
+
+// GetProductByID returns a product by ID including its variants
+func (s *ProductService) GetProductByID(ctx context.Context, id int) (*models.Product, error) {
+	product, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.variantRepo.GetByProductID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	product.Variants = variants
+
+	if product.IsBundle {
+		components, err := s.repo.GetComponents(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		product.Components = components
+	}
+
+	return product, nil
+}
+
+// CreateProduct creates a new product as version 1 of its history
+func (s *ProductService) CreateProduct(ctx context.Context, product models.Product) (*models.Product, error) {
+	if product.Unit == "" {
+		product.Unit = models.DefaultUnit
+	}
+	product.DeletedAt = nil
+	return s.repo.Create(ctx, product, actorFrom(ctx))
+}
+
+// UpdateProduct updates an existing product, recording the change in its
+// history with the user of ctx
+func (s *ProductService) UpdateProduct(ctx context.Context, id int, product models.Product) (*models.Product, error) {
+	// Keep the current unit when the request does not change it
+	if product.Unit == "" {
+		existing, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		product.Unit = existing.Unit
+	}
+	product.DeletedAt = nil
+	return s.repo.Update(ctx, id, product, actorFrom(ctx))
+}
+
+// GetProductHistory returns the versions of a product, oldest first, with the
+// fields each version changed. With at set it returns only the version in
+// effect at that time, none when the product did not exist yet.
+func (s *ProductService) GetProductHistory(ctx context.Context, id int, at *time.Time) ([]models.ProductVersion, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	versions, err := s.repo.GetHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(versions); i++ {
+		versions[i].Changes = versionChanges(versions[i-1], versions[i])
+	}
+
+	if at != nil {
+		var current []models.ProductVersion
+		for _, v := range versions {
+			if !v.ChangedAt.After(*at) {
+				current = []models.ProductVersion{v}
+			}
+		}
+		versions = current
+	}
+	if versions == nil {
+		versions = []models.ProductVersion{}
+	}
+	return versions, nil
+}
+
+// DeleteProduct soft deletes a product by ID; it is no longer sold or listed
+// but past sales keep it
+func (s *ProductService) DeleteProduct(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
 }
 
-// GetProductByID returns a product by ID
-func (s *ProductService) GetProductByID(id int) (*models.Product, error) {
-	return s.repo.GetByID(id)
+// RestoreProduct undoes the deletion of a product. A product whose category
+// was deleted meanwhile comes back without a category; the change is
+// recorded in its history with the user of ctx.
+func (s *ProductService) RestoreProduct(ctx context.Context, id int) (*models.Product, error) {
+	return s.repo.Restore(ctx, id, actorFrom(ctx))
 }
 
-// CreateProduct creates a new product
-func (s *ProductService) CreateProduct(product models.Product) (*models.Product, error) {
-	return s.repo.Create(product)
+// PurgeProduct permanently removes a deleted product that no sale, bundle,
+// transfer or open bill references. Its version history goes with it.
+func (s *ProductService) PurgeProduct(ctx context.Context, id int) error {
+	return s.repo.Purge(ctx, id)
 }
 
-// UpdateProduct updates an existing product
-func (s *ProductService) UpdateProduct(id int, product models.Product) (*models.Product, error) {
-	return s.repo.Update(id, product)
+// versionChanges returns the fields version b of a product changed from a
+func versionChanges(a, b models.ProductVersion) []string {
+	var changes []string
+	if a.Name != b.Name {
+		changes = append(changes, "name")
+	}
+	if a.Price != b.Price {
+		changes = append(changes, "price")
+	}
+	if a.Stock != b.Stock {
+		changes = append(changes, "stock")
+	}
+	if a.Unit != b.Unit {
+		changes = append(changes, "unit")
+	}
+	if !sameCategory(a.CategoryID, b.CategoryID) {
+		changes = append(changes, "category_id")
+	}
+	return changes
 }
 
-// DeleteProduct deletes a product by ID
-func (s *ProductService) DeleteProduct(id int) error {
-	return s.repo.Delete(id)
+// sameCategory reports whether two category IDs are equal; nil is no category
+func sameCategory(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// GetVariants returns all variants of a product
+func (s *ProductService) GetVariants(ctx context.Context, productID int) ([]models.ProductVariant, error) {
+	if _, err := s.repo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.variantRepo.GetByProductID(ctx, productID)
+}
+
+// CreateVariant adds a new variant to a product
+func (s *ProductService) CreateVariant(ctx context.Context, productID int, variant models.ProductVariant) (*models.ProductVariant, error) {
+	if _, err := s.repo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	if variant.Name == "" || variant.SKU == "" {
+		return nil, fmt.Errorf("variant name and sku are required")
+	}
+	variant.ProductID = productID
+	return s.variantRepo.Create(ctx, variant)
+}
+
+// GetVariant returns a variant of a product
+func (s *ProductService) GetVariant(ctx context.Context, productID, variantID int) (*models.ProductVariant, error) {
+	variant, err := s.variantRepo.GetByID(ctx, variantID)
+	if err != nil {
+		return nil, err
+	}
+	if variant.ProductID != productID {
+		return nil, fmt.Errorf("Variant with ID %d not found", variantID)
+	}
+	return variant, nil
+}
+
+// UpdateVariant updates a variant of a product
+func (s *ProductService) UpdateVariant(ctx context.Context, productID, variantID int, variant models.ProductVariant) (*models.ProductVariant, error) {
+	if variant.Name == "" || variant.SKU == "" {
+		return nil, fmt.Errorf("variant name and sku are required")
+	}
+	return s.variantRepo.Update(ctx, productID, variantID, variant)
+}
+
+// DeleteVariant deletes a variant of a product
+func (s *ProductService) DeleteVariant(ctx context.Context, productID, variantID int) error {
+	return s.variantRepo.Delete(ctx, productID, variantID)
+}
+
+// GetComponents returns the components of a bundle product
+func (s *ProductService) GetComponents(ctx context.Context, bundleID int) ([]models.BundleComponent, error) {
+	if _, err := s.repo.GetByID(ctx, bundleID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetComponents(ctx, bundleID)
+}
+
+// SetComponents replaces the components of a bundle product. An empty list
+// turns the bundle back into a regular product.
+func (s *ProductService) SetComponents(ctx context.Context, bundleID int, components []models.BundleComponent) ([]models.BundleComponent, error) {
+	seen := make(map[int]bool)
+	for _, c := range components {
+		if c.ProductID == bundleID {
+			return nil, fmt.Errorf("a bundle cannot contain itself")
+		}
+		if seen[c.ProductID] {
+			return nil, fmt.Errorf("product with ID %d is listed more than once", c.ProductID)
+		}
+		seen[c.ProductID] = true
+
+		if c.Quantity <= 0 {
+			return nil, fmt.Errorf("component quantity must be greater than 0")
+		}
+
+		component, err := s.repo.GetByID(ctx, c.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if component.DeletedAt != nil {
+			return nil, fmt.Errorf("product with ID %d is deleted and cannot be a component", c.ProductID)
+		}
+		if component.IsBundle {
+			return nil, fmt.Errorf("product with ID %d is a bundle and cannot be a component", c.ProductID)
+		}
+	}
+
+	if err := s.repo.SetComponents(ctx, bundleID, components); err != nil {
+		return nil, err
+	}
+	return s.repo.GetComponents(ctx, bundleID)
 }
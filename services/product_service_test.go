@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+func TestProductServiceCreateProduct(t *testing.T) {
+	tests := []struct {
+		name     string
+		product  models.Product
+		wantUnit string
+		wantErr  string
+	}{
+		{
+			name:     "defaults to pcs",
+			product:  models.Product{Name: "Gula", Price: 14000, CategoryID: intPtr(1)},
+			wantUnit: "pcs",
+		},
+		{
+			name:     "keeps given unit",
+			product:  models.Product{Name: "Minyak", Price: 18000, Unit: "liter", CategoryID: intPtr(1)},
+			wantUnit: "liter",
+		},
+		{
+			name:    "unknown unit",
+			product: models.Product{Name: "Kain", Price: 10000, Unit: "meter", CategoryID: intPtr(1)},
+			wantErr: "unit meter does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			got, err := env.products.CreateProduct(context.Background(), tt.product)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CreateProduct() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateProduct() error = %v", err)
+			}
+			if got.ID == 0 || got.Unit != tt.wantUnit {
+				t.Errorf("CreateProduct() = %+v, want an ID and unit %s", got, tt.wantUnit)
+			}
+		})
+	}
+}
+
+func TestProductServiceGetProductByID(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             int
+		wantVariants   int
+		wantComponents int
+		wantStock      float64
+		wantErr        string
+	}{
+		{name: "regular product", id: kopiID, wantStock: 10},
+		{name: "product with variants", id: esTehID, wantVariants: 2},
+		{name: "bundle stock follows components", id: paketID, wantComponents: 2, wantStock: 5},
+		{name: "not found", id: 99, wantErr: "Product with ID 99 not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			got, err := env.products.GetProductByID(context.Background(), tt.id)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("GetProductByID() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetProductByID() error = %v", err)
+			}
+			if len(got.Variants) != tt.wantVariants || len(got.Components) != tt.wantComponents || got.Stock != tt.wantStock {
+				t.Errorf("GetProductByID() = %d variants, %d components, stock %v; want %d, %d, %v",
+					len(got.Variants), len(got.Components), got.Stock, tt.wantVariants, tt.wantComponents, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestProductServiceUpdateProduct(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       int
+		product  models.Product
+		wantUnit string
+		wantErr  string
+	}{
+		{
+			name:     "keeps current unit",
+			id:       berasID,
+			product:  models.Product{Name: "Beras Premium", Price: 15000, Stock: 5, CategoryID: intPtr(1)},
+			wantUnit: "kg",
+		},
+		{
+			name:     "changes unit",
+			id:       berasID,
+			product:  models.Product{Name: "Beras", Price: 1200, Stock: 5000, Unit: "gram", CategoryID: intPtr(1)},
+			wantUnit: "gram",
+		},
+		{
+			name:    "not found",
+			id:      99,
+			product: models.Product{Name: "Gula", CategoryID: intPtr(1)},
+			wantErr: "Product with ID 99 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			got, err := env.products.UpdateProduct(context.Background(), tt.id, tt.product)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("UpdateProduct() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateProduct() error = %v", err)
+			}
+			if got.Unit != tt.wantUnit || got.Name != tt.product.Name {
+				t.Errorf("UpdateProduct() = %+v, want name %q and unit %s", got, tt.product.Name, tt.wantUnit)
+			}
+		})
+	}
+}
+
+func TestProductServiceDeleteProduct(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int
+		wantErr string
+	}{
+		{name: "bundle", id: paketID},
+		{name: "bundle component", id: berasID, wantErr: "referenced"},
+		{name: "not found", id: 99, wantErr: "Product with ID 99 not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			err := env.products.DeleteProduct(context.Background(), tt.id)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DeleteProduct() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeleteProduct() error = %v", err)
+			}
+			if product, err := env.products.GetProductByID(context.Background(), tt.id); err != nil || product.DeletedAt == nil {
+				t.Errorf("GetProductByID() after DeleteProduct() = %+v, %v, want it marked deleted", product, err)
+			}
+		})
+	}
+}
+
+func TestProductServiceSoftDelete(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if err := env.products.DeleteProduct(ctx, esTehID); err != nil {
+		t.Fatalf("DeleteProduct() error = %v", err)
+	}
+	if products, _ := env.products.GetAllProducts(ctx, models.ProductFilter{Name: "teh"}); len(products) != 0 {
+		t.Errorf("GetAllProducts() = %+v, want the deleted product left out", products)
+	}
+	if products, _ := env.products.GetAllProducts(ctx, models.ProductFilter{Name: "teh", IncludeDeleted: true}); len(products) != 1 {
+		t.Errorf("GetAllProducts() including deleted = %+v, want Es Teh", products)
+	}
+	if _, err := env.products.UpdateProduct(ctx, esTehID, models.Product{Name: "Es Teh", CategoryID: intPtr(1)}); err == nil {
+		t.Error("UpdateProduct() of deleted product succeeded")
+	}
+	if _, err := env.products.SetComponents(ctx, paketID, []models.BundleComponent{{ProductID: esTehID, Quantity: 1}}); err == nil {
+		t.Error("SetComponents() with a deleted component succeeded")
+	}
+	_, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: esTehID, VariantID: intPtr(esTehSmallID), Quantity: 1}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("selling a deleted product error = %v, want not found", err)
+	}
+
+	if _, err := env.products.RestoreProduct(ctx, esTehID); err != nil {
+		t.Fatalf("RestoreProduct() error = %v", err)
+	}
+	if _, err := env.products.RestoreProduct(ctx, esTehID); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("second RestoreProduct() error = %v, want ErrNotDeleted", err)
+	}
+	if product, err := env.products.GetProductByID(ctx, esTehID); err != nil || product.DeletedAt != nil || len(product.Variants) != 2 {
+		t.Errorf("restored product = %+v, %v, want it with its variants", product, err)
+	}
+}
+
+func TestProductServiceSellDeletedComponent(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	// A restored bundle may still hold a component deleted meanwhile
+	if err := env.products.DeleteProduct(ctx, paketID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.DeleteProduct(ctx, kopiID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.products.RestoreProduct(ctx, paketID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Loading the bundle components does not make Kopi sellable again
+	_, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: paketID, Quantity: 1}, {ProductID: kopiID, Quantity: 1}},
+	})
+	if err == nil || err.Error() != "product with ID 1 not found" {
+		t.Errorf("selling a deleted product next to its bundle error = %v, want not found", err)
+	}
+	if got := env.stockOf(t, kopiID); got != 10 {
+		t.Errorf("Kopi stock = %v, want 10", got)
+	}
+}
+
+func TestProductServicePurgeProduct(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if err := env.products.PurgeProduct(ctx, esTehID); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("PurgeProduct() of live product error = %v, want ErrNotDeleted", err)
+	}
+
+	// Sold products cannot be purged; the bundle keeps its components
+	_, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: paketID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.DeleteProduct(ctx, paketID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.PurgeProduct(ctx, paketID); !errors.Is(err, ErrInUse) {
+		t.Errorf("PurgeProduct() of sold bundle error = %v, want ErrInUse", err)
+	}
+	if err := env.products.DeleteProduct(ctx, kopiID); err != nil {
+		t.Fatalf("DeleteProduct() of component of deleted bundle error = %v", err)
+	}
+	if err := env.products.PurgeProduct(ctx, kopiID); !errors.Is(err, ErrInUse) {
+		t.Errorf("PurgeProduct() of sold component error = %v, want ErrInUse", err)
+	}
+
+	// A product on an open bill waits for the bill to close, so the stock it
+	// reserves is returned
+	bill, err := env.openBills.OpenBill(ctx, models.OpenBill{
+		Label: "Meja 1",
+		Items: []models.OpenBillItem{{ProductID: esTehID, VariantID: intPtr(esTehSmallID), Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.DeleteProduct(ctx, esTehID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.PurgeProduct(ctx, esTehID); !errors.Is(err, ErrInUse) {
+		t.Errorf("PurgeProduct() of product on open bill error = %v, want ErrInUse", err)
+	}
+	if err := env.openBills.CancelBill(ctx, bill.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.products.PurgeProduct(ctx, esTehID); err != nil {
+		t.Fatalf("PurgeProduct() error = %v", err)
+	}
+	if _, err := env.products.GetProductByID(ctx, esTehID); err == nil {
+		t.Error("GetProductByID() of purged product succeeded")
+	}
+	if variants, _ := env.products.GetVariants(ctx, esTehID); len(variants) != 0 {
+		t.Errorf("variants of purged product = %+v", variants)
+	}
+}
+
+func TestProductServiceGetProductHistory(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	env.store.Now = func() time.Time { return monday }
+	beras := models.Product{Name: "Beras", Price: 13000, Stock: 5, CategoryID: intPtr(1)}
+	if _, err := env.products.UpdateProduct(WithActor(ctx, "budi"), berasID, beras); err != nil {
+		t.Fatalf("UpdateProduct() error = %v", err)
+	}
+	// Saving the product unchanged records no version
+	if _, err := env.products.UpdateProduct(ctx, berasID, beras); err != nil {
+		t.Fatalf("UpdateProduct() unchanged error = %v", err)
+	}
+	env.store.Now = func() time.Time { return monday.AddDate(0, 0, 2) }
+	beras.Name, beras.Stock = "Beras Premium", 8
+	if _, err := env.products.UpdateProduct(WithActor(ctx, "sari"), berasID, beras); err != nil {
+		t.Fatalf("UpdateProduct() error = %v", err)
+	}
+
+	versions, err := env.products.GetProductHistory(ctx, berasID, nil)
+	if err != nil {
+		t.Fatalf("GetProductHistory() error = %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("GetProductHistory() = %+v, want 3 versions", versions)
+	}
+	if v := versions[1]; v.Version != 2 || v.Price != 13000 || v.ChangedBy != "budi" || strings.Join(v.Changes, ",") != "price" {
+		t.Errorf("version 2 = %+v, want the price change by budi", v)
+	}
+	if v := versions[2]; v.Version != 3 || v.ChangedBy != "sari" || strings.Join(v.Changes, ",") != "name,stock" {
+		t.Errorf("version 3 = %+v, want the name and stock change by sari", v)
+	}
+
+	tuesday := monday.AddDate(0, 0, 1)
+	versions, err = env.products.GetProductHistory(ctx, berasID, &tuesday)
+	if err != nil || len(versions) != 1 || versions[0].Price != 13000 || versions[0].Name != "Beras" {
+		t.Errorf("GetProductHistory(tuesday) = %+v, %v, want the price set on monday", versions, err)
+	}
+	before := monday.AddDate(-1, 0, 0)
+	if versions, err := env.products.GetProductHistory(ctx, berasID, &before); err != nil || len(versions) != 0 {
+		t.Errorf("GetProductHistory(before creation) = %+v, %v, want none", versions, err)
+	}
+	if _, err := env.products.GetProductHistory(ctx, 99, nil); err == nil || err.Error() != "Product with ID 99 not found" {
+		t.Errorf("GetProductHistory(99) error = %v, want not found", err)
+	}
+}
+
+func TestProductServiceVariants(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID int
+		variant   models.ProductVariant
+		wantErr   string
+	}{
+		{
+			name:      "create",
+			productID: kopiID,
+			variant:   models.ProductVariant{Name: "Susu", SKU: "KOPI-SUSU", Price: 7000, Stock: 3},
+		},
+		{
+			name:      "missing sku",
+			productID: kopiID,
+			variant:   models.ProductVariant{Name: "Susu"},
+			wantErr:   "variant name and sku are required",
+		},
+		{
+			name:      "duplicate sku",
+			productID: kopiID,
+			variant:   models.ProductVariant{Name: "S", SKU: "TEH-S"},
+			wantErr:   "already exists",
+		},
+		{
+			name:      "unknown product",
+			productID: 99,
+			variant:   models.ProductVariant{Name: "S", SKU: "X-S"},
+			wantErr:   "Product with ID 99 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			got, err := env.products.CreateVariant(ctx, tt.productID, tt.variant)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CreateVariant() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateVariant() error = %v", err)
+			}
+
+			variants, err := env.products.GetVariants(ctx, tt.productID)
+			if err != nil || len(variants) != 1 || variants[0].ID != got.ID {
+				t.Errorf("GetVariants() = %+v, %v; want the created variant", variants, err)
+			}
+		})
+	}
+}
+
+func TestProductServiceUpdateAndDeleteVariant(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := env.products.UpdateVariant(ctx, kopiID, esTehSmallID, models.ProductVariant{Name: "S", SKU: "TEH-S"}); err == nil {
+		t.Error("UpdateVariant() of a variant of another product succeeded, want error")
+	}
+
+	updated, err := env.products.UpdateVariant(ctx, esTehID, esTehSmallID, models.ProductVariant{Name: "Small", SKU: "TEH-S", Price: 3500, Stock: 4})
+	if err != nil {
+		t.Fatalf("UpdateVariant() error = %v", err)
+	}
+	if updated.Name != "Small" || updated.ProductID != esTehID {
+		t.Errorf("UpdateVariant() = %+v", updated)
+	}
+
+	if err := env.products.DeleteVariant(ctx, esTehID, esTehSmallID); err != nil {
+		t.Fatalf("DeleteVariant() error = %v", err)
+	}
+	if err := env.products.DeleteVariant(ctx, esTehID, esTehSmallID); err == nil {
+		t.Error("second DeleteVariant() succeeded, want not found")
+	}
+}
+
+func TestProductServiceSetComponents(t *testing.T) {
+	tests := []struct {
+		name       string
+		bundleID   int
+		components []models.BundleComponent
+		wantBundle bool
+		wantErr    string
+	}{
+		{
+			name:       "turns product into bundle",
+			bundleID:   esTehID,
+			components: []models.BundleComponent{{ProductID: kopiID, Quantity: 1}},
+			wantBundle: true,
+		},
+		{
+			name:     "empty list turns bundle back",
+			bundleID: paketID,
+		},
+		{
+			name:       "contains itself",
+			bundleID:   esTehID,
+			components: []models.BundleComponent{{ProductID: esTehID, Quantity: 1}},
+			wantErr:    "cannot contain itself",
+		},
+		{
+			name:       "listed twice",
+			bundleID:   esTehID,
+			components: []models.BundleComponent{{ProductID: kopiID, Quantity: 1}, {ProductID: kopiID, Quantity: 2}},
+			wantErr:    "more than once",
+		},
+		{
+			name:       "zero quantity",
+			bundleID:   esTehID,
+			components: []models.BundleComponent{{ProductID: kopiID}},
+			wantErr:    "greater than 0",
+		},
+		{
+			name:       "bundle as component",
+			bundleID:   esTehID,
+			components: []models.BundleComponent{{ProductID: paketID, Quantity: 1}},
+			wantErr:    "is a bundle",
+		},
+		{
+			name:       "unknown component",
+			bundleID:   esTehID,
+			components: []models.BundleComponent{{ProductID: 99, Quantity: 1}},
+			wantErr:    "Product with ID 99 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			got, err := env.products.SetComponents(ctx, tt.bundleID, tt.components)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SetComponents() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetComponents() error = %v", err)
+			}
+			if len(got) != len(tt.components) {
+				t.Errorf("SetComponents() returned %d components, want %d", len(got), len(tt.components))
+			}
+
+			product, err := env.products.GetProductByID(ctx, tt.bundleID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if product.IsBundle != tt.wantBundle {
+				t.Errorf("IsBundle = %v, want %v", product.IsBundle, tt.wantBundle)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// ReceiptService builds receipts of transactions
+type ReceiptService struct {
+	transactionRepo repositories.TransactionRepository
+	productRepo     repositories.ProductRepository
+	variantRepo     repositories.ProductVariantRepository
+	store           models.StoreInfo
+	location        *time.Location
+}
+
+// NewReceiptService creates a new ReceiptService. Receipts carry the store
+// identity and show their time in location.
+func NewReceiptService(transactionRepo repositories.TransactionRepository, productRepo repositories.ProductRepository, variantRepo repositories.ProductVariantRepository, store models.StoreInfo, location *time.Location) *ReceiptService {
+	return &ReceiptService{
+		transactionRepo: transactionRepo,
+		productRepo:     productRepo,
+		variantRepo:     variantRepo,
+		store:           store,
+		location:        location,
+	}
+}
+
+// GetReceipt returns the receipt of a transaction
+func (s *ReceiptService) GetReceipt(ctx context.Context, transactionID int) (_ *models.Receipt, err error) {
+	ctx, span := tracer.Start(ctx, "ReceiptService.GetReceipt", trace.WithAttributes(
+		attribute.Int("transaction.id", transactionID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	transaction, err := s.transactionRepo.GetByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &models.Receipt{
+		TransactionID: transaction.ID,
+		OutletID:      transaction.OutletID,
+		Store:         s.store,
+		CreatedAt:     transaction.CreatedAt.In(s.location),
+		Items:         make([]models.ReceiptItem, 0, len(transaction.Details)),
+		TotalAmount:   transaction.TotalAmount,
+
+		PointsRedeemed: transaction.PointsRedeemed,
+		PointsAmount:   transaction.PointsAmount,
+		PointsEarned:   transaction.PointsEarned,
+	}
+
+	for _, d := range transaction.Details {
+		product, err := s.productRepo.GetByID(ctx, d.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		// A deleted variant leaves the line on its product
+		name := product.Name
+		if d.VariantID != nil {
+			if variant, err := s.variantRepo.GetByID(ctx, *d.VariantID); err == nil {
+				name += " " + variant.Name
+			}
+		}
+
+		item := models.ReceiptItem{
+			Name:     name,
+			Quantity: d.UnitQuantity,
+			Unit:     d.Unit,
+			Subtotal: d.Subtotal,
+		}
+		if d.UnitQuantity > 0 {
+			item.UnitPrice = int(math.Round(float64(d.Subtotal) / d.UnitQuantity))
+		}
+		receipt.Items = append(receipt.Items, item)
+	}
+
+	return receipt, nil
+}
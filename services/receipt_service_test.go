@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestReceiptServiceGetReceipt(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	transaction, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{Items: []models.TransactionItem{
+		{ProductID: kopiID, Quantity: 1, Unit: "box"},
+		{ProductID: berasID, Quantity: 250, Unit: "gram"},
+		{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Quantity: 2},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, err := env.receipts.GetReceipt(ctx, transaction.ID)
+	if err != nil {
+		t.Fatalf("GetReceipt() error = %v", err)
+	}
+
+	if receipt.TransactionID != transaction.ID || receipt.TotalAmount != 43000 {
+		t.Errorf("receipt = %+v, want transaction %d with total 43000", receipt, transaction.ID)
+	}
+	if receipt.Store.Name != "Toko Test" || receipt.Store.Phone != "0812" {
+		t.Errorf("Store = %+v", receipt.Store)
+	}
+	if receipt.CreatedAt.Location() != jakarta {
+		t.Errorf("CreatedAt = %v, want it in the store timezone", receipt.CreatedAt)
+	}
+
+	want := []models.ReceiptItem{
+		{Name: "Kopi", Quantity: 1, Unit: "box", UnitPrice: 30000, Subtotal: 30000},
+		{Name: "Beras", Quantity: 250, Unit: "gram", UnitPrice: 12, Subtotal: 3000},
+		{Name: "Es Teh L", Quantity: 2, Unit: "pcs", UnitPrice: 5000, Subtotal: 10000},
+	}
+	if len(receipt.Items) != len(want) {
+		t.Fatalf("Items = %+v, want %+v", receipt.Items, want)
+	}
+	for i := range want {
+		if receipt.Items[i] != want[i] {
+			t.Errorf("Items[%d] = %+v, want %+v", i, receipt.Items[i], want[i])
+		}
+	}
+}
+
+func TestReceiptServiceGetReceiptMissing(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.receipts.GetReceipt(context.Background(), 99)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetReceipt() error = %v, want not found", err)
+	}
+}
@@ -1,29 +1,49 @@
 package services
 
 import (
+	"context"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
+// Bundle revenue attribution modes for reports
+const (
+	AttributeToBundle     = "bundle"
+	AttributeToComponents = "components"
+)
+
 // ReportService handles business logic for reports
 type ReportService struct {
-	repo *repositories.ReportRepository
+	repo              repositories.ReportRepository
+	bundleAttribution string
+	location          *time.Location
 }
 
-// NewReportService creates a new ReportService
-func NewReportService(repo *repositories.ReportRepository) *ReportService {
-	return &ReportService{repo: repo}
+// NewReportService creates a new ReportService. bundleAttribution selects
+// whether bundle sales are reported on the bundle or on its components;
+// report days start at midnight in location.
+func NewReportService(repo repositories.ReportRepository, bundleAttribution string, location *time.Location) *ReportService {
+	return &ReportService{repo: repo, bundleAttribution: bundleAttribution, location: location}
 }
 
-// GetTodayReport returns sales summary for today
-func (s *ReportService) GetTodayReport() (*models.SalesReport, error) {
-	now := time.Now()
+// GetTodayReport returns sales summary for today of outletIDs, or of all
+// outlets when outletIDs is nil
+func (s *ReportService) GetTodayReport(ctx context.Context, outletIDs []int) (_ *models.SalesReport, err error) {
+	ctx, span := tracer.Start(ctx, "ReportService.GetTodayReport", trace.WithAttributes(
+		attribute.IntSlice("report.outlet_ids", outletIDs),
+	))
+	defer func() { endSpan(span, err) }()
+
+	now := time.Now().In(s.location)
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	endOfDay := startOfDay.AddDate(0, 0, 1)
 
-	report, err := s.repo.GetSalesReport(startOfDay, endOfDay)
+	report, err := s.repo.GetSalesReport(ctx, startOfDay, endOfDay, s.bundleAttribution == AttributeToComponents, outletIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -34,22 +54,30 @@ func (s *ReportService) GetTodayReport() (*models.SalesReport, error) {
 	return report, nil
 }
 
-// GetReportByDateRange returns sales summary for a date range
-func (s *ReportService) GetReportByDateRange(startDateStr, endDateStr string) (*models.SalesReport, error) {
-	startDate, err := time.Parse("2006-01-02", startDateStr)
+// GetReportByDateRange returns sales summary for a date range of outletIDs,
+// or of all outlets when outletIDs is nil
+func (s *ReportService) GetReportByDateRange(ctx context.Context, startDateStr, endDateStr string, outletIDs []int) (_ *models.SalesReport, err error) {
+	ctx, span := tracer.Start(ctx, "ReportService.GetReportByDateRange", trace.WithAttributes(
+		attribute.String("report.start_date", startDateStr),
+		attribute.String("report.end_date", endDateStr),
+		attribute.IntSlice("report.outlet_ids", outletIDs),
+	))
+	defer func() { endSpan(span, err) }()
+
+	startDate, err := time.ParseInLocation("2006-01-02", startDateStr, s.location)
 	if err != nil {
 		return nil, err
 	}
 
-	endDate, err := time.Parse("2006-01-02", endDateStr)
+	endDate, err := time.ParseInLocation("2006-01-02", endDateStr, s.location)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add 1 day to end date to include the entire end day
-	endDate = endDate.Add(24 * time.Hour)
+	endDate = endDate.AddDate(0, 0, 1)
 
-	report, err := s.repo.GetSalesReport(startDate, endDate)
+	report, err := s.repo.GetSalesReport(ctx, startDate, endDate, s.bundleAttribution == AttributeToComponents, outletIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories/memory"
+)
+
+func TestReportServiceGetReportByDateRange(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		name        string
+		attribution string
+		location    *time.Location
+		start, end  string
+		wantRevenue int
+		wantCount   int
+		wantBest    string
+		wantLines   map[int]float64
+		wantErr     bool
+	}{
+		{
+			name:        "bundle sales stay on the bundle",
+			attribution: AttributeToBundle,
+			location:    time.UTC,
+			start:       "2026-03-01",
+			end:         "2026-03-01",
+			wantRevenue: 47000,
+			wantCount:   3,
+			wantBest:    "Paket Sarapan",
+			wantLines:   map[int]float64{kopiID: 1, berasID: 1, paketID: 2},
+		},
+		{
+			name:        "bundle sales attributed to components",
+			attribution: AttributeToComponents,
+			location:    time.UTC,
+			start:       "2026-03-01",
+			end:         "2026-03-01",
+			wantRevenue: 47000,
+			wantCount:   3,
+			wantBest:    "Kopi",
+			wantLines:   map[int]float64{kopiID: 5, berasID: 2},
+		},
+		{
+			name:        "late sale belongs to the next day in the store time zone",
+			attribution: AttributeToBundle,
+			location:    wib,
+			start:       "2026-03-01",
+			end:         "2026-03-01",
+			wantRevenue: 35000,
+			wantCount:   2,
+			wantBest:    "Paket Sarapan",
+			wantLines:   map[int]float64{kopiID: 1, paketID: 2},
+		},
+		{
+			name:        "days start at midnight in the store time zone",
+			attribution: AttributeToBundle,
+			location:    wib,
+			start:       "2026-03-02",
+			end:         "2026-03-02",
+			wantRevenue: 12000,
+			wantCount:   1,
+			wantBest:    "Beras",
+			wantLines:   map[int]float64{berasID: 1},
+		},
+		{
+			name:        "range covers both days",
+			attribution: AttributeToBundle,
+			location:    wib,
+			start:       "2026-03-01",
+			end:         "2026-03-02",
+			wantRevenue: 47000,
+			wantCount:   3,
+			wantBest:    "Paket Sarapan",
+			wantLines:   map[int]float64{kopiID: 1, berasID: 1, paketID: 2},
+		},
+		{
+			name:        "no sales",
+			attribution: AttributeToBundle,
+			location:    time.UTC,
+			start:       "2026-04-01",
+			end:         "2026-04-30",
+		},
+		{
+			name:        "invalid date",
+			attribution: AttributeToBundle,
+			location:    time.UTC,
+			start:       "01-03-2026",
+			end:         "2026-03-01",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+
+			// Three sales on 1 March UTC; the last one at 20:00 UTC is
+			// already 2 March in WIB
+			for _, sale := range []struct {
+				at    time.Time
+				items []models.TransactionItem
+			}{
+				{time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC), []models.TransactionItem{{ProductID: kopiID, Quantity: 1}, {ProductID: paketID, Quantity: 1}}},
+				{time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC), []models.TransactionItem{{ProductID: paketID, Quantity: 1}}},
+				{time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC), []models.TransactionItem{{ProductID: berasID, Quantity: 1}}},
+			} {
+				env.store.Now = func() time.Time { return sale.at }
+				if _, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{Items: sale.items}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reports := NewReportService(memory.NewReportRepository(env.store), tt.attribution, tt.location)
+			got, err := reports.GetReportByDateRange(ctx, tt.start, tt.end, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("GetReportByDateRange() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetReportByDateRange() error = %v", err)
+			}
+
+			if got.TotalRevenue != tt.wantRevenue || got.TotalTransaksi != tt.wantCount {
+				t.Errorf("totals = %d / %d, want %d / %d", got.TotalRevenue, got.TotalTransaksi, tt.wantRevenue, tt.wantCount)
+			}
+			if got.StartDate != tt.start || got.EndDate != tt.end {
+				t.Errorf("dates = %s..%s, want %s..%s", got.StartDate, got.EndDate, tt.start, tt.end)
+			}
+			if tt.wantBest == "" {
+				if got.ProdukTerlaris != nil {
+					t.Errorf("ProdukTerlaris = %+v, want none", got.ProdukTerlaris)
+				}
+			} else if got.ProdukTerlaris == nil || got.ProdukTerlaris.Nama != tt.wantBest {
+				t.Errorf("ProdukTerlaris = %+v, want %s", got.ProdukTerlaris, tt.wantBest)
+			}
+
+			if len(got.RincianProduk) != len(tt.wantLines) {
+				t.Fatalf("RincianProduk = %+v, want %d products", got.RincianProduk, len(tt.wantLines))
+			}
+			for _, line := range got.RincianProduk {
+				if want, ok := tt.wantLines[line.ProductID]; !ok || line.QtyTerjual != want {
+					t.Errorf("product %d sold %v, want %v", line.ProductID, line.QtyTerjual, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReportServiceGetTodayReport(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 2}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := env.reports.GetTodayReport(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetTodayReport() error = %v", err)
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	if got.TotalRevenue != 10000 || got.TotalTransaksi != 1 || got.StartDate != today || got.EndDate != today {
+		t.Errorf("GetTodayReport() = %+v, want one Rp 10.000 sale on %s", got, today)
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kasir-api/metrics"
+	"kasir-api/models"
+	"kasir-api/repositories/memory"
+)
+
+// Products seeded by newTestEnv, in creation order
+const (
+	kopiID  = 1 // pcs, Rp 5.000, 10 in stock, sold by the box of 6
+	berasID = 2 // kg, Rp 12.000, 5 kg in stock
+	esTehID = 3 // pcs, Rp 3.000, variants S (id 1) and L (id 2)
+	paketID = 4 // bundle of 2 Kopi and 0.5 kg Beras, Rp 15.000
+)
+
+// Variants of Es Teh seeded by newTestEnv
+const (
+	esTehSmallID = 1 // Rp 3.000, 5 in stock
+	esTehLargeID = 2 // Rp 5.000, 2 in stock
+)
+
+// testLoyaltyProgram earns a point per Rp 1.000 worth Rp 10, kept for a year
+var testLoyaltyProgram = LoyaltyProgram{RupiahPerPoint: 1000, PointValue: 10, PointsTTL: 365 * 24 * time.Hour}
+
+// jakarta is the store timezone used by the receipts and price rules of
+// newTestEnv
+var jakarta = time.FixedZone("WIB", 7*60*60)
+
+// testEnv wires every service to a fresh in-memory store
+type testEnv struct {
+	store        *memory.Store
+	products     *ProductService
+	units        *UnitService
+	categories   *CategoryService
+	customers    *CustomerService
+	outlets      *OutletService
+	transfers    *TransferService
+	loyalty      *LoyaltyService
+	priceLists   *PriceListService
+	transactions *TransactionService
+	openBills    *OpenBillService
+	receipts     *ReceiptService
+	reports      *ReportService
+	idempotency  *IdempotencyService
+	audit        *AuditService
+	metrics      *metrics.Metrics
+}
+
+// newTestEnv creates the services on an in-memory store seeded with a small
+// catalog: one category, a piece product, a weighed product, a product with
+// variants and a bundle, all stocked at the main outlet
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	store := memory.NewStore()
+	productRepo := memory.NewProductRepository(store)
+	variantRepo := memory.NewProductVariantRepository(store)
+
+	env := &testEnv{store: store, metrics: metrics.New()}
+	env.units = NewUnitService(memory.NewUnitRepository(store))
+	env.products = NewProductService(productRepo, variantRepo)
+	categoryRepo := memory.NewCategoryRepository(store)
+	env.categories = NewCategoryService(categoryRepo)
+	customerRepo := memory.NewCustomerRepository(store)
+	env.customers = NewCustomerService(customerRepo, memory.NewTransactionRepository(store))
+	outletRepo := memory.NewOutletRepository(store)
+	env.outlets = NewOutletService(outletRepo, productRepo)
+	env.transfers = NewTransferService(memory.NewTransferRepository(store), outletRepo, productRepo, variantRepo)
+	env.loyalty = NewLoyaltyService(memory.NewLoyaltyRepository(store), categoryRepo, customerRepo, testLoyaltyProgram)
+	env.priceLists = NewPriceListService(memory.NewPriceListRepository(store), productRepo, variantRepo, jakarta)
+	env.transactions = NewTransactionService(memory.NewTransactionRepository(store), productRepo, variantRepo, customerRepo, outletRepo, env.units, env.loyalty, env.priceLists, env.metrics)
+	env.openBills = NewOpenBillService(memory.NewOpenBillRepository(store), outletRepo, env.transactions)
+	env.receipts = NewReceiptService(memory.NewTransactionRepository(store), productRepo, variantRepo, models.StoreInfo{Name: "Toko Test", Phone: "0812"}, jakarta)
+	env.reports = NewReportService(memory.NewReportRepository(store), AttributeToBundle, time.UTC)
+	env.idempotency = NewIdempotencyService(memory.NewIdempotencyRepository(store), time.Hour)
+	env.audit = NewAuditService(memory.NewAuditRepository(store))
+
+	ctx := context.Background()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("seeding test data: %v", err)
+		}
+	}
+
+	category, err := env.categories.CreateCategory(ctx, models.Category{Name: "Sembako"})
+	must(err)
+	for _, p := range []models.Product{
+		{Name: "Kopi", Price: 5000, Stock: 10, CategoryID: &category.ID},
+		{Name: "Beras", Price: 12000, Stock: 5, Unit: "kg", CategoryID: &category.ID},
+		{Name: "Es Teh", Price: 3000, CategoryID: &category.ID},
+		{Name: "Paket Sarapan", Price: 15000, CategoryID: &category.ID},
+	} {
+		_, err := env.products.CreateProduct(ctx, p)
+		must(err)
+	}
+	_, err = env.products.CreateVariant(ctx, esTehID, models.ProductVariant{Name: "S", SKU: "TEH-S", Price: 3000, Stock: 5})
+	must(err)
+	_, err = env.products.CreateVariant(ctx, esTehID, models.ProductVariant{Name: "L", SKU: "TEH-L", Price: 5000, Stock: 2})
+	must(err)
+	_, err = env.products.SetComponents(ctx, paketID, []models.BundleComponent{
+		{ProductID: kopiID, Quantity: 2},
+		{ProductID: berasID, Quantity: 0.5},
+	})
+	must(err)
+	_, err = env.units.SaveConversion(ctx, kopiID, models.UnitConversion{Unit: "box", Factor: 6})
+	must(err)
+
+	return env
+}
+
+// stockOf returns the current stock of a product
+func (env *testEnv) stockOf(t *testing.T, productID int) float64 {
+	t.Helper()
+	product, err := env.products.GetProductByID(context.Background(), productID)
+	if err != nil {
+		t.Fatalf("GetProductByID(%d) error = %v", productID, err)
+	}
+	return product.Stock
+}
+
+// intPtr returns a pointer to v
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of the service calls; SQL spans started by the
+// repositories become their children
+var tracer = otel.Tracer("kasir-api/services")
+
+// endSpan marks span as failed when err is not nil and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
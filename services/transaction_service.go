@@ -1,75 +1,542 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"kasir-api/metrics"
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
+// uuidPattern matches the client generated IDs of offline transactions
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// maxClockSkew is how far in the future an offline transaction may be dated
+const maxClockSkew = 5 * time.Minute
+
+// MaxSyncBatch is the number of offline transactions a sync batch may hold
+const MaxSyncBatch = 500
+
 // TransactionService handles business logic for transactions
 type TransactionService struct {
-	transactionRepo *repositories.TransactionRepository
-	productRepo     *repositories.ProductRepository
+	transactionRepo repositories.TransactionRepository
+	productRepo     repositories.ProductRepository
+	variantRepo     repositories.ProductVariantRepository
+	customerRepo    repositories.CustomerRepository
+	outletRepo      repositories.OutletRepository
+	unitService     *UnitService
+	loyalty         *LoyaltyService
+	pricing         *PriceListService
+	metrics         *metrics.Metrics
 }
 
-// NewTransactionService creates a new TransactionService
-func NewTransactionService(transactionRepo *repositories.TransactionRepository, productRepo *repositories.ProductRepository) *TransactionService {
+// NewTransactionService creates a new TransactionService; a nil loyalty
+// service disables loyalty points, a nil pricing service sells at the catalog
+// and outlet prices only and metrics may be nil
+func NewTransactionService(transactionRepo repositories.TransactionRepository, productRepo repositories.ProductRepository, variantRepo repositories.ProductVariantRepository, customerRepo repositories.CustomerRepository, outletRepo repositories.OutletRepository, unitService *UnitService, loyalty *LoyaltyService, pricing *PriceListService, metrics *metrics.Metrics) *TransactionService {
 	return &TransactionService{
 		transactionRepo: transactionRepo,
 		productRepo:     productRepo,
+		variantRepo:     variantRepo,
+		customerRepo:    customerRepo,
+		outletRepo:      outletRepo,
+		unitService:     unitService,
+		loyalty:         loyalty,
+		pricing:         pricing,
+		metrics:         metrics,
+	}
+}
+
+// CreateTransaction creates a new transaction from items at an outlet, the
+// main outlet unless the request names one
+func (s *TransactionService) CreateTransaction(ctx context.Context, req models.CreateTransactionRequest) (*models.Transaction, error) {
+	return s.createTransaction(ctx, req, s.transactionRepo.Create)
+}
+
+// createTransaction prices a sale, applies the loyalty points of its customer
+// and stores it with create, which takes the sold quantities off the stock
+func (s *TransactionService) createTransaction(ctx context.Context, req models.CreateTransactionRequest, create func(context.Context, models.Transaction) (*models.Transaction, error)) (_ *models.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.CreateTransaction", trace.WithAttributes(
+		attribute.Int("transaction.item_count", len(req.Items)),
+		attribute.Int("outlet.id", req.OutletID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	// A sale is anonymous unless it names a customer; it is priced from the
+	// price list it names, or else the price list of its customer
+	pricing := priceContext{at: time.Now(), priceListID: req.PriceListID}
+	if req.CustomerID != nil {
+		span.SetAttributes(attribute.Int("customer.id", *req.CustomerID))
+		customer, err := s.customerRepo.GetByID(ctx, *req.CustomerID)
+		if err != nil {
+			slog.WarnContext(ctx, "transaction rejected", "error", err)
+			return nil, err
+		}
+		if pricing.priceListID == nil {
+			pricing.priceListID = customer.PriceListID
+		}
+	}
+
+	transaction, catalog, err := s.buildTransaction(ctx, req.OutletID, req.Items, pricing)
+	if err != nil {
+		slog.WarnContext(ctx, "transaction rejected", "error", err)
+		return nil, err
+	}
+	transaction.CustomerID = req.CustomerID
+
+	// Customers earn points on the sale and may pay part of it with points
+	if s.loyalty != nil {
+		err = s.loyalty.applyPoints(ctx, transaction, catalog.products, req.RedeemPoints)
+	} else if req.RedeemPoints != 0 {
+		err = fmt.Errorf("loyalty points are disabled")
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "transaction rejected", "error", err)
+		return nil, err
+	}
+
+	created, err := create(ctx, *transaction)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInsufficientStock) {
+			s.metrics.StockOutRejected()
+		}
+		slog.WarnContext(ctx, "transaction rejected", "error", err)
+		return nil, err
+	}
+	s.metrics.TransactionCreated(created, false)
+	span.SetAttributes(
+		attribute.Int("transaction.id", created.ID),
+		attribute.Int("transaction.total_amount", created.TotalAmount),
+		attribute.Int("transaction.points_earned", created.PointsEarned),
+		attribute.Int("transaction.points_redeemed", created.PointsRedeemed),
+	)
+
+	slog.InfoContext(ctx, "transaction created",
+		"transaction_id", created.ID,
+		"outlet_id", created.OutletID,
+		"total_amount", created.TotalAmount,
+		"lines", len(created.Details),
+		"points_earned", created.PointsEarned,
+		"points_redeemed", created.PointsRedeemed,
+	)
+	return created, nil
+}
+
+// SyncTransactions inserts a batch of transactions created while the POS was
+// offline. Transactions are applied in the order they were created; every
+// transaction gets its own result so one bad sale does not block the batch.
+// A temporary failure, such as a database outage or a cancelled request,
+// stops the batch with an error instead of rejecting the sale; the sales
+// synced before it are reported as duplicates when the batch is sent again.
+func (s *TransactionService) SyncTransactions(ctx context.Context, req models.SyncTransactionsRequest) (_ *models.SyncTransactionsResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.SyncTransactions", trace.WithAttributes(
+		attribute.Int("sync.batch_size", len(req.Transactions)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if len(req.Transactions) == 0 {
+		return nil, fmt.Errorf("sync batch must have at least one transaction")
+	}
+	if len(req.Transactions) > MaxSyncBatch {
+		return nil, fmt.Errorf("sync batch must have at most %d transactions", MaxSyncBatch)
+	}
+
+	order := make([]int, len(req.Transactions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return req.Transactions[order[a]].CreatedAt.Before(req.Transactions[order[b]].CreatedAt)
+	})
+
+	response := &models.SyncTransactionsResponse{
+		Results: make([]models.SyncResult, len(req.Transactions)),
+	}
+	for _, i := range order {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		response.Results[i], err = s.syncTransaction(ctx, req.Transactions[i])
+		if err != nil {
+			return nil, fmt.Errorf("syncing transaction %s: %w", req.Transactions[i].ClientID, err)
+		}
+	}
+
+	return response, nil
+}
+
+// syncTransaction inserts a single offline transaction. Invalid transactions
+// are rejected in the result; temporary failures are returned as the error,
+// since the same sale may succeed when it is sent again.
+func (s *TransactionService) syncTransaction(ctx context.Context, offline models.OfflineTransaction) (_ models.SyncResult, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.syncTransaction", trace.WithAttributes(
+		attribute.String("transaction.client_id", offline.ClientID),
+		attribute.Int("transaction.item_count", len(offline.Items)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	result := models.SyncResult{ClientID: offline.ClientID}
+
+	reject := func(reason string) (models.SyncResult, error) {
+		slog.WarnContext(ctx, "offline transaction rejected", "client_id", offline.ClientID, "reason", reason)
+		result.Status = models.SyncStatusRejected
+		result.Reason = reason
+		span.SetAttributes(attribute.String("sync.status", result.Status))
+		return result, nil
+	}
+	fail := func(err error) (models.SyncResult, error) {
+		if IsTransient(err) {
+			return result, err
+		}
+		return reject(err.Error())
+	}
+
+	if !uuidPattern.MatchString(offline.ClientID) {
+		return reject("client_id must be a UUID")
+	}
+	if offline.CreatedAt.IsZero() {
+		return reject("created_at is required")
+	}
+	if offline.CreatedAt.After(time.Now().Add(maxClockSkew)) {
+		return reject("created_at is in the future")
+	}
+
+	// Offline sales are priced as of when they were made
+	transaction, _, err := s.buildTransaction(ctx, offline.OutletID, offline.Items, priceContext{at: offline.CreatedAt})
+	if err != nil {
+		return fail(err)
+	}
+	clientID := strings.ToLower(offline.ClientID)
+	transaction.ClientID = &clientID
+	transaction.CreatedAt = offline.CreatedAt
+
+	created, duplicate, conflicts, err := s.transactionRepo.CreateOffline(ctx, *transaction)
+	if err != nil {
+		return fail(err)
+	}
+
+	result.TransactionID = created.ID
+	result.Status = models.SyncStatusAccepted
+	if duplicate {
+		result.Status = models.SyncStatusDuplicate
+	} else {
+		s.metrics.TransactionCreated(created, true)
+	}
+	result.Conflicts = conflicts
+	span.SetAttributes(
+		attribute.Int("transaction.id", created.ID),
+		attribute.String("sync.status", result.Status),
+		attribute.Int("sync.conflicts", len(conflicts)),
+	)
+	if len(conflicts) > 0 {
+		slog.WarnContext(ctx, "offline transaction took stock below zero",
+			"transaction_id", created.ID,
+			"client_id", clientID,
+			"conflicts", len(conflicts),
+		)
 	}
+	return result, nil
 }
 
-// CreateTransaction creates a new transaction from items
-func (s *TransactionService) CreateTransaction(req models.CreateTransactionRequest) (*models.Transaction, error) {
-	if len(req.Items) == 0 {
-		return nil, fmt.Errorf("transaction must have at least one item")
+// buildTransaction prices the requested items at an outlet, the main outlet
+// when outletID is 0, for the time and price list of pricing and builds the
+// transaction to insert; it returns the catalog the items were priced from
+// with it
+func (s *TransactionService) buildTransaction(ctx context.Context, outletID int, items []models.TransactionItem, pricing priceContext) (_ *models.Transaction, _ *basketCatalog, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.buildTransaction", trace.WithAttributes(
+		attribute.Int("transaction.item_count", len(items)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if len(items) == 0 {
+		return nil, nil, fmt.Errorf("transaction must have at least one item")
+	}
+
+	if outletID == 0 {
+		outletID = models.DefaultOutletID
+	}
+	if _, err := s.outletRepo.GetByID(ctx, outletID); err != nil {
+		return nil, nil, err
+	}
+
+	catalog, err := s.loadCatalog(ctx, outletID, items, pricing)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var totalAmount int
 	var details []models.TransactionDetail
 
-	for _, item := range req.Items {
-		// Get product to calculate subtotal
-		product, err := s.productRepo.GetByID(item.ProductID)
-		if err != nil {
-			return nil, fmt.Errorf("product with ID %d not found", item.ProductID)
+	for _, item := range items {
+		// Deleted products are no longer sold, as if they did not exist; the
+		// catalog only holds them as the components of bundles
+		product, ok := catalog.products[item.ProductID]
+		if !ok || product.DeletedAt != nil {
+			return nil, nil, fmt.Errorf("product with ID %d not found", item.ProductID)
 		}
 
 		if item.Quantity <= 0 {
-			return nil, fmt.Errorf("quantity must be greater than 0")
+			return nil, nil, fmt.Errorf("quantity must be greater than 0")
+		}
+
+		// A selected variant overrides the parent product price
+		price := product.Price
+		if item.VariantID != nil {
+			variant, ok := catalog.variants[*item.VariantID]
+			if !ok || variant.ProductID != product.ID {
+				return nil, nil, fmt.Errorf("variant with ID %d not found for product with ID %d", *item.VariantID, item.ProductID)
+			}
+			price = variant.Price
 		}
 
-		subtotal := int(product.Price) * item.Quantity
+		// Price and stock are kept in the product unit
+		quantity, err := catalog.units.ToProductUnit(product, item.Quantity, item.Unit)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		unit := item.Unit
+		if unit == "" {
+			unit = product.Unit
+		}
+
+		// A price rule in effect for the sale replaces the outlet price
+		price, ruleID := catalog.prices.price(product.ID, item.VariantID, quantity, price)
+
+		subtotal := int(math.Round(price * quantity))
 		totalAmount += subtotal
 
-		details = append(details, models.TransactionDetail{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Subtotal:  subtotal,
-		})
+		detail := models.TransactionDetail{
+			ProductID:    item.ProductID,
+			VariantID:    item.VariantID,
+			Quantity:     quantity,
+			Unit:         unit,
+			UnitQuantity: item.Quantity,
+			Subtotal:     subtotal,
+			PriceRuleID:  ruleID,
+		}
+
+		if product.IsBundle {
+			if item.VariantID != nil {
+				return nil, nil, fmt.Errorf("bundle product with ID %d has no variants", item.ProductID)
+			}
+			detail.Components, err = catalog.bundleComponents(product.ID, quantity, subtotal)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		details = append(details, detail)
 	}
 
-	transaction := models.Transaction{
+	return &models.Transaction{
+		OutletID:    outletID,
+		PriceListID: pricing.priceListID,
 		TotalAmount: totalAmount,
 		Details:     details,
+	}, catalog, nil
+}
+
+// basketCatalog holds the products, variants, bundle components and units a
+// basket refers to, priced at the outlet of the basket, and the price rules
+// in effect for it
+type basketCatalog struct {
+	products   map[int]models.Product
+	variants   map[int]models.ProductVariant
+	components map[int][]models.BundleComponent
+	units      *UnitConverter
+	prices     *priceBook
+}
+
+// loadCatalog loads everything the items of a basket refer to with one query
+// per kind of row, so pricing a basket takes the same number of queries
+// whatever its size. The prices the outlet overrides replace the catalog
+// prices.
+func (s *TransactionService) loadCatalog(ctx context.Context, outletID int, items []models.TransactionItem, pricing priceContext) (*basketCatalog, error) {
+	catalog := &basketCatalog{
+		products: make(map[int]models.Product),
+		variants: make(map[int]models.ProductVariant),
+	}
+
+	var productIDs, variantIDs []int
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+		if item.VariantID != nil {
+			variantIDs = append(variantIDs, *item.VariantID)
+		}
+	}
+
+	products, err := s.productRepo.GetByIDs(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	var bundleIDs []int
+	for _, p := range products {
+		catalog.products[p.ID] = p
+		if p.IsBundle && p.DeletedAt == nil {
+			bundleIDs = append(bundleIDs, p.ID)
+		}
+	}
+
+	// Bundles are split over their components by the component prices
+	if len(bundleIDs) > 0 {
+		catalog.components, err = s.productRepo.GetComponentsByBundleIDs(ctx, bundleIDs)
+		if err != nil {
+			return nil, err
+		}
+		var componentIDs []int
+		for _, components := range catalog.components {
+			for _, c := range components {
+				if _, ok := catalog.products[c.ProductID]; !ok {
+					componentIDs = append(componentIDs, c.ProductID)
+				}
+			}
+		}
+		if len(componentIDs) > 0 {
+			components, err := s.productRepo.GetByIDs(ctx, componentIDs)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range components {
+				catalog.products[p.ID] = p
+			}
+		}
 	}
 
-	return s.transactionRepo.Create(transaction)
+	if len(variantIDs) > 0 {
+		variants, err := s.variantRepo.GetByIDs(ctx, variantIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range variants {
+			catalog.variants[v.ID] = v
+		}
+	}
+
+	pricedIDs := make([]int, 0, len(catalog.products))
+	for id := range catalog.products {
+		pricedIDs = append(pricedIDs, id)
+	}
+	overrides, err := s.outletRepo.GetProducts(ctx, outletID, pricedIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if o.Price == nil {
+			continue
+		}
+		if o.VariantID != nil {
+			if v, ok := catalog.variants[*o.VariantID]; ok {
+				v.Price = *o.Price
+				catalog.variants[v.ID] = v
+			}
+			continue
+		}
+		p := catalog.products[o.ProductID]
+		p.Price = *o.Price
+		catalog.products[p.ID] = p
+	}
+
+	catalog.units, err = s.unitService.NewConverter(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.pricing != nil {
+		catalog.prices, err = s.pricing.loadPriceBook(ctx, productIDs, pricing)
+		if err != nil {
+			return nil, err
+		}
+	} else if pricing.priceListID != nil {
+		return nil, fmt.Errorf("price lists are disabled")
+	}
+	return catalog, nil
 }
 
-// GetAllTransactions returns all transactions
-func (s *TransactionService) GetAllTransactions() ([]models.Transaction, error) {
-	return s.transactionRepo.GetAll()
+// GetAllTransactions returns the transactions matching filter, newest first
+func (s *TransactionService) GetAllTransactions(ctx context.Context, filter models.TransactionFilter) (_ []models.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.GetAllTransactions")
+	defer func() { endSpan(span, err) }()
+
+	return s.transactionRepo.GetAll(ctx, filter)
 }
 
 // GetTransactionByID returns a transaction by ID
-func (s *TransactionService) GetTransactionByID(id int) (*models.Transaction, error) {
-	return s.transactionRepo.GetByID(id)
+func (s *TransactionService) GetTransactionByID(ctx context.Context, id int) (_ *models.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.GetTransactionByID", trace.WithAttributes(
+		attribute.Int("transaction.id", id),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return s.transactionRepo.GetByID(ctx, id)
 }
 
 // DeleteTransaction deletes a transaction by ID
-func (s *TransactionService) DeleteTransaction(id int) error {
-	return s.transactionRepo.Delete(id)
+func (s *TransactionService) DeleteTransaction(ctx context.Context, id int) (err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.DeleteTransaction", trace.WithAttributes(
+		attribute.Int("transaction.id", id),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.transactionRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.metrics.TransactionVoided()
+	slog.InfoContext(ctx, "transaction deleted, stock restored", "transaction_id", id)
+	return nil
+}
+
+// bundleComponents decomposes a sold bundle into the component quantities to
+// take off stock and splits the bundle subtotal over the components by their
+// regular price
+func (c *basketCatalog) bundleComponents(bundleID int, quantity float64, subtotal int) ([]models.TransactionDetailComponent, error) {
+	items := c.components[bundleID]
+	if len(items) == 0 {
+		return nil, fmt.Errorf("bundle product with ID %d has no components", bundleID)
+	}
+
+	components := make([]models.TransactionDetailComponent, len(items))
+	weights := make([]float64, len(items))
+	var totalWeight float64
+	for i, item := range items {
+		product, ok := c.products[item.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("product with ID %d not found", item.ProductID)
+		}
+
+		components[i] = models.TransactionDetailComponent{
+			ProductID: item.ProductID,
+			Quantity:  roundQuantity(item.Quantity*quantity, 3),
+		}
+		weights[i] = product.Price * item.Quantity
+		totalWeight += weights[i]
+	}
+
+	// The last component takes the rounding remainder so the shares add up
+	allocated := 0
+	for i := range components {
+		if i == len(components)-1 {
+			components[i].Subtotal = subtotal - allocated
+			break
+		}
+		share := 1 / float64(len(components))
+		if totalWeight > 0 {
+			share = weights[i] / totalWeight
+		}
+		components[i].Subtotal = int(math.Round(float64(subtotal) * share))
+		allocated += components[i].Subtotal
+	}
+
+	return components, nil
 }
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/repositories/memory"
+)
+
+// roundTrip is the latency of a query through the Supabase pooler the
+// benchmarks simulate for every repository call
+const roundTrip = 100 * time.Microsecond
+
+// roundTrips counts repository calls and makes each one cost a round trip
+type roundTrips struct {
+	n atomic.Int64
+}
+
+func (rt *roundTrips) add() {
+	rt.n.Add(1)
+	time.Sleep(roundTrip)
+}
+
+// slowProductRepository adds a round trip to the product reads of a checkout
+type slowProductRepository struct {
+	repositories.ProductRepository
+	trips *roundTrips
+}
+
+func (r slowProductRepository) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	r.trips.add()
+	return r.ProductRepository.GetByID(ctx, id)
+}
+
+func (r slowProductRepository) GetByIDs(ctx context.Context, ids []int) ([]models.Product, error) {
+	r.trips.add()
+	return r.ProductRepository.GetByIDs(ctx, ids)
+}
+
+func (r slowProductRepository) GetComponentsByBundleIDs(ctx context.Context, bundleIDs []int) (map[int][]models.BundleComponent, error) {
+	r.trips.add()
+	return r.ProductRepository.GetComponentsByBundleIDs(ctx, bundleIDs)
+}
+
+func (r slowProductRepository) GetComponents(ctx context.Context, bundleID int) ([]models.BundleComponent, error) {
+	r.trips.add()
+	return r.ProductRepository.GetComponents(ctx, bundleID)
+}
+
+// slowVariantRepository adds a round trip to the variant reads of a checkout
+type slowVariantRepository struct {
+	repositories.ProductVariantRepository
+	trips *roundTrips
+}
+
+func (r slowVariantRepository) GetByID(ctx context.Context, id int) (*models.ProductVariant, error) {
+	r.trips.add()
+	return r.ProductVariantRepository.GetByID(ctx, id)
+}
+
+func (r slowVariantRepository) GetByIDs(ctx context.Context, ids []int) ([]models.ProductVariant, error) {
+	r.trips.add()
+	return r.ProductVariantRepository.GetByIDs(ctx, ids)
+}
+
+// slowUnitRepository adds a round trip to the unit reads of a checkout
+type slowUnitRepository struct {
+	repositories.UnitRepository
+	trips *roundTrips
+}
+
+func (r slowUnitRepository) GetByCode(ctx context.Context, code string) (*models.Unit, error) {
+	r.trips.add()
+	return r.UnitRepository.GetByCode(ctx, code)
+}
+
+func (r slowUnitRepository) GetConversion(ctx context.Context, productID int, unit string) (*models.UnitConversion, error) {
+	r.trips.add()
+	return r.UnitRepository.GetConversion(ctx, productID, unit)
+}
+
+func (r slowUnitRepository) GetAll(ctx context.Context) ([]models.Unit, error) {
+	r.trips.add()
+	return r.UnitRepository.GetAll(ctx)
+}
+
+func (r slowUnitRepository) GetConversionsByProductIDs(ctx context.Context, productIDs []int) ([]models.UnitConversion, error) {
+	r.trips.add()
+	return r.UnitRepository.GetConversionsByProductIDs(ctx, productIDs)
+}
+
+// slowOutletRepository adds a round trip to the outlet reads of a checkout
+type slowOutletRepository struct {
+	repositories.OutletRepository
+	trips *roundTrips
+}
+
+func (r slowOutletRepository) GetByID(ctx context.Context, id int) (*models.Outlet, error) {
+	r.trips.add()
+	return r.OutletRepository.GetByID(ctx, id)
+}
+
+func (r slowOutletRepository) GetProducts(ctx context.Context, outletID int, productIDs []int) ([]models.OutletProduct, error) {
+	r.trips.add()
+	return r.OutletRepository.GetProducts(ctx, outletID, productIDs)
+}
+
+// slowTransactionRepository adds a round trip to the insert of a checkout
+type slowTransactionRepository struct {
+	repositories.TransactionRepository
+	trips *roundTrips
+}
+
+func (r slowTransactionRepository) Create(ctx context.Context, transaction models.Transaction) (*models.Transaction, error) {
+	r.trips.add()
+	return r.TransactionRepository.Create(ctx, transaction)
+}
+
+// BenchmarkCreateTransaction checks out baskets of increasing size and
+// reports the repository round trips each checkout takes
+func BenchmarkCreateTransaction(b *testing.B) {
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	b.Cleanup(func() { slog.SetDefault(logger) })
+
+	for _, size := range []int{1, 10, 40} {
+		b.Run(fmt.Sprintf("items=%d", size), func(b *testing.B) {
+			ctx := context.Background()
+			store := memory.NewStore()
+			trips := &roundTrips{}
+
+			productRepo := memory.NewProductRepository(store)
+			variantRepo := memory.NewProductVariantRepository(store)
+			unitRepo := memory.NewUnitRepository(store)
+			if _, err := memory.NewCategoryRepository(store).Create(ctx, models.Category{Name: "Sembako"}); err != nil {
+				b.Fatal(err)
+			}
+
+			items := make([]models.TransactionItem, size)
+			for i := range items {
+				product, err := productRepo.Create(ctx, models.Product{
+					Name:       fmt.Sprintf("Produk %d", i),
+					Price:      1000,
+					Stock:      1e9,
+					Unit:       "pcs",
+					CategoryID: intPtr(1),
+				}, "")
+				if err != nil {
+					b.Fatal(err)
+				}
+				items[i] = models.TransactionItem{ProductID: product.ID, Quantity: 1}
+			}
+
+			service := NewTransactionService(
+				slowTransactionRepository{memory.NewTransactionRepository(store), trips},
+				slowProductRepository{productRepo, trips},
+				slowVariantRepository{variantRepo, trips},
+				memory.NewCustomerRepository(store),
+				slowOutletRepository{memory.NewOutletRepository(store), trips},
+				NewUnitService(slowUnitRepository{unitRepo, trips}),
+				nil,
+				nil,
+				nil,
+			)
+			req := models.CreateTransactionRequest{Items: items}
+
+			b.ResetTimer()
+			for range b.N {
+				if _, err := service.CreateTransaction(ctx, req); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(trips.n.Load())/float64(b.N), "round-trips/op")
+		})
+	}
+}
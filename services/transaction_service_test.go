@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"kasir-api/models"
+)
+
+func TestTransactionServiceCreateTransaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []models.TransactionItem
+		wantTotal int
+		wantStock map[int]float64
+		wantErr   string
+	}{
+		{
+			name:      "single product",
+			items:     []models.TransactionItem{{ProductID: kopiID, Quantity: 2}},
+			wantTotal: 10000,
+			wantStock: map[int]float64{kopiID: 8},
+		},
+		{
+			name:      "packaging unit",
+			items:     []models.TransactionItem{{ProductID: kopiID, Quantity: 1, Unit: "box"}},
+			wantTotal: 30000,
+			wantStock: map[int]float64{kopiID: 4},
+		},
+		{
+			name:      "weighed product sold in grams",
+			items:     []models.TransactionItem{{ProductID: berasID, Quantity: 500, Unit: "gram"}},
+			wantTotal: 6000,
+			wantStock: map[int]float64{berasID: 4.5},
+		},
+		{
+			name:      "variant price",
+			items:     []models.TransactionItem{{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Quantity: 1}},
+			wantTotal: 5000,
+		},
+		{
+			name:      "bundle takes component stock",
+			items:     []models.TransactionItem{{ProductID: paketID, Quantity: 1}},
+			wantTotal: 15000,
+			wantStock: map[int]float64{kopiID: 8, berasID: 4.5},
+		},
+		{
+			name:      "several lines",
+			items:     []models.TransactionItem{{ProductID: kopiID, Quantity: 1}, {ProductID: berasID, Quantity: 1.5}},
+			wantTotal: 23000,
+			wantStock: map[int]float64{kopiID: 9, berasID: 3.5},
+		},
+		{
+			name:      "insufficient stock",
+			items:     []models.TransactionItem{{ProductID: kopiID, Quantity: 11}},
+			wantErr:   "insufficient stock for product with ID 1",
+			wantStock: map[int]float64{kopiID: 10},
+		},
+		{
+			name:      "insufficient stock on a later line rolls back earlier lines",
+			items:     []models.TransactionItem{{ProductID: kopiID, Quantity: 2}, {ProductID: berasID, Quantity: 6}},
+			wantErr:   "insufficient stock for product with ID 2",
+			wantStock: map[int]float64{kopiID: 10, berasID: 5},
+		},
+		{
+			name:    "no items",
+			wantErr: "transaction must have at least one item",
+		},
+		{
+			name:    "unknown product",
+			items:   []models.TransactionItem{{ProductID: 99, Quantity: 1}},
+			wantErr: "product with ID 99 not found",
+		},
+		{
+			name:    "zero quantity",
+			items:   []models.TransactionItem{{ProductID: kopiID}},
+			wantErr: "quantity must be greater than 0",
+		},
+		{
+			name:    "variant of another product",
+			items:   []models.TransactionItem{{ProductID: kopiID, VariantID: intPtr(esTehSmallID), Quantity: 1}},
+			wantErr: "variant with ID 1 not found for product with ID 1",
+		},
+		{
+			name:    "unit that cannot be converted",
+			items:   []models.TransactionItem{{ProductID: berasID, Quantity: 1, Unit: "liter"}},
+			wantErr: "cannot convert liter to kg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+
+			got, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{Items: tt.items})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CreateTransaction() error = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("CreateTransaction() error = %v", err)
+				}
+				if got.ID == 0 || got.TotalAmount != tt.wantTotal || len(got.Details) != len(tt.items) {
+					t.Errorf("CreateTransaction() = %+v, want total %d with %d details", got, tt.wantTotal, len(tt.items))
+				}
+			}
+
+			for productID, want := range tt.wantStock {
+				if stock := env.stockOf(t, productID); stock != want {
+					t.Errorf("stock of product %d = %v, want %v", productID, stock, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTransactionServiceBundleRevenueSplit(t *testing.T) {
+	env := newTestEnv(t)
+
+	got, err := env.transactions.CreateTransaction(context.Background(), models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: paketID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+
+	// Kopi weighs 2 x 5.000 and Beras 0.5 x 12.000 of the Rp 15.000 bundle
+	want := []models.TransactionDetailComponent{
+		{ProductID: kopiID, Quantity: 2, Subtotal: 9375},
+		{ProductID: berasID, Quantity: 0.5, Subtotal: 5625},
+	}
+	components := got.Details[0].Components
+	if len(components) != len(want) {
+		t.Fatalf("components = %+v, want %+v", components, want)
+	}
+	for i := range want {
+		if components[i] != want[i] {
+			t.Errorf("component %d = %+v, want %+v", i, components[i], want[i])
+		}
+	}
+}
+
+func TestTransactionServiceVariantStock(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	_, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Quantity: 3}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "insufficient stock") {
+		t.Fatalf("CreateTransaction() error = %v, want insufficient stock", err)
+	}
+
+	created, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+
+	variants, _ := env.products.GetVariants(ctx, esTehID)
+	if variants[1].Stock != 0 {
+		t.Errorf("variant stock = %v, want 0", variants[1].Stock)
+	}
+
+	if err := env.transactions.DeleteTransaction(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteTransaction() error = %v", err)
+	}
+	variants, _ = env.products.GetVariants(ctx, esTehID)
+	if variants[1].Stock != 2 {
+		t.Errorf("variant stock after void = %v, want 2", variants[1].Stock)
+	}
+}
+
+func TestTransactionServiceDeleteTransaction(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	created, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{
+			{ProductID: kopiID, Quantity: 3},
+			{ProductID: paketID, Quantity: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if stock := env.stockOf(t, kopiID); stock != 3 {
+		t.Fatalf("stock of Kopi = %v, want 3", stock)
+	}
+
+	if err := env.transactions.DeleteTransaction(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteTransaction() error = %v", err)
+	}
+	if stock := env.stockOf(t, kopiID); stock != 10 {
+		t.Errorf("stock of Kopi after void = %v, want 10", stock)
+	}
+	if stock := env.stockOf(t, berasID); stock != 5 {
+		t.Errorf("stock of Beras after void = %v, want 5", stock)
+	}
+
+	if _, err := env.transactions.GetTransactionByID(ctx, created.ID); err == nil {
+		t.Error("GetTransactionByID() after delete succeeded, want not found")
+	}
+	if err := env.transactions.DeleteTransaction(ctx, created.ID); err == nil {
+		t.Error("second DeleteTransaction() succeeded, want not found")
+	}
+}
+
+func TestTransactionServiceGetTransactions(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	env.store.Now = func() time.Time { return now }
+	first, _ := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 1}}})
+	now = now.Add(time.Hour)
+	second, _ := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{Items: []models.TransactionItem{{ProductID: paketID, Quantity: 1}}})
+
+	all, err := env.transactions.GetAllTransactions(ctx, models.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("GetAllTransactions() error = %v", err)
+	}
+	if len(all) != 2 || all[0].ID != second.ID || all[1].ID != first.ID {
+		t.Errorf("GetAllTransactions() = %+v, want newest first", all)
+	}
+
+	got, err := env.transactions.GetTransactionByID(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("GetTransactionByID() error = %v", err)
+	}
+	if len(got.Details) != 1 || len(got.Details[0].Components) != 2 {
+		t.Errorf("GetTransactionByID() = %+v, want the bundle line with its components", got)
+	}
+}
+
+func TestTransactionServiceSyncTransactions(t *testing.T) {
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	kopi := func(quantity float64) []models.TransactionItem {
+		return []models.TransactionItem{{ProductID: kopiID, Quantity: quantity}}
+	}
+
+	tests := []struct {
+		name          string
+		transactions  []models.OfflineTransaction
+		wantStatuses  []string
+		wantConflicts []int
+		wantStock     float64
+		wantErr       string
+	}{
+		{
+			name: "accepted",
+			transactions: []models.OfflineTransaction{
+				{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000001", CreatedAt: base, Items: kopi(2)},
+			},
+			wantStatuses:  []string{models.SyncStatusAccepted},
+			wantConflicts: []int{0},
+			wantStock:     8,
+		},
+		{
+			name: "re-sent transaction is a duplicate",
+			transactions: []models.OfflineTransaction{
+				{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000001", CreatedAt: base, Items: kopi(2)},
+				{ClientID: "0B0C6F7E-1D2A-4C3B-9E8F-000000000001", CreatedAt: base, Items: kopi(2)},
+			},
+			wantStatuses:  []string{models.SyncStatusAccepted, models.SyncStatusDuplicate},
+			wantConflicts: []int{0, 0},
+			wantStock:     8,
+		},
+		{
+			name: "applied in creation order with conflicts on the later sale",
+			transactions: []models.OfflineTransaction{
+				{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000002", CreatedAt: base.Add(time.Minute), Items: kopi(6)},
+				{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000001", CreatedAt: base, Items: kopi(6)},
+			},
+			wantStatuses:  []string{models.SyncStatusAccepted, models.SyncStatusAccepted},
+			wantConflicts: []int{1, 0},
+			wantStock:     -2,
+		},
+		{
+			name: "invalid transactions are rejected without blocking the batch",
+			transactions: []models.OfflineTransaction{
+				{ClientID: "not-a-uuid", CreatedAt: base, Items: kopi(1)},
+				{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000003", Items: kopi(1)},
+				{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000004", CreatedAt: time.Now().Add(time.Hour), Items: kopi(1)},
+				{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000005", CreatedAt: base, Items: []models.TransactionItem{{ProductID: 99, Quantity: 1}}},
+				{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000006", CreatedAt: base, Items: kopi(1)},
+			},
+			wantStatuses: []string{
+				models.SyncStatusRejected,
+				models.SyncStatusRejected,
+				models.SyncStatusRejected,
+				models.SyncStatusRejected,
+				models.SyncStatusAccepted,
+			},
+			wantConflicts: []int{0, 0, 0, 0, 0},
+			wantStock:     9,
+		},
+		{
+			name:      "empty batch",
+			wantErr:   "at least one transaction",
+			wantStock: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			got, err := env.transactions.SyncTransactions(context.Background(), models.SyncTransactionsRequest{Transactions: tt.transactions})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SyncTransactions() error = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("SyncTransactions() error = %v", err)
+				}
+				if len(got.Results) != len(tt.wantStatuses) {
+					t.Fatalf("SyncTransactions() returned %d results, want %d", len(got.Results), len(tt.wantStatuses))
+				}
+				for i, result := range got.Results {
+					if result.ClientID != tt.transactions[i].ClientID {
+						t.Errorf("result %d client_id = %s, want results in request order", i, result.ClientID)
+					}
+					if result.Status != tt.wantStatuses[i] {
+						t.Errorf("result %d status = %s (%s), want %s", i, result.Status, result.Reason, tt.wantStatuses[i])
+					}
+					if len(result.Conflicts) != tt.wantConflicts[i] {
+						t.Errorf("result %d has %d conflicts, want %d", i, len(result.Conflicts), tt.wantConflicts[i])
+					}
+				}
+			}
+
+			if stock := env.stockOf(t, kopiID); stock != tt.wantStock {
+				t.Errorf("stock of Kopi = %v, want %v", stock, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestTransactionServiceSyncTemporaryFailure(t *testing.T) {
+	env := newTestEnv(t)
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	req := models.SyncTransactionsRequest{Transactions: []models.OfflineTransaction{
+		{ClientID: "0b0c6f7e-1d2a-4c3b-9e8f-000000000001", CreatedAt: base, Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 2}}},
+	}}
+
+	// A cancelled request fails the batch instead of rejecting its sales
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := env.transactions.SyncTransactions(ctx, req); !IsTransient(err) {
+		t.Fatalf("SyncTransactions() error = %v, want a temporary failure", err)
+	}
+	if got := env.stockOf(t, kopiID); got != 10 {
+		t.Errorf("stock of Kopi = %v, want 10", got)
+	}
+
+	got, err := env.transactions.SyncTransactions(context.Background(), req)
+	if err != nil || got.Results[0].Status != models.SyncStatusAccepted {
+		t.Fatalf("SyncTransactions() retry = %+v, %v; want accepted", got, err)
+	}
+
+	req.Transactions = make([]models.OfflineTransaction, MaxSyncBatch+1)
+	if _, err := env.transactions.SyncTransactions(context.Background(), req); err == nil || err.Error() != "sync batch must have at most 500 transactions" {
+		t.Errorf("SyncTransactions() of a large batch error = %v, want at most 500", err)
+	}
+}
+
+func TestTransactionServiceMetrics(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	created, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 2}, {ProductID: berasID, Quantity: 1.5}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if _, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 50}},
+	}); err == nil {
+		t.Fatal("CreateTransaction() beyond stock succeeded, want error")
+	}
+	if _, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: 99, Quantity: 1}},
+	}); err == nil {
+		t.Fatal("CreateTransaction() of an unknown product succeeded, want error")
+	}
+	offline := models.OfflineTransaction{
+		ClientID:  "0b0c6f7e-1d2a-4c3b-9e8f-000000000001",
+		CreatedAt: time.Now().Add(-time.Minute),
+		Items:     []models.TransactionItem{{ProductID: kopiID, Quantity: 1}},
+	}
+	for range 2 {
+		if _, err := env.transactions.SyncTransactions(ctx, models.SyncTransactionsRequest{Transactions: []models.OfflineTransaction{offline}}); err != nil {
+			t.Fatalf("SyncTransactions() error = %v", err)
+		}
+	}
+	if err := env.transactions.DeleteTransaction(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteTransaction() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	env.metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`kasir_transactions_created_total{source="pos"} 1`,
+		`kasir_transactions_created_total{source="offline"} 1`,
+		`kasir_items_sold_total 4.5`,
+		`kasir_revenue_rupiah_total 33000`,
+		`kasir_transactions_voided_total 1`,
+		`kasir_stock_out_rejections_total 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want+"\n") {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+}
+
+// spanRecorder records the spans of the services. The tracer of the package
+// is bound to the first global provider, so it is installed once for all tests.
+var spanRecorder = sync.OnceValue(func() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+})
+
+func TestTransactionServiceTracing(t *testing.T) {
+	recorder := spanRecorder()
+	env := newTestEnv(t)
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "POST /api/transactions")
+	created, err := env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{
+		Items: []models.TransactionItem{{ProductID: kopiID, Quantity: 1}, {ProductID: berasID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	_, err = env.transactions.CreateTransaction(ctx, models.CreateTransactionRequest{})
+	root.End()
+	if err == nil {
+		t.Fatal("CreateTransaction() without items succeeded, want error")
+	}
+
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == root.SpanContext().TraceID() && span.Name() != "POST /api/transactions" {
+			spans = append(spans, span)
+		}
+	}
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	want := "TransactionService.buildTransaction TransactionService.CreateTransaction TransactionService.buildTransaction TransactionService.CreateTransaction"
+	if strings.Join(names, " ") != want {
+		t.Fatalf("spans = %v, want %s", names, want)
+	}
+
+	build, checkout := spans[0], spans[1]
+	if build.Parent().SpanID() != checkout.SpanContext().SpanID() || checkout.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Error("spans are not nested request > CreateTransaction > buildTransaction")
+	}
+	attrs := make(map[attribute.Key]int64)
+	for _, attr := range checkout.Attributes() {
+		attrs[attr.Key] = attr.Value.AsInt64()
+	}
+	if attrs["transaction.id"] != int64(created.ID) || attrs["transaction.item_count"] != 2 || attrs["transaction.total_amount"] != int64(created.TotalAmount) {
+		t.Errorf("CreateTransaction attributes = %v", checkout.Attributes())
+	}
+
+	if failed := spans[3]; failed.Status().Code != codes.Error || failed.Status().Description != "transaction must have at least one item" {
+		t.Errorf("status of a rejected checkout = %v, want the error", failed.Status())
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// ErrTransferStatus is wrapped by the errors returned when a transfer is not
+// in the status a change requires, such as editing a transfer already sent
+var ErrTransferStatus = repositories.ErrTransferStatus
+
+// TransferService handles business logic for stock transfers between outlets
+type TransferService struct {
+	repo        repositories.TransferRepository
+	outletRepo  repositories.OutletRepository
+	productRepo repositories.ProductRepository
+	variantRepo repositories.ProductVariantRepository
+}
+
+// NewTransferService creates a new TransferService
+func NewTransferService(repo repositories.TransferRepository, outletRepo repositories.OutletRepository, productRepo repositories.ProductRepository, variantRepo repositories.ProductVariantRepository) *TransferService {
+	return &TransferService{repo: repo, outletRepo: outletRepo, productRepo: productRepo, variantRepo: variantRepo}
+}
+
+// GetAllTransfers returns the transfers matching filter, newest first
+func (s *TransferService) GetAllTransfers(ctx context.Context, filter models.TransferFilter) ([]models.StockTransfer, error) {
+	switch filter.Status {
+	case "", models.TransferDraft, models.TransferInTransit, models.TransferReceived:
+	default:
+		return nil, fmt.Errorf("unknown transfer status %q", filter.Status)
+	}
+	return s.repo.GetAll(ctx, filter)
+}
+
+// GetTransferByID returns a transfer by ID with its lines
+func (s *TransferService) GetTransferByID(ctx context.Context, id int) (*models.StockTransfer, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// CreateTransfer creates a draft transfer; its stock moves once it is sent
+func (s *TransferService) CreateTransfer(ctx context.Context, transfer models.StockTransfer) (*models.StockTransfer, error) {
+	if err := s.validateTransfer(ctx, &transfer); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, transfer)
+}
+
+// UpdateTransfer replaces the outlets, note and lines of a draft transfer
+func (s *TransferService) UpdateTransfer(ctx context.Context, id int, transfer models.StockTransfer) (*models.StockTransfer, error) {
+	if err := s.validateTransfer(ctx, &transfer); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, id, transfer)
+}
+
+// DeleteTransfer deletes a draft transfer
+func (s *TransferService) DeleteTransfer(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// SendTransfer takes the lines of a draft transfer off the stock of the
+// source outlet and puts the transfer in transit
+func (s *TransferService) SendTransfer(ctx context.Context, id int) (*models.StockTransfer, error) {
+	return s.repo.Send(ctx, id)
+}
+
+// ReceiveTransfer adds the quantities that arrived to the stock of the
+// destination outlet. Lines may arrive over several receipts; the transfer
+// is received once every line arrived in full or a receipt closes it.
+func (s *TransferService) ReceiveTransfer(ctx context.Context, id int, receipt models.TransferReceipt) (*models.StockTransfer, error) {
+	if len(receipt.Lines) == 0 && !receipt.Close {
+		return nil, fmt.Errorf("receipt must have at least one line")
+	}
+
+	seen := make(map[int]bool, len(receipt.Lines))
+	for i := range receipt.Lines {
+		line := &receipt.Lines[i]
+		if line.Quantity < 0 {
+			return nil, fmt.Errorf("received quantity must not be negative")
+		}
+		if seen[line.LineID] {
+			return nil, fmt.Errorf("transfer line with ID %d is received twice", line.LineID)
+		}
+		seen[line.LineID] = true
+		line.Quantity = roundQuantity(line.Quantity, 3)
+		line.Note = strings.TrimSpace(line.Note)
+	}
+	return s.repo.Receive(ctx, id, receipt)
+}
+
+// validateTransfer checks the outlets and lines of a transfer. Lines move the
+// stock of a product, or of one of its variants; bundles have no stock of
+// their own and a product or variant may appear on one line only.
+func (s *TransferService) validateTransfer(ctx context.Context, transfer *models.StockTransfer) error {
+	transfer.Note = strings.TrimSpace(transfer.Note)
+
+	if transfer.FromOutletID == transfer.ToOutletID {
+		return fmt.Errorf("transfer must be between two different outlets")
+	}
+	for _, id := range []int{transfer.FromOutletID, transfer.ToOutletID} {
+		if _, err := s.outletRepo.GetByID(ctx, id); err != nil {
+			return err
+		}
+	}
+	if len(transfer.Lines) == 0 {
+		return fmt.Errorf("transfer must have at least one line")
+	}
+
+	var productIDs, variantIDs []int
+	for _, l := range transfer.Lines {
+		productIDs = append(productIDs, l.ProductID)
+		if l.VariantID != nil {
+			variantIDs = append(variantIDs, *l.VariantID)
+		}
+	}
+	products, err := s.productRepo.GetByIDs(ctx, productIDs)
+	if err != nil {
+		return err
+	}
+	productByID := make(map[int]models.Product, len(products))
+	for _, p := range products {
+		productByID[p.ID] = p
+	}
+	variantByID := make(map[int]models.ProductVariant)
+	if len(variantIDs) > 0 {
+		variants, err := s.variantRepo.GetByIDs(ctx, variantIDs)
+		if err != nil {
+			return err
+		}
+		for _, v := range variants {
+			variantByID[v.ID] = v
+		}
+	}
+
+	seen := make(map[[2]int]bool, len(transfer.Lines))
+	for i := range transfer.Lines {
+		line := &transfer.Lines[i]
+		product, ok := productByID[line.ProductID]
+		if !ok {
+			return fmt.Errorf("product with ID %d not found", line.ProductID)
+		}
+		if product.IsBundle {
+			return fmt.Errorf("bundle product with ID %d has no stock of its own", line.ProductID)
+		}
+
+		key := [2]int{line.ProductID, 0}
+		if line.VariantID != nil {
+			if v, ok := variantByID[*line.VariantID]; !ok || v.ProductID != line.ProductID {
+				return fmt.Errorf("variant with ID %d not found for product with ID %d", *line.VariantID, line.ProductID)
+			}
+			key[1] = *line.VariantID
+		}
+		if seen[key] {
+			return fmt.Errorf("product with ID %d is on the transfer twice", line.ProductID)
+		}
+		seen[key] = true
+
+		line.Quantity = roundQuantity(line.Quantity, 3)
+		if line.Quantity <= 0 {
+			return fmt.Errorf("quantity must be greater than 0")
+		}
+	}
+	return nil
+}
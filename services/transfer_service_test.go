@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// newTransfer drafts a transfer of 4 Kopi and 2.5 kg Beras from the main
+// outlet to a new branch and returns it with the branch ID
+func newTransfer(t *testing.T, env *testEnv) (*models.StockTransfer, int) {
+	t.Helper()
+	branchID := newBranch(t, env)
+	transfer, err := env.transfers.CreateTransfer(context.Background(), models.StockTransfer{
+		FromOutletID: models.DefaultOutletID,
+		ToOutletID:   branchID,
+		Note:         " restock ",
+		Lines: []models.TransferLine{
+			{ProductID: kopiID, Quantity: 4},
+			{ProductID: berasID, Quantity: 2.5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateTransfer() error = %v", err)
+	}
+	return transfer, branchID
+}
+
+func TestTransferServiceLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	transfer, branchID := newTransfer(t, env)
+
+	// A draft does not move stock
+	if transfer.Status != models.TransferDraft || transfer.Note != "restock" || len(transfer.Lines) != 2 {
+		t.Fatalf("transfer = %+v, want a draft with 2 lines", transfer)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, kopiID, nil); got != 10 {
+		t.Errorf("main outlet Kopi stock = %v, want 10", got)
+	}
+
+	// Sending takes the lines off the source; the goods are in no outlet
+	sent, err := env.transfers.SendTransfer(ctx, transfer.ID)
+	if err != nil {
+		t.Fatalf("SendTransfer() error = %v", err)
+	}
+	if sent.Status != models.TransferInTransit || sent.SentAt == nil {
+		t.Errorf("sent = %+v, want in transit", sent)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, kopiID, nil); got != 6 {
+		t.Errorf("main outlet Kopi stock = %v, want 6", got)
+	}
+	if got := env.stockOf(t, kopiID); got != 6 {
+		t.Errorf("total Kopi stock = %v, want 6", got)
+	}
+
+	// Part of a line arrives first
+	kopiLine, berasLine := sent.Lines[0].ID, sent.Lines[1].ID
+	partial, err := env.transfers.ReceiveTransfer(ctx, transfer.ID, models.TransferReceipt{
+		Lines: []models.ReceivedLine{{LineID: kopiLine, Quantity: 3}},
+	})
+	if err != nil {
+		t.Fatalf("ReceiveTransfer() error = %v", err)
+	}
+	if partial.Status != models.TransferInTransit || partial.Lines[0].ReceivedQuantity != 3 {
+		t.Errorf("partial = %+v, want in transit with 3 Kopi received", partial)
+	}
+	if got := env.outletStock(t, branchID, kopiID, nil); got != 3 {
+		t.Errorf("branch Kopi stock = %v, want 3", got)
+	}
+
+	// The rest arrives in full and completes the transfer
+	received, err := env.transfers.ReceiveTransfer(ctx, transfer.ID, models.TransferReceipt{
+		Lines: []models.ReceivedLine{{LineID: kopiLine, Quantity: 1}, {LineID: berasLine, Quantity: 2.5}},
+	})
+	if err != nil {
+		t.Fatalf("ReceiveTransfer() error = %v", err)
+	}
+	if received.Status != models.TransferReceived || received.ReceivedAt == nil {
+		t.Errorf("received = %+v, want received", received)
+	}
+	for _, l := range received.Lines {
+		if l.Discrepancy != 0 {
+			t.Errorf("line %d discrepancy = %v, want 0", l.ID, l.Discrepancy)
+		}
+	}
+	if got := env.outletStock(t, branchID, berasID, nil); got != 2.5 {
+		t.Errorf("branch Beras stock = %v, want 2.5", got)
+	}
+	if got := env.stockOf(t, kopiID); got != 10 {
+		t.Errorf("total Kopi stock = %v, want 10", got)
+	}
+
+	// A received transfer is final
+	_, err = env.transfers.ReceiveTransfer(ctx, transfer.ID, models.TransferReceipt{Close: true})
+	if !errors.Is(err, ErrTransferStatus) {
+		t.Errorf("ReceiveTransfer() error = %v, want ErrTransferStatus", err)
+	}
+}
+
+func TestTransferServiceDiscrepancy(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	transfer, branchID := newTransfer(t, env)
+	if _, err := env.transfers.SendTransfer(ctx, transfer.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing records what never arrived; it leaves the chain's stock
+	received, err := env.transfers.ReceiveTransfer(ctx, transfer.ID, models.TransferReceipt{
+		Lines: []models.ReceivedLine{{LineID: transfer.Lines[0].ID, Quantity: 3, Note: " one box broken "}},
+		Close: true,
+	})
+	if err != nil {
+		t.Fatalf("ReceiveTransfer() error = %v", err)
+	}
+	if received.Status != models.TransferReceived {
+		t.Errorf("status = %s, want received", received.Status)
+	}
+	kopi, beras := received.Lines[0], received.Lines[1]
+	if kopi.Discrepancy != 1 || kopi.DiscrepancyNote != "one box broken" {
+		t.Errorf("Kopi line = %+v, want 1 missing with the note", kopi)
+	}
+	if beras.Discrepancy != 2.5 || beras.ReceivedQuantity != 0 {
+		t.Errorf("Beras line = %+v, want all 2.5 kg missing", beras)
+	}
+	if got := env.outletStock(t, branchID, kopiID, nil); got != 3 {
+		t.Errorf("branch Kopi stock = %v, want 3", got)
+	}
+	if got := env.stockOf(t, kopiID); got != 9 {
+		t.Errorf("total Kopi stock = %v, want 9", got)
+	}
+}
+
+func TestTransferServiceDraft(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	transfer, branchID := newTransfer(t, env)
+
+	updated, err := env.transfers.UpdateTransfer(ctx, transfer.ID, models.StockTransfer{
+		FromOutletID: models.DefaultOutletID,
+		ToOutletID:   branchID,
+		Lines:        []models.TransferLine{{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateTransfer() error = %v", err)
+	}
+	if len(updated.Lines) != 1 || updated.Lines[0].VariantID == nil || updated.Note != "" {
+		t.Errorf("updated = %+v, want the Es Teh L line only", updated)
+	}
+
+	transfers, err := env.transfers.GetAllTransfers(ctx, models.TransferFilter{OutletIDs: []int{branchID}, Status: models.TransferDraft})
+	if err != nil {
+		t.Fatalf("GetAllTransfers() error = %v", err)
+	}
+	if len(transfers) != 1 || transfers[0].Lines != nil {
+		t.Errorf("transfers = %+v, want the draft without lines", transfers)
+	}
+
+	// Sending more than the source holds is refused as a whole
+	if _, err := env.transfers.UpdateTransfer(ctx, transfer.ID, models.StockTransfer{
+		FromOutletID: models.DefaultOutletID,
+		ToOutletID:   branchID,
+		Lines: []models.TransferLine{
+			{ProductID: esTehID, VariantID: intPtr(esTehLargeID), Quantity: 2},
+			{ProductID: kopiID, Quantity: 11},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.transfers.SendTransfer(ctx, transfer.ID); !errors.Is(err, repositories.ErrInsufficientStock) {
+		t.Fatalf("SendTransfer() error = %v, want ErrInsufficientStock", err)
+	}
+	if got := env.outletStock(t, models.DefaultOutletID, esTehID, intPtr(esTehLargeID)); got != 2 {
+		t.Errorf("main outlet Es Teh L stock = %v, want 2", got)
+	}
+
+	// The branch cannot be deleted while it has transfers
+	if err := env.outlets.DeleteOutlet(ctx, branchID); err == nil {
+		t.Error("DeleteOutlet() of outlet with transfers succeeded")
+	}
+	if err := env.transfers.DeleteTransfer(ctx, transfer.ID); err != nil {
+		t.Fatalf("DeleteTransfer() error = %v", err)
+	}
+	if _, err := env.transfers.GetTransferByID(ctx, transfer.ID); err == nil {
+		t.Error("GetTransferByID() of deleted transfer succeeded")
+	}
+}
+
+func TestTransferServiceRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(env *testEnv, transfer *models.StockTransfer, branchID int) error
+		wantErr string
+	}{
+		{
+			name: "same outlet",
+			run: func(env *testEnv, _ *models.StockTransfer, _ int) error {
+				_, err := env.transfers.CreateTransfer(context.Background(), models.StockTransfer{
+					FromOutletID: 1, ToOutletID: 1,
+					Lines: []models.TransferLine{{ProductID: kopiID, Quantity: 1}},
+				})
+				return err
+			},
+			wantErr: "transfer must be between two different outlets",
+		},
+		{
+			name: "unknown outlet",
+			run: func(env *testEnv, _ *models.StockTransfer, _ int) error {
+				_, err := env.transfers.CreateTransfer(context.Background(), models.StockTransfer{
+					FromOutletID: 1, ToOutletID: 9,
+					Lines: []models.TransferLine{{ProductID: kopiID, Quantity: 1}},
+				})
+				return err
+			},
+			wantErr: "Outlet with ID 9 not found",
+		},
+		{
+			name: "no lines",
+			run: func(env *testEnv, _ *models.StockTransfer, branchID int) error {
+				_, err := env.transfers.CreateTransfer(context.Background(), models.StockTransfer{FromOutletID: 1, ToOutletID: branchID})
+				return err
+			},
+			wantErr: "transfer must have at least one line",
+		},
+		{
+			name: "bundle",
+			run: func(env *testEnv, _ *models.StockTransfer, branchID int) error {
+				_, err := env.transfers.CreateTransfer(context.Background(), models.StockTransfer{
+					FromOutletID: 1, ToOutletID: branchID,
+					Lines: []models.TransferLine{{ProductID: paketID, Quantity: 1}},
+				})
+				return err
+			},
+			wantErr: "bundle product with ID 4 has no stock of its own",
+		},
+		{
+			name: "product twice",
+			run: func(env *testEnv, _ *models.StockTransfer, branchID int) error {
+				_, err := env.transfers.CreateTransfer(context.Background(), models.StockTransfer{
+					FromOutletID: 1, ToOutletID: branchID,
+					Lines: []models.TransferLine{{ProductID: kopiID, Quantity: 1}, {ProductID: kopiID, Quantity: 2}},
+				})
+				return err
+			},
+			wantErr: "product with ID 1 is on the transfer twice",
+		},
+		{
+			name: "variant of another product",
+			run: func(env *testEnv, _ *models.StockTransfer, branchID int) error {
+				_, err := env.transfers.CreateTransfer(context.Background(), models.StockTransfer{
+					FromOutletID: 1, ToOutletID: branchID,
+					Lines: []models.TransferLine{{ProductID: kopiID, VariantID: intPtr(esTehSmallID), Quantity: 1}},
+				})
+				return err
+			},
+			wantErr: "variant with ID 1 not found for product with ID 1",
+		},
+		{
+			name: "zero quantity",
+			run: func(env *testEnv, _ *models.StockTransfer, branchID int) error {
+				_, err := env.transfers.CreateTransfer(context.Background(), models.StockTransfer{
+					FromOutletID: 1, ToOutletID: branchID,
+					Lines: []models.TransferLine{{ProductID: kopiID, Quantity: 0.0001}},
+				})
+				return err
+			},
+			wantErr: "quantity must be greater than 0",
+		},
+		{
+			name: "receive draft",
+			run: func(env *testEnv, transfer *models.StockTransfer, _ int) error {
+				_, err := env.transfers.ReceiveTransfer(context.Background(), transfer.ID, models.TransferReceipt{Close: true})
+				return err
+			},
+			wantErr: "transfer with ID 1 is draft, not in_transit",
+		},
+		{
+			name: "empty receipt",
+			run: func(env *testEnv, transfer *models.StockTransfer, _ int) error {
+				_, err := env.transfers.ReceiveTransfer(context.Background(), transfer.ID, models.TransferReceipt{})
+				return err
+			},
+			wantErr: "receipt must have at least one line",
+		},
+		{
+			name: "receive more than sent",
+			run: func(env *testEnv, transfer *models.StockTransfer, _ int) error {
+				if _, err := env.transfers.SendTransfer(context.Background(), transfer.ID); err != nil {
+					return err
+				}
+				_, err := env.transfers.ReceiveTransfer(context.Background(), transfer.ID, models.TransferReceipt{
+					Lines: []models.ReceivedLine{{LineID: transfer.Lines[1].ID, Quantity: 1}, {LineID: transfer.Lines[0].ID, Quantity: 5}},
+				})
+				return err
+			},
+			wantErr: "received quantity of transfer line with ID 1 exceeds the quantity sent",
+		},
+		{
+			name: "unknown line",
+			run: func(env *testEnv, transfer *models.StockTransfer, _ int) error {
+				if _, err := env.transfers.SendTransfer(context.Background(), transfer.ID); err != nil {
+					return err
+				}
+				_, err := env.transfers.ReceiveTransfer(context.Background(), transfer.ID, models.TransferReceipt{
+					Lines: []models.ReceivedLine{{LineID: 9, Quantity: 1}},
+				})
+				return err
+			},
+			wantErr: "Transfer line with ID 9 not found",
+		},
+		{
+			name: "edit sent transfer",
+			run: func(env *testEnv, transfer *models.StockTransfer, _ int) error {
+				if _, err := env.transfers.SendTransfer(context.Background(), transfer.ID); err != nil {
+					return err
+				}
+				return env.transfers.DeleteTransfer(context.Background(), transfer.ID)
+			},
+			wantErr: "transfer with ID 1 is in_transit, not draft",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			transfer, branchID := newTransfer(t, env)
+			if err := tt.run(env, transfer, branchID); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if got := env.outletStock(t, branchID, kopiID, nil); got != 0 {
+				t.Errorf("branch Kopi stock = %v, want 0", got)
+			}
+		})
+	}
+}
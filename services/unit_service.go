@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// UnitService handles business logic for units of measure and quantity conversions
+type UnitService struct {
+	repo repositories.UnitRepository
+}
+
+// NewUnitService creates a new UnitService
+func NewUnitService(repo repositories.UnitRepository) *UnitService {
+	return &UnitService{repo: repo}
+}
+
+// GetAllUnits returns all units of measure
+func (s *UnitService) GetAllUnits(ctx context.Context) ([]models.Unit, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// CreateUnit creates a new unit of measure
+func (s *UnitService) CreateUnit(ctx context.Context, unit models.Unit) (*models.Unit, error) {
+	if unit.Code == "" || unit.Name == "" {
+		return nil, fmt.Errorf("unit code and name are required")
+	}
+	if err := validateUnit(&unit); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, unit)
+}
+
+// UpdateUnit updates an existing unit of measure
+func (s *UnitService) UpdateUnit(ctx context.Context, code string, unit models.Unit) (*models.Unit, error) {
+	if unit.Name == "" {
+		return nil, fmt.Errorf("unit name is required")
+	}
+	if err := validateUnit(&unit); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, code, unit)
+}
+
+// GetConversions returns the packaging units of a product
+func (s *UnitService) GetConversions(ctx context.Context, productID int) ([]models.UnitConversion, error) {
+	return s.repo.GetConversions(ctx, productID)
+}
+
+// SaveConversion creates or updates a packaging unit of a product
+func (s *UnitService) SaveConversion(ctx context.Context, productID int, conversion models.UnitConversion) (*models.UnitConversion, error) {
+	if conversion.Unit == "" {
+		return nil, fmt.Errorf("unit is required")
+	}
+	if conversion.Factor <= 0 {
+		return nil, fmt.Errorf("factor must be greater than 0")
+	}
+	conversion.ProductID = productID
+	return s.repo.SaveConversion(ctx, conversion)
+}
+
+// DeleteConversion deletes a packaging unit of a product
+func (s *UnitService) DeleteConversion(ctx context.Context, productID int, unit string) error {
+	return s.repo.DeleteConversion(ctx, productID, unit)
+}
+
+// ToProductUnit converts a quantity sold in the given unit into the unit the
+// product is priced and stocked in. An empty unit means the product unit.
+func (s *UnitService) ToProductUnit(ctx context.Context, product models.Product, quantity float64, unit string) (float64, error) {
+	converter, err := s.NewConverter(ctx, []int{product.ID})
+	if err != nil {
+		return 0, err
+	}
+	return converter.ToProductUnit(product, quantity, unit)
+}
+
+// NewConverter loads the units of measure and the packaging units of the
+// given products, so the lines of a basket are converted without a query each
+func (s *UnitService) NewConverter(ctx context.Context, productIDs []int) (*UnitConverter, error) {
+	units, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conversions, err := s.repo.GetConversionsByProductIDs(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &UnitConverter{
+		units:       make(map[string]models.Unit, len(units)),
+		conversions: make(map[int]map[string]models.UnitConversion),
+	}
+	for _, u := range units {
+		c.units[u.Code] = u
+	}
+	for _, conversion := range conversions {
+		if c.conversions[conversion.ProductID] == nil {
+			c.conversions[conversion.ProductID] = make(map[string]models.UnitConversion)
+		}
+		c.conversions[conversion.ProductID][conversion.Unit] = conversion
+	}
+	return c, nil
+}
+
+// UnitConverter converts sold quantities with the units loaded by NewConverter
+type UnitConverter struct {
+	units       map[string]models.Unit
+	conversions map[int]map[string]models.UnitConversion
+}
+
+// ToProductUnit converts a quantity sold in the given unit into the unit the
+// product is priced and stocked in. An empty unit means the product unit.
+func (c *UnitConverter) ToProductUnit(product models.Product, quantity float64, unit string) (float64, error) {
+	if unit == "" {
+		unit = product.Unit
+	}
+
+	productUnit, err := c.unit(product.Unit)
+	if err != nil {
+		return 0, err
+	}
+
+	factor := 1.0
+	precision := productUnit.Precision
+	if unit != product.Unit {
+		if conversion, ok := c.conversions[product.ID][unit]; ok {
+			// Packaging units are only sold whole
+			factor = conversion.Factor
+			precision = 0
+		} else {
+			soldUnit, err := c.unit(unit)
+			if err != nil {
+				return 0, err
+			}
+			if baseOf(soldUnit) != baseOf(productUnit) {
+				return 0, fmt.Errorf("cannot convert %s to %s for product with ID %d", unit, product.Unit, product.ID)
+			}
+			factor = soldUnit.Factor / productUnit.Factor
+			precision = soldUnit.Precision
+		}
+	}
+
+	if roundQuantity(quantity, precision) != quantity {
+		return 0, fmt.Errorf("quantity %v exceeds the precision of unit %s (%d decimals)", quantity, unit, precision)
+	}
+
+	converted := roundQuantity(quantity*factor, productUnit.Precision)
+	if converted <= 0 {
+		return 0, fmt.Errorf("quantity %v %s is too small to sell in %s", quantity, unit, product.Unit)
+	}
+	return converted, nil
+}
+
+// unit returns a unit of measure by code
+func (c *UnitConverter) unit(code string) (*models.Unit, error) {
+	u, ok := c.units[code]
+	if !ok {
+		return nil, fmt.Errorf("Unit %s not found", code)
+	}
+	return &u, nil
+}
+
+// validateUnit checks the precision and conversion factor of a unit
+func validateUnit(unit *models.Unit) error {
+	if unit.Precision < 0 || unit.Precision > 3 {
+		return fmt.Errorf("precision must be between 0 and 3")
+	}
+	if unit.Factor == 0 {
+		unit.Factor = 1
+	}
+	if unit.Factor < 0 {
+		return fmt.Errorf("factor must be greater than 0")
+	}
+	return nil
+}
+
+// baseOf returns the unit a unit converts into
+func baseOf(unit *models.Unit) string {
+	if unit.BaseUnit != "" {
+		return unit.BaseUnit
+	}
+	return unit.Code
+}
+
+// roundQuantity rounds a quantity to the given number of decimals
+func roundQuantity(quantity float64, precision int) float64 {
+	pow := math.Pow(10, float64(precision))
+	return math.Round(quantity*pow) / pow
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestUnitServiceToProductUnit(t *testing.T) {
+	kopi := models.Product{ID: kopiID, Unit: "pcs"}
+	beras := models.Product{ID: berasID, Unit: "kg"}
+
+	tests := []struct {
+		name     string
+		product  models.Product
+		quantity float64
+		unit     string
+		want     float64
+		wantErr  string
+	}{
+		{name: "product unit by default", product: kopi, quantity: 3, want: 3},
+		{name: "packaging unit", product: kopi, quantity: 2, unit: "box", want: 12},
+		{name: "packaging unit is sold whole", product: kopi, quantity: 1.5, unit: "box", wantErr: "exceeds the precision"},
+		{name: "pieces are sold whole", product: kopi, quantity: 0.5, wantErr: "exceeds the precision"},
+		{name: "weighed product", product: beras, quantity: 1.25, want: 1.25},
+		{name: "gram to kilogram", product: beras, quantity: 250, unit: "gram", want: 0.25},
+		{name: "more decimals than the unit allows", product: beras, quantity: 1.2345, wantErr: "exceeds the precision"},
+		{name: "incompatible unit", product: beras, quantity: 1, unit: "liter", wantErr: "cannot convert liter to kg"},
+		{name: "unknown unit", product: beras, quantity: 1, unit: "ons", wantErr: "Unit ons not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			got, err := env.units.ToProductUnit(context.Background(), tt.product, tt.quantity, tt.unit)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ToProductUnit() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToProductUnit() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ToProductUnit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnitServiceCreateUnit(t *testing.T) {
+	tests := []struct {
+		name       string
+		unit       models.Unit
+		wantFactor float64
+		wantErr    string
+	}{
+		{name: "defaults factor to 1", unit: models.Unit{Code: "pack", Name: "Pack"}, wantFactor: 1},
+		{name: "derived unit", unit: models.Unit{Code: "ons", Name: "Ons", Precision: 1, BaseUnit: "kg", Factor: 0.1}, wantFactor: 0.1},
+		{name: "missing name", unit: models.Unit{Code: "pack"}, wantErr: "unit code and name are required"},
+		{name: "precision out of range", unit: models.Unit{Code: "mg", Name: "Milligram", Precision: 4}, wantErr: "precision must be between 0 and 3"},
+		{name: "negative factor", unit: models.Unit{Code: "x", Name: "X", Factor: -1}, wantErr: "factor must be greater than 0"},
+		{name: "duplicate code", unit: models.Unit{Code: "kg", Name: "Kilo"}, wantErr: "already exists"},
+		{name: "unknown base unit", unit: models.Unit{Code: "ml", Name: "Mililiter", BaseUnit: "l", Factor: 0.001}, wantErr: "unit l does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			got, err := env.units.CreateUnit(context.Background(), tt.unit)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CreateUnit() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateUnit() error = %v", err)
+			}
+			if got.Factor != tt.wantFactor {
+				t.Errorf("CreateUnit() factor = %v, want %v", got.Factor, tt.wantFactor)
+			}
+		})
+	}
+}
+
+func TestUnitServiceConversions(t *testing.T) {
+	tests := []struct {
+		name       string
+		productID  int
+		conversion models.UnitConversion
+		wantErr    string
+	}{
+		{name: "create", productID: berasID, conversion: models.UnitConversion{Unit: "karung", Factor: 25}},
+		{name: "update existing", productID: kopiID, conversion: models.UnitConversion{Unit: "box", Factor: 12}},
+		{name: "missing unit", productID: kopiID, conversion: models.UnitConversion{Factor: 12}, wantErr: "unit is required"},
+		{name: "zero factor", productID: kopiID, conversion: models.UnitConversion{Unit: "box"}, wantErr: "factor must be greater than 0"},
+		{name: "unknown product", productID: 99, conversion: models.UnitConversion{Unit: "box", Factor: 2}, wantErr: "does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			got, err := env.units.SaveConversion(ctx, tt.productID, tt.conversion)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SaveConversion() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SaveConversion() error = %v", err)
+			}
+
+			conversions, err := env.units.GetConversions(ctx, tt.productID)
+			if err != nil || len(conversions) != 1 || conversions[0].Factor != tt.conversion.Factor || conversions[0].ID != got.ID {
+				t.Errorf("GetConversions() = %+v, %v; want the saved conversion only", conversions, err)
+			}
+		})
+	}
+}
+
+func TestUnitServiceDeleteConversion(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if err := env.units.DeleteConversion(ctx, kopiID, "box"); err != nil {
+		t.Fatalf("DeleteConversion() error = %v", err)
+	}
+	if err := env.units.DeleteConversion(ctx, kopiID, "box"); err == nil {
+		t.Error("second DeleteConversion() succeeded, want not found")
+	}
+}
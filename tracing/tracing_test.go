@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+// restoreGlobals puts back the global tracer provider and propagator Setup
+// replaces
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestSetupStdout(t *testing.T) {
+	restoreGlobals(t)
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{
+		ServiceName:    "Kasir API",
+		ServiceVersion: "1.0",
+		Environment:    "development",
+		Exporter:       ExporterStdout,
+		SampleRatio:    1,
+		Stdout:         &buf,
+	})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "checkout")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{`"Name":"checkout"`, `"Value":"Kasir API"`, `"Key":"deployment.environment.name"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("exported spans = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestSetupSampleRatioZero(t *testing.T) {
+	restoreGlobals(t)
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, Stdout: &buf})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "checkout")
+	span.End()
+	shutdown(context.Background())
+	if buf.Len() != 0 {
+		t.Errorf("exported spans = %s, want none with a sample ratio of 0", buf.String())
+	}
+}
+
+func TestSetupNone(t *testing.T) {
+	restoreGlobals(t)
+	provider := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+	if otel.GetTracerProvider() != provider {
+		t.Error("Setup() with no exporter replaced the tracer provider")
+	}
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	restoreGlobals(t)
+	if _, err := Setup(context.Background(), Config{Exporter: "jaeger"}); err == nil || !strings.Contains(err.Error(), "jaeger") {
+		t.Errorf("Setup() error = %v, want unknown exporter", err)
+	}
+}